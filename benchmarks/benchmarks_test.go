@@ -0,0 +1,109 @@
+package benchmarks
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/supratick/message_producer/internal/codec"
+	"github.com/supratick/message_producer/internal/generator"
+	"github.com/supratick/message_producer/internal/models"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// newProducer returns a seeded producer over the bundled reference data
+func newProducer(b *testing.B) *generator.Producer {
+	rd, err := generator.LoadReferenceData("../data")
+	if err != nil {
+		b.Fatal(err)
+	}
+	p := generator.NewProducer(rd, discard)
+	p.SetSeed(1)
+	return p
+}
+
+// transactions pre-generates n realistic transactions so sink benchmarks
+// measure the sink alone
+func transactions(b *testing.B, n int) []*models.Transaction {
+	p := newProducer(b)
+	txns := make([]*models.Transaction, n)
+	for i := range txns {
+		txns[i] = p.GenerateSingle()
+	}
+	return txns
+}
+
+func BenchmarkGenerateTransaction(b *testing.B) {
+	p := newProducer(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.GenerateSingle()
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msgs/sec")
+}
+
+// fileSink is the part of the file writers the flush benchmarks drive
+type fileSink interface {
+	Write(ctx context.Context, input <-chan *models.Transaction) error
+	Close() error
+}
+
+// benchmarkFlush streams b.N transactions through a file writer and closes
+// it, so buffering, flushing and the final footer are all measured
+func benchmarkFlush(b *testing.B, open func(dir string) (fileSink, error)) {
+	txns := transactions(b, 10000)
+	w, err := open(b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	input := make(chan *models.Transaction, 10000)
+	go func() {
+		for i := 0; i < b.N; i++ {
+			input <- txns[i%len(txns)]
+		}
+		close(input)
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	if err := w.Write(context.Background(), input); err != nil {
+		b.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		b.Fatal(err)
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msgs/sec")
+}
+
+func BenchmarkCSVFlush(b *testing.B) {
+	benchmarkFlush(b, func(dir string) (fileSink, error) {
+		return writer.NewCSVWriter(dir, "bench.csv", 10000, writer.CSVFormat{}, discard)
+	})
+}
+
+func BenchmarkParquetFlush(b *testing.B) {
+	benchmarkFlush(b, func(dir string) (fileSink, error) {
+		return writer.NewParquetWriter(dir, "bench.parquet", 50000, "snappy", writer.ParquetTuning{}, discard)
+	})
+}
+
+func BenchmarkJSONEncode(b *testing.B) {
+	txns := transactions(b, 10000)
+	format, err := codec.Lookup("json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	buf := make([]byte, 0, 512)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf = format.Append(txns[i%len(txns)], buf[:0])
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msgs/sec")
+}
@@ -0,0 +1,141 @@
+// Command compare checks `go test -bench` output against a stored baseline
+// and exits non-zero when a benchmark regressed by more than the threshold:
+//
+//	go run ./benchmarks/compare -baseline benchmarks/baseline.txt bench.out
+//
+// Both files may hold several runs per benchmark (-count); medians are
+// compared. ns/op and allocs/op are gated, B/op is reported only.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// cpuSuffix is the -GOMAXPROCS suffix go test appends to benchmark names
+var cpuSuffix = regexp.MustCompile(`-\d+$`)
+
+// samples holds every measurement of one benchmark, keyed by unit
+type samples map[string][]float64
+
+func main() {
+	baselinePath := flag.String("baseline", "benchmarks/baseline.txt", "Baseline benchmark output")
+	threshold := flag.Float64("threshold", 10, "Allowed regression in percent")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: compare [-baseline file] [-threshold 10] current.txt")
+		os.Exit(2)
+	}
+
+	baseline, err := parse(*baselinePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	current, err := parse(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if len(current) == 0 {
+		fmt.Fprintln(os.Stderr, "no benchmark results in", flag.Arg(0))
+		os.Exit(2)
+	}
+
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BENCHMARK\tUNIT\tBASELINE\tCURRENT\tDELTA\tRESULT")
+	regressions := 0
+	for _, name := range names {
+		base, ok := baseline[name]
+		if !ok {
+			fmt.Fprintf(tw, "%s\t\t\t\t\tnew (not in baseline)\n", name)
+			continue
+		}
+		for _, unit := range []string{"ns/op", "B/op", "allocs/op"} {
+			b, c := median(base[unit]), median(current[name][unit])
+			if b < 0 || c < 0 {
+				continue
+			}
+			delta := 0.0
+			if b > 0 {
+				delta = (c - b) / b * 100
+			} else if c > 0 {
+				delta = 100
+			}
+			result := "ok"
+			if unit != "B/op" && delta > *threshold {
+				result = "REGRESSION"
+				regressions++
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%.1f\t%.1f\t%+.1f%%\t%s\n", name, unit, b, c, delta, result)
+		}
+	}
+	tw.Flush()
+
+	if regressions > 0 {
+		fmt.Printf("\n%d regressions over %.0f%%\n", regressions, *threshold)
+		os.Exit(1)
+	}
+	fmt.Printf("\nno regressions over %.0f%%\n", *threshold)
+}
+
+// parse reads benchmark result lines: name, iterations, then value/unit pairs
+func parse(path string) (map[string]samples, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open benchmark results: %w", err)
+	}
+	defer f.Close()
+
+	results := make(map[string]samples)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		name := cpuSuffix.ReplaceAllString(fields[0], "")
+		if results[name] == nil {
+			results[name] = make(samples)
+		}
+		for i := 2; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			results[name][fields[i+1]] = append(results[name][fields[i+1]], v)
+		}
+	}
+	return results, scanner.Err()
+}
+
+// median returns the middle value, or -1 without samples
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return -1
+	}
+	sorted := slices.Clone(values)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
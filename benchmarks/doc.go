@@ -0,0 +1,4 @@
+// Package benchmarks holds the performance regression suite: micro-benchmarks
+// of the hot paths (generation, CSV and Parquet flushing, JSON encoding)
+// compared against baseline.txt by `make bench-check`
+package benchmarks
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/supratick/message_producer/internal/config"
+)
+
+// archiveFlags are the `producer archive` options, applied over the loaded
+// configuration to turn the run around: a Kafka topic is consumed and written
+// to the file sinks with their usual schema and rotation
+type archiveFlags struct {
+	topic         *string
+	format        *string
+	serialization *string
+	follow        *bool
+}
+
+// registerArchiveFlags adds the archive options to the run's flag set
+func registerArchiveFlags(fs *flag.FlagSet) *archiveFlags {
+	a := &archiveFlags{
+		topic:         fs.String("topic", "", "Topic to archive (default kafka.topic)"),
+		format:        fs.String("format", "", "Files to write: csv, parquet, both or msgpack (default output.format)"),
+		serialization: fs.String("serialization", "", "Record encoding on the topic: json, csv or avro (default kafka.serialization)"),
+		follow:        fs.Bool("follow", false, "Keep archiving new records instead of stopping at the end of the topic"),
+	}
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: producer archive [-config config.yaml] [-topic name] [-format parquet] [-serialization json] [-follow]")
+		fs.PrintDefaults()
+	}
+	return a
+}
+
+// apply reads the topic from the beginning through a Kafka source and keeps
+// only the file sinks
+func (a *archiveFlags) apply(cfg *config.Config) error {
+	cfg.Source = config.SourceConfig{
+		Type:   "kafka",
+		Format: firstNonEmpty(*a.serialization, cfg.Kafka.Serialization, "json"),
+		Kafka: config.SourceKafkaConfig{
+			Brokers:       cfg.Kafka.Brokers,
+			Topic:         firstNonEmpty(*a.topic, cfg.Kafka.Topic),
+			FromBeginning: true,
+			StopAtEnd:     !*a.follow,
+		},
+	}
+	if *a.format != "" {
+		cfg.Output.Format = *a.format
+	}
+	cfg.Output.Sinks = nil
+	switch cfg.Output.Format {
+	case "csv":
+		cfg.Output.CSV.Enabled = true
+	case "parquet":
+		cfg.Output.Parquet.Enabled = true
+	case "both":
+		cfg.Output.CSV.Enabled = true
+		cfg.Output.Parquet.Enabled = true
+	case "msgpack":
+		cfg.Output.Msgpack.Enabled = true
+	}
+
+	cfg.Kafka.Enabled = false
+	cfg.Socket.Enabled = false
+	cfg.Fluent.Enabled = false
+	cfg.Syslog.Enabled = false
+	cfg.FIFO.Enabled = false
+	cfg.Snowflake.Enabled = false
+	cfg.Postgres.Enabled = false
+	cfg.ClickHouse.Enabled = false
+	cfg.GRPC.Enabled = false
+	if cfg.Producer.Transport == "ring" {
+		cfg.Producer.Transport = "channel"
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid archive configuration: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"time"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/pipeline"
+)
+
+// sinkBatching returns the pipeline batching configured for sink
+func sinkBatching(cfg config.BatchingConfig, sink string) pipeline.Batching {
+	limits := cfg.For(sink)
+	return pipeline.Batching{
+		MaxRecords: limits.MaxRecords,
+		MaxBytes:   int(limits.MaxBytes),
+		Linger:     time.Duration(limits.LingerMs) * time.Millisecond,
+	}
+}
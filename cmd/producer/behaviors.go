@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/generator"
+)
+
+// startPlayerBehaviors injects scripted responsible-gambling sessions into
+// producer and writes their labels as JSON lines in dir. The returned closer
+// flushes the labels file; call it after generation has finished.
+func startPlayerBehaviors(cfg config.PlayerBehaviorsConfig, dir string, producer *generator.Producer, logger *slog.Logger) (func() error, error) {
+	path := filepath.Join(dir, firstNonEmpty(cfg.LabelsFile, "rg_labels.jsonl"))
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create labels file: %w", err)
+	}
+	buf := bufio.NewWriter(file)
+	encoder := json.NewEncoder(buf)
+
+	var labels int64
+	var writeErr error
+	err = producer.SetBehaviors(generator.BehaviorConfig{
+		Rate:          cfg.Rate,
+		Weights:       cfg.Weights,
+		SessionLength: cfg.SessionLength,
+	}, func(label generator.BehaviorLabel) {
+		// Called under the injector's lock, so writes are serialized
+		if err := encoder.Encode(label); err != nil && writeErr == nil {
+			writeErr = err
+		}
+		labels++
+	})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	logger.Info("Player behavior scenarios enabled", "rate", cfg.Rate, "labels", path)
+
+	return func() error {
+		err := writeErr
+		if flushErr := buf.Flush(); err == nil {
+			err = flushErr
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		logger.Info("Player behavior labels written", "labels", labels, "path", path)
+		return err
+	}, nil
+}
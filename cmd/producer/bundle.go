@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/supratick/message_producer/internal/bundle"
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/provenance"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// maxEarlyLog caps the log held in memory until the config says whether a
+// bundle wants it
+const maxEarlyLog = 1 << 20
+
+// defaultBundleSample is the number of transactions in sample.jsonl when
+// bundle.sample_size is unset
+const defaultBundleSample = 1000
+
+// logCapture passes the log through to out and, for the run bundle, copies
+// it to a temporary file. Lines logged before the config is loaded are held
+// in memory until keep or discard decides.
+type logCapture struct {
+	mu      sync.Mutex
+	out     io.Writer
+	early   bytes.Buffer
+	decided bool
+	file    *os.File
+}
+
+func newLogCapture(out io.Writer) *logCapture {
+	return &logCapture{out: out}
+}
+
+func (l *logCapture) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch {
+	case l.file != nil:
+		l.file.Write(p)
+	case !l.decided && l.early.Len()+len(p) <= maxEarlyLog:
+		l.early.Write(p)
+	}
+	return l.out.Write(p)
+}
+
+// keep copies the log from its first line on to a temporary file
+func (l *logCapture) keep() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	file, err := os.CreateTemp("", "producer-*.log")
+	if err != nil {
+		return fmt.Errorf("failed to create log copy: %w", err)
+	}
+	if _, err := file.Write(l.early.Bytes()); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to write log copy: %w", err)
+	}
+	l.file, l.decided = file, true
+	l.early = bytes.Buffer{}
+	return nil
+}
+
+// discard stops holding the log
+func (l *logCapture) discard() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.decided = true
+	l.early = bytes.Buffer{}
+}
+
+// close stops copying and removes the copy
+func (l *logCapture) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		l.file.Close()
+		os.Remove(l.file.Name())
+		l.file = nil
+	}
+}
+
+// runBundle gathers what bundle.enabled packages at the end of a run
+type runBundle struct {
+	cfg       config.BundleConfig
+	snapshot  []byte // effective config, before secret references are resolved
+	collector *bundle.Collector
+	log       *logCapture
+}
+
+// newRunBundle snapshots cfg and starts copying the log. Call it before
+// secrets are resolved so the snapshot keeps their references.
+func newRunBundle(cfg *config.Config, log *logCapture) (*runBundle, error) {
+	snapshot, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot config: %w", err)
+	}
+	if err := log.keep(); err != nil {
+		return nil, err
+	}
+	sample := cfg.Bundle.SampleSize
+	if sample == 0 {
+		sample = defaultBundleSample
+	}
+	return &runBundle{
+		cfg:       cfg.Bundle,
+		snapshot:  snapshot,
+		collector: bundle.NewCollector(sample),
+		log:       log,
+	}, nil
+}
+
+// write packages the run into its tar.gz. stmt is the provenance statement
+// already written, or nil to hash the output for the bundle's manifest.
+// Failures are logged; the output itself is already complete.
+func (b *runBundle) write(cfg *config.Config, vars writer.FilenameVars, run provenance.Run, stmt *provenance.Statement, sinks map[string]int64, started time.Time) {
+	defer b.log.close()
+
+	if stmt == nil {
+		var err error
+		if stmt, err = provenanceStatement(cfg, run, sinks, started); err != nil {
+			slog.Error("Bundle manifest failed", "error", err)
+			return
+		}
+	}
+	manifest, err := stmt.Marshal()
+	if err != nil {
+		slog.Error("Bundle manifest failed", "error", err)
+		return
+	}
+	aggregates, err := b.collector.Aggregates()
+	if err != nil {
+		slog.Error("Bundle aggregates failed", "error", err)
+		return
+	}
+
+	files := []bundle.File{
+		{Name: "manifest.json", Data: manifest},
+		{Name: "config.yaml", Data: b.snapshot},
+		{Name: "sample.jsonl", Data: b.collector.Sample()},
+		{Name: "aggregates.json", Data: aggregates},
+	}
+	if cfg.Provenance.Enabled {
+		envelope := filepath.Join(cfg.Output.Directory, envelopeFilename(provenanceFilename(cfg.Provenance)))
+		if _, err := os.Stat(envelope); err == nil {
+			files = append(files, bundle.File{Name: "manifest.dsse.json", Path: envelope})
+		}
+	}
+
+	path := vars.Expand(b.cfg.Path)
+	if path == "" {
+		path = vars.Expand("run-{{run_id}}.tar.gz")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cfg.Output.Directory, path)
+	}
+	slog.Info("Writing run bundle", "path", path)
+
+	b.log.mu.Lock()
+	if b.log.file != nil {
+		b.log.file.Sync()
+		files = append(files, bundle.File{Name: "producer.log", Path: b.log.file.Name()})
+	}
+	b.log.mu.Unlock()
+
+	if err := bundle.Write(path, "run-"+vars.RunID, files, time.Now()); err != nil {
+		slog.Error("Failed to write run bundle", "path", path, "error", err)
+		return
+	}
+	slog.Info("Run bundle written", "path", path, "files", len(files))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/supratick/message_producer/internal/awsauth"
+	"github.com/supratick/message_producer/internal/catalog"
+	"github.com/supratick/message_producer/internal/config"
+)
+
+// registerCatalog adds the partitions found under dir to the configured
+// catalog. Failures are logged; the output itself is already complete.
+func registerCatalog(cfg config.CatalogConfig, dir string, logger *slog.Logger) {
+	partitions, err := catalog.DiscoverPartitions(dir, cfg.Location, cfg.PartitionKeys)
+	if err != nil {
+		slog.Error("Catalog registration failed", "error", err)
+		return
+	}
+	if len(partitions) == 0 {
+		slog.Warn("No partitions found for catalog registration",
+			"directory", dir,
+			"partition_keys", cfg.PartitionKeys,
+		)
+		return
+	}
+
+	registrar, err := newRegistrar(cfg, logger)
+	if err != nil {
+		slog.Error("Failed to create catalog registrar", "type", cfg.Type, "error", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	created, err := registrar.Register(ctx, partitions)
+	if err != nil {
+		slog.Error("Catalog registration failed", "type", cfg.Type, "registered", created, "error", err)
+		return
+	}
+	slog.Info("Catalog partitions registered",
+		"type", cfg.Type,
+		"table", cfg.Database+"."+cfg.Table,
+		"partitions", len(partitions),
+		"created", created,
+	)
+}
+
+// newRegistrar creates the registrar for cfg.Type
+func newRegistrar(cfg config.CatalogConfig, logger *slog.Logger) (catalog.Registrar, error) {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	switch cfg.Type {
+	case "glue":
+		creds := awsauth.FromEnv()
+		if cfg.AccessKeyID != "" {
+			creds = awsauth.Credentials{
+				AccessKeyID:     cfg.AccessKeyID,
+				SecretAccessKey: cfg.SecretAccessKey,
+				SessionToken:    cfg.SessionToken,
+			}
+		}
+		return catalog.NewGlueRegistrar(cfg.Region, cfg.Endpoint, cfg.Database, cfg.Table, creds, timeout, logger)
+	case "hive":
+		return catalog.NewHiveRegistrar(cfg.Endpoint, cfg.User, cfg.Database, cfg.Table, cfg.PartitionKeys, timeout, logger), nil
+	}
+	return nil, fmt.Errorf("unsupported catalog type: %s", cfg.Type)
+}
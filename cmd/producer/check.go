@@ -0,0 +1,460 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/generator"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// connCheck is one connectivity or auth test; run returns a short detail
+// for the report
+type connCheck struct {
+	name   string
+	target string
+	run    func(ctx context.Context) (string, error)
+}
+
+type checkResult struct {
+	connCheck
+	detail  string
+	err     error
+	elapsed time.Duration
+}
+
+// runCheck implements `producer check [-config config.yaml] [-timeout 10s]`,
+// testing every configured sink and data source before a long run
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "Path to configuration file")
+	scenarioName := fs.String("scenario", "", "Bundled scenario to apply before checking")
+	timeout := fs.Duration("timeout", 10*time.Second, "Timeout for each check")
+	params := paramFlags{}
+	fs.Var(params, "param", "Config template parameter name=value (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: producer check [-config config.yaml] [-param name=value] [-scenario name] [-timeout 10s]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	// Keep sink logging off the report
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	cfg, err := config.LoadTemplate(*configPath, params)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *scenarioName != "" {
+		if err := applyScenario(cfg, *scenarioName); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	applySchemaVersion(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Secrets come first: every other check needs the resolved values
+	var results []checkResult
+	if vault, aws := countReferences(reflect.ValueOf(cfg)); vault+aws > 0 {
+		results = append(results, runConnCheck(ctx, connCheck{
+			name:   "secrets",
+			target: secretsTarget(vault, aws),
+			run: func(ctx context.Context) (string, error) {
+				return fmt.Sprintf("%d references", vault+aws), resolveSecrets(ctx, cfg, logger)
+			},
+		}, *timeout))
+	}
+
+	checks := buildChecks(cfg, logger)
+	checked := make([]checkResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c connCheck) {
+			defer wg.Done()
+			checked[i] = runConnCheck(ctx, c, *timeout)
+		}(i, c)
+	}
+	wg.Wait()
+	results = append(results, checked...)
+
+	return printCheckResults(results)
+}
+
+func runConnCheck(ctx context.Context, c connCheck, timeout time.Duration) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := c.run(ctx)
+	return checkResult{connCheck: c, detail: detail, err: err, elapsed: time.Since(start)}
+}
+
+// buildChecks lists a check for each enabled sink and data source
+func buildChecks(cfg *config.Config, logger *slog.Logger) []connCheck {
+	checks := []connCheck{{
+		name:   "reference data",
+		target: filepath.Dir(cfg.Data.CurrencyRates),
+		run: func(ctx context.Context) (string, error) {
+			rd, err := generator.LoadReferenceData(filepath.Dir(cfg.Data.CurrencyRates))
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d currencies, %d agents, %d game categories",
+				len(rd.Currencies), len(rd.Agents), len(rd.GameCategories)), nil
+		},
+	}}
+
+	if cfg.Data.Houses != "" {
+		checks = append(checks, connCheck{
+			name:   "houses",
+			target: cfg.Data.Houses,
+			run: func(ctx context.Context) (string, error) {
+				rd, err := generator.LoadReferenceData(filepath.Dir(cfg.Data.CurrencyRates))
+				if err != nil {
+					return "", err
+				}
+				houses, err := generator.LoadHouses(cfg.Data.Houses)
+				if err != nil {
+					return "", err
+				}
+				if err := generator.NewProducer(rd, logger).SetHouses(houses); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%d houses", len(houses)), nil
+			},
+		})
+	}
+
+	if len(cfg.Data.Datasets) > 0 || len(cfg.Data.Fields) > 0 {
+		checks = append(checks, connCheck{
+			name:   "datasets",
+			target: fmt.Sprintf("%d datasets", len(cfg.Data.Datasets)),
+			run: func(ctx context.Context) (string, error) {
+				rd, err := generator.LoadReferenceData(filepath.Dir(cfg.Data.CurrencyRates))
+				if err != nil {
+					return "", err
+				}
+				if err := setupDatasets(generator.NewProducer(rd, logger), cfg.Data); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%d fields", len(cfg.Data.Fields)), nil
+			},
+		})
+	}
+
+	for _, dir := range outputDirectories(cfg) {
+		checks = append(checks, connCheck{
+			name:   "output directory",
+			target: dir,
+			run: func(ctx context.Context) (string, error) {
+				return "writable", checkWritable(dir)
+			},
+		})
+	}
+
+	if cfg.Output.Delivery.Protocol != "" {
+		checks = append(checks, checkDelivery(cfg.Output.Delivery))
+	}
+
+	if cfg.Output.DuckDB.Enabled {
+		checks = append(checks, connCheck{
+			name:   "duckdb",
+			target: filepath.Join(cfg.Output.Directory, cfg.Output.DuckDB.Filename),
+			run: func(ctx context.Context) (string, error) {
+				if !slices.Contains(sql.Drivers(), "duckdb") {
+					return "", errors.New("duckdb support not compiled in; rebuild with -tags duckdb")
+				}
+				return "driver linked", nil
+			},
+		})
+	}
+
+	if cfg.Output.SQLite.Enabled {
+		checks = append(checks, connCheck{
+			name:   "sqlite",
+			target: filepath.Join(cfg.Output.Directory, cfg.Output.SQLite.Filename),
+			run: func(ctx context.Context) (string, error) {
+				if !writer.SQLiteLinked() {
+					return "", errors.New("sqlite support not compiled in; rebuild with -tags sqlite")
+				}
+				return "driver linked", nil
+			},
+		})
+	}
+
+	if cfg.Kafka.Enabled {
+		checks = append(checks, connCheck{
+			name:   "kafka",
+			target: strings.Join(cfg.Kafka.Brokers, ",") + "/" + cfg.Kafka.Topic,
+			run: func(ctx context.Context) (string, error) {
+				deadline, _ := ctx.Deadline()
+				partitions, err := writer.CheckKafka(cfg.Kafka.Brokers, cfg.Kafka.Topic, kafkaAuth(cfg.Kafka), time.Until(deadline))
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("topic has %d partitions", partitions), nil
+			},
+		})
+	}
+	if cfg.Kafka.Enabled && cfg.Kafka.SchemaCheck.Enabled {
+		checks = append(checks, connCheck{
+			name:   "schema registry",
+			target: cfg.Kafka.Key.Registry.URL,
+			run: func(ctx context.Context) (string, error) {
+				// Report incompatibility even when the run would only warn
+				strict := cfg.Kafka
+				strict.SchemaCheck.OnIncompatible = "fail"
+				return "value schema compatible", checkValueSchema(ctx, strict, logger)
+			},
+		})
+	}
+
+	if cfg.Source.Type == "kafka" {
+		brokers := cfg.Source.Kafka.Brokers
+		if len(brokers) == 0 {
+			brokers = cfg.Kafka.Brokers
+		}
+		checks = append(checks, connCheck{
+			name:   "source kafka",
+			target: strings.Join(brokers, ",") + "/" + cfg.Source.Kafka.Topic,
+			run: func(ctx context.Context) (string, error) {
+				deadline, _ := ctx.Deadline()
+				partitions, err := writer.CheckKafka(brokers, cfg.Source.Kafka.Topic, kafkaAuth(cfg.Kafka), time.Until(deadline))
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("topic has %d partitions", partitions), nil
+			},
+		})
+	}
+
+	if cfg.Socket.Enabled {
+		checks = append(checks, dialCheck("socket", cfg.Socket.Network, cfg.Socket.Address))
+	}
+	if cfg.Fluent.Enabled {
+		checks = append(checks, dialCheck("fluent", "tcp", cfg.Fluent.Address))
+	}
+	if cfg.Syslog.Enabled {
+		checks = append(checks, dialCheck("syslog", cfg.Syslog.Network, cfg.Syslog.Address))
+	}
+
+	if cfg.Snowflake.Enabled {
+		sf := cfg.Snowflake
+		checks = append(checks, connCheck{
+			name:   "snowflake",
+			target: fmt.Sprintf("%s/%s.%s.%s", sf.Account, sf.Database, sf.Schema, sf.Table),
+			run: func(ctx context.Context) (string, error) {
+				w, err := writer.NewSnowflakeWriter(snowflakeOptions(sf), logger)
+				if err != nil {
+					return "", err
+				}
+				if err := w.Ping(ctx); err != nil {
+					return "", err
+				}
+				if sf.Method == writer.SnowflakeStreaming {
+					return "streaming pipe reachable as " + sf.User, nil
+				}
+				return "table readable as " + sf.User, nil
+			},
+		})
+	}
+
+	if cfg.Postgres.Enabled {
+		pg := cfg.Postgres
+		checks = append(checks, connCheck{
+			name:   "postgres",
+			target: fmt.Sprintf("%s/%s.%s", pg.Host, pg.Database, pg.Table),
+			run: func(ctx context.Context) (string, error) {
+				version, err := writer.CheckPostgres(ctx, postgresOptions(pg))
+				if err != nil {
+					return "", err
+				}
+				return "server " + version + " as " + pg.User, nil
+			},
+		})
+	}
+
+	if cfg.ClickHouse.Enabled {
+		ch := cfg.ClickHouse
+		checks = append(checks, connCheck{
+			name:   "clickhouse",
+			target: fmt.Sprintf("%s/%s.%s", ch.Address, firstNonEmpty(ch.Database, "default"), ch.Table),
+			run: func(ctx context.Context) (string, error) {
+				version, err := writer.CheckClickHouse(ctx, clickHouseOptions(ch))
+				if err != nil {
+					return "", err
+				}
+				return "server " + version, nil
+			},
+		})
+	}
+
+	if cfg.Catalog.Enabled && cfg.Output.Parquet.Enabled {
+		checks = append(checks, connCheck{
+			name:   "catalog",
+			target: cfg.Catalog.Type + ":" + cfg.Catalog.Database + "." + cfg.Catalog.Table,
+			run: func(ctx context.Context) (string, error) {
+				registrar, err := newRegistrar(cfg.Catalog, logger)
+				if err != nil {
+					return "", err
+				}
+				return "table found", registrar.Check(ctx)
+			},
+		})
+	}
+
+	if cfg.GRPC.Enabled {
+		checks = append(checks, connCheck{
+			name:   "grpc",
+			target: cfg.GRPC.Address,
+			run: func(ctx context.Context) (string, error) {
+				lis, err := net.Listen("tcp", cfg.GRPC.Address)
+				if err != nil {
+					return "", err
+				}
+				lis.Close()
+				return "address available", nil
+			},
+		})
+	}
+
+	return checks
+}
+
+// dialCheck opens and closes a connection. UDP has no handshake, so only
+// address resolution is verified.
+func dialCheck(name, network, address string) connCheck {
+	return connCheck{
+		name:   name,
+		target: network + "://" + address,
+		run: func(ctx context.Context) (string, error) {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, network, address)
+			if err != nil {
+				return "", err
+			}
+			conn.Close()
+			if network == "udp" {
+				return "resolved (udp is not verifiable)", nil
+			}
+			return "connected", nil
+		},
+	}
+}
+
+// outputDirectories returns the directories the enabled file formats write to
+func outputDirectories(cfg *config.Config) []string {
+	var dirs []string
+	add := func(destinations []config.DestinationConfig) {
+		if len(destinations) == 0 {
+			destinations = []config.DestinationConfig{{Directory: cfg.Output.Directory}}
+		}
+		for _, d := range destinations {
+			if !slices.Contains(dirs, d.Directory) {
+				dirs = append(dirs, d.Directory)
+			}
+		}
+	}
+
+	format := cfg.Output.Format
+	if cfg.Output.CSV.Enabled && (format == "csv" || format == "both") {
+		add(cfg.Output.CSV.Destinations)
+	}
+	if cfg.Output.Parquet.Enabled && (format == "parquet" || format == "both") {
+		add(cfg.Output.Parquet.Destinations)
+	}
+	if cfg.Output.JSONL.Enabled {
+		add(cfg.Output.JSONL.Destinations)
+	}
+	if cfg.Output.Msgpack.Enabled {
+		add(cfg.Output.Msgpack.Destinations)
+	}
+	if cfg.Output.Render.Enabled {
+		add(cfg.Output.Render.Destinations)
+	}
+	if cfg.Output.Arrow.Enabled {
+		add(cfg.Output.Arrow.Destinations)
+	}
+	if cfg.Output.FixedWidth.Enabled {
+		add(cfg.Output.FixedWidth.Destinations)
+	}
+	if cfg.Output.XML.Enabled {
+		add(cfg.Output.XML.Destinations)
+	}
+	if cfg.Output.XLSX.Enabled {
+		add(nil)
+	}
+	if cfg.Output.DuckDB.Enabled {
+		add(nil)
+	}
+	if cfg.Output.SQLite.Enabled {
+		add(nil)
+	}
+	return dirs
+}
+
+// checkWritable creates dir if needed and writes and removes a probe file
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, ".producer-check-*")
+	if err != nil {
+		return fmt.Errorf("output directory is not writable: %w", err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+func secretsTarget(vault, aws int) string {
+	var backends []string
+	if vault > 0 {
+		backends = append(backends, "vault")
+	}
+	if aws > 0 {
+		backends = append(backends, "aws-sm")
+	}
+	return strings.Join(backends, ",")
+}
+
+// printCheckResults prints the pass/fail table and returns the exit code
+func printCheckResults(results []checkResult) int {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tTARGET\tRESULT\tTIME\tDETAIL")
+
+	failed := 0
+	for _, r := range results {
+		status, detail := "PASS", r.detail
+		if r.err != nil {
+			status, detail = "FAIL", r.err.Error()
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.name, r.target, status, r.elapsed.Round(time.Millisecond), detail)
+	}
+	tw.Flush()
+
+	fmt.Printf("\n%d passed, %d failed\n", len(results)-failed, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/generator"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// startCommissions attributes transactions to sub-agents and publishes
+// commission settlements to the commissions topic every period. The returned
+// closer settles the final partial period and flushes; call it after
+// generation has finished.
+func startCommissions(ctx context.Context, cfg config.KafkaConfig, producer *generator.Producer, logger *slog.Logger) (func() error, error) {
+	settings := cfg.Commissions
+	topic := firstNonEmpty(settings.Topic, cfg.Topic+"-commissions")
+	interval := time.Duration(settings.Interval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	subAgents := settings.SubAgents
+	if subAgents == 0 {
+		subAgents = 3
+	}
+	rates := generator.CommissionRates(settings.Rates)
+	if rates == (generator.CommissionRates{}) {
+		rates = generator.CommissionRates{MasterAgent: 0.05, Agent: 0.10, SubAgent: 0.20}
+	}
+
+	eventWriter, err := writer.NewKafkaRecordWriter(cfg.Brokers, topic, kafkaAuth(cfg), logger)
+	if err != nil {
+		return nil, err
+	}
+	commissions := generator.NewCommissions(subAgents, rates, time.Now(), func(e generator.CommissionEvent) {
+		eventWriter.Publish(fmt.Sprintf("%s-%d", e.Level, e.ID), e)
+	})
+	producer.SetCommissions(commissions)
+
+	runCtx, stop := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		commissions.Run(runCtx, interval)
+	}()
+	logger.Info("Commission events enabled",
+		"topic", topic,
+		"interval", interval,
+		"sub_agents", subAgents,
+		"master_agent_rate", rates.MasterAgent,
+		"agent_rate", rates.Agent,
+		"sub_agent_rate", rates.SubAgent,
+	)
+
+	return func() error {
+		stop()
+		<-stopped
+		commissions.Flush(time.Now())
+		err := eventWriter.Close()
+		logger.Info("Commission events published", "events", eventWriter.Count(), "errors", eventWriter.Errors())
+		return err
+	}, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/generator"
+)
+
+// maxLoggedViolations caps the violations logged one by one; the rest are
+// only counted
+const maxLoggedViolations = 10
+
+// startConsistencyCheck has producer verify a sample of its transactions.
+// With fatal the first violation fails the run and stops it through stop.
+// The returned report logs the totals once generation has finished and
+// returns an error when a fatal check found violations.
+func startConsistencyCheck(cfg config.ConsistencyCheckConfig, producer *generator.Producer, failure *runFailure, stop func(), logger *slog.Logger) func() error {
+	var logged atomic.Int64
+	producer.SetConsistencyCheck(cfg.SampleRate, func(v generator.Violation) {
+		if logged.Add(1) <= maxLoggedViolations {
+			logger.Error("Consistency violation", "rule", v.Rule, "transaction_id", v.TransactionID, "detail", v.Detail)
+		}
+		if cfg.Fatal {
+			failure.set(fmt.Errorf("consistency: %s: %s", v.Rule, v.Detail))
+			stop()
+		}
+	})
+	logger.Info("Consistency check enabled", "sample_rate", cfg.SampleRate, "fatal", cfg.Fatal)
+
+	return func() error {
+		checked, violations := producer.Consistency()
+		total := int64(0)
+		args := []any{"checked", checked}
+		for rule, n := range violations {
+			total += n
+			args = append(args, rule, n)
+		}
+		if total == 0 {
+			logger.Info("Consistency check passed", args...)
+			return nil
+		}
+		logger.Warn("Consistency check found violations", args...)
+		if cfg.Fatal {
+			return fmt.Errorf("%d consistency violations in %d checked transactions", total, checked)
+		}
+		return nil
+	}
+}
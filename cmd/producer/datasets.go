@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/generator"
+)
+
+// valueAttribute is the attribute of records built from inline values and
+// weights, and the default attribute of fields
+const valueAttribute = "value"
+
+// setupDatasets registers the configured custom datasets with producer and
+// sets the fields drawn from them, from built-in generators or from earlier
+// values
+func setupDatasets(producer *generator.Producer, data config.DataConfig) error {
+	for _, name := range slices.Sorted(maps.Keys(data.Datasets)) {
+		ds, err := datasetOf(name, data.Datasets[name])
+		if err != nil {
+			return fmt.Errorf("dataset %s: %w", name, err)
+		}
+		if err := producer.RegisterDataset(ds); err != nil {
+			return err
+		}
+	}
+
+	fields := make([]generator.Field, len(data.Fields))
+	for i, f := range data.Fields {
+		fields[i] = generator.Field{
+			Name:       f.Name,
+			Generator:  f.Generator,
+			Length:     f.Length,
+			Start:      f.Start,
+			References: f.References,
+			Window:     time.Duration(f.Window) * time.Second,
+			Pool:       f.Pool,
+		}
+		if f.Generator == "" && f.References == "" {
+			fields[i].Dataset, fields[i].Attribute = f.Dataset, firstNonEmpty(f.Attribute, valueAttribute)
+		}
+	}
+	return producer.SetFields(fields)
+}
+
+// datasetOf builds a dataset from its file or inline definition
+func datasetOf(name string, cfg config.DatasetConfig) (generator.Dataset, error) {
+	ds := generator.Dataset{Name: name, Strategy: cfg.Strategy, WeightAttribute: cfg.WeightAttribute}
+	switch {
+	case cfg.File != "":
+		records, err := generator.LoadDataset(cfg.File)
+		if err != nil {
+			return ds, err
+		}
+		ds.Records = records
+	case len(cfg.Values) > 0:
+		for _, v := range cfg.Values {
+			ds.Records = append(ds.Records, map[string]any{valueAttribute: v})
+		}
+	case len(cfg.Weights) > 0:
+		// Sorted so seeded runs draw the same values
+		for _, v := range slices.Sorted(maps.Keys(cfg.Weights)) {
+			ds.Records = append(ds.Records, map[string]any{valueAttribute: v, "weight": cfg.Weights[v]})
+		}
+		ds.Strategy = firstNonEmpty(cfg.Strategy, generator.SelectWeighted)
+		ds.WeightAttribute = ""
+	default:
+		ds.Records = cfg.Records
+	}
+	return ds, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/supratick/message_producer/internal/encrypt"
+)
+
+// runDecrypt implements `producer decrypt (-identity <file> | -key <key>) [-o <out>] <file>`
+func runDecrypt(args []string) int {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	identity := fs.String("identity", "", "age identity file (AGE-SECRET-KEY-1...) for .age files")
+	key := fs.String("key", "", "AES-256-GCM key, hex or base64, for .enc files")
+	keyFile := fs.String("key-file", "", "File holding the AES-256-GCM key")
+	output := fs.String("o", "", "Output path (default: input without its extension, - for stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: producer decrypt (-identity <file> | -key <key> | -key-file <file>) [-o <out>] <file>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	path := fs.Arg(0)
+
+	in, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer in.Close()
+
+	outPath := *output
+	if outPath == "" {
+		outPath = strings.TrimSuffix(strings.TrimSuffix(path, ".age"), ".enc")
+		if outPath == path {
+			fmt.Fprintln(os.Stderr, "cannot derive output name; use -o")
+			return 2
+		}
+	}
+	var out io.WriteCloser = os.Stdout
+	if outPath != "-" {
+		if out, err = os.Create(outPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	switch {
+	case *identity != "":
+		err = encrypt.DecryptAge(out, in, *identity)
+	case *key != "" || *keyFile != "":
+		k := *key
+		if k == "" {
+			data, readErr := os.ReadFile(*keyFile)
+			if readErr != nil {
+				fmt.Fprintln(os.Stderr, readErr)
+				return 1
+			}
+			k = string(data)
+		}
+		var parsed []byte
+		if parsed, err = encrypt.ParseKey(k); err == nil {
+			err = encrypt.DecryptGCM(out, in, parsed)
+		}
+	default:
+		fs.Usage()
+		return 2
+	}
+	if outPath != "-" {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if outPath != "-" {
+			os.Remove(outPath)
+		}
+		return 1
+	}
+	return 0
+}
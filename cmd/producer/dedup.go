@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/pipeline"
+)
+
+// defaultDedupWindow is the number of recent IDs remembered per file writer
+// when output.dedup.window is unset
+const defaultDedupWindow = 1000000
+
+// fileDedup puts a duplicate filter ahead of each file writer when
+// output.dedup is enabled and checks afterwards that every writer wrote
+// exactly the distinct transactions it was given
+type fileDedup struct {
+	window int // 0 = dedup disabled
+	mu     sync.Mutex
+	stages []dedupStage // grows at runtime as failover opens standbys
+	logger *slog.Logger
+}
+
+type dedupStage struct {
+	name  string
+	dedup *pipeline.Dedup
+	count func() int64
+}
+
+func newFileDedup(cfg config.DedupConfig, logger *slog.Logger) *fileDedup {
+	f := &fileDedup{logger: logger}
+	if cfg.Enabled {
+		f.window = cfg.Window
+		if f.window == 0 {
+			f.window = defaultDedupWindow
+		}
+		logger.Info("File output deduplication enabled", "window", f.window)
+	}
+	return f
+}
+
+// wrap returns w behind a duplicate filter, or w itself when dedup is off.
+// count reports what the writer has written.
+func (f *fileDedup) wrap(name string, w pipeline.Writer, count func() int64) pipeline.Writer {
+	if f.window == 0 {
+		return w
+	}
+	d := pipeline.NewDedup(w, f.window)
+	f.mu.Lock()
+	f.stages = append(f.stages, dedupStage{name: name, dedup: d, count: count})
+	f.mu.Unlock()
+	return d
+}
+
+// report logs the duplicates each writer was spared. With exact it also
+// fails when a writer's count differs from the distinct transactions it was
+// given; interrupted runs pass false, as their writers stop with records
+// still queued.
+func (f *fileDedup) report(exact bool) error {
+	f.mu.Lock()
+	stages := f.stages
+	f.mu.Unlock()
+	var first error
+	for _, s := range stages {
+		passed, written := s.dedup.Passed(), s.count()
+		f.logger.Info("Deduplicated file output",
+			"writer", s.name,
+			"distinct", passed,
+			"duplicates_dropped", s.dedup.Dropped(),
+			"written", written,
+		)
+		if exact && written != passed && first == nil {
+			first = fmt.Errorf("%s writer wrote %d of %d distinct transactions", s.name, written, passed)
+		}
+	}
+	return first
+}
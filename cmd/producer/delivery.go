@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/supratick/message_producer/internal/awsauth"
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/delivery"
+	"github.com/supratick/message_producer/internal/metrics"
+)
+
+// deliveryDialer builds the connection factory for output.delivery
+func deliveryDialer(cfg config.DeliveryConfig, logger *slog.Logger) (delivery.Dialer, error) {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	if cfg.Protocol == "s3" {
+		return delivery.NewS3Dialer(s3Delivery(cfg, timeout), logger)
+	}
+	port := cfg.Port
+	switch {
+	case port != 0:
+	case cfg.Protocol == "sftp":
+		port = 22
+	case cfg.Implicit:
+		port = 990
+	default:
+		port = 21
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+
+	if cfg.Protocol == "sftp" {
+		return delivery.NewSFTPDialer(delivery.SFTPConfig{
+			Addr:                  addr,
+			User:                  cfg.Username,
+			Password:              cfg.Password,
+			PrivateKeyFile:        cfg.PrivateKeyFile,
+			KnownHosts:            cfg.KnownHosts,
+			HostKey:               cfg.HostKey,
+			InsecureIgnoreHostKey: cfg.InsecureSkipVerify,
+			Timeout:               timeout,
+		})
+	}
+	return delivery.NewFTPSDialer(delivery.FTPSConfig{
+		Addr:               addr,
+		User:               cfg.Username,
+		Password:           cfg.Password,
+		Implicit:           cfg.Implicit,
+		CAFile:             cfg.CAFile,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		Timeout:            timeout,
+	})
+}
+
+// s3Delivery maps output.delivery to the S3 client settings, filling in the
+// defaults
+func s3Delivery(cfg config.DeliveryConfig, timeout time.Duration) delivery.S3Config {
+	creds := awsauth.FromEnv()
+	if cfg.Username != "" {
+		creds = awsauth.Credentials{AccessKeyID: cfg.Username, SecretAccessKey: cfg.Password}
+	}
+	s3 := delivery.S3Config{
+		Endpoint:        cfg.Endpoint,
+		Region:          firstNonEmpty(cfg.Region, "us-east-1"),
+		Bucket:          cfg.Bucket,
+		Credentials:     creds,
+		PartSize:        int64(cfg.PartSize),
+		Concurrency:     cfg.Concurrency,
+		RateLimit:       int64(cfg.RateLimit),
+		ThrottleRetries: cfg.ThrottleRetries,
+		ThrottleBackoff: time.Duration(cfg.ThrottleBackoffMs) * time.Millisecond,
+		Timeout:         timeout,
+	}
+	if s3.PartSize == 0 {
+		s3.PartSize = 16 << 20
+	}
+	if s3.Concurrency == 0 {
+		s3.Concurrency = 4
+	}
+	switch {
+	case s3.ThrottleRetries < 0:
+		s3.ThrottleRetries = 0
+	case s3.ThrottleRetries == 0:
+		s3.ThrottleRetries = 8
+	}
+	if s3.ThrottleBackoff == 0 {
+		s3.ThrottleBackoff = 200 * time.Millisecond
+	}
+	return s3
+}
+
+// deliveryServer names where files are delivered, for logs and receipts
+func deliveryServer(d config.DeliveryConfig) string {
+	server := d.Protocol + "://" + d.Host
+	if d.Protocol == "s3" {
+		server = "s3://" + d.Bucket
+	}
+	if d.Directory != "" {
+		server += "/" + strings.TrimPrefix(d.Directory, "/")
+	}
+	return server
+}
+
+// newFileDelivery starts delivering finished files to output.delivery,
+// recording each upload with monitor. It returns the function closing the
+// deliverer, to be called after the writers, and the function that queues a
+// file; both are nil when delivery is off. Files keep their path below the output directory or destination
+// that holds them, so {{date}}/transactions.csv arrives as
+// <directory>/2024-01-01/transactions.csv.
+func newFileDelivery(cfg config.OutputConfig, runID string, monitor *metrics.Monitor, logger *slog.Logger) (func() error, func(path string, rows int64), error) {
+	d := cfg.Delivery
+	if d.Protocol == "" {
+		return nil, nil, nil
+	}
+	dial, err := deliveryDialer(d, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	if d.InsecureSkipVerify {
+		logger.Warn("Delivery server identity is not verified", "protocol", d.Protocol, "host", d.Host)
+	}
+
+	backoff := time.Duration(d.RetryBackoff) * time.Second
+	if backoff <= 0 {
+		backoff = 2 * time.Second
+	}
+	notifier := deliveryNotifier(d, runID, logger)
+	tempSuffix := firstNonEmpty(d.TempSuffix, ".part")
+	if d.Protocol == "s3" {
+		// An object only appears once its upload completes
+		tempSuffix = ""
+	}
+	opts := delivery.Options{
+		Directory:    d.Directory,
+		TempSuffix:   tempSuffix,
+		Retries:      d.Retries,
+		RetryBackoff: backoff,
+		RemoveLocal:  d.RemoveLocal,
+		Queue:        64,
+		OnDelivered: func(r delivery.Receipt) {
+			monitor.RecordUpload(r.Bytes, r.Took)
+			if notifier != nil {
+				notifier.Delivered(r)
+			}
+		},
+	}
+	deliverer := delivery.New(dial, opts, logger)
+
+	roots := outputDirs(cfg)
+	enqueue := func(path string, rows int64) {
+		deliverer.Enqueue(path, remoteName(roots, path), rows)
+	}
+
+	logger.Info("File delivery enabled",
+		"protocol", d.Protocol,
+		"server", deliveryServer(d),
+		"retries", d.Retries,
+		"notify", len(d.Email.To),
+	)
+	closeDelivery := func() error {
+		err := deliverer.Close()
+		if notifier != nil {
+			// After the last file, so a digest covers the whole run
+			if notifyErr := notifier.Close(); err == nil {
+				err = notifyErr
+			}
+		}
+		return err
+	}
+	return closeDelivery, enqueue, nil
+}
+
+// deliveryNotifier returns the notifier emailing delivery receipts, or nil
+// when output.delivery.email is off
+func deliveryNotifier(d config.DeliveryConfig, runID string, logger *slog.Logger) *delivery.Notifier {
+	e := d.Email
+	if e.Host == "" {
+		return nil
+	}
+	port := e.Port
+	if port == 0 {
+		port = 587
+		if e.ImplicitTLS {
+			port = 465
+		}
+	}
+	server := deliveryServer(d)
+	return delivery.NewNotifier(delivery.MailConfig{
+		Addr:        net.JoinHostPort(e.Host, strconv.Itoa(port)),
+		Username:    e.Username,
+		Password:    e.Password,
+		From:        e.From,
+		To:          e.To,
+		ImplicitTLS: e.ImplicitTLS,
+		Timeout:     time.Duration(e.Timeout) * time.Second,
+	}, delivery.NotifyOptions{
+		SubjectPrefix: e.SubjectPrefix,
+		Server:        server,
+		RunID:         runID,
+		Digest:        e.Digest,
+	}, logger)
+}
+
+// remoteName returns path relative to the first root holding it, slash
+// separated, or its base name when no root does
+func remoteName(roots []string, path string) string {
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(path)
+}
+
+// checkDelivery connects and logs in to the delivery server, or checks the
+// bucket is reachable with the access key
+func checkDelivery(cfg config.DeliveryConfig) connCheck {
+	target := fmt.Sprintf("%s://%s@%s/%s", cfg.Protocol, cfg.Username, cfg.Host, strings.TrimPrefix(cfg.Directory, "/"))
+	if cfg.Protocol == "s3" {
+		target = deliveryServer(cfg)
+	}
+	return connCheck{
+		name:   "delivery",
+		target: target,
+		run: func(ctx context.Context) (string, error) {
+			dial, err := deliveryDialer(cfg, nil)
+			if err != nil {
+				return "", err
+			}
+			client, err := dial(ctx)
+			if err != nil {
+				return "", err
+			}
+			client.Close()
+			if cfg.Protocol == "s3" {
+				return "bucket reachable", nil
+			}
+			return "logged in", nil
+		},
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/supratick/message_producer/internal/inspect"
+)
+
+// runDiff implements `producer diff [-json] [-tolerance f] [-ids] <a> <b>`
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	tolerance := fs.Float64("tolerance", 0.01, "Relative difference allowed between counts and numeric aggregates")
+	sameIDs := fs.Bool("ids", false, "Require both outputs to hold the same transaction IDs (runs with a fixed seed)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: producer diff [-json] [-tolerance f] [-ids] <a> <b>")
+		fmt.Fprintln(fs.Output(), "Compares two CSV or Parquet outputs, each a file or a directory of files.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	report, err := inspect.Diff(fs.Arg(0), fs.Arg(1), inspect.DiffOptions{Tolerance: *tolerance, SameIDs: *sameIDs})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else {
+		printDiffReport(report)
+	}
+
+	if len(report.Differences) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printDiffReport(r *inspect.DiffReport) {
+	fmt.Printf("A: %s (%d files, %d rows)\n", r.A.Path, len(r.A.Files), r.A.Rows)
+	fmt.Printf("B: %s (%d files, %d rows)\n\n", r.B.Path, len(r.B.Files), r.B.Rows)
+
+	fmt.Printf("  %-24s %-34s %s\n", "column", "a", "b")
+	for _, col := range r.Columns {
+		fmt.Printf("  %-24s %-34s %s\n", col.Name, columnLine(col.A), columnLine(col.B))
+	}
+
+	fmt.Printf("\nIDs only in A: %d", r.OnlyInA)
+	if len(r.SampleA) > 0 {
+		fmt.Printf(" (e.g. %s)", r.SampleA[0])
+	}
+	fmt.Printf("\nIDs only in B: %d", r.OnlyInB)
+	if len(r.SampleB) > 0 {
+		fmt.Printf(" (e.g. %s)", r.SampleB[0])
+	}
+	fmt.Println()
+
+	if len(r.Differences) > 0 {
+		fmt.Println("\nDifferences:")
+		for _, d := range r.Differences {
+			fmt.Printf("  %s\n", d)
+		}
+	} else {
+		fmt.Println("\nNo differences beyond tolerance")
+	}
+}
+
+// columnLine formats one side of a column comparison
+func columnLine(c *inspect.ColumnSummary) string {
+	if c == nil {
+		return "-"
+	}
+	distinct := fmt.Sprint(c.Distinct)
+	if c.DistinctCapped {
+		distinct = ">" + distinct
+	}
+	if c.Numeric {
+		return fmt.Sprintf("n=%d distinct=%s mean=%.4g", c.Count, distinct, *c.Mean)
+	}
+	return fmt.Sprintf("n=%d distinct=%s", c.Count, distinct)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/events"
+	"github.com/supratick/message_producer/internal/metrics"
+)
+
+// newEmitter creates the run's event emitter with a publisher for each
+// configured destination and notifier, or nil when both are disabled
+func newEmitter(cfg *config.Config, runID string, monitor *metrics.Monitor, logger *slog.Logger) (*events.Emitter, error) {
+	if !cfg.Events.Enabled && !cfg.Notifications.Enabled {
+		return nil, nil
+	}
+
+	var publishers []events.Publisher
+	if n := cfg.Notifications; n.Enabled {
+		triggers := n.Events
+		if len(triggers) == 0 {
+			triggers = []string{events.NotifyFinish}
+		}
+		format := n.Format
+		if format == "" {
+			format = events.FormatSlack
+		}
+		timeout := time.Duration(n.Timeout) * time.Millisecond
+		if timeout <= 0 {
+			timeout = 5 * time.Second
+		}
+		publishers = append(publishers, events.NewNotifier(n.WebhookURL, format, triggers, n.ErrorThreshold, timeout))
+		logger.Info("Notifications enabled", "events", triggers, "format", format)
+	}
+	if !cfg.Events.Enabled {
+		return newRunEmitter(cfg, runID, monitor, publishers, logger), nil
+	}
+
+	if k := cfg.Events.Kafka; k.Topic != "" {
+		brokers := k.Brokers
+		if len(brokers) == 0 {
+			brokers = cfg.Kafka.Brokers
+		}
+		p, err := events.NewKafkaPublisher(brokers, k.Topic, kafkaAuth(cfg.Kafka), 10*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, p)
+	}
+	if w := cfg.Events.Webhook; w.URL != "" {
+		timeout := time.Duration(w.Timeout) * time.Millisecond
+		if timeout <= 0 {
+			timeout = 5 * time.Second
+		}
+		publishers = append(publishers, events.NewWebhookPublisher(w.URL, w.Headers, timeout))
+	}
+
+	logger.Info("Run events enabled",
+		"kafka_topic", cfg.Events.Kafka.Topic,
+		"webhook", cfg.Events.Webhook.URL != "",
+		"interval", eventInterval(cfg.Events).String(),
+	)
+	return newRunEmitter(cfg, runID, monitor, publishers, logger), nil
+}
+
+func newRunEmitter(cfg *config.Config, runID string, monitor *metrics.Monitor, publishers []events.Publisher, logger *slog.Logger) *events.Emitter {
+	return events.NewEmitter(runID, cfg.Scenario.Name, int64(cfg.Producer.MessageCount), monitor.LiveCounts, monitor.LiveErrors, publishers, logger)
+}
+
+func eventInterval(cfg config.EventsConfig) time.Duration {
+	if cfg.Interval <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(cfg.Interval) * time.Second
+}
+
+// exitSLOFailed is the exit status of a run that completed but missed its SLO
+const exitSLOFailed = 3
+
+// sloObjective converts the metrics slo block, windowing on the metrics
+// interval unless a window is set
+func sloObjective(cfg config.MetricsConfig) metrics.SLO {
+	window := cfg.SLO.Window
+	if window <= 0 {
+		window = cfg.Interval
+	}
+	return metrics.SLO{
+		MinRate:      cfg.SLO.MinRate,
+		MaxErrorRate: cfg.SLO.MaxErrorRate,
+		Window:       time.Duration(window) * time.Second,
+		Warmup:       time.Duration(cfg.SLO.Warmup) * time.Second,
+	}
+}
+
+// runFailure records the first error that fails the run
+type runFailure struct {
+	mu    sync.Mutex
+	first error
+}
+
+func (f *runFailure) set(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.first == nil {
+		f.first = err
+	}
+}
+
+func (f *runFailure) err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.first
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/supratick/message_producer/internal/inspect"
+)
+
+// runInspect implements `producer inspect [-json] <file.parquet>`
+func runInspect(args []string) int {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: producer inspect [-json] <file.parquet>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	report, err := inspect.InspectParquet(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else {
+		printParquetReport(report)
+	}
+
+	if len(report.Problems) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printParquetReport(r *inspect.ParquetReport) {
+	fmt.Printf("File:       %s (%d bytes)\n", r.Path, r.Size)
+	fmt.Printf("Rows:       %d\n", r.NumRows)
+	fmt.Printf("Created by: %s\n", r.CreatedBy)
+	fmt.Printf("Row groups: %d\n\n", len(r.RowGroups))
+	fmt.Println(r.Schema)
+
+	for _, rg := range r.RowGroups {
+		fmt.Printf("\nRow group %d: %d rows, %d bytes (%d compressed)\n",
+			rg.Index, rg.NumRows, rg.TotalByteSize, rg.CompressedSize)
+		if len(rg.SortingColumns) > 0 {
+			fmt.Printf("  sorted by: %s\n", strings.Join(rg.SortingColumns, ", "))
+		}
+		for _, col := range rg.Columns {
+			fmt.Printf("  %-24s %-10s %-12s %-28s %10d/%-10d nulls=%d min=%s max=%s",
+				col.Path, col.Type, col.Codec, strings.Join(col.Encodings, ","),
+				col.CompressedSize, col.UncompressedSize, col.NullCount, col.Min, col.Max)
+			if col.BloomFilter {
+				fmt.Print(" bloom")
+			}
+			fmt.Println()
+		}
+	}
+
+	if len(r.Problems) > 0 {
+		fmt.Println("\nProblems:")
+		for _, p := range r.Problems {
+			fmt.Printf("  %s\n", p)
+		}
+	} else {
+		fmt.Println("\nStatistics OK: every column chunk has min/max with min <= max")
+	}
+}
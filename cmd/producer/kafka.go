@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/supratick/message_producer/internal/codec"
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/encrypt"
+	"github.com/supratick/message_producer/internal/generator"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// openKafkaSink creates the Kafka writer with its region routes and starts
+// the side streams (rates, commissions, metadata) that ride along with it
+func openKafkaSink(env *sinkEnv) (*openedSink, error) {
+	cfg, producer, monitor, logger := env.cfg, env.producer, env.monitor, env.logger
+
+	var envelope *encrypt.Envelope
+	if cfg.Kafka.Encryption.Enabled {
+		key, err := encrypt.ParseKey(cfg.Kafka.Encryption.Key)
+		if err == nil {
+			envelope, err = encrypt.NewEnvelope(cfg.Kafka.Encryption.KeyID, key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up Kafka encryption: %w", err)
+		}
+	}
+
+	serialization := firstNonEmpty(cfg.Kafka.Serialization, "json")
+	format, err := codec.Lookup(serialization)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka writer: %w", err)
+	}
+	if cfg.Kafka.SchemaCheck.Enabled {
+		if err := checkValueSchema(env.ctx, cfg.Kafka, logger); err != nil {
+			return nil, fmt.Errorf("incompatible Kafka value schema: %w", err)
+		}
+	}
+	if cfg.Kafka.PayloadValidation != "" {
+		format, env.reportPayloads, err = validatePayloads(format, cfg.Kafka.PayloadValidation, env.failure, env.stop, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up Kafka payload validation: %w", err)
+		}
+	}
+
+	keyEncoder, err := kafkaKey(cfg.Kafka)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up Kafka keys: %w", err)
+	}
+	traceHeader := ""
+	if cfg.Kafka.Trace.Enabled {
+		if err := producer.SetTracing(firstNonEmpty(cfg.Kafka.Trace.Scope, generator.TraceTransaction)); err != nil {
+			return nil, fmt.Errorf("failed to set up trace IDs: %w", err)
+		}
+		traceHeader = firstNonEmpty(cfg.Kafka.Trace.Header, writer.TraceHeader)
+	}
+	newKafkaWriter := func(brokers []string, topic string) (*writer.KafkaWriter, error) {
+		w, err := writer.NewKafkaWriter(
+			brokers,
+			topic,
+			cfg.Kafka.Compression,
+			cfg.Kafka.CompressionLevel,
+			cfg.Kafka.BatchSize,
+			cfg.Kafka.FlushFrequency,
+			cfg.Kafka.Async,
+			format,
+			kafkaAuth(cfg.Kafka),
+			writer.KafkaLimits{
+				MaxOpenRequests:   cfg.Kafka.MaxOpenRequests,
+				ChannelBufferSize: cfg.Kafka.ChannelBufferSize,
+				MaxInFlightBytes:  int64(cfg.Kafka.MaxInFlightBytes),
+			},
+			envelope,
+			logger,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if keyEncoder != nil {
+			w.SetKey(keyEncoder)
+		}
+		w.SetSchemaVersion(cfg.SchemaVersion.Version)
+		if traceHeader != "" {
+			w.SetTraceHeader(traceHeader)
+		}
+		return w, nil
+	}
+
+	kafkaWriter, err := newKafkaWriter(cfg.Kafka.Brokers, cfg.Kafka.Topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka writer: %w", err)
+	}
+	s := &openedSink{sink: kafkaWriter, label: "Kafka", errors: true}
+	if cfg.Kafka.Failover.Enabled {
+		if err := startKafkaFailover(cfg.Kafka, kafkaWriter, logger); err != nil {
+			return nil, fmt.Errorf("failed to set up Kafka failover: %w", err)
+		}
+	}
+
+	if watch := cfg.Kafka.ClusterWatch; watch.Enabled {
+		interval := time.Duration(watch.RefreshInterval) * time.Second
+		if interval == 0 {
+			interval = 5 * time.Second
+		}
+		kafkaWriter.WatchCluster(interval)
+	}
+
+	// Region routes get their own writers; the rest stay on kafkaWriter
+	kafkaProduced := kafkaWriter.Count
+	var routes []writer.RegionRoute
+	if regions := cfg.Kafka.Regions; len(regions.Weights) > 0 {
+		if err := producer.SetRegions(regions.Weights); err != nil {
+			return nil, fmt.Errorf("failed to set up regions: %w", err)
+		}
+		routes, err = regionRoutes(cfg.Kafka, newKafkaWriter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Kafka region writers: %w", err)
+		}
+		for _, route := range routes {
+			s.closers = append(s.closers, namedCloser{"Kafka " + route.Region, route.Writer.CloseContext})
+			monitor.Track("kafka_"+route.Region, route.Writer.Count)
+			monitor.TrackErrors(route.Writer.Errors)
+		}
+		if len(routes) > 0 {
+			s.stage = writer.NewRegionRouter(kafkaWriter, routes)
+			kafkaProduced = func() int64 {
+				total := kafkaWriter.Count()
+				for _, route := range routes {
+					total += route.Writer.Count()
+				}
+				return total
+			}
+		}
+	}
+	s.done = func() {
+		if throttled := kafkaWriter.Throttled(); throttled > 0 {
+			logger.Info("Kafka sends waited for the in-flight limit", "times", throttled, "max_in_flight_bytes", int64(cfg.Kafka.MaxInFlightBytes))
+		}
+		if cfg.Kafka.ClusterWatch.Enabled || kafkaWriter.Retries() > 0 {
+			refreshes, failed := kafkaWriter.MetadataRefreshes()
+			logger.Info("Kafka cluster events",
+				"retries", kafkaWriter.Retries(),
+				"metadata_refreshes", refreshes,
+				"failed_refreshes", failed,
+				"leader_changes", kafkaWriter.LeaderChanges(),
+			)
+		}
+		monitor.IncrementKafka(kafkaWriter.Count())
+		monitor.IncrementKafkaErrors(kafkaWriter.Errors())
+		for _, route := range routes {
+			monitor.IncrementSink("kafka_"+route.Region, route.Writer.Count())
+			monitor.IncrementKafkaErrors(route.Writer.Errors())
+		}
+	}
+
+	if cfg.Kafka.Probe.Enabled {
+		env.latencyProbe = startLatencyProbe(cfg.Kafka, kafkaWriter, time.Duration(cfg.Metrics.Interval)*time.Second, env.doneCh, logger)
+	}
+	if cfg.Kafka.PartitionReport.Enabled {
+		env.partitionReport = startPartitionReport(cfg.Kafka, kafkaWriter.Count, logger)
+	}
+	if cfg.Kafka.Rates.Enabled {
+		closeRates, err := startRateWalk(env.ctx, cfg.Kafka, cfg.Scenario.Seed, env.refData.CurrencyRates, producer, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start currency rate simulation: %w", err)
+		}
+		s.closers = append(s.closers, namedCloser{"Kafka rates", closeWithin(closeRates)})
+	}
+	if cfg.Kafka.Commissions.Enabled {
+		closeCommissions, err := startCommissions(env.ctx, cfg.Kafka, producer, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start commission events: %w", err)
+		}
+		s.closers = append(s.closers, namedCloser{"Kafka commissions", closeWithin(closeCommissions)})
+	}
+	if cfg.Kafka.Metadata.Enabled {
+		closeMetadata, err := startMetadata(env.ctx, cfg, env.fileVars.RunID, kafkaProduced, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start generator metadata: %w", err)
+		}
+		s.closers = append(s.closers, namedCloser{"Kafka metadata", closeWithin(closeMetadata)})
+	}
+
+	slog.Info("Kafka writer initialized",
+		"brokers", cfg.Kafka.Brokers,
+		"topic", cfg.Kafka.Topic,
+		"compression", cfg.Kafka.Compression,
+		"serialization", serialization,
+		"encrypted", cfg.Kafka.Encryption.Enabled,
+		"key", firstNonEmpty(cfg.Kafka.Key.Format, writer.KeyString),
+		"schema_version", cfg.SchemaVersion.Version,
+		"trace", cfg.Kafka.Trace.Enabled,
+		"regions", len(cfg.Kafka.Regions.Weights),
+		"region_routes", len(cfg.Kafka.Regions.Routes),
+		"failover", cfg.Kafka.Failover.Enabled,
+		"max_in_flight_bytes", int64(cfg.Kafka.MaxInFlightBytes),
+		"cluster_watch", cfg.Kafka.ClusterWatch.Enabled,
+	)
+	return s, nil
+}
+
+// startKafkaFailover spools what the brokers do not take and forwards it,
+// with any backlog an earlier run left, once a probe finds them back
+func startKafkaFailover(cfg config.KafkaConfig, kafkaWriter *writer.KafkaWriter, logger *slog.Logger) error {
+	spool, err := writer.OpenSpool(firstNonEmpty(cfg.Failover.SpoolDir, "kafka-spool"), int64(cfg.Failover.SpoolLimit), logger)
+	if err != nil {
+		return err
+	}
+	interval := time.Duration(cfg.Failover.RetryInterval) * time.Second
+	if interval == 0 {
+		interval = 10 * time.Second
+	}
+	if backlog := spool.Backlog(); backlog > 0 {
+		logger.Info("Forwarding Kafka spool backlog from an earlier run", "backlog_bytes", backlog)
+	}
+	kafkaWriter.SetFailover(writer.KafkaFailover{
+		Spool: spool,
+		Probe: func() error {
+			_, err := writer.CheckKafka(cfg.Brokers, cfg.Topic, kafkaAuth(cfg), interval)
+			return err
+		},
+		RetryInterval: interval,
+	})
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/probe"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// startLatencyProbe stamps every Nth Kafka message and consumes the topic
+// back, logging latency percentiles each interval until done is closed. The
+// returned function waits for outstanding probes and logs the run summary;
+// call it after the Kafka writer has been closed.
+func startLatencyProbe(cfg config.KafkaConfig, w *writer.KafkaWriter, interval time.Duration, done <-chan struct{}, logger *slog.Logger) func() {
+	every := cfg.Probe.Every
+	if every <= 0 {
+		every = 100
+	}
+	grace := time.Duration(cfg.Probe.Grace) * time.Second
+	if grace <= 0 {
+		grace = 10 * time.Second
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	consumer, err := probe.NewConsumer(cfg.Brokers, cfg.Topic, kafkaAuth(cfg), 10*time.Second)
+	if err != nil {
+		slog.Error("Failed to start latency probe consumer", "error", err)
+		os.Exit(1)
+	}
+	w.SetProbe(every)
+	logger.Info("Latency probes enabled", "every", every, "topic", cfg.Topic)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				consumer.Report(logger)
+			}
+		}
+	}()
+
+	return func() {
+		sent := w.ProbesSent()
+		received := consumer.Wait(sent, grace)
+		attrs := append(consumer.Summary().Attrs(), "sent", sent, "lost", max(sent-received, 0))
+		logger.Info("Latency summary", attrs...)
+		if err := consumer.Close(); err != nil {
+			logger.Warn("Failed to close latency probe consumer", "error", err)
+		}
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
-	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -15,15 +14,60 @@ import (
 	"github.com/supratick/message_producer/internal/generator"
 	"github.com/supratick/message_producer/internal/metrics"
 	"github.com/supratick/message_producer/internal/models"
+	"github.com/supratick/message_producer/internal/pipeline"
+	"github.com/supratick/message_producer/internal/provenance"
+	"github.com/supratick/message_producer/internal/ring"
+	"github.com/supratick/message_producer/internal/server"
 	"github.com/supratick/message_producer/internal/writer"
 )
 
 func main() {
+	// Subcommands run instead of the producer
+	if len(os.Args) > 1 && os.Args[1] == "inspect" {
+		os.Exit(runInspect(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(runDiff(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		os.Exit(runVerify(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "decrypt" {
+		os.Exit(runDecrypt(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		os.Exit(runCheck(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "schedule" {
+		os.Exit(runSchedule(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		os.Exit(runSchema(os.Args[2:]))
+	}
+
+	// `producer archive` is a run with a Kafka source and file sinks only
+	var archive *archiveFlags
+	if len(os.Args) > 1 && os.Args[1] == "archive" {
+		archive = registerArchiveFlags(flag.CommandLine)
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	// Parse command-line flags
 	configPath := flag.String("config", "config.yaml", "Path to configuration file")
 	logLevel := flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+	scenarioName := flag.String("scenario", "", "Bundled scenario to run over the config (\"list\" shows them)")
+	params := paramFlags{}
+	flag.Var(params, "param", "Config template parameter name=value (repeatable)")
+	pprofAddr := flag.String("pprof", "", "Serve net/http/pprof on this address (e.g. localhost:6060)")
+	profileDir := flag.String("profile", "", "Write CPU and heap profiles of the run to this directory")
+	replaySpeed := flag.String("speed", "", "Replay source records at this multiple of their original pace (e.g. 10x, 0.5x)")
 	flag.Parse()
 
+	if *scenarioName == "list" {
+		printScenarios()
+		return
+	}
+
 	// Initialize structured logging
 	var level slog.Level
 	switch *logLevel {
@@ -39,13 +83,18 @@ func main() {
 		level = slog.LevelInfo
 	}
 
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	logs := newLogCapture(os.Stdout)
+	logger := slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{
 		Level: level,
 	}))
 	slog.SetDefault(logger)
 
 	slog.Info("Starting message producer", "version", "1.0.0")
 
+	if *pprofAddr != "" {
+		servePprof(*pprofAddr, logger)
+	}
+
 	// Check if config file exists
 	var cfg *config.Config
 	var err error
@@ -73,6 +122,47 @@ func main() {
 					RowGroupSize: 50000,
 					Compression:  "snappy",
 				},
+				JSONL: config.JSONLConfig{
+					Enabled:    false,
+					Filename:   "transactions.jsonl",
+					BufferSize: 10000,
+				},
+				Msgpack: config.MsgpackConfig{
+					Enabled:    false,
+					Filename:   "transactions.msgpack",
+					BufferSize: 10000,
+				},
+				Arrow: config.ArrowConfig{
+					Enabled:  false,
+					Filename: "transactions.arrow",
+				},
+				FixedWidth: config.FixedWidthConfig{
+					Enabled:    false,
+					Filename:   "transactions.fw",
+					BufferSize: 10000,
+				},
+				XML: config.XMLConfig{
+					Enabled:    false,
+					Filename:   "transactions.xml",
+					BufferSize: 10000,
+				},
+				XLSX: config.XLSXConfig{
+					Enabled:  false,
+					Filename: "transactions-sample.xlsx",
+					MaxRows:  1000,
+				},
+				DuckDB: config.DuckDBConfig{
+					Enabled:   false,
+					Filename:  "transactions.duckdb",
+					Table:     "transactions",
+					BatchSize: 1000,
+				},
+				SQLite: config.SQLiteConfig{
+					Enabled:   false,
+					Filename:  "transactions.sqlite",
+					Table:     "transactions",
+					BatchSize: 1000,
+				},
 			},
 			Kafka: config.KafkaConfig{
 				Enabled:        false,
@@ -93,6 +183,60 @@ func main() {
 				Interval: 5,
 				Detailed: true,
 			},
+			GRPC: config.GRPCConfig{
+				Enabled: false,
+				Address: ":50051",
+			},
+			Socket: config.SocketConfig{
+				Enabled:     false,
+				Network:     "tcp",
+				Framing:     "ndjson",
+				BufferSize:  65536,
+				DialTimeout: 5000,
+			},
+			Fluent: config.FluentConfig{
+				Enabled:   false,
+				Address:   "localhost:24224",
+				Tag:       "producer.transactions",
+				BatchSize: 500,
+				Timeout:   5000,
+			},
+			Syslog: config.SyslogConfig{
+				Enabled: false,
+				Network: "tcp",
+				Address: "localhost:514",
+				AppName: "message-producer",
+				Timeout: 5000,
+			},
+			FIFO: config.FIFOConfig{
+				Enabled:      false,
+				Format:       "ndjson",
+				SlowReaderMs: 1000,
+				BufferSize:   65536,
+			},
+			Snowflake: config.SnowflakeConfig{
+				Enabled:   false,
+				Schema:    "PUBLIC",
+				Table:     "TRANSACTIONS",
+				Method:    "insert",
+				BatchSize: 10000,
+				Timeout:   60,
+			},
+			Postgres: config.PostgresConfig{
+				Enabled:   false,
+				Host:      "localhost:5432",
+				Table:     "transactions",
+				BatchSize: 10000,
+				PoolSize:  2,
+				Timeout:   30,
+			},
+			ClickHouse: config.ClickHouseConfig{
+				Enabled:   false,
+				Address:   "localhost:9000",
+				Table:     "transactions",
+				BlockSize: 100000,
+				Timeout:   30,
+			},
 		}
 		// Apply environment variable overrides
 		cfg.ApplyEnvOverrides()
@@ -104,35 +248,94 @@ func main() {
 		}
 	} else {
 		// Load configuration from file
-		cfg, err = config.Load(*configPath)
+		cfg, err = config.LoadTemplate(*configPath, params)
 		if err != nil {
 			slog.Error("Failed to load configuration", "error", err, "config_path", *configPath)
 			os.Exit(1)
 		}
 	}
 
+	if *scenarioName != "" {
+		if err := applyScenario(cfg, *scenarioName); err != nil {
+			slog.Error("Failed to apply scenario", "error", err)
+			os.Exit(1)
+		}
+	}
+	if *replaySpeed != "" {
+		cfg.Source.Replay.Speed, cfg.Source.Replay.Compress = *replaySpeed, ""
+		if err := cfg.Validate(); err != nil {
+			slog.Error("Invalid -speed", "error", err)
+			os.Exit(1)
+		}
+	}
+	applySchemaVersion(cfg)
+	if archive != nil {
+		if err := archive.apply(cfg); err != nil {
+			slog.Error("Failed to set up archive", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Size auto worker counts for the CPUs this process may actually use
+	setMaxProcs(logger)
+	resolveWorkers(cfg, logger)
+
 	continuousMode := cfg.Producer.MessageCount == 0
 	slog.Info("Configuration loaded",
 		"message_count", cfg.Producer.MessageCount,
 		"workers", cfg.Producer.Workers,
+		"ordering_key", cfg.Producer.OrderingKey,
+		"strict_ordering", cfg.Producer.StrictOrdering,
+		"transport", cfg.Producer.Transport,
+		"lock_os_thread", cfg.Producer.LockOSThread,
+		"scenario", cfg.Scenario.Name,
+		"seed", cfg.Scenario.Seed,
+		"rate", cfg.Scenario.Rate,
+		"arrivals", cfg.Scenario.Arrivals.Process,
 		"output_format", cfg.Output.Format,
 		"kafka_enabled", cfg.Kafka.Enabled,
 		"continuous_mode", continuousMode,
 	)
 
-	// Set up context with cancellation
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Stages shut down in order: generation stops first, fan-out drains
+	// what was generated, and the sinks flush last
+	lifecycle := pipeline.NewLifecycle(context.Background(), drainTimeout(cfg.Producer), logger)
+	defer lifecycle.Abort()
+	ctx := lifecycle.Context(pipeline.PhaseGenerate)
+	stop := lifecycle.Stop
+	if cfg.Producer.DrainTimeout < 0 {
+		stop = lifecycle.Abort
+	}
 
-	// Handle graceful shutdown
-	sigCh := make(chan os.Signal, 1)
+	// Handle graceful shutdown; a second signal drops what is left
+	sigCh := make(chan os.Signal, 2)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		sig := <-sigCh
-		slog.Info("Shutdown signal received", "signal", sig.String())
-		cancel()
+		slog.Info("Shutdown signal received, draining", "signal", sig.String())
+		stop()
+		sig = <-sigCh
+		slog.Warn("Second shutdown signal received, aborting", "signal", sig.String())
+		lifecycle.Abort()
 	}()
 
+	// The run bundle snapshots the config while secrets are still references
+	var runBundle *runBundle
+	if cfg.Bundle.Enabled {
+		if runBundle, err = newRunBundle(cfg, logs); err != nil {
+			slog.Error("Failed to set up run bundle", "error", err)
+			os.Exit(1)
+		}
+	} else {
+		logs.discard()
+	}
+
+	// Resolve vault: and aws-sm: references before any sink connects
+	if err := resolveSecrets(ctx, cfg, logger); err != nil {
+		slog.Error("Failed to resolve secrets", "error", err)
+		os.Exit(1)
+	}
+
 	// Load reference data
 	dataPath := cfg.Data.CurrencyRates[:len(cfg.Data.CurrencyRates)-len("/currency_rates.json")]
 	slog.Info("Loading reference data", "data_path", dataPath)
@@ -150,20 +353,104 @@ func main() {
 
 	// Initialize metrics monitor
 	monitor := metrics.NewMonitor(cfg.Metrics.Interval, cfg.Metrics.Detailed, logger)
+	if slo := sloObjective(cfg.Metrics); slo.Enabled() {
+		monitor.SetSLO(slo)
+	}
 	doneCh := make(chan struct{})
 	go monitor.StartReporting(doneCh)
 
-	// Create transaction channel
+	// Create transaction channel, or the ring buffer replacing it
 	txnChan := make(chan *models.Transaction, cfg.Producer.BufferSize)
+	var txnRing *ring.Ring[*models.Transaction]
+	if cfg.Producer.Transport == "ring" {
+		txnRing = ring.New[*models.Transaction](cfg.Producer.BufferSize)
+	}
+
+	// Every writer pulls from the shared transport through the pipeline
+	var source pipeline.Source = pipeline.FromChannel(txnChan)
+	if txnRing != nil {
+		source = txnRing
+	}
+	pipe := pipeline.New(source, cfg.Producer.BufferSize, logger)
+	if err := pipe.SetDispatch(cfg.Producer.Dispatch); err != nil {
+		slog.Error("Invalid dispatch mode", "error", err)
+		os.Exit(1)
+	}
 
 	// Initialize producer
 	producer := generator.NewProducer(refData, logger)
+	producer.SetSeed(cfg.Scenario.Seed)
+	producer.SetProfile(scenarioProfile(cfg.Scenario))
+	if err := producer.SetTemplates(scenarioTemplates(cfg.Scenario.Templates)); err != nil {
+		slog.Error("Invalid scenario templates", "error", err)
+		os.Exit(1)
+	}
+	producer.SetLockOSThread(cfg.Producer.LockOSThread)
+	producer.SetFixedPoint(cfg.Producer.FixedPointAmounts)
+	if cfg.Data.Houses != "" {
+		houses, err := generator.LoadHouses(cfg.Data.Houses)
+		if err == nil {
+			err = producer.SetHouses(houses)
+		}
+		if err != nil {
+			slog.Error("Failed to load houses", "error", err, "path", cfg.Data.Houses)
+			os.Exit(1)
+		}
+		slog.Info("Houses loaded", "houses", len(houses))
+	}
+	if len(cfg.Data.Datasets) > 0 || len(cfg.Data.Fields) > 0 {
+		if err := setupDatasets(producer, cfg.Data); err != nil {
+			slog.Error("Failed to load datasets", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("Datasets loaded", "datasets", len(cfg.Data.Datasets), "fields", len(cfg.Data.Fields))
+	}
+	if cfg.Metrics.PerWorker {
+		monitor.TrackWorkers(producer.WorkerCounts, producer.WorkerBusy)
+	}
+
+	// gRPC source mode - clients pull transactions instead of writers
+	if cfg.GRPC.Enabled {
+		grpcServer := server.NewGRPCServer(producer, cfg.GRPC.Address, cfg.GRPC.DefaultRate, cfg.GRPC.MaxRate, logger)
+		slog.Info("Starting gRPC source mode",
+			"address", cfg.GRPC.Address,
+			"default_rate", cfg.GRPC.DefaultRate,
+			"max_rate", cfg.GRPC.MaxRate,
+		)
+		if err := grpcServer.Serve(ctx); err != nil {
+			slog.Error("gRPC source error", "error", err)
+			os.Exit(1)
+		}
+		close(doneCh)
+		monitor.IncrementTotal(grpcServer.Count())
+		monitor.FinalReport()
+		slog.Info("gRPC source stopped", "streamed", grpcServer.Count())
+		return
+	}
 
 	// Set up writers
-	var wg sync.WaitGroup
-	var writers []struct {
-		name   string
-		closer func() error
+	var failure runFailure
+	var writers []namedCloser
+
+	// Ride out EINTR and NFS hiccups rather than lose the rest of a file
+	writer.SetWriteRetry(fileWriteRetry(cfg.Output))
+
+	// Resolve filename templates once so every file of the run agrees
+	runStarted := time.Now()
+	fileVars := writer.NewFilenameVars(cfg.Output.RunID)
+	slog.Info("Output run", "run_id", fileVars.RunID)
+
+	// Give the run its own subdirectory, pruning earlier runs
+	if cfg.Output.RunSubdir != "" {
+		if err := prepareRunDirs(&cfg.Output, fileVars, logger); err != nil {
+			slog.Error("Failed to prepare run directory", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("Run directory ready",
+			"directory", cfg.Output.Directory,
+			"keep_runs", cfg.Output.Retention.KeepRuns,
+			"max_age_days", cfg.Output.Retention.MaxAgeDays,
+		)
 	}
 
 	// Create output directory
@@ -172,207 +459,325 @@ func main() {
 		os.Exit(1)
 	}
 
-	// CSV Writer
-	if cfg.Output.CSV.Enabled && (cfg.Output.Format == "csv" || cfg.Output.Format == "both") {
-		csvWriter, err := writer.NewCSVWriter(cfg.Output.Directory, cfg.Output.CSV.Filename, cfg.Output.CSV.BufferSize, logger)
+	if cfg.Scenario.PlayerBehaviors.Rate > 0 {
+		closeLabels, err := startPlayerBehaviors(cfg.Scenario.PlayerBehaviors, cfg.Output.Directory, producer, logger)
 		if err != nil {
-			slog.Error("Failed to create CSV writer", "error", err)
+			slog.Error("Failed to set up player behavior scenarios", "error", err)
 			os.Exit(1)
 		}
-		writers = append(writers, struct {
-			name   string
-			closer func() error
-		}{"CSV", csvWriter.Close})
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			csvChan := make(chan *models.Transaction, cfg.Producer.BufferSize)
-			go func() {
-				for txn := range txnChan {
-					csvChan <- txn
-				}
-				close(csvChan)
-			}()
-			
-			if err := csvWriter.Write(ctx, csvChan); err != nil {
-				slog.Error("CSV writer error", "error", err)
-			}
-			monitor.IncrementCSV(csvWriter.Count())
-		}()
-		
-		slog.Info("CSV writer initialized",
-			"directory", cfg.Output.Directory,
-			"filename", cfg.Output.CSV.Filename,
-		)
+		writers = append(writers, namedCloser{"Player behavior labels", closeWithin(closeLabels)})
 	}
 
-	// Parquet Writer
-	if cfg.Output.Parquet.Enabled && (cfg.Output.Format == "parquet" || cfg.Output.Format == "both") {
-		parquetWriter, err := writer.NewParquetWriter(
-			cfg.Output.Directory,
-			cfg.Output.Parquet.Filename,
-			cfg.Output.Parquet.RowGroupSize,
-			cfg.Output.Parquet.Compression,
-			logger,
-		)
-		if err != nil {
-			slog.Error("Failed to create Parquet writer", "error", err)
-			os.Exit(1)
-		}
-		writers = append(writers, struct {
-			name   string
-			closer func() error
-		}{"Parquet", parquetWriter.Close})
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			parquetChan := make(chan *models.Transaction, cfg.Producer.BufferSize)
-			go func() {
-				for txn := range txnChan {
-					parquetChan <- txn
-				}
-				close(parquetChan)
-			}()
-			
-			if err := parquetWriter.Write(ctx, parquetChan); err != nil {
-				slog.Error("Parquet writer error", "error", err)
-			}
-			monitor.IncrementParquet(parquetWriter.Count())
-		}()
+	reportConsistency := func() error { return nil }
+	reportPayloads := func() error { return nil }
+	dedup := newFileDedup(cfg.Output.Dedup, logger)
+	if cfg.Producer.ConsistencyCheck.SampleRate > 0 {
+		reportConsistency = startConsistencyCheck(cfg.Producer.ConsistencyCheck, producer, &failure, stop, logger)
+	}
 
-		slog.Info("Parquet writer initialized",
-			"directory", cfg.Output.Directory,
-			"filename", cfg.Output.Parquet.Filename,
-			"compression", cfg.Output.Parquet.Compression,
-		)
+	closeDelivery, deliverFile, err := newFileDelivery(cfg.Output, fileVars.RunID, monitor, logger)
+	if err != nil {
+		slog.Error("Failed to set up file delivery", "error", err)
+		os.Exit(1)
+	}
+	sealFile, err := newFileSealer(cfg.Output.Encryption, deliverFile, logger)
+	if err != nil {
+		slog.Error("Failed to set up output encryption", "error", err)
+		os.Exit(1)
 	}
 
-	// Kafka Writer
-	if cfg.Kafka.Enabled {
-		kafkaWriter, err := writer.NewKafkaWriter(
-			cfg.Kafka.Brokers,
-			cfg.Kafka.Topic,
-			cfg.Kafka.Compression,
-			cfg.Kafka.BatchSize,
-			cfg.Kafka.FlushFrequency,
-			cfg.Kafka.Async,
-			logger,
-		)
-		if err != nil {
-			slog.Error("Failed to create Kafka writer", "error", err)
+	// Open the configured sinks, each in its own pipeline stage
+	sinks := &sinkEnv{
+		ctx:      lifecycle.Context(pipeline.PhaseSink),
+		stop:     stop,
+		cfg:      cfg,
+		refData:  refData,
+		producer: producer,
+		monitor:  monitor,
+		fileVars: fileVars,
+		seal:     sealFile,
+		doneCh:   doneCh,
+		failure:  &failure,
+		logger:   logger,
+	}
+	// Decide what a sink failing mid-run does to the rest of it
+	sinkErrors := &sinkErrorPolicy{cfg: cfg.Output.OnSinkError, failure: &failure, stop: stop, logger: logger}
+	pipe.SetOnError(sinkErrors.handle)
+	sinkClosers, err := startSinks(sinks, pipe, dedup)
+	if err != nil {
+		slog.Error("Failed to set up sink", "error", err)
+		os.Exit(1)
+	}
+	writers = append(writers, sinkClosers...)
+	if closeDelivery != nil {
+		// After the sinks, whose last files it still has to deliver
+		writers = append(writers, namedCloser{"File delivery", closeWithin(closeDelivery)})
+	}
+	if sinks.reportPayloads != nil {
+		reportPayloads = sinks.reportPayloads
+	}
+
+	// Lifecycle and progress events for orchestrators
+	emitter, err := newEmitter(cfg, fileVars.RunID, monitor, logger)
+	if err != nil {
+		slog.Error("Failed to set up events", "error", err)
+		os.Exit(1)
+	}
+	progressCtx, stopProgress := context.WithCancel(context.Background())
+	if emitter != nil {
+		emitter.Started(ctx)
+		go emitter.Run(progressCtx, eventInterval(cfg.Events))
+	}
+
+	// Profile from the first generated message until the writers are closed
+	stopProfiles := func() {}
+	if *profileDir != "" {
+		if stopProfiles, err = startProfiles(*profileDir, logger); err != nil {
+			slog.Error("Failed to start profiling", "error", err)
 			os.Exit(1)
 		}
-		writers = append(writers, struct {
-			name   string
-			closer func() error
-		}{"Kafka", kafkaWriter.Close})
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			kafkaChan := make(chan *models.Transaction, cfg.Producer.BufferSize)
-			go func() {
-				for txn := range txnChan {
-					kafkaChan <- txn
-				}
-				close(kafkaChan)
-			}()
-			
-			if err := kafkaWriter.Write(ctx, kafkaChan); err != nil {
-				slog.Error("Kafka writer error", "error", err)
-			}
-			monitor.IncrementKafka(kafkaWriter.Count())
-			monitor.IncrementKafkaErrors(kafkaWriter.Errors())
-		}()
-		
-		slog.Info("Kafka writer initialized",
-			"brokers", cfg.Kafka.Brokers,
-			"topic", cfg.Kafka.Topic,
-			"compression", cfg.Kafka.Compression,
-		)
 	}
 
+	// Stop cleanly once the run's files pass the output limits
+	if cfg.Output.Limits.MaxBytes > 0 || cfg.Output.Limits.MaxFiles > 0 {
+		go watchOutputLimits(ctx, cfg.Output, runStarted, stop, logger)
+	}
+
+	// The run bundle samples and aggregates the broadcast stream
+	if runBundle != nil {
+		pipe.Start(lifecycle.Context(pipeline.PhaseSink), pipeline.Stage{Name: "bundle", Writer: runBundle.collector})
+	}
+
+	// Every stage is started; feed them
+	pipe.Dispatch(lifecycle.Context(pipeline.PhaseFanOut))
+	lifecycle.Go(pipeline.PhaseSink, "writers", func(context.Context) error {
+		// Stage errors were logged and handled by sinkErrors as they happened
+		pipe.Wait()
+		return nil
+	})
+
 	slog.Info("Starting message generation", "continuous_mode", continuousMode)
 
 	// Start generation
 	startTime := time.Now()
+
+	// Generators feed genChan; with a scenario rate it is paced into txnChan
+	genChan := txnChan
+	if cfg.Scenario.Rate > 0 {
+		genChan = make(chan *models.Transaction, cfg.Producer.BufferSize)
+		lifecycle.Go(pipeline.PhaseFanOut, "pace", func(ctx context.Context) error {
+			pipeline.Pace(ctx, genChan, txnChan, cfg.Scenario.Rate, scenarioSpikes(cfg.Scenario.Spikes), scenarioArrivals(cfg.Scenario), pipeline.SystemClock)
+			return nil
+		})
+	}
 	
-	if continuousMode {
+	if cfg.Source.Type != "" {
+		// Source mode - sinks take transformed records from stdin or Kafka,
+		// optionally at their original pace
+		sourceChan := genChan
+		if speed, _ := cfg.Source.Replay.Factor(); speed > 0 {
+			sourceChan = make(chan *models.Transaction, cfg.Producer.BufferSize)
+			maxGap := time.Duration(cfg.Source.Replay.MaxGap) * time.Second
+			lifecycle.Go(pipeline.PhaseFanOut, "replay", func(ctx context.Context) error {
+				pipeline.Replay(ctx, sourceChan, genChan, speed, maxGap, pipeline.SystemClock)
+				return nil
+			})
+			slog.Info("Replaying source at original pace", "speed", speed, "max_gap", maxGap)
+		}
+		if err := startSource(lifecycle, cfg, sourceChan, monitor, logger); err != nil {
+			slog.Error("Failed to start source", "error", err)
+			os.Exit(1)
+		}
+	} else if continuousMode {
 		// Continuous mode - generate until stopped
 		var totalGenerated atomic.Int64
-		go func() {
+		lifecycle.Go(pipeline.PhaseGenerate, "generator", func(ctx context.Context) error {
+			defer close(genChan)
 			for {
 				select {
 				case <-ctx.Done():
-					close(txnChan)
-					return
+					return nil
 				default:
 					txn := producer.GenerateSingle()
 					select {
-					case txnChan <- txn:
+					case genChan <- txn:
 						totalGenerated.Add(1)
 					case <-ctx.Done():
-						close(txnChan)
-						return
+						return nil
 					}
 				}
 			}
-		}()
+		})
 
 		// Update monitor periodically in continuous mode
-		go func() {
+		lifecycle.Go(pipeline.PhaseGenerate, "generation monitor", func(ctx context.Context) error {
 			ticker := time.NewTicker(time.Second)
 			defer ticker.Stop()
 			var lastCount int64
 			for {
 				select {
 				case <-ticker.C:
-					current := totalGenerated.Load()
-					monitor.IncrementTotal(current - lastCount)
-					lastCount = current
 				case <-ctx.Done():
-					return
+					// Count what was generated since the last tick too
+					monitor.IncrementTotal(totalGenerated.Load() - lastCount)
+					return nil
 				}
+				current := totalGenerated.Load()
+				monitor.IncrementTotal(current - lastCount)
+				lastCount = current
 			}
-		}()
-
-		// Wait for context cancellation
-		<-ctx.Done()
+		})
 	} else {
 		// Fixed count mode
-		go func() {
-			if err := producer.Generate(ctx, cfg.Producer.MessageCount, cfg.Producer.Workers, txnChan); err != nil {
-				slog.Error("Generation error", "error", err)
+		lifecycle.Go(pipeline.PhaseGenerate, "generator", func(ctx context.Context) error {
+			generate := producer.Generate
+			if cfg.Producer.StrictOrdering {
+				generate = producer.GenerateStrict
+			} else if cfg.Producer.OrderingKey != "" {
+				// Keep each key on one worker so sinks see it in sequence order
+				generate = func(ctx context.Context, count, workers int, output chan<- *models.Transaction) error {
+					return producer.GenerateOrdered(ctx, count, workers, cfg.Producer.OrderingKey, output)
+				}
+			}
+			var err error
+			if txnRing != nil {
+				err = producer.GenerateRing(ctx, cfg.Producer.MessageCount, int(cfg.Producer.Workers), txnRing)
+			} else {
+				err = generate(ctx, cfg.Producer.MessageCount, int(cfg.Producer.Workers), genChan)
 			}
 			monitor.IncrementTotal(int64(cfg.Producer.MessageCount))
-		}()
+			return err
+		})
 	}
 
-	// Wait for writers to complete
-	wg.Wait()
+	// Wait for generation, fan-out and the writers to finish, in that order
+	if err := lifecycle.Wait(); err != nil {
+		failure.set(err)
+	}
+	stopProgress()
 	
 	// Stop metrics reporting
 	close(doneCh)
 	
 	elapsed := time.Since(startTime)
 
-	// Close all writers
-	slog.Info("Closing writers", "count", len(writers))
+	// Close all writers, each within output.close_timeout
+	closeTimeout := writerCloseTimeout(cfg.Output)
+	slog.Info("Closing writers", "count", len(writers), "close_timeout", closeTimeout)
 	for _, w := range writers {
-		if err := w.closer(); err != nil {
+		closeCtx, cancelClose := context.WithTimeout(context.Background(), closeTimeout)
+		closeStarted := time.Now()
+		err := w.closer(closeCtx)
+		cancelClose()
+		monitor.RecordClose(w.name, time.Since(closeStarted), err)
+		if err != nil {
 			slog.Error("Error closing writer", "writer", w.name, "error", err)
 		} else {
 			slog.Info("Writer closed", "writer", w.name)
 		}
 	}
+	stopProfiles()
+	if sinks.latencyProbe != nil {
+		sinks.latencyProbe()
+	}
+	if sinks.partitionReport != nil {
+		sinks.partitionReport()
+	}
+
+	// Register finished Parquet partitions so they are queryable right away
+	if cfg.Catalog.Enabled && cfg.Output.Parquet.Enabled {
+		parquetDir := cfg.Output.Directory
+		if len(cfg.Output.Parquet.Destinations) > 0 {
+			parquetDir = cfg.Output.Parquet.Destinations[0].Directory
+		}
+		registerCatalog(cfg.Catalog, parquetDir, logger)
+	}
+
+	// Record checksums of everything this run wrote
+	run := provenance.Run{
+		RunID:        fileVars.RunID,
+		Version:      "1.0.0",
+		Scenario:     cfg.Scenario.Name,
+		Seed:         cfg.Scenario.Seed,
+		MessageCount: cfg.Producer.MessageCount,
+		StartedAt:    runStarted.UTC(),
+		FinishedAt:   time.Now().UTC(),
+	}
+	var statement *provenance.Statement
+	if cfg.Provenance.Enabled {
+		statement = writeProvenance(cfg, run, monitor.SinkCounts(), runStarted)
+	}
+
+	if emitter != nil {
+		emitter.Finished(context.Background(), failure.err())
+		emitter.Close()
+	}
 
 	// Print final report
 	monitor.FinalReport()
+	if runBundle != nil {
+		runBundle.write(cfg, fileVars, run, statement, monitor.SinkCounts(), runStarted)
+	}
+	if err := reportConsistency(); err != nil {
+		slog.Error("Generated data is inconsistent", "error", err)
+		os.Exit(1)
+	}
+	if err := reportPayloads(); err != nil {
+		slog.Error("Kafka payloads are invalid", "error", err)
+		os.Exit(1)
+	}
+	// Counts are exact only when the writers drained their input
+	if err := dedup.report(!lifecycle.Stopped(pipeline.PhaseSink)); err != nil {
+		slog.Error("File output is incomplete", "error", err)
+		os.Exit(1)
+	}
+	if err := sinkErrors.aborted.err(); err != nil {
+		slog.Error("Run aborted by sink failure", "error", err)
+		os.Exit(1)
+	}
+	if err := sinkErrors.failed.err(); err != nil {
+		slog.Error("Run failed, a sink stopped mid-run", "error", err)
+		os.Exit(1)
+	}
 	
 	slog.Info("Generation completed",
 		"duration", elapsed.String(),
 		"output_directory", cfg.Output.Directory,
 	)
+
+	if !monitor.SLOPassed() {
+		os.Exit(exitSLOFailed)
+	}
+}
+
+// fileWriteRetry is the retry policy for transient file write errors
+func fileWriteRetry(cfg config.OutputConfig) writer.WriteRetry {
+	retry := writer.WriteRetry{Retries: cfg.WriteRetries, Backoff: time.Duration(cfg.WriteRetryBackoff) * time.Millisecond}
+	switch {
+	case cfg.WriteRetries < 0:
+		retry.Retries = 0
+	case cfg.WriteRetries == 0:
+		retry.Retries = 3
+	}
+	if retry.Backoff == 0 {
+		retry.Backoff = 50 * time.Millisecond
+	}
+	return retry
+}
+
+// writerCloseTimeout is how long each writer may take to close
+func writerCloseTimeout(cfg config.OutputConfig) time.Duration {
+	if cfg.CloseTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(cfg.CloseTimeout) * time.Second
+}
+
+// drainTimeout is how long the sinks get to write out what was generated
+// after a stop; -1 stops at once and is handled by the caller
+func drainTimeout(cfg config.ProducerConfig) time.Duration {
+	if cfg.DrainTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(cfg.DrainTimeout) * time.Second
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// generatorState is a record on the metadata topic: the generator settings
+// in effect at Timestamp, for joining downstream results against
+type generatorState struct {
+	RunID          string    `json:"run_id"`
+	Timestamp      time.Time `json:"timestamp"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+	Scenario       string    `json:"scenario,omitempty"`
+	Seed           int64     `json:"seed"`
+	Phase          string    `json:"phase"`         // baseline, or the active anomaly kinds joined with +
+	TargetRate     float64   `json:"target_rate"`   // messages/sec with spikes applied, 0 = unthrottled
+	ProducedRate   float64   `json:"produced_rate"` // messages/sec produced to the topic since the last record
+	Produced       int64     `json:"produced"`
+	BehaviorRate   float64   `json:"player_behavior_rate,omitempty"`
+	Anomalies      []anomaly `json:"anomalies,omitempty"`
+	Final          bool      `json:"final,omitempty"`
+}
+
+// anomaly is one scheduled scenario anomaly and whether it is in effect
+type anomaly struct {
+	Kind       string  `json:"kind"`             // spike, vendor_outage or currency_shock
+	Target     string  `json:"target,omitempty"` // vendor or currency
+	Start      int     `json:"start"`            // seconds into the run
+	Duration   int     `json:"duration"`
+	Multiplier float64 `json:"multiplier,omitempty"`
+	Active     bool    `json:"active"`
+}
+
+// startMetadata publishes the generator state to the metadata topic every
+// interval. produced reports the messages written to the main topic. The
+// returned closer publishes a final record and flushes; call it after
+// generation has finished.
+func startMetadata(ctx context.Context, cfg *config.Config, runID string, produced func() int64, logger *slog.Logger) (func() error, error) {
+	settings := cfg.Kafka.Metadata
+	topic := firstNonEmpty(settings.Topic, cfg.Kafka.Topic+"-metadata")
+	interval := time.Duration(settings.Interval) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	stateWriter, err := writer.NewKafkaRecordWriter(cfg.Kafka.Brokers, topic, kafkaAuth(cfg.Kafka), logger)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	last, lastCount := start, int64(0)
+	publish := func(now time.Time, final bool) {
+		count := produced()
+		state := scenarioState(cfg.Scenario, now.Sub(start))
+		state.RunID = runID
+		state.Timestamp = now.UTC()
+		state.ElapsedSeconds = now.Sub(start).Seconds()
+		state.Produced = count
+		if seconds := now.Sub(last).Seconds(); seconds > 0 {
+			state.ProducedRate = float64(count-lastCount) / seconds
+		}
+		state.Final = final
+		stateWriter.Publish(runID, state)
+		last, lastCount = now, count
+	}
+
+	runCtx, stop := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		publish(time.Now(), false)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				publish(now, false)
+			case <-runCtx.Done():
+				return
+			}
+		}
+	}()
+	logger.Info("Generator metadata enabled", "topic", topic, "interval", interval)
+
+	return func() error {
+		stop()
+		<-stopped
+		publish(time.Now(), true)
+		err := stateWriter.Close()
+		logger.Info("Generator metadata published", "records", stateWriter.Count(), "errors", stateWriter.Errors())
+		return err
+	}, nil
+}
+
+// scenarioState fills the scenario fields of the generator state at elapsed
+// into the run
+func scenarioState(sc config.ScenarioConfig, elapsed time.Duration) generatorState {
+	state := generatorState{
+		Scenario:     sc.Name,
+		Seed:         sc.Seed,
+		TargetRate:   float64(sc.Rate),
+		BehaviorRate: sc.PlayerBehaviors.Rate,
+	}
+	within := func(start, duration int) bool {
+		from := time.Duration(start) * time.Second
+		return elapsed >= from && elapsed < from+time.Duration(duration)*time.Second
+	}
+
+	for _, s := range sc.Spikes {
+		active := within(s.Start, s.Duration)
+		if active {
+			state.TargetRate *= s.Multiplier
+		}
+		state.Anomalies = append(state.Anomalies, anomaly{
+			Kind: "spike", Start: s.Start, Duration: s.Duration, Multiplier: s.Multiplier, Active: active,
+		})
+	}
+	for _, o := range sc.VendorOutages {
+		state.Anomalies = append(state.Anomalies, anomaly{
+			Kind: "vendor_outage", Target: o.Vendor, Start: o.Start, Duration: o.Duration, Active: within(o.Start, o.Duration),
+		})
+	}
+	for _, s := range sc.CurrencyShocks {
+		// A shock stays at its multiplier once the ramp is over
+		state.Anomalies = append(state.Anomalies, anomaly{
+			Kind: "currency_shock", Target: s.Currency, Start: s.Start, Duration: s.Duration, Multiplier: s.Multiplier,
+			Active: elapsed >= time.Duration(s.Start)*time.Second,
+		})
+	}
+
+	var phase []string
+	for _, a := range state.Anomalies {
+		if a.Active && !slices.Contains(phase, a.Kind) {
+			phase = append(phase, a.Kind)
+		}
+	}
+	state.Phase = "baseline"
+	if len(phase) > 0 {
+		state.Phase = strings.Join(phase, "+")
+	}
+	return state
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/encrypt"
+	"github.com/supratick/message_producer/internal/models"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// fileOutput describes one file format's output: where it goes and how a
+// single file is opened
+type fileOutput struct {
+	destinations []config.DestinationConfig
+	directory    string // used when no destinations are configured
+	filename     string // template with {{seq}} still unresolved
+	shards       int
+	shardKey     string
+	open         func(dir, filename string) (writer.FileWriter, error)
+	seal         func(path string, rows int64) error // run on each finished file, nil = none
+}
+
+// newFileOutput composes sharding, rotation and multiple destinations around
+// the single-file writer. Each destination receives the full stream.
+func newFileOutput(out fileOutput, logger *slog.Logger) (writer.FileWriter, error) {
+	destinations := out.destinations
+	if len(destinations) == 0 {
+		destinations = []config.DestinationConfig{{Directory: out.directory}}
+	}
+
+	var opened []writer.Destination
+	closeOpened := func() {
+		for _, d := range opened {
+			d.Writer.Close()
+		}
+	}
+
+	openFile := out.open
+	if out.seal != nil {
+		openFile = func(dir, filename string) (writer.FileWriter, error) {
+			w, err := out.open(dir, filename)
+			if err != nil {
+				return nil, err
+			}
+			return writer.NewSealedWriter(w, filepath.Join(dir, filename), out.seal), nil
+		}
+	}
+
+	for _, dest := range destinations {
+		dir := dest.Directory
+		rotating := dest.RotateRows > 0 || dest.RotateInterval > 0
+
+		openSeq := func(seq int) (writer.FileWriter, error) {
+			name := out.filename
+			if rotating {
+				name = writer.RotationFilename(name, seq)
+			}
+			if out.shards > 1 {
+				return writer.NewShardedWriter(out.shards, out.shardKey, func(part int) (writer.FileWriter, error) {
+					return openFile(dir, writer.PartFilename(name, part))
+				}, logger)
+			}
+			return openFile(dir, writer.FileSeq(name, 0))
+		}
+
+		var w writer.FileWriter
+		var err error
+		if rotating {
+			w, err = writer.NewRotatingWriter(openSeq, int64(dest.RotateRows), time.Duration(dest.RotateInterval)*time.Second, logger)
+		} else {
+			w, err = openSeq(0)
+		}
+		if err != nil {
+			closeOpened()
+			return nil, err
+		}
+		opened = append(opened, writer.Destination{Name: dir, Writer: w})
+	}
+
+	if len(opened) == 1 {
+		return opened[0].Writer, nil
+	}
+	return writer.NewTeeWriter(opened, logger), nil
+}
+
+// prepareRunDirs moves the output directory and every file destination into
+// this run's subdirectory, marking each, and prunes earlier runs beside them
+// according to the retention policy
+func prepareRunDirs(cfg *config.OutputConfig, vars writer.FilenameVars, logger *slog.Logger) error {
+	subdir := vars.Expand(cfg.RunSubdir)
+	maxAge := time.Duration(cfg.Retention.MaxAgeDays) * 24 * time.Hour
+	prepared := make(map[string]string)
+
+	runDir := func(base string) (string, error) {
+		if dir, ok := prepared[base]; ok {
+			return dir, nil
+		}
+		dir := filepath.Join(base, subdir)
+		if err := writer.CreateRunDir(dir, vars.RunID); err != nil {
+			return "", fmt.Errorf("failed to create run directory %s: %w", dir, err)
+		}
+		removed, err := writer.PruneRuns(base, dir, cfg.Retention.KeepRuns, maxAge, time.Now())
+		for _, old := range removed {
+			logger.Info("Removed old run directory", "directory", old)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to prune runs in %s: %w", base, err)
+		}
+		prepared[base] = dir
+		return dir, nil
+	}
+
+	var err error
+	if cfg.Directory, err = runDir(cfg.Directory); err != nil {
+		return err
+	}
+	for _, destinations := range [][]config.DestinationConfig{cfg.CSV.Destinations, cfg.Parquet.Destinations, cfg.JSONL.Destinations, cfg.Msgpack.Destinations, cfg.Render.Destinations, cfg.Arrow.Destinations, cfg.FixedWidth.Destinations, cfg.XML.Destinations} {
+		for i := range destinations {
+			if destinations[i].Directory, err = runDir(destinations[i].Directory); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// outputDirs lists the output directory followed by every file destination
+func outputDirs(cfg config.OutputConfig) []string {
+	dirs := []string{cfg.Directory}
+	for _, d := range cfg.CSV.Destinations {
+		dirs = append(dirs, d.Directory)
+	}
+	for _, d := range cfg.Parquet.Destinations {
+		dirs = append(dirs, d.Directory)
+	}
+	for _, d := range cfg.JSONL.Destinations {
+		dirs = append(dirs, d.Directory)
+	}
+	for _, d := range cfg.Msgpack.Destinations {
+		dirs = append(dirs, d.Directory)
+	}
+	for _, d := range cfg.Render.Destinations {
+		dirs = append(dirs, d.Directory)
+	}
+	for _, d := range cfg.Arrow.Destinations {
+		dirs = append(dirs, d.Directory)
+	}
+	for _, d := range cfg.FixedWidth.Destinations {
+		dirs = append(dirs, d.Directory)
+	}
+	for _, d := range cfg.XML.Destinations {
+		dirs = append(dirs, d.Directory)
+	}
+	return dirs
+}
+
+// watchOutputLimits polls the size and number of files this run has written
+// and calls stop once either passes its limit, which ends the run as a
+// shutdown signal would
+func watchOutputLimits(ctx context.Context, cfg config.OutputConfig, since time.Time, stop func(), logger *slog.Logger) {
+	limits := cfg.Limits
+	interval := time.Duration(limits.CheckInterval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	dirs := outputDirs(cfg)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		bytes, files, err := writer.OutputUsage(dirs, since)
+		if err != nil {
+			logger.Warn("Failed to measure output", "error", err)
+			continue
+		}
+		if (limits.MaxBytes > 0 && bytes >= int64(limits.MaxBytes)) || (limits.MaxFiles > 0 && files >= limits.MaxFiles) {
+			logger.Warn("Output limit reached, stopping generation",
+				"bytes", bytes,
+				"files", files,
+				"max_bytes", int64(limits.MaxBytes),
+				"max_files", limits.MaxFiles,
+			)
+			stop()
+			return
+		}
+	}
+}
+
+// newFileSealer returns the step run on each finished output file: encrypt
+// it, then pass the result and its row count to deliver. It returns nil when
+// encryption is off and deliver is nil.
+func newFileSealer(cfg config.EncryptionConfig, deliver func(path string, rows int64), logger *slog.Logger) (func(path string, rows int64) error, error) {
+	if cfg.Mode == "" {
+		if deliver == nil {
+			return nil, nil
+		}
+		return func(path string, rows int64) error {
+			deliver(path, rows)
+			return nil
+		}, nil
+	}
+
+	key := cfg.Key
+	if key == "" && cfg.KeyFile != "" {
+		data, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key file: %w", err)
+		}
+		key = string(data)
+	}
+	enc, err := encrypt.NewFileEncryptor(cfg.Mode, cfg.Recipients, key, cfg.KeepPlaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(path string, rows int64) error {
+		encrypted, err := enc.EncryptFile(path)
+		if err != nil {
+			return err
+		}
+		logger.Info("Output file encrypted", "path", encrypted, "mode", cfg.Mode)
+		if deliver != nil {
+			deliver(encrypted, rows)
+		}
+		return nil
+	}, nil
+}
+
+// kafkaAuth returns the broker authentication settings
+func kafkaAuth(cfg config.KafkaConfig) writer.KafkaAuth {
+	auth := writer.KafkaAuth{TLS: cfg.TLS}
+	if cfg.SASL.Enabled {
+		auth.Username = cfg.SASL.Username
+		auth.Password = cfg.SASL.Password
+	}
+	return auth
+}
+
+// kafkaKey builds the message key encoder, registering the Avro key schema
+// when keys are Avro. It returns nil for the default transaction ID key.
+func kafkaKey(cfg config.KafkaConfig) (*writer.KeyEncoder, error) {
+	key := cfg.Key
+	if key.Format == "" || key.Format == writer.KeyString {
+		return nil, nil
+	}
+	enc, err := writer.NewKeyEncoder(key.Format, key.Fields)
+	if err != nil {
+		return nil, err
+	}
+	if key.Format == writer.KeyAvro {
+		subject := firstNonEmpty(key.Registry.Subject, cfg.Topic+"-key")
+		registry := writer.NewSchemaRegistry(key.Registry.URL, key.Registry.Username, key.Registry.Password, 10*time.Second)
+		id, err := registry.Register(context.Background(), subject, enc.AvroSchema())
+		if err != nil {
+			return nil, err
+		}
+		enc.SetSchemaID(id)
+		slog.Info("Kafka key schema registered", "subject", subject, "id", id)
+	}
+	return enc, nil
+}
+
+// checkValueSchema tests the schema of the Kafka value serialization against
+// the latest version of the value subject. An incompatible schema is an error
+// unless on_incompatible is warn; a registry failure always is.
+func checkValueSchema(ctx context.Context, cfg config.KafkaConfig, logger *slog.Logger) error {
+	schemaType, schema := writer.SchemaTypeJSON, models.TransactionJSONSchema
+	if cfg.Serialization == "avro" {
+		schemaType, schema = writer.SchemaTypeAvro, models.TransactionAvroSchema
+	}
+	subject := firstNonEmpty(cfg.SchemaCheck.Subject, cfg.Topic+"-value")
+	registry := writer.NewSchemaRegistry(cfg.Key.Registry.URL, cfg.Key.Registry.Username, cfg.Key.Registry.Password, 10*time.Second)
+
+	compatible, messages, err := registry.CheckCompatibility(ctx, subject, schemaType, schema)
+	if err != nil {
+		return err
+	}
+	if compatible {
+		logger.Info("Kafka value schema is compatible with the registry", "subject", subject, "schema_type", schemaType)
+		return nil
+	}
+	if cfg.SchemaCheck.OnIncompatible == "warn" {
+		logger.Warn("Kafka value schema is incompatible with the registry", "subject", subject, "schema_type", schemaType, "messages", messages)
+		return nil
+	}
+	return fmt.Errorf("value schema is incompatible with the latest version of %s: %s", subject, strings.Join(messages, "; "))
+}
+
+// snowflakeOptions maps the snowflake config block to writer options
+func snowflakeOptions(cfg config.SnowflakeConfig) writer.SnowflakeOptions {
+	return writer.SnowflakeOptions{
+		Account:        cfg.Account,
+		User:           cfg.User,
+		PrivateKeyPath: cfg.PrivateKeyPath,
+		PrivateKey:     cfg.PrivateKey,
+		Role:           cfg.Role,
+		Warehouse:      cfg.Warehouse,
+		Database:       cfg.Database,
+		Schema:         cfg.Schema,
+		Table:          cfg.Table,
+		Method:         cfg.Method,
+		Pipe:           cfg.Pipe,
+		Channel:        cfg.Channel,
+		BatchSize:      cfg.BatchSize,
+		Timeout:        time.Duration(cfg.Timeout) * time.Second,
+	}
+}
+
+// postgresOptions maps the postgres config block to writer options
+func postgresOptions(cfg config.PostgresConfig) writer.PostgresOptions {
+	return writer.PostgresOptions{
+		Host:      cfg.Host,
+		User:      cfg.User,
+		Password:  cfg.Password,
+		Database:  cfg.Database,
+		SSLMode:   cfg.SSLMode,
+		Table:     cfg.Table,
+		BatchSize: cfg.BatchSize,
+		PoolSize:  cfg.PoolSize,
+		Timeout:   time.Duration(cfg.Timeout) * time.Second,
+	}
+}
+
+// clickHouseOptions maps the clickhouse config block to writer options
+func clickHouseOptions(cfg config.ClickHouseConfig) writer.ClickHouseOptions {
+	return writer.ClickHouseOptions{
+		Addr:               cfg.Address,
+		User:               cfg.User,
+		Password:           cfg.Password,
+		Database:           cfg.Database,
+		Secure:             cfg.TLS,
+		Table:              cfg.Table,
+		BlockSize:          cfg.BlockSize,
+		AsyncInsert:        cfg.AsyncInsert,
+		WaitForAsyncInsert: cfg.WaitForAsyncInsert,
+		Timeout:            time.Duration(cfg.Timeout) * time.Second,
+	}
+}
+
+// fifoOptions maps the fifo config onto the FIFO writer's options
+func fifoOptions(cfg config.FIFOConfig) writer.FIFOOptions {
+	return writer.FIFOOptions{
+		Path:       cfg.Path,
+		Format:     cfg.Format,
+		NoReader:   firstNonEmpty(cfg.NoReader, writer.FIFOWait),
+		SlowReader: time.Duration(cfg.SlowReaderMs) * time.Millisecond,
+		SpoolDir:   cfg.SpoolDir,
+		SpoolLimit: int64(cfg.SpoolLimit),
+		BufferSize: cfg.BufferSize,
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// paramFlags collects repeated -param name=value flags for config templates
+type paramFlags map[string]string
+
+func (p paramFlags) String() string {
+	pairs := make([]string, 0, len(p))
+	for k, v := range p {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (p paramFlags) Set(value string) error {
+	name, v, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("param must be name=value, got %q", value)
+	}
+	p[name] = v
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// startPartitionReport snapshots the topic's partition offsets before any
+// message is sent. The returned function snapshots them again and logs how
+// the run's messages were spread, warning when the busiest partition exceeds
+// max_skew times the mean or the topic grew by more than delivered counts,
+// which means another producer wrote to it. Call it after the Kafka writer
+// has been closed.
+func startPartitionReport(cfg config.KafkaConfig, delivered func() int64, logger *slog.Logger) func() {
+	maxSkew := cfg.PartitionReport.MaxSkew
+	if maxSkew == 0 {
+		maxSkew = 1.5
+	}
+	auth := kafkaAuth(cfg)
+
+	before, err := writer.TopicOffsets(cfg.Brokers, cfg.Topic, auth, 10*time.Second)
+	if err != nil {
+		logger.Warn("Partition report disabled, failed to read topic offsets", "error", err)
+		return func() {}
+	}
+
+	return func() {
+		after, err := writer.TopicOffsets(cfg.Brokers, cfg.Topic, auth, 10*time.Second)
+		if err != nil {
+			logger.Warn("Failed to read topic offsets for partition report", "error", err)
+			return
+		}
+		dist := writer.NewPartitionDistribution(before, after)
+		logger.Info("Kafka partition distribution", append([]any{"topic", cfg.Topic}, dist.Attrs()...)...)
+
+		if sent := delivered(); dist.Total != sent {
+			logger.Warn("Topic offsets moved by a different count than was delivered",
+				"offsets", dist.Total, "delivered", sent)
+		}
+		if dist.Skew > maxSkew {
+			logger.Warn("Partition skew exceeds expectation for the key hash partitioner",
+				"skew", dist.Skew, "max_skew", maxSkew, "min", dist.Min, "max", dist.Max)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	runtimepprof "runtime/pprof"
+)
+
+// servePprof exposes the net/http/pprof handlers on addr for live profiling,
+// e.g. `go tool pprof http://localhost:6060/debug/pprof/profile?seconds=30`
+func servePprof(addr string, logger *slog.Logger) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	go func() {
+		logger.Info("pprof server listening", "address", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("pprof server failed", "error", err)
+		}
+	}()
+}
+
+// startProfiles writes a CPU profile to dir/cpu.pprof until the returned stop
+// function runs, which also writes dir/heap.pprof and dir/allocs.pprof
+func startProfiles(dir string, logger *slog.Logger) (func(), error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	cpuPath := filepath.Join(dir, "cpu.pprof")
+	cpu, err := os.Create(cpuPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+	}
+	if err := runtimepprof.StartCPUProfile(cpu); err != nil {
+		cpu.Close()
+		return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+	}
+
+	return func() {
+		runtimepprof.StopCPUProfile()
+		cpu.Close()
+
+		// Collect first so the heap profile reflects live objects at the end
+		runtime.GC()
+		for _, name := range []string{"heap", "allocs"} {
+			if err := writeProfile(name, filepath.Join(dir, name+".pprof")); err != nil {
+				logger.Error("Failed to write profile", "profile", name, "error", err)
+			}
+		}
+		logger.Info("Profiles written", "directory", dir, "cpu", cpuPath)
+	}, nil
+}
+
+func writeProfile(name, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := runtimepprof.Lookup(name).WriteTo(f, 0); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/provenance"
+)
+
+// envelopeFilename derives the signed envelope name from the statement name,
+// e.g. manifest.json -> manifest.dsse.json
+func envelopeFilename(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + ".dsse.json"
+}
+
+// provenanceFilename is the statement's name in the output directory
+func provenanceFilename(cfg config.ProvenanceConfig) string {
+	if cfg.Filename == "" {
+		return "manifest.json"
+	}
+	return cfg.Filename
+}
+
+// provenanceStatement hashes the files written since started into an
+// in-toto statement for run
+func provenanceStatement(cfg *config.Config, run provenance.Run, sinks map[string]int64, started time.Time) (*provenance.Statement, error) {
+	for name, count := range sinks {
+		run.Sinks = append(run.Sinks, provenance.Sink{Name: name, Count: count})
+	}
+	sort.Slice(run.Sinks, func(i, j int) bool { return run.Sinks[i].Name < run.Sinks[j].Name })
+
+	filename := provenanceFilename(cfg.Provenance)
+	return provenance.NewStatement(run, cfg.Output.Directory, outputDirs(cfg.Output), started, filename, envelopeFilename(filename))
+}
+
+// writeProvenance writes the statement of the files written since started
+// to the output directory and signs it when a key is configured. It returns
+// the statement, or nil when it failed. Failures are logged; the output
+// itself is already complete.
+func writeProvenance(cfg *config.Config, run provenance.Run, sinks map[string]int64, started time.Time) *provenance.Statement {
+	filename := provenanceFilename(cfg.Provenance)
+	stmt, err := provenanceStatement(cfg, run, sinks, started)
+	if err != nil {
+		slog.Error("Provenance statement failed", "error", err)
+		return nil
+	}
+	data, err := stmt.Marshal()
+	if err != nil {
+		slog.Error("Provenance statement failed", "error", err)
+		return nil
+	}
+	path := filepath.Join(cfg.Output.Directory, filename)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		slog.Error("Failed to write provenance statement", "path", path, "error", err)
+		return nil
+	}
+	slog.Info("Provenance statement written", "path", path, "files", len(stmt.Subject))
+
+	if cfg.Provenance.SigningKey == "" {
+		return stmt
+	}
+	signer, err := provenance.LoadSigner(cfg.Provenance.SigningKey)
+	if err != nil {
+		slog.Error("Provenance signing failed", "error", err)
+		return stmt
+	}
+	envelope, err := provenance.Sign(stmt, signer, cfg.Provenance.KeyID)
+	if err != nil {
+		slog.Error("Provenance signing failed", "error", err)
+		return stmt
+	}
+	data, err = json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		slog.Error("Provenance signing failed", "error", err)
+		return stmt
+	}
+	path = filepath.Join(cfg.Output.Directory, envelopeFilename(filename))
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		slog.Error("Failed to write provenance envelope", "path", path, "error", err)
+		return stmt
+	}
+	slog.Info("Provenance envelope signed", "path", path, "key_id", envelope.Signatures[0].KeyID)
+	return stmt
+}
+
+// runVerify implements `producer verify -key <public.pem> <manifest.dsse.json>`
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	keyPath := fs.String("key", "", "PEM public key or certificate of the signer")
+	dir := fs.String("dir", "", "Directory file names are relative to (default: the envelope's directory)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: producer verify -key <public.pem> [-dir <output>] <manifest.dsse.json>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *keyPath == "" {
+		fs.Usage()
+		return 2
+	}
+
+	pub, err := provenance.LoadPublicKey(*keyPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var envelope provenance.Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to decode envelope: %v\n", err)
+		return 1
+	}
+
+	stmt, err := envelope.Verify(pub)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Signature OK: run %s, %d files\n", stmt.Predicate.RunID, len(stmt.Subject))
+
+	base := *dir
+	if base == "" {
+		base = filepath.Dir(fs.Arg(0))
+	}
+	mismatches := stmt.CheckSubjects(base)
+	for _, m := range mismatches {
+		fmt.Printf("  MISMATCH %s: %s\n", m.Name, m.Reason)
+	}
+	if len(mismatches) > 0 {
+		return 1
+	}
+	fmt.Println("All file digests match")
+	return 0
+}
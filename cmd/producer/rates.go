@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/generator"
+	"github.com/supratick/message_producer/internal/models"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// startRateWalk publishes simulated currency rates to the rates topic and has
+// producer reference them. The returned closer stops the walk and flushes the
+// rates; call it after generation has finished.
+func startRateWalk(ctx context.Context, cfg config.KafkaConfig, seed int64, rates []models.CurrencyRate, producer *generator.Producer, logger *slog.Logger) (func() error, error) {
+	topic := firstNonEmpty(cfg.Rates.Topic, cfg.Topic+"-rates")
+	interval := time.Duration(cfg.Rates.Interval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	volatility := cfg.Rates.Volatility
+	if volatility == 0 {
+		volatility = 0.001
+	}
+
+	rateWriter, err := writer.NewKafkaRecordWriter(cfg.Brokers, topic, kafkaAuth(cfg), logger)
+	if err != nil {
+		return nil, err
+	}
+	walk := generator.NewRateWalk(rates, interval, volatility, seed, time.Now(), func(rate models.CurrencyRate) {
+		rateWriter.Publish(rate.CurrencyFrom+"/"+rate.CurrencyTo, rate)
+	})
+	producer.SetRateWalk(walk)
+
+	walkCtx, stop := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		walk.Run(walkCtx)
+	}()
+	logger.Info("Currency rate simulation enabled",
+		"topic", topic,
+		"pairs", len(rates),
+		"interval", interval,
+		"volatility", volatility,
+	)
+
+	return func() error {
+		stop()
+		<-stopped
+		err := rateWriter.Close()
+		logger.Info("Currency rates published", "rates", rateWriter.Count(), "errors", rateWriter.Errors())
+		return err
+	}, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// regionRoutes creates a Kafka writer for every routed region, in region
+// order. Routes default to the main brokers and topic.
+func regionRoutes(cfg config.KafkaConfig, newWriter func(brokers []string, topic string) (*writer.KafkaWriter, error)) ([]writer.RegionRoute, error) {
+	var routes []writer.RegionRoute
+	for _, region := range slices.Sorted(maps.Keys(cfg.Regions.Routes)) {
+		route := cfg.Regions.Routes[region]
+		brokers := route.Brokers
+		if len(brokers) == 0 {
+			brokers = cfg.Brokers
+		}
+		w, err := newWriter(brokers, firstNonEmpty(route.Topic, cfg.Topic))
+		if err != nil {
+			for _, r := range routes {
+				r.Writer.CloseContext(context.Background())
+			}
+			return nil, fmt.Errorf("region %s: %w", region, err)
+		}
+		routes = append(routes, writer.RegionRoute{Region: region, Writer: w})
+	}
+	return routes, nil
+}
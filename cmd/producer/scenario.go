@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/generator"
+	"github.com/supratick/message_producer/internal/pipeline"
+	"github.com/supratick/message_producer/internal/scenario"
+)
+
+// applyScenario overlays the bundled scenario on cfg. Environment overrides
+// still win so a scenario can be tweaked without editing it.
+func applyScenario(cfg *config.Config, name string) error {
+	s, err := scenario.Get(name)
+	if err != nil {
+		return err
+	}
+	if err := cfg.ApplyOverlay(s.Overlay); err != nil {
+		return err
+	}
+	cfg.Scenario.Name = name
+	cfg.ApplyEnvOverrides()
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration with scenario %s: %w", name, err)
+	}
+	return nil
+}
+
+// printScenarios lists the bundled scenarios
+func printScenarios() {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, s := range scenario.List() {
+		fmt.Fprintf(tw, "%s\t%s\n", s.Name, s.Description)
+	}
+	tw.Flush()
+}
+
+// scenarioProfile converts the scenario distributions and anomalies into a
+// generator profile, or nil when the scenario leaves generation uniform
+func scenarioProfile(sc config.ScenarioConfig) *generator.Profile {
+	if len(sc.VendorWeights) == 0 && len(sc.CurrencyWeights) == 0 &&
+		len(sc.VendorOutages) == 0 && len(sc.CurrencyShocks) == 0 && len(sc.CategoryWindows) == 0 {
+		return nil
+	}
+
+	profile := &generator.Profile{
+		VendorWeights:   sc.VendorWeights,
+		CurrencyWeights: sc.CurrencyWeights,
+	}
+	for _, o := range sc.VendorOutages {
+		profile.VendorOutages = append(profile.VendorOutages, generator.VendorOutage{
+			Vendor:   o.Vendor,
+			Start:    time.Duration(o.Start) * time.Second,
+			Duration: time.Duration(o.Duration) * time.Second,
+			Backfill: o.Backfill,
+		})
+	}
+	for _, s := range sc.CurrencyShocks {
+		profile.CurrencyShocks = append(profile.CurrencyShocks, generator.CurrencyShock{
+			Currency:   s.Currency,
+			Start:      time.Duration(s.Start) * time.Second,
+			Duration:   time.Duration(s.Duration) * time.Second,
+			Multiplier: s.Multiplier,
+			Volatility: s.Volatility,
+		})
+	}
+	for _, w := range sc.CategoryWindows {
+		// Validate has checked the times and the timezone
+		open, _ := config.ParseClock(w.Open)
+		closing, _ := config.ParseClock(w.Close)
+		multiplier := w.Multiplier
+		if multiplier == 0 {
+			multiplier = 1
+		}
+		profile.CategoryWindows = append(profile.CategoryWindows, generator.CategoryWindow{
+			Category:   w.Category,
+			Open:       open,
+			Close:      closing,
+			Multiplier: multiplier,
+		})
+	}
+	if sc.CategoryTimezone != "" {
+		profile.Location, _ = time.LoadLocation(sc.CategoryTimezone)
+	}
+	return profile
+}
+
+// scenarioTemplates converts the scenario record templates for the generator
+func scenarioTemplates(templates []config.TemplateConfig) []generator.Template {
+	out := make([]generator.Template, len(templates))
+	for i, t := range templates {
+		out[i] = generator.Template{
+			Name:           t.Name,
+			Weight:         t.Weight,
+			Categories:     t.Categories,
+			Vendors:        t.Vendors,
+			Currencies:     t.Currencies,
+			BetMin:         t.BetMin,
+			BetMax:         t.BetMax,
+			WinMultipliers: t.WinMultipliers,
+			Fields:         t.Fields,
+		}
+	}
+	return out
+}
+
+// scenarioSpikes converts the scenario rate spikes for pipeline.Pace
+func scenarioSpikes(spikes []config.SpikeConfig) []pipeline.Spike {
+	out := make([]pipeline.Spike, len(spikes))
+	for i, s := range spikes {
+		out[i] = pipeline.Spike{
+			Start:      time.Duration(s.Start) * time.Second,
+			Duration:   time.Duration(s.Duration) * time.Second,
+			Multiplier: s.Multiplier,
+		}
+	}
+	return out
+}
+
+// scenarioArrivals builds the arrival process or jitter that spaces paced
+// transactions, drawn from the scenario seed so a seeded run repeats its
+// arrival times; nil keeps arrivals evenly spaced
+func scenarioArrivals(sc config.ScenarioConfig) pipeline.Jitter {
+	seed := sc.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+	seconds := func(s, fallback float64) time.Duration {
+		if s == 0 {
+			s = fallback
+		}
+		return time.Duration(s * float64(time.Second))
+	}
+
+	switch arrivals := sc.Arrivals; arrivals.Process {
+	case "poisson":
+		return pipeline.ExponentialJitter(rng)
+	case "mmpp":
+		states := []pipeline.ArrivalState{
+			{Multiplier: 1, Duration: time.Minute},
+			{Multiplier: 4, Duration: 10 * time.Second},
+		}
+		if len(arrivals.States) > 0 {
+			states = make([]pipeline.ArrivalState, len(arrivals.States))
+			for i, s := range arrivals.States {
+				states[i] = pipeline.ArrivalState{Multiplier: s.Multiplier, Duration: seconds(s.Duration, 0)}
+			}
+		}
+		return pipeline.MMPPArrivals(rng, states)
+	case "bursty":
+		hurst := arrivals.Hurst
+		if hurst == 0 {
+			hurst = 0.8
+		}
+		return pipeline.BurstyArrivals(rng, hurst, seconds(arrivals.On, 1), seconds(arrivals.Off, 1))
+	}
+
+	switch sc.Jitter.Distribution {
+	case "exponential":
+		return pipeline.ExponentialJitter(rng)
+	case "uniform":
+		spread := sc.Jitter.Spread
+		if spread == 0 {
+			spread = 0.5
+		}
+		return pipeline.UniformJitter(rng, spread)
+	default:
+		return nil
+	}
+}
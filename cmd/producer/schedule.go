@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/schedule"
+)
+
+// runSchedule implements `producer schedule [-config config.yaml]`: the
+// process stays resident and starts each job in schedule.jobs on its cron
+// expression as a child producer run
+func runSchedule(args []string) int {
+	fs := flag.NewFlagSet("schedule", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "Configuration file holding schedule.jobs")
+	logLevel := fs.String("log-level", "info", "Log level for the scheduler and its jobs")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: producer schedule [-config config.yaml] [-log-level info]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		level = slog.LevelInfo
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		logger.Error("Failed to load configuration", "error", err, "config_path", *configPath)
+		return 1
+	}
+	if len(cfg.Schedule.Jobs) == 0 {
+		logger.Error("No jobs in schedule.jobs", "config_path", *configPath)
+		return 1
+	}
+
+	location := time.Local
+	if cfg.Schedule.Timezone != "" {
+		if location, err = time.LoadLocation(cfg.Schedule.Timezone); err != nil {
+			logger.Error("Invalid schedule timezone", "error", err)
+			return 1
+		}
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		logger.Error("Failed to locate producer binary", "error", err)
+		return 1
+	}
+
+	jobs := make([]schedule.Job, 0, len(cfg.Schedule.Jobs))
+	for _, j := range cfg.Schedule.Jobs {
+		cron, err := schedule.ParseCron(j.Cron)
+		if err != nil {
+			logger.Error("Invalid schedule job", "job", j.Name, "error", err)
+			return 1
+		}
+
+		jobConfig := j.Config
+		if jobConfig == "" {
+			jobConfig = *configPath
+		}
+		jobArgs := []string{"-config", jobConfig, "-log-level", *logLevel}
+		if j.Scenario != "" {
+			jobArgs = append(jobArgs, "-scenario", j.Scenario)
+		}
+		for name, value := range j.Params {
+			jobArgs = append(jobArgs, "-param", name+"="+value)
+		}
+		var env []string
+		for k, v := range j.Env {
+			env = append(env, k+"="+v)
+		}
+
+		jobs = append(jobs, schedule.Job{
+			Name:         j.Name,
+			Cron:         cron,
+			Args:         jobArgs,
+			Env:          env,
+			AllowOverlap: j.AllowOverlap,
+		})
+	}
+
+	grace := time.Duration(cfg.Schedule.GracePeriod) * time.Second
+	if grace <= 0 {
+		grace = time.Minute
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("Scheduler started", "jobs", len(jobs), "timezone", location.String())
+	schedule.NewScheduler(binary, jobs, location, grace, logger).Run(ctx)
+	logger.Info("Scheduler stopped")
+	return 0
+}
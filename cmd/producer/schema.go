@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/supratick/message_producer/internal/codec"
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/models"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// schemaFormats lists the formats `producer schema export` prints
+var schemaFormats = []string{"avro", "jsonschema", "parquet", "proto"}
+
+// runSchema implements `producer schema export [-format avro|jsonschema|parquet|proto] [-config <file>] [-o <out>]`
+func runSchema(args []string) int {
+	usage := "Usage: producer schema export [-format " + strings.Join(schemaFormats, "|") + "] [-config <file>] [-o <out>]"
+	if len(args) == 0 || args[0] != "export" {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	fs := flag.NewFlagSet("schema export", flag.ExitOnError)
+	format := fs.String("format", "jsonschema", "Schema format: "+strings.Join(schemaFormats, ", "))
+	configPath := fs.String("config", "", "Configuration file whose output settings shape the schema (parquet dictionary columns)")
+	output := fs.String("o", "-", "Output path, - for stdout")
+	params := paramFlags{}
+	fs.Var(params, "param", "Config template parameter name=value (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args[1:])
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var cfg *config.Config
+	if *configPath != "" {
+		var err error
+		if cfg, err = config.LoadTemplate(*configPath, params); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	schema, err := exportSchema(*format, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *output == "-" {
+		os.Stdout.Write(schema)
+		return 0
+	}
+	if err := os.WriteFile(*output, schema, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// exportSchema renders the Transaction schema in format. The schemas come
+// from the generated serializers, so they match what the producer emits;
+// cfg, when set, applies the output settings that change the Parquet schema.
+func exportSchema(format string, cfg *config.Config) ([]byte, error) {
+	var schema bytes.Buffer
+	switch format {
+	case "avro":
+		if err := json.Indent(&schema, []byte(models.TransactionAvroSchema), "", "  "); err != nil {
+			return nil, err
+		}
+		schema.WriteByte('\n')
+	case "jsonschema":
+		if err := json.Indent(&schema, []byte(models.TransactionJSONSchema), "", "  "); err != nil {
+			return nil, err
+		}
+		schema.WriteByte('\n')
+	case "proto":
+		schema.WriteString(models.TransactionProtoSchema)
+	case "parquet":
+		var dictionaryColumns []string
+		if cfg != nil {
+			dictionaryColumns = cfg.Output.Parquet.DictionaryColumns
+		}
+		text, err := writer.ParquetSchema(dictionaryColumns)
+		if err != nil {
+			return nil, err
+		}
+		schema.WriteString(text)
+		if !strings.HasSuffix(text, "\n") {
+			schema.WriteByte('\n')
+		}
+	default:
+		return nil, fmt.Errorf("unknown schema format %q (have %s)", format, strings.Join(schemaFormats, ", "))
+	}
+	return schema.Bytes(), nil
+}
+
+// validatePayloads wraps format so every message it encodes is checked
+// against the Transaction JSON Schema. Violations are logged, the first ten
+// individually; in fatal mode the first one fails the run and stops it
+// through stop. The returned report logs the total once the run is over and,
+// in fatal mode, returns an error when any message was invalid.
+func validatePayloads(format codec.Format, mode string, failure *runFailure, stop func(), logger *slog.Logger) (codec.Format, func() error, error) {
+	schema, err := codec.ParseJSONSchema(models.TransactionJSONSchema)
+	if err != nil {
+		return format, nil, err
+	}
+	fatal := mode == "fatal"
+
+	var invalid atomic.Int64
+	format = codec.Validated(format, schema.Validate, func(err error) {
+		if invalid.Add(1) <= maxLoggedViolations {
+			logger.Error("Kafka payload does not match the JSON Schema", "error", err)
+		}
+		if fatal {
+			failure.set(fmt.Errorf("payload validation: %w", err))
+			stop()
+		}
+	})
+	logger.Info("Kafka payload validation enabled", "mode", mode)
+
+	return format, func() error {
+		n := invalid.Load()
+		if n == 0 {
+			logger.Info("Kafka payloads matched the JSON Schema")
+			return nil
+		}
+		logger.Warn("Kafka payloads did not match the JSON Schema", "invalid", n)
+		if fatal {
+			return fmt.Errorf("%d payloads did not match the JSON Schema", n)
+		}
+		return nil
+	}, nil
+}
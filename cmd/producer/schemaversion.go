@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// applySchemaVersion suffixes the transaction topic and output filenames
+// with the configured schema version, so every later user of cfg (subjects,
+// probes, checks) sees the versioned names
+func applySchemaVersion(cfg *config.Config) {
+	sv := cfg.SchemaVersion
+	if sv.SuffixTopic {
+		cfg.Kafka.Topic = fmt.Sprintf("%s.v%d", cfg.Kafka.Topic, sv.Version)
+	}
+	if sv.SuffixFiles {
+		cfg.Output.CSV.Filename = writer.VersionFilename(cfg.Output.CSV.Filename, sv.Version)
+		cfg.Output.Parquet.Filename = writer.VersionFilename(cfg.Output.Parquet.Filename, sv.Version)
+		cfg.Output.JSONL.Filename = writer.VersionFilename(cfg.Output.JSONL.Filename, sv.Version)
+		cfg.Output.Msgpack.Filename = writer.VersionFilename(cfg.Output.Msgpack.Filename, sv.Version)
+		cfg.Output.Render.Filename = writer.VersionFilename(cfg.Output.Render.Filename, sv.Version)
+		cfg.Output.Arrow.Filename = writer.VersionFilename(cfg.Output.Arrow.Filename, sv.Version)
+		cfg.Output.FixedWidth.Filename = writer.VersionFilename(cfg.Output.FixedWidth.Filename, sv.Version)
+		cfg.Output.XML.Filename = writer.VersionFilename(cfg.Output.XML.Filename, sv.Version)
+		cfg.Output.XLSX.Filename = writer.VersionFilename(cfg.Output.XLSX.Filename, sv.Version)
+		cfg.Output.DuckDB.Filename = writer.VersionFilename(cfg.Output.DuckDB.Filename, sv.Version)
+		cfg.Output.SQLite.Filename = writer.VersionFilename(cfg.Output.SQLite.Filename, sv.Version)
+	}
+}
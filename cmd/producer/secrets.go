@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/supratick/message_producer/internal/awsauth"
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/secrets"
+)
+
+// resolveSecrets replaces vault: and aws-sm: references in cfg with the
+// secret values. Backends are only contacted when references exist; with
+// secrets.renew set the Vault token and leases are kept alive until ctx ends.
+func resolveSecrets(ctx context.Context, cfg *config.Config, logger *slog.Logger) error {
+	vaultRefs, awsRefs := countReferences(reflect.ValueOf(cfg))
+	if vaultRefs == 0 && awsRefs == 0 {
+		return nil
+	}
+
+	timeout := time.Duration(cfg.Secrets.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	var vault *secrets.VaultClient
+	if vaultRefs > 0 {
+		v := cfg.Secrets.Vault
+		address := firstNonEmpty(v.Address, os.Getenv("VAULT_ADDR"))
+		if address == "" {
+			return fmt.Errorf("vault references found but no vault address is configured (secrets.vault.address or VAULT_ADDR)")
+		}
+		token := os.Getenv("VAULT_TOKEN")
+		if v.TokenFile != "" {
+			data, err := os.ReadFile(v.TokenFile)
+			if err != nil {
+				return fmt.Errorf("failed to read vault token file: %w", err)
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		if token == "" {
+			return fmt.Errorf("vault references found but no token is configured (secrets.vault.token_file or VAULT_TOKEN)")
+		}
+		vault = secrets.NewVaultClient(address, token, firstNonEmpty(v.Namespace, os.Getenv("VAULT_NAMESPACE")), timeout)
+	}
+
+	var aws *secrets.AWSClient
+	if awsRefs > 0 {
+		region := firstNonEmpty(cfg.Secrets.AWS.Region, os.Getenv("AWS_REGION"))
+		if region == "" {
+			return fmt.Errorf("aws-sm references found but no region is configured (secrets.aws.region or AWS_REGION)")
+		}
+		var err error
+		aws, err = secrets.NewAWSClient(region, cfg.Secrets.AWS.Endpoint, awsauth.FromEnv(), timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	resolver := secrets.NewResolver(vault, aws, logger)
+	n, err := resolver.ResolveAll(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	logger.Info("Secrets resolved", "count", n, "vault", vaultRefs, "aws_secrets_manager", awsRefs)
+
+	if cfg.Secrets.Renew && vault != nil {
+		go resolver.KeepAlive(ctx)
+	}
+	return nil
+}
+
+// countReferences counts the vault: and aws-sm: string values reachable
+// from v
+func countReferences(v reflect.Value) (vault, aws int) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			return countReferences(v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				a, b := countReferences(v.Field(i))
+				vault, aws = vault+a, aws+b
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			a, b := countReferences(v.Index(i))
+			vault, aws = vault+a, aws+b
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			a, b := countReferences(v.MapIndex(k))
+			vault, aws = vault+a, aws+b
+		}
+	case reflect.String:
+		switch {
+		case strings.HasPrefix(v.String(), secrets.VaultPrefix):
+			vault++
+		case strings.HasPrefix(v.String(), secrets.AWSPrefix):
+			aws++
+		}
+	}
+	return vault, aws
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,821 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/generator"
+	"github.com/supratick/message_producer/internal/metrics"
+	"github.com/supratick/message_producer/internal/models"
+	"github.com/supratick/message_producer/internal/pipeline"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// namedCloser is a writer closed at the end of the run, within the deadline
+// of the context it is given
+type namedCloser struct {
+	name   string
+	closer func(ctx context.Context) error
+}
+
+// closeWithin adapts a closer that takes no context; it is abandoned once
+// the context is done
+func closeWithin(close func() error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return writer.CloseWithin(ctx, close)
+	}
+}
+
+// sinkEnv is the run state sink factories build their outputs from
+type sinkEnv struct {
+	ctx      context.Context // the sink phase's, cancelled only after the drain timeout
+	stop     func()          // stops the run, draining what was generated
+	cfg      *config.Config
+	refData  *models.ReferenceData
+	producer *generator.Producer
+	monitor  *metrics.Monitor
+	fileVars writer.FilenameVars
+	seal     func(path string, rows int64) error
+	doneCh   chan struct{}
+	failure  *runFailure
+	logger   *slog.Logger
+
+	// Set by the Kafka sink for the end of the run
+	latencyProbe    func()       // reports probe latency once writers are closed
+	partitionReport func()       // reports partition spread once writers are closed
+	reportPayloads  func() error // fails the run on invalid payloads
+}
+
+// openedSink is a sink a factory created and how the pipeline runs it
+type openedSink struct {
+	sink    writer.Sink
+	label   string          // writer name in the close log, e.g. "CSV"
+	stage   pipeline.Writer // what the stage feeds, the sink itself when nil
+	file    bool            // file outputs run behind output.dedup
+	batch   bool            // takes its limits from the batching settings
+	errors  bool            // reports <name>_errors next to its count
+	done    func()          // reports the final counts instead of the default
+	closers []namedCloser   // side writers, closed after the sink
+}
+
+// sinkFactory opens the sink registered under a name
+type sinkFactory func(env *sinkEnv) (*openedSink, error)
+
+// sinkFactories are the registered sinks by name
+var sinkFactories = make(map[string]sinkFactory)
+
+// registerSink adds a sink that output.sinks can name
+func registerSink(name string, f sinkFactory) {
+	sinkFactories[name] = f
+}
+
+// lookupSink returns the factory registered under name
+func lookupSink(name string) (sinkFactory, error) {
+	f, ok := sinkFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown sink %q (available: %s)", name, strings.Join(sinkNames(), ", "))
+	}
+	return f, nil
+}
+
+// sinkNames returns the registered sink names, sorted
+func sinkNames() []string {
+	names := make([]string, 0, len(sinkFactories))
+	for name := range sinkFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	registerSink("csv", openCSVSink)
+	registerSink("parquet", openParquetSink)
+	registerSink("jsonl", openJSONLSink)
+	registerSink("msgpack", openMsgpackSink)
+	registerSink("render", openRenderSink)
+	registerSink("arrow", openArrowSink)
+	registerSink("fixed_width", openFixedWidthSink)
+	registerSink("xml", openXMLSink)
+	registerSink("xlsx", openXLSXSink)
+	registerSink("duckdb", openDuckDBSink)
+	registerSink("sqlite", openSQLiteSink)
+	registerSink("kafka", openKafkaSink)
+	registerSink("socket", openSocketSink)
+	registerSink("fluent", openFluentSink)
+	registerSink("syslog", openSyslogSink)
+	registerSink("fifo", openFIFOSink)
+	registerSink("snowflake", openSnowflakeSink)
+	registerSink("postgres", openPostgresSink)
+	registerSink("clickhouse", openClickHouseSink)
+}
+
+// sinkErrorPolicy applies output.on_sink_error to sinks failing mid-run
+type sinkErrorPolicy struct {
+	cfg     config.SinkErrorConfig
+	failure *runFailure
+	stop    func()
+	aborted runFailure // the sink error that stopped the run, if one did
+	failed  runFailure // the first sink error the run carried on past
+	logger  *slog.Logger
+}
+
+// handle is the pipeline's error handler. The pipeline has already logged
+// the error and dropped the sink; this decides what happens to the run. A
+// sink under failover only gets here once its standby failed as well.
+func (p *sinkErrorPolicy) handle(err *pipeline.StageError) {
+	switch p.cfg.For(err.Stage) {
+	case config.SinkErrorIgnore:
+		p.logger.Info("Sink failure ignored", "sink", err.Stage)
+	case config.SinkErrorAbort:
+		p.logger.Error("Stopping run after sink failure", "sink", err.Stage)
+		p.failure.set(err)
+		p.aborted.set(err)
+		p.stop()
+	default:
+		p.failure.set(err)
+		p.failed.set(err)
+	}
+}
+
+// startSinks opens the configured sinks in order and starts a pipeline
+// stage for each. It returns the writers to close at the end of the run.
+func startSinks(env *sinkEnv, pipe *pipeline.Pipeline, dedup *fileDedup) ([]namedCloser, error) {
+	// Reject unknown names before any sink connects
+	names := env.cfg.SinkNames()
+	factories := make([]sinkFactory, len(names))
+	for i, name := range names {
+		f, err := lookupSink(name)
+		if err != nil {
+			return nil, err
+		}
+		factories[i] = f
+	}
+
+	var closers []namedCloser
+	for i, name := range names {
+		open := factories[i]
+		if env.cfg.Output.Spool.Spools(name) {
+			open = spooled(name, open)
+		}
+		s, err := open(env)
+		if err != nil {
+			return nil, err
+		}
+		closers = append(closers, namedCloser{s.label, s.sink.CloseContext})
+		closers = append(closers, s.closers...)
+
+		env.monitor.Track(name, s.sink.Count)
+		if s.errors {
+			env.monitor.TrackErrors(s.sink.Errors)
+		}
+		stage := sinkStage(env, name, s, dedup)
+		if standby := env.cfg.Output.OnSinkError.Standby[name]; standby != "" && env.cfg.Output.OnSinkError.For(name) == config.SinkErrorFailover {
+			sb := &standbySink{name: standby}
+			stage.Standby = sb.opener(env, dedup)
+			closers = append(closers, namedCloser{standby + " standby", sb.close})
+		}
+		pipe.Start(env.ctx, stage)
+	}
+	return closers, nil
+}
+
+// sinkStage is the pipeline stage that feeds an opened sink
+func sinkStage(env *sinkEnv, name string, s *openedSink, dedup *fileDedup) pipeline.Stage {
+	stage := pipeline.Stage{Name: name, Writer: s.stage, Done: s.done}
+	if stage.Writer == nil {
+		stage.Writer = s.sink
+	}
+	if s.file {
+		stage.Writer = dedup.wrap(name, stage.Writer, s.sink.Count)
+	}
+	if s.batch {
+		stage.Batch = sinkBatching(env.cfg.Batching, name)
+	}
+	if stage.Done == nil {
+		stage.Done = func() {
+			env.monitor.IncrementSink(name, s.sink.Count())
+			if s.errors {
+				env.monitor.IncrementSink(name+"_errors", s.sink.Errors())
+			}
+		}
+	}
+	return stage
+}
+
+// standbySink is the sink on_sink_error failover opens in place of a failed
+// one. It is only opened if that sink fails, and closed at the end of the
+// run with the others.
+type standbySink struct {
+	name   string
+	mu     sync.Mutex
+	opened *openedSink
+}
+
+// opener returns the pipeline's Standby hook, which opens the sink and
+// returns its stage
+func (sb *standbySink) opener(env *sinkEnv, dedup *fileDedup) func() (*pipeline.Stage, error) {
+	return func() (*pipeline.Stage, error) {
+		open, err := lookupSink(sb.name)
+		if err != nil {
+			return nil, err
+		}
+		if env.cfg.Output.Spool.Spools(sb.name) {
+			open = spooled(sb.name, open)
+		}
+		s, err := open(env)
+		if err != nil {
+			return nil, err
+		}
+		sb.mu.Lock()
+		sb.opened = s
+		sb.mu.Unlock()
+
+		env.monitor.Track(sb.name, s.sink.Count)
+		if s.errors {
+			env.monitor.TrackErrors(s.sink.Errors)
+		}
+		stage := sinkStage(env, sb.name, s, dedup)
+		return &stage, nil
+	}
+}
+
+// close closes the standby and its side writers, if it was opened
+func (sb *standbySink) close(ctx context.Context) error {
+	sb.mu.Lock()
+	s := sb.opened
+	sb.mu.Unlock()
+	if s == nil {
+		return nil
+	}
+	err := s.sink.CloseContext(ctx)
+	for _, c := range s.closers {
+		if closeErr := c.closer(ctx); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// spooled puts the sink open opens behind a disk spool in its own
+// subdirectory of output.spool.directory. The sink itself is opened, and
+// reopened after failing, by the spool's forwarder.
+func spooled(name string, open sinkFactory) sinkFactory {
+	return func(env *sinkEnv) (*openedSink, error) {
+		cfg := env.cfg.Output.Spool
+		dir := filepath.Join(firstNonEmpty(cfg.Directory, "spool"), name)
+		spool, err := writer.OpenSpool(dir, int64(cfg.MaxSize), env.logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s spool: %w", name, err)
+		}
+		if backlog := spool.Backlog(); backlog > 0 {
+			env.logger.Info("Forwarding spool backlog from an earlier run", "sink", name, "backlog_bytes", backlog)
+		}
+		spooledSink := writer.NewSpooledSink(env.ctx, spool, writer.SpooledOptions{
+			Open: func() (writer.Sink, error) {
+				s, err := open(env)
+				if err != nil {
+					return nil, err
+				}
+				return s.sink, nil
+			},
+			RetryInterval: time.Duration(cfg.RetryInterval) * time.Second,
+		}, env.logger.With("sink", name))
+
+		slog.Info("Spool initialized",
+			"sink", name,
+			"directory", dir,
+			"max_size", int64(cfg.MaxSize),
+		)
+		return &openedSink{sink: spooledSink, label: name + " spool", errors: true}, nil
+	}
+}
+
+func openCSVSink(env *sinkEnv) (*openedSink, error) {
+	cfg, logger := env.cfg, env.logger
+	csvFormat := writer.CSVFormat{
+		Quoting:       cfg.Output.CSV.Quoting,
+		DecimalPlaces: cfg.Output.CSV.DecimalPlaces,
+		Append:        cfg.Output.CSV.Append,
+	}
+	csvFilename := env.fileVars.Expand(cfg.Output.CSV.Filename)
+	csvWriter, err := newFileOutput(fileOutput{
+		destinations: cfg.Output.CSV.Destinations,
+		directory:    cfg.Output.Directory,
+		filename:     csvFilename,
+		shards:       int(cfg.Output.CSV.Shards),
+		shardKey:     cfg.Output.CSV.ShardKey,
+		open: func(dir, filename string) (writer.FileWriter, error) {
+			return writer.NewCSVWriter(dir, filename, cfg.Output.CSV.BufferSize, csvFormat, logger)
+		},
+		seal: env.seal,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CSV writer: %w", err)
+	}
+
+	slog.Info("CSV writer initialized",
+		"directory", cfg.Output.Directory,
+		"filename", csvFilename,
+		"quoting", cfg.Output.CSV.Quoting,
+		"append", cfg.Output.CSV.Append,
+		"shards", max(int(cfg.Output.CSV.Shards), 1),
+		"shard_key", cfg.Output.CSV.ShardKey,
+		"destinations", len(cfg.Output.CSV.Destinations),
+	)
+	return &openedSink{
+		sink:  writer.FileSink(csvWriter),
+		label: "CSV",
+		file:  true,
+		done: func() {
+			env.monitor.IncrementCSV(csvWriter.Count())
+		},
+	}, nil
+}
+
+func openParquetSink(env *sinkEnv) (*openedSink, error) {
+	cfg, logger := env.cfg, env.logger
+	tuning := writer.ParquetTuning{
+		PageSize:           cfg.Output.Parquet.PageSize,
+		DictionaryColumns:  cfg.Output.Parquet.DictionaryColumns,
+		BloomFilterColumns: cfg.Output.Parquet.BloomFilterColumns,
+		BloomFilterBits:    cfg.Output.Parquet.BloomFilterBits,
+		SortColumn:         cfg.Output.Parquet.SortColumn,
+		SortDescending:     cfg.Output.Parquet.SortDescending,
+		PageStatistics:     cfg.Output.Parquet.PageStatistics,
+		CompressionLevel:   cfg.Output.Parquet.CompressionLevel,
+	}
+	parquetFilename := env.fileVars.Expand(cfg.Output.Parquet.Filename)
+	parquetWriter, err := newFileOutput(fileOutput{
+		destinations: cfg.Output.Parquet.Destinations,
+		directory:    cfg.Output.Directory,
+		filename:     parquetFilename,
+		shards:       int(cfg.Output.Parquet.Shards),
+		shardKey:     cfg.Output.Parquet.ShardKey,
+		open: func(dir, filename string) (writer.FileWriter, error) {
+			if cfg.Output.Parquet.Engine == "arrow" {
+				return writer.NewArrowParquetWriter(dir, filename, cfg.Output.Parquet.RowGroupSize, cfg.Output.Parquet.Compression, tuning, logger)
+			}
+			return writer.NewParquetWriter(dir, filename, cfg.Output.Parquet.RowGroupSize, cfg.Output.Parquet.Compression, tuning, logger)
+		},
+		seal: env.seal,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Parquet writer: %w", err)
+	}
+
+	slog.Info("Parquet writer initialized",
+		"directory", cfg.Output.Directory,
+		"filename", parquetFilename,
+		"compression", cfg.Output.Parquet.Compression,
+		"engine", cfg.Output.Parquet.Engine,
+		"dictionary_columns", cfg.Output.Parquet.DictionaryColumns,
+		"bloom_filter_columns", cfg.Output.Parquet.BloomFilterColumns,
+		"sort_column", cfg.Output.Parquet.SortColumn,
+		"shards", max(int(cfg.Output.Parquet.Shards), 1),
+		"shard_key", cfg.Output.Parquet.ShardKey,
+		"destinations", len(cfg.Output.Parquet.Destinations),
+	)
+	return &openedSink{
+		sink:  writer.FileSink(parquetWriter),
+		label: "Parquet",
+		file:  true,
+		done: func() {
+			env.monitor.IncrementParquet(parquetWriter.Count())
+		},
+	}, nil
+}
+
+func openJSONLSink(env *sinkEnv) (*openedSink, error) {
+	cfg, logger := env.cfg.Output, env.logger
+	jsonlFilename := env.fileVars.Expand(cfg.JSONL.Filename)
+	if cfg.JSONL.Gzip && !strings.HasSuffix(jsonlFilename, ".gz") {
+		jsonlFilename += ".gz"
+	}
+	jsonlWriter, err := newFileOutput(fileOutput{
+		destinations: cfg.JSONL.Destinations,
+		directory:    cfg.Directory,
+		filename:     jsonlFilename,
+		shards:       cfg.JSONL.Shards,
+		shardKey:     cfg.JSONL.ShardKey,
+		open: func(dir, filename string) (writer.FileWriter, error) {
+			return writer.NewJSONLWriter(dir, filename, cfg.JSONL.BufferSize, cfg.JSONL.Gzip, logger)
+		},
+		seal: env.seal,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JSONL writer: %w", err)
+	}
+
+	slog.Info("JSONL writer initialized",
+		"directory", cfg.Directory,
+		"filename", jsonlFilename,
+		"gzip", cfg.JSONL.Gzip,
+		"shards", max(cfg.JSONL.Shards, 1),
+		"shard_key", cfg.JSONL.ShardKey,
+		"destinations", len(cfg.JSONL.Destinations),
+	)
+	return &openedSink{sink: writer.FileSink(jsonlWriter), label: "JSONL", file: true}, nil
+}
+
+func openMsgpackSink(env *sinkEnv) (*openedSink, error) {
+	cfg, logger := env.cfg.Output, env.logger
+	msgpackFilename := env.fileVars.Expand(cfg.Msgpack.Filename)
+	if cfg.Msgpack.Gzip && !strings.HasSuffix(msgpackFilename, ".gz") {
+		msgpackFilename += ".gz"
+	}
+	msgpackWriter, err := newFileOutput(fileOutput{
+		destinations: cfg.Msgpack.Destinations,
+		directory:    cfg.Directory,
+		filename:     msgpackFilename,
+		shards:       cfg.Msgpack.Shards,
+		shardKey:     cfg.Msgpack.ShardKey,
+		open: func(dir, filename string) (writer.FileWriter, error) {
+			return writer.NewMsgpackWriter(dir, filename, cfg.Msgpack.BufferSize, cfg.Msgpack.Gzip, logger)
+		},
+		seal: env.seal,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MessagePack writer: %w", err)
+	}
+
+	slog.Info("MessagePack writer initialized",
+		"directory", cfg.Directory,
+		"filename", msgpackFilename,
+		"gzip", cfg.Msgpack.Gzip,
+		"shards", max(cfg.Msgpack.Shards, 1),
+		"shard_key", cfg.Msgpack.ShardKey,
+		"destinations", len(cfg.Msgpack.Destinations),
+	)
+	return &openedSink{sink: writer.FileSink(msgpackWriter), label: "MessagePack", file: true}, nil
+}
+
+func openRenderSink(env *sinkEnv) (*openedSink, error) {
+	cfg, logger := env.cfg.Output, env.logger
+	text := cfg.Render.Template
+	if cfg.Render.TemplateFile != "" {
+		data, err := os.ReadFile(cfg.Render.TemplateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read render template: %w", err)
+		}
+		// A trailing newline in the file is the line ending's job
+		text = strings.TrimRight(string(data), "\r\n")
+	}
+	tmpl, err := writer.ParseRenderTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+	format := writer.RenderFormat{
+		Template:   tmpl,
+		Header:     env.fileVars.Expand(cfg.Render.Header),
+		LineEnding: cfg.Render.LineEnding,
+	}
+
+	renderFilename := env.fileVars.Expand(cfg.Render.Filename)
+	renderWriter, err := newFileOutput(fileOutput{
+		destinations: cfg.Render.Destinations,
+		directory:    cfg.Directory,
+		filename:     renderFilename,
+		open: func(dir, filename string) (writer.FileWriter, error) {
+			return writer.NewRenderWriter(dir, filename, cfg.Render.BufferSize, format, logger)
+		},
+		seal: env.seal,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create render writer: %w", err)
+	}
+
+	slog.Info("Render writer initialized",
+		"directory", cfg.Directory,
+		"filename", renderFilename,
+		"template_file", cfg.Render.TemplateFile,
+		"line_ending", firstNonEmpty(cfg.Render.LineEnding, writer.LineEndingLF),
+		"destinations", len(cfg.Render.Destinations),
+	)
+	return &openedSink{sink: writer.FileSink(renderWriter), label: "Render", file: true}, nil
+}
+
+func openArrowSink(env *sinkEnv) (*openedSink, error) {
+	cfg, logger := env.cfg.Output, env.logger
+	// Batches default to the Parquet row group size, so both hold the same rows
+	batchSize := cfg.Arrow.BatchSize
+	if batchSize == 0 {
+		batchSize = cfg.Parquet.RowGroupSize
+	}
+	arrowFilename := env.fileVars.Expand(cfg.Arrow.Filename)
+	arrowWriter, err := newFileOutput(fileOutput{
+		destinations: cfg.Arrow.Destinations,
+		directory:    cfg.Directory,
+		filename:     arrowFilename,
+		open: func(dir, filename string) (writer.FileWriter, error) {
+			return writer.NewArrowIPCWriter(dir, filename, batchSize, cfg.Arrow.Format, cfg.Arrow.Compression, logger)
+		},
+		seal: env.seal,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Arrow writer: %w", err)
+	}
+
+	slog.Info("Arrow writer initialized",
+		"directory", cfg.Directory,
+		"filename", arrowFilename,
+		"format", firstNonEmpty(cfg.Arrow.Format, writer.ArrowIPCFile),
+		"compression", firstNonEmpty(cfg.Arrow.Compression, "none"),
+		"batch_size", batchSize,
+		"destinations", len(cfg.Arrow.Destinations),
+	)
+	return &openedSink{sink: writer.FileSink(arrowWriter), label: "Arrow", file: true}, nil
+}
+
+func openFixedWidthSink(env *sinkEnv) (*openedSink, error) {
+	cfg, logger := env.cfg.Output, env.logger
+	format := writer.FixedWidthFormat{
+		Encoding:   cfg.FixedWidth.Encoding,
+		LineEnding: cfg.FixedWidth.LineEnding,
+		Overflow:   cfg.FixedWidth.Overflow,
+		Header:     cfg.FixedWidth.Header,
+	}
+	for _, col := range cfg.FixedWidth.Columns {
+		var pad rune // space
+		if col.Pad != "" {
+			pad, _ = utf8.DecodeRuneInString(col.Pad)
+		}
+		format.Columns = append(format.Columns, writer.FixedWidthColumn{
+			Name:            col.Name,
+			Width:           col.Width,
+			Align:           col.Align,
+			Pad:             pad,
+			ImpliedDecimals: col.ImpliedDecimals,
+			Overflow:        col.Overflow,
+		})
+	}
+
+	fixedWidthFilename := env.fileVars.Expand(cfg.FixedWidth.Filename)
+	fixedWidthWriter, err := newFileOutput(fileOutput{
+		destinations: cfg.FixedWidth.Destinations,
+		directory:    cfg.Directory,
+		filename:     fixedWidthFilename,
+		open: func(dir, filename string) (writer.FileWriter, error) {
+			return writer.NewFixedWidthWriter(dir, filename, cfg.FixedWidth.BufferSize, format, logger)
+		},
+		seal: env.seal,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fixed-width writer: %w", err)
+	}
+
+	slog.Info("Fixed-width writer initialized",
+		"directory", cfg.Directory,
+		"filename", fixedWidthFilename,
+		"columns", len(format.Columns),
+		"encoding", firstNonEmpty(cfg.FixedWidth.Encoding, writer.EncodingASCII),
+		"line_ending", firstNonEmpty(cfg.FixedWidth.LineEnding, writer.LineEndingLF),
+		"destinations", len(cfg.FixedWidth.Destinations),
+	)
+	return &openedSink{sink: writer.FileSink(fixedWidthWriter), label: "Fixed-width", file: true}, nil
+}
+
+func openXMLSink(env *sinkEnv) (*openedSink, error) {
+	cfg, logger := env.cfg.Output, env.logger
+	format := writer.XMLFormat{
+		RootElement:   cfg.XML.RootElement,
+		RecordElement: cfg.XML.RecordElement,
+		Namespace:     cfg.XML.Namespace,
+		Pretty:        cfg.XML.Pretty,
+	}
+	xmlFilename := env.fileVars.Expand(cfg.XML.Filename)
+	xmlWriter, err := newFileOutput(fileOutput{
+		destinations: cfg.XML.Destinations,
+		directory:    cfg.Directory,
+		filename:     xmlFilename,
+		open: func(dir, filename string) (writer.FileWriter, error) {
+			return writer.NewXMLWriter(dir, filename, cfg.XML.BufferSize, format, logger)
+		},
+		seal: env.seal,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create XML writer: %w", err)
+	}
+
+	slog.Info("XML writer initialized",
+		"directory", cfg.Directory,
+		"filename", xmlFilename,
+		"root_element", firstNonEmpty(cfg.XML.RootElement, "transactions"),
+		"pretty", cfg.XML.Pretty,
+		"destinations", len(cfg.XML.Destinations),
+	)
+	return &openedSink{sink: writer.FileSink(xmlWriter), label: "XML", file: true}, nil
+}
+
+func openXLSXSink(env *sinkEnv) (*openedSink, error) {
+	cfg, logger := env.cfg.Output, env.logger
+	maxRows := cfg.XLSX.MaxRows
+	if maxRows == 0 {
+		maxRows = 1000
+	}
+	xlsxFilename := env.fileVars.Expand(cfg.XLSX.Filename)
+	xlsxWriter, err := newFileOutput(fileOutput{
+		directory: cfg.Directory,
+		filename:  xlsxFilename,
+		open: func(dir, filename string) (writer.FileWriter, error) {
+			return writer.NewXLSXWriter(dir, filename, maxRows, logger)
+		},
+		seal: env.seal,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create XLSX writer: %w", err)
+	}
+
+	slog.Info("XLSX sample writer initialized",
+		"directory", cfg.Directory,
+		"filename", xlsxFilename,
+		"max_rows", maxRows,
+	)
+	// A sample, so it stays out of output.dedup's exact-count check
+	return &openedSink{sink: writer.FileSink(xlsxWriter), label: "XLSX sample"}, nil
+}
+
+func openDuckDBSink(env *sinkEnv) (*openedSink, error) {
+	cfg := env.cfg
+	duckdbFilename := writer.FileSeq(env.fileVars.Expand(cfg.Output.DuckDB.Filename), 0)
+	duckdbWriter, err := writer.NewDuckDBWriter(
+		cfg.Output.Directory,
+		duckdbFilename,
+		cfg.Output.DuckDB.Table,
+		cfg.Output.DuckDB.BatchSize,
+		env.logger,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DuckDB writer: %w", err)
+	}
+
+	slog.Info("DuckDB writer initialized",
+		"directory", cfg.Output.Directory,
+		"filename", duckdbFilename,
+		"table", cfg.Output.DuckDB.Table,
+	)
+	return &openedSink{sink: duckdbWriter, label: "DuckDB", file: true, batch: true}, nil
+}
+
+func openSQLiteSink(env *sinkEnv) (*openedSink, error) {
+	cfg := env.cfg
+	sqliteFilename := writer.FileSeq(env.fileVars.Expand(cfg.Output.SQLite.Filename), 0)
+	sqliteWriter, err := writer.NewSQLiteWriter(
+		cfg.Output.Directory,
+		sqliteFilename,
+		cfg.Output.SQLite.Table,
+		cfg.Output.SQLite.BatchSize,
+		env.logger,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SQLite writer: %w", err)
+	}
+
+	slog.Info("SQLite writer initialized",
+		"directory", cfg.Output.Directory,
+		"filename", sqliteFilename,
+		"table", cfg.Output.SQLite.Table,
+	)
+	return &openedSink{sink: sqliteWriter, label: "SQLite", file: true, batch: true}, nil
+}
+
+func openSocketSink(env *sinkEnv) (*openedSink, error) {
+	cfg := env.cfg.Socket
+	socketWriter, err := writer.NewSocketWriter(
+		cfg.Network,
+		cfg.Address,
+		cfg.Framing,
+		cfg.BufferSize,
+		time.Duration(cfg.DialTimeout)*time.Millisecond,
+		env.logger,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket writer: %w", err)
+	}
+
+	slog.Info("Socket writer initialized",
+		"network", cfg.Network,
+		"address", cfg.Address,
+		"framing", cfg.Framing,
+	)
+	return &openedSink{sink: socketWriter, label: "Socket", batch: true, errors: true}, nil
+}
+
+func openFluentSink(env *sinkEnv) (*openedSink, error) {
+	cfg := env.cfg.Fluent
+	fluentWriter, err := writer.NewFluentWriter(
+		cfg.Address,
+		cfg.Tag,
+		cfg.BatchSize,
+		time.Duration(cfg.Timeout)*time.Millisecond,
+		env.logger,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Fluentd writer: %w", err)
+	}
+
+	slog.Info("Fluentd writer initialized",
+		"address", cfg.Address,
+		"tag", cfg.Tag,
+	)
+	return &openedSink{sink: fluentWriter, label: "Fluentd", batch: true}, nil
+}
+
+func openSyslogSink(env *sinkEnv) (*openedSink, error) {
+	cfg := env.cfg.Syslog
+	syslogWriter, err := writer.NewSyslogWriter(
+		cfg.Network,
+		cfg.Address,
+		cfg.AppName,
+		time.Duration(cfg.Timeout)*time.Millisecond,
+		env.logger,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create syslog writer: %w", err)
+	}
+
+	slog.Info("Syslog writer initialized",
+		"network", cfg.Network,
+		"address", cfg.Address,
+	)
+	return &openedSink{sink: syslogWriter, label: "Syslog", batch: true, errors: true}, nil
+}
+
+func openFIFOSink(env *sinkEnv) (*openedSink, error) {
+	cfg := env.cfg.FIFO
+	fifoWriter, err := writer.NewFIFOWriter(fifoOptions(cfg), env.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create FIFO writer: %w", err)
+	}
+
+	slog.Info("FIFO writer initialized",
+		"path", cfg.Path,
+		"format", cfg.Format,
+		"no_reader", firstNonEmpty(cfg.NoReader, writer.FIFOWait),
+	)
+	return &openedSink{
+		sink:  fifoWriter,
+		label: "FIFO",
+		done: func() {
+			env.monitor.IncrementSink("fifo", fifoWriter.Count())
+			env.monitor.IncrementSink("fifo_dropped", fifoWriter.Dropped())
+		},
+	}, nil
+}
+
+func openSnowflakeSink(env *sinkEnv) (*openedSink, error) {
+	cfg := env.cfg.Snowflake
+	snowflakeWriter, err := writer.NewSnowflakeWriter(snowflakeOptions(cfg), env.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Snowflake writer: %w", err)
+	}
+
+	slog.Info("Snowflake writer initialized",
+		"account", cfg.Account,
+		"method", cfg.Method,
+		"warehouse", cfg.Warehouse,
+		"table", cfg.Database+"."+cfg.Schema+"."+cfg.Table,
+	)
+	return &openedSink{sink: snowflakeWriter, label: "Snowflake", batch: true, errors: true}, nil
+}
+
+func openPostgresSink(env *sinkEnv) (*openedSink, error) {
+	cfg := env.cfg.Postgres
+	postgresWriter, err := writer.NewPostgresWriter(env.ctx, postgresOptions(cfg), env.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Postgres writer: %w", err)
+	}
+
+	slog.Info("Postgres writer initialized",
+		"host", cfg.Host,
+		"table", cfg.Database+"."+cfg.Table,
+		"batch_size", cfg.BatchSize,
+		"pool_size", cfg.PoolSize,
+	)
+	return &openedSink{sink: postgresWriter, label: "Postgres", batch: true, errors: true}, nil
+}
+
+func openClickHouseSink(env *sinkEnv) (*openedSink, error) {
+	cfg := env.cfg.ClickHouse
+	clickHouseWriter, err := writer.NewClickHouseWriter(clickHouseOptions(cfg), env.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ClickHouse writer: %w", err)
+	}
+
+	slog.Info("ClickHouse writer initialized",
+		"address", cfg.Address,
+		"table", cfg.Database+"."+cfg.Table,
+		"block_size", cfg.BlockSize,
+		"async_insert", cfg.AsyncInsert,
+	)
+	return &openedSink{sink: clickHouseWriter, label: "ClickHouse", batch: true}, nil
+}
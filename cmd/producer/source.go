@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/supratick/message_producer/internal/bridge"
+	"github.com/supratick/message_producer/internal/config"
+	"github.com/supratick/message_producer/internal/metrics"
+	"github.com/supratick/message_producer/internal/models"
+	"github.com/supratick/message_producer/internal/pipeline"
+)
+
+// maxLoggedRecordErrors caps the undecodable source records logged one by
+// one; the rest are only counted
+const maxLoggedRecordErrors = 10
+
+// startSource feeds out from the configured source instead of the
+// generator, closing it once the source is drained, message_count records
+// were sent or generation is stopped
+func startSource(run *pipeline.Lifecycle, cfg *config.Config, out chan<- *models.Transaction, monitor *metrics.Monitor, logger *slog.Logger) error {
+	transform, err := bridge.NewTransform(cfg.Source.Transform.Set, cfg.Source.Transform.NewIDs)
+	if err != nil {
+		return fmt.Errorf("invalid source transform: %w", err)
+	}
+	var logged atomic.Int64
+	b, err := bridge.New(cfg.Source.Format, transform, cfg.Producer.MessageCount, func(err error) {
+		if logged.Add(1) <= maxLoggedRecordErrors {
+			logger.Warn("Skipping undecodable source record", "error", err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	finish := func() {
+		close(out)
+		monitor.IncrementTotal(b.Sent())
+		logger.Info("Source drained", "type", cfg.Source.Type, "sent", b.Sent(), "undecodable", b.Failed())
+	}
+
+	if cfg.Source.Type == "stdin" {
+		logger.Info("Reading source records from stdin", "format", firstNonEmpty(cfg.Source.Format, bridge.FormatJSON))
+		run.Go(pipeline.PhaseGenerate, "source", func(ctx context.Context) error {
+			defer finish()
+			return b.RunReader(ctx, os.Stdin, out)
+		})
+		return nil
+	}
+
+	sk := cfg.Source.Kafka
+	brokers := sk.Brokers
+	if len(brokers) == 0 {
+		brokers = cfg.Kafka.Brokers
+	}
+	source, err := bridge.NewKafkaSource(brokers, sk.Topic, kafkaAuth(cfg.Kafka), sk.FromBeginning, sk.StopAtEnd, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	records, err := source.Records(run.Context(pipeline.PhaseGenerate))
+	if err != nil {
+		source.Close()
+		return err
+	}
+	logger.Info("Reading source records from Kafka",
+		"brokers", brokers,
+		"topic", sk.Topic,
+		"format", firstNonEmpty(cfg.Source.Format, bridge.FormatJSON),
+		"from_beginning", sk.FromBeginning,
+		"stop_at_end", sk.StopAtEnd,
+	)
+	run.Go(pipeline.PhaseGenerate, "source", func(ctx context.Context) error {
+		defer finish()
+		b.Run(ctx, records, out)
+		if err := source.Close(); err != nil {
+			logger.Warn("Failed to close source consumer", "error", err)
+		}
+		return nil
+	})
+	return nil
+}
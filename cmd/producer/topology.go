@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/automaxprocs/maxprocs"
+
+	"github.com/supratick/message_producer/internal/config"
+)
+
+// setMaxProcs lowers GOMAXPROCS to the container's cgroup CPU quota, so a
+// producer limited to 2 CPUs on a 64-core host does not run 64 threads. An
+// explicit GOMAXPROCS environment variable is left alone.
+func setMaxProcs(logger *slog.Logger) {
+	_, err := maxprocs.Set(maxprocs.Logger(func(format string, args ...interface{}) {
+		logger.Debug(fmt.Sprintf(format, args...))
+	}))
+	if err != nil {
+		logger.Warn("Failed to apply cgroup CPU quota to GOMAXPROCS", "error", err)
+	}
+}
+
+// resolveWorkers replaces auto worker and shard counts with sizes for the
+// usable CPUs and logs the chosen topology. Each auto-sharded file sink gets
+// a quarter of the CPUs, every sink writer is assumed to keep about one CPU
+// busy, and generation gets the rest but never less than half.
+func resolveWorkers(cfg *config.Config, logger *slog.Logger) {
+	procs := runtime.GOMAXPROCS(0)
+
+	csv := cfg.Output.CSV.Enabled && (cfg.Output.Format == "csv" || cfg.Output.Format == "both")
+	parquet := cfg.Output.Parquet.Enabled && (cfg.Output.Format == "parquet" || cfg.Output.Format == "both")
+	shards := func(n config.WorkerCount) config.WorkerCount {
+		if n.IsAuto() {
+			return config.WorkerCount(max(procs/4, 1))
+		}
+		return n
+	}
+	cfg.Output.CSV.Shards = shards(cfg.Output.CSV.Shards)
+	cfg.Output.Parquet.Shards = shards(cfg.Output.Parquet.Shards)
+
+	sinkCPUs := 0
+	if csv {
+		sinkCPUs += max(int(cfg.Output.CSV.Shards), 1)
+	}
+	if parquet {
+		sinkCPUs += max(int(cfg.Output.Parquet.Shards), 1)
+	}
+	if cfg.Output.JSONL.Enabled {
+		sinkCPUs += max(cfg.Output.JSONL.Shards, 1)
+	}
+	if cfg.Output.Msgpack.Enabled {
+		sinkCPUs += max(cfg.Output.Msgpack.Shards, 1)
+	}
+	for _, enabled := range []bool{cfg.Output.Render.Enabled, cfg.Output.Arrow.Enabled, cfg.Output.FixedWidth.Enabled,
+		cfg.Output.XML.Enabled, cfg.Output.DuckDB.Enabled, cfg.Output.SQLite.Enabled, cfg.Kafka.Enabled, cfg.Socket.Enabled,
+		cfg.Fluent.Enabled, cfg.Syslog.Enabled, cfg.FIFO.Enabled, cfg.Snowflake.Enabled, cfg.Postgres.Enabled, cfg.ClickHouse.Enabled} {
+		if enabled {
+			sinkCPUs++
+		}
+	}
+
+	auto := cfg.Producer.Workers.IsAuto()
+	if auto {
+		cfg.Producer.Workers = config.WorkerCount(max(procs-sinkCPUs, procs/2, 1))
+	}
+
+	attrs := []any{
+		"cpus", runtime.NumCPU(),
+		"gomaxprocs", procs,
+		"generation_workers", int(cfg.Producer.Workers),
+		"workers_auto", auto,
+		"sink_cpus", sinkCPUs,
+	}
+	if csv {
+		attrs = append(attrs, "csv_shards", max(int(cfg.Output.CSV.Shards), 1))
+	}
+	if parquet {
+		attrs = append(attrs, "parquet_shards", max(int(cfg.Output.Parquet.Shards), 1))
+	}
+	logger.Info("Worker topology", attrs...)
+}
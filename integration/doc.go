@@ -0,0 +1,5 @@
+// Package integration holds the end-to-end suite: it starts real brokers in
+// containers via testcontainers-go, runs a small generation through the
+// writers and checks what a consumer sees. The tests carry the integration
+// build tag and need a Docker daemon; run them with `make test-integration`.
+package integration
@@ -0,0 +1,114 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/kafka"
+
+	"github.com/supratick/message_producer/internal/generator"
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// kafkaImage is the single-node KRaft broker the kafka module supports
+const kafkaImage = "confluentinc/confluent-local:7.5.0"
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// startKafka runs a broker for the test and returns its bootstrap addresses.
+// The container is terminated when the test finishes.
+func startKafka(t *testing.T) []string {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx := context.Background()
+	container, err := kafka.Run(ctx, kafkaImage, kafka.WithClusterID("integration"))
+	testcontainers.CleanupContainer(t, container)
+	if err != nil {
+		t.Fatalf("failed to start kafka: %v", err)
+	}
+
+	brokers, err := container.Brokers(ctx)
+	if err != nil {
+		t.Fatalf("failed to get kafka brokers: %v", err)
+	}
+	return brokers
+}
+
+// generate feeds n seeded transactions from the bundled reference data into
+// a closed channel, returning it with the transactions keyed by ID
+func generate(t *testing.T, n int) (<-chan *models.Transaction, map[string]*models.Transaction) {
+	t.Helper()
+	rd, err := generator.LoadReferenceData("../data")
+	if err != nil {
+		t.Fatalf("failed to load reference data: %v", err)
+	}
+	p := generator.NewProducer(rd, discard)
+	p.SetSeed(1)
+
+	input := make(chan *models.Transaction, n)
+	sent := make(map[string]*models.Transaction, n)
+	for i := 0; i < n; i++ {
+		txn := p.GenerateSingle()
+		sent[txn.ID] = txn
+		input <- txn
+	}
+	close(input)
+	return input, sent
+}
+
+// consume reads every partition of topic from the oldest offset until want
+// messages have arrived or the timeout passes, returning what it got
+func consume(t *testing.T, brokers []string, topic string, want int, timeout time.Duration) []*sarama.ConsumerMessage {
+	t.Helper()
+	consumer, err := sarama.NewConsumer(brokers, sarama.NewConfig())
+	if err != nil {
+		t.Fatalf("failed to create consumer: %v", err)
+	}
+	defer consumer.Close()
+
+	partitions, err := consumer.Partitions(topic)
+	if err != nil {
+		t.Fatalf("failed to list partitions for %s: %v", topic, err)
+	}
+
+	messages := make(chan *sarama.ConsumerMessage)
+	done := make(chan struct{})
+	for _, partition := range partitions {
+		pc, err := consumer.ConsumePartition(topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			t.Fatalf("failed to consume partition %d: %v", partition, err)
+		}
+		defer pc.Close()
+		go func() {
+			for msg := range pc.Messages() {
+				select {
+				case messages <- msg:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	// Runs before the partition consumers close so no forwarder is left blocked
+	defer close(done)
+
+	got := make([]*sarama.ConsumerMessage, 0, want)
+	deadline := time.After(timeout)
+	for len(got) < want {
+		select {
+		case msg := <-messages:
+			got = append(got, msg)
+		case <-deadline:
+			return got
+		}
+	}
+	return got
+}
@@ -0,0 +1,117 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/codec"
+	"github.com/supratick/message_producer/internal/models"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+const messageCount = 500
+
+// produce writes the generated transactions to topic with the given
+// serialization and closes the writer so every message is acknowledged
+func produce(t *testing.T, brokers []string, topic, serialization string, input <-chan *models.Transaction) {
+	t.Helper()
+	format, err := codec.Lookup(serialization)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := writer.NewKafkaWriter(brokers, topic, "snappy", 0, 100, 50, true, format, writer.KafkaAuth{}, writer.KafkaLimits{}, nil, discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.CloseContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if w.Errors() != 0 {
+		t.Fatalf("kafka writer reported %d errors", w.Errors())
+	}
+}
+
+func TestKafkaJSON(t *testing.T) {
+	brokers := startKafka(t)
+	input, sent := generate(t, messageCount)
+	produce(t, brokers, "transactions-json", "json", input)
+
+	messages := consume(t, brokers, "transactions-json", messageCount, time.Minute)
+	if len(messages) != messageCount {
+		t.Fatalf("consumed %d messages, want %d", len(messages), messageCount)
+	}
+
+	for _, msg := range messages {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(msg.Value, &fields); err != nil {
+			t.Fatalf("message %s is not JSON: %v", msg.Key, err)
+		}
+		if len(fields) != len(models.TransactionCSVHeader) {
+			t.Fatalf("message %s has %d fields, want %d", msg.Key, len(fields), len(models.TransactionCSVHeader))
+		}
+		for _, column := range models.TransactionCSVHeader {
+			if _, ok := fields[column]; !ok {
+				t.Fatalf("message %s is missing field %s", msg.Key, column)
+			}
+		}
+
+		var got models.Transaction
+		if err := json.Unmarshal(msg.Value, &got); err != nil {
+			t.Fatal(err)
+		}
+		want, ok := sent[string(msg.Key)]
+		if !ok {
+			t.Fatalf("consumed unknown key %s", msg.Key)
+		}
+		if got.ID != want.ID || got.BetAmount != want.BetAmount || got.SettledAt != want.SettledAt {
+			t.Fatalf("message %s = %+v, want %+v", msg.Key, got, *want)
+		}
+		delete(sent, string(msg.Key))
+	}
+	if len(sent) != 0 {
+		t.Fatalf("%d transactions were never consumed", len(sent))
+	}
+}
+
+func TestKafkaCSV(t *testing.T) {
+	brokers := startKafka(t)
+	input, _ := generate(t, messageCount)
+	produce(t, brokers, "transactions-csv", "csv", input)
+
+	messages := consume(t, brokers, "transactions-csv", messageCount, time.Minute)
+	if len(messages) != messageCount {
+		t.Fatalf("consumed %d messages, want %d", len(messages), messageCount)
+	}
+
+	for _, msg := range messages {
+		var contentType string
+		for _, h := range msg.Headers {
+			if string(h.Key) == "content-type" {
+				contentType = string(h.Value)
+			}
+		}
+		if contentType != "text/csv" {
+			t.Fatalf("message %s content-type = %q, want text/csv", msg.Key, contentType)
+		}
+
+		record, err := csv.NewReader(strings.NewReader(string(msg.Value))).Read()
+		if err != nil {
+			t.Fatalf("message %s is not CSV: %v", msg.Key, err)
+		}
+		if len(record) != len(models.TransactionCSVHeader) {
+			t.Fatalf("message %s has %d columns, want %d", msg.Key, len(record), len(models.TransactionCSVHeader))
+		}
+		if record[0] != string(msg.Key) {
+			t.Fatalf("message %s has id column %q", msg.Key, record[0])
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// Package bridge turns an existing stream of transaction records, read from
+// stdin or a Kafka topic, into transactions for the sinks, optionally
+// rewriting fields on the way. Sinks re-key and re-serialize them with their
+// own settings, so the producer doubles as a bridging and replay tool.
+package bridge
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Formats of source records
+const (
+	FormatJSON = "json" // one JSON object per line or message
+	FormatCSV  = "csv"  // one CSV row per line or message; stdin starts with a header
+	FormatAvro = "avro" // one Avro binary record per message, models.TransactionAvroSchema
+)
+
+// maxLine bounds a single stdin record
+const maxLine = 1 << 20
+
+// fieldIndex maps each column to its Transaction field
+var fieldIndex = func() map[string]int {
+	index := make(map[string]int)
+	t := reflect.TypeOf(models.Transaction{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := t.Field(i).Tag.Get("json"); name != "" && name != "-" {
+			index[name] = i
+		}
+	}
+	return index
+}()
+
+// SetField sets one column of txn, parsing integer columns
+func SetField(txn *models.Transaction, column, value string) error {
+	i, ok := fieldIndex[column]
+	if !ok {
+		return fmt.Errorf("unknown column %s", column)
+	}
+	field := reflect.ValueOf(txn).Elem().Field(i)
+	if field.Kind() == reflect.Int {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("column %s: %q is not an integer", column, value)
+		}
+		field.SetInt(n)
+		return nil
+	}
+	field.SetString(value)
+	return nil
+}
+
+// Decoder parses one source record into a transaction
+type Decoder struct {
+	format  string
+	columns []string // CSV column order
+}
+
+// NewDecoder returns a decoder for format. CSV records are read in
+// models.TransactionCSVHeader order until SetHeader says otherwise; Avro
+// records always are.
+func NewDecoder(format string) (*Decoder, error) {
+	switch format {
+	case "", FormatJSON:
+		return &Decoder{format: FormatJSON}, nil
+	case FormatCSV, FormatAvro:
+		return &Decoder{format: format, columns: models.TransactionCSVHeader}, nil
+	}
+	return nil, fmt.Errorf("unsupported source format: %s", format)
+}
+
+// SetHeader sets the CSV column order, which may name a subset of columns
+func (d *Decoder) SetHeader(columns []string) error {
+	if d.format != FormatCSV {
+		return fmt.Errorf("%s records have no header", d.format)
+	}
+	for _, c := range columns {
+		if _, ok := fieldIndex[c]; !ok {
+			return fmt.Errorf("unknown column %s in CSV header", c)
+		}
+	}
+	d.columns = columns
+	return nil
+}
+
+// Decode parses a record
+func (d *Decoder) Decode(data []byte) (*models.Transaction, error) {
+	txn := &models.Transaction{}
+	if d.format == FormatJSON {
+		if err := json.Unmarshal(data, txn); err != nil {
+			return nil, fmt.Errorf("invalid JSON record: %w", err)
+		}
+		return txn, nil
+	}
+	if d.format == FormatAvro {
+		return txn, decodeAvro(data, txn)
+	}
+
+	fields, err := csv.NewReader(bytes.NewReader(data)).Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV record: %w", err)
+	}
+	if len(fields) != len(d.columns) {
+		return nil, fmt.Errorf("CSV record has %d fields, want %d", len(fields), len(d.columns))
+	}
+	for i, value := range fields {
+		if err := SetField(txn, d.columns[i], value); err != nil {
+			return nil, err
+		}
+	}
+	return txn, nil
+}
+
+// decodeAvro reads the fields of models.TransactionAvroSchema, which are all
+// longs or strings, in column order
+func decodeAvro(data []byte, txn *models.Transaction) error {
+	v := reflect.ValueOf(txn).Elem()
+	for _, column := range models.TransactionCSVHeader {
+		n, size := binary.Varint(data)
+		if size <= 0 {
+			return fmt.Errorf("invalid Avro record: truncated at %s", column)
+		}
+		data = data[size:]
+		field := v.Field(fieldIndex[column])
+		if field.Kind() == reflect.Int {
+			field.SetInt(n)
+			continue
+		}
+		if n < 0 || n > int64(len(data)) {
+			return fmt.Errorf("invalid Avro record: bad length for %s", column)
+		}
+		field.SetString(string(data[:n]))
+		data = data[n:]
+	}
+	if len(data) > 0 {
+		return fmt.Errorf("invalid Avro record: %d trailing bytes", len(data))
+	}
+	return nil
+}
+
+// Transform rewrites decoded transactions before they reach the sinks
+type Transform struct {
+	columns []string
+	values  []string
+	newIDs  bool
+	seq     atomic.Int64
+}
+
+// NewTransform sets every column in set on each transaction and, with
+// newIDs, replaces the ID with a fresh TXN-<date>-<seq> one so replays do
+// not collide with the records they were read from
+func NewTransform(set map[string]string, newIDs bool) (*Transform, error) {
+	t := &Transform{newIDs: newIDs}
+	var probe models.Transaction
+	for _, column := range slices.Sorted(maps.Keys(set)) {
+		if err := SetField(&probe, column, set[column]); err != nil {
+			return nil, err
+		}
+		t.columns = append(t.columns, column)
+		t.values = append(t.values, set[column])
+	}
+	return t, nil
+}
+
+// Apply rewrites txn in place
+func (t *Transform) Apply(txn *models.Transaction) {
+	for i, column := range t.columns {
+		// Values were checked by NewTransform
+		SetField(txn, column, t.values[i])
+	}
+	if t.newIDs {
+		seq := t.seq.Add(1)
+		txn.ID = fmt.Sprintf("TXN-%s-%08d", time.Now().UTC().Format("20060102"), seq)
+	}
+}
+
+// Bridge decodes and transforms source records into transactions
+type Bridge struct {
+	decoder   *Decoder
+	transform *Transform
+	limit     int64
+	onError   func(error)
+	sent      atomic.Int64
+	failed    atomic.Int64
+}
+
+// New creates a bridge for records in format. A limit above zero stops after
+// that many transactions. Records that fail to decode are skipped and passed
+// to onError.
+func New(format string, transform *Transform, limit int, onError func(error)) (*Bridge, error) {
+	decoder, err := NewDecoder(format)
+	if err != nil {
+		return nil, err
+	}
+	return &Bridge{decoder: decoder, transform: transform, limit: int64(limit), onError: onError}, nil
+}
+
+// Run sends a transaction for every record from records to out until
+// records is closed, the limit is reached or ctx is cancelled
+func (b *Bridge) Run(ctx context.Context, records <-chan []byte, out chan<- *models.Transaction) {
+	for {
+		select {
+		case data, ok := <-records:
+			if !ok || !b.send(ctx, data, out) {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RunReader sends a transaction for every line of r, the first of which is
+// the header for CSV, until r is exhausted, the limit is reached or ctx is
+// cancelled
+func (b *Bridge) RunReader(ctx context.Context, r io.Reader, out chan<- *models.Transaction) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+	if b.decoder.format == FormatCSV {
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		header, err := csv.NewReader(bytes.NewReader(scanner.Bytes())).Read()
+		if err != nil {
+			return fmt.Errorf("invalid CSV header: %w", err)
+		}
+		if err := b.decoder.SetHeader(header); err != nil {
+			return err
+		}
+	}
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if !b.send(ctx, scanner.Bytes(), out) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// send decodes one record and hands it to out, reporting whether to go on
+func (b *Bridge) send(ctx context.Context, data []byte, out chan<- *models.Transaction) bool {
+	txn, err := b.decoder.Decode(data)
+	if err != nil {
+		b.failed.Add(1)
+		if b.onError != nil {
+			b.onError(err)
+		}
+		return true
+	}
+	if b.transform != nil {
+		b.transform.Apply(txn)
+	}
+	select {
+	case out <- txn:
+	case <-ctx.Done():
+		return false
+	}
+	sent := b.sent.Add(1)
+	return b.limit <= 0 || sent < b.limit
+}
+
+// Sent returns the number of transactions handed to the sinks
+func (b *Bridge) Sent() int64 {
+	return b.sent.Load()
+}
+
+// Failed returns the number of records that could not be decoded
+func (b *Bridge) Failed() int64 {
+	return b.failed.Load()
+}
@@ -0,0 +1,104 @@
+package bridge
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func sample() *models.Transaction {
+	return &models.Transaction{
+		ID: "TXN-1", ExternalTransactionID: "EXT-1", VendorBetID: "BET-1", RoundID: "ROUND-1",
+		VendorID: 3, VendorCode: "PG", VendorLineID: 1, GameCategoryID: 2, HouseID: 1,
+		MasterAgentID: 10, AgentID: 11, CurrencyID: 1, CurrencyCode: "USD",
+		BetAmount: "10.000000", WinAmount: "12.500000", WinLoss: "2.500000", SettledAt: "2024-01-01T00:00:00Z",
+	}
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	want := sample()
+	for _, tc := range []struct {
+		format string
+		data   []byte
+	}{
+		{FormatJSON, want.AppendJSON(nil)},
+		{FormatCSV, want.AppendCSV(nil)},
+		{FormatAvro, want.AppendAvro(nil)},
+	} {
+		d, err := NewDecoder(tc.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := d.Decode(tc.data)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.format, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: decoded %+v, want %+v", tc.format, got, want)
+		}
+	}
+
+	d, _ := NewDecoder(FormatAvro)
+	if _, err := d.Decode(want.AppendAvro(nil)[:20]); err == nil {
+		t.Error("truncated Avro record decoded")
+	}
+	if _, err := NewDecoder("msgpack"); err == nil {
+		t.Error("msgpack source format accepted")
+	}
+}
+
+func TestTransform(t *testing.T) {
+	tf, err := NewTransform(map[string]string{"house_id": "7", "vendor_code": "REPLAY"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := sample()
+	tf.Apply(txn)
+	if txn.HouseID != 7 || txn.VendorCode != "REPLAY" {
+		t.Errorf("transform set house %d vendor %s", txn.HouseID, txn.VendorCode)
+	}
+	if txn.ID == "TXN-1" || !strings.HasSuffix(txn.ID, "-00000001") {
+		t.Errorf("new id = %s", txn.ID)
+	}
+
+	if _, err := NewTransform(map[string]string{"agent_id": "x"}, false); err == nil {
+		t.Error("non-integer agent_id accepted")
+	}
+	if _, err := NewTransform(map[string]string{"player": "p"}, false); err == nil {
+		t.Error("unknown column accepted")
+	}
+}
+
+func TestRunReader(t *testing.T) {
+	input := "round_id,agent_id,bet_amount\n" +
+		"ROUND-1,11,10.00\n" +
+		"\n" +
+		"ROUND-2,not-a-number,5.00\n" +
+		"ROUND-3,12,7.50\n" +
+		"ROUND-4,13,1.00\n"
+
+	var errs []error
+	b, err := New(FormatCSV, nil, 2, func(err error) { errs = append(errs, err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(chan *models.Transaction, 10)
+	if err := b.RunReader(context.Background(), strings.NewReader(input), out); err != nil {
+		t.Fatal(err)
+	}
+	close(out)
+
+	var rounds []string
+	for txn := range out {
+		rounds = append(rounds, txn.RoundID)
+	}
+	if strings.Join(rounds, ",") != "ROUND-1,ROUND-3" {
+		t.Errorf("read rounds %v, want ROUND-1 and ROUND-3 (limit 2)", rounds)
+	}
+	if b.Sent() != 2 || b.Failed() != 1 || len(errs) != 1 {
+		t.Errorf("sent %d failed %d errors %v", b.Sent(), b.Failed(), errs)
+	}
+}
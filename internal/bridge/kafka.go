@@ -0,0 +1,151 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// KafkaSource reads the record values of every partition of a topic
+type KafkaSource struct {
+	client   sarama.Client
+	consumer sarama.Consumer
+	topic    string
+	// per partition: first offset to read and, with stopAtEnd, the offset
+	// to stop at
+	partitions []kafkaPartition
+
+	stop     chan struct{} // closed by Close or cancellation
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+type kafkaPartition struct {
+	id    int32
+	start int64
+	end   int64 // -1 = never stop
+}
+
+// NewKafkaSource connects to the brokers and resolves where each partition
+// of topic is read from: the oldest offset with fromBeginning, otherwise the
+// newest. With stopAtEnd the source closes once it reaches the offsets that
+// were newest when it was created.
+func NewKafkaSource(brokers []string, topic string, auth writer.KafkaAuth, fromBeginning, stopAtEnd bool, timeout time.Duration) (*KafkaSource, error) {
+	config := sarama.NewConfig()
+	config.Net.DialTimeout = timeout
+	config.Consumer.Return.Errors = false
+	auth.Apply(config)
+
+	client, err := sarama.NewClient(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect source consumer: %w", err)
+	}
+	ids, err := client.Partitions(topic)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to list partitions for %s: %w", topic, err)
+	}
+
+	s := &KafkaSource{client: client, topic: topic, stop: make(chan struct{})}
+	for _, id := range ids {
+		p := kafkaPartition{id: id, start: sarama.OffsetNewest, end: -1}
+		if fromBeginning {
+			p.start = sarama.OffsetOldest
+		}
+		if stopAtEnd {
+			oldest, err := client.GetOffset(topic, id, sarama.OffsetOldest)
+			if err == nil {
+				p.end, err = client.GetOffset(topic, id, sarama.OffsetNewest)
+			}
+			if err != nil {
+				client.Close()
+				return nil, fmt.Errorf("failed to fetch offsets for %s partition %d: %w", topic, id, err)
+			}
+			if !fromBeginning || oldest >= p.end {
+				continue // nothing to read before the end
+			}
+		}
+		s.partitions = append(s.partitions, p)
+	}
+
+	if s.consumer, err = sarama.NewConsumerFromClient(client); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to create source consumer: %w", err)
+	}
+	return s, nil
+}
+
+// Records streams record values until every partition reaches its end or
+// ctx is cancelled, then closes the channel
+func (s *KafkaSource) Records(ctx context.Context) (<-chan []byte, error) {
+	var pcs []sarama.PartitionConsumer
+	for _, p := range s.partitions {
+		pc, err := s.consumer.ConsumePartition(s.topic, p.id, p.start)
+		if err != nil {
+			for _, pc := range pcs {
+				pc.AsyncClose()
+			}
+			return nil, fmt.Errorf("failed to consume %s partition %d: %w", s.topic, p.id, err)
+		}
+		pcs = append(pcs, pc)
+	}
+
+	records := make(chan []byte, 1024)
+	for i, pc := range pcs {
+		s.wg.Add(1)
+		go func(pc sarama.PartitionConsumer, end int64) {
+			defer s.wg.Done()
+			defer pc.AsyncClose()
+			for {
+				select {
+				case msg, ok := <-pc.Messages():
+					if !ok {
+						return
+					}
+					select {
+					case records <- msg.Value:
+					case <-s.stop:
+						return
+					}
+					if end >= 0 && msg.Offset+1 >= end {
+						return
+					}
+				case <-s.stop:
+					return
+				}
+			}
+		}(pc, s.partitions[i].end)
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.halt()
+		case <-s.stop:
+		}
+	}()
+	go func() {
+		s.wg.Wait()
+		close(records)
+	}()
+	return records, nil
+}
+
+func (s *KafkaSource) halt() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// Close stops reading and disconnects from the brokers
+func (s *KafkaSource) Close() error {
+	s.halt()
+	s.wg.Wait()
+	if err := s.consumer.Close(); err != nil {
+		s.client.Close()
+		return err
+	}
+	return s.client.Close()
+}
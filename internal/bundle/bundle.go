@@ -0,0 +1,88 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// File is one entry of a bundle, taken from Data or, when Path is set, from
+// the file at Path
+type File struct {
+	Name string
+	Data []byte
+	Path string
+}
+
+// Write packages files into a gzipped tar at dest, each under the directory
+// prefix. The archive is written to a temporary file and renamed into place,
+// so dest is either complete or absent.
+func Write(dest, prefix string, files []File, now time.Time) (err error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	gz := gzip.NewWriter(tmp)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		if err := add(tw, path.Join(prefix, f.Name), f, now); err != nil {
+			return fmt.Errorf("failed to add %s to bundle: %w", f.Name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+func add(tw *tar.Writer, name string, f File, now time.Time) error {
+	if f.Path == "" {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(f.Data)), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(f.Data)
+		return err
+	}
+
+	src, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	// A file still growing (the log) is cut at the size in the header
+	_, err = io.CopyN(tw, src, info.Size())
+	return err
+}
@@ -0,0 +1,130 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func TestCollector(t *testing.T) {
+	input := make(chan *models.Transaction, 10)
+	for i := 0; i < 5; i++ {
+		currency := "USD"
+		if i%2 == 1 {
+			currency = "EUR"
+		}
+		input <- &models.Transaction{
+			ID:             fmt.Sprintf("TXN-%d", i),
+			CurrencyCode:   currency,
+			VendorCode:     "PRAGMATIC",
+			GameCategoryID: 1 + i%2,
+			HouseID:        1,
+			BetAmount:      "10.000000",
+			WinAmount:      "15.500000",
+			WinLoss:        "5.500000",
+			SettledAt:      fmt.Sprintf("2024-01-01T00:00:0%dZ", 4-i),
+		}
+	}
+	close(input)
+
+	c := NewCollector(2)
+	if err := c.Write(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(c.Sample())), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"id":"TXN-1"`) {
+		t.Fatalf("sample = %q, want TXN-0 and TXN-1", lines)
+	}
+
+	data, err := c.Aggregates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Aggregates
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Transactions != 5 || got.SettledFrom != "2024-01-01T00:00:00Z" || got.SettledTo != "2024-01-01T00:00:04Z" {
+		t.Errorf("totals = %+v", got)
+	}
+	usd := got.Currencies["USD"]
+	if usd == nil || usd.Transactions != 3 || usd.Bet.String() != "30" || usd.WinLoss.String() != "16.5" {
+		t.Errorf("USD = %+v", usd)
+	}
+	if got.Vendors["PRAGMATIC"] != 5 || got.GameCategories[1] != 3 || got.GameCategories[2] != 2 || got.Houses[1] != 5 {
+		t.Errorf("counts = %v %v %v", got.Vendors, got.GameCategories, got.Houses)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "producer.log")
+	if err := os.WriteFile(logPath, []byte("{\"msg\":\"done\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(dir, "out", "run-1.tar.gz")
+	err := Write(dest, "run-1", []File{
+		{Name: "config.yaml", Data: []byte("producer: {}\n")},
+		{Name: "producer.log", Path: logPath},
+	}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	got := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		io.Copy(&buf, tr)
+		got[hdr.Name] = buf.String()
+	}
+	if got["run-1/config.yaml"] != "producer: {}\n" || got["run-1/producer.log"] != "{\"msg\":\"done\"}\n" || len(got) != 2 {
+		t.Errorf("bundle entries = %q", got)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(dest))
+	if len(entries) != 1 {
+		t.Errorf("bundle directory holds %d files, want only the bundle", len(entries))
+	}
+}
+
+func TestWriteMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "run.tar.gz")
+	err := Write(dest, "run", []File{{Name: "producer.log", Path: filepath.Join(dir, "missing.log")}}, time.Now())
+	if err == nil {
+		t.Fatal("missing file accepted")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("failed bundle left %d files behind", len(entries))
+	}
+}
@@ -0,0 +1,132 @@
+// Package bundle packages the evidence of a run (manifest, config snapshot,
+// sample data, ground-truth aggregates and logs) into a single tar.gz that
+// can be attached to a test report
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Aggregates are ground-truth totals over every transaction of a run, for
+// checking what downstream systems computed from it
+type Aggregates struct {
+	Transactions   int64                      `json:"transactions"`
+	SettledFrom    string                     `json:"settled_from,omitempty"`
+	SettledTo      string                     `json:"settled_to,omitempty"`
+	Currencies     map[string]*CurrencyTotals `json:"currencies"`
+	Vendors        map[string]int64           `json:"vendors"`
+	GameCategories map[int]int64              `json:"game_categories"` // by game category ID
+	Houses         map[int]int64              `json:"houses"`          // by house ID
+}
+
+// CurrencyTotals sums the amounts of one currency
+type CurrencyTotals struct {
+	Transactions int64           `json:"transactions"`
+	Bet          decimal.Decimal `json:"bet"`
+	Win          decimal.Decimal `json:"win"`
+	WinLoss      decimal.Decimal `json:"win_loss"`
+}
+
+// Collector is a pipeline writer that keeps the first transactions of a run
+// as a sample and aggregates all of them. It must see the whole stream, so
+// the pipeline has to broadcast.
+type Collector struct {
+	sampleSize int
+	mu         sync.Mutex
+	sample     []byte // JSON lines
+	sampled    int
+	totals     Aggregates
+}
+
+// NewCollector creates a collector keeping sampleSize transactions
+func NewCollector(sampleSize int) *Collector {
+	return &Collector{
+		sampleSize: sampleSize,
+		totals: Aggregates{
+			Currencies:     make(map[string]*CurrencyTotals),
+			Vendors:        make(map[string]int64),
+			GameCategories: make(map[int]int64),
+			Houses:         make(map[int]int64),
+		},
+	}
+}
+
+// Write samples and aggregates transactions from the channel
+func (c *Collector) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case txn, ok := <-input:
+			if !ok {
+				return nil
+			}
+			c.add(txn)
+		}
+	}
+}
+
+func (c *Collector) add(txn *models.Transaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sampled < c.sampleSize {
+		c.sample = append(txn.AppendJSON(c.sample), '\n')
+		c.sampled++
+	}
+
+	t := &c.totals
+	t.Transactions++
+	// RFC 3339 timestamps in one zone sort as strings
+	if t.SettledFrom == "" || txn.SettledAt < t.SettledFrom {
+		t.SettledFrom = txn.SettledAt
+	}
+	if txn.SettledAt > t.SettledTo {
+		t.SettledTo = txn.SettledAt
+	}
+	currency := t.Currencies[txn.CurrencyCode]
+	if currency == nil {
+		currency = &CurrencyTotals{}
+		t.Currencies[txn.CurrencyCode] = currency
+	}
+	currency.Transactions++
+	currency.Bet = currency.Bet.Add(parseAmount(txn.BetAmount))
+	currency.Win = currency.Win.Add(parseAmount(txn.WinAmount))
+	currency.WinLoss = currency.WinLoss.Add(parseAmount(txn.WinLoss))
+	t.Vendors[txn.VendorCode]++
+	t.GameCategories[txn.GameCategoryID]++
+	t.Houses[txn.HouseID]++
+}
+
+// parseAmount reads a generated amount, counting a malformed one as zero
+func parseAmount(s string) decimal.Decimal {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Zero
+	}
+	return d
+}
+
+// Sample returns the sampled transactions as JSON lines
+func (c *Collector) Sample() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]byte(nil), c.sample...)
+}
+
+// Aggregates returns the totals as indented JSON
+func (c *Collector) Aggregates() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := json.MarshalIndent(&c.totals, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
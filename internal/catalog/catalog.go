@@ -0,0 +1,91 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Partition is one Hive-style partition directory of Parquet files
+type Partition struct {
+	Values   []string // in PartitionKeys order
+	Location string   // catalog location, e.g. s3://bucket/transactions/dt=2024-01-01/
+}
+
+// Registrar adds partitions to a table in an external catalog
+type Registrar interface {
+	Register(ctx context.Context, partitions []Partition) (int, error)
+	// Check verifies the catalog is reachable and the table exists
+	Check(ctx context.Context) error
+}
+
+// DiscoverPartitions walks root for directories holding .parquet files and
+// parses key=value path segments into partitions. Locations are built by
+// replacing root with locationPrefix, which is where the output directory
+// is published (e.g. synced to S3).
+func DiscoverPartitions(root, locationPrefix string, keys []string) ([]Partition, error) {
+	seen := make(map[string]bool)
+	var partitions []Partition
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(p) != ".parquet" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, filepath.Dir(p))
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if seen[rel] {
+			return nil
+		}
+		seen[rel] = true
+
+		values, ok := partitionValues(rel, keys)
+		if !ok {
+			return nil
+		}
+		partitions = append(partitions, Partition{
+			Values:   values,
+			Location: strings.TrimSuffix(locationPrefix, "/") + "/" + path.Clean(rel) + "/",
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan output for partitions: %w", err)
+	}
+
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].Location < partitions[j].Location
+	})
+	return partitions, nil
+}
+
+// partitionValues extracts the value of every key from key=value segments,
+// reporting false unless all keys are present
+func partitionValues(rel string, keys []string) ([]string, bool) {
+	found := make(map[string]string)
+	for _, segment := range strings.Split(rel, "/") {
+		if k, v, ok := strings.Cut(segment, "="); ok {
+			found[k] = v
+		}
+	}
+
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		v, ok := found[key]
+		if !ok {
+			return nil, false
+		}
+		values[i] = v
+	}
+	return values, true
+}
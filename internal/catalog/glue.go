@@ -0,0 +1,160 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/supratick/message_producer/internal/awsauth"
+)
+
+// glueBatchLimit is the maximum partitions per BatchCreatePartition call
+const glueBatchLimit = 100
+
+// GlueRegistrar registers partitions with the AWS Glue Data Catalog through
+// its JSON API, signing requests with SigV4
+type GlueRegistrar struct {
+	client   *http.Client
+	creds    awsauth.Credentials
+	endpoint string
+	region   string
+	database string
+	table    string
+	logger   *slog.Logger
+}
+
+// NewGlueRegistrar creates a Glue registrar. An empty endpoint uses the
+// regional Glue endpoint.
+func NewGlueRegistrar(region, endpoint, database, table string, creds awsauth.Credentials, timeout time.Duration, logger *slog.Logger) (*GlueRegistrar, error) {
+	if !creds.Valid() {
+		return nil, fmt.Errorf("AWS access key ID and secret access key are required for Glue")
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://glue.%s.amazonaws.com", region)
+	}
+	return &GlueRegistrar{
+		client:   &http.Client{Timeout: timeout},
+		creds:    creds,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		region:   region,
+		database: database,
+		table:    table,
+		logger:   logger,
+	}, nil
+}
+
+// Register creates the partitions, reusing the table's storage descriptor
+// with each partition's location. Existing partitions are skipped.
+func (g *GlueRegistrar) Register(ctx context.Context, partitions []Partition) (int, error) {
+	var table struct {
+		Table struct {
+			StorageDescriptor map[string]interface{} `json:"StorageDescriptor"`
+		} `json:"Table"`
+	}
+	if err := g.call(ctx, "GetTable", map[string]interface{}{
+		"DatabaseName": g.database,
+		"Name":         g.table,
+	}, &table); err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for start := 0; start < len(partitions); start += glueBatchLimit {
+		end := min(start+glueBatchLimit, len(partitions))
+
+		inputs := make([]map[string]interface{}, 0, end-start)
+		for _, p := range partitions[start:end] {
+			sd := make(map[string]interface{}, len(table.Table.StorageDescriptor))
+			for k, v := range table.Table.StorageDescriptor {
+				sd[k] = v
+			}
+			sd["Location"] = p.Location
+			inputs = append(inputs, map[string]interface{}{
+				"Values":            p.Values,
+				"StorageDescriptor": sd,
+			})
+		}
+
+		var resp struct {
+			Errors []struct {
+				PartitionValues []string `json:"PartitionValues"`
+				ErrorDetail     struct {
+					ErrorCode    string `json:"ErrorCode"`
+					ErrorMessage string `json:"ErrorMessage"`
+				} `json:"ErrorDetail"`
+			} `json:"Errors"`
+		}
+		if err := g.call(ctx, "BatchCreatePartition", map[string]interface{}{
+			"DatabaseName":       g.database,
+			"TableName":          g.table,
+			"PartitionInputList": inputs,
+		}, &resp); err != nil {
+			return created, err
+		}
+
+		failed := 0
+		for _, e := range resp.Errors {
+			if e.ErrorDetail.ErrorCode == "AlreadyExistsException" {
+				failed++
+				continue
+			}
+			g.logger.Error("Failed to create Glue partition",
+				"values", e.PartitionValues,
+				"code", e.ErrorDetail.ErrorCode,
+				"error", e.ErrorDetail.ErrorMessage,
+			)
+			failed++
+		}
+		created += len(inputs) - failed
+	}
+	return created, nil
+}
+
+// Check looks up the table with GetTable
+func (g *GlueRegistrar) Check(ctx context.Context) error {
+	var table map[string]interface{}
+	return g.call(ctx, "GetTable", map[string]interface{}{
+		"DatabaseName": g.database,
+		"Name":         g.table,
+	}, &table)
+}
+
+// call invokes a Glue API action
+func (g *GlueRegistrar) call(ctx context.Context, action string, input, output interface{}) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("failed to encode Glue %s request: %w", action, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Glue request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AWSGlue."+action)
+	awsauth.SignV4(req, body, g.creds, g.region, "glue", time.Now().UTC())
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("glue %s request failed: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read Glue %s response: %w", action, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("glue %s failed (%d): %s", action, resp.StatusCode, data)
+	}
+	if err := json.Unmarshal(data, output); err != nil {
+		return fmt.Errorf("failed to decode Glue %s response: %w", action, err)
+	}
+	return nil
+}
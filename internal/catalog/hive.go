@@ -0,0 +1,119 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HiveRegistrar adds partitions to a Hive metastore table through the
+// WebHCat (Templeton) REST API
+type HiveRegistrar struct {
+	client   *http.Client
+	endpoint string
+	user     string
+	database string
+	table    string
+	keys     []string
+	logger   *slog.Logger
+}
+
+// NewHiveRegistrar creates a Hive registrar for the WebHCat server at
+// endpoint, e.g. http://hive:50111
+func NewHiveRegistrar(endpoint, user, database, table string, keys []string, timeout time.Duration, logger *slog.Logger) *HiveRegistrar {
+	return &HiveRegistrar{
+		client:   &http.Client{Timeout: timeout},
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		user:     user,
+		database: database,
+		table:    table,
+		keys:     keys,
+		logger:   logger,
+	}
+}
+
+// Register adds each partition with IF NOT EXISTS semantics
+func (h *HiveRegistrar) Register(ctx context.Context, partitions []Partition) (int, error) {
+	created := 0
+	for _, p := range partitions {
+		if err := h.addPartition(ctx, p); err != nil {
+			return created, err
+		}
+		created++
+	}
+	return created, nil
+}
+
+// Check describes the table through WebHCat
+func (h *HiveRegistrar) Check(ctx context.Context) error {
+	u := fmt.Sprintf("%s/templeton/v1/ddl/database/%s/table/%s?user.name=%s",
+		h.endpoint,
+		url.PathEscape(h.database),
+		url.PathEscape(h.table),
+		url.QueryEscape(h.user),
+	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create Hive request: %w", err)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("hive table request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to describe Hive table %s.%s (%d): %s", h.database, h.table, resp.StatusCode, data)
+	}
+	return nil
+}
+
+func (h *HiveRegistrar) addPartition(ctx context.Context, p Partition) error {
+	spec := make([]string, len(h.keys))
+	for i, key := range h.keys {
+		spec[i] = fmt.Sprintf("%s='%s'", key, strings.ReplaceAll(p.Values[i], "'", "\\'"))
+	}
+
+	u := fmt.Sprintf("%s/templeton/v1/ddl/database/%s/table/%s/partition/%s?user.name=%s",
+		h.endpoint,
+		url.PathEscape(h.database),
+		url.PathEscape(h.table),
+		url.PathEscape(strings.Join(spec, ",")),
+		url.QueryEscape(h.user),
+	)
+	body, err := json.Marshal(map[string]interface{}{
+		"location":    p.Location,
+		"ifNotExists": true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode Hive partition request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Hive request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("hive partition request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to add Hive partition %s (%d): %s", strings.Join(spec, ","), resp.StatusCode, data)
+	}
+	h.logger.Debug("Hive partition added", "partition", strings.Join(spec, ","), "location", p.Location)
+	return nil
+}
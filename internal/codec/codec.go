@@ -0,0 +1,194 @@
+// Package codec holds the serializer registry and the append helpers used by
+// the reflection-free marshalers generated with ./gen
+package codec
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Marshaler is implemented by generated model serializers. Each method
+// appends one encoded record to dst and returns the extended slice.
+type Marshaler interface {
+	AppendJSON(dst []byte) []byte
+	AppendCSV(dst []byte) []byte
+	AppendAvro(dst []byte) []byte
+	AppendMsgpack(dst []byte) []byte
+}
+
+// Format is a named record encoding
+type Format struct {
+	Name        string
+	ContentType string
+	Append      func(m Marshaler, dst []byte) []byte
+}
+
+var formats = map[string]Format{}
+
+// Register adds a format to the registry, replacing one of the same name
+func Register(f Format) {
+	formats[f.Name] = f
+}
+
+// Lookup returns the format registered under name
+func Lookup(name string) (Format, error) {
+	f, ok := formats[name]
+	if !ok {
+		return Format{}, fmt.Errorf("unknown serialization %q (have %s)", name, strings.Join(Names(), ", "))
+	}
+	return f, nil
+}
+
+// Names lists the registered formats
+func Names() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register(Format{Name: "json", ContentType: "application/json", Append: Marshaler.AppendJSON})
+	Register(Format{Name: "csv", ContentType: "text/csv", Append: Marshaler.AppendCSV})
+	Register(Format{Name: "avro", ContentType: "avro/binary", Append: Marshaler.AppendAvro})
+	Register(Format{Name: "msgpack", ContentType: "application/msgpack", Append: Marshaler.AppendMsgpack})
+}
+
+const hexDigits = "0123456789abcdef"
+
+// AppendJSONString quotes s with encoding/json's escaping, including its
+// HTML-safe escapes and replacement of invalid UTF-8
+func AppendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				dst = append(dst, '\\', b)
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, "\ufffd"...)
+		case r == '\u2028' || r == '\u2029':
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+// AppendCSVField appends a field, quoting it the way encoding/csv would
+// unless force is set
+func AppendCSVField(dst []byte, field string, force bool) []byte {
+	if !force && !csvFieldNeedsQuotes(field) {
+		return append(dst, field...)
+	}
+	dst = append(dst, '"')
+	for {
+		i := strings.IndexByte(field, '"')
+		if i < 0 {
+			break
+		}
+		dst = append(dst, field[:i+1]...)
+		dst = append(dst, '"')
+		field = field[i+1:]
+	}
+	dst = append(dst, field...)
+	return append(dst, '"')
+}
+
+func csvFieldNeedsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` || field[0] == ' ' || field[0] == '\t' {
+		return true
+	}
+	return strings.ContainsAny(field, ",\"\r\n")
+}
+
+// AppendAvroLong appends an Avro int or long: a zig-zag varint
+func AppendAvroLong(dst []byte, v int64) []byte {
+	u := uint64(v<<1) ^ uint64(v>>63)
+	for u >= 0x80 {
+		dst = append(dst, byte(u)|0x80)
+		u >>= 7
+	}
+	return append(dst, byte(u))
+}
+
+// AppendAvroString appends an Avro string: its length, then the bytes
+func AppendAvroString(dst []byte, s string) []byte {
+	dst = AppendAvroLong(dst, int64(len(s)))
+	return append(dst, s...)
+}
+
+// AppendAvroDouble appends an Avro double, little-endian IEEE 754
+func AppendAvroDouble(dst []byte, v float64) []byte {
+	b := math.Float64bits(v)
+	return append(dst, byte(b), byte(b>>8), byte(b>>16), byte(b>>24),
+		byte(b>>32), byte(b>>40), byte(b>>48), byte(b>>56))
+}
+
+// AppendAvroBoolean appends an Avro boolean
+func AppendAvroBoolean(dst []byte, v bool) []byte {
+	if v {
+		return append(dst, 1)
+	}
+	return append(dst, 0)
+}
+
+// AppendJSONFloat appends v formatted as encoding/json formats a float64
+func AppendJSONFloat(dst []byte, v float64) []byte {
+	abs := math.Abs(v)
+	format := byte('f')
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	dst = strconv.AppendFloat(dst, v, format, -1, 64)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		if n := len(dst); n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+	return dst
+}
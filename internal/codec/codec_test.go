@@ -0,0 +1,385 @@
+package codec_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/supratick/message_producer/internal/codec"
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func testTransactions() []*models.Transaction {
+	txns := make([]*models.Transaction, 8)
+	for i := range txns {
+		txns[i] = &models.Transaction{
+			ID:                    fmt.Sprintf("TXN-20240101-%08d", i),
+			ExternalTransactionID: fmt.Sprintf("EXT-PRAGMATIC-%08d", i),
+			VendorBetID:           fmt.Sprintf("BET-%08d", i),
+			RoundID:               fmt.Sprintf("ROUND-%08d", i/10),
+			VendorID:              i%10 + 1,
+			VendorCode:            "PRAGMATIC",
+			VendorLineID:          1,
+			GameCategoryID:        i%6 + 1,
+			HouseID:               1,
+			MasterAgentID:         i%36 + 1,
+			AgentID:               i%54 + 1,
+			CurrencyID:            i%8 + 1,
+			CurrencyCode:          "USDT",
+			BetAmount:             "125.500000",
+			WinAmount:             "250.000000",
+			WinLoss:               "124.500000",
+			SettledAt:             "2024-01-01T00:00:00Z",
+		}
+	}
+	txns[1].VendorCode = `quoted "vendor", with \ backslash`
+	txns[2].RoundID = "<script>&amp;</script>"
+	txns[3].CurrencyCode = "ctrl\n\r\t\b\f\x00\x1f"
+	txns[4].VendorBetID = "invalid \xff utf8 and \u2028\u2029 separators"
+	txns[5].SettledAt = "unicode \u2713 \u65e5\u672c"
+	txns[6].VendorID = -42
+	txns[7].RoundID = " leading space"
+	return txns
+}
+
+func TestTransactionJSONMatchesMarshal(t *testing.T) {
+	for _, txn := range testTransactions() {
+		want, err := json.Marshal(txn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := txn.AppendJSON(nil); !bytes.Equal(got, want) {
+			t.Fatalf("encoding differs from json.Marshal:\ngot  %s\nwant %s", got, want)
+		}
+	}
+}
+
+func TestTransactionCSVMatchesEncodingCSV(t *testing.T) {
+	for _, txn := range testTransactions() {
+		var want bytes.Buffer
+		w := csv.NewWriter(&want)
+		w.Write([]string{
+			txn.ID, txn.ExternalTransactionID, txn.VendorBetID, txn.RoundID,
+			strconv.Itoa(txn.VendorID), txn.VendorCode, strconv.Itoa(txn.VendorLineID),
+			strconv.Itoa(txn.GameCategoryID), strconv.Itoa(txn.HouseID),
+			strconv.Itoa(txn.MasterAgentID), strconv.Itoa(txn.AgentID),
+			strconv.Itoa(txn.CurrencyID), txn.CurrencyCode, txn.BetAmount,
+			txn.WinAmount, txn.WinLoss, txn.SettledAt,
+		})
+		w.Flush()
+		if got := txn.AppendCSV(nil); !bytes.Equal(got, want.Bytes()) {
+			t.Fatalf("encoding differs from encoding/csv:\ngot  %s\nwant %s", got, want.Bytes())
+		}
+	}
+}
+
+func TestTransactionAvroFollowsSchema(t *testing.T) {
+	var schema struct {
+		Fields []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(models.TransactionAvroSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, txn := range testTransactions() {
+		// Decode the record field by field and compare with the JSON values
+		var want map[string]any
+		if err := json.Unmarshal(txn.AppendJSON(nil), &want); err != nil {
+			t.Fatal(err)
+		}
+		data := txn.AppendAvro(nil)
+		for _, f := range schema.Fields {
+			n, size := binary.Varint(data)
+			if size <= 0 {
+				t.Fatalf("field %s: bad varint", f.Name)
+			}
+			data = data[size:]
+			switch f.Type {
+			case "long":
+				if float64(n) != want[f.Name] {
+					t.Fatalf("field %s: got %d, want %v", f.Name, n, want[f.Name])
+				}
+			case "string":
+				// JSON replaces invalid UTF-8; Avro strings carry the raw bytes
+				if got := strings.ToValidUTF8(string(data[:n]), "\ufffd"); got != want[f.Name] {
+					t.Fatalf("field %s: got %q, want %q", f.Name, got, want[f.Name])
+				}
+				data = data[n:]
+			default:
+				t.Fatalf("field %s: unexpected type %s", f.Name, f.Type)
+			}
+		}
+		if len(data) != 0 {
+			t.Fatalf("%d trailing bytes", len(data))
+		}
+	}
+}
+
+// decodeMsgpack decodes one value of the types the generated marshalers
+// write, returning it with the rest of data. Integers decode as float64, the
+// way encoding/json decodes numbers.
+func decodeMsgpack(data []byte) (any, []byte, error) {
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("unexpected end of data")
+	}
+	b, data := data[0], data[1:]
+	fixed := func(n int) ([]byte, error) {
+		if len(data) < n {
+			return nil, fmt.Errorf("truncated value of type 0x%x", b)
+		}
+		v := data[:n]
+		data = data[n:]
+		return v, nil
+	}
+	str := func(n int) (any, []byte, error) {
+		v, err := fixed(n)
+		return strings.ToValidUTF8(string(v), "\ufffd"), data, err
+	}
+	switch {
+	case b <= 0x7f:
+		return float64(b), data, nil
+	case b >= 0xe0:
+		return float64(int8(b)), data, nil
+	case b&0xe0 == 0xa0:
+		return str(int(b & 0x1f))
+	case b&0xf0 == 0x80:
+		return decodeMsgpackMap(int(b&0x0f), data)
+	case b == 0xde:
+		if len(data) < 2 {
+			return nil, nil, fmt.Errorf("truncated map header")
+		}
+		return decodeMsgpackMap(int(binary.BigEndian.Uint16(data)), data[2:])
+	}
+	var size int
+	switch b {
+	case 0xc2, 0xc3:
+		return b == 0xc3, data, nil
+	case 0xcc, 0xd0, 0xd9:
+		size = 1
+	case 0xcd, 0xd1, 0xda:
+		size = 2
+	case 0xce, 0xd2, 0xdb:
+		size = 4
+	case 0xcf, 0xd3, 0xcb:
+		size = 8
+	default:
+		return nil, nil, fmt.Errorf("unsupported type 0x%x", b)
+	}
+	v, err := fixed(size)
+	if err != nil {
+		return nil, nil, err
+	}
+	var u uint64
+	for _, c := range v {
+		u = u<<8 | uint64(c)
+	}
+	switch b {
+	case 0xd9, 0xda, 0xdb:
+		return str(int(u))
+	case 0xcb:
+		return math.Float64frombits(u), data, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return float64(u), data, nil
+	}
+	// Sign-extend the int family
+	shift := 64 - 8*size
+	return float64(int64(u<<shift) >> shift), data, nil
+}
+
+// decodeMsgpackMap decodes n string-keyed pairs
+func decodeMsgpackMap(n int, data []byte) (any, []byte, error) {
+	m := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		key, rest, err := decodeMsgpack(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		k, ok := key.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("map key %v is not a string", key)
+		}
+		if m[k], data, err = decodeMsgpack(rest); err != nil {
+			return nil, nil, err
+		}
+	}
+	return m, data, nil
+}
+
+func TestTransactionMsgpackMatchesJSON(t *testing.T) {
+	txns := testTransactions()
+	txns[0].MasterAgentID = 200
+	txns[1].AgentID = 70000
+	txns[2].CurrencyID = -200
+	txns[3].VendorLineID = -40000
+	txns[4].HouseID = 1 << 40
+	txns[5].RoundID = strings.Repeat("r", 300)
+	for _, txn := range txns {
+		var want map[string]any
+		if err := json.Unmarshal(txn.AppendJSON(nil), &want); err != nil {
+			t.Fatal(err)
+		}
+		got, rest, err := decodeMsgpack(txn.AppendMsgpack(nil))
+		if err != nil {
+			t.Fatalf("%s: %v", txn.ID, err)
+		}
+		if len(rest) != 0 {
+			t.Fatalf("%s: %d trailing bytes", txn.ID, len(rest))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: decoded %v, want %v", txn.ID, got, want)
+		}
+	}
+}
+
+func TestAppendMsgpackInt(t *testing.T) {
+	for v, want := range map[int64]string{
+		0:             "00",
+		127:           "7f",
+		128:           "cc80",
+		-1:            "ff",
+		-32:           "e0",
+		-33:           "d0df",
+		-129:          "d1ff7f",
+		65536:         "ce00010000",
+		-32769:        "d2ffff7fff",
+		1 << 32:       "cf0000000100000000",
+		math.MinInt64: "d38000000000000000",
+	} {
+		if got := fmt.Sprintf("%x", codec.AppendMsgpackInt(nil, v)); got != want {
+			t.Errorf("%d: got %s, want %s", v, got, want)
+		}
+	}
+}
+
+func TestTransactionJSONFollowsSchema(t *testing.T) {
+	schema, err := codec.ParseJSONSchema(models.TransactionJSONSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, txn := range testTransactions() {
+		if err := schema.Validate(txn.AppendJSON(nil)); err != nil {
+			t.Fatalf("%s: %v", txn.ID, err)
+		}
+	}
+
+	for doc, want := range map[string]string{
+		`[1]`:          "not a JSON object",
+		`{"id":"TXN"}`: "missing required property",
+		strings.Replace(string(testTransactions()[0].AppendJSON(nil)), `"vendor_id":1`, `"vendor_id":"1"`, 1): "property vendor_id: string is not of type integer",
+		strings.Replace(string(testTransactions()[0].AppendJSON(nil)), `"vendor_id":1`, `"vendor_id":1.5`, 1): "property vendor_id: number is not of type integer",
+		strings.Replace(string(testTransactions()[0].AppendJSON(nil)), `{"id"`, `{"extra":true,"id"`, 1):      "unexpected property extra",
+	} {
+		if err := schema.Validate([]byte(doc)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate(%.40s...) = %v, want %q", doc, err, want)
+		}
+	}
+}
+
+func TestValidated(t *testing.T) {
+	format, _ := codec.Lookup("json")
+	var invalid []error
+	format = codec.Validated(format, func(data []byte) error {
+		if !bytes.Contains(data, []byte("PRAGMATIC")) {
+			return fmt.Errorf("no vendor in %s", data)
+		}
+		return nil
+	}, func(err error) { invalid = append(invalid, err) })
+
+	txns := testTransactions()
+	txns[1].VendorCode = "EGT"
+	txns[1].ExternalTransactionID = "EXT-EGT-1"
+	buf := []byte("prefix")
+	for _, txn := range txns {
+		buf = format.Append(txn, buf)
+	}
+	if len(invalid) != 1 {
+		t.Errorf("%d invalid records, want 1", len(invalid))
+	}
+	if !bytes.HasPrefix(buf, []byte("prefix{")) {
+		t.Errorf("appended records changed the prefix: %.20s", buf)
+	}
+}
+
+func TestTransactionProtoMatchesAvro(t *testing.T) {
+	var schema struct {
+		Fields []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(models.TransactionAvroSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+	protoTypes := map[string]string{"string": "string", "long": "int64"}
+
+	var lines []string
+	for _, line := range strings.Split(models.TransactionProtoSchema, "\n") {
+		if strings.HasPrefix(line, "  ") {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	if len(lines) != len(schema.Fields) {
+		t.Fatalf("%d proto fields, %d Avro fields", len(lines), len(schema.Fields))
+	}
+	for i, f := range schema.Fields {
+		if want := fmt.Sprintf("%s %s = %d;", protoTypes[f.Type], f.Name, i+1); lines[i] != want {
+			t.Errorf("proto field %d = %q, want %q", i+1, lines[i], want)
+		}
+	}
+}
+
+func TestAppendJSONFloatMatchesMarshal(t *testing.T) {
+	for _, v := range []float64{0, 1, -1.5, 123456.789, 1e-7, 2.5e-9, 1e20, 1e21, 1.5e300, math.SmallestNonzeroFloat64} {
+		want, _ := json.Marshal(v)
+		if got := codec.AppendJSONFloat(nil, v); !bytes.Equal(got, want) {
+			t.Errorf("%v: got %s, want %s", v, got, want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"json", "csv", "avro", "msgpack"} {
+		if _, err := codec.Lookup(name); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := codec.Lookup("xml"); err == nil {
+		t.Error("expected error for unknown serialization")
+	}
+}
+
+// BenchmarkTransaction measures each registered format on one core, the
+// budget being well over 100k msg/s:
+//
+//	go test -run xxx -bench Transaction -cpu 1 ./internal/codec
+func BenchmarkTransaction(b *testing.B) {
+	txns := testTransactions()
+	for _, name := range codec.Names() {
+		format, _ := codec.Lookup(name)
+		b.Run(name, func(b *testing.B) {
+			buf := make([]byte, 0, 512)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buf = format.Append(txns[i%len(txns)], buf[:0])
+			}
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msgs/sec")
+		})
+	}
+	b.Run("encoding-json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			json.Marshal(txns[i%len(txns)])
+		}
+		b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msgs/sec")
+	})
+}
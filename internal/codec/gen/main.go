@@ -0,0 +1,299 @@
+// Command gen writes reflection-free JSON, CSV, Avro and MessagePack
+// marshalers for model structs, implementing codec.Marshaler, with the Avro,
+// JSON Schema and Protobuf definitions they follow. Run it through
+// go:generate from the package holding the types:
+//
+//	//go:generate go run ../codec/gen -type Transaction -output transaction_codec.go
+//
+// Supported field types are string, the signed integers, float64 and bool.
+// JSON names come from json tags; fields tagged "-" are skipped.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "Comma-separated struct types to generate marshalers for")
+	input := flag.String("input", os.Getenv("GOFILE"), "Go source file declaring the types")
+	output := flag.String("output", "", "Generated file to write")
+	namespace := flag.String("namespace", "message_producer", "Avro schema namespace")
+	flag.Parse()
+
+	if *typeNames == "" || *input == "" || *output == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	src, err := generate(*input, strings.Split(*typeNames, ","), *namespace)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// field is one serialized struct field
+type field struct {
+	goName    string
+	jsonName  string
+	kind      string // string, int, float or bool
+	avroType  string
+	jsonType  string // JSON Schema type
+	protoType string
+}
+
+// generate parses input and returns the formatted generated source
+func generate(input string, typeNames []string, namespace string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, input, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", input, err)
+	}
+
+	var body bytes.Buffer
+	for _, name := range typeNames {
+		fields, err := structFields(file, name)
+		if err != nil {
+			return nil, err
+		}
+		writeSchema(&body, name, namespace, fields)
+		writeJSON(&body, name, fields)
+		writeCSV(&body, name, fields)
+		writeAvro(&body, name, fields)
+		writeMsgpack(&body, name, fields)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by codec/gen; DO NOT EDIT.\n\npackage %s\n\nimport (\n", file.Name.Name)
+	if bytes.Contains(body.Bytes(), []byte("strconv.")) {
+		buf.WriteString("\t\"strconv\"\n\n")
+	}
+	buf.WriteString("\t\"github.com/supratick/message_producer/internal/codec\"\n)\n")
+	buf.Write(body.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+	return src, nil
+}
+
+func structFields(file *ast.File, name string) ([]field, error) {
+	obj := file.Scope.Lookup(name)
+	if obj == nil {
+		return nil, fmt.Errorf("type %s not found", name)
+	}
+	spec, ok := obj.Decl.(*ast.TypeSpec)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a type", name)
+	}
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a struct", name)
+	}
+
+	var fields []field
+	for _, f := range st.Fields.List {
+		ident, ok := f.Type.(*ast.Ident)
+		for _, n := range f.Names {
+			if !n.IsExported() {
+				continue
+			}
+			jsonName := n.Name
+			if f.Tag != nil {
+				tag, _ := strconv.Unquote(f.Tag.Value)
+				if j, ok := reflect.StructTag(tag).Lookup("json"); ok {
+					opts := strings.Split(j, ",")
+					if opts[0] == "-" {
+						continue
+					}
+					if len(opts) > 1 {
+						return nil, fmt.Errorf("%s.%s: json tag options are not supported", name, n.Name)
+					}
+					if opts[0] != "" {
+						jsonName = opts[0]
+					}
+				}
+			}
+			if !ok {
+				return nil, fmt.Errorf("%s.%s: unsupported field type", name, n.Name)
+			}
+			kind, avroType, err := kindOf(ident.Name)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", name, n.Name, err)
+			}
+			fields = append(fields, field{
+				goName:    n.Name,
+				jsonName:  jsonName,
+				kind:      kind,
+				avroType:  avroType,
+				jsonType:  jsonTypes[kind],
+				protoType: protoTypes[avroType],
+			})
+		}
+	}
+	return fields, nil
+}
+
+func kindOf(goType string) (kind, avroType string, err error) {
+	switch goType {
+	case "string":
+		return "string", "string", nil
+	case "int", "int64":
+		return "int", "long", nil
+	case "int8", "int16", "int32":
+		return "int", "int", nil
+	case "float64":
+		return "float", "double", nil
+	case "bool":
+		return "bool", "boolean", nil
+	}
+	return "", "", fmt.Errorf("unsupported field type %s", goType)
+}
+
+// jsonTypes maps field kinds to JSON Schema types
+var jsonTypes = map[string]string{"string": "string", "int": "integer", "float": "number", "bool": "boolean"}
+
+// protoTypes maps Avro types to the proto3 scalar of the same width
+var protoTypes = map[string]string{"string": "string", "long": "int64", "int": "int32", "double": "double", "boolean": "bool"}
+
+func writeSchema(buf *bytes.Buffer, name, namespace string, fields []field) {
+	var schema strings.Builder
+	fmt.Fprintf(&schema, `{"type":"record","name":%q,"namespace":%q,"fields":[`, name, namespace)
+	header := make([]string, len(fields))
+	for i, f := range fields {
+		if i > 0 {
+			schema.WriteByte(',')
+		}
+		fmt.Fprintf(&schema, `{"name":%q,"type":%q}`, f.jsonName, f.avroType)
+		header[i] = strconv.Quote(f.jsonName)
+	}
+	schema.WriteString("]}")
+
+	// Every field is always written, so every property is required
+	var jsonSchema strings.Builder
+	fmt.Fprintf(&jsonSchema, `{"$schema":"https://json-schema.org/draft/2020-12/schema","$id":"%s.%s","title":%q,"type":"object","properties":{`, namespace, name, name)
+	for i, f := range fields {
+		if i > 0 {
+			jsonSchema.WriteByte(',')
+		}
+		fmt.Fprintf(&jsonSchema, `%q:{"type":%q}`, f.jsonName, f.jsonType)
+	}
+	fmt.Fprintf(&jsonSchema, `},"required":[%s],"additionalProperties":false}`, strings.Join(header, ","))
+
+	fmt.Fprintf(buf, "\n// %sAvroSchema is the Avro schema of %s.AppendAvro\n", name, name)
+	fmt.Fprintf(buf, "const %sAvroSchema = `%s`\n", name, schema.String())
+	fmt.Fprintf(buf, "\n// %sJSONSchema is the JSON Schema of %s.AppendJSON\n", name, name)
+	fmt.Fprintf(buf, "const %sJSONSchema = `%s`\n", name, jsonSchema.String())
+
+	// Field numbers follow field order, like the Avro record
+	var proto strings.Builder
+	fmt.Fprintf(&proto, "syntax = \"proto3\";\n\npackage %s;\n\nmessage %s {\n", namespace, name)
+	for i, f := range fields {
+		fmt.Fprintf(&proto, "  %s %s = %d;\n", f.protoType, f.jsonName, i+1)
+	}
+	proto.WriteString("}\n")
+
+	fmt.Fprintf(buf, "\n// %sProtoSchema is the proto3 definition of %s, fields numbered in order\n", name, name)
+	fmt.Fprintf(buf, "const %sProtoSchema = `%s`\n", name, proto.String())
+	fmt.Fprintf(buf, "\n// %sCSVHeader names the columns of %s.AppendCSV\n", name, name)
+	fmt.Fprintf(buf, "var %sCSVHeader = []string{%s}\n", name, strings.Join(header, ", "))
+}
+
+func writeJSON(buf *bytes.Buffer, name string, fields []field) {
+	fmt.Fprintf(buf, "\n// AppendJSON appends the JSON object json.Marshal would produce\n")
+	fmt.Fprintf(buf, "func (t *%s) AppendJSON(dst []byte) []byte {\n", name)
+	for i, f := range fields {
+		sep := ","
+		if i == 0 {
+			sep = "{"
+		}
+		fmt.Fprintf(buf, "\tdst = append(dst, `%s%q:`...)\n", sep, f.jsonName)
+		switch f.kind {
+		case "string":
+			fmt.Fprintf(buf, "\tdst = codec.AppendJSONString(dst, t.%s)\n", f.goName)
+		case "int":
+			fmt.Fprintf(buf, "\tdst = strconv.AppendInt(dst, int64(t.%s), 10)\n", f.goName)
+		case "float":
+			fmt.Fprintf(buf, "\tdst = codec.AppendJSONFloat(dst, t.%s)\n", f.goName)
+		case "bool":
+			fmt.Fprintf(buf, "\tdst = strconv.AppendBool(dst, t.%s)\n", f.goName)
+		}
+	}
+	if len(fields) == 0 {
+		buf.WriteString("\tdst = append(dst, '{')\n")
+	}
+	buf.WriteString("\treturn append(dst, '}')\n}\n")
+}
+
+func writeCSV(buf *bytes.Buffer, name string, fields []field) {
+	fmt.Fprintf(buf, "\n// AppendCSV appends a CSV line in field order, quoted as encoding/csv would\n")
+	fmt.Fprintf(buf, "func (t *%s) AppendCSV(dst []byte) []byte {\n", name)
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteString("\tdst = append(dst, ',')\n")
+		}
+		switch f.kind {
+		case "string":
+			fmt.Fprintf(buf, "\tdst = codec.AppendCSVField(dst, t.%s, false)\n", f.goName)
+		case "int":
+			fmt.Fprintf(buf, "\tdst = strconv.AppendInt(dst, int64(t.%s), 10)\n", f.goName)
+		case "float":
+			fmt.Fprintf(buf, "\tdst = strconv.AppendFloat(dst, t.%s, 'g', -1, 64)\n", f.goName)
+		case "bool":
+			fmt.Fprintf(buf, "\tdst = strconv.AppendBool(dst, t.%s)\n", f.goName)
+		}
+	}
+	buf.WriteString("\treturn append(dst, '\\n')\n}\n")
+}
+
+func writeAvro(buf *bytes.Buffer, name string, fields []field) {
+	fmt.Fprintf(buf, "\n// AppendAvro appends the Avro binary encoding of %sAvroSchema\n", name)
+	fmt.Fprintf(buf, "func (t *%s) AppendAvro(dst []byte) []byte {\n", name)
+	for _, f := range fields {
+		switch f.kind {
+		case "string":
+			fmt.Fprintf(buf, "\tdst = codec.AppendAvroString(dst, t.%s)\n", f.goName)
+		case "int":
+			fmt.Fprintf(buf, "\tdst = codec.AppendAvroLong(dst, int64(t.%s))\n", f.goName)
+		case "float":
+			fmt.Fprintf(buf, "\tdst = codec.AppendAvroDouble(dst, t.%s)\n", f.goName)
+		case "bool":
+			fmt.Fprintf(buf, "\tdst = codec.AppendAvroBoolean(dst, t.%s)\n", f.goName)
+		}
+	}
+	buf.WriteString("\treturn dst\n}\n")
+}
+
+func writeMsgpack(buf *bytes.Buffer, name string, fields []field) {
+	fmt.Fprintf(buf, "\n// AppendMsgpack appends a MessagePack map keyed by the JSON field names\n")
+	fmt.Fprintf(buf, "func (t *%s) AppendMsgpack(dst []byte) []byte {\n", name)
+	fmt.Fprintf(buf, "\tdst = codec.AppendMsgpackMapHeader(dst, %d)\n", len(fields))
+	for _, f := range fields {
+		fmt.Fprintf(buf, "\tdst = codec.AppendMsgpackString(dst, %q)\n", f.jsonName)
+		switch f.kind {
+		case "string":
+			fmt.Fprintf(buf, "\tdst = codec.AppendMsgpackString(dst, t.%s)\n", f.goName)
+		case "int":
+			fmt.Fprintf(buf, "\tdst = codec.AppendMsgpackInt(dst, int64(t.%s))\n", f.goName)
+		case "float":
+			fmt.Fprintf(buf, "\tdst = codec.AppendMsgpackFloat(dst, t.%s)\n", f.goName)
+		case "bool":
+			fmt.Fprintf(buf, "\tdst = codec.AppendMsgpackBool(dst, t.%s)\n", f.goName)
+		}
+	}
+	buf.WriteString("\treturn dst\n}\n")
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// TestTransactionCodecUpToDate fails when models.Transaction changed without
+// rerunning go generate ./internal/models
+func TestTransactionCodecUpToDate(t *testing.T) {
+	want, err := generate("../../models/models.go", []string{"Transaction"}, "message_producer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("../../models/transaction_codec.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("internal/models/transaction_codec.go is stale; run go generate ./internal/models")
+	}
+}
@@ -0,0 +1,136 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// JSONSchema validates JSON documents against the subset of JSON Schema the
+// generated *JSONSchema constants use: an object of properties with scalar
+// types, required properties and additionalProperties
+type JSONSchema struct {
+	properties map[string]string // property -> type
+	required   []string
+	additional bool
+}
+
+// ParseJSONSchema compiles schema, rejecting keywords outside the supported
+// subset
+func ParseJSONSchema(schema string) (*JSONSchema, error) {
+	var raw struct {
+		Type       string `json:"type"`
+		Properties map[string]struct {
+			Type string `json:"type"`
+		} `json:"properties"`
+		Required             []string `json:"required"`
+		AdditionalProperties *bool    `json:"additionalProperties"`
+	}
+	if err := json.Unmarshal([]byte(schema), &raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	if raw.Type != "object" {
+		return nil, fmt.Errorf("unsupported JSON Schema: type %q, want object", raw.Type)
+	}
+
+	s := &JSONSchema{
+		properties: make(map[string]string, len(raw.Properties)),
+		required:   raw.Required,
+		additional: raw.AdditionalProperties == nil || *raw.AdditionalProperties,
+	}
+	for name, p := range raw.Properties {
+		switch p.Type {
+		case "string", "integer", "number", "boolean":
+		default:
+			return nil, fmt.Errorf("unsupported JSON Schema: property %s has type %q", name, p.Type)
+		}
+		s.properties[name] = p.Type
+	}
+	return s, nil
+}
+
+// Validate reports the first way doc breaks the schema, or nil
+func (s *JSONSchema) Validate(doc []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var object map[string]any
+	if err := dec.Decode(&object); err != nil {
+		return fmt.Errorf("not a JSON object: %w", err)
+	}
+	if dec.More() {
+		return fmt.Errorf("trailing data after JSON object")
+	}
+
+	for _, name := range s.required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("missing required property %s", name)
+		}
+	}
+	for name, value := range object {
+		want, ok := s.properties[name]
+		if !ok {
+			if !s.additional {
+				return fmt.Errorf("unexpected property %s", name)
+			}
+			continue
+		}
+		if !hasJSONType(value, want) {
+			return fmt.Errorf("property %s: %s is not of type %s", name, jsonTypeOf(value), want)
+		}
+	}
+	return nil
+}
+
+func hasJSONType(value any, want string) bool {
+	switch v := value.(type) {
+	case string:
+		return want == "string"
+	case bool:
+		return want == "boolean"
+	case json.Number:
+		if want == "number" {
+			return true
+		}
+		// Integers may be written with a zero fraction or an exponent
+		if want == "integer" {
+			if !strings.ContainsAny(string(v), ".eE") {
+				return true
+			}
+			f, err := v.Float64()
+			return err == nil && f == float64(int64(f))
+		}
+	}
+	return false
+}
+
+func jsonTypeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case []any:
+		return "array"
+	}
+	return "object"
+}
+
+// Validated wraps f so every record it appends is passed to validate. Records
+// that fail are still appended; their error goes to onInvalid.
+func Validated(f Format, validate func([]byte) error, onInvalid func(error)) Format {
+	inner := f.Append
+	f.Append = func(m Marshaler, dst []byte) []byte {
+		start := len(dst)
+		dst = inner(m, dst)
+		if err := validate(dst[start:]); err != nil {
+			onInvalid(err)
+		}
+		return dst
+	}
+	return f
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseClock(t *testing.T) {
+	tests := map[string]time.Duration{
+		"00:00": 0,
+		"9:30":  9*time.Hour + 30*time.Minute,
+		"18:05": 18*time.Hour + 5*time.Minute,
+		"24:00": 24 * time.Hour,
+	}
+	for in, want := range tests {
+		got, err := ParseClock(in)
+		if err != nil || got != want {
+			t.Errorf("ParseClock(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "18", "18:5", "24:01", "12:60", "-1:00", "noon"} {
+		if _, err := ParseClock(in); err == nil {
+			t.Errorf("ParseClock(%q) succeeded", in)
+		}
+	}
+}
@@ -2,62 +2,593 @@ package config
 
 import (
 	"fmt"
+	"net/mail"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
+
+	"github.com/supratick/message_producer/internal/codec"
 )
 
 // Config holds all application configuration
 type Config struct {
-	Producer ProducerConfig `yaml:"producer"`
-	Output   OutputConfig   `yaml:"output"`
-	Kafka    KafkaConfig    `yaml:"kafka"`
-	Data     DataConfig     `yaml:"data"`
-	Metrics  MetricsConfig  `yaml:"metrics"`
+	Producer   ProducerConfig   `yaml:"producer"`
+	Output     OutputConfig     `yaml:"output"`
+	Kafka      KafkaConfig      `yaml:"kafka"`
+	Data       DataConfig       `yaml:"data"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+	GRPC       GRPCConfig       `yaml:"grpc"`
+	Source     SourceConfig     `yaml:"source"`
+	Batching   BatchingConfig   `yaml:"batching"`
+	Socket     SocketConfig     `yaml:"socket"`
+	Fluent     FluentConfig     `yaml:"fluent"`
+	Syslog     SyslogConfig     `yaml:"syslog"`
+	FIFO       FIFOConfig       `yaml:"fifo"`
+	Snowflake  SnowflakeConfig  `yaml:"snowflake"`
+	Postgres   PostgresConfig   `yaml:"postgres"`
+	ClickHouse ClickHouseConfig `yaml:"clickhouse"`
+	Catalog    CatalogConfig    `yaml:"catalog"`
+	Scenario   ScenarioConfig   `yaml:"scenario"`
+	Provenance ProvenanceConfig `yaml:"provenance"`
+	Bundle     BundleConfig     `yaml:"bundle"`
+	Secrets    SecretsConfig    `yaml:"secrets"`
+	Events     EventsConfig     `yaml:"events"`
+
+	SchemaVersion SchemaVersionConfig `yaml:"schema_version"`
+
+	Notifications NotificationsConfig `yaml:"notifications"`
+	Schedule      ScheduleConfig      `yaml:"schedule"`
 }
 
 // ProducerConfig holds producer-specific settings
 type ProducerConfig struct {
-	MessageCount int `yaml:"message_count"`
-	Workers      int `yaml:"workers"`
-	BufferSize   int `yaml:"buffer_size"`
+	MessageCount int         `yaml:"message_count"`
+	Workers      WorkerCount `yaml:"workers"` // number or auto
+	BufferSize   int         `yaml:"buffer_size"`
+	OrderingKey  string      `yaml:"ordering_key"` // round_id, agent_id or master_agent_id; empty = unordered
+	// StrictOrdering emits every transaction in exact sequence order
+	StrictOrdering bool `yaml:"strict_ordering"`
+	// Transport between generation workers and sinks: channel (default) or ring
+	Transport string `yaml:"transport"`
+	// Dispatch between sinks: broadcast (default) gives every sink every
+	// transaction, split shares them out
+	Dispatch string `yaml:"dispatch"`
+	// DrainTimeout is how many seconds the sinks get to write out what was
+	// already generated after a stop; 0 = 30, -1 = stop at once
+	DrainTimeout int `yaml:"drain_timeout"`
+	// LockOSThread pins each generation worker to its own OS thread
+	LockOSThread bool `yaml:"lock_os_thread"`
+	// FixedPointAmounts does amount math in int64 minor units instead of decimals
+	FixedPointAmounts bool `yaml:"fixed_point_amounts"`
+	// ConsistencyCheck verifies cross-field invariants of generated records
+	ConsistencyCheck ConsistencyCheckConfig `yaml:"consistency_check"`
+}
+
+// ConsistencyCheckConfig samples generated transactions and checks win_loss,
+// currency, agent and house fields agree with each other and reference data
+type ConsistencyCheckConfig struct {
+	SampleRate float64 `yaml:"sample_rate"` // fraction checked, 0 = off, 1 = every record
+	Fatal      bool    `yaml:"fatal"`       // stop and fail the run on the first violation
 }
 
 // OutputConfig holds output-related configuration
 type OutputConfig struct {
-	Format    string        `yaml:"format"`
-	Directory string        `yaml:"directory"`
-	CSV       CSVConfig     `yaml:"csv"`
-	Parquet   ParquetConfig `yaml:"parquet"`
+	Format     string           `yaml:"format"`
+	Directory  string           `yaml:"directory"`
+	RunID      string           `yaml:"run_id"` // {{run_id}} in filenames, empty = generated
+	CSV        CSVConfig        `yaml:"csv"`
+	Parquet    ParquetConfig    `yaml:"parquet"`
+	DuckDB     DuckDBConfig     `yaml:"duckdb"`
+	SQLite     SQLiteConfig     `yaml:"sqlite"`
+	JSONL      JSONLConfig      `yaml:"jsonl"`
+	Msgpack    MsgpackConfig    `yaml:"msgpack"`
+	Render     RenderConfig     `yaml:"render"`
+	Arrow      ArrowConfig      `yaml:"arrow"`
+	FixedWidth FixedWidthConfig `yaml:"fixed_width"`
+	XML        XMLConfig        `yaml:"xml"`
+	XLSX       XLSXConfig       `yaml:"xlsx"`
+
+	// Sinks names the outputs to run, in order, e.g. [csv, kafka]. When set
+	// it decides which outputs are enabled, overriding format and each
+	// output's enabled flag.
+	Sinks []string `yaml:"sinks"`
+
+	// RunSubdir gives every run its own directory under directory and each
+	// destination, a filename template such as "{{run_id}}", empty = none
+	RunSubdir string          `yaml:"run_subdir"`
+	Retention RetentionConfig `yaml:"retention"`
+	Limits    LimitsConfig    `yaml:"limits"`
+	Dedup     DedupConfig     `yaml:"dedup"`
+
+	Encryption EncryptionConfig `yaml:"encryption"`
+	Delivery   DeliveryConfig   `yaml:"delivery"`
+
+	// CloseTimeout is how many seconds each writer gets to flush and close
+	// at the end of the run before it is abandoned, 0 = 30
+	CloseTimeout int `yaml:"close_timeout"`
+	// WriteRetries retries file writes failing with a transient error
+	// (EINTR, NFS timeouts) instead of failing the writer; 0 = 3, -1 = none
+	WriteRetries      int `yaml:"write_retries"`
+	WriteRetryBackoff int `yaml:"write_retry_backoff_ms"` // before the first retry, doubling; default 50
+
+	OnSinkError SinkErrorConfig `yaml:"on_sink_error"`
+	Spool       SpoolConfig     `yaml:"spool"`
+}
+
+// SpoolSinks are the network sinks a spool can be put in front of. Kafka
+// has its own failover spool.
+var SpoolSinks = []string{"socket", "fluent", "syslog", "snowflake", "postgres", "clickhouse"}
+
+// SpoolConfig puts a disk spool in front of network sinks, so generation
+// runs at disk speed while each sink is fed from its spool at its own pace
+// and reopened after it fails
+type SpoolConfig struct {
+	Enabled       bool     `yaml:"enabled"`
+	Directory     string   `yaml:"directory"`      // one subdirectory per sink, default spool; keep it across runs to forward their backlog
+	MaxSize       ByteSize `yaml:"max_size"`       // per sink; a full spool slows generation down to the sink, 0 = unlimited
+	Sinks         []string `yaml:"sinks"`          // empty = every enabled network sink
+	RetryInterval int      `yaml:"retry_interval"` // seconds before reopening a failed sink, default 5
+}
+
+// Spools reports whether sink runs behind a spool
+func (c SpoolConfig) Spools(sink string) bool {
+	if !c.Enabled || !slices.Contains(SpoolSinks, sink) {
+		return false
+	}
+	return len(c.Sinks) == 0 || slices.Contains(c.Sinks, sink)
+}
+
+// Sink error policies: what a sink failing mid-run does to the run
+const (
+	SinkErrorContinue = "continue" // the other sinks carry on; the run fails and exits 1 at the end (default)
+	SinkErrorAbort    = "abort"    // stop generating and drain the other sinks; the run fails
+	SinkErrorIgnore   = "ignore"   // the other sinks carry on and the run does not fail
+	SinkErrorFailover = "failover" // the sink's standby takes the rest of the run; it fails only if that fails too
+)
+
+// SinkErrorConfig decides what a sink failing does to the run. Policy
+// applies to every sink without an entry in Sinks.
+type SinkErrorConfig struct {
+	Policy  string            `yaml:"policy"`  // continue (default), abort, ignore or failover
+	Sinks   map[string]string `yaml:"sinks"`   // sink name -> policy
+	Standby map[string]string `yaml:"standby"` // sink name -> sink opened in its place under failover
+}
+
+// For returns the policy for sink
+func (c SinkErrorConfig) For(sink string) string {
+	if policy, ok := c.Sinks[sink]; ok {
+		return policy
+	}
+	if c.Policy == "" {
+		return SinkErrorContinue
+	}
+	return c.Policy
+}
+
+// DedupConfig drops repeated transaction IDs ahead of the file writers, so
+// files used as ground truth stay duplicate-free when replays or retries
+// repeat records
+type DedupConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Window  int  `yaml:"window"` // recent distinct IDs remembered, default 1000000
+}
+
+// RetentionConfig prunes old run subdirectories on startup
+type RetentionConfig struct {
+	KeepRuns   int `yaml:"keep_runs"`    // keep the newest N runs including this one, 0 = no limit
+	MaxAgeDays int `yaml:"max_age_days"` // remove runs older than M days, 0 = no limit
+}
+
+// LimitsConfig stops generation cleanly once the files this run has written
+// pass a total size or count, so a runaway continuous run cannot fill a
+// shared host's disk
+type LimitsConfig struct {
+	MaxBytes      ByteSize `yaml:"max_bytes"`      // e.g. 50GB, 0 = no limit
+	MaxFiles      int      `yaml:"max_files"`      // 0 = no limit
+	CheckInterval int      `yaml:"check_interval"` // seconds between checks, default 5
+}
+
+// EncryptionConfig holds settings for encrypting finished CSV and Parquet
+// files at rest
+type EncryptionConfig struct {
+	Mode          string   `yaml:"mode"`           // age or aes-gcm, empty = off
+	Recipients    []string `yaml:"recipients"`     // age public keys (age1...)
+	Key           string   `yaml:"key"`            // aes-gcm: 32 bytes, hex or base64
+	KeyFile       string   `yaml:"key_file"`       // aes-gcm: file holding the key
+	KeepPlaintext bool     `yaml:"keep_plaintext"` // keep the unencrypted file too
+}
+
+// DeliveryConfig pushes every finished output file, after encryption, to
+// a partner's SFTP or FTPS server
+type DeliveryConfig struct {
+	Protocol  string `yaml:"protocol"` // sftp, ftps or s3, empty = off
+	Host      string `yaml:"host"`
+	Port      int    `yaml:"port"` // 0 = 22 for sftp, 21 for ftps, 990 for implicit ftps
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+	Directory string `yaml:"directory"` // remote directory, empty = the login directory
+
+	PrivateKeyFile string `yaml:"private_key_file"` // sftp: key authentication
+	KnownHosts     string `yaml:"known_hosts"`      // sftp: known_hosts file holding the server's key
+	HostKey        string `yaml:"host_key"`         // sftp: or its SHA256:... fingerprint
+	Implicit       bool   `yaml:"implicit"`         // ftps: TLS from connect instead of AUTH TLS
+	CAFile         string `yaml:"ca_file"`          // ftps: CA bundle, empty = system roots
+
+	// s3 uploads to a bucket below directory, authenticating with username
+	// and password as the access key ID and secret (default the AWS_*
+	// variables). GCS works through its XML API with HMAC keys.
+	Bucket      string   `yaml:"bucket"`
+	Region      string   `yaml:"region"`      // default us-east-1, auto for GCS
+	Endpoint    string   `yaml:"endpoint"`    // S3-compatible store URL, e.g. https://storage.googleapis.com; empty = AWS
+	PartSize    ByteSize `yaml:"part_size"`   // multipart part size, default 16MiB, at least 5MiB
+	Concurrency int      `yaml:"concurrency"` // parts of a file uploaded in parallel, default 4
+	RateLimit   ByteSize `yaml:"rate_limit"`  // upload bytes per second, 0 = unlimited
+	// ThrottleRetries retries a request answered 503 SlowDown, 429 or
+	// another 5xx, 0 = 8, -1 = none
+	ThrottleRetries   int `yaml:"throttle_retries"`
+	ThrottleBackoffMs int `yaml:"throttle_backoff_ms"` // before the first, doubling with jitter up to 30s; default 200
+	// InsecureSkipVerify accepts any host key or certificate, for throwaway
+	// test servers only
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+
+	TempSuffix   string `yaml:"temp_suffix"`   // uploaded as name+suffix, then renamed; default .part
+	Retries      int    `yaml:"retries"`       // attempts after a failed upload
+	RetryBackoff int    `yaml:"retry_backoff"` // seconds before the first retry, doubling; default 2
+	Timeout      int    `yaml:"timeout"`       // seconds per connection attempt and operation, default 30
+	RemoveLocal  bool   `yaml:"remove_local"`  // delete local files once delivered
+
+	Email DeliveryEmailConfig `yaml:"email"`
+}
+
+// DeliveryEmailConfig emails a summary of delivered files (name, rows,
+// checksum) through an SMTP server
+type DeliveryEmailConfig struct {
+	Host          string   `yaml:"host"` // SMTP server, empty = off
+	Port          int      `yaml:"port"` // 0 = 587, or 465 with implicit_tls
+	Username      string   `yaml:"username"`
+	Password      string   `yaml:"password"`
+	From          string   `yaml:"from"`
+	To            []string `yaml:"to"`
+	ImplicitTLS   bool     `yaml:"implicit_tls"`   // TLS from connect instead of STARTTLS
+	SubjectPrefix string   `yaml:"subject_prefix"` // e.g. [UAT]
+	Digest        bool     `yaml:"digest"`         // one email at the end of the run instead of one per file
+	Timeout       int      `yaml:"timeout"`        // seconds per message, default 30
 }
 
 // CSVConfig holds CSV-specific settings
 type CSVConfig struct {
+	Enabled    bool        `yaml:"enabled"`
+	Filename   string      `yaml:"filename"`
+	BufferSize int         `yaml:"buffer_size"`
+	Shards     WorkerCount `yaml:"shards"`    // parallel part files, 0/1 = single file, auto
+	ShardKey   string      `yaml:"shard_key"` // route by column hash, empty = round-robin
+	// Quoting is minimal (default), strings (quote text, raw numbers) or all
+	Quoting string `yaml:"quoting"`
+	// DecimalPlaces fixes the places of amount columns; negative trims zeros
+	DecimalPlaces map[string]int `yaml:"decimal_places"`
+	// Destinations replaces output.directory with one or more copies
+	Destinations []DestinationConfig `yaml:"destinations"`
+	// Append continues an existing file (same header) instead of truncating
+	// it, cutting a partial final line left by an interrupted run
+	Append bool `yaml:"append"`
+}
+
+// DestinationConfig is one directory a file format is written to, with its
+// own rotation policy
+type DestinationConfig struct {
+	Directory      string `yaml:"directory"`
+	RotateRows     int    `yaml:"rotate_rows"`     // start a new file after N rows, 0 = never
+	RotateInterval int    `yaml:"rotate_interval"` // seconds, 0 = never
+}
+
+// ParquetConfig holds Parquet-specific settings
+type ParquetConfig struct {
+	Enabled            bool        `yaml:"enabled"`
+	Filename           string      `yaml:"filename"`
+	RowGroupSize       int         `yaml:"row_group_size"`
+	Compression        string      `yaml:"compression"`
+	CompressionLevel   int         `yaml:"compression_level"`    // gzip 1-9, zstd 1-19, lz4 1-9; 0 = default
+	Engine             string      `yaml:"engine"`               // parquet-go (default) or arrow
+	PageSize           int         `yaml:"page_size"`            // bytes, 0 = 1MB
+	DictionaryColumns  []string    `yaml:"dictionary_columns"`   // RLE dictionary encoded columns
+	BloomFilterColumns []string    `yaml:"bloom_filter_columns"` // columns with bloom filters
+	BloomFilterBits    uint        `yaml:"bloom_filter_bits"`    // bits per value, 0 = 10
+	SortColumn         string      `yaml:"sort_column"`          // sort rows within each row group
+	SortDescending     bool        `yaml:"sort_descending"`
+	PageStatistics     bool        `yaml:"page_statistics"` // min/max in page headers too
+	Shards             WorkerCount `yaml:"shards"`          // parallel part files, 0/1 = single file, auto
+	ShardKey           string      `yaml:"shard_key"`       // route by column hash, empty = round-robin
+	// Destinations replaces output.directory with one or more copies
+	Destinations []DestinationConfig `yaml:"destinations"`
+}
+
+// JSONLConfig holds settings for newline-delimited JSON files
+type JSONLConfig struct {
 	Enabled    bool   `yaml:"enabled"`
 	Filename   string `yaml:"filename"`
 	BufferSize int    `yaml:"buffer_size"`
+	Gzip       bool   `yaml:"gzip"`      // compress, adding .gz to the filename when missing
+	Shards     int    `yaml:"shards"`    // parallel part files, 0/1 = single file
+	ShardKey   string `yaml:"shard_key"` // route by column hash, empty = round-robin
+	// Destinations replaces output.directory with one or more copies
+	Destinations []DestinationConfig `yaml:"destinations"`
 }
 
-// ParquetConfig holds Parquet-specific settings
-type ParquetConfig struct {
+// MsgpackConfig holds settings for MessagePack files, a stream of one map
+// per transaction
+type MsgpackConfig struct {
+	Enabled    bool   `yaml:"enabled"` // also set by output.format: msgpack
+	Filename   string `yaml:"filename"`
+	BufferSize int    `yaml:"buffer_size"`
+	Gzip       bool   `yaml:"gzip"`      // compress, adding .gz to the filename when missing
+	Shards     int    `yaml:"shards"`    // parallel part files, 0/1 = single file
+	ShardKey   string `yaml:"shard_key"` // route by column hash, empty = round-robin
+	// Destinations replaces output.directory with one or more copies
+	Destinations []DestinationConfig `yaml:"destinations"`
+}
+
+// RenderConfig renders every transaction through a Go text/template, for
+// fixed-width and other legacy line formats
+type RenderConfig struct {
 	Enabled      bool   `yaml:"enabled"`
 	Filename     string `yaml:"filename"`
-	RowGroupSize int    `yaml:"row_group_size"`
-	Compression  string `yaml:"compression"`
+	Template     string `yaml:"template"`      // inline record template
+	TemplateFile string `yaml:"template_file"` // or a file holding it
+	Header       string `yaml:"header"`        // first line of each file, filename placeholders allowed
+	LineEnding   string `yaml:"line_ending"`   // lf (default), crlf or none
+	BufferSize   int    `yaml:"buffer_size"`
+	// Destinations replaces output.directory with one or more copies
+	Destinations []DestinationConfig `yaml:"destinations"`
+}
+
+// ArrowConfig holds settings for Arrow IPC (Feather) files
+type ArrowConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	Filename    string `yaml:"filename"`
+	Format      string `yaml:"format"`      // file (default, Feather v2) or stream
+	BatchSize   int    `yaml:"batch_size"`  // rows per record batch, 0 = parquet row_group_size
+	Compression string `yaml:"compression"` // none (default), lz4 or zstd
+	// Destinations replaces output.directory with one or more copies
+	Destinations []DestinationConfig `yaml:"destinations"`
+}
+
+// FixedWidthConfig lays out every transaction as a fixed-width flat-file
+// record in an 8-bit encoding, for legacy settlement systems
+type FixedWidthConfig struct {
+	Enabled    bool                     `yaml:"enabled"`
+	Filename   string                   `yaml:"filename"`
+	Encoding   string                   `yaml:"encoding"`    // ascii (default), latin1 or ebcdic (code page 037)
+	LineEnding string                   `yaml:"line_ending"` // lf (default), crlf or none
+	Overflow   string                   `yaml:"overflow"`    // text longer than its column: truncate (default), truncate_left or error
+	Header     bool                     `yaml:"header"`      // column names as a first record
+	BufferSize int                      `yaml:"buffer_size"`
+	Columns    []FixedWidthColumnConfig `yaml:"columns"`
+	// Destinations replaces output.directory with one or more copies
+	Destinations []DestinationConfig `yaml:"destinations"`
+}
+
+// FixedWidthColumnConfig is one field of the fixed-width record layout
+type FixedWidthColumnConfig struct {
+	Name            string `yaml:"name"`             // transaction column, as in the CSV header
+	Width           int    `yaml:"width"`            // characters, which are bytes in every encoding
+	Align           string `yaml:"align"`            // left or right, default right for numbers and left for text
+	Pad             string `yaml:"pad"`              // one padding character, default space
+	ImpliedDecimals int    `yaml:"implied_decimals"` // amounts as integers, e.g. 10.5 as 1050 with 2
+	Overflow        string `yaml:"overflow"`         // overrides fixed_width overflow for this column
+}
+
+// XMLConfig holds settings for XML batch files
+type XMLConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	Filename      string `yaml:"filename"`
+	RootElement   string `yaml:"root_element"`   // default transactions
+	RecordElement string `yaml:"record_element"` // default transaction
+	Namespace     string `yaml:"namespace"`      // xmlns of the root element, empty = none
+	Pretty        bool   `yaml:"pretty"`         // indented; compact writes one record per line
+	BufferSize    int    `yaml:"buffer_size"`
+	// Destinations replaces output.directory with one or more copies
+	Destinations []DestinationConfig `yaml:"destinations"`
+}
+
+// XLSXConfig holds settings for the Excel sample of a run's first
+// transactions
+type XLSXConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Filename string `yaml:"filename"`
+	MaxRows  int    `yaml:"max_rows"` // transactions in the sample, 0 = 1000
+}
+
+// DuckDBConfig holds DuckDB-specific settings
+type DuckDBConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Filename  string `yaml:"filename"`
+	Table     string `yaml:"table"`
+	BatchSize int    `yaml:"batch_size"`
+}
+
+// SQLiteConfig holds SQLite-specific settings
+type SQLiteConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Filename  string `yaml:"filename"`
+	Table     string `yaml:"table"`
+	BatchSize int    `yaml:"batch_size"`
 }
 
 // KafkaConfig holds Kafka-related configuration
 type KafkaConfig struct {
-	Enabled        bool     `yaml:"enabled"`
-	Brokers        []string `yaml:"brokers"`
-	Topic          string   `yaml:"topic"`
-	Compression    string   `yaml:"compression"`
-	BatchSize      int      `yaml:"batch_size"`
-	FlushFrequency int      `yaml:"flush_frequency"`
-	Async          bool     `yaml:"async"`
+	Enabled          bool     `yaml:"enabled"`
+	Brokers          []string `yaml:"brokers"`
+	Topic            string   `yaml:"topic"`
+	Compression      string   `yaml:"compression"`
+	CompressionLevel int      `yaml:"compression_level"` // gzip 1-9 or zstd 1-19; 0 = default
+	BatchSize        int      `yaml:"batch_size"`
+	FlushFrequency   int      `yaml:"flush_frequency"`
+	Async            bool     `yaml:"async"`
+	Serialization    string   `yaml:"serialization"` // json (default), avro or csv
+	// PayloadValidation checks every JSON message against the Transaction
+	// JSON Schema: warn logs violations, fatal also stops the run; empty = off
+	PayloadValidation string `yaml:"payload_validation"`
+
+	// Flow control, so a slow broker cannot grow producer memory without
+	// bound: requests per broker connection (0 = 5), messages queued in
+	// each internal channel (0 = 10000) and bytes of unacknowledged
+	// messages (0 = unlimited)
+	MaxOpenRequests   int      `yaml:"max_open_requests"`
+	ChannelBufferSize int      `yaml:"channel_buffer_size"`
+	MaxInFlightBytes  ByteSize `yaml:"max_in_flight_bytes"`
+
+	SASL       KafkaSASLConfig       `yaml:"sasl"`
+	TLS        bool                  `yaml:"tls"`
+	Encryption KafkaEncryptionConfig `yaml:"encryption"`
+	Probe      KafkaProbeConfig      `yaml:"probe"`
+	Key        KafkaKeyConfig        `yaml:"key"`
+	// SchemaCheck tests the value schema against the registry before producing
+	SchemaCheck KafkaSchemaCheckConfig `yaml:"schema_check"`
+
+	PartitionReport KafkaPartitionReportConfig `yaml:"partition_report"`
+	Rates           KafkaRatesConfig           `yaml:"rates"`
+	Commissions     KafkaCommissionsConfig     `yaml:"commissions"`
+	Metadata        KafkaMetadataConfig        `yaml:"metadata"`
+	Trace           KafkaTraceConfig           `yaml:"trace"`
+	Regions         KafkaRegionsConfig         `yaml:"regions"`
+	Failover        KafkaFailoverConfig        `yaml:"failover"`
+	ClusterWatch    KafkaClusterWatchConfig    `yaml:"cluster_watch"`
+}
+
+// KafkaClusterWatchConfig forces periodic metadata refreshes on the producer
+// and logs them with partition leader changes and producer retries, so
+// throughput dips in chaos tests can be lined up with cluster events
+type KafkaClusterWatchConfig struct {
+	Enabled         bool `yaml:"enabled"`
+	RefreshInterval int  `yaml:"refresh_interval"` // seconds between metadata refreshes, default 5
+}
+
+// KafkaFailoverConfig spools messages the brokers do not take to local
+// NDJSON files and forwards them once the brokers are back
+type KafkaFailoverConfig struct {
+	Enabled       bool     `yaml:"enabled"`
+	SpoolDir      string   `yaml:"spool_dir"`      // default kafka-spool; keep it across runs to forward their backlog
+	SpoolLimit    ByteSize `yaml:"spool_limit"`    // drop beyond this, 0 = unlimited
+	RetryInterval int      `yaml:"retry_interval"` // seconds between broker checks while down, default 10
+}
+
+// KafkaRegionsConfig tags transactions with a simulated origin region, carried
+// in a message header, and optionally routes regions to their own cluster or
+// topic for geo-replication and failover tests
+type KafkaRegionsConfig struct {
+	Weights map[string]float64          `yaml:"weights"` // region -> relative share, empty = untagged
+	Routes  map[string]KafkaRegionRoute `yaml:"routes"`  // region -> destination; other regions use brokers/topic
+}
+
+// KafkaRegionRoute is the destination of one region's transactions
+type KafkaRegionRoute struct {
+	Brokers []string `yaml:"brokers"` // default kafka brokers
+	Topic   string   `yaml:"topic"`   // default kafka topic
+}
+
+// KafkaTraceConfig gives every transaction a UUIDv7 trace ID, carried in a
+// message header, for lineage tests
+type KafkaTraceConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Scope   string `yaml:"scope"`  // transaction (default), round or player: which transactions share an ID
+	Header  string `yaml:"header"` // default trace-id
+}
+
+// KafkaMetadataConfig publishes periodic generator-state records (rate,
+// scenario phase and anomalies, run_id) so results can be joined with the
+// generator configuration in effect at the time
+type KafkaMetadataConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Topic    string `yaml:"topic"`    // default <topic>-metadata
+	Interval int    `yaml:"interval"` // seconds between records, default 10
+}
+
+// KafkaCommissionsConfig adds sub-agents below every agent and publishes
+// periodic commission settlements aggregated up the agent hierarchy
+type KafkaCommissionsConfig struct {
+	Enabled   bool                  `yaml:"enabled"`
+	Topic     string                `yaml:"topic"`      // default <topic>-commissions
+	Interval  int                   `yaml:"interval"`   // seconds per settlement period, default 60
+	SubAgents int                   `yaml:"sub_agents"` // per agent, 1-99, default 3
+	Rates     CommissionRatesConfig `yaml:"rates"`
+}
+
+// CommissionRatesConfig is the share of GGR each level earns; all zero uses
+// 0.05 / 0.10 / 0.20
+type CommissionRatesConfig struct {
+	MasterAgent float64 `yaml:"master_agent"`
+	Agent       float64 `yaml:"agent"`
+	SubAgent    float64 `yaml:"sub_agent"`
+}
+
+// KafkaRatesConfig simulates currency rates as a random walk, publishes each
+// rate to a rates topic and tags transactions with the rate in effect
+type KafkaRatesConfig struct {
+	Enabled    bool    `yaml:"enabled"`
+	Topic      string  `yaml:"topic"`      // default <topic>-rates
+	Interval   int     `yaml:"interval"`   // seconds between rate steps, default 60
+	Volatility float64 `yaml:"volatility"` // standard deviation of each step's log change, default 0.001
+}
+
+// KafkaPartitionReportConfig compares the topic's partition offsets before and
+// after the run to report how messages were spread across partitions
+type KafkaPartitionReportConfig struct {
+	Enabled bool    `yaml:"enabled"`
+	MaxSkew float64 `yaml:"max_skew"` // busiest partition over the mean before warning, default 1.5
+}
+
+// KafkaKeyConfig selects how message keys are encoded
+type KafkaKeyConfig struct {
+	Format   string               `yaml:"format"` // string (default, transaction ID), json or avro
+	Fields   []string             `yaml:"fields"` // json/avro key columns, default agent_id, round_id
+	Registry SchemaRegistryConfig `yaml:"registry"`
+}
+
+// KafkaSchemaCheckConfig checks the schema of the value serialization (avro
+// or json) against the latest version of the value subject in the schema
+// registry of kafka.key.registry before any message is produced
+type KafkaSchemaCheckConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	Subject        string `yaml:"subject"`         // default <topic>-value
+	OnIncompatible string `yaml:"on_incompatible"` // fail (default) or warn
+}
+
+// SchemaRegistryConfig locates the schema registry that avro keys are
+// registered with and value schemas are checked against
+type SchemaRegistryConfig struct {
+	URL      string `yaml:"url"`
+	Subject  string `yaml:"subject"` // default <topic>-key
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// KafkaProbeConfig stamps a sample of messages with their send time and
+// consumes them back to report produce-to-consume latency percentiles
+type KafkaProbeConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Every   int  `yaml:"every"` // stamp every Nth message, default 100
+	Grace   int  `yaml:"grace"` // seconds to wait for outstanding probes at the end, default 10
+}
+
+// KafkaSASLConfig holds SASL/PLAIN credentials; values may be secret references
+type KafkaSASLConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// KafkaEncryptionConfig holds settings for AES-GCM payload envelopes
+type KafkaEncryptionConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	KeyID   string `yaml:"key_id"` // sent in the enc-key-id header
+	Key     string `yaml:"key"`    // 32 bytes, hex or base64
 }
 
 // DataConfig holds paths to data files
@@ -66,16 +597,458 @@ type DataConfig struct {
 	Agents         string `yaml:"agents"`
 	GameCategories string `yaml:"game_categories"`
 	Currencies     string `yaml:"currencies"`
+	Houses         string `yaml:"houses"` // optional brands reference, empty = house 1 only
+
+	// Datasets are custom reference files, e.g. promotions, by name
+	Datasets map[string]DatasetConfig `yaml:"datasets"`
+	// Fields add values drawn from the datasets to every transaction
+	Fields []FieldConfig `yaml:"fields"`
+}
+
+// DatasetConfig is a custom reference dataset, read from a file (a JSON array
+// of objects) or given inline as exactly one of values, weights or records
+type DatasetConfig struct {
+	File string `yaml:"file"`
+	// Values is an enumeration; each value is a record with a value attribute
+	Values []string `yaml:"values"`
+	// Weights is a weighted enumeration, value -> weight; strategy defaults
+	// to weighted
+	Weights map[string]float64 `yaml:"weights"`
+	// Records is an inline lookup table with any attributes
+	Records         []map[string]any `yaml:"records"`
+	Strategy        string           `yaml:"strategy"`         // uniform (default), weighted, round_robin or agent
+	WeightAttribute string           `yaml:"weight_attribute"` // weighted: attribute holding the weight, default weight
+}
+
+// FieldConfig fills a named value with an attribute of the record chosen
+// from a dataset, or with a built-in generator
+type FieldConfig struct {
+	Name      string `yaml:"name"`
+	Dataset   string `yaml:"dataset"`
+	Attribute string `yaml:"attribute"` // default value, the attribute of values and weights records
+	// Generator replaces the dataset: uuid, ulid, name, first_name,
+	// last_name, email, ipv4, ipv6, user_agent, country, lorem or counter
+	Generator string `yaml:"generator"`
+	Length    int    `yaml:"length"` // lorem: characters, default 32
+	Start     int64  `yaml:"start"`  // counter: first value
+	// References takes values already emitted by another field or the id,
+	// external_transaction_id, vendor_bet_id or round_id column
+	References string `yaml:"references"`
+	Window     int    `yaml:"window"` // references: seconds back a value may be from, 0 = any remembered
+	Pool       int    `yaml:"pool"`   // references: values remembered, default 10000
 }
 
 // MetricsConfig holds metrics-related configuration
 type MetricsConfig struct {
-	Interval int  `yaml:"interval"`
-	Detailed bool `yaml:"detailed"`
+	Interval  int       `yaml:"interval"`
+	Detailed  bool      `yaml:"detailed"`
+	PerWorker bool      `yaml:"per_worker"` // per-generation-worker rates
+	SLO       SLOConfig `yaml:"slo"`
+}
+
+// SLOConfig declares objectives evaluated per window; a missed objective
+// fails the run with exit code 3 so perf CI can gate on it
+type SLOConfig struct {
+	MinRate      float64 `yaml:"min_rate"`       // messages/sec every window must sustain, 0 = none
+	MaxErrorRate float64 `yaml:"max_error_rate"` // fraction, e.g. 0.001 = 0.1%, 0 = none
+	Window       int     `yaml:"window"`         // seconds per window, default metrics interval
+	Warmup       int     `yaml:"warmup"`         // seconds ignored at the start
+}
+
+// GRPCConfig holds settings for the gRPC streaming source mode
+type GRPCConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	Address     string `yaml:"address"`
+	DefaultRate int    `yaml:"default_rate"` // messages/sec per client, 0 = unthrottled
+	MaxRate     int    `yaml:"max_rate"`     // upper bound on client-requested rates, 0 = no limit
+}
+
+// SourceConfig replaces generation with records read from stdin or a Kafka
+// topic, transformed and written to the configured sinks
+type SourceConfig struct {
+	Type      string                `yaml:"type"`   // empty = generate, stdin or kafka
+	Format    string                `yaml:"format"` // json (default), csv, or avro for kafka
+	Kafka     SourceKafkaConfig     `yaml:"kafka"`
+	Transform SourceTransformConfig `yaml:"transform"`
+	Replay    SourceReplayConfig    `yaml:"replay"`
+}
+
+// SourceReplayConfig replays source records at their original pace by
+// settled_at, with the gaps between them rescaled; empty = as fast as the
+// sinks take them
+type SourceReplayConfig struct {
+	Speed    string `yaml:"speed"`    // e.g. 10x or 0.5x, or the -speed flag
+	Compress string `yaml:"compress"` // original:wall time, e.g. 24h:1h; instead of speed
+	MaxGap   int    `yaml:"max_gap"`  // seconds, longest pause after rescaling, 0 = no cap
+}
+
+// SourceKafkaConfig selects the topic a kafka source reads
+type SourceKafkaConfig struct {
+	Brokers       []string `yaml:"brokers"` // empty = kafka.brokers
+	Topic         string   `yaml:"topic"`
+	FromBeginning bool     `yaml:"from_beginning"` // default: only records produced after startup
+	StopAtEnd     bool     `yaml:"stop_at_end"`    // finish at the offsets newest at startup
+}
+
+// SourceTransformConfig rewrites source records before the sinks see them
+type SourceTransformConfig struct {
+	Set    map[string]string `yaml:"set"`     // column -> value for every record
+	NewIDs bool              `yaml:"new_ids"` // replace id with a fresh TXN-<date>-<seq>
+}
+
+// BatchingSinks are the sinks whose batches the pipeline can assemble
+var BatchingSinks = []string{"duckdb", "sqlite", "postgres", "clickhouse", "fluent", "snowflake", "socket", "syslog"}
+
+// BatchingConfig has the pipeline batch records for the sinks in
+// BatchingSinks. Default applies to each of them without its own entry.
+type BatchingConfig struct {
+	Default BatchLimits            `yaml:"default"`
+	Sinks   map[string]BatchLimits `yaml:"sinks"`
+}
+
+// BatchLimits ends a batch at whichever limit is reached first; all zero
+// leaves batching to the sink's own settings
+type BatchLimits struct {
+	MaxRecords int      `yaml:"max_records"`
+	MaxBytes   ByteSize `yaml:"max_bytes"` // approximate record data, e.g. 1MB
+	LingerMs   int      `yaml:"linger_ms"` // write a partial batch this long after its first record
+}
+
+// For returns the limits of sink
+func (c BatchingConfig) For(sink string) BatchLimits {
+	if limits, ok := c.Sinks[sink]; ok {
+		return limits
+	}
+	return c.Default
+}
+
+// SocketConfig holds settings for the raw TCP/Unix socket sink
+type SocketConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	Network     string `yaml:"network"` // tcp or unix
+	Address     string `yaml:"address"` // host:port or socket path
+	Framing     string `yaml:"framing"` // ndjson or length_prefixed
+	BufferSize  int    `yaml:"buffer_size"`
+	DialTimeout int    `yaml:"dial_timeout"` // milliseconds
+}
+
+// FluentConfig holds settings for the Fluentd forward protocol sink
+type FluentConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Address   string `yaml:"address"`
+	Tag       string `yaml:"tag"`
+	BatchSize int    `yaml:"batch_size"`
+	Timeout   int    `yaml:"timeout"` // milliseconds
+}
+
+// SyslogConfig holds settings for the RFC5424 syslog sink
+type SyslogConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Network string `yaml:"network"` // tcp or udp
+	Address string `yaml:"address"`
+	AppName string `yaml:"app_name"`
+	Timeout int    `yaml:"timeout"` // milliseconds
+}
+
+// FIFOConfig holds settings for the named pipe sink
+type FIFOConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	Path         string   `yaml:"path"`           // created if missing
+	Format       string   `yaml:"format"`         // ndjson or csv
+	NoReader     string   `yaml:"no_reader"`      // wait, drop or buffer
+	SlowReaderMs int      `yaml:"slow_reader_ms"` // how long a full pipe is waited out before drop/buffer apply
+	SpoolDir     string   `yaml:"spool_dir"`      // buffer: default the system temp dir
+	SpoolLimit   ByteSize `yaml:"spool_limit"`    // buffer: drop beyond this, 0 = unlimited
+	BufferSize   int      `yaml:"buffer_size"`
+}
+
+// SnowflakeConfig holds settings for the Snowflake sink
+type SnowflakeConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	Account        string `yaml:"account"`
+	User           string `yaml:"user"`
+	PrivateKeyPath string `yaml:"private_key_path"` // PEM-encoded RSA key registered for the user
+	PrivateKey     string `yaml:"private_key"`      // the PEM itself, e.g. a secret reference
+	Role           string `yaml:"role"`
+	Warehouse      string `yaml:"warehouse"`
+	Database       string `yaml:"database"`
+	Schema         string `yaml:"schema"`
+	Table          string `yaml:"table"`
+	Method         string `yaml:"method"`  // insert (SQL API, default) or streaming (Snowpipe Streaming)
+	Pipe           string `yaml:"pipe"`    // streaming: default <table>-STREAMING
+	Channel        string `yaml:"channel"` // streaming: default message_producer
+	BatchSize      int    `yaml:"batch_size"`
+	Timeout        int    `yaml:"timeout"` // seconds
+}
+
+// PostgresConfig holds settings for the PostgreSQL sink
+type PostgresConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Host      string `yaml:"host"` // host or host:port
+	User      string `yaml:"user"`
+	Password  string `yaml:"password"`
+	Database  string `yaml:"database"`
+	SSLMode   string `yaml:"sslmode"` // disable, prefer, require or verify-full
+	Table     string `yaml:"table"`
+	BatchSize int    `yaml:"batch_size"` // rows per COPY
+	PoolSize  int    `yaml:"pool_size"`  // connections copying in parallel
+	Timeout   int    `yaml:"timeout"`    // seconds
+}
+
+// ClickHouseConfig holds settings for the ClickHouse sink
+type ClickHouseConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Address   string `yaml:"address"` // host or host:port of the native protocol
+	User      string `yaml:"user"`
+	Password  string `yaml:"password"`
+	Database  string `yaml:"database"`
+	TLS       bool   `yaml:"tls"`
+	Table     string `yaml:"table"`
+	BlockSize int    `yaml:"block_size"` // rows per INSERT block
+	// AsyncInsert has the server buffer inserts and write them in the
+	// background; WaitForAsyncInsert acknowledges them only once written
+	AsyncInsert        bool `yaml:"async_insert"`
+	WaitForAsyncInsert bool `yaml:"wait_for_async_insert"`
+	Timeout            int  `yaml:"timeout"` // seconds
+}
+
+// CatalogConfig holds settings for registering Parquet partitions with a
+// data catalog after a run
+type CatalogConfig struct {
+	Enabled       bool     `yaml:"enabled"`
+	Type          string   `yaml:"type"` // glue or hive
+	Database      string   `yaml:"database"`
+	Table         string   `yaml:"table"`
+	Location      string   `yaml:"location"`       // where the Parquet output directory is published, e.g. s3://bucket/transactions
+	PartitionKeys []string `yaml:"partition_keys"` // key=value directory names, in table order
+	Region        string   `yaml:"region"`         // glue
+	Endpoint      string   `yaml:"endpoint"`       // glue endpoint override or WebHCat URL for hive
+	User          string   `yaml:"user"`           // hive user.name
+	Timeout       int      `yaml:"timeout"`        // seconds
+
+	// Glue credentials; empty = AWS_* environment variables
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	SessionToken    string `yaml:"session_token"`
+}
+
+// SecretsConfig holds the backends for secret references such as
+// "vault:secret/data/kafka#password" or "aws-sm:prod/kafka#password"
+type SecretsConfig struct {
+	Vault   VaultConfig      `yaml:"vault"`
+	AWS     AWSSecretsConfig `yaml:"aws"`
+	Renew   bool             `yaml:"renew"`   // keep the Vault token and leases alive
+	Timeout int              `yaml:"timeout"` // seconds
+}
+
+// VaultConfig locates Vault; empty fields fall back to VAULT_ADDR,
+// VAULT_TOKEN and VAULT_NAMESPACE
+type VaultConfig struct {
+	Address   string `yaml:"address"`
+	TokenFile string `yaml:"token_file"`
+	Namespace string `yaml:"namespace"`
+}
+
+// AWSSecretsConfig locates AWS Secrets Manager; credentials come from the
+// AWS_* environment variables
+type AWSSecretsConfig struct {
+	Region   string `yaml:"region"` // empty = AWS_REGION
+	Endpoint string `yaml:"endpoint"`
+}
+
+// ProvenanceConfig holds settings for the post-run provenance statement
+type ProvenanceConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	Filename   string `yaml:"filename"`    // written to output.directory, default manifest.json
+	SigningKey string `yaml:"signing_key"` // PEM private key path; empty = unsigned
+	KeyID      string `yaml:"key_id"`      // empty = public key fingerprint
+}
+
+// BundleConfig packages the run's manifest, config snapshot, sample data,
+// ground-truth aggregates and log into one tar.gz at completion
+type BundleConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	Path       string `yaml:"path"`        // filename template, default <output.directory>/run-{{run_id}}.tar.gz
+	SampleSize int    `yaml:"sample_size"` // transactions in sample.jsonl, default 1000
+}
+
+// SchemaVersionConfig stamps Kafka messages with the payload version and
+// can keep each version's topic and files apart, so two versions of the
+// producer can run side by side while consumers migrate
+type SchemaVersionConfig struct {
+	Version     int  `yaml:"version"`      // schema-version header on Kafka messages, 0 = none
+	SuffixTopic bool `yaml:"suffix_topic"` // transactions -> transactions.v2
+	SuffixFiles bool `yaml:"suffix_files"` // transactions.csv -> transactions.v2.csv
+}
+
+// EventsConfig holds settings for run lifecycle and progress events
+type EventsConfig struct {
+	Enabled  bool                `yaml:"enabled"`
+	Interval int                 `yaml:"interval"` // seconds between progress events, 0 = 10
+	Kafka    EventsKafkaConfig   `yaml:"kafka"`
+	Webhook  EventsWebhookConfig `yaml:"webhook"`
+}
+
+// EventsKafkaConfig publishes events to a control topic
+type EventsKafkaConfig struct {
+	Topic   string   `yaml:"topic"`   // empty = no Kafka events
+	Brokers []string `yaml:"brokers"` // empty = kafka.brokers
+}
+
+// EventsWebhookConfig posts events to an HTTP endpoint
+type EventsWebhookConfig struct {
+	URL     string            `yaml:"url"` // empty = no webhook events
+	Headers map[string]string `yaml:"headers"`
+	Timeout int               `yaml:"timeout"` // milliseconds
+}
+
+// NotificationsConfig holds settings for chat notifications about a run
+type NotificationsConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	WebhookURL     string   `yaml:"webhook_url"`     // e.g. a Slack incoming webhook
+	Format         string   `yaml:"format"`          // slack (default) or json
+	Events         []string `yaml:"events"`          // start, finish, error_threshold; empty = finish
+	ErrorThreshold int64    `yaml:"error_threshold"` // sink errors that trigger error_threshold
+	Timeout        int      `yaml:"timeout"`         // milliseconds
+}
+
+// ScheduleConfig holds the jobs run by `producer schedule`
+type ScheduleConfig struct {
+	Timezone    string              `yaml:"timezone"`     // IANA name for cron times, empty = local
+	GracePeriod int                 `yaml:"grace_period"` // seconds a job gets to stop on shutdown, 0 = 60
+	Jobs        []ScheduleJobConfig `yaml:"jobs"`
+}
+
+// ScheduleJobConfig is one cron-triggered producer run
+type ScheduleJobConfig struct {
+	Name         string            `yaml:"name"`
+	Cron         string            `yaml:"cron"`     // e.g. "0 2 * * *" or @daily
+	Config       string            `yaml:"config"`   // empty = the scheduler's own config file
+	Scenario     string            `yaml:"scenario"` // bundled scenario, as with -scenario
+	Params       map[string]string `yaml:"params"`   // template parameters, as with -param
+	Env          map[string]string `yaml:"env"`      // environment overrides, e.g. PRODUCER_MESSAGE_COUNT
+	AllowOverlap bool              `yaml:"allow_overlap"`
+}
+
+// ScenarioConfig shapes traffic and data distributions for a run. Times are
+// seconds since generation started.
+type ScenarioConfig struct {
+	Name            string                `yaml:"name"`
+	Seed            int64                 `yaml:"seed"` // 0 = time-based randomness
+	Rate            int                   `yaml:"rate"` // messages/sec, 0 = unthrottled
+	Spikes          []SpikeConfig         `yaml:"spikes"`
+	Jitter          JitterConfig          `yaml:"jitter"`
+	Arrivals        ArrivalsConfig        `yaml:"arrivals"`
+	VendorWeights   map[string]float64    `yaml:"vendor_weights"`   // unlisted vendors weigh 1
+	CurrencyWeights map[string]float64    `yaml:"currency_weights"` // unlisted currencies weigh 1
+	VendorOutages   []VendorOutageConfig  `yaml:"vendor_outages"`
+	CurrencyShocks  []CurrencyShockConfig `yaml:"currency_shocks"`
+
+	CategoryWindows  []CategoryWindowConfig `yaml:"category_windows"`
+	CategoryTimezone string                 `yaml:"category_timezone"` // IANA name for window times, empty = local
+
+	PlayerBehaviors PlayerBehaviorsConfig `yaml:"player_behaviors"`
+
+	// Templates make every record follow one of several shapes, picked by
+	// weight, for multimodal data; empty = unconstrained records
+	Templates []TemplateConfig `yaml:"templates"`
+}
+
+// JitterConfig varies the gaps between paced transactions around the mean
+// the rate sets, as real arrivals are not evenly spaced
+type JitterConfig struct {
+	Distribution string  `yaml:"distribution"` // exponential (Poisson arrivals) or uniform; empty = even gaps
+	Spread       float64 `yaml:"spread"`       // uniform only: fraction of the mean gap either side, 0-1, default 0.5
+}
+
+// ArrivalsConfig paces transactions by an arrival process instead of
+// evenly, keeping the scenario rate on average, for studying downstream
+// queueing under realistic arrival statistics
+type ArrivalsConfig struct {
+	Process string `yaml:"process"` // poisson, mmpp or bursty; empty = even gaps or jitter
+	// mmpp: rate levels the process switches between, default a quiet and
+	// a busy one
+	States []ArrivalStateConfig `yaml:"states"`
+	// bursty: Pareto on/off bursts; hurst 0.5-1 (exclusive) sets how bursty
+	// longer windows stay, default 0.8
+	Hurst float64 `yaml:"hurst"`
+	On    float64 `yaml:"on"`  // mean seconds of a burst, default 1
+	Off   float64 `yaml:"off"` // mean seconds of silence between bursts, default 1
+}
+
+// ArrivalStateConfig is one state of a Markov-modulated Poisson process
+type ArrivalStateConfig struct {
+	Multiplier float64 `yaml:"multiplier"` // relative rate, scaled so the average is the scenario rate; 0 = silent
+	Duration   float64 `yaml:"duration"`   // mean seconds in the state
+}
+
+// TemplateConfig is one record shape, e.g. small slot bets or sports parlays.
+// Constraints left empty keep the usual choice.
+type TemplateConfig struct {
+	Name           string            `yaml:"name"`
+	Weight         float64           `yaml:"weight"`
+	Categories     []string          `yaml:"categories"` // game category codes
+	Vendors        []string          `yaml:"vendors"`
+	Currencies     []string          `yaml:"currencies"`
+	BetMin         float64           `yaml:"bet_min"` // base bet range, scaled per currency like the usual sizes
+	BetMax         float64           `yaml:"bet_max"`
+	WinMultipliers []float64         `yaml:"win_multipliers"` // outcomes picked uniformly, at most two decimals
+	Fields         map[string]string `yaml:"fields"`          // constant fields, sent as Kafka headers
+}
+
+// PlayerBehaviorsConfig injects scripted responsible-gambling sessions
+// (escalating_stakes, loss_chasing, long_session, deposit_limit) and writes a
+// label for each of their transactions
+type PlayerBehaviorsConfig struct {
+	Rate          float64            `yaml:"rate"`           // fraction of transactions, 0 = off
+	Weights       map[string]float64 `yaml:"weights"`        // unlisted behaviors weigh 1
+	SessionLength int                `yaml:"session_length"` // bets per session, default 20
+	LabelsFile    string             `yaml:"labels_file"`    // in output.directory, default rg_labels.jsonl
+}
+
+// SpikeConfig multiplies the scenario rate for a window
+type SpikeConfig struct {
+	Start      int     `yaml:"start"`
+	Duration   int     `yaml:"duration"`
+	Multiplier float64 `yaml:"multiplier"`
+}
+
+// VendorOutageConfig stops a vendor from receiving bets for a window, then
+// settles a backfill fraction of the missed bets late in a burst
+type VendorOutageConfig struct {
+	Vendor   string  `yaml:"vendor"`
+	Start    int     `yaml:"start"`
+	Duration int     `yaml:"duration"`
+	Backfill float64 `yaml:"backfill"` // 0 = missed bets are lost
+}
+
+// CategoryWindowConfig opens a game category between two HH:MM times of
+// day; a category with windows is closed outside them
+type CategoryWindowConfig struct {
+	Category   string  `yaml:"category"` // game category code, e.g. LIVE_CASINO
+	Open       string  `yaml:"open"`
+	Close      string  `yaml:"close"`      // before open wraps past midnight, equal for all day
+	Multiplier float64 `yaml:"multiplier"` // category weight inside the window, 0 = 1
+}
+
+// CurrencyShockConfig scales bet amounts in a currency from start, ramping
+// to multiplier over duration, with lognormal noise of the given volatility
+type CurrencyShockConfig struct {
+	Currency   string  `yaml:"currency"`
+	Start      int     `yaml:"start"`
+	Duration   int     `yaml:"duration"`
+	Multiplier float64 `yaml:"multiplier"`
+	Volatility float64 `yaml:"volatility"`
 }
 
 // Load reads and parses the configuration file
 func Load(path string) (*Config, error) {
+	return LoadTemplate(path, nil)
+}
+
+// LoadTemplate reads a configuration file, resolving ${param} placeholders
+// from params first
+func LoadTemplate(path string, params map[string]string) (*Config, error) {
 	// Try to load .env file if it exists (non-fatal if missing)
 	_ = godotenv.Load()
 
@@ -83,6 +1056,9 @@ func Load(path string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
+	if data, err = ExpandParams(data, params); err != nil {
+		return nil, err
+	}
 
 	var cfg Config
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
@@ -99,6 +1075,15 @@ func Load(path string) (*Config, error) {
 	return &cfg, nil
 }
 
+// ApplyOverlay merges a partial YAML configuration (such as a bundled
+// scenario) over c. Settings the overlay does not mention are kept.
+func (c *Config) ApplyOverlay(data []byte) error {
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse configuration overlay: %w", err)
+	}
+	return nil
+}
+
 // ApplyEnvOverrides is a public wrapper for applyEnvOverrides
 func (c *Config) ApplyEnvOverrides() {
 	c.applyEnvOverrides()
@@ -113,15 +1098,46 @@ func (c *Config) applyEnvOverrides() {
 		}
 	}
 	if v := os.Getenv("PRODUCER_WORKERS"); v != "" {
-		if workers, err := strconv.Atoi(v); err == nil {
+		if workers, err := ParseWorkerCount(v); err == nil {
 			c.Producer.Workers = workers
 		}
 	}
+	if v := os.Getenv("PRODUCER_TRANSPORT"); v != "" {
+		c.Producer.Transport = v
+	}
+	if v := os.Getenv("PRODUCER_DISPATCH"); v != "" {
+		c.Producer.Dispatch = v
+	}
+	if v := os.Getenv("PRODUCER_DRAIN_TIMEOUT"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			c.Producer.DrainTimeout = seconds
+		}
+	}
+	if v := os.Getenv("PRODUCER_LOCK_OS_THREAD"); v != "" {
+		c.Producer.LockOSThread = v == "true"
+	}
 	if v := os.Getenv("PRODUCER_BUFFER_SIZE"); v != "" {
 		if size, err := strconv.Atoi(v); err == nil {
 			c.Producer.BufferSize = size
 		}
 	}
+	if v := os.Getenv("PRODUCER_ORDERING_KEY"); v != "" {
+		c.Producer.OrderingKey = v
+	}
+	if v := os.Getenv("PRODUCER_FIXED_POINT_AMOUNTS"); v != "" {
+		c.Producer.FixedPointAmounts = v == "true"
+	}
+	if v := os.Getenv("PRODUCER_STRICT_ORDERING"); v != "" {
+		c.Producer.StrictOrdering = v == "true"
+	}
+	if v := os.Getenv("PRODUCER_CONSISTENCY_SAMPLE_RATE"); v != "" {
+		if rate, err := strconv.ParseFloat(v, 64); err == nil {
+			c.Producer.ConsistencyCheck.SampleRate = rate
+		}
+	}
+	if v := os.Getenv("PRODUCER_CONSISTENCY_FATAL"); v != "" {
+		c.Producer.ConsistencyCheck.Fatal = v == "true"
+	}
 
 	// Output config
 	if v := os.Getenv("OUTPUT_FORMAT"); v != "" {
@@ -130,104 +1146,1347 @@ func (c *Config) applyEnvOverrides() {
 	if v := os.Getenv("OUTPUT_DIRECTORY"); v != "" {
 		c.Output.Directory = v
 	}
-
-	// CSV config
-	if v := os.Getenv("CSV_ENABLED"); v != "" {
-		c.Output.CSV.Enabled = v == "true"
+	if v := os.Getenv("OUTPUT_SINKS"); v != "" {
+		c.Output.Sinks = strings.Split(v, ",")
 	}
-	if v := os.Getenv("CSV_FILENAME"); v != "" {
-		c.Output.CSV.Filename = v
+	if v := os.Getenv("RUN_ID"); v != "" {
+		c.Output.RunID = v
 	}
-	if v := os.Getenv("CSV_BUFFER_SIZE"); v != "" {
-		if size, err := strconv.Atoi(v); err == nil {
-			c.Output.CSV.BufferSize = size
+	if v := os.Getenv("OUTPUT_RUN_SUBDIR"); v != "" {
+		c.Output.RunSubdir = v
+	}
+	if v := os.Getenv("OUTPUT_KEEP_RUNS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Output.Retention.KeepRuns = n
 		}
 	}
-
-	// Parquet config
-	if v := os.Getenv("PARQUET_ENABLED"); v != "" {
-		c.Output.Parquet.Enabled = v == "true"
+	if v := os.Getenv("OUTPUT_MAX_AGE_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Output.Retention.MaxAgeDays = n
+		}
 	}
-	if v := os.Getenv("PARQUET_FILENAME"); v != "" {
-		c.Output.Parquet.Filename = v
+	if v := os.Getenv("OUTPUT_MAX_BYTES"); v != "" {
+		if size, err := ParseByteSize(v); err == nil {
+			c.Output.Limits.MaxBytes = size
+		}
 	}
-	if v := os.Getenv("PARQUET_ROW_GROUP_SIZE"); v != "" {
-		if size, err := strconv.Atoi(v); err == nil {
-			c.Output.Parquet.RowGroupSize = size
+	if v := os.Getenv("OUTPUT_MAX_FILES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Output.Limits.MaxFiles = n
 		}
 	}
-	if v := os.Getenv("PARQUET_COMPRESSION"); v != "" {
-		c.Output.Parquet.Compression = v
+	if v := os.Getenv("OUTPUT_CLOSE_TIMEOUT"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			c.Output.CloseTimeout = seconds
+		}
 	}
-
-	// Kafka config
-	if v := os.Getenv("KAFKA_ENABLED"); v != "" {
-		c.Kafka.Enabled = v == "true"
+	if v := os.Getenv("OUTPUT_WRITE_RETRIES"); v != "" {
+		if retries, err := strconv.Atoi(v); err == nil {
+			c.Output.WriteRetries = retries
+		}
 	}
-	if v := os.Getenv("KAFKA_BROKERS"); v != "" {
-		c.Kafka.Brokers = strings.Split(v, ",")
+	if v := os.Getenv("OUTPUT_ON_SINK_ERROR"); v != "" {
+		c.Output.OnSinkError.Policy = v
 	}
-	if v := os.Getenv("KAFKA_TOPIC"); v != "" {
-		c.Kafka.Topic = v
+	if v := os.Getenv("OUTPUT_SPOOL_ENABLED"); v != "" {
+		c.Output.Spool.Enabled = v == "true"
 	}
-	if v := os.Getenv("KAFKA_COMPRESSION"); v != "" {
-		c.Kafka.Compression = v
+	if v := os.Getenv("OUTPUT_SPOOL_DIRECTORY"); v != "" {
+		c.Output.Spool.Directory = v
 	}
-	if v := os.Getenv("KAFKA_BATCH_SIZE"); v != "" {
-		if size, err := strconv.Atoi(v); err == nil {
-			c.Kafka.BatchSize = size
+	if v := os.Getenv("OUTPUT_SPOOL_MAX_SIZE"); v != "" {
+		if size, err := ParseByteSize(v); err == nil {
+			c.Output.Spool.MaxSize = size
 		}
 	}
-	if v := os.Getenv("KAFKA_FLUSH_FREQUENCY"); v != "" {
-		if freq, err := strconv.Atoi(v); err == nil {
-			c.Kafka.FlushFrequency = freq
+	if v := os.Getenv("OUTPUT_DEDUP_ENABLED"); v != "" {
+		c.Output.Dedup.Enabled = v == "true"
+	}
+	if v := os.Getenv("OUTPUT_DEDUP_WINDOW"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Output.Dedup.Window = n
+		}
+	}
+	if v := os.Getenv("OUTPUT_ENCRYPTION_MODE"); v != "" {
+		c.Output.Encryption.Mode = v
+	}
+	if v := os.Getenv("OUTPUT_ENCRYPTION_RECIPIENTS"); v != "" {
+		c.Output.Encryption.Recipients = strings.Split(v, ",")
+	}
+	if v := os.Getenv("OUTPUT_ENCRYPTION_KEY"); v != "" {
+		c.Output.Encryption.Key = v
+	}
+	if v := os.Getenv("OUTPUT_ENCRYPTION_KEY_FILE"); v != "" {
+		c.Output.Encryption.KeyFile = v
+	}
+	if v := os.Getenv("DELIVERY_PROTOCOL"); v != "" {
+		c.Output.Delivery.Protocol = v
+	}
+	if v := os.Getenv("DELIVERY_HOST"); v != "" {
+		c.Output.Delivery.Host = v
+	}
+	if v := os.Getenv("DELIVERY_PORT"); v != "" {
+		if port, err := strconv.Atoi(v); err == nil {
+			c.Output.Delivery.Port = port
+		}
+	}
+	if v := os.Getenv("DELIVERY_USERNAME"); v != "" {
+		c.Output.Delivery.Username = v
+	}
+	if v := os.Getenv("DELIVERY_PASSWORD"); v != "" {
+		c.Output.Delivery.Password = v
+	}
+	if v := os.Getenv("DELIVERY_DIRECTORY"); v != "" {
+		c.Output.Delivery.Directory = v
+	}
+	if v := os.Getenv("DELIVERY_BUCKET"); v != "" {
+		c.Output.Delivery.Bucket = v
+	}
+	if v := os.Getenv("DELIVERY_EMAIL_HOST"); v != "" {
+		c.Output.Delivery.Email.Host = v
+	}
+	if v := os.Getenv("DELIVERY_EMAIL_PORT"); v != "" {
+		if port, err := strconv.Atoi(v); err == nil {
+			c.Output.Delivery.Email.Port = port
+		}
+	}
+	if v := os.Getenv("DELIVERY_EMAIL_USERNAME"); v != "" {
+		c.Output.Delivery.Email.Username = v
+	}
+	if v := os.Getenv("DELIVERY_EMAIL_PASSWORD"); v != "" {
+		c.Output.Delivery.Email.Password = v
+	}
+	if v := os.Getenv("DELIVERY_EMAIL_FROM"); v != "" {
+		c.Output.Delivery.Email.From = v
+	}
+	if v := os.Getenv("DELIVERY_EMAIL_TO"); v != "" {
+		c.Output.Delivery.Email.To = strings.Split(v, ",")
+	}
+
+	// CSV config
+	if v := os.Getenv("CSV_ENABLED"); v != "" {
+		c.Output.CSV.Enabled = v == "true"
+	}
+	if v := os.Getenv("CSV_FILENAME"); v != "" {
+		c.Output.CSV.Filename = v
+	}
+	if v := os.Getenv("CSV_APPEND"); v != "" {
+		c.Output.CSV.Append = v == "true"
+	}
+	if v := os.Getenv("CSV_BUFFER_SIZE"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			c.Output.CSV.BufferSize = size
+		}
+	}
+	if v := os.Getenv("CSV_SHARDS"); v != "" {
+		if shards, err := ParseWorkerCount(v); err == nil {
+			c.Output.CSV.Shards = shards
+		}
+	}
+	if v := os.Getenv("CSV_SHARD_KEY"); v != "" {
+		c.Output.CSV.ShardKey = v
+	}
+	if v := os.Getenv("CSV_QUOTING"); v != "" {
+		c.Output.CSV.Quoting = v
+	}
+
+	// Parquet config
+	if v := os.Getenv("PARQUET_ENABLED"); v != "" {
+		c.Output.Parquet.Enabled = v == "true"
+	}
+	if v := os.Getenv("PARQUET_FILENAME"); v != "" {
+		c.Output.Parquet.Filename = v
+	}
+	if v := os.Getenv("PARQUET_ROW_GROUP_SIZE"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			c.Output.Parquet.RowGroupSize = size
+		}
+	}
+	if v := os.Getenv("PARQUET_COMPRESSION"); v != "" {
+		c.Output.Parquet.Compression = v
+	}
+	if v := os.Getenv("PARQUET_COMPRESSION_LEVEL"); v != "" {
+		if level, err := strconv.Atoi(v); err == nil {
+			c.Output.Parquet.CompressionLevel = level
+		}
+	}
+	if v := os.Getenv("PARQUET_ENGINE"); v != "" {
+		c.Output.Parquet.Engine = v
+	}
+	if v := os.Getenv("PARQUET_PAGE_SIZE"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			c.Output.Parquet.PageSize = size
+		}
+	}
+	if v := os.Getenv("PARQUET_DICTIONARY_COLUMNS"); v != "" {
+		c.Output.Parquet.DictionaryColumns = strings.Split(v, ",")
+	}
+	if v := os.Getenv("PARQUET_BLOOM_FILTER_COLUMNS"); v != "" {
+		c.Output.Parquet.BloomFilterColumns = strings.Split(v, ",")
+	}
+	if v := os.Getenv("PARQUET_SORT_COLUMN"); v != "" {
+		c.Output.Parquet.SortColumn = v
+	}
+	if v := os.Getenv("PARQUET_PAGE_STATISTICS"); v != "" {
+		c.Output.Parquet.PageStatistics = v == "true"
+	}
+	if v := os.Getenv("PARQUET_SHARDS"); v != "" {
+		if shards, err := ParseWorkerCount(v); err == nil {
+			c.Output.Parquet.Shards = shards
+		}
+	}
+	if v := os.Getenv("PARQUET_SHARD_KEY"); v != "" {
+		c.Output.Parquet.ShardKey = v
+	}
+
+	// JSONL config
+	if v := os.Getenv("JSONL_ENABLED"); v != "" {
+		c.Output.JSONL.Enabled = v == "true"
+	}
+	if v := os.Getenv("JSONL_FILENAME"); v != "" {
+		c.Output.JSONL.Filename = v
+	}
+	if v := os.Getenv("JSONL_BUFFER_SIZE"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			c.Output.JSONL.BufferSize = size
+		}
+	}
+	if v := os.Getenv("JSONL_GZIP"); v != "" {
+		c.Output.JSONL.Gzip = v == "true"
+	}
+
+	// MessagePack config
+	if v := os.Getenv("MSGPACK_ENABLED"); v != "" {
+		c.Output.Msgpack.Enabled = v == "true"
+	}
+	if v := os.Getenv("MSGPACK_FILENAME"); v != "" {
+		c.Output.Msgpack.Filename = v
+	}
+	if v := os.Getenv("MSGPACK_GZIP"); v != "" {
+		c.Output.Msgpack.Gzip = v == "true"
+	}
+
+	// Render config
+	if v := os.Getenv("RENDER_ENABLED"); v != "" {
+		c.Output.Render.Enabled = v == "true"
+	}
+	if v := os.Getenv("RENDER_FILENAME"); v != "" {
+		c.Output.Render.Filename = v
+	}
+	if v := os.Getenv("RENDER_TEMPLATE_FILE"); v != "" {
+		c.Output.Render.TemplateFile = v
+		c.Output.Render.Template = ""
+	}
+
+	// Arrow config
+	if v := os.Getenv("ARROW_ENABLED"); v != "" {
+		c.Output.Arrow.Enabled = v == "true"
+	}
+	if v := os.Getenv("ARROW_FILENAME"); v != "" {
+		c.Output.Arrow.Filename = v
+	}
+	if v := os.Getenv("ARROW_FORMAT"); v != "" {
+		c.Output.Arrow.Format = v
+	}
+	if v := os.Getenv("ARROW_COMPRESSION"); v != "" {
+		c.Output.Arrow.Compression = v
+	}
+
+	// Fixed-width config
+	if v := os.Getenv("FIXED_WIDTH_ENABLED"); v != "" {
+		c.Output.FixedWidth.Enabled = v == "true"
+	}
+	if v := os.Getenv("FIXED_WIDTH_FILENAME"); v != "" {
+		c.Output.FixedWidth.Filename = v
+	}
+	if v := os.Getenv("FIXED_WIDTH_ENCODING"); v != "" {
+		c.Output.FixedWidth.Encoding = v
+	}
+
+	// XML config
+	if v := os.Getenv("XML_ENABLED"); v != "" {
+		c.Output.XML.Enabled = v == "true"
+	}
+	if v := os.Getenv("XML_FILENAME"); v != "" {
+		c.Output.XML.Filename = v
+	}
+	if v := os.Getenv("XML_PRETTY"); v != "" {
+		c.Output.XML.Pretty = v == "true"
+	}
+
+	// XLSX config
+	if v := os.Getenv("XLSX_ENABLED"); v != "" {
+		c.Output.XLSX.Enabled = v == "true"
+	}
+	if v := os.Getenv("XLSX_FILENAME"); v != "" {
+		c.Output.XLSX.Filename = v
+	}
+	if v := os.Getenv("XLSX_MAX_ROWS"); v != "" {
+		if rows, err := strconv.Atoi(v); err == nil {
+			c.Output.XLSX.MaxRows = rows
+		}
+	}
+
+	// DuckDB config
+	if v := os.Getenv("DUCKDB_ENABLED"); v != "" {
+		c.Output.DuckDB.Enabled = v == "true"
+	}
+	if v := os.Getenv("DUCKDB_FILENAME"); v != "" {
+		c.Output.DuckDB.Filename = v
+	}
+	if v := os.Getenv("DUCKDB_TABLE"); v != "" {
+		c.Output.DuckDB.Table = v
+	}
+	if v := os.Getenv("DUCKDB_BATCH_SIZE"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			c.Output.DuckDB.BatchSize = size
+		}
+	}
+
+	// SQLite config
+	if v := os.Getenv("SQLITE_ENABLED"); v != "" {
+		c.Output.SQLite.Enabled = v == "true"
+	}
+	if v := os.Getenv("SQLITE_FILENAME"); v != "" {
+		c.Output.SQLite.Filename = v
+	}
+	if v := os.Getenv("SQLITE_TABLE"); v != "" {
+		c.Output.SQLite.Table = v
+	}
+	if v := os.Getenv("SQLITE_BATCH_SIZE"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			c.Output.SQLite.BatchSize = size
+		}
+	}
+
+	// Kafka config
+	if v := os.Getenv("KAFKA_ENABLED"); v != "" {
+		c.Kafka.Enabled = v == "true"
+	}
+	if v := os.Getenv("KAFKA_BROKERS"); v != "" {
+		c.Kafka.Brokers = strings.Split(v, ",")
+	}
+	if v := os.Getenv("KAFKA_TOPIC"); v != "" {
+		c.Kafka.Topic = v
+	}
+	if v := os.Getenv("KAFKA_COMPRESSION"); v != "" {
+		c.Kafka.Compression = v
+	}
+	if v := os.Getenv("KAFKA_COMPRESSION_LEVEL"); v != "" {
+		if level, err := strconv.Atoi(v); err == nil {
+			c.Kafka.CompressionLevel = level
+		}
+	}
+	if v := os.Getenv("KAFKA_SERIALIZATION"); v != "" {
+		c.Kafka.Serialization = v
+	}
+	if v := os.Getenv("KAFKA_PAYLOAD_VALIDATION"); v != "" {
+		c.Kafka.PayloadValidation = v
+	}
+	if v := os.Getenv("KAFKA_BATCH_SIZE"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			c.Kafka.BatchSize = size
+		}
+	}
+	if v := os.Getenv("KAFKA_FLUSH_FREQUENCY"); v != "" {
+		if freq, err := strconv.Atoi(v); err == nil {
+			c.Kafka.FlushFrequency = freq
 		}
 	}
 	if v := os.Getenv("KAFKA_ASYNC"); v != "" {
 		c.Kafka.Async = v == "true"
 	}
+	if v := os.Getenv("KAFKA_ENCRYPTION_ENABLED"); v != "" {
+		c.Kafka.Encryption.Enabled = v == "true"
+	}
+	if v := os.Getenv("KAFKA_ENCRYPTION_KEY_ID"); v != "" {
+		c.Kafka.Encryption.KeyID = v
+	}
+	if v := os.Getenv("KAFKA_ENCRYPTION_KEY"); v != "" {
+		c.Kafka.Encryption.Key = v
+	}
+	if v := os.Getenv("KAFKA_SASL_USERNAME"); v != "" {
+		c.Kafka.SASL.Enabled = true
+		c.Kafka.SASL.Username = v
+	}
+	if v := os.Getenv("KAFKA_SASL_PASSWORD"); v != "" {
+		c.Kafka.SASL.Password = v
+	}
+	if v := os.Getenv("KAFKA_PROBE_ENABLED"); v != "" {
+		c.Kafka.Probe.Enabled = v == "true"
+	}
+	if v := os.Getenv("KAFKA_PROBE_EVERY"); v != "" {
+		if every, err := strconv.Atoi(v); err == nil {
+			c.Kafka.Probe.Every = every
+		}
+	}
+	if v := os.Getenv("KAFKA_KEY_FORMAT"); v != "" {
+		c.Kafka.Key.Format = v
+	}
+	if v := os.Getenv("KAFKA_SCHEMA_REGISTRY_URL"); v != "" {
+		c.Kafka.Key.Registry.URL = v
+	}
+	if v := os.Getenv("KAFKA_SCHEMA_REGISTRY_PASSWORD"); v != "" {
+		c.Kafka.Key.Registry.Password = v
+	}
+	if v := os.Getenv("KAFKA_SCHEMA_CHECK_ENABLED"); v != "" {
+		c.Kafka.SchemaCheck.Enabled = v == "true"
+	}
+	if v := os.Getenv("KAFKA_SCHEMA_CHECK_ON_INCOMPATIBLE"); v != "" {
+		c.Kafka.SchemaCheck.OnIncompatible = v
+	}
+	if v := os.Getenv("KAFKA_RATES_ENABLED"); v != "" {
+		c.Kafka.Rates.Enabled = v == "true"
+	}
+	if v := os.Getenv("KAFKA_RATES_TOPIC"); v != "" {
+		c.Kafka.Rates.Topic = v
+	}
+	if v := os.Getenv("KAFKA_COMMISSIONS_ENABLED"); v != "" {
+		c.Kafka.Commissions.Enabled = v == "true"
+	}
+	if v := os.Getenv("KAFKA_COMMISSIONS_TOPIC"); v != "" {
+		c.Kafka.Commissions.Topic = v
+	}
+	if v := os.Getenv("KAFKA_METADATA_ENABLED"); v != "" {
+		c.Kafka.Metadata.Enabled = v == "true"
+	}
+	if v := os.Getenv("KAFKA_METADATA_TOPIC"); v != "" {
+		c.Kafka.Metadata.Topic = v
+	}
+	if v := os.Getenv("KAFKA_TRACE_ENABLED"); v != "" {
+		c.Kafka.Trace.Enabled = v == "true"
+	}
+	if v := os.Getenv("KAFKA_TRACE_SCOPE"); v != "" {
+		c.Kafka.Trace.Scope = v
+	}
+	if v := os.Getenv("KAFKA_MAX_OPEN_REQUESTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Kafka.MaxOpenRequests = n
+		}
+	}
+	if v := os.Getenv("KAFKA_CHANNEL_BUFFER_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Kafka.ChannelBufferSize = n
+		}
+	}
+	if v := os.Getenv("KAFKA_MAX_IN_FLIGHT_BYTES"); v != "" {
+		if size, err := ParseByteSize(v); err == nil {
+			c.Kafka.MaxInFlightBytes = size
+		}
+	}
+	if v := os.Getenv("KAFKA_FAILOVER_ENABLED"); v != "" {
+		c.Kafka.Failover.Enabled = v == "true"
+	}
+	if v := os.Getenv("KAFKA_FAILOVER_SPOOL_DIR"); v != "" {
+		c.Kafka.Failover.SpoolDir = v
+	}
+	if v := os.Getenv("KAFKA_CLUSTER_WATCH_ENABLED"); v != "" {
+		c.Kafka.ClusterWatch.Enabled = v == "true"
+	}
+	if v := os.Getenv("KAFKA_CLUSTER_WATCH_REFRESH_INTERVAL"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			c.Kafka.ClusterWatch.RefreshInterval = seconds
+		}
+	}
+	if v := os.Getenv("KAFKA_PARTITION_REPORT"); v != "" {
+		c.Kafka.PartitionReport.Enabled = v == "true"
+	}
+	if v := os.Getenv("KAFKA_TLS"); v != "" {
+		c.Kafka.TLS = v == "true"
+	}
+
+	// Socket config
+	if v := os.Getenv("SOCKET_ENABLED"); v != "" {
+		c.Socket.Enabled = v == "true"
+	}
+	if v := os.Getenv("SOCKET_NETWORK"); v != "" {
+		c.Socket.Network = v
+	}
+	if v := os.Getenv("SOCKET_ADDRESS"); v != "" {
+		c.Socket.Address = v
+	}
+	if v := os.Getenv("SOCKET_FRAMING"); v != "" {
+		c.Socket.Framing = v
+	}
+	if v := os.Getenv("SOCKET_BUFFER_SIZE"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			c.Socket.BufferSize = size
+		}
+	}
+	if v := os.Getenv("SOCKET_DIAL_TIMEOUT"); v != "" {
+		if timeout, err := strconv.Atoi(v); err == nil {
+			c.Socket.DialTimeout = timeout
+		}
+	}
+
+	// Fluent config
+	if v := os.Getenv("FLUENT_ENABLED"); v != "" {
+		c.Fluent.Enabled = v == "true"
+	}
+	if v := os.Getenv("FLUENT_ADDRESS"); v != "" {
+		c.Fluent.Address = v
+	}
+	if v := os.Getenv("FLUENT_TAG"); v != "" {
+		c.Fluent.Tag = v
+	}
+	if v := os.Getenv("FLUENT_BATCH_SIZE"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			c.Fluent.BatchSize = size
+		}
+	}
+
+	// Syslog config
+	if v := os.Getenv("SYSLOG_ENABLED"); v != "" {
+		c.Syslog.Enabled = v == "true"
+	}
+	if v := os.Getenv("SYSLOG_NETWORK"); v != "" {
+		c.Syslog.Network = v
+	}
+	if v := os.Getenv("SYSLOG_ADDRESS"); v != "" {
+		c.Syslog.Address = v
+	}
+	if v := os.Getenv("SYSLOG_APP_NAME"); v != "" {
+		c.Syslog.AppName = v
+	}
+
+	// FIFO config
+	if v := os.Getenv("FIFO_ENABLED"); v != "" {
+		c.FIFO.Enabled = v == "true"
+	}
+	if v := os.Getenv("FIFO_PATH"); v != "" {
+		c.FIFO.Path = v
+	}
+	if v := os.Getenv("FIFO_FORMAT"); v != "" {
+		c.FIFO.Format = v
+	}
+	if v := os.Getenv("FIFO_NO_READER"); v != "" {
+		c.FIFO.NoReader = v
+	}
+
+	// Snowflake config
+	if v := os.Getenv("SNOWFLAKE_ENABLED"); v != "" {
+		c.Snowflake.Enabled = v == "true"
+	}
+	if v := os.Getenv("SNOWFLAKE_ACCOUNT"); v != "" {
+		c.Snowflake.Account = v
+	}
+	if v := os.Getenv("SNOWFLAKE_USER"); v != "" {
+		c.Snowflake.User = v
+	}
+	if v := os.Getenv("SNOWFLAKE_PRIVATE_KEY_PATH"); v != "" {
+		c.Snowflake.PrivateKeyPath = v
+	}
+	if v := os.Getenv("SNOWFLAKE_ROLE"); v != "" {
+		c.Snowflake.Role = v
+	}
+	if v := os.Getenv("SNOWFLAKE_WAREHOUSE"); v != "" {
+		c.Snowflake.Warehouse = v
+	}
+	if v := os.Getenv("SNOWFLAKE_DATABASE"); v != "" {
+		c.Snowflake.Database = v
+	}
+	if v := os.Getenv("SNOWFLAKE_SCHEMA"); v != "" {
+		c.Snowflake.Schema = v
+	}
+	if v := os.Getenv("SNOWFLAKE_TABLE"); v != "" {
+		c.Snowflake.Table = v
+	}
+	if v := os.Getenv("SNOWFLAKE_METHOD"); v != "" {
+		c.Snowflake.Method = v
+	}
+	if v := os.Getenv("SNOWFLAKE_BATCH_SIZE"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			c.Snowflake.BatchSize = size
+		}
+	}
+
+	// Postgres config
+	if v := os.Getenv("POSTGRES_ENABLED"); v != "" {
+		c.Postgres.Enabled = v == "true"
+	}
+	if v := os.Getenv("POSTGRES_HOST"); v != "" {
+		c.Postgres.Host = v
+	}
+	if v := os.Getenv("POSTGRES_USER"); v != "" {
+		c.Postgres.User = v
+	}
+	if v := os.Getenv("POSTGRES_PASSWORD"); v != "" {
+		c.Postgres.Password = v
+	}
+	if v := os.Getenv("POSTGRES_DATABASE"); v != "" {
+		c.Postgres.Database = v
+	}
+	if v := os.Getenv("POSTGRES_SSLMODE"); v != "" {
+		c.Postgres.SSLMode = v
+	}
+	if v := os.Getenv("POSTGRES_TABLE"); v != "" {
+		c.Postgres.Table = v
+	}
+	if v := os.Getenv("POSTGRES_BATCH_SIZE"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			c.Postgres.BatchSize = size
+		}
+	}
+	if v := os.Getenv("POSTGRES_POOL_SIZE"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			c.Postgres.PoolSize = size
+		}
+	}
+
+	// ClickHouse config
+	if v := os.Getenv("CLICKHOUSE_ENABLED"); v != "" {
+		c.ClickHouse.Enabled = v == "true"
+	}
+	if v := os.Getenv("CLICKHOUSE_ADDRESS"); v != "" {
+		c.ClickHouse.Address = v
+	}
+	if v := os.Getenv("CLICKHOUSE_USER"); v != "" {
+		c.ClickHouse.User = v
+	}
+	if v := os.Getenv("CLICKHOUSE_PASSWORD"); v != "" {
+		c.ClickHouse.Password = v
+	}
+	if v := os.Getenv("CLICKHOUSE_DATABASE"); v != "" {
+		c.ClickHouse.Database = v
+	}
+	if v := os.Getenv("CLICKHOUSE_TABLE"); v != "" {
+		c.ClickHouse.Table = v
+	}
+	if v := os.Getenv("CLICKHOUSE_BLOCK_SIZE"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			c.ClickHouse.BlockSize = size
+		}
+	}
+	if v := os.Getenv("CLICKHOUSE_ASYNC_INSERT"); v != "" {
+		c.ClickHouse.AsyncInsert = v == "true"
+	}
+
+	// Catalog config
+	if v := os.Getenv("CATALOG_ENABLED"); v != "" {
+		c.Catalog.Enabled = v == "true"
+	}
+	if v := os.Getenv("CATALOG_TYPE"); v != "" {
+		c.Catalog.Type = v
+	}
+	if v := os.Getenv("CATALOG_DATABASE"); v != "" {
+		c.Catalog.Database = v
+	}
+	if v := os.Getenv("CATALOG_TABLE"); v != "" {
+		c.Catalog.Table = v
+	}
+	if v := os.Getenv("CATALOG_LOCATION"); v != "" {
+		c.Catalog.Location = v
+	}
+	if v := os.Getenv("CATALOG_REGION"); v != "" {
+		c.Catalog.Region = v
+	}
+	if v := os.Getenv("CATALOG_ENDPOINT"); v != "" {
+		c.Catalog.Endpoint = v
+	}
+
+	// Provenance config
+	if v := os.Getenv("PROVENANCE_ENABLED"); v != "" {
+		c.Provenance.Enabled = v == "true"
+	}
+	if v := os.Getenv("PROVENANCE_SIGNING_KEY"); v != "" {
+		c.Provenance.SigningKey = v
+	}
+	if v := os.Getenv("PROVENANCE_KEY_ID"); v != "" {
+		c.Provenance.KeyID = v
+	}
+
+	// Bundle config
+	if v := os.Getenv("BUNDLE_ENABLED"); v != "" {
+		c.Bundle.Enabled = v == "true"
+	}
+	if v := os.Getenv("BUNDLE_PATH"); v != "" {
+		c.Bundle.Path = v
+	}
+
+	// Events config
+	if v := os.Getenv("EVENTS_ENABLED"); v != "" {
+		c.Events.Enabled = v == "true"
+	}
+	if v := os.Getenv("EVENTS_KAFKA_TOPIC"); v != "" {
+		c.Events.Kafka.Topic = v
+	}
+	if v := os.Getenv("EVENTS_WEBHOOK_URL"); v != "" {
+		c.Events.Webhook.URL = v
+	}
+
+	// Schema version config
+	if v := os.Getenv("SCHEMA_VERSION"); v != "" {
+		if version, err := strconv.Atoi(v); err == nil {
+			c.SchemaVersion.Version = version
+		}
+	}
+	if v := os.Getenv("SCHEMA_VERSION_SUFFIX_TOPIC"); v != "" {
+		c.SchemaVersion.SuffixTopic = v == "true"
+	}
+	if v := os.Getenv("SCHEMA_VERSION_SUFFIX_FILES"); v != "" {
+		c.SchemaVersion.SuffixFiles = v == "true"
+	}
+
+	// Notifications config
+	if v := os.Getenv("NOTIFICATIONS_ENABLED"); v != "" {
+		c.Notifications.Enabled = v == "true"
+	}
+	if v := os.Getenv("NOTIFICATIONS_WEBHOOK_URL"); v != "" {
+		c.Notifications.WebhookURL = v
+	}
+
+	// Scenario config
+	if v := os.Getenv("SCENARIO_SEED"); v != "" {
+		if seed, err := strconv.ParseInt(v, 10, 64); err == nil {
+			c.Scenario.Seed = seed
+		}
+	}
+	if v := os.Getenv("SCENARIO_RATE"); v != "" {
+		if rate, err := strconv.Atoi(v); err == nil {
+			c.Scenario.Rate = rate
+		}
+	}
+	if v := os.Getenv("SCENARIO_JITTER"); v != "" {
+		c.Scenario.Jitter.Distribution = v
+	}
+	if v := os.Getenv("SCENARIO_ARRIVALS"); v != "" {
+		c.Scenario.Arrivals.Process = v
+	}
+	if v := os.Getenv("SCENARIO_PLAYER_BEHAVIOR_RATE"); v != "" {
+		if rate, err := strconv.ParseFloat(v, 64); err == nil {
+			c.Scenario.PlayerBehaviors.Rate = rate
+		}
+	}
+
+	// Data config
+	if v := os.Getenv("DATA_CURRENCY_RATES"); v != "" {
+		c.Data.CurrencyRates = v
+	}
+	if v := os.Getenv("DATA_AGENTS"); v != "" {
+		c.Data.Agents = v
+	}
+	if v := os.Getenv("DATA_GAME_CATEGORIES"); v != "" {
+		c.Data.GameCategories = v
+	}
+	if v := os.Getenv("DATA_CURRENCIES"); v != "" {
+		c.Data.Currencies = v
+	}
+	if v := os.Getenv("DATA_HOUSES"); v != "" {
+		c.Data.Houses = v
+	}
+
+	// Metrics config
+	if v := os.Getenv("METRICS_INTERVAL"); v != "" {
+		if interval, err := strconv.Atoi(v); err == nil {
+			c.Metrics.Interval = interval
+		}
+	}
+	if v := os.Getenv("METRICS_DETAILED"); v != "" {
+		c.Metrics.Detailed = v == "true"
+	}
+	if v := os.Getenv("METRICS_PER_WORKER"); v != "" {
+		c.Metrics.PerWorker = v == "true"
+	}
+	if v := os.Getenv("SLO_MIN_RATE"); v != "" {
+		if rate, err := strconv.ParseFloat(v, 64); err == nil {
+			c.Metrics.SLO.MinRate = rate
+		}
+	}
+	if v := os.Getenv("SLO_MAX_ERROR_RATE"); v != "" {
+		if rate, err := strconv.ParseFloat(v, 64); err == nil {
+			c.Metrics.SLO.MaxErrorRate = rate
+		}
+	}
+
+	// gRPC config
+	if v := os.Getenv("GRPC_ENABLED"); v != "" {
+		c.GRPC.Enabled = v == "true"
+	}
+	if v := os.Getenv("GRPC_ADDRESS"); v != "" {
+		c.GRPC.Address = v
+	}
+	if v := os.Getenv("GRPC_DEFAULT_RATE"); v != "" {
+		if rate, err := strconv.Atoi(v); err == nil {
+			c.GRPC.DefaultRate = rate
+		}
+	}
+	if v := os.Getenv("GRPC_MAX_RATE"); v != "" {
+		if rate, err := strconv.Atoi(v); err == nil {
+			c.GRPC.MaxRate = rate
+		}
+	}
+
+	// Batching config
+	if v := os.Getenv("BATCHING_MAX_RECORDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Batching.Default.MaxRecords = n
+		}
+	}
+	if v := os.Getenv("BATCHING_MAX_BYTES"); v != "" {
+		if size, err := ParseByteSize(v); err == nil {
+			c.Batching.Default.MaxBytes = size
+		}
+	}
+	if v := os.Getenv("BATCHING_LINGER_MS"); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil {
+			c.Batching.Default.LingerMs = ms
+		}
+	}
+
+	// Source config
+	if v := os.Getenv("SOURCE_TYPE"); v != "" {
+		c.Source.Type = v
+	}
+	if v := os.Getenv("SOURCE_FORMAT"); v != "" {
+		c.Source.Format = v
+	}
+	if v := os.Getenv("SOURCE_KAFKA_TOPIC"); v != "" {
+		c.Source.Kafka.Topic = v
+	}
+	if v := os.Getenv("SOURCE_REPLAY_SPEED"); v != "" {
+		c.Source.Replay.Speed = v
+		c.Source.Replay.Compress = ""
+	}
+
+	c.applySinks()
+}
+
+// BuiltinSinks are the outputs with an enabled flag of their own, in the
+// order they start when output.sinks is unset
+var BuiltinSinks = []string{"csv", "parquet", "jsonl", "msgpack", "render", "arrow", "fixed_width", "xml", "xlsx", "duckdb", "sqlite", "kafka", "socket", "fluent", "syslog", "fifo", "snowflake", "postgres", "clickhouse"}
+
+// applySinks enables exactly the built-in outputs output.sinks lists, so the
+// rest of the configuration agrees with the list. Without a list,
+// output.format msgpack enables the MessagePack file output.
+func (c *Config) applySinks() {
+	if len(c.Output.Sinks) == 0 {
+		if c.Output.Format == "msgpack" {
+			c.Output.Msgpack.Enabled = true
+		}
+		return
+	}
+	has := func(name string) bool { return slices.Contains(c.Output.Sinks, name) }
+	switch {
+	case has("csv") && has("parquet"):
+		c.Output.Format = "both"
+	case has("csv"):
+		c.Output.Format = "csv"
+	case has("parquet"):
+		c.Output.Format = "parquet"
+	}
+	c.Output.CSV.Enabled = has("csv")
+	c.Output.Parquet.Enabled = has("parquet")
+	c.Output.JSONL.Enabled = has("jsonl")
+	c.Output.Msgpack.Enabled = has("msgpack")
+	c.Output.Render.Enabled = has("render")
+	c.Output.Arrow.Enabled = has("arrow")
+	c.Output.FixedWidth.Enabled = has("fixed_width")
+	c.Output.XML.Enabled = has("xml")
+	c.Output.XLSX.Enabled = has("xlsx")
+	c.Output.DuckDB.Enabled = has("duckdb")
+	c.Output.SQLite.Enabled = has("sqlite")
+	c.Kafka.Enabled = has("kafka")
+	c.Socket.Enabled = has("socket")
+	c.Fluent.Enabled = has("fluent")
+	c.Syslog.Enabled = has("syslog")
+	c.FIFO.Enabled = has("fifo")
+	c.Snowflake.Enabled = has("snowflake")
+	c.Postgres.Enabled = has("postgres")
+	c.ClickHouse.Enabled = has("clickhouse")
+}
+
+// SinkNames returns the outputs to run, in order: output.sinks when set,
+// otherwise every enabled built-in output
+func (c *Config) SinkNames() []string {
+	if len(c.Output.Sinks) > 0 {
+		return c.Output.Sinks
+	}
+	format := c.Output.Format
+	enabled := map[string]bool{
+		"csv":         c.Output.CSV.Enabled && (format == "csv" || format == "both"),
+		"parquet":     c.Output.Parquet.Enabled && (format == "parquet" || format == "both"),
+		"jsonl":       c.Output.JSONL.Enabled,
+		"msgpack":     c.Output.Msgpack.Enabled,
+		"render":      c.Output.Render.Enabled,
+		"arrow":       c.Output.Arrow.Enabled,
+		"fixed_width": c.Output.FixedWidth.Enabled,
+		"xml":         c.Output.XML.Enabled,
+		"xlsx":        c.Output.XLSX.Enabled,
+		"duckdb":      c.Output.DuckDB.Enabled,
+		"sqlite":      c.Output.SQLite.Enabled,
+		"kafka":       c.Kafka.Enabled,
+		"socket":      c.Socket.Enabled,
+		"fluent":      c.Fluent.Enabled,
+		"syslog":      c.Syslog.Enabled,
+		"fifo":        c.FIFO.Enabled,
+		"snowflake":   c.Snowflake.Enabled,
+		"postgres":    c.Postgres.Enabled,
+		"clickhouse":  c.ClickHouse.Enabled,
+	}
+	var names []string
+	for _, name := range BuiltinSinks {
+		if enabled[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Validate checks if the configuration is valid
+func (c *Config) Validate() error {
+	if c.Producer.MessageCount < 0 {
+		return fmt.Errorf("message_count must be non-negative (0 for continuous mode)")
+	}
+
+	if c.Producer.Workers <= 0 && !c.Producer.Workers.IsAuto() {
+		return fmt.Errorf("workers must be positive or auto")
+	}
+
+	if c.Producer.BufferSize <= 0 {
+		return fmt.Errorf("buffer_size must be positive")
+	}
+
+	switch c.Producer.OrderingKey {
+	case "", "round_id", "agent_id", "master_agent_id":
+	default:
+		return fmt.Errorf("ordering_key must be 'round_id', 'agent_id', 'master_agent_id', or empty")
+	}
+	if c.Producer.StrictOrdering && c.Producer.OrderingKey != "" {
+		return fmt.Errorf("strict_ordering already orders every key; leave ordering_key empty")
+	}
+	if rate := c.Producer.ConsistencyCheck.SampleRate; rate < 0 || rate > 1 {
+		return fmt.Errorf("consistency_check sample_rate must be between 0 and 1")
+	}
+
+	switch c.Producer.Transport {
+	case "", "channel":
+	case "ring":
+		// The ring replaces the unordered fixed-count hand-off only
+		if c.Producer.MessageCount == 0 || c.Producer.OrderingKey != "" || c.Producer.StrictOrdering || c.Scenario.Rate > 0 {
+			return fmt.Errorf("ring transport needs a message_count and no ordering_key, strict_ordering or scenario rate")
+		}
+	default:
+		return fmt.Errorf("producer transport must be 'channel' or 'ring'")
+	}
+	switch c.Producer.Dispatch {
+	case "", "broadcast", "split":
+	default:
+		return fmt.Errorf("producer dispatch must be 'broadcast' or 'split'")
+	}
+	if c.Producer.DrainTimeout < -1 {
+		return fmt.Errorf("producer drain_timeout must be -1 or more")
+	}
+	if c.Output.CloseTimeout < 0 {
+		return fmt.Errorf("output close_timeout must be non-negative")
+	}
+	if c.Output.WriteRetries < -1 || c.Output.WriteRetryBackoff < 0 {
+		return fmt.Errorf("output write_retries must be -1 or more and write_retry_backoff_ms non-negative")
+	}
+	validSinkError := func(policy string) bool {
+		return policy == SinkErrorContinue || policy == SinkErrorAbort || policy == SinkErrorIgnore || policy == SinkErrorFailover
+	}
+	if c.Output.OnSinkError.Policy != "" && !validSinkError(c.Output.OnSinkError.Policy) {
+		return fmt.Errorf("output on_sink_error policy must be 'continue', 'abort', 'ignore' or 'failover'")
+	}
+	for sink, policy := range c.Output.OnSinkError.Sinks {
+		if !slices.Contains(BuiltinSinks, sink) {
+			return fmt.Errorf("on_sink_error sink %q must be one of %s", sink, strings.Join(BuiltinSinks, ", "))
+		}
+		if !validSinkError(policy) {
+			return fmt.Errorf("on_sink_error policy for %s must be 'continue', 'abort', 'ignore' or 'failover'", sink)
+		}
+	}
+	running := c.SinkNames()
+	for sink, standby := range c.Output.OnSinkError.Standby {
+		if !slices.Contains(BuiltinSinks, sink) || !slices.Contains(BuiltinSinks, standby) {
+			return fmt.Errorf("on_sink_error standby %s: %s must both be one of %s", sink, standby, strings.Join(BuiltinSinks, ", "))
+		}
+		// A standby already running would get the rest of the run twice
+		if slices.Contains(running, standby) {
+			return fmt.Errorf("on_sink_error standby %s for %s is already an enabled sink", standby, sink)
+		}
+	}
+	for _, sink := range running {
+		if c.Output.OnSinkError.For(sink) == SinkErrorFailover && c.Output.OnSinkError.Standby[sink] == "" {
+			return fmt.Errorf("on_sink_error failover for %s needs a standby sink", sink)
+		}
+	}
+	if c.Output.Spool.MaxSize < 0 || c.Output.Spool.RetryInterval < 0 {
+		return fmt.Errorf("output spool max_size and retry_interval must be non-negative")
+	}
+	for _, sink := range c.Output.Spool.Sinks {
+		if !slices.Contains(SpoolSinks, sink) {
+			return fmt.Errorf("output spool sink %q must be one of %s", sink, strings.Join(SpoolSinks, ", "))
+		}
+	}
+	if c.Bundle.Enabled {
+		// The bundle aggregates every transaction, which split dispatch
+		// would share out between it and the sinks
+		if c.Producer.Dispatch == "split" {
+			return fmt.Errorf("bundle needs producer dispatch 'broadcast'")
+		}
+		if c.GRPC.Enabled {
+			return fmt.Errorf("bundle is not available in gRPC source mode")
+		}
+		if c.Bundle.SampleSize < 0 {
+			return fmt.Errorf("bundle sample_size must not be negative")
+		}
+	}
 
-	// Data config
-	if v := os.Getenv("DATA_CURRENCY_RATES"); v != "" {
-		c.Data.CurrencyRates = v
+	switch c.Output.Format {
+	case "csv", "parquet", "both", "msgpack":
+	default:
+		return fmt.Errorf("output format must be 'csv', 'parquet', 'both', or 'msgpack'")
 	}
-	if v := os.Getenv("DATA_AGENTS"); v != "" {
-		c.Data.Agents = v
+	for i, name := range c.Output.Sinks {
+		if name == "" {
+			return fmt.Errorf("output sinks must not contain an empty name")
+		}
+		if slices.Contains(c.Output.Sinks[:i], name) {
+			return fmt.Errorf("output sinks lists %q twice", name)
+		}
 	}
-	if v := os.Getenv("DATA_GAME_CATEGORIES"); v != "" {
-		c.Data.GameCategories = v
+
+	if filepath.IsAbs(c.Output.RunSubdir) || strings.Contains(c.Output.RunSubdir, "..") {
+		return fmt.Errorf("output run_subdir must be a relative path inside the output directory")
 	}
-	if v := os.Getenv("DATA_CURRENCIES"); v != "" {
-		c.Data.Currencies = v
+	if c.Output.Retention.KeepRuns < 0 || c.Output.Retention.MaxAgeDays < 0 {
+		return fmt.Errorf("output retention keep_runs and max_age_days must be non-negative")
+	}
+	if (c.Output.Retention.KeepRuns > 0 || c.Output.Retention.MaxAgeDays > 0) && c.Output.RunSubdir == "" {
+		return fmt.Errorf("output retention needs run_subdir so only run directories are removed")
 	}
 
-	// Metrics config
-	if v := os.Getenv("METRICS_INTERVAL"); v != "" {
-		if interval, err := strconv.Atoi(v); err == nil {
-			c.Metrics.Interval = interval
+	if c.Output.Limits.MaxFiles < 0 || c.Output.Limits.CheckInterval < 0 {
+		return fmt.Errorf("output limits max_files and check_interval must be non-negative")
+	}
+	if c.Output.Dedup.Window < 0 {
+		return fmt.Errorf("output dedup window must be non-negative")
+	}
+
+	switch c.Output.Encryption.Mode {
+	case "":
+	case "age":
+		if len(c.Output.Encryption.Recipients) == 0 {
+			return fmt.Errorf("encryption recipients are required for age")
+		}
+	case "aes-gcm":
+		if c.Output.Encryption.Key == "" && c.Output.Encryption.KeyFile == "" {
+			return fmt.Errorf("encryption key or key_file is required for aes-gcm")
 		}
+	default:
+		return fmt.Errorf("encryption mode must be 'age', 'aes-gcm', or empty")
 	}
-	if v := os.Getenv("METRICS_DETAILED"); v != "" {
-		c.Metrics.Detailed = v == "true"
+
+	if d := c.Output.Delivery; d.Protocol != "" {
+		if d.Protocol != "sftp" && d.Protocol != "ftps" && d.Protocol != "s3" {
+			return fmt.Errorf("delivery protocol must be 'sftp', 'ftps', 's3', or empty")
+		}
+		if d.Protocol == "s3" {
+			if d.Bucket == "" {
+				return fmt.Errorf("s3 delivery needs a bucket")
+			}
+			if d.PartSize != 0 && d.PartSize < 5<<20 {
+				return fmt.Errorf("delivery part_size must be at least 5MiB, the smallest part S3 accepts")
+			}
+			if d.Concurrency < 0 || d.RateLimit < 0 || d.ThrottleRetries < -1 || d.ThrottleBackoffMs < 0 {
+				return fmt.Errorf("delivery concurrency, rate_limit, throttle_retries and throttle_backoff_ms must be non-negative")
+			}
+		} else if d.Host == "" || d.Username == "" {
+			return fmt.Errorf("delivery host and username are required")
+		}
+		if d.Protocol == "sftp" {
+			if d.Password == "" && d.PrivateKeyFile == "" {
+				return fmt.Errorf("sftp delivery needs a password or private_key_file")
+			}
+			if d.KnownHosts == "" && d.HostKey == "" && !d.InsecureSkipVerify {
+				return fmt.Errorf("sftp delivery needs known_hosts or host_key to verify the server")
+			}
+		}
+		if d.Port < 0 || d.Port > 65535 {
+			return fmt.Errorf("delivery port must be between 0 and 65535")
+		}
+		if d.Retries < 0 || d.RetryBackoff < 0 || d.Timeout < 0 {
+			return fmt.Errorf("delivery retries, retry_backoff and timeout must be non-negative")
+		}
+	}
+	if e := c.Output.Delivery.Email; e.Host != "" {
+		if c.Output.Delivery.Protocol == "" {
+			return fmt.Errorf("delivery email needs delivery protocol to be set")
+		}
+		if e.From == "" || len(e.To) == 0 {
+			return fmt.Errorf("delivery email from and to are required")
+		}
+		for _, addr := range append([]string{e.From}, e.To...) {
+			if _, err := mail.ParseAddress(addr); err != nil {
+				return fmt.Errorf("delivery email address %q: %w", addr, err)
+			}
+		}
+		if e.Port < 0 || e.Port > 65535 {
+			return fmt.Errorf("delivery email port must be between 0 and 65535")
+		}
+		if e.Timeout < 0 {
+			return fmt.Errorf("delivery email timeout must be non-negative")
+		}
 	}
-}
 
-// Validate checks if the configuration is valid
-func (c *Config) Validate() error {
-	if c.Producer.MessageCount < 0 {
-		return fmt.Errorf("message_count must be non-negative (0 for continuous mode)")
+	if c.Output.Parquet.PageSize < 0 {
+		return fmt.Errorf("parquet page_size must be non-negative")
 	}
 
-	if c.Producer.Workers <= 0 {
-		return fmt.Errorf("workers must be positive")
+	switch c.Output.CSV.Quoting {
+	case "", "minimal", "strings", "all":
+	default:
+		return fmt.Errorf("csv quoting must be 'minimal', 'strings', or 'all'")
+	}
+	for column := range c.Output.CSV.DecimalPlaces {
+		if column != "bet_amount" && column != "win_amount" && column != "win_loss" {
+			return fmt.Errorf("csv decimal_places column must be bet_amount, win_amount, or win_loss: %s", column)
+		}
 	}
 
-	if c.Producer.BufferSize <= 0 {
-		return fmt.Errorf("buffer_size must be positive")
+	if err := validCompressionLevel("parquet", c.Output.Parquet.Compression, c.Output.Parquet.CompressionLevel, true); err != nil {
+		return err
+	}
+
+	switch c.Output.Parquet.Engine {
+	case "", "parquet-go":
+	case "arrow":
+		if len(c.Output.Parquet.BloomFilterColumns) > 0 {
+			return fmt.Errorf("parquet bloom_filter_columns are not supported by the arrow engine")
+		}
+	default:
+		return fmt.Errorf("parquet engine must be 'parquet-go' or 'arrow'")
+	}
+
+	if (c.Output.CSV.Shards < 0 && !c.Output.CSV.Shards.IsAuto()) || (c.Output.Parquet.Shards < 0 && !c.Output.Parquet.Shards.IsAuto()) {
+		return fmt.Errorf("csv and parquet shards must be non-negative or auto")
+	}
+
+	if c.Output.JSONL.Enabled {
+		if c.Output.JSONL.Filename == "" {
+			return fmt.Errorf("jsonl filename is required when jsonl is enabled")
+		}
+		if c.Output.JSONL.BufferSize < 0 || c.Output.JSONL.Shards < 0 {
+			return fmt.Errorf("jsonl buffer_size and shards must be non-negative")
+		}
+	}
+
+	if m := c.Output.Msgpack; m.Enabled {
+		if m.Filename == "" {
+			return fmt.Errorf("msgpack filename is required when msgpack is enabled")
+		}
+		if m.BufferSize < 0 || m.Shards < 0 {
+			return fmt.Errorf("msgpack buffer_size and shards must be non-negative")
+		}
+	}
+
+	if r := c.Output.Render; r.Enabled {
+		if r.Filename == "" {
+			return fmt.Errorf("render filename is required when render is enabled")
+		}
+		if (r.Template == "") == (r.TemplateFile == "") {
+			return fmt.Errorf("render needs exactly one of template or template_file")
+		}
+		switch r.LineEnding {
+		case "", "lf", "crlf", "none":
+		default:
+			return fmt.Errorf("render line_ending must be 'lf', 'crlf', or 'none'")
+		}
+		if r.BufferSize < 0 {
+			return fmt.Errorf("render buffer_size must be non-negative")
+		}
+	}
+
+	if a := c.Output.Arrow; a.Enabled {
+		if a.Filename == "" {
+			return fmt.Errorf("arrow filename is required when arrow is enabled")
+		}
+		switch a.Format {
+		case "", "file", "stream":
+		default:
+			return fmt.Errorf("arrow format must be 'file' or 'stream'")
+		}
+		switch a.Compression {
+		case "", "none", "lz4", "zstd":
+		default:
+			return fmt.Errorf("arrow compression must be 'none', 'lz4', or 'zstd'")
+		}
+		if a.BatchSize < 0 {
+			return fmt.Errorf("arrow batch_size must be non-negative")
+		}
+	}
+
+	if f := c.Output.FixedWidth; f.Enabled {
+		if f.Filename == "" {
+			return fmt.Errorf("fixed_width filename is required when fixed_width is enabled")
+		}
+		switch f.Encoding {
+		case "", "ascii", "latin1", "ebcdic":
+		default:
+			return fmt.Errorf("fixed_width encoding must be 'ascii', 'latin1', or 'ebcdic'")
+		}
+		switch f.LineEnding {
+		case "", "lf", "crlf", "none":
+		default:
+			return fmt.Errorf("fixed_width line_ending must be 'lf', 'crlf', or 'none'")
+		}
+		switch f.Overflow {
+		case "", "truncate", "truncate_left", "error":
+		default:
+			return fmt.Errorf("fixed_width overflow must be 'truncate', 'truncate_left' or 'error'")
+		}
+		if f.BufferSize < 0 {
+			return fmt.Errorf("fixed_width buffer_size must be non-negative")
+		}
+		if len(f.Columns) == 0 {
+			return fmt.Errorf("fixed_width needs at least one column")
+		}
+		for i, col := range f.Columns {
+			if col.Name == "" || col.Width <= 0 {
+				return fmt.Errorf("fixed_width column %d needs a name and a positive width", i+1)
+			}
+			if utf8.RuneCountInString(col.Pad) > 1 {
+				return fmt.Errorf("fixed_width column %s: pad must be a single character", col.Name)
+			}
+			switch col.Align {
+			case "", "left", "right":
+			default:
+				return fmt.Errorf("fixed_width column %s: align must be 'left' or 'right'", col.Name)
+			}
+			if col.ImpliedDecimals < 0 {
+				return fmt.Errorf("fixed_width column %s: implied_decimals must be non-negative", col.Name)
+			}
+			switch col.Overflow {
+			case "", "truncate", "truncate_left", "error":
+			default:
+				return fmt.Errorf("fixed_width column %s: overflow must be 'truncate', 'truncate_left' or 'error'", col.Name)
+			}
+		}
+	}
+
+	if x := c.Output.XML; x.Enabled {
+		if x.Filename == "" {
+			return fmt.Errorf("xml filename is required when xml is enabled")
+		}
+		if x.BufferSize < 0 {
+			return fmt.Errorf("xml buffer_size must be non-negative")
+		}
+	}
+
+	if x := c.Output.XLSX; x.Enabled {
+		if x.Filename == "" {
+			return fmt.Errorf("xlsx filename is required when xlsx is enabled")
+		}
+		if x.MaxRows < 0 || x.MaxRows > 1048575 {
+			return fmt.Errorf("xlsx max_rows must be between 0 and 1048575")
+		}
+		// Split dispatch would give the sample whichever transactions it
+		// happened to take rather than the first ones
+		if c.Producer.Dispatch == "split" {
+			return fmt.Errorf("xlsx needs producer dispatch 'broadcast'")
+		}
+	}
+
+	for _, destinations := range [][]DestinationConfig{c.Output.CSV.Destinations, c.Output.Parquet.Destinations,
+		c.Output.JSONL.Destinations, c.Output.Msgpack.Destinations, c.Output.Render.Destinations,
+		c.Output.Arrow.Destinations, c.Output.FixedWidth.Destinations, c.Output.XML.Destinations} {
+		for _, dest := range destinations {
+			if dest.Directory == "" {
+				return fmt.Errorf("output destination directory cannot be empty")
+			}
+			if dest.RotateRows < 0 || dest.RotateInterval < 0 {
+				return fmt.Errorf("destination %s: rotate_rows and rotate_interval must be non-negative", dest.Directory)
+			}
+		}
+	}
+
+	if err := c.checkFilePaths(); err != nil {
+		return err
+	}
+
+	if c.Output.DuckDB.Enabled {
+		if c.Output.DuckDB.Filename == "" || c.Output.DuckDB.Table == "" {
+			return fmt.Errorf("duckdb filename and table are required when duckdb is enabled")
+		}
+		if c.Output.DuckDB.BatchSize <= 0 {
+			return fmt.Errorf("duckdb batch_size must be positive")
+		}
+	}
+
+	if c.Output.SQLite.Enabled {
+		if c.Output.SQLite.Filename == "" || c.Output.SQLite.Table == "" {
+			return fmt.Errorf("sqlite filename and table are required when sqlite is enabled")
+		}
+		if c.Output.SQLite.BatchSize <= 0 {
+			return fmt.Errorf("sqlite batch_size must be positive")
+		}
+	}
+
+	for name, ds := range c.Data.Datasets {
+		sources := 0
+		for _, set := range []bool{ds.File != "", len(ds.Values) > 0, len(ds.Weights) > 0, len(ds.Records) > 0} {
+			if set {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return fmt.Errorf("dataset %s: set exactly one of file, values, weights or records", name)
+		}
+		for value, weight := range ds.Weights {
+			if weight < 0 {
+				return fmt.Errorf("dataset %s: weight of %s must be non-negative", name, value)
+			}
+		}
+		switch ds.Strategy {
+		case "", "uniform", "weighted", "round_robin", "agent":
+		default:
+			return fmt.Errorf("dataset %s: strategy must be 'uniform', 'weighted', 'round_robin' or 'agent'", name)
+		}
+	}
+	fieldNames := make(map[string]bool, len(c.Data.Fields))
+	for _, f := range c.Data.Fields {
+		if f.Name == "" {
+			return fmt.Errorf("data fields need a name")
+		}
+		if fieldNames[f.Name] {
+			return fmt.Errorf("duplicate data field %s", f.Name)
+		}
+		fieldNames[f.Name] = true
+		if (f.Dataset != "" && f.Generator != "") || (f.References != "" && (f.Dataset != "" || f.Generator != "")) {
+			return fmt.Errorf("data field %s: set one of dataset, generator or references", f.Name)
+		}
+		if f.References != "" {
+			switch f.References {
+			case "id", "external_transaction_id", "vendor_bet_id", "round_id":
+			default:
+				if !slices.ContainsFunc(c.Data.Fields, func(other FieldConfig) bool { return other.Name == f.References }) || f.References == f.Name {
+					return fmt.Errorf("data field %s: references unknown source %q", f.Name, f.References)
+				}
+			}
+			if f.Window < 0 || f.Pool < 0 {
+				return fmt.Errorf("data field %s: window and pool must be non-negative", f.Name)
+			}
+			continue
+		}
+		if f.Generator != "" {
+			switch f.Generator {
+			case "uuid", "ulid", "name", "first_name", "last_name", "email", "ipv4", "ipv6", "user_agent", "country", "lorem", "counter":
+			default:
+				return fmt.Errorf("data field %s: unknown generator %q", f.Name, f.Generator)
+			}
+			if f.Length < 0 {
+				return fmt.Errorf("data field %s: length must be positive", f.Name)
+			}
+			continue
+		}
+		if _, ok := c.Data.Datasets[f.Dataset]; !ok {
+			return fmt.Errorf("data field %s: unknown dataset %q", f.Name, f.Dataset)
+		}
 	}
 
-	if c.Output.Format != "csv" && c.Output.Format != "parquet" && c.Output.Format != "both" {
-		return fmt.Errorf("output format must be 'csv', 'parquet', or 'both'")
+	if slo := c.Metrics.SLO; slo.MinRate < 0 || slo.MaxErrorRate < 0 || slo.MaxErrorRate > 1 || slo.Window < 0 || slo.Warmup < 0 {
+		return fmt.Errorf("metrics slo min_rate, window and warmup must be non-negative and max_error_rate between 0 and 1")
 	}
 
 	if c.Kafka.Enabled {
@@ -237,7 +2496,459 @@ func (c *Config) Validate() error {
 		if c.Kafka.Topic == "" {
 			return fmt.Errorf("kafka topic cannot be empty when kafka is enabled")
 		}
+		if c.Kafka.Probe.Every < 0 || c.Kafka.Probe.Grace < 0 {
+			return fmt.Errorf("kafka probe every and grace must be non-negative")
+		}
+		if err := validCompressionLevel("kafka", c.Kafka.Compression, c.Kafka.CompressionLevel, false); err != nil {
+			return err
+		}
+		if c.Kafka.Rates.Interval < 0 || c.Kafka.Rates.Volatility < 0 {
+			return fmt.Errorf("kafka rates interval and volatility must be non-negative")
+		}
+		commissions := c.Kafka.Commissions
+		if commissions.Interval < 0 || commissions.SubAgents < 0 || commissions.SubAgents > 99 {
+			return fmt.Errorf("kafka commissions interval must be non-negative and sub_agents between 0 and 99")
+		}
+		for _, rate := range []float64{commissions.Rates.MasterAgent, commissions.Rates.Agent, commissions.Rates.SubAgent} {
+			if rate < 0 || rate > 1 {
+				return fmt.Errorf("kafka commission rates must be between 0 and 1")
+			}
+		}
+		if c.Kafka.Metadata.Interval < 0 {
+			return fmt.Errorf("kafka metadata interval must be non-negative")
+		}
+		if c.Kafka.MaxOpenRequests < 0 || c.Kafka.ChannelBufferSize < 0 || c.Kafka.MaxInFlightBytes < 0 {
+			return fmt.Errorf("kafka max_open_requests, channel_buffer_size and max_in_flight_bytes must be non-negative")
+		}
+		if c.Kafka.Failover.SpoolLimit < 0 || c.Kafka.Failover.RetryInterval < 0 {
+			return fmt.Errorf("kafka failover spool_limit and retry_interval must be non-negative")
+		}
+		if c.Kafka.ClusterWatch.RefreshInterval < 0 {
+			return fmt.Errorf("kafka cluster_watch refresh_interval must be non-negative")
+		}
+		switch c.Kafka.Trace.Scope {
+		case "", "transaction", "round", "player":
+		default:
+			return fmt.Errorf("kafka trace scope must be 'transaction', 'round' or 'player'")
+		}
+		for region, weight := range c.Kafka.Regions.Weights {
+			if weight < 0 {
+				return fmt.Errorf("kafka region %s weight must be non-negative", region)
+			}
+		}
+		for region, route := range c.Kafka.Regions.Routes {
+			if _, ok := c.Kafka.Regions.Weights[region]; !ok {
+				return fmt.Errorf("kafka region route %s has no weight", region)
+			}
+			if len(route.Brokers) == 0 && route.Topic == "" {
+				return fmt.Errorf("kafka region route %s needs brokers or a topic", region)
+			}
+		}
+		switch c.Kafka.Key.Format {
+		case "", "string", "json":
+		case "avro":
+			if c.Kafka.Key.Registry.URL == "" {
+				return fmt.Errorf("kafka avro keys need key.registry.url")
+			}
+		default:
+			return fmt.Errorf("kafka key format must be 'string', 'json', or 'avro'")
+		}
+		if c.Kafka.PartitionReport.MaxSkew != 0 && c.Kafka.PartitionReport.MaxSkew < 1 {
+			return fmt.Errorf("kafka partition_report max_skew must be at least 1")
+		}
+		if c.Kafka.SASL.Enabled && (c.Kafka.SASL.Username == "" || c.Kafka.SASL.Password == "") {
+			return fmt.Errorf("kafka sasl username and password are required when sasl is enabled")
+		}
+		if c.Kafka.Encryption.Enabled && (c.Kafka.Encryption.KeyID == "" || c.Kafka.Encryption.Key == "") {
+			return fmt.Errorf("kafka encryption key_id and key are required when encryption is enabled")
+		}
+		if c.Kafka.Serialization != "" {
+			if _, err := codec.Lookup(c.Kafka.Serialization); err != nil {
+				return fmt.Errorf("kafka %w", err)
+			}
+		}
+		if check := c.Kafka.SchemaCheck; check.Enabled {
+			if c.Kafka.Key.Registry.URL == "" {
+				return fmt.Errorf("kafka schema_check needs key.registry.url")
+			}
+			switch c.Kafka.Serialization {
+			case "", "json", "avro":
+			default:
+				return fmt.Errorf("kafka schema_check needs json or avro serialization")
+			}
+			if check.OnIncompatible != "" && check.OnIncompatible != "fail" && check.OnIncompatible != "warn" {
+				return fmt.Errorf("kafka schema_check on_incompatible must be 'fail' or 'warn'")
+			}
+		}
+		switch c.Kafka.PayloadValidation {
+		case "":
+		case "warn", "fatal":
+			if c.Kafka.Serialization != "" && c.Kafka.Serialization != "json" {
+				return fmt.Errorf("kafka payload_validation needs json serialization")
+			}
+		default:
+			return fmt.Errorf("kafka payload_validation must be 'warn', 'fatal' or empty")
+		}
+	}
+
+	if c.Socket.Enabled {
+		if c.Socket.Network != "tcp" && c.Socket.Network != "unix" {
+			return fmt.Errorf("socket network must be 'tcp' or 'unix'")
+		}
+		if c.Socket.Address == "" {
+			return fmt.Errorf("socket address cannot be empty when socket is enabled")
+		}
+		if c.Socket.Framing != "ndjson" && c.Socket.Framing != "length_prefixed" {
+			return fmt.Errorf("socket framing must be 'ndjson' or 'length_prefixed'")
+		}
+		if c.Socket.BufferSize <= 0 {
+			return fmt.Errorf("socket buffer_size must be positive")
+		}
+	}
+
+	if c.Fluent.Enabled {
+		if c.Fluent.Address == "" {
+			return fmt.Errorf("fluent address cannot be empty when fluent is enabled")
+		}
+		if c.Fluent.Tag == "" {
+			return fmt.Errorf("fluent tag cannot be empty when fluent is enabled")
+		}
+		if c.Fluent.BatchSize <= 0 {
+			return fmt.Errorf("fluent batch_size must be positive")
+		}
+	}
+
+	if c.Syslog.Enabled {
+		if c.Syslog.Network != "tcp" && c.Syslog.Network != "udp" {
+			return fmt.Errorf("syslog network must be 'tcp' or 'udp'")
+		}
+		if c.Syslog.Address == "" {
+			return fmt.Errorf("syslog address cannot be empty when syslog is enabled")
+		}
+	}
+
+	if c.FIFO.Enabled {
+		if c.FIFO.Path == "" {
+			return fmt.Errorf("fifo path cannot be empty when fifo is enabled")
+		}
+		if c.FIFO.Format != "ndjson" && c.FIFO.Format != "csv" {
+			return fmt.Errorf("fifo format must be 'ndjson' or 'csv'")
+		}
+		switch c.FIFO.NoReader {
+		case "", "wait", "drop", "buffer":
+		default:
+			return fmt.Errorf("fifo no_reader must be 'wait', 'drop' or 'buffer'")
+		}
+		if c.FIFO.BufferSize <= 0 {
+			return fmt.Errorf("fifo buffer_size must be positive")
+		}
+		if c.FIFO.SlowReaderMs < 0 || c.FIFO.SpoolLimit < 0 {
+			return fmt.Errorf("fifo slow_reader_ms and spool_limit must be non-negative")
+		}
+	}
+
+	if c.Snowflake.Enabled {
+		if c.Snowflake.Account == "" || c.Snowflake.User == "" || (c.Snowflake.PrivateKeyPath == "" && c.Snowflake.PrivateKey == "") {
+			return fmt.Errorf("snowflake account, user and private_key_path (or private_key) are required when snowflake is enabled")
+		}
+		if c.Snowflake.Method != "" && c.Snowflake.Method != "insert" && c.Snowflake.Method != "streaming" {
+			return fmt.Errorf("snowflake method must be 'insert' or 'streaming'")
+		}
+		if c.Snowflake.Database == "" || c.Snowflake.Schema == "" || c.Snowflake.Table == "" {
+			return fmt.Errorf("snowflake database, schema and table are required when snowflake is enabled")
+		}
+		// Snowpipe Streaming runs on serverless compute
+		if c.Snowflake.Warehouse == "" && c.Snowflake.Method != "streaming" {
+			return fmt.Errorf("snowflake warehouse is required unless method is 'streaming'")
+		}
+		if c.Snowflake.BatchSize <= 0 {
+			return fmt.Errorf("snowflake batch_size must be positive")
+		}
+	}
+
+	if c.Postgres.Enabled {
+		if c.Postgres.Host == "" || c.Postgres.User == "" || c.Postgres.Database == "" || c.Postgres.Table == "" {
+			return fmt.Errorf("postgres host, user, database and table are required when postgres is enabled")
+		}
+		if c.Postgres.BatchSize <= 0 {
+			return fmt.Errorf("postgres batch_size must be positive")
+		}
+		if c.Postgres.PoolSize < 0 || c.Postgres.Timeout < 0 {
+			return fmt.Errorf("postgres pool_size and timeout must be non-negative")
+		}
+		switch c.Postgres.SSLMode {
+		case "", "disable", "prefer", "require", "verify-full":
+		default:
+			return fmt.Errorf("postgres sslmode must be disable, prefer, require or verify-full")
+		}
+	}
+
+	if c.ClickHouse.Enabled {
+		if c.ClickHouse.Address == "" || c.ClickHouse.Table == "" {
+			return fmt.Errorf("clickhouse address and table are required when clickhouse is enabled")
+		}
+		if c.ClickHouse.BlockSize <= 0 {
+			return fmt.Errorf("clickhouse block_size must be positive")
+		}
+		if c.ClickHouse.Timeout < 0 {
+			return fmt.Errorf("clickhouse timeout must be non-negative")
+		}
+	}
+
+	if c.GRPC.Enabled {
+		if c.GRPC.Address == "" {
+			return fmt.Errorf("grpc address cannot be empty when grpc is enabled")
+		}
+		if c.GRPC.DefaultRate < 0 || c.GRPC.MaxRate < 0 {
+			return fmt.Errorf("grpc rates must be non-negative")
+		}
+	}
+
+	for sink, limits := range c.Batching.Sinks {
+		if !slices.Contains(BatchingSinks, sink) {
+			return fmt.Errorf("batching sink %q must be one of %s", sink, strings.Join(BatchingSinks, ", "))
+		}
+		if limits.MaxRecords < 0 || limits.MaxBytes < 0 || limits.LingerMs < 0 {
+			return fmt.Errorf("batching limits for %s must be non-negative", sink)
+		}
+	}
+	if d := c.Batching.Default; d.MaxRecords < 0 || d.MaxBytes < 0 || d.LingerMs < 0 {
+		return fmt.Errorf("batching default limits must be non-negative")
+	}
+
+	switch c.Source.Type {
+	case "":
+	case "stdin", "kafka":
+		if c.GRPC.Enabled || c.Producer.Transport == "ring" {
+			return fmt.Errorf("a source cannot be combined with grpc or the ring transport")
+		}
+		switch c.Source.Format {
+		case "", "json", "csv":
+		case "avro":
+			if c.Source.Type != "kafka" {
+				return fmt.Errorf("avro source records need a kafka source")
+			}
+		default:
+			return fmt.Errorf("source format must be 'json', 'csv' or 'avro'")
+		}
+		if c.Source.Type == "kafka" {
+			if c.Source.Kafka.Topic == "" {
+				return fmt.Errorf("source kafka topic cannot be empty")
+			}
+			if len(c.Source.Kafka.Brokers) == 0 && len(c.Kafka.Brokers) == 0 {
+				return fmt.Errorf("source kafka brokers cannot be empty (set source.kafka.brokers or kafka.brokers)")
+			}
+			if c.Source.Kafka.StopAtEnd && !c.Source.Kafka.FromBeginning {
+				return fmt.Errorf("source kafka stop_at_end needs from_beginning")
+			}
+			if c.Kafka.Enabled && len(c.Source.Kafka.Brokers) == 0 && c.Source.Kafka.Topic == c.Kafka.Topic {
+				return fmt.Errorf("source kafka topic cannot be the kafka sink topic on the same brokers")
+			}
+		}
+	default:
+		return fmt.Errorf("source type must be 'stdin' or 'kafka'")
+	}
+	if _, err := c.Source.Replay.Factor(); err != nil {
+		return fmt.Errorf("source replay: %w", err)
+	}
+	if replay := c.Source.Replay; replay.MaxGap < 0 || (c.Source.Type == "" && (replay.Speed != "" || replay.Compress != "")) {
+		return fmt.Errorf("source replay needs a source type and a non-negative max_gap")
+	}
+
+	if c.Catalog.Enabled {
+		if c.Catalog.Type != "glue" && c.Catalog.Type != "hive" {
+			return fmt.Errorf("catalog type must be 'glue' or 'hive'")
+		}
+		if c.Catalog.Database == "" || c.Catalog.Table == "" || c.Catalog.Location == "" {
+			return fmt.Errorf("catalog database, table and location are required when catalog is enabled")
+		}
+		if len(c.Catalog.PartitionKeys) == 0 {
+			return fmt.Errorf("catalog partition_keys cannot be empty when catalog is enabled")
+		}
+		if c.Catalog.Type == "glue" && c.Catalog.Region == "" && c.Catalog.Endpoint == "" {
+			return fmt.Errorf("catalog region is required for glue")
+		}
+		if c.Catalog.Type == "hive" && c.Catalog.Endpoint == "" {
+			return fmt.Errorf("catalog endpoint is required for hive")
+		}
+	}
+
+	if c.Events.Enabled {
+		if c.Events.Kafka.Topic == "" && c.Events.Webhook.URL == "" {
+			return fmt.Errorf("events need a kafka topic or a webhook url when events are enabled")
+		}
+		if c.Events.Kafka.Topic != "" && len(c.Events.Kafka.Brokers) == 0 && len(c.Kafka.Brokers) == 0 {
+			return fmt.Errorf("events kafka brokers cannot be empty (set events.kafka.brokers or kafka.brokers)")
+		}
+		if c.Events.Interval < 0 {
+			return fmt.Errorf("events interval must be non-negative")
+		}
+	}
+
+	if c.SchemaVersion.Version < 0 {
+		return fmt.Errorf("schema_version version must be non-negative")
+	}
+	if c.SchemaVersion.Version == 0 && (c.SchemaVersion.SuffixTopic || c.SchemaVersion.SuffixFiles) {
+		return fmt.Errorf("schema_version suffixes need a version")
+	}
+
+	if c.Notifications.Enabled {
+		if c.Notifications.WebhookURL == "" {
+			return fmt.Errorf("notifications webhook_url cannot be empty when notifications are enabled")
+		}
+		if f := c.Notifications.Format; f != "" && f != "slack" && f != "json" {
+			return fmt.Errorf("notifications format must be 'slack' or 'json'")
+		}
+		for _, e := range c.Notifications.Events {
+			switch e {
+			case "start", "finish":
+			case "error_threshold":
+				if c.Notifications.ErrorThreshold <= 0 {
+					return fmt.Errorf("notifications error_threshold must be positive for the error_threshold event")
+				}
+			default:
+				return fmt.Errorf("unknown notification event: %s (use start, finish or error_threshold)", e)
+			}
+		}
+	}
+
+	jobNames := make(map[string]bool, len(c.Schedule.Jobs))
+	for _, job := range c.Schedule.Jobs {
+		if job.Name == "" || job.Cron == "" {
+			return fmt.Errorf("schedule jobs need a name and a cron expression")
+		}
+		if jobNames[job.Name] {
+			return fmt.Errorf("duplicate schedule job name: %s", job.Name)
+		}
+		jobNames[job.Name] = true
+	}
+
+	if c.Scenario.Rate < 0 {
+		return fmt.Errorf("scenario rate must be non-negative (0 for unthrottled)")
+	}
+	for _, spike := range c.Scenario.Spikes {
+		if spike.Start < 0 || spike.Duration <= 0 || spike.Multiplier <= 0 {
+			return fmt.Errorf("scenario spikes need start >= 0, duration > 0 and multiplier > 0")
+		}
+	}
+	switch c.Scenario.Jitter.Distribution {
+	case "", "exponential", "uniform":
+	default:
+		return fmt.Errorf("scenario jitter distribution must be 'exponential', 'uniform' or empty")
+	}
+	if c.Scenario.Jitter.Spread < 0 || c.Scenario.Jitter.Spread > 1 {
+		return fmt.Errorf("scenario jitter spread must be between 0 and 1")
+	}
+	arrivals := c.Scenario.Arrivals
+	switch arrivals.Process {
+	case "", "poisson", "mmpp", "bursty":
+	default:
+		return fmt.Errorf("scenario arrivals process must be 'poisson', 'mmpp', 'bursty' or empty")
+	}
+	if arrivals.Process != "" && c.Scenario.Jitter.Distribution != "" {
+		return fmt.Errorf("scenario arrivals and jitter cannot both be set")
+	}
+	active := len(arrivals.States) == 0
+	for _, state := range arrivals.States {
+		if state.Multiplier < 0 || state.Duration <= 0 {
+			return fmt.Errorf("scenario arrival states need multiplier >= 0 and duration > 0")
+		}
+		active = active || state.Multiplier > 0
+	}
+	if !active {
+		return fmt.Errorf("scenario arrival states need at least one positive multiplier")
+	}
+	if arrivals.Hurst != 0 && (arrivals.Hurst < 0.5 || arrivals.Hurst >= 1) {
+		return fmt.Errorf("scenario arrivals hurst must be at least 0.5 and below 1")
+	}
+	if arrivals.On < 0 || arrivals.Off < 0 {
+		return fmt.Errorf("scenario arrivals on and off must be non-negative")
+	}
+	for vendor, weight := range c.Scenario.VendorWeights {
+		if weight < 0 {
+			return fmt.Errorf("scenario vendor weight for %s must be non-negative", vendor)
+		}
+	}
+	for currency, weight := range c.Scenario.CurrencyWeights {
+		if weight < 0 {
+			return fmt.Errorf("scenario currency weight for %s must be non-negative", currency)
+		}
+	}
+	for _, outage := range c.Scenario.VendorOutages {
+		if outage.Vendor == "" || outage.Start < 0 || outage.Duration <= 0 || outage.Backfill < 0 || outage.Backfill > 1 {
+			return fmt.Errorf("scenario vendor outages need a vendor, start >= 0, duration > 0 and backfill between 0 and 1")
+		}
+	}
+	behaviors := c.Scenario.PlayerBehaviors
+	if behaviors.Rate < 0 || behaviors.Rate > 1 || behaviors.SessionLength < 0 {
+		return fmt.Errorf("scenario player_behaviors rate must be between 0 and 1 and session_length non-negative")
+	}
+	for name, weight := range behaviors.Weights {
+		if weight < 0 {
+			return fmt.Errorf("scenario player behavior weight for %s must be non-negative", name)
+		}
+	}
+	for _, window := range c.Scenario.CategoryWindows {
+		_, openErr := ParseClock(window.Open)
+		_, closeErr := ParseClock(window.Close)
+		if window.Category == "" || openErr != nil || closeErr != nil || window.Multiplier < 0 {
+			return fmt.Errorf("scenario category windows need a category, HH:MM open and close times and multiplier >= 0")
+		}
+	}
+	if c.Scenario.CategoryTimezone != "" {
+		if _, err := time.LoadLocation(c.Scenario.CategoryTimezone); err != nil {
+			return fmt.Errorf("invalid scenario category_timezone: %w", err)
+		}
+	}
+	for _, shock := range c.Scenario.CurrencyShocks {
+		if shock.Currency == "" || shock.Start < 0 || shock.Duration < 0 || shock.Multiplier <= 0 || shock.Volatility < 0 {
+			return fmt.Errorf("scenario currency shocks need a currency, start >= 0, duration >= 0, multiplier > 0 and volatility >= 0")
+		}
+	}
+	templateNames := make(map[string]bool, len(c.Scenario.Templates))
+	for _, tpl := range c.Scenario.Templates {
+		if tpl.Name == "" || templateNames[tpl.Name] {
+			return fmt.Errorf("scenario templates need unique names")
+		}
+		templateNames[tpl.Name] = true
+		if tpl.Weight < 0 {
+			return fmt.Errorf("scenario template %s weight must be non-negative", tpl.Name)
+		}
+		if (tpl.BetMin != 0 || tpl.BetMax != 0) && (tpl.BetMin <= 0 || tpl.BetMax < tpl.BetMin) {
+			return fmt.Errorf("scenario template %s needs 0 < bet_min <= bet_max", tpl.Name)
+		}
+		for _, m := range tpl.WinMultipliers {
+			if m < 0 {
+				return fmt.Errorf("scenario template %s win multipliers must be non-negative", tpl.Name)
+			}
+		}
 	}
 
 	return nil
 }
+
+// validCompressionLevel checks level against the range codec accepts. Zero
+// always means the codec default. The Kafka client takes no lz4 level.
+func validCompressionLevel(sink, codec string, level int, lz4Levels bool) error {
+	if level == 0 {
+		return nil
+	}
+	var maxLevel int
+	switch codec {
+	case "gzip":
+		maxLevel = 9
+	case "zstd":
+		maxLevel = 19
+	case "lz4":
+		if lz4Levels {
+			maxLevel = 9
+		}
+	}
+	if maxLevel == 0 {
+		return fmt.Errorf("%s compression_level is not supported with %q compression", sink, codec)
+	}
+	if level < 1 || level > maxLevel {
+		return fmt.Errorf("%s %s compression_level must be between 1 and %d", sink, codec, maxLevel)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// checkFilePaths rejects enabled file outputs that resolve to the same file,
+// which would interleave their records in it
+func (c *Config) checkFilePaths() error {
+	o := c.Output
+	gzipped := func(filename string, gzip bool) string {
+		if gzip && !strings.HasSuffix(filename, ".gz") {
+			return filename + ".gz"
+		}
+		return filename
+	}
+	type fileOutput struct {
+		filename     string
+		destinations []DestinationConfig
+	}
+	outputs := map[string]fileOutput{
+		"csv":         {o.CSV.Filename, o.CSV.Destinations},
+		"parquet":     {o.Parquet.Filename, o.Parquet.Destinations},
+		"jsonl":       {gzipped(o.JSONL.Filename, o.JSONL.Gzip), o.JSONL.Destinations},
+		"msgpack":     {gzipped(o.Msgpack.Filename, o.Msgpack.Gzip), o.Msgpack.Destinations},
+		"render":      {o.Render.Filename, o.Render.Destinations},
+		"arrow":       {o.Arrow.Filename, o.Arrow.Destinations},
+		"fixed_width": {o.FixedWidth.Filename, o.FixedWidth.Destinations},
+		"xml":         {o.XML.Filename, o.XML.Destinations},
+		"xlsx":        {o.XLSX.Filename, nil},
+		"duckdb":      {o.DuckDB.Filename, nil},
+		"sqlite":      {o.SQLite.Filename, nil},
+	}
+
+	writers := make(map[string]string) // path -> output writing it
+	for _, name := range c.SinkNames() {
+		out, ok := outputs[name]
+		if !ok {
+			continue
+		}
+		dirs := []string{o.Directory}
+		if len(out.destinations) > 0 {
+			dirs = dirs[:0]
+			for _, dest := range out.destinations {
+				dirs = append(dirs, dest.Directory)
+			}
+		}
+		for _, dir := range dirs {
+			path := filepath.Join(dir, out.filename)
+			if other, ok := writers[path]; ok {
+				return fmt.Errorf("%s and %s both write %s; give one of them another filename", other, name, path)
+			}
+			writers[path] = name
+		}
+	}
+	return nil
+}
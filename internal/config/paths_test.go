@@ -0,0 +1,42 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckFilePaths(t *testing.T) {
+	c := &Config{Output: OutputConfig{
+		Directory:  "out",
+		Render:     RenderConfig{Enabled: true, Filename: "transactions.dat"},
+		FixedWidth: FixedWidthConfig{Enabled: true, Filename: "transactions.dat"},
+	}}
+	if err := c.checkFilePaths(); err == nil || !strings.Contains(err.Error(), "render and fixed_width both write out/transactions.dat") {
+		t.Errorf("err = %v", err)
+	}
+
+	c.Output.FixedWidth.Filename = "transactions.fw"
+	if err := c.checkFilePaths(); err != nil {
+		t.Errorf("distinct filenames: %v", err)
+	}
+
+	// A destination moves the file out of output.directory
+	c.Output.FixedWidth.Filename = "transactions.dat"
+	c.Output.FixedWidth.Destinations = []DestinationConfig{{Directory: "legacy"}}
+	if err := c.checkFilePaths(); err != nil {
+		t.Errorf("separate destinations: %v", err)
+	}
+
+	// gzip adds .gz, so jsonl.gz clashes with a render file of that name
+	c.Output.JSONL = JSONLConfig{Enabled: true, Filename: "transactions", Gzip: true}
+	c.Output.Render.Filename = "transactions.gz"
+	if err := c.checkFilePaths(); err == nil {
+		t.Error("jsonl gzip path clash accepted")
+	}
+
+	// Disabled outputs do not count
+	c.Output.Render.Enabled = false
+	if err := c.checkFilePaths(); err != nil {
+		t.Errorf("disabled render: %v", err)
+	}
+}
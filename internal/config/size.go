@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ByteSize is a size in bytes that may carry a unit, e.g. 500MB or 2GiB
+type ByteSize int64
+
+// byteUnits maps suffixes to multipliers; decimal and binary units are both
+// accepted, longest suffixes first so "MiB" is not read as "B"
+var byteUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// ParseByteSize parses a plain byte count or a number with a unit
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	multiplier := int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(strings.ToUpper(s), strings.ToUpper(u.suffix)) {
+			s = strings.TrimSpace(s[:len(s)-len(u.suffix)])
+			multiplier = u.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q: want bytes or a number with KB, MB, GB, TB (or KiB, MiB, ...)", s)
+	}
+	return ByteSize(n * float64(multiplier)), nil
+}
+
+func (b *ByteSize) UnmarshalYAML(value *yaml.Node) error {
+	n, err := ParseByteSize(value.Value)
+	if err != nil {
+		return err
+	}
+	*b = n
+	return nil
+}
@@ -0,0 +1,26 @@
+package config
+
+import "testing"
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]ByteSize{
+		"0":       0,
+		"1048576": 1 << 20,
+		"512B":    512,
+		"10KB":    10_000,
+		"1.5GB":   1_500_000_000,
+		"2GiB":    2 << 30,
+		"100 mib": 100 << 20,
+	}
+	for in, want := range tests {
+		got, err := ParseByteSize(in)
+		if err != nil || got != want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "GB", "-1MB", "ten"} {
+		if _, err := ParseByteSize(in); err == nil {
+			t.Errorf("ParseByteSize(%q) succeeded", in)
+		}
+	}
+}
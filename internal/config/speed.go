@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseSpeed parses a replay speed such as 10x, 0.5x or 2 into the factor
+// original time is divided by
+func ParseSpeed(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "x"), 64)
+	if err != nil || v <= 0 || v > 1e6 {
+		return 0, fmt.Errorf("invalid speed %q: want a positive factor such as 10x or 0.5x", s)
+	}
+	return v, nil
+}
+
+// ParseCompression parses a time compression such as 24h:1h, that much
+// original time replayed in that much wall time, into a speed factor
+func ParseCompression(s string) (float64, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), ":")
+	original, ferr := time.ParseDuration(from)
+	wall, terr := time.ParseDuration(to)
+	if !ok || ferr != nil || terr != nil || original <= 0 || wall <= 0 {
+		return 0, fmt.Errorf("invalid compression %q: want original:wall durations such as 24h:1h", s)
+	}
+	return float64(original) / float64(wall), nil
+}
+
+// Factor returns the speed the source is replayed at, 0 when it is not
+// paced by its original timestamps
+func (r SourceReplayConfig) Factor() (float64, error) {
+	switch {
+	case r.Speed != "" && r.Compress != "":
+		return 0, fmt.Errorf("set replay speed or compress, not both")
+	case r.Speed != "":
+		return ParseSpeed(r.Speed)
+	case r.Compress != "":
+		return ParseCompression(r.Compress)
+	}
+	return 0, nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import "testing"
+
+func TestParseSpeed(t *testing.T) {
+	tests := map[string]float64{"10x": 10, "0.5x": 0.5, "2": 2, " 1x ": 1}
+	for in, want := range tests {
+		got, err := ParseSpeed(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSpeed(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "x", "0x", "-2x", "fast"} {
+		if _, err := ParseSpeed(in); err == nil {
+			t.Errorf("ParseSpeed(%q) succeeded", in)
+		}
+	}
+}
+
+func TestReplayFactor(t *testing.T) {
+	tests := []struct {
+		replay SourceReplayConfig
+		want   float64
+	}{
+		{SourceReplayConfig{}, 0},
+		{SourceReplayConfig{Speed: "4x"}, 4},
+		{SourceReplayConfig{Compress: "24h:1h"}, 24},
+		{SourceReplayConfig{Compress: "30m:1h"}, 0.5},
+	}
+	for _, tt := range tests {
+		got, err := tt.replay.Factor()
+		if err != nil || got != tt.want {
+			t.Errorf("%+v.Factor() = %v, %v; want %v", tt.replay, got, err, tt.want)
+		}
+	}
+	for _, bad := range []SourceReplayConfig{
+		{Speed: "2x", Compress: "24h:1h"},
+		{Compress: "24h"},
+		{Compress: "1h:0s"},
+	} {
+		if _, err := bad.Factor(); err == nil {
+			t.Errorf("%+v.Factor() succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholder matches ${name} and ${name:-default}; $${ escapes a literal ${
+var placeholder = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_.]*)(?::-([^}]*))?\}`)
+
+// ExpandParams resolves ${name} and ${name:-default} placeholders in a
+// configuration template. Values are inserted as text before YAML parsing,
+// so quote placeholders that stand for strings. Placeholders without a value
+// or default, and params the template never uses (usually typos), are errors.
+func ExpandParams(data []byte, params map[string]string) ([]byte, error) {
+	missing := make(map[string]bool)
+	used := make(map[string]bool)
+	out := placeholder.ReplaceAllFunc(data, func(m []byte) []byte {
+		if m[1] == '$' {
+			return m[1:] // escaped
+		}
+		groups := placeholder.FindSubmatch(m)
+		name := string(groups[1])
+		used[name] = true
+		if v, ok := params[name]; ok {
+			return []byte(v)
+		}
+		if bytes.Contains(m, []byte(":-")) {
+			return groups[2]
+		}
+		missing[name] = true
+		return m
+	})
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing template parameters: %s (set with -param name=value)", sortedKeys(missing))
+	}
+	unused := make(map[string]bool)
+	for name := range params {
+		if !used[name] {
+			unused[name] = true
+		}
+	}
+	if len(unused) > 0 {
+		return nil, fmt.Errorf("template does not use parameters: %s", sortedKeys(unused))
+	}
+	return out, nil
+}
+
+func sortedKeys(set map[string]bool) string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WorkerCount is a worker or shard count that may be set to "auto"
+type WorkerCount int
+
+// AutoWorkers marks a count sized from the available CPUs at startup
+const AutoWorkers WorkerCount = -1
+
+// IsAuto reports whether the count is left to startup sizing
+func (w WorkerCount) IsAuto() bool {
+	return w == AutoWorkers
+}
+
+// ParseWorkerCount parses a count or "auto"
+func ParseWorkerCount(s string) (WorkerCount, error) {
+	if s == "auto" {
+		return AutoWorkers, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid worker count %q: want a number or auto", s)
+	}
+	return WorkerCount(n), nil
+}
+
+func (w *WorkerCount) UnmarshalYAML(value *yaml.Node) error {
+	n, err := ParseWorkerCount(value.Value)
+	if err != nil {
+		return err
+	}
+	*w = n
+	return nil
+}
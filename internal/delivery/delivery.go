@@ -0,0 +1,233 @@
+// Package delivery pushes finished output files to a partner's SFTP or FTPS
+// server, the way batch files are handed over in production: uploaded under
+// a temporary name and renamed once complete, so the receiving side never
+// picks up a partial file. Files can go to an S3 or GCS bucket too, where a
+// multipart upload only appears once complete.
+package delivery
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Client is a connection to one remote server
+type Client interface {
+	// Put writes r to the file at remote, replacing it, and creates the
+	// directories above it
+	Put(remote string, r io.Reader) error
+	// Rename moves from to to, replacing to if it exists
+	Rename(from, to string) error
+	Close() error
+}
+
+// Dialer opens a new connection
+type Dialer func(ctx context.Context) (Client, error)
+
+// Options controls where and how files are delivered
+type Options struct {
+	Directory    string        // remote directory, empty = the login directory
+	TempSuffix   string        // a file is uploaded as name+suffix, then renamed
+	Retries      int           // attempts after the first failed one
+	RetryBackoff time.Duration // wait before the first retry, doubling after each
+	RemoveLocal  bool          // delete the local file once it is delivered
+	Queue        int           // files waiting for upload before Enqueue blocks
+	// OnDelivered is called from the delivery goroutine after each file
+	// is delivered, nil = none
+	OnDelivered func(Receipt)
+}
+
+// Receipt describes one delivered file
+type Receipt struct {
+	Local     string        // local path
+	Remote    string        // remote path, below the remote directory
+	Rows      int64         // records in the file
+	Bytes     int64         // size as uploaded
+	SHA256    string        // hex checksum of the uploaded content
+	Attempts  int           // uploads tried, 1 when the first succeeded
+	Took      time.Duration // time the successful upload took
+	Delivered time.Time     // when the rename completed
+}
+
+type job struct {
+	local, remote string
+	rows          int64
+}
+
+// Deliverer uploads files in the background over one reused connection, so
+// rotating writers are not held up by the network. Failed uploads are
+// retried on a fresh connection.
+type Deliverer struct {
+	dial      Dialer
+	opts      Options
+	client    Client // nil until the first upload or after a failure
+	jobs      chan job
+	done      chan struct{}
+	closeOnce sync.Once
+	delivered atomic.Int64
+	bytes     atomic.Int64
+	failed    []string // remote names that could not be delivered
+	logger    *slog.Logger
+}
+
+// New starts a deliverer connecting with dial
+func New(dial Dialer, opts Options, logger *slog.Logger) *Deliverer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	d := &Deliverer{
+		dial:   dial,
+		opts:   opts,
+		jobs:   make(chan job, max(opts.Queue, 1)),
+		done:   make(chan struct{}),
+		logger: logger,
+	}
+	go d.run()
+	return d
+}
+
+// Enqueue schedules the local file, holding rows records, for upload as
+// remote, a slash-separated path below the remote directory
+func (d *Deliverer) Enqueue(local, remote string, rows int64) {
+	d.jobs <- job{local: local, remote: remote, rows: rows}
+}
+
+// Delivered returns the number of files and bytes uploaded so far
+func (d *Deliverer) Delivered() (files, bytes int64) {
+	return d.delivered.Load(), d.bytes.Load()
+}
+
+func (d *Deliverer) run() {
+	defer close(d.done)
+	for j := range d.jobs {
+		if err := d.deliver(j); err != nil {
+			d.failed = append(d.failed, j.remote)
+			d.logger.Error("File delivery failed", "file", j.local, "remote", j.remote, "error", err)
+		}
+	}
+	if d.client != nil {
+		d.client.Close()
+	}
+}
+
+// deliver uploads one file, retrying with backoff
+func (d *Deliverer) deliver(j job) error {
+	remote := path.Join(d.opts.Directory, j.remote)
+	backoff := d.opts.RetryBackoff
+	var err error
+	for attempt := 0; attempt <= d.opts.Retries; attempt++ {
+		if attempt > 0 {
+			d.logger.Warn("Retrying file delivery", "remote", remote, "attempt", attempt, "error", err)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var size int64
+		var sum string
+		start := time.Now()
+		if size, sum, err = d.upload(j.local, remote); err == nil {
+			took := time.Since(start)
+			d.delivered.Add(1)
+			d.bytes.Add(size)
+			d.logger.Info("File delivered", "file", j.local, "remote", remote, "bytes", size, "sha256", sum,
+				"took", took, "mb_per_sec", megabytesPerSecond(size, took))
+			if d.opts.RemoveLocal {
+				if err := os.Remove(j.local); err != nil {
+					d.logger.Warn("Failed to remove delivered file", "file", j.local, "error", err)
+				}
+			}
+			if d.opts.OnDelivered != nil {
+				d.opts.OnDelivered(Receipt{
+					Local:     j.local,
+					Remote:    remote,
+					Rows:      j.rows,
+					Bytes:     size,
+					SHA256:    sum,
+					Attempts:  attempt + 1,
+					Took:      took,
+					Delivered: time.Now(),
+				})
+			}
+			return nil
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			return err // retrying will not bring the local file back
+		}
+		// The connection may be broken; the next attempt starts afresh
+		if d.client != nil {
+			d.client.Close()
+			d.client = nil
+		}
+	}
+	return err
+}
+
+// upload sends one file, returning its size and the SHA-256 of what was sent
+func (d *Deliverer) upload(local, remote string) (int64, string, error) {
+	f, err := os.Open(local)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, "", err
+	}
+
+	if d.client == nil {
+		if d.client, err = d.dial(context.Background()); err != nil {
+			return 0, "", fmt.Errorf("failed to connect: %w", err)
+		}
+	}
+	temp := remote + d.opts.TempSuffix
+	hash := sha256.New()
+	if err := d.client.Put(temp, io.TeeReader(f, hash)); err != nil {
+		return 0, "", fmt.Errorf("failed to upload %s: %w", temp, err)
+	}
+	if temp != remote {
+		if err := d.client.Rename(temp, remote); err != nil {
+			return 0, "", fmt.Errorf("failed to rename %s to %s: %w", temp, remote, err)
+		}
+	}
+	return info.Size(), hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Close waits for queued files to be delivered and reports the ones that
+// could not be
+func (d *Deliverer) Close() error {
+	d.closeOnce.Do(func() { close(d.jobs) })
+	<-d.done
+	if len(d.failed) > 0 {
+		return fmt.Errorf("%d file(s) not delivered: %s", len(d.failed), strings.Join(d.failed, ", "))
+	}
+	return nil
+}
+
+// megabytesPerSecond is the throughput of n bytes in d, rounded to 0.01
+func megabytesPerSecond(n int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return math.Round(float64(n)/1e6/d.Seconds()*100) / 100
+}
+
+// parentDirs returns the directories above the slash-separated file path,
+// outermost first
+func parentDirs(file string) []string {
+	var dirs []string
+	for dir := path.Dir(file); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+	}
+	return dirs
+}
@@ -0,0 +1,136 @@
+package delivery
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memClient is an in-memory server whose first failPuts uploads fail
+type memClient struct {
+	mu       sync.Mutex
+	files    map[string]string
+	failPuts int
+	dials    int
+}
+
+func (m *memClient) dial(ctx context.Context) (Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dials++
+	return m, nil
+}
+
+func (m *memClient) Put(remote string, r io.Reader) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failPuts > 0 {
+		m.failPuts--
+		return errors.New("connection reset")
+	}
+	data, err := io.ReadAll(r)
+	m.files[remote] = string(data)
+	return err
+}
+
+func (m *memClient) Rename(from, to string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[from]
+	if !ok {
+		return os.ErrNotExist
+	}
+	delete(m.files, from)
+	m.files[to] = data
+	return nil
+}
+
+func (m *memClient) Close() error { return nil }
+
+func writeLocal(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDelivererRetriesAndRenames(t *testing.T) {
+	dir := t.TempDir()
+	server := &memClient{files: make(map[string]string), failPuts: 2}
+	var receipts []Receipt
+	d := New(server.dial, Options{
+		Directory:   "/incoming",
+		TempSuffix:  ".part",
+		Retries:     3,
+		RemoveLocal: true,
+		OnDelivered: func(r Receipt) { receipts = append(receipts, r) },
+	}, nil)
+
+	local := writeLocal(t, dir, "transactions.csv", "id\nTXN-1\n")
+	d.Enqueue(local, "2024-01-01/transactions.csv", 1)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := server.files["/incoming/2024-01-01/transactions.csv"]; got != "id\nTXN-1\n" {
+		t.Errorf("remote files = %v", server.files)
+	}
+	if len(server.files) != 1 {
+		t.Errorf("temporary file left behind: %v", server.files)
+	}
+	// Every failure drops the connection
+	if server.dials != 3 {
+		t.Errorf("dials = %d, want 3", server.dials)
+	}
+	if files, bytes := d.Delivered(); files != 1 || bytes != 9 {
+		t.Errorf("delivered %d files, %d bytes", files, bytes)
+	}
+	if _, err := os.Stat(local); !os.IsNotExist(err) {
+		t.Error("local file kept with remove_local")
+	}
+
+	if len(receipts) != 1 {
+		t.Fatalf("receipts = %v", receipts)
+	}
+	r := receipts[0]
+	sum := sha256.Sum256([]byte("id\nTXN-1\n"))
+	if r.Remote != "/incoming/2024-01-01/transactions.csv" || r.Rows != 1 || r.Bytes != 9 || r.Attempts != 3 || r.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("receipt = %+v", r)
+	}
+}
+
+func TestDelivererReportsFailures(t *testing.T) {
+	dir := t.TempDir()
+	server := &memClient{files: make(map[string]string), failPuts: 10}
+	d := New(server.dial, Options{Retries: 1}, nil)
+
+	d.Enqueue(writeLocal(t, dir, "a.csv", "a"), "a.csv", 1)
+	d.Enqueue(filepath.Join(dir, "missing.csv"), "missing.csv", 0)
+	err := d.Close()
+	if err == nil || !strings.Contains(err.Error(), "2 file(s) not delivered: a.csv, missing.csv") {
+		t.Fatalf("Close = %v", err)
+	}
+	// The missing file is not retried
+	if server.failPuts != 8 {
+		t.Errorf("puts attempted = %d, want 2", 10-server.failPuts)
+	}
+}
+
+func TestParentDirs(t *testing.T) {
+	got := strings.Join(parentDirs("/incoming/2024/01/transactions.csv"), " ")
+	if got != "/incoming /incoming/2024 /incoming/2024/01" {
+		t.Errorf("parentDirs = %s", got)
+	}
+	if dirs := parentDirs("transactions.csv"); len(dirs) != 0 {
+		t.Errorf("parentDirs = %v", dirs)
+	}
+}
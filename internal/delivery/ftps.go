@@ -0,0 +1,238 @@
+package delivery
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FTPSConfig holds the connection settings of an FTPS server
+type FTPSConfig struct {
+	Addr     string // host:port
+	User     string
+	Password string
+	// Implicit starts TLS on connect (usually port 990) instead of upgrading
+	// the control connection with AUTH TLS
+	Implicit           bool
+	CAFile             string // PEM CA bundle for the server certificate, empty = system roots
+	InsecureSkipVerify bool
+	Timeout            time.Duration // per connection attempt and command
+}
+
+// NewFTPSDialer returns a dialer for the server, failing early on an
+// unreadable CA file
+func NewFTPSDialer(cfg FTPSConfig) (Dialer, error) {
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ftps address: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		// Servers such as vsftpd require data connections to resume the
+		// control connection's TLS session
+		ClientSessionCache: tls.NewLRUClientSessionCache(0),
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ftps CA file: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in ftps CA file %s", cfg.CAFile)
+		}
+	}
+
+	return func(ctx context.Context) (Client, error) {
+		dialer := net.Dialer{Timeout: cfg.Timeout}
+		conn, err := dialer.DialContext(ctx, "tcp", cfg.Addr)
+		if err != nil {
+			return nil, err
+		}
+		c := &ftpsClient{host: host, tls: tlsConfig, timeout: cfg.Timeout, dialer: dialer}
+		if err := c.login(conn, cfg); err != nil {
+			c.conn.Close()
+			return nil, err
+		}
+		return c, nil
+	}, nil
+}
+
+// ftpsClient speaks FTP over TLS (RFC 4217) on both the control and the
+// passive data connections
+type ftpsClient struct {
+	host    string
+	tls     *tls.Config
+	timeout time.Duration
+	dialer  net.Dialer
+	conn    net.Conn
+	text    *textproto.Conn
+}
+
+// login secures the control connection and authenticates
+func (c *ftpsClient) login(conn net.Conn, cfg FTPSConfig) error {
+	c.conn = conn
+	if cfg.Implicit {
+		c.conn = tls.Client(conn, c.tls)
+	}
+	c.text = textproto.NewConn(c.conn)
+	c.deadline()
+	if _, _, err := c.text.ReadResponse(220); err != nil {
+		return err
+	}
+	if !cfg.Implicit {
+		if _, _, err := c.cmd(234, "AUTH TLS"); err != nil {
+			return err
+		}
+		c.conn = tls.Client(conn, c.tls)
+		c.text = textproto.NewConn(c.conn)
+	}
+
+	code, _, err := c.cmd(0, "USER %s", cfg.User)
+	if err != nil {
+		return err
+	}
+	switch code {
+	case 230:
+	case 331:
+		if _, _, err := c.cmd(230, "PASS %s", cfg.Password); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("ftps login refused with %d", code)
+	}
+
+	// Protect the data connections too, and transfer bytes as they are
+	for _, command := range []string{"PBSZ 0", "PROT P", "TYPE I"} {
+		if _, _, err := c.cmd(200, "%s", command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Put uploads r to remote through a passive data connection
+func (c *ftpsClient) Put(remote string, r io.Reader) error {
+	for _, dir := range parentDirs(remote) {
+		// An existing directory answers 550, which is fine; a real problem
+		// shows up when the file is stored
+		c.cmd(0, "MKD %s", dir)
+	}
+
+	addr, err := c.passive()
+	if err != nil {
+		return err
+	}
+	dataConn, err := c.dialer.Dial("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to open data connection: %w", err)
+	}
+	defer dataConn.Close()
+
+	// 125 or 150: the server is ready for the data
+	if _, _, err := c.cmd(1, "STOR %s", remote); err != nil {
+		return err
+	}
+	data := tls.Client(dataConn, c.tls)
+	if _, err := io.Copy(deadlineWriter{data, c.timeout}, r); err != nil {
+		return fmt.Errorf("failed to send data: %w", err)
+	}
+	if err := data.Close(); err != nil {
+		return fmt.Errorf("failed to close data connection: %w", err)
+	}
+
+	// 226 or 250: the file is stored
+	c.deadline()
+	_, _, err = c.text.ReadResponse(2)
+	return err
+}
+
+// passive enters extended passive mode, falling back to PASV, and returns
+// the data address. The host is always the control connection's, since
+// servers behind NAT often advertise an internal address.
+func (c *ftpsClient) passive() (string, error) {
+	if _, msg, err := c.cmd(229, "EPSV"); err == nil {
+		// Entering Extended Passive Mode (|||6446|)
+		start, end := strings.Index(msg, "(|||"), strings.LastIndex(msg, "|)")
+		if start < 0 || end < start+4 {
+			return "", fmt.Errorf("unparseable EPSV reply %q", msg)
+		}
+		return net.JoinHostPort(c.host, msg[start+4:end]), nil
+	}
+
+	_, msg, err := c.cmd(227, "PASV")
+	if err != nil {
+		return "", err
+	}
+	// Entering Passive Mode (h1,h2,h3,h4,p1,p2)
+	start, end := strings.Index(msg, "("), strings.LastIndex(msg, ")")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("unparseable PASV reply %q", msg)
+	}
+	fields := strings.Split(msg[start+1:end], ",")
+	if len(fields) != 6 {
+		return "", fmt.Errorf("unparseable PASV reply %q", msg)
+	}
+	hi, err1 := strconv.Atoi(fields[4])
+	lo, err2 := strconv.Atoi(fields[5])
+	if err1 != nil || err2 != nil {
+		return "", fmt.Errorf("unparseable PASV reply %q", msg)
+	}
+	return net.JoinHostPort(c.host, strconv.Itoa(hi<<8|lo)), nil
+}
+
+// Rename moves from to to with RNFR and RNTO
+func (c *ftpsClient) Rename(from, to string) error {
+	if _, _, err := c.cmd(350, "RNFR %s", from); err != nil {
+		return err
+	}
+	_, _, err := c.cmd(250, "RNTO %s", to)
+	return err
+}
+
+// Close ends the session
+func (c *ftpsClient) Close() error {
+	c.deadline()
+	c.text.PrintfLine("QUIT")
+	return c.conn.Close()
+}
+
+// cmd sends a command and reads the reply, which must match code as
+// textproto.Reader.ReadResponse checks it (0 accepts any reply)
+func (c *ftpsClient) cmd(code int, format string, args ...any) (int, string, error) {
+	c.deadline()
+	if err := c.text.PrintfLine(format, args...); err != nil {
+		return 0, "", err
+	}
+	return c.text.ReadResponse(code)
+}
+
+// deadline bounds the next control connection exchange by the timeout
+func (c *ftpsClient) deadline() {
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+}
+
+// deadlineWriter refreshes the connection's deadline before every write, so
+// the timeout catches a stalled transfer rather than a long one
+type deadlineWriter struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (w deadlineWriter) Write(p []byte) (int, error) {
+	if w.timeout > 0 {
+		w.conn.SetDeadline(time.Now().Add(w.timeout))
+	}
+	return w.conn.Write(p)
+}
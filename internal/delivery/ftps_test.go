@@ -0,0 +1,198 @@
+package delivery
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCertificate returns a self-signed certificate for 127.0.0.1 and its
+// PEM encoding
+func testCertificate(t *testing.T) (tls.Certificate, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// fakeFTPSServer accepts one explicit-TLS session and stores uploads in
+// memory
+type fakeFTPSServer struct {
+	tls   *tls.Config
+	mu    sync.Mutex
+	files map[string]string
+	dirs  []string
+}
+
+func (s *fakeFTPSServer) serve(t *testing.T, ln net.Listener) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	var rw net.Conn = conn
+	r := bufio.NewReader(rw)
+	reply := func(format string, args ...any) { fmt.Fprintf(rw, format+"\r\n", args...) }
+
+	reply("220 ready")
+	var data net.Listener
+	var renameFrom string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch verb {
+		case "AUTH":
+			reply("234 go ahead")
+			rw = tls.Server(conn, s.tls)
+			r = bufio.NewReader(rw)
+		case "USER":
+			reply("331 password please")
+		case "PASS":
+			if arg != "secret" {
+				reply("530 login incorrect")
+				continue
+			}
+			reply("230 logged in")
+		case "PBSZ", "PROT", "TYPE":
+			reply("200 ok")
+		case "MKD":
+			s.mu.Lock()
+			s.dirs = append(s.dirs, arg)
+			s.mu.Unlock()
+			reply("257 created")
+		case "EPSV":
+			if data, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+				t.Error(err)
+				return
+			}
+			reply("229 Entering Extended Passive Mode (|||%d|)", data.Addr().(*net.TCPAddr).Port)
+		case "STOR":
+			dataConn, err := data.Accept()
+			data.Close()
+			if err != nil {
+				return
+			}
+			reply("150 send it")
+			content, err := io.ReadAll(tls.Server(dataConn, s.tls))
+			dataConn.Close()
+			if err != nil {
+				reply("426 transfer aborted")
+				continue
+			}
+			s.mu.Lock()
+			s.files[arg] = string(content)
+			s.mu.Unlock()
+			reply("226 stored")
+		case "RNFR":
+			renameFrom = arg
+			reply("350 ready for RNTO")
+		case "RNTO":
+			s.mu.Lock()
+			s.files[arg] = s.files[renameFrom]
+			delete(s.files, renameFrom)
+			s.mu.Unlock()
+			reply("250 renamed")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestFTPSDelivery(t *testing.T) {
+	cert, certPEM := testCertificate(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	server := &fakeFTPSServer{tls: &tls.Config{Certificates: []tls.Certificate{cert}}, files: make(map[string]string)}
+	done := make(chan struct{})
+	go func() {
+		server.serve(t, ln)
+		close(done)
+	}()
+
+	dial, err := NewFTPSDialer(FTPSConfig{Addr: ln.Addr().String(), User: "partner", Password: "secret", CAFile: caFile, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := dial(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Put("in/batch.csv.part", strings.NewReader("id\nTXN-1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Rename("in/batch.csv.part", "in/batch.csv"); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	<-done
+
+	if got := server.files["in/batch.csv"]; got != "id\nTXN-1\n" || len(server.files) != 1 {
+		t.Errorf("files = %v", server.files)
+	}
+	if strings.Join(server.dirs, " ") != "in" {
+		t.Errorf("directories = %v", server.dirs)
+	}
+}
+
+func TestFTPSUntrustedCertificate(t *testing.T) {
+	cert, _ := testCertificate(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	server := &fakeFTPSServer{tls: &tls.Config{Certificates: []tls.Certificate{cert}}, files: make(map[string]string)}
+	go server.serve(t, ln)
+
+	dial, err := NewFTPSDialer(FTPSConfig{Addr: ln.Addr().String(), User: "partner", Password: "secret", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dial(context.Background()); err == nil {
+		t.Fatal("self-signed certificate accepted without ca_file")
+	}
+}
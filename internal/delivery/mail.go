@@ -0,0 +1,183 @@
+package delivery
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MailConfig is the SMTP server notifications are sent through
+type MailConfig struct {
+	Addr        string // host:port
+	Username    string // empty = no authentication
+	Password    string
+	From        string
+	To          []string
+	ImplicitTLS bool // TLS from connect (port 465) instead of STARTTLS
+	Timeout     time.Duration
+}
+
+// NotifyOptions controls what the notifications say
+type NotifyOptions struct {
+	SubjectPrefix string // prepended to every subject
+	Server        string // where files were delivered, e.g. sftp://partner.example.com
+	RunID         string
+	Digest        bool // one message for the run at Close instead of one per file
+}
+
+// Notifier emails a summary of delivered files to the partner-facing
+// recipients, the way a delivery confirmation accompanies a batch file
+type Notifier struct {
+	mail    MailConfig
+	opts    NotifyOptions
+	mu      sync.Mutex
+	pending []Receipt // digest mode: files delivered so far
+	sent    int
+	logger  *slog.Logger
+}
+
+// NewNotifier creates a notifier sending through mail
+func NewNotifier(mail MailConfig, opts NotifyOptions, logger *slog.Logger) *Notifier {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if mail.Timeout <= 0 {
+		mail.Timeout = 30 * time.Second
+	}
+	return &Notifier{mail: mail, opts: opts, logger: logger}
+}
+
+// Delivered notifies about one delivered file, or holds it for the digest.
+// Send failures are logged: a missed email does not undo the delivery.
+func (n *Notifier) Delivered(r Receipt) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.opts.Digest {
+		n.pending = append(n.pending, r)
+		return
+	}
+	subject := fmt.Sprintf("%s delivered (%d rows)", path.Base(r.Remote), r.Rows)
+	if err := n.send(subject, []Receipt{r}); err != nil {
+		n.logger.Error("Failed to send delivery notification", "remote", r.Remote, "error", err)
+		return
+	}
+	n.sent++
+}
+
+// Close sends the digest, if any files were delivered. Call it after the
+// deliverer is closed.
+func (n *Notifier) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.pending) > 0 {
+		var rows int64
+		for _, r := range n.pending {
+			rows += r.Rows
+		}
+		subject := fmt.Sprintf("%d file(s) delivered (%d rows)", len(n.pending), rows)
+		if err := n.send(subject, n.pending); err != nil {
+			return fmt.Errorf("failed to send delivery digest: %w", err)
+		}
+		n.pending = nil
+		n.sent++
+	}
+	n.logger.Info("Delivery notifications sent", "messages", n.sent, "recipients", len(n.mail.To))
+	return nil
+}
+
+// message renders the plain-text email for receipts
+func (n *Notifier) message(subject string, receipts []Receipt) []byte {
+	if n.opts.SubjectPrefix != "" {
+		subject = n.opts.SubjectPrefix + " " + subject
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", n.mail.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(n.mail.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	if n.opts.Server != "" {
+		fmt.Fprintf(&b, "Server:    %s\r\n", n.opts.Server)
+	}
+	if n.opts.RunID != "" {
+		fmt.Fprintf(&b, "Run ID:    %s\r\n", n.opts.RunID)
+	}
+	for _, r := range receipts {
+		b.WriteString("\r\n")
+		fmt.Fprintf(&b, "File:      %s\r\n", r.Remote)
+		fmt.Fprintf(&b, "Rows:      %d\r\n", r.Rows)
+		fmt.Fprintf(&b, "Bytes:     %d\r\n", r.Bytes)
+		fmt.Fprintf(&b, "SHA-256:   %s\r\n", r.SHA256)
+		fmt.Fprintf(&b, "Delivered: %s\r\n", r.Delivered.UTC().Format(time.RFC3339))
+	}
+	return []byte(b.String())
+}
+
+// send delivers one message to every recipient
+func (n *Notifier) send(subject string, receipts []Receipt) error {
+	host, _, err := net.SplitHostPort(n.mail.Addr)
+	if err != nil {
+		return err
+	}
+	tlsConfig := &tls.Config{ServerName: host}
+	dialer := &net.Dialer{Timeout: n.mail.Timeout}
+
+	var conn net.Conn
+	if n.mail.ImplicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", n.mail.Addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", n.mail.Addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", n.mail.Addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(n.mail.Timeout))
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if !n.mail.ImplicitTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("STARTTLS failed: %w", err)
+			}
+		}
+	}
+	if n.mail.Username != "" {
+		// PlainAuth refuses to send the password unencrypted, except to localhost
+		if err := c.Auth(smtp.PlainAuth("", n.mail.Username, n.mail.Password, host)); err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+
+	if err := c.Mail(n.mail.From); err != nil {
+		return err
+	}
+	for _, to := range n.mail.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(n.message(subject, receipts)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,172 @@
+package delivery
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer accepts plain SMTP sessions and records each message
+type fakeSMTPServer struct {
+	mu         sync.Mutex
+	messages   []string
+	recipients []string
+}
+
+func (s *fakeSMTPServer) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.session(conn)
+	}
+}
+
+func (s *fakeSMTPServer) session(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			reply("250 ok")
+		case "RCPT":
+			s.mu.Lock()
+			s.recipients = append(s.recipients, arg)
+			s.mu.Unlock()
+			reply("250 ok")
+		case "DATA":
+			reply("354 end with .")
+			var msg strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				msg.WriteString(line)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, msg.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func startSMTP(t *testing.T) (*fakeSMTPServer, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	server := &fakeSMTPServer{}
+	go server.serve(ln)
+	return server, ln.Addr().String()
+}
+
+func testReceipt(name string, rows int64) Receipt {
+	return Receipt{
+		Remote:    "/incoming/" + name,
+		Rows:      rows,
+		Bytes:     rows * 100,
+		SHA256:    strings.Repeat("ab", 32),
+		Attempts:  1,
+		Delivered: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestNotifierPerFile(t *testing.T) {
+	server, addr := startSMTP(t)
+	n := NewNotifier(MailConfig{
+		Addr: addr,
+		From: "producer@example.com",
+		To:   []string{"ops@example.com", "partner@example.com"},
+	}, NotifyOptions{SubjectPrefix: "[UAT]", Server: "sftp://partner.example.com", RunID: "run-1"}, nil)
+
+	n.Delivered(testReceipt("transactions-001.csv", 500))
+	n.Delivered(testReceipt("transactions-002.csv", 250))
+	if err := n.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(server.messages) != 2 {
+		t.Fatalf("messages = %d, want 2", len(server.messages))
+	}
+	msg := server.messages[0]
+	for _, want := range []string{
+		"Subject: [UAT] transactions-001.csv delivered (500 rows)\r\n",
+		"To: ops@example.com, partner@example.com\r\n",
+		"Server:    sftp://partner.example.com\r\n",
+		"Run ID:    run-1\r\n",
+		"File:      /incoming/transactions-001.csv\r\n",
+		"Rows:      500\r\n",
+		"SHA-256:   " + strings.Repeat("ab", 32) + "\r\n",
+		"Delivered: 2024-01-01T12:00:00Z\r\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+	if got := strings.Join(server.recipients, " "); got != "TO:<ops@example.com> TO:<partner@example.com> TO:<ops@example.com> TO:<partner@example.com>" {
+		t.Errorf("recipients = %s", got)
+	}
+}
+
+func TestNotifierDigest(t *testing.T) {
+	server, addr := startSMTP(t)
+	n := NewNotifier(MailConfig{Addr: addr, From: "producer@example.com", To: []string{"ops@example.com"}}, NotifyOptions{Digest: true}, nil)
+
+	n.Delivered(testReceipt("a.csv", 500))
+	n.Delivered(testReceipt("b.csv", 250))
+	if len(server.messages) != 0 {
+		t.Fatal("digest sent before Close")
+	}
+	if err := n.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(server.messages) != 1 {
+		t.Fatalf("messages = %d, want 1", len(server.messages))
+	}
+	msg := server.messages[0]
+	if !strings.Contains(msg, "Subject: 2 file(s) delivered (750 rows)\r\n") || !strings.Contains(msg, "/incoming/a.csv") || !strings.Contains(msg, "/incoming/b.csv") {
+		t.Errorf("digest = %s", msg)
+	}
+}
+
+func TestNotifierUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	n := NewNotifier(MailConfig{Addr: addr, From: "producer@example.com", To: []string{"ops@example.com"}, Timeout: time.Second}, NotifyOptions{Digest: true}, nil)
+	n.Delivered(testReceipt("a.csv", 1))
+	if err := n.Close(); err == nil {
+		t.Fatal("digest to a closed port succeeded")
+	}
+}
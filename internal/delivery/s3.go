@@ -0,0 +1,430 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/supratick/message_producer/internal/awsauth"
+)
+
+const (
+	// s3MaxParts is the most parts a multipart upload may have
+	s3MaxParts = 10000
+	// s3MaxBackoff caps the wait between retries of a throttled request
+	s3MaxBackoff = 30 * time.Second
+)
+
+// S3Config holds the settings of an S3 bucket, or a bucket in an
+// S3-compatible store such as GCS (its XML API with HMAC keys) or MinIO
+type S3Config struct {
+	Endpoint    string // store URL, empty = AWS S3 in Region
+	Region      string
+	Bucket      string
+	Credentials awsauth.Credentials
+	PartSize    int64 // bytes per multipart part; smaller files take one PUT
+	Concurrency int   // parts of a file uploaded in parallel
+	RateLimit   int64 // bytes per second across all uploads, 0 = unlimited
+	// ThrottleRetries is how often a request answered 503 SlowDown, 429 or
+	// another 5xx is retried, waiting ThrottleBackoff with jitter and
+	// doubling up to 30s
+	ThrottleRetries int
+	ThrottleBackoff time.Duration
+	Timeout         time.Duration // per request
+}
+
+// NewS3Dialer returns a dialer for the bucket. Objects appear only once
+// their upload completes, so the deliverer needs no temp suffix. Every
+// connection shares the rate limit and the throttling count.
+func NewS3Dialer(cfg S3Config, logger *slog.Logger) (Dialer, error) {
+	if !cfg.Credentials.Valid() {
+		return nil, errors.New("s3 delivery needs an access key: set username and password or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if cfg.Bucket == "" || cfg.PartSize <= 0 || cfg.Concurrency <= 0 {
+		return nil, errors.New("s3 delivery needs a bucket, part size and concurrency")
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	shared := &s3Shared{logger: logger}
+	if cfg.RateLimit > 0 {
+		shared.limiter = &rateLimiter{rate: float64(cfg.RateLimit)}
+	}
+
+	return func(ctx context.Context) (Client, error) {
+		c := &s3Client{cfg: cfg, http: &http.Client{Timeout: cfg.Timeout}, shared: shared}
+		// HEAD on the bucket checks the endpoint, key and bucket access
+		if _, _, err := c.do(ctx, http.MethodHead, "", nil, nil); err != nil {
+			return nil, fmt.Errorf("failed to reach bucket %s: %w", cfg.Bucket, err)
+		}
+		return c, nil
+	}, nil
+}
+
+// s3Shared is the state every connection of a dialer shares
+type s3Shared struct {
+	limiter   *rateLimiter
+	throttled atomic.Int64
+	logger    *slog.Logger
+}
+
+// s3Client uploads to one bucket over HTTPS. It keeps no connection state
+// beyond the HTTP client's idle connections.
+type s3Client struct {
+	cfg    S3Config
+	http   *http.Client
+	shared *s3Shared
+}
+
+// Put uploads r to the object named remote: in one request when it fits in
+// a part, otherwise as a multipart upload with Concurrency parts in flight
+func (c *s3Client) Put(remote string, r io.Reader) error {
+	key := strings.TrimPrefix(remote, "/")
+	first := make([]byte, c.cfg.PartSize)
+	n, err := io.ReadFull(r, first)
+	switch err {
+	case io.EOF, io.ErrUnexpectedEOF:
+		if err := c.shared.limiter.wait(context.Background(), n); err != nil {
+			return err
+		}
+		_, _, err = c.do(context.Background(), http.MethodPut, key, nil, first[:n])
+		return err
+	case nil:
+		return c.multipart(key, first, r)
+	default:
+		return err
+	}
+}
+
+// multipart uploads first and the rest of r as the parts of one upload,
+// aborting the upload if any part fails
+func (c *s3Client) multipart(key string, first []byte, r io.Reader) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, body, err := c.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+	var started struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(body, &started); err != nil || started.UploadID == "" {
+		return fmt.Errorf("failed to start multipart upload: no upload ID in %q", body)
+	}
+	uploadID := started.UploadID
+
+	type part struct {
+		number int
+		data   []byte
+	}
+	var (
+		mu       sync.Mutex
+		etags    = make(map[int]string)
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	// Concurrency parts upload while the next one is read; buffers are
+	// recycled so memory stays at (Concurrency+1) * PartSize
+	parts := make(chan part)
+	free := make(chan []byte, c.cfg.Concurrency+1)
+	allocated := 1
+	buffer := func() []byte {
+		select {
+		case b := <-free:
+			return b
+		default:
+		}
+		if allocated <= c.cfg.Concurrency {
+			allocated++
+			return make([]byte, c.cfg.PartSize)
+		}
+		select {
+		case b := <-free:
+			return b
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	for i := 0; i < c.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range parts {
+				etag, err := c.uploadPart(ctx, key, uploadID, p.number, p.data)
+				free <- p.data[:cap(p.data)]
+				if err != nil {
+					fail(fmt.Errorf("failed to upload part %d: %w", p.number, err))
+					continue
+				}
+				mu.Lock()
+				etags[p.number] = etag
+				mu.Unlock()
+			}
+		}()
+	}
+
+	data, last := first, false
+read:
+	for number := 1; ; number++ {
+		if number > s3MaxParts {
+			fail(fmt.Errorf("file needs more than %d parts; raise the part size", s3MaxParts))
+			break
+		}
+		select {
+		case parts <- part{number, data}:
+		case <-ctx.Done():
+			break read
+		}
+		if last {
+			break
+		}
+		buf := buffer()
+		if buf == nil {
+			break
+		}
+		n, err := io.ReadFull(r, buf)
+		switch err {
+		case nil:
+			data = buf
+		case io.ErrUnexpectedEOF:
+			data, last = buf[:n], true
+		case io.EOF:
+			break read
+		default:
+			fail(err)
+			break read
+		}
+	}
+	close(parts)
+	wg.Wait()
+
+	if firstErr != nil {
+		if _, _, err := c.do(context.Background(), http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil); err != nil {
+			c.shared.logger.Warn("Failed to abort multipart upload", "key", key, "error", err)
+		}
+		return firstErr
+	}
+	return c.complete(key, uploadID, etags)
+}
+
+// uploadPart sends one part, paced by the rate limit, and returns its ETag
+func (c *s3Client) uploadPart(ctx context.Context, key, uploadID string, number int, data []byte) (string, error) {
+	if err := c.shared.limiter.wait(ctx, len(data)); err != nil {
+		return "", err
+	}
+	query := url.Values{"partNumber": {fmt.Sprint(number)}, "uploadId": {uploadID}}
+	header, _, err := c.do(ctx, http.MethodPut, key, query, data)
+	if err != nil {
+		return "", err
+	}
+	return header.Get("ETag"), nil
+}
+
+// complete assembles the uploaded parts into the object
+func (c *s3Client) complete(key, uploadID string, etags map[int]string) error {
+	type completedPart struct {
+		PartNumber int
+		ETag       string
+	}
+	var request struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}
+	for number, etag := range etags {
+		request.Parts = append(request.Parts, completedPart{number, etag})
+	}
+	sort.Slice(request.Parts, func(i, j int) bool { return request.Parts[i].PartNumber < request.Parts[j].PartNumber })
+	body, err := xml.Marshal(request)
+	if err != nil {
+		return err
+	}
+	if _, _, err := c.do(context.Background(), http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body); err != nil {
+		return fmt.Errorf("failed to complete multipart upload of %d parts: %w", len(etags), err)
+	}
+	return nil
+}
+
+// Rename is not needed: an object only appears once its upload completes
+func (c *s3Client) Rename(from, to string) error {
+	return errors.New("object stores cannot rename; s3 delivery uploads without a temp suffix")
+}
+
+// Close releases the client's idle connections
+func (c *s3Client) Close() error {
+	c.http.CloseIdleConnections()
+	return nil
+}
+
+// s3Error is the error document S3 answers with
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// do sends a signed request, retrying throttled and failed ones with
+// jittered exponential backoff, and returns the response headers and body
+func (c *s3Client) do(ctx context.Context, method, key string, query url.Values, body []byte) (http.Header, []byte, error) {
+	backoff := c.cfg.ThrottleBackoff
+	for attempt := 0; ; attempt++ {
+		header, data, status, err := c.send(ctx, method, key, query, body)
+		if err == nil || ctx.Err() != nil || !retryable(status) || attempt >= c.cfg.ThrottleRetries {
+			return header, data, err
+		}
+
+		wait := backoff/2 + time.Duration(rand.Int64N(int64(backoff/2)+1))
+		if status == http.StatusServiceUnavailable || status == http.StatusTooManyRequests {
+			c.shared.logger.Warn("Object store throttled, backing off",
+				"bucket", c.cfg.Bucket,
+				"key", key,
+				"status", status,
+				"wait", wait,
+				"throttled_total", c.shared.throttled.Add(1),
+			)
+		} else {
+			c.shared.logger.Warn("Object store request failed, retrying", "bucket", c.cfg.Bucket, "key", key, "error", err, "wait", wait)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, err
+		case <-time.After(wait):
+		}
+		backoff = min(2*backoff, s3MaxBackoff)
+	}
+}
+
+// retryable reports whether a failed request may succeed if sent again:
+// throttling, server errors and requests that got no response
+func retryable(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status >= 500
+}
+
+// send makes one signed request. status is 0 when no response arrived.
+func (c *s3Client) send(ctx context.Context, method, key string, query url.Values, body []byte) (http.Header, []byte, int, error) {
+	target, err := url.Parse(c.objectURL(key))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	// S3 signs each path segment escaped as RFC 3986 requires, which
+	// net/url leaves alone for characters like '=' in dt=2024-01-01
+	target.RawPath = s3EscapePath(target.Path)
+	target.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to create s3 request: %w", err)
+	}
+	req.URL = target
+	req.ContentLength = int64(len(body))
+	if body == nil {
+		req.Body = http.NoBody
+	}
+	sum := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	awsauth.SignV4(req, body, c.cfg.Credentials, c.cfg.Region, "s3", time.Now().UTC())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("s3 request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to read s3 response: %w", err)
+	}
+
+	var failure s3Error
+	// CompleteMultipartUpload can fail after answering 200
+	if resp.StatusCode/100 != 2 || bytes.Contains(data, []byte("<Error>")) {
+		status := resp.StatusCode
+		if xml.Unmarshal(data, &failure) == nil && failure.Code != "" {
+			if status/100 == 2 || failure.Code == "SlowDown" {
+				status = http.StatusServiceUnavailable
+			}
+			return nil, nil, status, fmt.Errorf("%d %s: %s", resp.StatusCode, failure.Code, failure.Message)
+		}
+		if status/100 != 2 {
+			return nil, nil, status, fmt.Errorf("%s answered %s", method, resp.Status)
+		}
+	}
+	return resp.Header, data, resp.StatusCode, nil
+}
+
+// objectURL addresses key in the bucket: virtual-hosted on AWS, path-style
+// on other endpoints. An empty key is the bucket itself.
+func (c *s3Client) objectURL(key string) string {
+	if c.cfg.Endpoint == "" {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", c.cfg.Bucket, c.cfg.Region, key)
+	}
+	return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(c.cfg.Endpoint, "/"), c.cfg.Bucket, key)
+}
+
+// s3EscapePath escapes everything in path but unreserved characters and
+// the slashes between segments
+func s3EscapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		ch := path[i]
+		if 'A' <= ch && ch <= 'Z' || 'a' <= ch && ch <= 'z' || '0' <= ch && ch <= '9' || strings.IndexByte("-._~/", ch) >= 0 {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// rateLimiter paces uploads to a byte rate shared by every part in flight.
+// A nil limiter does not wait.
+type rateLimiter struct {
+	mu   sync.Mutex
+	rate float64 // bytes per second
+	next time.Time
+}
+
+// wait blocks until n more bytes fit in the rate
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	start := l.next
+	l.next = l.next.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+	l.mu.Unlock()
+
+	if delay := time.Until(start); delay > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/awsauth"
+)
+
+// fakeS3 is an in-memory bucket speaking the S3 object and multipart
+// upload API path-style. The first throttle part uploads answer 503
+// SlowDown.
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	uploads  map[string]map[int][]byte
+	aborted  int
+	throttle int
+	inFlight int
+	peak     int
+	paths    []string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{objects: make(map[string][]byte), uploads: make(map[string]map[int][]byte)}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+		r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>bad signature</Message></Error>", http.StatusForbidden)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	query := r.URL.Query()
+
+	f.mu.Lock()
+	f.paths = append(f.paths, r.URL.EscapedPath())
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodHead:
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		id := fmt.Sprintf("upload-%d", len(f.uploads)+1)
+		f.uploads[id] = make(map[int][]byte)
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		if f.throttle > 0 {
+			f.throttle--
+			http.Error(w, "<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>", http.StatusServiceUnavailable)
+			return
+		}
+		f.inFlight++
+		f.peak = max(f.peak, f.inFlight)
+		f.mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		f.mu.Lock()
+		f.inFlight--
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		f.uploads[query.Get("uploadId")][number] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, number))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		var done struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		if err := xml.Unmarshal(body, &done); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		parts := f.uploads[query.Get("uploadId")]
+		var object []byte
+		for i, p := range done.Parts {
+			if p.PartNumber != i+1 || p.ETag != fmt.Sprintf(`"etag-%d"`, i+1) {
+				fmt.Fprint(w, "<Error><Code>InvalidPart</Code><Message>part out of order</Message></Error>")
+				return
+			}
+			object = append(object, parts[p.PartNumber]...)
+		}
+		f.objects[key] = object
+		delete(f.uploads, query.Get("uploadId"))
+		fmt.Fprint(w, "<CompleteMultipartUploadResult/>")
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		f.aborted++
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func testS3Config(endpoint string) S3Config {
+	return S3Config{
+		Endpoint:        endpoint,
+		Region:          "us-east-1",
+		Bucket:          "bucket",
+		Credentials:     awsauth.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+		PartSize:        1024,
+		Concurrency:     3,
+		ThrottleRetries: 3,
+		ThrottleBackoff: time.Millisecond,
+		Timeout:         5 * time.Second,
+	}
+}
+
+func dialS3(t *testing.T, cfg S3Config) Client {
+	t.Helper()
+	dial, err := NewS3Dialer(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := dial(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestS3Put(t *testing.T) {
+	f, srv := newFakeS3(t)
+	client := dialS3(t, testS3Config(srv.URL))
+	data := bytes.Repeat([]byte("0123456789"), 1000) // ten parts of 1024
+
+	for _, tc := range []struct {
+		name   string
+		key    string
+		data   []byte
+		single bool
+	}{
+		{"empty", "empty.csv", nil, true},
+		{"below part size", "small.csv", data[:1000], true},
+		{"exactly one part", "one.csv", data[:1024], false},
+		{"multipart", "dt=2024-01-01/transactions.csv", data, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			uploads := len(f.paths)
+			if err := client.Put("/"+tc.key, bytes.NewReader(tc.data)); err != nil {
+				t.Fatal(err)
+			}
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			if !bytes.Equal(f.objects[tc.key], tc.data) {
+				t.Errorf("stored %d bytes, want %d", len(f.objects[tc.key]), len(tc.data))
+			}
+			if requests := len(f.paths) - uploads; tc.single != (requests == 1) {
+				t.Errorf("%d requests", requests)
+			}
+		})
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// Parts go out in parallel, no more than Concurrency at a time
+	if f.peak < 2 || f.peak > 3 {
+		t.Errorf("peak parts in flight = %d, want 2 or 3", f.peak)
+	}
+	// '=' is escaped the way S3 signs it
+	if got := f.paths[len(f.paths)-1]; got != "/bucket/dt%3D2024-01-01/transactions.csv" {
+		t.Errorf("path = %s", got)
+	}
+}
+
+func TestS3PutBacksOffWhenThrottled(t *testing.T) {
+	f, srv := newFakeS3(t)
+	f.throttle = 4
+	client := dialS3(t, testS3Config(srv.URL))
+	data := bytes.Repeat([]byte("x"), 3000)
+	if err := client.Put("transactions.csv", bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !bytes.Equal(f.objects["transactions.csv"], data) || f.throttle != 0 {
+		t.Errorf("stored %d bytes, %d throttles left", len(f.objects["transactions.csv"]), f.throttle)
+	}
+}
+
+func TestS3PutAbortsFailedUpload(t *testing.T) {
+	f, srv := newFakeS3(t)
+	f.throttle = 100
+	cfg := testS3Config(srv.URL)
+	cfg.ThrottleRetries = 1
+	client := dialS3(t, cfg)
+
+	err := client.Put("transactions.csv", bytes.NewReader(bytes.Repeat([]byte("x"), 5000)))
+	if err == nil || !strings.Contains(err.Error(), "SlowDown") {
+		t.Fatalf("err = %v, want SlowDown", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.aborted != 1 || len(f.uploads) != 0 || len(f.objects) != 0 {
+		t.Errorf("aborted %d, %d uploads left, %d objects", f.aborted, len(f.uploads), len(f.objects))
+	}
+}
+
+func TestS3RateLimit(t *testing.T) {
+	_, srv := newFakeS3(t)
+	cfg := testS3Config(srv.URL)
+	cfg.RateLimit = 20 * 1024
+	client := dialS3(t, cfg)
+
+	// 10 KiB at 20 KiB/s: the first part goes at once, the rest wait
+	start := time.Now()
+	if err := client.Put("transactions.csv", bytes.NewReader(make([]byte, 10*1024))); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took < 400*time.Millisecond {
+		t.Errorf("upload took %v, want about 450ms", took)
+	}
+}
+
+func TestS3DialChecksBucket(t *testing.T) {
+	_, srv := newFakeS3(t)
+	cfg := testS3Config(srv.URL)
+	cfg.Credentials.AccessKeyID = "OTHER"
+	dial, err := NewS3Dialer(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// HEAD answers carry no error document, only the status
+	if _, err := dial(context.Background()); err == nil || !strings.Contains(err.Error(), "HEAD answered 403 Forbidden") {
+		t.Errorf("err = %v", err)
+	}
+
+	cfg.Credentials = awsauth.Credentials{}
+	if _, err := NewS3Dialer(cfg, nil); err == nil {
+		t.Error("dialer without an access key")
+	}
+}
+
+func TestS3EscapePath(t *testing.T) {
+	for in, want := range map[string]string{
+		"/bucket/a/b.csv":          "/bucket/a/b.csv",
+		"/bucket/dt=2024-01-01/x":  "/bucket/dt%3D2024-01-01/x",
+		"/bucket/run 1/~tmp_x.csv": "/bucket/run%201/~tmp_x.csv",
+		"/bucket/café/data+1":      "/bucket/caf%C3%A9/data%2B1",
+	} {
+		if got := s3EscapePath(in); got != want {
+			t.Errorf("s3EscapePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
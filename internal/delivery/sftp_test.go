@@ -0,0 +1,151 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+)
+
+// fakeSFTPServer answers the requests sftpClient sends from an in-memory
+// file tree
+type fakeSFTPServer struct {
+	r        io.Reader
+	w        io.Writer
+	files    map[string][]byte
+	dirs     map[string]bool
+	extended bool // advertise posix-rename@openssh.com
+}
+
+func (s *fakeSFTPServer) serve() {
+	handles := make(map[string]string)
+	for {
+		var length [4]byte
+		if _, err := io.ReadFull(s.r, length[:]); err != nil {
+			return
+		}
+		packet := make([]byte, binary.BigEndian.Uint32(length[:]))
+		if _, err := io.ReadFull(s.r, packet); err != nil {
+			return
+		}
+		typ, body := packet[0], packet[1:]
+		if typ == sshFxpInit {
+			reply := binary.BigEndian.AppendUint32([]byte{sshFxpVersion}, 3)
+			if s.extended {
+				reply = appendString(appendString(reply, posixRename), "1")
+			}
+			s.send(reply)
+			continue
+		}
+
+		id, body := body[:4], body[4:]
+		status := func(code uint32) {
+			reply := append([]byte{sshFxpStatus}, id...)
+			reply = binary.BigEndian.AppendUint32(reply, code)
+			s.send(appendString(appendString(reply, ""), ""))
+		}
+		switch typ {
+		case sshFxpMkdir:
+			dir, _, _ := readString(body)
+			if s.dirs[dir] {
+				status(4)
+			} else {
+				s.dirs[dir] = true
+				status(0)
+			}
+		case sshFxpOpen:
+			name, _, _ := readString(body)
+			s.files[name] = nil
+			handles["h"] = name
+			s.send(appendString(append([]byte{sshFxpHandle}, id...), "h"))
+		case sshFxpWrite:
+			handle, rest, _ := readString(body)
+			offset := binary.BigEndian.Uint64(rest)
+			data, _, _ := readString(rest[8:])
+			name := handles[handle]
+			s.files[name] = append(s.files[name][:offset], data...)
+			status(0)
+		case sshFxpClose:
+			status(0)
+		case sshFxpRemove:
+			name, _, _ := readString(body)
+			delete(s.files, name)
+			status(0)
+		case sshFxpRename, sshFxpExtended:
+			if typ == sshFxpExtended {
+				_, body, _ = readString(body)
+			}
+			from, rest, _ := readString(body)
+			to, _, _ := readString(rest)
+			if _, exists := s.files[to]; exists && typ == sshFxpRename {
+				status(4) // SSH_FXP_RENAME never overwrites
+				continue
+			}
+			s.files[to] = s.files[from]
+			delete(s.files, from)
+			status(0)
+		default:
+			status(8) // SSH_FX_OP_UNSUPPORTED
+		}
+	}
+}
+
+func (s *fakeSFTPServer) send(packet []byte) {
+	s.w.Write(binary.BigEndian.AppendUint32(nil, uint32(len(packet))))
+	s.w.Write(packet)
+}
+
+func newFakeSFTP(t *testing.T, extended bool) (*sftpClient, *fakeSFTPServer) {
+	t.Helper()
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	server := &fakeSFTPServer{r: serverR, w: serverW, files: make(map[string][]byte), dirs: make(map[string]bool), extended: extended}
+	go server.serve()
+	t.Cleanup(func() { clientW.Close(); serverW.Close() })
+
+	c, err := newSFTPClient(clientW, clientR, func() error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, server
+}
+
+func TestSFTPPutAndRename(t *testing.T) {
+	for _, extended := range []bool{false, true} {
+		c, server := newFakeSFTP(t, extended)
+		server.files["/in/batch.csv"] = []byte("old")
+		server.dirs["/in"] = true
+
+		// Larger than one write request
+		data := bytes.Repeat([]byte("0123456789"), 5000)
+		if err := c.Put("/in/2024/batch.csv.part", bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+		if !server.dirs["/in/2024"] {
+			t.Error("parent directory not created")
+		}
+		if err := c.Put("/in/batch.csv.part", strings.NewReader("new")); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Rename("/in/batch.csv.part", "/in/batch.csv"); err != nil {
+			t.Fatalf("extended=%v: %v", extended, err)
+		}
+
+		if !bytes.Equal(server.files["/in/2024/batch.csv.part"], data) {
+			t.Errorf("uploaded %d bytes, want %d", len(server.files["/in/2024/batch.csv.part"]), len(data))
+		}
+		if got := string(server.files["/in/batch.csv"]); got != "new" {
+			t.Errorf("extended=%v: renamed file = %q", extended, got)
+		}
+		if _, ok := server.files["/in/batch.csv.part"]; ok {
+			t.Error("temporary file left behind")
+		}
+	}
+}
+
+func TestSFTPHostKeyRequired(t *testing.T) {
+	if _, err := NewSFTPDialer(SFTPConfig{Addr: "localhost:22"}); err == nil {
+		t.Fatal("dialer without host key verification accepted")
+	}
+}
@@ -0,0 +1,63 @@
+package encrypt
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// Headers identifying an encrypted message payload
+const (
+	HeaderAlgorithm = "enc-alg"
+	HeaderKeyID     = "enc-key-id"
+	AlgorithmAESGCM = "AES-256-GCM"
+)
+
+// Envelope encrypts individual message payloads with AES-256-GCM. A sealed
+// payload is nonce (12 bytes) | ciphertext | tag (16 bytes), authenticated
+// with the key ID as additional data so a payload cannot be replayed under
+// another key's header.
+type Envelope struct {
+	keyID string
+	aead  cipher.AEAD
+}
+
+// NewEnvelope creates an envelope for the 32-byte key named keyID
+func NewEnvelope(keyID string, key []byte) (*Envelope, error) {
+	if keyID == "" {
+		return nil, errors.New("envelope encryption needs a key ID")
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{keyID: keyID, aead: aead}, nil
+}
+
+// KeyID returns the ID carried in the message header
+func (e *Envelope) KeyID() string {
+	return e.keyID
+}
+
+// Seal encrypts plaintext into a new payload
+func (e *Envelope) Seal(plaintext []byte) ([]byte, error) {
+	out := make([]byte, e.aead.NonceSize(), e.aead.NonceSize()+len(plaintext)+e.aead.Overhead())
+	if _, err := rand.Read(out); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return e.aead.Seal(out, out, plaintext, []byte(e.keyID)), nil
+}
+
+// Open decrypts a payload sealed under this envelope's key
+func (e *Envelope) Open(payload []byte) ([]byte, error) {
+	n := e.aead.NonceSize()
+	if len(payload) < n+e.aead.Overhead() {
+		return nil, errors.New("encrypted payload too short")
+	}
+	plaintext, err := e.aead.Open(nil, payload[:n], payload[n:], []byte(e.keyID))
+	if err != nil {
+		return nil, errors.New("failed to decrypt payload: corrupt or wrong key")
+	}
+	return plaintext, nil
+}
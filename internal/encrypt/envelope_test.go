@@ -0,0 +1,89 @@
+package encrypt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	e, err := NewEnvelope("key-2024", testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plaintext := range [][]byte{nil, []byte(`{"id":"t1","amount":9.99}`), bytes.Repeat([]byte("x"), 1<<20)} {
+		sealed, err := e.Seal(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sealed) != len(plaintext)+12+16 {
+			t.Errorf("sealed %d bytes for %d", len(sealed), len(plaintext))
+		}
+		got, err := e.Open(sealed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("opened %d bytes, want %d", len(got), len(plaintext))
+		}
+	}
+
+	// Fresh nonces: the same payload never seals the same way twice
+	a, _ := e.Seal([]byte("same"))
+	b, _ := e.Seal([]byte("same"))
+	if bytes.Equal(a, b) {
+		t.Error("two seals of the same payload are identical")
+	}
+}
+
+func TestEnvelopeRejectsDamage(t *testing.T) {
+	e, err := NewEnvelope("key-2024", testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := e.Seal([]byte(`{"id":"t1","amount":9.99}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	flip := func(i int) []byte {
+		damaged := bytes.Clone(sealed)
+		damaged[i] ^= 1
+		return damaged
+	}
+	wrongKey, _ := NewEnvelope("key-2024", testKey(2))
+	// Same key under another ID: the ID is authenticated, so a payload
+	// cannot be relabelled with another key's header
+	wrongID, _ := NewEnvelope("key-2025", testKey(1))
+
+	for _, tc := range []struct {
+		name    string
+		opener  *Envelope
+		payload []byte
+		want    string
+	}{
+		{"truncated tag", e, sealed[:len(sealed)-1], "corrupt or wrong key"},
+		{"too short", e, sealed[:12+15], "too short"},
+		{"empty", e, nil, "too short"},
+		{"tampered nonce", e, flip(0), "corrupt or wrong key"},
+		{"tampered ciphertext", e, flip(14), "corrupt or wrong key"},
+		{"tampered tag", e, flip(len(sealed) - 1), "corrupt or wrong key"},
+		{"wrong key", wrongKey, sealed, "corrupt or wrong key"},
+		{"wrong key ID", wrongID, sealed, "corrupt or wrong key"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.opener.Open(tc.payload)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewEnvelopeErrors(t *testing.T) {
+	if _, err := NewEnvelope("", testKey(1)); err == nil {
+		t.Error("empty key ID accepted")
+	}
+	if _, err := NewEnvelope("key", testKey(1)[:16]); err == nil {
+		t.Error("16-byte key accepted")
+	}
+}
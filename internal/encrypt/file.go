@@ -0,0 +1,160 @@
+// Package encrypt seals finished output files at rest, either to age
+// recipients or with a shared AES-256-GCM key
+package encrypt
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// Encryption modes
+const (
+	ModeAge    = "age"
+	ModeAESGCM = "aes-gcm"
+)
+
+// FileEncryptor replaces finished files with encrypted copies
+type FileEncryptor struct {
+	mode          string
+	recipients    []age.Recipient
+	key           []byte
+	keepPlaintext bool
+}
+
+// NewFileEncryptor creates an encryptor. age mode takes age1... recipients;
+// aes-gcm mode takes a 32-byte key encoded as hex or base64.
+func NewFileEncryptor(mode string, recipients []string, key string, keepPlaintext bool) (*FileEncryptor, error) {
+	e := &FileEncryptor{mode: mode, keepPlaintext: keepPlaintext}
+	switch mode {
+	case ModeAge:
+		if len(recipients) == 0 {
+			return nil, fmt.Errorf("age encryption needs at least one recipient")
+		}
+		parsed, err := age.ParseRecipients(strings.NewReader(strings.Join(recipients, "\n")))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse age recipients: %w", err)
+		}
+		e.recipients = parsed
+	case ModeAESGCM:
+		k, err := ParseKey(key)
+		if err != nil {
+			return nil, err
+		}
+		e.key = k
+	default:
+		return nil, fmt.Errorf("unsupported encryption mode: %s", mode)
+	}
+	return e, nil
+}
+
+// ParseKey decodes a 32-byte AES key from hex or base64
+func ParseKey(key string) ([]byte, error) {
+	key = strings.TrimSpace(key)
+	if k, err := hex.DecodeString(key); err == nil && len(k) == 32 {
+		return k, nil
+	}
+	if k, err := base64.StdEncoding.DecodeString(key); err == nil && len(k) == 32 {
+		return k, nil
+	}
+	return nil, fmt.Errorf("encryption key must be 32 bytes, hex or base64 encoded")
+}
+
+// Extension is appended to encrypted file names
+func (e *FileEncryptor) Extension() string {
+	if e.mode == ModeAge {
+		return ".age"
+	}
+	return ".enc"
+}
+
+// EncryptFile writes path+Extension() and removes the plaintext unless
+// configured to keep it. It returns the encrypted file's path.
+func (e *FileEncryptor) EncryptFile(path string) (string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file for encryption: %w", err)
+	}
+	defer in.Close()
+
+	outPath := path + e.Extension()
+	out, err := os.OpenFile(outPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create encrypted file: %w", err)
+	}
+
+	if err := e.encrypt(out, in); err != nil {
+		out.Close()
+		os.Remove(outPath)
+		return "", fmt.Errorf("failed to encrypt %s: %w", path, err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(outPath)
+		return "", fmt.Errorf("failed to close encrypted file: %w", err)
+	}
+
+	if !e.keepPlaintext {
+		if err := os.Remove(path); err != nil {
+			return outPath, fmt.Errorf("failed to remove plaintext file: %w", err)
+		}
+	}
+	return outPath, nil
+}
+
+func (e *FileEncryptor) encrypt(dst io.Writer, src io.Reader) error {
+	bw := bufio.NewWriterSize(dst, 64*1024)
+	var w io.WriteCloser
+	var err error
+	if e.mode == ModeAge {
+		w, err = age.Encrypt(bw, e.recipients...)
+	} else {
+		w, err = NewGCMWriter(bw, e.key)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// DecryptAge writes the plaintext of an age file to dst using the identities
+// (AGE-SECRET-KEY-1...) in identityFile
+func DecryptAge(dst io.Writer, src io.Reader, identityFile string) error {
+	f, err := os.Open(identityFile)
+	if err != nil {
+		return fmt.Errorf("failed to open age identity file: %w", err)
+	}
+	defer f.Close()
+
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return fmt.Errorf("failed to parse age identities: %w", err)
+	}
+	r, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt age file: %w", err)
+	}
+	_, err = io.Copy(dst, r)
+	return err
+}
+
+// DecryptGCM writes the plaintext of an AES-GCM file to dst
+func DecryptGCM(dst io.Writer, src io.Reader, key []byte) error {
+	r, err := NewGCMReader(src, key)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, r)
+	return err
+}
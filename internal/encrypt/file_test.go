@@ -0,0 +1,175 @@
+package encrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// writeFile writes data to name in a temporary directory and returns its path
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// ageIdentity generates an X25519 identity and writes it to an identity file
+func ageIdentity(t *testing.T) (*age.X25519Identity, string) {
+	t.Helper()
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id, writeFile(t, "key.txt", []byte(id.String()+"\n"))
+}
+
+func TestParseKey(t *testing.T) {
+	key := testKey(7)
+	for _, tc := range []struct {
+		name  string
+		input string
+		ok    bool
+	}{
+		{"hex", hex.EncodeToString(key), true},
+		{"base64", base64.StdEncoding.EncodeToString(key), true},
+		{"surrounding whitespace", " " + hex.EncodeToString(key) + "\n", true},
+		{"short hex", hex.EncodeToString(key[:16]), false},
+		{"short base64", base64.StdEncoding.EncodeToString(key[:31]), false},
+		{"neither", "not a key", false},
+		{"empty", "", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseKey(tc.input)
+			if tc.ok && (err != nil || !bytes.Equal(got, key)) {
+				t.Errorf("ParseKey = %x, %v", got, err)
+			}
+			if !tc.ok && err == nil {
+				t.Errorf("ParseKey accepted %q", tc.input)
+			}
+		})
+	}
+}
+
+func TestEncryptFileRoundTrip(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("1,USD,9.99\n"), 20000)
+	id, identityFile := ageIdentity(t)
+	key := hex.EncodeToString(testKey(3))
+
+	for _, tc := range []struct {
+		name       string
+		mode       string
+		recipients []string
+		ext        string
+		decrypt    func(dst *bytes.Buffer, src *os.File) error
+	}{
+		{"age", ModeAge, []string{id.Recipient().String()}, ".age", func(dst *bytes.Buffer, src *os.File) error {
+			return DecryptAge(dst, src, identityFile)
+		}},
+		{"aes-gcm", ModeAESGCM, nil, ".enc", func(dst *bytes.Buffer, src *os.File) error {
+			return DecryptGCM(dst, src, testKey(3))
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, keep := range []bool{false, true} {
+				path := writeFile(t, "transactions.csv", plaintext)
+				e, err := NewFileEncryptor(tc.mode, tc.recipients, key, keep)
+				if err != nil {
+					t.Fatal(err)
+				}
+				out, err := e.EncryptFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if out != path+tc.ext {
+					t.Errorf("encrypted to %s, want %s", out, path+tc.ext)
+				}
+				if _, err := os.Stat(path); (err == nil) != keep {
+					t.Errorf("keep plaintext %v: plaintext stat err = %v", keep, err)
+				}
+
+				f, err := os.Open(out)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got bytes.Buffer
+				err = tc.decrypt(&got, f)
+				f.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got.Bytes(), plaintext) {
+					t.Errorf("decrypted %d bytes, want %d", got.Len(), len(plaintext))
+				}
+			}
+		})
+	}
+}
+
+func TestDecryptAgeRejectsDamage(t *testing.T) {
+	id, identityFile := ageIdentity(t)
+	_, otherIdentity := ageIdentity(t)
+	e, err := NewFileEncryptor(ModeAge, []string{id.Recipient().String()}, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// age seals 64 KiB chunks too; this spans three
+	out, err := e.EncryptFile(writeFile(t, "transactions.csv", bytes.Repeat([]byte("x"), 150*1024)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-100] ^= 1
+
+	for _, tc := range []struct {
+		name     string
+		sealed   []byte
+		identity string
+	}{
+		{"final chunk cut short", sealed[:len(sealed)-1], identityFile},
+		{"final chunk dropped", sealed[:len(sealed)-(150*1024-128*1024)-16], identityFile},
+		{"tampered chunk", tampered, identityFile},
+		{"wrong identity", sealed, otherIdentity},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := DecryptAge(&got, bytes.NewReader(tc.sealed), tc.identity); err == nil {
+				t.Errorf("decrypted %d bytes without an error", got.Len())
+			}
+		})
+	}
+}
+
+func TestNewFileEncryptorErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		mode       string
+		recipients []string
+		key        string
+		want       string
+	}{
+		{"age without recipients", ModeAge, nil, "", "at least one recipient"},
+		{"bad recipient", ModeAge, []string{"age1notarecipient"}, "", "failed to parse age recipients"},
+		{"bad key", ModeAESGCM, nil, "abcd", "must be 32 bytes"},
+		{"unknown mode", "rot13", nil, "", "unsupported encryption mode"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewFileEncryptor(tc.mode, tc.recipients, tc.key, false)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
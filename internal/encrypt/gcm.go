@@ -0,0 +1,209 @@
+package encrypt
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The AES-GCM file format is a header followed by independently sealed
+// chunks, so files of any size stream in constant memory:
+//
+//	header: "MPAESGCM" | version (1 byte) | chunk size (4 bytes BE) | nonce prefix (7 bytes)
+//	chunk:  AES-256-GCM(plaintext) with nonce = prefix | counter (4 bytes BE) | last flag (1 byte)
+//
+// Every chunk authenticates the header as additional data and the final
+// chunk carries the last flag, which detects reordering and truncation.
+const (
+	gcmMagic      = "MPAESGCM"
+	gcmVersion    = 1
+	gcmChunkSize  = 64 * 1024
+	gcmPrefixSize = 7
+	gcmHeaderSize = len(gcmMagic) + 1 + 4 + gcmPrefixSize
+)
+
+// GCMWriter encrypts a stream into the chunked AES-GCM format
+type GCMWriter struct {
+	dst     io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	out     []byte
+	started bool
+}
+
+// NewGCMWriter returns a writer encrypting to dst with a 32-byte key. Close
+// must be called to write the final chunk.
+func NewGCMWriter(dst io.Writer, key []byte) (*GCMWriter, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, gcmHeaderSize)
+	copy(header, gcmMagic)
+	header[len(gcmMagic)] = gcmVersion
+	binary.BigEndian.PutUint32(header[len(gcmMagic)+1:], gcmChunkSize)
+	prefix := header[len(gcmMagic)+5:]
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return &GCMWriter{
+		dst:    dst,
+		aead:   aead,
+		header: header,
+		prefix: prefix,
+		buf:    make([]byte, 0, gcmChunkSize),
+		out:    make([]byte, 0, gcmChunkSize+aead.Overhead()),
+	}, nil
+}
+
+// Write buffers p, sealing each full chunk once more data follows it
+func (w *GCMWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		if len(w.buf) == gcmChunkSize {
+			if err := w.seal(false); err != nil {
+				return n, err
+			}
+		}
+		c := copy(w.buf[len(w.buf):gcmChunkSize], p)
+		w.buf = w.buf[:len(w.buf)+c]
+		p = p[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// Close seals the buffered data as the final chunk
+func (w *GCMWriter) Close() error {
+	return w.seal(true)
+}
+
+func (w *GCMWriter) seal(last bool) error {
+	if !w.started {
+		if _, err := w.dst.Write(w.header); err != nil {
+			return err
+		}
+		w.started = true
+	}
+	if w.counter == ^uint32(0) {
+		return errors.New("encrypted stream too long")
+	}
+
+	w.out = w.aead.Seal(w.out[:0], chunkNonce(w.prefix, w.counter, last), w.buf, w.header)
+	w.counter++
+	w.buf = w.buf[:0]
+	_, err := w.dst.Write(w.out)
+	return err
+}
+
+// GCMReader decrypts the chunked AES-GCM format
+type GCMReader struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	header  []byte
+	prefix  []byte
+	counter uint32
+	chunk   []byte
+	plain   []byte
+	done    bool
+}
+
+// NewGCMReader reads and checks the header from src
+func NewGCMReader(src io.Reader, key []byte) (*GCMReader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReaderSize(src, gcmChunkSize+aead.Overhead()+1)
+	header := make([]byte, gcmHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read encryption header: %w", err)
+	}
+	if string(header[:len(gcmMagic)]) != gcmMagic || header[len(gcmMagic)] != gcmVersion {
+		return nil, errors.New("not an AES-GCM encrypted file")
+	}
+	chunkSize := binary.BigEndian.Uint32(header[len(gcmMagic)+1:])
+	if chunkSize == 0 || chunkSize > 16*1024*1024 {
+		return nil, fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+
+	return &GCMReader{
+		src:    r,
+		aead:   aead,
+		header: header,
+		prefix: header[len(gcmMagic)+5:],
+		chunk:  make([]byte, int(chunkSize)+aead.Overhead()),
+	}, nil
+}
+
+// Read returns decrypted data, failing if any chunk was modified or the
+// stream ends before the final chunk
+func (r *GCMReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *GCMReader) open() error {
+	n, err := io.ReadFull(r.src, r.chunk)
+	last := false
+	switch {
+	case err == io.ErrUnexpectedEOF || err == io.EOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, err := r.src.Peek(1); err == io.EOF {
+			last = true
+		}
+	}
+
+	plain, err := r.aead.Open(r.chunk[:0], chunkNonce(r.prefix, r.counter, last), r.chunk[:n], r.header)
+	if err != nil {
+		return errors.New("encrypted file is corrupt, truncated or the key is wrong")
+	}
+	r.counter++
+	r.plain = plain
+	r.done = last
+	return nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("AES-256-GCM needs a 32-byte key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[gcmPrefixSize:], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
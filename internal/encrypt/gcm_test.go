@@ -0,0 +1,156 @@
+package encrypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testKey returns a 32-byte key filled with b
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+// sealGCM encrypts plaintext into the chunked format
+func sealGCM(t *testing.T, key, plaintext []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewGCMWriter(&buf, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func openGCM(sealed, key []byte) ([]byte, error) {
+	var out bytes.Buffer
+	err := DecryptGCM(&out, bytes.NewReader(sealed), key)
+	return out.Bytes(), err
+}
+
+func TestGCMRoundTrip(t *testing.T) {
+	random := make([]byte, 3*gcmChunkSize+123)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name      string
+		plaintext []byte
+		chunks    int
+	}{
+		{"empty", nil, 1},
+		{"short", []byte("id,amount\n1,9.99\n"), 1},
+		{"one full chunk", random[:gcmChunkSize], 1},
+		{"full chunk and a byte", random[:gcmChunkSize+1], 2},
+		{"several chunks", random, 4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sealed := sealGCM(t, testKey(1), tc.plaintext)
+			if want := gcmHeaderSize + len(tc.plaintext) + tc.chunks*16; len(sealed) != want {
+				t.Errorf("sealed %d bytes, want %d", len(sealed), want)
+			}
+			got, err := openGCM(sealed, testKey(1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tc.plaintext) {
+				t.Errorf("decrypted %d bytes, want %d", len(got), len(tc.plaintext))
+			}
+		})
+	}
+}
+
+func TestGCMWriteSizes(t *testing.T) {
+	// Small writes land in the same chunks as one large one
+	plaintext := bytes.Repeat([]byte("0123456789"), gcmChunkSize/5)
+	var buf bytes.Buffer
+	w, err := NewGCMWriter(&buf, testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p := plaintext; len(p) > 0; p = p[min(7, len(p)):] {
+		if _, err := w.Write(p[:min(7, len(p))]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := openGCM(buf.Bytes(), testKey(1))
+	if err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypted %d bytes, %v", len(got), err)
+	}
+}
+
+func TestGCMRejectsDamage(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("x"), 2*gcmChunkSize+100)
+	sealed := sealGCM(t, testKey(1), plaintext)
+	chunk := gcmChunkSize + 16
+	last := gcmHeaderSize + 2*chunk
+
+	flip := func(i int) []byte {
+		damaged := bytes.Clone(sealed)
+		damaged[i] ^= 1
+		return damaged
+	}
+	swapped := bytes.Clone(sealed)
+	copy(swapped[gcmHeaderSize:], sealed[gcmHeaderSize+chunk:last])
+	copy(swapped[gcmHeaderSize+chunk:], sealed[gcmHeaderSize:gcmHeaderSize+chunk])
+
+	for _, tc := range []struct {
+		name   string
+		sealed []byte
+		key    []byte
+		want   string
+	}{
+		{"final chunk dropped", sealed[:last], testKey(1), "corrupt, truncated"},
+		{"final chunk cut short", sealed[:len(sealed)-1], testKey(1), "corrupt, truncated"},
+		{"cut mid-stream", sealed[:gcmHeaderSize+chunk/2], testKey(1), "corrupt, truncated"},
+		{"header only", sealed[:gcmHeaderSize], testKey(1), "corrupt, truncated"},
+		{"tampered chunk", flip(gcmHeaderSize + chunk + 10), testKey(1), "corrupt, truncated"},
+		{"tampered tag", flip(len(sealed) - 1), testKey(1), "corrupt, truncated"},
+		{"tampered nonce prefix", flip(gcmHeaderSize - 1), testKey(1), "corrupt, truncated"},
+		{"chunks reordered", swapped, testKey(1), "corrupt, truncated"},
+		{"wrong key", sealed, testKey(2), "key is wrong"},
+		{"not encrypted", []byte(strings.Repeat("id,amount\n", 10)), testKey(1), "not an AES-GCM encrypted file"},
+		{"short header", sealed[:10], testKey(1), "failed to read encryption header"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := openGCM(tc.sealed, tc.key)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestGCMReaderStopsAtDamage(t *testing.T) {
+	// The chunks before the damaged one still decrypt, so a reader streaming
+	// the file sees good data up to the error
+	sealed := sealGCM(t, testKey(1), bytes.Repeat([]byte("x"), 2*gcmChunkSize+100))
+	r, err := NewGCMReader(bytes.NewReader(sealed[:gcmHeaderSize+2*(gcmChunkSize+16)]), testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := io.Copy(io.Discard, r)
+	if err == nil || n != gcmChunkSize {
+		t.Errorf("read %d bytes, %v; want %d and an error", n, err, gcmChunkSize)
+	}
+}
+
+func TestGCMKeySize(t *testing.T) {
+	if _, err := NewGCMWriter(io.Discard, make([]byte, 16)); err == nil {
+		t.Error("16-byte key accepted")
+	}
+	if _, err := NewGCMReader(bytes.NewReader(nil), make([]byte, 31)); err == nil {
+		t.Error("31-byte key accepted")
+	}
+}
@@ -0,0 +1,160 @@
+// Package events publishes run lifecycle and progress events so
+// orchestrators can follow many producer jobs without scraping logs
+package events
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	TypeStarted   = "started"
+	TypeProgress  = "progress"
+	TypeCompleted = "completed"
+	TypeFailed    = "failed"
+)
+
+// Event is one lifecycle or progress message, published as JSON
+type Event struct {
+	Type      string           `json:"type"`
+	RunID     string           `json:"run_id"`
+	Time      time.Time        `json:"time"`
+	Host      string           `json:"host,omitempty"`
+	Scenario  string           `json:"scenario,omitempty"`
+	Target    int64            `json:"target"`    // configured message count, 0 = continuous
+	Delivered int64            `json:"delivered"` // messages accepted by all sinks
+	Errors    int64            `json:"errors"`    // messages sinks failed to deliver
+	Rate      float64          `json:"rate"`      // messages/sec since the previous event
+	Elapsed   float64          `json:"elapsed_seconds"`
+	Sinks     map[string]int64 `json:"sinks,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// Publisher delivers events to one destination
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+	Close() error
+}
+
+// Emitter stamps events with the run's details and sends them to every
+// publisher. Publish failures are logged and never fail the run.
+type Emitter struct {
+	publishers []Publisher
+	runID      string
+	host       string
+	scenario   string
+	target     int64
+	counts     func() map[string]int64
+	errors     func() int64
+	started    time.Time
+	logger     *slog.Logger
+
+	mu        sync.Mutex
+	lastCount int64
+	lastTime  time.Time
+}
+
+// NewEmitter creates an emitter for one run. counts returns the live
+// per-sink delivery counts and errors the live failed-delivery total.
+func NewEmitter(runID, scenario string, target int64, counts func() map[string]int64, errors func() int64, publishers []Publisher, logger *slog.Logger) *Emitter {
+	host, _ := os.Hostname()
+	now := time.Now()
+	return &Emitter{
+		publishers: publishers,
+		runID:      runID,
+		host:       host,
+		scenario:   scenario,
+		target:     target,
+		counts:     counts,
+		errors:     errors,
+		started:    now,
+		lastTime:   now,
+		logger:     logger,
+	}
+}
+
+// Started publishes the started event
+func (e *Emitter) Started(ctx context.Context) {
+	e.emit(ctx, TypeStarted, nil)
+}
+
+// Run publishes a progress event every interval until ctx is done
+func (e *Emitter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.emit(ctx, TypeProgress, nil)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Finished publishes completed, or failed when err is non-nil
+func (e *Emitter) Finished(ctx context.Context, err error) {
+	if err != nil {
+		e.emit(ctx, TypeFailed, err)
+		return
+	}
+	e.emit(ctx, TypeCompleted, nil)
+}
+
+// Close closes every publisher
+func (e *Emitter) Close() {
+	for _, p := range e.publishers {
+		if err := p.Close(); err != nil {
+			e.logger.Warn("Failed to close event publisher", "error", err)
+		}
+	}
+}
+
+func (e *Emitter) emit(ctx context.Context, eventType string, err error) {
+	event := e.snapshot(eventType)
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	for _, p := range e.publishers {
+		if err := p.Publish(ctx, event); err != nil {
+			e.logger.Warn("Failed to publish event", "type", eventType, "error", err)
+		}
+	}
+}
+
+func (e *Emitter) snapshot(eventType string) Event {
+	now := time.Now()
+	sinks := e.counts()
+	var delivered int64
+	for _, n := range sinks {
+		delivered += n
+	}
+
+	e.mu.Lock()
+	var rate float64
+	if elapsed := now.Sub(e.lastTime).Seconds(); elapsed > 0 {
+		rate = float64(delivered-e.lastCount) / elapsed
+	}
+	e.lastCount, e.lastTime = delivered, now
+	e.mu.Unlock()
+
+	return Event{
+		Type:      eventType,
+		RunID:     e.runID,
+		Time:      now.UTC(),
+		Host:      e.host,
+		Scenario:  e.scenario,
+		Target:    e.target,
+		Delivered: delivered,
+		Errors:    e.errors(),
+		Rate:      rate,
+		Elapsed:   now.Sub(e.started).Seconds(),
+		Sinks:     sinks,
+	}
+}
@@ -0,0 +1,58 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// KafkaPublisher sends events to a control topic, keyed by run ID so each
+// run's events stay ordered on one partition
+type KafkaPublisher struct {
+	producer sarama.SyncProducer
+	topic    string
+}
+
+// NewKafkaPublisher connects a synchronous producer for the control topic
+func NewKafkaPublisher(brokers []string, topic string, auth writer.KafkaAuth, timeout time.Duration) (*KafkaPublisher, error) {
+	config := sarama.NewConfig()
+	config.Producer.Return.Successes = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Timeout = timeout
+	config.Net.DialTimeout = timeout
+	auth.Apply(config)
+
+	producer, err := sarama.NewSyncProducer(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka event producer: %w", err)
+	}
+	return &KafkaPublisher{producer: producer, topic: topic}, nil
+}
+
+// Publish sends the event as JSON with an event-type header
+func (p *KafkaPublisher) Publish(ctx context.Context, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	_, _, err = p.producer.SendMessage(&sarama.ProducerMessage{
+		Topic:   p.topic,
+		Key:     sarama.StringEncoder(e.RunID),
+		Value:   sarama.ByteEncoder(data),
+		Headers: []sarama.RecordHeader{{Key: []byte("event-type"), Value: []byte(e.Type)}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send event to %s: %w", p.topic, err)
+	}
+	return nil
+}
+
+// Close closes the producer
+func (p *KafkaPublisher) Close() error {
+	return p.producer.Close()
+}
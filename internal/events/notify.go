@@ -0,0 +1,144 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notification triggers
+const (
+	NotifyStart          = "start"
+	NotifyFinish         = "finish"
+	NotifyErrorThreshold = "error_threshold"
+)
+
+// Notification formats
+const (
+	FormatSlack = "slack"
+	FormatJSON  = "json"
+)
+
+// Notifier is a Publisher that posts human-readable summaries of selected
+// events to a chat webhook. Progress events are only used to detect the
+// error threshold, which notifies once per run.
+type Notifier struct {
+	client    *http.Client
+	url       string
+	format    string
+	triggers  map[string]bool
+	threshold int64
+
+	mu       sync.Mutex
+	exceeded bool
+}
+
+// NewNotifier creates a notifier posting to url in the slack ({"text": ...})
+// or json (the raw event) format for the given triggers
+func NewNotifier(url, format string, triggers []string, errorThreshold int64, timeout time.Duration) *Notifier {
+	n := &Notifier{
+		client:    &http.Client{Timeout: timeout},
+		url:       url,
+		format:    format,
+		triggers:  make(map[string]bool, len(triggers)),
+		threshold: errorThreshold,
+	}
+	for _, t := range triggers {
+		n.triggers[t] = true
+	}
+	return n
+}
+
+// Publish posts the event if one of the notifier's triggers matches
+func (n *Notifier) Publish(ctx context.Context, e Event) error {
+	if !n.matches(e) {
+		return nil
+	}
+
+	var body []byte
+	var err error
+	if n.format == FormatJSON {
+		body, err = json.Marshal(e)
+	} else {
+		body, err = json.Marshal(map[string]string{"text": summary(e, n.threshold)})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	return post(ctx, n.client, n.url, nil, body)
+}
+
+// Close is a no-op
+func (n *Notifier) Close() error {
+	return nil
+}
+
+func (n *Notifier) matches(e Event) bool {
+	switch e.Type {
+	case TypeStarted:
+		return n.triggers[NotifyStart]
+	case TypeCompleted, TypeFailed:
+		return n.triggers[NotifyFinish]
+	case TypeProgress:
+		if !n.triggers[NotifyErrorThreshold] || n.threshold <= 0 || e.Errors < n.threshold {
+			return false
+		}
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if n.exceeded {
+			return false
+		}
+		n.exceeded = true
+		return true
+	}
+	return false
+}
+
+// summary renders an event as chat text
+func summary(e Event, threshold int64) string {
+	elapsed := time.Duration(e.Elapsed * float64(time.Second)).Round(time.Second)
+	run := fmt.Sprintf("Producer run `%s` on %s", e.RunID, e.Host)
+	if e.Scenario != "" {
+		run += fmt.Sprintf(" (scenario %s)", e.Scenario)
+	}
+
+	var b strings.Builder
+	switch e.Type {
+	case TypeStarted:
+		target := "continuous"
+		if e.Target > 0 {
+			target = fmt.Sprintf("%d messages", e.Target)
+		}
+		fmt.Fprintf(&b, ":arrow_forward: %s started: %s", run, target)
+		return b.String()
+	case TypeProgress:
+		fmt.Fprintf(&b, ":warning: %s reached %d sink errors (threshold %d) after %s, %d messages delivered",
+			run, e.Errors, threshold, elapsed, e.Delivered)
+		return b.String()
+	case TypeFailed:
+		fmt.Fprintf(&b, ":x: %s failed after %s: %s", run, elapsed, e.Error)
+	default:
+		fmt.Fprintf(&b, ":white_check_mark: %s completed in %s", run, elapsed)
+	}
+
+	var throughput float64
+	if e.Elapsed > 0 {
+		throughput = float64(e.Delivered) / e.Elapsed
+	}
+	fmt.Fprintf(&b, "\nDelivered %d messages (%.0f msg/sec), %d errors", e.Delivered, throughput, e.Errors)
+
+	sinks := make([]string, 0, len(e.Sinks))
+	for name := range e.Sinks {
+		sinks = append(sinks, name)
+	}
+	sort.Strings(sinks)
+	for _, name := range sinks {
+		fmt.Fprintf(&b, "\n• %s: %d", name, e.Sinks[name])
+	}
+	return b.String()
+}
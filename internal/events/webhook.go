@@ -0,0 +1,66 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookPublisher POSTs each event as JSON to a URL
+type WebhookPublisher struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+}
+
+// NewWebhookPublisher creates a publisher for url; headers are added to
+// every request, e.g. for an Authorization token
+func NewWebhookPublisher(url string, headers map[string]string, timeout time.Duration) *WebhookPublisher {
+	return &WebhookPublisher{
+		client:  &http.Client{Timeout: timeout},
+		url:     url,
+		headers: headers,
+	}
+}
+
+// Publish posts the event and expects a 2xx response
+func (p *WebhookPublisher) Publish(ctx context.Context, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	return post(ctx, p.client, p.url, p.headers, data)
+}
+
+// Close is a no-op
+func (p *WebhookPublisher) Close() error {
+	return nil
+}
+
+func post(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,172 @@
+package generator
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// minorScale is the number of minor units per whole unit in fixed-point
+// amounts: six decimal places, as every amount is written
+const minorScale = 1_000_000
+
+// amounts holds one transaction's formatted bet, win and win/loss
+type amounts struct {
+	bet, win, winLoss string
+}
+
+// currencyScale adjusts base bets per currency as a multiplier and divisor:
+// crypto gets smaller amounts, some fiat larger
+var currencyScale = map[string][2]int64{
+	"BTC": {1, 10000},
+	"ETH": {1, 1000},
+	"JPY": {100, 1},
+	"CNY": {7, 1},
+}
+
+// SetFixedPoint switches amount math from shopspring/decimal to int64 minor
+// units, formatted only when the transaction is built. Without currency
+// shocks the output is identical; a shocked bet is rounded to the minor unit
+// before the win is derived from it.
+func (p *Producer) SetFixedPoint(fixed bool) {
+	p.fixedPoint = fixed
+}
+
+// generateAmounts draws a transaction's amounts in currency code at now,
+// within the bet range and outcomes of tpl unless it is nil
+func (p *Producer) generateAmounts(rng *rand.Rand, code string, now time.Time, tpl *template) amounts {
+	if p.fixedPoint {
+		return p.fixedAmounts(rng, code, now, tpl)
+	}
+	return p.decimalAmounts(rng, code, now, tpl)
+}
+
+// decimalAmounts computes amounts with arbitrary-precision decimals
+func (p *Producer) decimalAmounts(rng *rand.Rand, code string, now time.Time, tpl *template) amounts {
+	// Generate bet amount based on currency
+	var betAmount decimal.Decimal
+	if tpl != nil && tpl.betCents[1] > 0 {
+		betAmount = decimal.New(tpl.baseBet(rng), -2)
+	} else {
+		betAmount = p.betAmounts[rng.Intn(len(p.betAmounts))]
+	}
+
+	// Adjust for currency (crypto gets smaller amounts, fiat gets larger)
+	if code == "BTC" {
+		betAmount = betAmount.Div(decimal.NewFromFloat(10000))
+	} else if code == "ETH" {
+		betAmount = betAmount.Div(decimal.NewFromFloat(1000))
+	} else if code == "JPY" {
+		betAmount = betAmount.Mul(decimal.NewFromFloat(100))
+	} else if code == "CNY" {
+		betAmount = betAmount.Mul(decimal.NewFromFloat(7))
+	}
+	if p.profile != nil {
+		betAmount = p.profile.shockAmount(rng, code, betAmount, now.Sub(p.profile.start))
+	}
+
+	// Generate win amount (weighted towards losses)
+	winMultiplier := p.winMultiplier(rng, tpl)
+	winAmount := betAmount.Mul(decimal.NewFromFloat(winMultiplier))
+	winLoss := winAmount.Sub(betAmount)
+
+	return amounts{
+		bet:     betAmount.StringFixed(6),
+		win:     winAmount.StringFixed(6),
+		winLoss: winLoss.StringFixed(6),
+	}
+}
+
+// fixedAmounts computes amounts in int64 minor units, drawing from rng in the
+// same order as decimalAmounts so seeded runs pick the same values
+func (p *Producer) fixedAmounts(rng *rand.Rand, code string, now time.Time, tpl *template) amounts {
+	var bet int64
+	if tpl != nil && tpl.betCents[1] > 0 {
+		bet = tpl.baseBet(rng) * (minorScale / 100)
+	} else {
+		bet = p.betMinor[rng.Intn(len(p.betMinor))]
+	}
+	if scale, ok := currencyScale[code]; ok {
+		bet = bet * scale[0] / scale[1]
+	}
+	if p.profile != nil {
+		bet = p.profile.shockMinor(rng, code, bet, now.Sub(p.profile.start))
+	}
+
+	winMultiplier := p.winMultiplier(rng, tpl)
+	win := mulMinor(bet, winMultiplier)
+
+	return amounts{
+		bet:     formatMinor(bet),
+		win:     formatMinor(win),
+		winLoss: formatMinor(win - bet),
+	}
+}
+
+// winMultiplier draws the outcome of a bet from tpl's outcomes, or the usual
+// ones when it has none
+func (p *Producer) winMultiplier(rng *rand.Rand, tpl *template) float64 {
+	if tpl != nil && len(tpl.wins) > 0 {
+		return tpl.wins[rng.Intn(len(tpl.wins))]
+	}
+	return p.winMultipliers[rng.Intn(len(p.winMultipliers))]
+}
+
+// mulMinor multiplies a non-negative amount by a multiplier with at most two
+// decimal places, rounding half away from zero
+func mulMinor(amount int64, multiplier float64) int64 {
+	hundredths := int64(math.Round(multiplier * 100))
+	return (amount*hundredths + 50) / 100
+}
+
+// formatMinor formats minor units with six decimal places, as
+// decimal.StringFixed(6) would
+func formatMinor(v int64) string {
+	var buf [24]byte
+	dst := buf[:0]
+	if v < 0 {
+		dst = append(dst, '-')
+		v = -v
+	}
+	dst = strconv.AppendInt(dst, v/minorScale, 10)
+	frac := v % minorScale
+	dst = append(dst, '.')
+	for scale := int64(minorScale / 10); scale > 0; scale /= 10 {
+		dst = append(dst, byte('0'+frac/scale%10))
+	}
+	return string(dst)
+}
+
+// parseMinor parses an amount formatted by formatMinor or
+// decimal.StringFixed(6) back into minor units
+func parseMinor(s string) int64 {
+	var v int64
+	negative := false
+	scale := int64(-1)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '-' && i == 0:
+			negative = true
+		case c == '.':
+			scale = 0
+		case c >= '0' && c <= '9':
+			if scale >= 6 {
+				continue
+			}
+			v = v*10 + int64(c-'0')
+			if scale >= 0 {
+				scale++
+			}
+		}
+	}
+	for scale = max(scale, 0); scale < 6; scale++ {
+		v *= 10
+	}
+	if negative {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,103 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func testProducer() *Producer {
+	refData := &models.ReferenceData{
+		Currencies: []models.Currency{
+			{ID: 1, Code: "USD"}, {ID: 2, Code: "BTC"}, {ID: 3, Code: "ETH"},
+			{ID: 4, Code: "JPY"}, {ID: 5, Code: "CNY"},
+		},
+		GameCategories:   []models.GameCategory{{ID: 1}},
+		AgentsByMasterID: map[int][]models.Agent{1: {{ID: 10, MasterAgentID: 1}}},
+	}
+	return NewProducer(refData, nil)
+}
+
+func TestFormatMinor(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	values := []int64{0, 1, -1, 999999, -999999, 1000000, -1000000, 123456789012}
+	for i := 0; i < 10000; i++ {
+		values = append(values, rng.Int63n(2e12)-1e12)
+	}
+	for _, v := range values {
+		want := decimal.New(v, -6).StringFixed(6)
+		if got := formatMinor(v); got != want {
+			t.Fatalf("formatMinor(%d) = %s, want %s", v, got, want)
+		}
+	}
+}
+
+func TestFixedPointMatchesDecimal(t *testing.T) {
+	decimalProducer := testProducer()
+	fixedProducer := testProducer()
+	fixedProducer.SetFixedPoint(true)
+	decimalProducer.SetSeed(42)
+	fixedProducer.SetSeed(42)
+
+	decimalRngs, fixedRngs := decimalProducer.newStreams(0), fixedProducer.newStreams(0)
+	for i := 0; i < 20000; i++ {
+		want := decimalProducer.generateTransaction(decimalRngs)
+		got := fixedProducer.generateTransaction(fixedRngs)
+		if got.CurrencyCode != want.CurrencyCode || got.BetAmount != want.BetAmount ||
+			got.WinAmount != want.WinAmount || got.WinLoss != want.WinLoss {
+			t.Fatalf("transaction %d: fixed %s %s/%s/%s, decimal %s %s/%s/%s", i,
+				got.CurrencyCode, got.BetAmount, got.WinAmount, got.WinLoss,
+				want.CurrencyCode, want.BetAmount, want.WinAmount, want.WinLoss)
+		}
+	}
+}
+
+func TestFixedPointShocksWithinOneUnit(t *testing.T) {
+	profile := &Profile{CurrencyShocks: []CurrencyShock{
+		{Currency: "USD", Multiplier: 1.37, Volatility: 0.2},
+		{Currency: "BTC", Multiplier: 0.913},
+	}}
+	decimalProducer := testProducer()
+	fixedProducer := testProducer()
+	fixedProducer.SetFixedPoint(true)
+	for _, p := range []*Producer{decimalProducer, fixedProducer} {
+		p.SetSeed(7)
+		p.SetProfile(profile)
+		p.profile.start = time.Now().Add(-time.Hour)
+	}
+
+	decimalRngs, fixedRngs := decimalProducer.newStreams(0), fixedProducer.newStreams(0)
+	for i := 0; i < 20000; i++ {
+		want := decimalProducer.generateTransaction(decimalRngs)
+		got := fixedProducer.generateTransaction(fixedRngs)
+		for _, pair := range [][2]string{{got.BetAmount, want.BetAmount}, {got.WinAmount, want.WinAmount}, {got.WinLoss, want.WinLoss}} {
+			g, w := decimal.RequireFromString(pair[0]), decimal.RequireFromString(pair[1])
+			// The bet rounds once; the win multiplies that rounding by up to 10
+			if g.Sub(w).Abs().GreaterThan(decimal.New(11, -6)) {
+				t.Fatalf("transaction %d (%s): fixed %s, decimal %s", i, got.CurrencyCode, pair[0], pair[1])
+			}
+		}
+	}
+}
+
+func BenchmarkAmounts(b *testing.B) {
+	for _, fixed := range []bool{false, true} {
+		name := "decimal"
+		if fixed {
+			name = "fixed"
+		}
+		b.Run(name, func(b *testing.B) {
+			p := testProducer()
+			p.SetFixedPoint(fixed)
+			rng := rand.New(rand.NewSource(1))
+			now := time.Now()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				p.generateAmounts(rng, "BTC", now, nil)
+			}
+		})
+	}
+}
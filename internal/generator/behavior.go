@@ -0,0 +1,218 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Scripted player behaviours for responsible-gambling detection
+const (
+	BehaviorEscalatingStakes = "escalating_stakes"
+	BehaviorLossChasing      = "loss_chasing"
+	BehaviorLongSession      = "long_session"
+	BehaviorDepositLimit     = "deposit_limit"
+)
+
+// Behaviors lists the scripted behaviours in weight order
+var Behaviors = []string{BehaviorEscalatingStakes, BehaviorLossChasing, BehaviorLongSession, BehaviorDepositLimit}
+
+// maxActiveSessions bounds how many scripted players bet at once, so their
+// transactions interleave with each other and the background traffic
+const maxActiveSessions = 8
+
+// BehaviorConfig injects scripted player sessions into the stream
+type BehaviorConfig struct {
+	Rate          float64            // fraction of transactions taken by scripted sessions
+	Weights       map[string]float64 // behaviour -> weight, unlisted behaviours weigh 1
+	SessionLength int                // bets per session, long sessions run 5x; 0 = 20
+}
+
+// BehaviorLabel marks one transaction as part of a scripted session. Event
+// names the signal the step shows, if any: stake_increase, chase or
+// limit_hit.
+type BehaviorLabel struct {
+	TransactionID string `json:"transaction_id"`
+	PlayerID      string `json:"player_id"`
+	SessionID     int64  `json:"session_id"`
+	Behavior      string `json:"behavior"`
+	Step          int    `json:"step"`
+	Steps         int    `json:"steps"`
+	Event         string `json:"event,omitempty"`
+}
+
+// behaviorSession is one scripted player's progress
+type behaviorSession struct {
+	id       int64
+	behavior string
+	player   string
+	steps    int
+	step     int
+
+	agent, master int
+	house         int
+	currencyID    int
+	currencyCode  string
+	vendorCode    string
+
+	baseBet  int64 // minor units
+	lastBet  int64
+	lastLost bool
+	staked   int64
+	limit    int64
+}
+
+// behaviorInjector turns a share of transactions into steps of scripted
+// sessions
+type behaviorInjector struct {
+	mu            sync.Mutex
+	rate          float64
+	weights       []float64 // aligned with Behaviors
+	sessionLength int
+	active        []*behaviorSession
+	nextID        int64
+	emit          func(BehaviorLabel)
+}
+
+// SetBehaviors injects scripted player sessions, passing each scripted
+// transaction's label to emit
+func (p *Producer) SetBehaviors(cfg BehaviorConfig, emit func(BehaviorLabel)) error {
+	for name := range cfg.Weights {
+		if !slices.Contains(Behaviors, name) {
+			return fmt.Errorf("unknown player behavior: %s", name)
+		}
+	}
+	length := cfg.SessionLength
+	if length <= 0 {
+		length = 20
+	}
+	b := &behaviorInjector{rate: cfg.Rate, sessionLength: length, emit: emit}
+	for _, name := range Behaviors {
+		b.weights = append(b.weights, weightOf(cfg.Weights, name))
+	}
+	p.behaviors = b
+	return nil
+}
+
+// apply makes txn the next step of a scripted session with probability rate
+func (b *behaviorInjector) apply(rng *rand.Rand, txn *models.Transaction) {
+	if rng.Float64() >= b.rate {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var s *behaviorSession
+	if len(b.active) == 0 || (len(b.active) < maxActiveSessions && rng.Float64() < 1/float64(b.sessionLength)) {
+		s = b.start(rng, txn)
+	} else {
+		s = b.active[rng.Intn(len(b.active))]
+	}
+
+	event, done := s.next(rng, txn)
+	label := BehaviorLabel{
+		TransactionID: txn.ID,
+		PlayerID:      s.player,
+		SessionID:     s.id,
+		Behavior:      s.behavior,
+		Step:          s.step,
+		Steps:         s.steps,
+		Event:         event,
+	}
+	s.step++
+	if done || s.step >= s.steps {
+		b.active = slices.DeleteFunc(b.active, func(a *behaviorSession) bool { return a == s })
+	}
+	if b.emit != nil {
+		b.emit(label)
+	}
+}
+
+// start opens a session for a new player placed like txn
+func (b *behaviorInjector) start(rng *rand.Rand, txn *models.Transaction) *behaviorSession {
+	b.nextID++
+	s := &behaviorSession{
+		id:           b.nextID,
+		behavior:     Behaviors[pickWeighted(rng, b.weights, nil)],
+		player:       fmt.Sprintf("RG-%08d", b.nextID),
+		steps:        b.sessionLength,
+		agent:        txn.AgentID,
+		master:       txn.MasterAgentID,
+		house:        txn.HouseID,
+		currencyID:   txn.CurrencyID,
+		currencyCode: txn.CurrencyCode,
+		vendorCode:   txn.VendorCode,
+		baseBet:      max(parseMinor(txn.BetAmount), 1),
+	}
+	switch s.behavior {
+	case BehaviorLongSession:
+		s.steps *= 5
+	case BehaviorDepositLimit:
+		// Triple stakes hit the limit halfway through the session
+		s.limit = s.baseBet * int64(s.steps) / 2 * 3
+	}
+	b.active = append(b.active, s)
+	return s
+}
+
+// next rewrites txn as the session's next bet, returning the step's signal
+// and whether the session has ended
+func (s *behaviorSession) next(rng *rand.Rand, txn *models.Transaction) (event string, done bool) {
+	bet := s.baseBet
+	multiplier := naturalMultiplier(txn)
+
+	switch s.behavior {
+	case BehaviorEscalatingStakes:
+		bet = int64(math.Round(float64(s.baseBet) * math.Pow(1.25, float64(s.step))))
+		if s.step > 0 {
+			event = "stake_increase"
+		}
+	case BehaviorLossChasing:
+		if s.lastLost {
+			bet = s.lastBet * 2
+			event = "chase"
+		}
+		multiplier = 0
+		if rng.Float64() < 0.3 {
+			multiplier = 2
+		}
+	case BehaviorDepositLimit:
+		bet = s.baseBet * 3
+		if s.staked+bet >= s.limit {
+			bet = s.limit - s.staked
+			event = "limit_hit"
+			done = true
+		}
+		s.staked += bet
+	}
+
+	win := int64(math.Round(float64(bet) * multiplier))
+	s.lastBet = bet
+	s.lastLost = win < bet
+
+	txn.AgentID = s.agent
+	txn.MasterAgentID = s.master
+	txn.HouseID = s.house
+	txn.CurrencyID = s.currencyID
+	txn.CurrencyCode = s.currencyCode
+	txn.VendorCode = s.vendorCode
+	txn.BetAmount = formatMinor(bet)
+	txn.WinAmount = formatMinor(win)
+	txn.WinLoss = formatMinor(win - bet)
+	txn.PlayerID = s.player
+	txn.Behavior = s.behavior
+	return event, done
+}
+
+// naturalMultiplier is the win multiplier the generator drew for txn
+func naturalMultiplier(txn *models.Transaction) float64 {
+	bet := parseMinor(txn.BetAmount)
+	if bet == 0 {
+		return 0
+	}
+	return float64(parseMinor(txn.WinAmount)) / float64(bet)
+}
@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"testing"
+)
+
+func TestBehaviorSessions(t *testing.T) {
+	p := testProducer()
+	p.SetSeed(5)
+	var labels []BehaviorLabel
+	if err := p.SetBehaviors(BehaviorConfig{Rate: 0.2, SessionLength: 10}, func(l BehaviorLabel) {
+		labels = append(labels, l)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	rngs := p.newStreams(0)
+	byID := map[string]string{} // transaction ID -> player ID
+	type player struct {
+		agent    int
+		currency string
+		steps    []BehaviorLabel
+		bets     []int64
+		lost     []bool
+	}
+	players := map[string]*player{}
+	total := 20000
+	for i := 0; i < total; i++ {
+		txn := p.generateTransaction(rngs)
+		if txn.PlayerID == "" {
+			continue
+		}
+		byID[txn.ID] = txn.PlayerID
+		pl := players[txn.PlayerID]
+		if pl == nil {
+			pl = &player{agent: txn.AgentID, currency: txn.CurrencyCode}
+			players[txn.PlayerID] = pl
+		}
+		if txn.AgentID != pl.agent || txn.CurrencyCode != pl.currency {
+			t.Fatalf("player %s moved from agent %d %s to %d %s", txn.PlayerID, pl.agent, pl.currency, txn.AgentID, txn.CurrencyCode)
+		}
+		if parseMinor(txn.WinAmount)-parseMinor(txn.BetAmount) != parseMinor(txn.WinLoss) {
+			t.Fatalf("inconsistent amounts %s/%s/%s", txn.BetAmount, txn.WinAmount, txn.WinLoss)
+		}
+		pl.bets = append(pl.bets, parseMinor(txn.BetAmount))
+		pl.lost = append(pl.lost, parseMinor(txn.WinLoss) < 0)
+	}
+
+	if len(labels) != len(byID) {
+		t.Fatalf("%d labels for %d scripted transactions", len(labels), len(byID))
+	}
+	if share := float64(len(labels)) / float64(total); share < 0.17 || share > 0.23 {
+		t.Errorf("scripted share %.3f, want about 0.2", share)
+	}
+	seen := map[string]bool{}
+	for _, l := range labels {
+		if byID[l.TransactionID] != l.PlayerID {
+			t.Fatalf("label %+v does not match its transaction", l)
+		}
+		seen[l.Behavior] = true
+		players[l.PlayerID].steps = append(players[l.PlayerID].steps, l)
+	}
+	for _, b := range Behaviors {
+		if !seen[b] {
+			t.Errorf("no %s sessions", b)
+		}
+	}
+
+	for id, pl := range players {
+		for i, l := range pl.steps {
+			if l.Step != i {
+				t.Fatalf("player %s step %d labelled %d", id, i, l.Step)
+			}
+			switch l.Behavior {
+			case BehaviorEscalatingStakes:
+				if i > 0 && pl.bets[i] <= pl.bets[i-1] {
+					t.Fatalf("player %s stakes did not escalate: %v", id, pl.bets)
+				}
+			case BehaviorLossChasing:
+				if i > 0 && pl.lost[i-1] && (pl.bets[i] != 2*pl.bets[i-1] || l.Event != "chase") {
+					t.Fatalf("player %s did not chase a loss: %v", id, pl.bets)
+				}
+			case BehaviorDepositLimit:
+				if l.Event == "limit_hit" && i != len(pl.steps)-1 {
+					t.Fatalf("player %s kept betting after the limit", id)
+				}
+			}
+		}
+	}
+}
+
+func TestSetBehaviorsRejectsUnknown(t *testing.T) {
+	p := testProducer()
+	if err := p.SetBehaviors(BehaviorConfig{Weights: map[string]float64{"martingale": 1}}, nil); err == nil {
+		t.Error("unknown behavior accepted")
+	}
+}
@@ -0,0 +1,201 @@
+package generator
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Hierarchy levels of commission events
+const (
+	LevelMasterAgent = "master_agent"
+	LevelAgent       = "agent"
+	LevelSubAgent    = "sub_agent"
+)
+
+// maxSubAgents bounds sub-agents per agent so their IDs stay agent ID * 100 + n
+const maxSubAgents = 99
+
+// CommissionRates is the share of GGR each level earns on everything below it
+type CommissionRates struct {
+	MasterAgent float64
+	Agent       float64
+	SubAgent    float64
+}
+
+// CommissionEvent is one hierarchy node's settlement for a period in one
+// currency. Amounts have six decimal places; GGR is the house's win (bets
+// minus wins), so a negative commission is owed back.
+type CommissionEvent struct {
+	PeriodStart    string  `json:"period_start"`
+	PeriodEnd      string  `json:"period_end"`
+	Level          string  `json:"level"`
+	ID             int     `json:"id"`
+	ParentID       int     `json:"parent_id,omitempty"`
+	MasterAgentID  int     `json:"master_agent_id"`
+	CurrencyCode   string  `json:"currency_code"`
+	Transactions   int64   `json:"transactions"`
+	Turnover       string  `json:"turnover"`
+	GGR            string  `json:"ggr"`
+	CommissionRate float64 `json:"commission_rate"`
+	Commission     string  `json:"commission"`
+}
+
+// commissionKey identifies one node's totals in one currency
+type commissionKey struct {
+	level    string
+	id       int
+	currency string
+}
+
+// commissionTotals accumulates a node's period in minor units
+type commissionTotals struct {
+	parent       int
+	master       int
+	transactions int64
+	turnover     int64
+	ggr          int64
+}
+
+// Commissions extends each agent with sub-agents, attributes transactions to
+// them and aggregates turnover and GGR up the tree, emitting a settlement
+// event per active node and currency at the end of every period
+type Commissions struct {
+	mu          sync.Mutex
+	subAgents   int
+	rates       CommissionRates
+	periodStart time.Time
+	totals      map[commissionKey]*commissionTotals
+	emit        func(CommissionEvent)
+}
+
+// NewCommissions gives every agent subAgents sub-agents (1-99) with IDs
+// agent ID * 100 + 1..subAgents, starting the first period at start
+func NewCommissions(subAgents int, rates CommissionRates, start time.Time, emit func(CommissionEvent)) *Commissions {
+	return &Commissions{
+		subAgents:   min(max(subAgents, 1), maxSubAgents),
+		rates:       rates,
+		periodStart: start,
+		totals:      make(map[commissionKey]*commissionTotals),
+		emit:        emit,
+	}
+}
+
+// SetCommissions attributes every transaction to a sub-agent of its agent and
+// feeds it to c. Nil stops attributing.
+func (p *Producer) SetCommissions(c *Commissions) {
+	p.commissions = c
+}
+
+// SubAgentID returns the ID of an agent's nth sub-agent, counting from 0
+func SubAgentID(agentID, n int) int {
+	return agentID*100 + n + 1
+}
+
+// attribute picks txn's sub-agent and adds the transaction to the totals of
+// it and its ancestors
+func (c *Commissions) attribute(rng *rand.Rand, txn *models.Transaction) {
+	txn.SubAgentID = SubAgentID(txn.AgentID, rng.Intn(c.subAgents))
+	bet := parseMinor(txn.BetAmount)
+	ggr := -parseMinor(txn.WinLoss)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, node := range [...]struct {
+		level  string
+		id     int
+		parent int
+	}{
+		{LevelSubAgent, txn.SubAgentID, txn.AgentID},
+		{LevelAgent, txn.AgentID, txn.MasterAgentID},
+		{LevelMasterAgent, txn.MasterAgentID, 0},
+	} {
+		key := commissionKey{level: node.level, id: node.id, currency: txn.CurrencyCode}
+		t := c.totals[key]
+		if t == nil {
+			t = &commissionTotals{parent: node.parent, master: txn.MasterAgentID}
+			c.totals[key] = t
+		}
+		t.transactions++
+		t.turnover += bet
+		t.ggr += ggr
+	}
+}
+
+// Flush ends the current period at now, emitting sub-agents, then agents,
+// then master agents, each ordered by ID and currency
+func (c *Commissions) Flush(now time.Time) {
+	c.mu.Lock()
+	totals := c.totals
+	start := c.periodStart
+	c.totals = make(map[commissionKey]*commissionTotals)
+	c.periodStart = now
+	c.mu.Unlock()
+
+	if len(totals) == 0 || c.emit == nil {
+		return
+	}
+	levelOrder := map[string]int{LevelSubAgent: 0, LevelAgent: 1, LevelMasterAgent: 2}
+	keys := make([]commissionKey, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b commissionKey) int {
+		return cmp.Or(
+			cmp.Compare(levelOrder[a.level], levelOrder[b.level]),
+			cmp.Compare(a.id, b.id),
+			cmp.Compare(a.currency, b.currency),
+		)
+	})
+
+	for _, key := range keys {
+		t := totals[key]
+		rate := c.rate(key.level)
+		c.emit(CommissionEvent{
+			PeriodStart:    start.UTC().Format(time.RFC3339),
+			PeriodEnd:      now.UTC().Format(time.RFC3339),
+			Level:          key.level,
+			ID:             key.id,
+			ParentID:       t.parent,
+			MasterAgentID:  t.master,
+			CurrencyCode:   key.currency,
+			Transactions:   t.transactions,
+			Turnover:       formatMinor(t.turnover),
+			GGR:            formatMinor(t.ggr),
+			CommissionRate: rate,
+			Commission:     formatMinor(int64(math.Round(float64(t.ggr) * rate))),
+		})
+	}
+}
+
+// Run flushes a period every interval until ctx is cancelled. Flush once more
+// after generation ends to settle the final partial period.
+func (c *Commissions) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.Flush(now)
+		}
+	}
+}
+
+func (c *Commissions) rate(level string) float64 {
+	switch level {
+	case LevelMasterAgent:
+		return c.rates.MasterAgent
+	case LevelAgent:
+		return c.rates.Agent
+	default:
+		return c.rates.SubAgent
+	}
+}
@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func TestParseMinor(t *testing.T) {
+	for _, s := range []string{"0.000000", "-0.500000", "12.345678", "-1000.000000", "7", "0.1", "3.1234567"} {
+		want := decimal.RequireFromString(s).Shift(6).Truncate(0).IntPart()
+		if got := parseMinor(s); got != want {
+			t.Errorf("parseMinor(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
+
+func TestCommissionsRollUp(t *testing.T) {
+	var events []CommissionEvent
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewCommissions(3, CommissionRates{MasterAgent: 0.05, Agent: 0.1, SubAgent: 0.2}, start, func(e CommissionEvent) {
+		events = append(events, e)
+	})
+
+	rng := rand.New(rand.NewSource(1))
+	txns := []*models.Transaction{
+		{AgentID: 1, MasterAgentID: 1, CurrencyCode: "USD", BetAmount: "100.000000", WinLoss: "-100.000000"},
+		{AgentID: 1, MasterAgentID: 1, CurrencyCode: "USD", BetAmount: "50.000000", WinLoss: "25.000000"},
+		{AgentID: 2, MasterAgentID: 1, CurrencyCode: "USD", BetAmount: "10.000000", WinLoss: "-10.000000"},
+		{AgentID: 2, MasterAgentID: 1, CurrencyCode: "BTC", BetAmount: "0.001000", WinLoss: "-0.001000"},
+	}
+	for _, txn := range txns {
+		c.attribute(rng, txn)
+		if txn.SubAgentID/100 != txn.AgentID || txn.SubAgentID%100 < 1 || txn.SubAgentID%100 > 3 {
+			t.Fatalf("sub-agent %d is not one of agent %d's", txn.SubAgentID, txn.AgentID)
+		}
+	}
+	c.Flush(start.Add(time.Hour))
+
+	find := func(level string, id int, currency string) CommissionEvent {
+		for _, e := range events {
+			if e.Level == level && e.ID == id && e.CurrencyCode == currency {
+				return e
+			}
+		}
+		t.Fatalf("no %s %d %s event", level, id, currency)
+		return CommissionEvent{}
+	}
+
+	master := find(LevelMasterAgent, 1, "USD")
+	if master.Transactions != 3 || master.Turnover != "160.000000" || master.GGR != "85.000000" || master.Commission != "4.250000" {
+		t.Errorf("master USD = %+v", master)
+	}
+	agent := find(LevelAgent, 1, "USD")
+	if agent.ParentID != 1 || agent.Transactions != 2 || agent.GGR != "75.000000" || agent.Commission != "7.500000" {
+		t.Errorf("agent 1 USD = %+v", agent)
+	}
+	if btc := find(LevelMasterAgent, 1, "BTC"); btc.GGR != "0.001000" {
+		t.Errorf("master BTC = %+v", btc)
+	}
+	if master.PeriodStart != "2024-01-01T00:00:00Z" || master.PeriodEnd != "2024-01-01T01:00:00Z" {
+		t.Errorf("period = %s..%s", master.PeriodStart, master.PeriodEnd)
+	}
+
+	// Sub-agent totals add up to their agent's
+	var subGGR int64
+	for _, e := range events {
+		if e.Level == LevelSubAgent && e.ParentID == 1 && e.CurrencyCode == "USD" {
+			subGGR += parseMinor(e.GGR)
+		}
+	}
+	if subGGR != parseMinor(agent.GGR) {
+		t.Errorf("sub-agent GGR %d, agent GGR %s", subGGR, agent.GGR)
+	}
+	// Sub-agents come first, master agents last
+	if events[0].Level != LevelSubAgent || events[len(events)-1].Level != LevelMasterAgent {
+		t.Errorf("event order %s..%s", events[0].Level, events[len(events)-1].Level)
+	}
+
+	// A quiet period emits nothing
+	events = nil
+	c.Flush(start.Add(2 * time.Hour))
+	if len(events) != 0 {
+		t.Errorf("empty period emitted %d events", len(events))
+	}
+}
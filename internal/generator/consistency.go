@@ -0,0 +1,127 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+
+	"github.com/shopspring/decimal"
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Consistency rules checked on generated transactions
+const (
+	RuleWinLoss  = "win_loss" // win_loss equals win_amount - bet_amount
+	RuleCurrency = "currency" // currency_code is the code of currency_id
+	RuleAgent    = "agent"    // agent_id is an agent of master_agent_id
+	RuleHouse    = "house"    // house_id is the house of master_agent_id
+)
+
+var consistencyRules = []string{RuleWinLoss, RuleCurrency, RuleAgent, RuleHouse}
+
+// Violation is a generated transaction that breaks a consistency rule
+type Violation struct {
+	Rule          string
+	TransactionID string
+	Detail        string
+}
+
+// consistencyChecker verifies every n-th transaction by sequence number, so
+// sampling draws nothing from the workers' random sources
+type consistencyChecker struct {
+	every       int64
+	onViolation func(Violation)
+	checked     atomic.Int64
+	violations  []atomic.Int64 // aligned with consistencyRules
+}
+
+// SetConsistencyCheck verifies a sampleRate fraction of generated
+// transactions against cross-field invariants (1 checks every one), calling
+// onViolation, from the generating worker, for each broken rule. Zero turns
+// checking off.
+func (p *Producer) SetConsistencyCheck(sampleRate float64, onViolation func(Violation)) {
+	if sampleRate <= 0 {
+		p.consistency = nil
+		return
+	}
+	p.consistency = &consistencyChecker{
+		every:       max(1, int64(math.Round(1/sampleRate))),
+		onViolation: onViolation,
+		violations:  make([]atomic.Int64, len(consistencyRules)),
+	}
+}
+
+// Consistency returns how many transactions were checked and the violations
+// found per rule
+func (p *Producer) Consistency() (checked int64, violations map[string]int64) {
+	c := p.consistency
+	if c == nil {
+		return 0, nil
+	}
+	violations = make(map[string]int64, len(consistencyRules))
+	for i, rule := range consistencyRules {
+		violations[rule] = c.violations[i].Load()
+	}
+	return c.checked.Load(), violations
+}
+
+// checkConsistency verifies txn if seq is sampled
+func (p *Producer) checkConsistency(seq int64, txn *models.Transaction) {
+	c := p.consistency
+	if seq%c.every != 0 {
+		return
+	}
+	c.checked.Add(1)
+	for i, rule := range consistencyRules {
+		if detail := p.violates(rule, txn); detail != "" {
+			c.violations[i].Add(1)
+			if c.onViolation != nil {
+				c.onViolation(Violation{Rule: rule, TransactionID: txn.ID, Detail: detail})
+			}
+		}
+	}
+}
+
+// violates describes how txn breaks rule, or returns "" when it holds
+func (p *Producer) violates(rule string, txn *models.Transaction) string {
+	switch rule {
+	case RuleWinLoss:
+		bet, betErr := decimal.NewFromString(txn.BetAmount)
+		win, winErr := decimal.NewFromString(txn.WinAmount)
+		winLoss, winLossErr := decimal.NewFromString(txn.WinLoss)
+		if betErr != nil || winErr != nil || winLossErr != nil {
+			return fmt.Sprintf("unparseable amounts %q/%q/%q", txn.BetAmount, txn.WinAmount, txn.WinLoss)
+		}
+		if !win.Sub(bet).Equal(winLoss) {
+			return fmt.Sprintf("win %s - bet %s != win_loss %s", txn.WinAmount, txn.BetAmount, txn.WinLoss)
+		}
+	case RuleCurrency:
+		currency, ok := p.refData.CurrencyByID[txn.CurrencyID]
+		if !ok {
+			return fmt.Sprintf("unknown currency_id %d", txn.CurrencyID)
+		}
+		if currency.Code != txn.CurrencyCode {
+			return fmt.Sprintf("currency_id %d is %s, not %s", txn.CurrencyID, currency.Code, txn.CurrencyCode)
+		}
+	case RuleAgent:
+		for _, a := range p.refData.AgentsByMasterID[txn.MasterAgentID] {
+			if a.ID == txn.AgentID {
+				return ""
+			}
+		}
+		return fmt.Sprintf("agent %d is not under master agent %d", txn.AgentID, txn.MasterAgentID)
+	case RuleHouse:
+		want := 1
+		if p.houses != nil {
+			i, ok := p.houses.byMaster[txn.MasterAgentID]
+			if !ok {
+				return fmt.Sprintf("master agent %d is in no house", txn.MasterAgentID)
+			}
+			want = p.houses.houses[i].ID
+		}
+		if txn.HouseID != want {
+			return fmt.Sprintf("master agent %d is in house %d, not %d", txn.MasterAgentID, want, txn.HouseID)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func TestConsistencyCheck(t *testing.T) {
+	p := testProducer()
+	p.refData.CurrencyByID = map[int]*models.Currency{}
+	for i := range p.refData.Currencies {
+		p.refData.CurrencyByID[p.refData.Currencies[i].ID] = &p.refData.Currencies[i]
+	}
+	var violations []Violation
+	p.SetConsistencyCheck(1, func(v Violation) { violations = append(violations, v) })
+
+	rngs := p.newStreams(0)
+	for i := 0; i < 1000; i++ {
+		p.generateTransaction(rngs)
+	}
+	for _, fixed := range []bool{false, true} {
+		p.SetFixedPoint(fixed)
+		for i := 0; i < 1000; i++ {
+			p.generateTransaction(rngs)
+		}
+	}
+	if len(violations) > 0 {
+		t.Fatalf("generated transactions break %d rules, first %+v", len(violations), violations[0])
+	}
+
+	txn := p.generateTransaction(rngs)
+	txn.WinLoss = "1.000000"
+	txn.CurrencyCode = "XYZ"
+	txn.AgentID = 99
+	txn.HouseID = 2
+	p.checkConsistency(2, txn)
+
+	checked, counts := p.Consistency()
+	if checked != 3002 {
+		t.Errorf("checked %d transactions, want 3002", checked)
+	}
+	for _, rule := range []string{RuleWinLoss, RuleCurrency, RuleAgent, RuleHouse} {
+		if counts[rule] != 1 {
+			t.Errorf("%s violations = %d, want 1", rule, counts[rule])
+		}
+	}
+	if len(violations) != 4 || violations[0].TransactionID != txn.ID {
+		t.Errorf("violations = %+v", violations)
+	}
+}
+
+func TestConsistencySampling(t *testing.T) {
+	p := testProducer()
+	p.SetConsistencyCheck(0.1, nil)
+	rngs := p.newStreams(0)
+	for i := 0; i < 1000; i++ {
+		p.generateTransaction(rngs)
+	}
+	if checked, _ := p.Consistency(); checked != 100 {
+		t.Errorf("checked %d of 1000 at 10%%, want 100", checked)
+	}
+}
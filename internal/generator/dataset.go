@@ -0,0 +1,256 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Selection strategies: how a custom dataset record is chosen per transaction
+const (
+	SelectUniform    = "uniform"     // any record, equally likely (default)
+	SelectWeighted   = "weighted"    // by the record's weight attribute
+	SelectRoundRobin = "round_robin" // records in turn by sequence number
+	SelectAgent      = "agent"       // the same record for every transaction of an agent
+)
+
+// SelectStrategies lists the accepted selection strategies
+var SelectStrategies = []string{SelectUniform, SelectWeighted, SelectRoundRobin, SelectAgent}
+
+// Dataset is a custom reference dataset, e.g. promotions or campaigns, that
+// fields can draw values from alongside the built-in reference files
+type Dataset struct {
+	Name     string
+	Records  []map[string]any
+	Strategy string // one of SelectStrategies, default uniform
+	// WeightAttribute holds each record's weight for the weighted strategy,
+	// default weight
+	WeightAttribute string
+}
+
+// Field adds a named value to every transaction: the attribute of the record
+// chosen from a dataset, or the output of a built-in generator. Fields of the
+// same dataset share the chosen record.
+type Field struct {
+	Name      string `yaml:"name" json:"name"`
+	Dataset   string `yaml:"dataset" json:"dataset"`
+	Attribute string `yaml:"attribute" json:"attribute"`
+	// Generator is one of Generators, used instead of a dataset
+	Generator string `yaml:"generator" json:"generator"`
+	Length    int    `yaml:"length" json:"length"` // lorem: characters, default 32
+	Start     int64  `yaml:"start" json:"start"`   // counter: first value
+	// References takes a value already emitted by another field or by the
+	// id, external_transaction_id, vendor_bet_id or round_id column, so
+	// joins on it always find a match. The field is unset until the source
+	// has emitted a value within Window (any remembered value when zero).
+	References string        `yaml:"references" json:"references"`
+	Window     time.Duration `yaml:"window" json:"window"`
+	Pool       int           `yaml:"pool" json:"pool"` // source values remembered, default 10000
+}
+
+// dataset is a registered Dataset with its records flattened to strings
+type dataset struct {
+	name     string
+	strategy string
+	records  []map[string]string
+	weights  []float64
+}
+
+// fieldSet fills the configured fields of every transaction
+type fieldSet struct {
+	fields   []Field
+	datasets []*dataset          // the datasets the fields use, in first-use order
+	source   []int               // per field: index into datasets, -1 for other fields
+	fakers   []faker             // per field: its generator, nil for other fields
+	refs     []*refField         // per field: its reference, nil for other fields; nil without references
+	pools    map[string]*refPool // referenced source -> its emitted values
+}
+
+// LoadDataset reads a dataset's records from a JSON array of objects
+func LoadDataset(path string) ([]map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var records []map[string]any
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// RegisterDataset makes d available to SetFields, replacing any dataset of
+// the same name. Attribute values are formatted as strings.
+func (p *Producer) RegisterDataset(d Dataset) error {
+	if d.Name == "" {
+		return fmt.Errorf("dataset name is required")
+	}
+	if len(d.Records) == 0 {
+		return fmt.Errorf("dataset %s: no records", d.Name)
+	}
+	strategy := d.Strategy
+	if strategy == "" {
+		strategy = SelectUniform
+	}
+	if !slices.Contains(SelectStrategies, strategy) {
+		return fmt.Errorf("dataset %s: unknown selection strategy %s", d.Name, strategy)
+	}
+
+	ds := &dataset{name: d.Name, strategy: strategy, records: make([]map[string]string, len(d.Records))}
+	weightAttr := d.WeightAttribute
+	if weightAttr == "" {
+		weightAttr = "weight"
+	}
+	for i, record := range d.Records {
+		ds.records[i] = make(map[string]string, len(record))
+		for k, v := range record {
+			ds.records[i][k] = formatAttribute(v)
+		}
+		if strategy != SelectWeighted {
+			continue
+		}
+		weight, err := strconv.ParseFloat(ds.records[i][weightAttr], 64)
+		if err != nil || weight < 0 {
+			return fmt.Errorf("dataset %s: record %d needs a non-negative %s", d.Name, i, weightAttr)
+		}
+		ds.weights = append(ds.weights, weight)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.datasets == nil {
+		p.datasets = make(map[string]*dataset)
+	}
+	p.datasets[d.Name] = ds
+	return nil
+}
+
+// formatAttribute formats a record attribute, writing whole JSON numbers
+// without a fraction
+func formatAttribute(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// SetFields fills fields on every subsequent transaction from registered
+// datasets and built-in generators. No fields stops filling them.
+func (p *Producer) SetFields(fields []Field) error {
+	if len(fields) == 0 {
+		p.fields = nil
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := &fieldSet{fields: fields}
+	used := make(map[string]int)
+	names := make(map[string]bool)
+	for _, f := range fields {
+		if f.Name == "" {
+			return fmt.Errorf("field name is required")
+		}
+		if names[f.Name] {
+			return fmt.Errorf("duplicate field %s", f.Name)
+		}
+		names[f.Name] = true
+		kinds := 0
+		for _, set := range []bool{f.Dataset != "", f.Generator != "", f.References != ""} {
+			if set {
+				kinds++
+			}
+		}
+		if kinds > 1 {
+			return fmt.Errorf("field %s: set one of a dataset, a generator or references", f.Name)
+		}
+		if f.References != "" {
+			s.source = append(s.source, -1)
+			s.fakers = append(s.fakers, nil)
+			continue
+		}
+		if f.Generator != "" {
+			fake, err := newFaker(f)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", f.Name, err)
+			}
+			s.source = append(s.source, -1)
+			s.fakers = append(s.fakers, fake)
+			continue
+		}
+		if f.Attribute == "" {
+			return fmt.Errorf("field %s: attribute is required", f.Name)
+		}
+		i, ok := used[f.Dataset]
+		if !ok {
+			ds, registered := p.datasets[f.Dataset]
+			if !registered {
+				return fmt.Errorf("field %s: unknown dataset %q", f.Name, f.Dataset)
+			}
+			i = len(s.datasets)
+			used[f.Dataset] = i
+			s.datasets = append(s.datasets, ds)
+		}
+		s.source = append(s.source, i)
+		s.fakers = append(s.fakers, nil)
+	}
+	if err := s.addRefs(); err != nil {
+		return err
+	}
+	p.fields = s
+	return nil
+}
+
+// fill picks a record from each dataset, runs the generators, resolves the
+// references and sets txn's field values
+func (s *fieldSet) fill(rng *rand.Rand, seq int64, txn *models.Transaction, now time.Time) {
+	records := make([]map[string]string, len(s.datasets))
+	for i, ds := range s.datasets {
+		records[i] = ds.records[ds.pick(rng, seq, txn.AgentID)]
+	}
+	txn.Fields = make(map[string]string, len(s.fields))
+	for i, f := range s.fields {
+		switch {
+		case s.fakers[i] != nil:
+			txn.Fields[f.Name] = s.fakers[i](rng, now)
+		case s.source[i] >= 0:
+			txn.Fields[f.Name] = records[s.source[i]][f.Attribute]
+		default:
+			if v, ok := s.refs[i].pool.pick(rng, now, s.refs[i].window); ok {
+				txn.Fields[f.Name] = v
+			}
+		}
+	}
+	if s.pools != nil {
+		s.remember(txn, now)
+	}
+}
+
+func (d *dataset) pick(rng *rand.Rand, seq int64, agentID int) int {
+	switch d.strategy {
+	case SelectWeighted:
+		return pickWeighted(rng, d.weights, nil)
+	case SelectRoundRobin:
+		return int(seq % int64(len(d.records)))
+	case SelectAgent:
+		h := fnv.New32a()
+		h.Write([]byte(d.name))
+		h.Write([]byte(strconv.Itoa(agentID)))
+		return int(h.Sum32() % uint32(len(d.records)))
+	default:
+		return rng.Intn(len(d.records))
+	}
+}
@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDatasetFields(t *testing.T) {
+	p := testProducer()
+	p.SetSeed(5)
+	err := p.RegisterDataset(Dataset{
+		Name: "promotions",
+		Records: []map[string]any{
+			{"id": float64(101), "name": "welcome", "weight": float64(3)},
+			{"id": float64(102), "name": "reload", "weight": "1"},
+			{"id": float64(103), "name": "retired", "weight": 0},
+		},
+		Strategy: SelectWeighted,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.SetFields([]Field{
+		{Name: "promotion_id", Dataset: "promotions", Attribute: "id"},
+		{Name: "promotion_name", Dataset: "promotions", Attribute: "name"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rngs := p.newStreams(0)
+	counts := map[string]int{}
+	const total = 10000
+	for i := 0; i < total; i++ {
+		fields := p.generateTransaction(rngs).Fields
+		pair := fields["promotion_id"] + "/" + fields["promotion_name"]
+		counts[pair]++
+	}
+	if counts["103/retired"] != 0 {
+		t.Errorf("zero-weight record chosen %d times", counts["103/retired"])
+	}
+	if counts["101/welcome"]+counts["102/reload"] != total {
+		t.Fatalf("fields of one dataset drew different records: %v", counts)
+	}
+	if share := float64(counts["101/welcome"]) / total; math.Abs(share-0.75) > 0.02 {
+		t.Errorf("welcome share %.3f, want about 0.75", share)
+	}
+}
+
+func TestDatasetStrategies(t *testing.T) {
+	p := testProducer()
+	records := []map[string]any{{"code": "A"}, {"code": "B"}, {"code": "C"}}
+	for _, strategy := range []string{SelectRoundRobin, SelectAgent} {
+		if err := p.RegisterDataset(Dataset{Name: strategy, Records: records, Strategy: strategy}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := p.SetFields([]Field{
+		{Name: "turn", Dataset: SelectRoundRobin, Attribute: "code"},
+		{Name: "sticky", Dataset: SelectAgent, Attribute: "code"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rngs := p.newStreams(0)
+	var turns string
+	sticky := map[string]bool{}
+	for i := 0; i < 6; i++ {
+		fields := p.generateTransaction(rngs).Fields
+		turns += fields["turn"]
+		sticky[fields["sticky"]] = true
+	}
+	if turns != "BCABCA" {
+		t.Errorf("round robin gave %s, want BCABCA", turns)
+	}
+	if len(sticky) != 1 {
+		t.Errorf("one agent drew %d records, want 1", len(sticky))
+	}
+}
+
+func TestDatasetErrors(t *testing.T) {
+	p := testProducer()
+	if err := p.RegisterDataset(Dataset{Name: "empty"}); err == nil {
+		t.Error("empty dataset accepted")
+	}
+	if err := p.RegisterDataset(Dataset{Name: "x", Records: []map[string]any{{"a": 1}}, Strategy: "random"}); err == nil {
+		t.Error("unknown strategy accepted")
+	}
+	if err := p.RegisterDataset(Dataset{Name: "w", Records: []map[string]any{{"a": 1}}, Strategy: SelectWeighted}); err == nil {
+		t.Error("weighted record without a weight accepted")
+	}
+	if err := p.SetFields([]Field{{Name: "f", Dataset: "missing", Attribute: "a"}}); err == nil {
+		t.Error("field of an unregistered dataset accepted")
+	}
+}
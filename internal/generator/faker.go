@@ -0,0 +1,193 @@
+package generator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Built-in field generators
+const (
+	GenUUID      = "uuid" // random UUIDv4
+	GenULID      = "ulid" // ULID stamped with the generation time
+	GenName      = "name" // first and last name
+	GenFirstName = "first_name"
+	GenLastName  = "last_name"
+	GenEmail     = "email"      // first.last with a number at an example domain
+	GenIPv4      = "ipv4"       // public-looking IPv4 address
+	GenIPv6      = "ipv6"       // global unicast IPv6 address
+	GenUserAgent = "user_agent" // common browser or app user agent
+	GenCountry   = "country"    // ISO 3166-1 alpha-2 code
+	GenLorem     = "lorem"      // lorem ipsum text of Length characters
+	GenCounter   = "counter"    // monotonic integer from Start, across workers
+)
+
+// Generators lists the built-in field generators
+var Generators = []string{
+	GenUUID, GenULID, GenName, GenFirstName, GenLastName, GenEmail,
+	GenIPv4, GenIPv6, GenUserAgent, GenCountry, GenLorem, GenCounter,
+}
+
+// faker produces one field value. Random parts come from the worker's rng so
+// seeded runs repeat.
+type faker func(rng *rand.Rand, now time.Time) string
+
+var (
+	firstNames = []string{
+		"James", "Mary", "Wei", "Fatima", "Carlos", "Aiko", "Olga", "Liam",
+		"Priya", "Mateo", "Chloe", "Ahmed", "Sofia", "Noah", "Mei", "Lucas",
+		"Amara", "Ivan", "Hana", "Diego", "Emma", "Kofi", "Yuki", "Elena",
+	}
+	lastNames = []string{
+		"Smith", "Garcia", "Wang", "Khan", "Silva", "Tanaka", "Ivanova", "Murphy",
+		"Patel", "Rossi", "Martin", "Hassan", "Kim", "Nguyen", "Schmidt", "Lopez",
+		"Okafor", "Novak", "Sato", "Costa", "Brown", "Mensah", "Suzuki", "Popescu",
+	}
+	emailDomains = []string{"example.com", "example.net", "example.org"}
+	userAgents   = []string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.0.0",
+		"BetApp/5.12.0 (iOS 17.4; iPhone15,2)",
+		"BetApp/5.12.0 (Android 14; SM-S918B)",
+	}
+	countries = []string{
+		"US", "GB", "DE", "FR", "ES", "IT", "NL", "SE", "PL", "BR",
+		"MX", "AR", "CA", "JP", "KR", "CN", "IN", "TH", "VN", "PH",
+		"ID", "MY", "AU", "NZ", "ZA", "NG", "KE", "TR", "AE", "IL",
+	}
+	loremWords = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do
+		eiusmod tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis
+		nostrud exercitation ullamco laboris nisi aliquip ex ea commodo consequat`)
+)
+
+// crockford is the ULID alphabet
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newFaker returns the generator of field f
+func newFaker(f Field) (faker, error) {
+	pick := func(list []string) faker {
+		return func(rng *rand.Rand, _ time.Time) string { return list[rng.Intn(len(list))] }
+	}
+	switch f.Generator {
+	case GenUUID:
+		return fakeUUID, nil
+	case GenULID:
+		return fakeULID, nil
+	case GenName:
+		return func(rng *rand.Rand, _ time.Time) string {
+			return firstNames[rng.Intn(len(firstNames))] + " " + lastNames[rng.Intn(len(lastNames))]
+		}, nil
+	case GenFirstName:
+		return pick(firstNames), nil
+	case GenLastName:
+		return pick(lastNames), nil
+	case GenEmail:
+		return fakeEmail, nil
+	case GenIPv4:
+		return fakeIPv4, nil
+	case GenIPv6:
+		return fakeIPv6, nil
+	case GenUserAgent:
+		return pick(userAgents), nil
+	case GenCountry:
+		return pick(countries), nil
+	case GenLorem:
+		length := f.Length
+		if length == 0 {
+			length = 32
+		}
+		if length < 0 {
+			return nil, fmt.Errorf("lorem length must be positive")
+		}
+		return func(rng *rand.Rand, _ time.Time) string { return lorem(rng, length) }, nil
+	case GenCounter:
+		var next atomic.Int64
+		next.Store(f.Start)
+		return func(*rand.Rand, time.Time) string {
+			return strconv.FormatInt(next.Add(1)-1, 10)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown generator %q", f.Generator)
+	}
+}
+
+func fakeUUID(rng *rand.Rand, _ time.Time) string {
+	var u [16]byte
+	binary.BigEndian.PutUint64(u[:8], rng.Uint64())
+	binary.BigEndian.PutUint64(u[8:], rng.Uint64())
+	u[6] = 0x40 | u[6]&0x0f
+	u[8] = 0x80 | u[8]&0x3f
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
+
+// fakeULID encodes a 48-bit millisecond timestamp and 80 random bits as 26
+// Crockford base32 characters
+func fakeULID(rng *rand.Rand, now time.Time) string {
+	var b [16]byte
+	ms := uint64(now.UnixMilli())
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	binary.BigEndian.PutUint16(b[6:8], uint16(rng.Uint32()))
+	binary.BigEndian.PutUint64(b[8:], rng.Uint64())
+
+	// 128 bits as 26 five-bit groups, the first holding only 3 bits
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+func fakeEmail(rng *rand.Rand, _ time.Time) string {
+	first := strings.ToLower(firstNames[rng.Intn(len(firstNames))])
+	last := strings.ToLower(lastNames[rng.Intn(len(lastNames))])
+	return fmt.Sprintf("%s.%s%d@%s", first, last, rng.Intn(1000), emailDomains[rng.Intn(len(emailDomains))])
+}
+
+// fakeIPv4 returns an address outside the private, loopback, link-local and
+// multicast ranges
+func fakeIPv4(rng *rand.Rand, _ time.Time) string {
+	for {
+		var a [4]byte
+		binary.BigEndian.PutUint32(a[:], rng.Uint32())
+		addr := netip.AddrFrom4(a)
+		if a[0] != 0 && a[0] < 224 && !addr.IsPrivate() && !addr.IsLoopback() && !addr.IsLinkLocalUnicast() {
+			return addr.String()
+		}
+	}
+}
+
+// fakeIPv6 returns an address in 2000::/3, the global unicast range
+func fakeIPv6(rng *rand.Rand, _ time.Time) string {
+	var a [16]byte
+	binary.BigEndian.PutUint64(a[:8], rng.Uint64())
+	binary.BigEndian.PutUint64(a[8:], rng.Uint64())
+	a[0] = 0x20 | a[0]&0x1f
+	return netip.AddrFrom16(a).String()
+}
+
+// lorem returns lorem ipsum words, cut to exactly length characters
+func lorem(rng *rand.Rand, length int) string {
+	var b strings.Builder
+	b.Grow(length + 16)
+	for b.Len() < length {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(loremWords[rng.Intn(len(loremWords))])
+	}
+	return b.String()[:length]
+}
@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"math/rand"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFakers(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	patterns := map[string]*regexp.Regexp{
+		GenUUID:      regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		GenULID:      regexp.MustCompile(`^01HF[0-9A-HJKMNP-TV-Z]{22}$`),
+		GenName:      regexp.MustCompile(`^[A-Z][a-z]+ [A-Z][a-z]+$`),
+		GenEmail:     regexp.MustCompile(`^[a-z]+\.[a-z]+\d{1,3}@example\.(com|net|org)$`),
+		GenCountry:   regexp.MustCompile(`^[A-Z]{2}$`),
+		GenUserAgent: regexp.MustCompile(`^(Mozilla|BetApp)/`),
+	}
+	rng := rand.New(rand.NewSource(1))
+	for gen, pattern := range patterns {
+		fake, err := newFaker(Field{Name: gen, Generator: gen})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 50; i++ {
+			if v := fake(rng, now); !pattern.MatchString(v) {
+				t.Errorf("%s gave %q", gen, v)
+			}
+		}
+	}
+
+	v4, _ := newFaker(Field{Generator: GenIPv4})
+	v6, _ := newFaker(Field{Generator: GenIPv6})
+	for i := 0; i < 200; i++ {
+		a := netip.MustParseAddr(v4(rng, now))
+		if !a.Is4() || a.IsPrivate() || a.IsLoopback() || a.IsMulticast() {
+			t.Errorf("ipv4 gave %s", a)
+		}
+		b := netip.MustParseAddr(v6(rng, now))
+		if !b.Is6() || !b.IsGlobalUnicast() || b.As16()[0]>>5 != 1 {
+			t.Errorf("ipv6 gave %s", b)
+		}
+	}
+
+	for _, length := range []int{1, 5, 32, 500} {
+		fake, _ := newFaker(Field{Generator: GenLorem, Length: length})
+		if v := fake(rng, now); len(v) != length {
+			t.Errorf("lorem of %d gave %d characters", length, len(v))
+		}
+	}
+
+	counter, _ := newFaker(Field{Generator: GenCounter, Start: 100})
+	for want := int64(100); want < 105; want++ {
+		if v := counter(rng, now); v != strconv.FormatInt(want, 10) {
+			t.Errorf("counter gave %s, want %d", v, want)
+		}
+	}
+
+	if _, err := newFaker(Field{Generator: "phone"}); err == nil {
+		t.Error("unknown generator accepted")
+	}
+}
+
+func TestGeneratedFields(t *testing.T) {
+	p := testProducer()
+	p.RegisterDataset(Dataset{Name: "channel", Records: []map[string]any{{"value": "web"}}})
+	err := p.SetFields([]Field{
+		{Name: "session_id", Generator: GenULID},
+		{Name: "channel", Dataset: "channel", Attribute: "value"},
+		{Name: "seq", Generator: GenCounter},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rngs := p.newStreams(0)
+	for want := 0; want < 3; want++ {
+		fields := p.generateTransaction(rngs).Fields
+		if len(fields["session_id"]) != 26 || fields["channel"] != "web" || fields["seq"] != strconv.Itoa(want) {
+			t.Errorf("transaction %d fields %v", want, fields)
+		}
+	}
+	if err := p.SetFields([]Field{{Name: "x", Dataset: "channel", Generator: GenUUID}}); err == nil {
+		t.Error("field with both a dataset and a generator accepted")
+	}
+}
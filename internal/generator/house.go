@@ -0,0 +1,127 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// houseSet routes traffic across houses: a house by weight, then a uniform
+// master agent of that house, then a uniform agent of that master
+type houseSet struct {
+	houses   []models.House
+	weights  []float64
+	byMaster map[int]int         // master agent ID -> index of its house
+	allowed  []map[int]bool      // per house: currency IDs it may use; nil allows all
+	currency [][]models.Currency // per house: the allowed currencies in reference order
+}
+
+// LoadHouses reads a houses reference file
+func LoadHouses(path string) ([]models.House, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var houses []models.House
+	if err := json.Unmarshal(data, &houses); err != nil {
+		return nil, err
+	}
+	return houses, nil
+}
+
+// SetHouses spreads transactions across houses instead of the single house 1.
+// Each master agent belongs to at most one house; master agents not listed
+// under any house generate no traffic.
+func (p *Producer) SetHouses(houses []models.House) error {
+	if len(houses) == 0 {
+		return fmt.Errorf("no houses defined")
+	}
+	byCode := make(map[string]models.Currency, len(p.refData.Currencies))
+	for _, c := range p.refData.Currencies {
+		byCode[c.Code] = c
+	}
+
+	s := &houseSet{byMaster: make(map[int]int)}
+	ids := make(map[int]bool)
+	total := 0.0
+	for i, h := range houses {
+		if ids[h.ID] {
+			return fmt.Errorf("duplicate house id %d", h.ID)
+		}
+		ids[h.ID] = true
+		if h.Weight < 0 {
+			return fmt.Errorf("house %d: weight must be non-negative", h.ID)
+		}
+		if len(h.MasterAgentIDs) == 0 {
+			return fmt.Errorf("house %d: no master agents", h.ID)
+		}
+		for _, id := range h.MasterAgentIDs {
+			if len(p.refData.AgentsByMasterID[id]) == 0 {
+				return fmt.Errorf("house %d: unknown master agent %d", h.ID, id)
+			}
+			if other, ok := s.byMaster[id]; ok {
+				return fmt.Errorf("master agent %d belongs to houses %d and %d", id, houses[other].ID, h.ID)
+			}
+			s.byMaster[id] = i
+		}
+
+		var allowed map[int]bool
+		var currencies []models.Currency
+		if len(h.Currencies) > 0 {
+			allowed = make(map[int]bool, len(h.Currencies))
+			for _, code := range h.Currencies {
+				c, ok := byCode[code]
+				if !ok {
+					return fmt.Errorf("house %d: unknown currency %s", h.ID, code)
+				}
+				allowed[c.ID] = true
+			}
+			for _, c := range p.refData.Currencies {
+				if allowed[c.ID] {
+					currencies = append(currencies, c)
+				}
+			}
+		}
+
+		h.MasterAgentIDs = slices.Clone(h.MasterAgentIDs)
+		slices.Sort(h.MasterAgentIDs)
+		s.houses = append(s.houses, h)
+		s.weights = append(s.weights, h.Weight)
+		s.allowed = append(s.allowed, allowed)
+		s.currency = append(s.currency, currencies)
+		total += h.Weight
+	}
+	if total == 0 {
+		return fmt.Errorf("house weights sum to zero")
+	}
+	p.houses = s
+	return nil
+}
+
+// pickMaster chooses a house by weight and one of its master agents
+func (s *houseSet) pickMaster(rng *rand.Rand) int {
+	masters := s.houses[pickWeighted(rng, s.weights, nil)].MasterAgentIDs
+	return masters[rng.Intn(len(masters))]
+}
+
+// share is the fraction of traffic master agent id receives
+func (s *houseSet) share(id int) float64 {
+	i, ok := s.byMaster[id]
+	if !ok {
+		return 0
+	}
+	total := 0.0
+	for _, w := range s.weights {
+		total += w
+	}
+	return s.weights[i] / total / float64(len(s.houses[i].MasterAgentIDs))
+}
+
+// of returns the index of the house master agent id belongs to
+func (s *houseSet) of(id int) int {
+	return s.byMaster[id]
+}
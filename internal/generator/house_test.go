@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func TestHouses(t *testing.T) {
+	p := testProducer()
+	p.refData.AgentsByMasterID = map[int][]models.Agent{
+		1: {{ID: 10, MasterAgentID: 1}, {ID: 11, MasterAgentID: 1}},
+		2: {{ID: 20, MasterAgentID: 2}},
+		3: {{ID: 30, MasterAgentID: 3}},
+	}
+	p.SetSeed(3)
+	err := p.SetHouses([]models.House{
+		{ID: 7, Code: "ALPHA", Weight: 3, MasterAgentIDs: []int{1, 2}, Currencies: []string{"USD", "BTC"}},
+		{ID: 9, Code: "BETA", Weight: 1, MasterAgentIDs: []int{3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rngs := p.newStreams(0)
+	byHouse := map[int]int{}
+	total := 20000
+	for i := 0; i < total; i++ {
+		txn := p.generateTransaction(rngs)
+		byHouse[txn.HouseID]++
+		switch txn.MasterAgentID {
+		case 1, 2:
+			if txn.HouseID != 7 {
+				t.Fatalf("master agent %d in house %d", txn.MasterAgentID, txn.HouseID)
+			}
+			if txn.CurrencyCode != "USD" && txn.CurrencyCode != "BTC" {
+				t.Fatalf("house 7 bet in %s", txn.CurrencyCode)
+			}
+		case 3:
+			if txn.HouseID != 9 {
+				t.Fatalf("master agent 3 in house %d", txn.HouseID)
+			}
+		}
+	}
+	if share := float64(byHouse[7]) / float64(total); math.Abs(share-0.75) > 0.02 {
+		t.Errorf("house 7 share = %.3f, want 0.75", share)
+	}
+
+	pool := p.newAgentPool(func(models.Agent) bool { return true })
+	if got := pool.cumulative[len(pool.cumulative)-1]; math.Abs(got-1) > 1e-9 {
+		t.Errorf("agent pool weights sum to %f", got)
+	}
+}
+
+func TestSetHousesRejects(t *testing.T) {
+	for name, houses := range map[string][]models.House{
+		"empty":            nil,
+		"unknown master":   {{ID: 1, Weight: 1, MasterAgentIDs: []int{99}}},
+		"shared master":    {{ID: 1, Weight: 1, MasterAgentIDs: []int{1}}, {ID: 2, Weight: 1, MasterAgentIDs: []int{1}}},
+		"unknown currency": {{ID: 1, Weight: 1, MasterAgentIDs: []int{1}, Currencies: []string{"XYZ"}}},
+		"zero weights":     {{ID: 1, MasterAgentIDs: []int{1}}},
+	} {
+		if err := testProducer().SetHouses(houses); err == nil {
+			t.Errorf("%s: SetHouses accepted %+v", name, houses)
+		}
+	}
+}
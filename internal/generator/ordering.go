@@ -0,0 +1,259 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Ordering keys supported by GenerateOrdered
+const (
+	OrderByRound       = "round_id"
+	OrderByAgent       = "agent_id"
+	OrderByMasterAgent = "master_agent_id"
+)
+
+// IsOrderingKey reports whether key can be used with GenerateOrdered
+func IsOrderingKey(key string) bool {
+	switch key {
+	case OrderByRound, OrderByAgent, OrderByMasterAgent:
+		return true
+	}
+	return false
+}
+
+// GenerateOrdered produces transactions like Generate, but shards keys across
+// workers so that every key is owned by a single worker. Each worker sends its
+// transactions in sequence order, so all transactions sharing a key reach
+// output (and every sink reading it) in ascending sequence order.
+func (p *Producer) GenerateOrdered(ctx context.Context, count int, workers int, key string, output chan<- *models.Transaction) error {
+	defer close(output)
+
+	switch key {
+	case OrderByRound:
+		p.generateByRound(ctx, count, workers, output)
+		return nil
+	case OrderByAgent, OrderByMasterAgent:
+		p.generateByAgent(ctx, count, workers, key, output)
+		return nil
+	}
+	return fmt.Errorf("unsupported ordering key: %s", key)
+}
+
+// strictBlockSize is the number of consecutive sequence numbers a worker
+// generates at a time in GenerateStrict
+const strictBlockSize = 1000
+
+// GenerateStrict produces transactions in exact sequence order. Workers build
+// consecutive blocks of sequence numbers in parallel and a single sequencer
+// emits the blocks in order, so throughput is bounded by the sequencer and
+// sinks reading output see one global order.
+func (p *Producer) GenerateStrict(ctx context.Context, count int, workers int, output chan<- *models.Transaction) error {
+	defer close(output)
+
+	base := p.sequence.Add(int64(count)) - int64(count)
+	pool := p.newAgentPool(func(models.Agent) bool { return true })
+	blocks := (count + strictBlockSize - 1) / strictBlockSize
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lanes := make([]chan []*models.Transaction, workers)
+	p.startWorkers(workers)
+	for i := range lanes {
+		lanes[i] = make(chan []*models.Transaction, 1)
+		go func(worker int) {
+			defer close(lanes[worker])
+			generated, release := p.enterWorker(worker)
+			defer release()
+			rngs := p.newStreams(int64(worker))
+
+			for b := worker; b < blocks; b += workers {
+				first := base + int64(b*strictBlockSize) + 1
+				last := min(first+strictBlockSize-1, base+int64(count))
+				block := make([]*models.Transaction, 0, last-first+1)
+				for seq := first; seq <= last; seq++ {
+					block = append(block, p.buildTransaction(rngs, seq, pool.pick(rngs.entity)))
+				}
+				generated.Add(int64(len(block)))
+				select {
+				case lanes[worker] <- block:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(i)
+	}
+
+	for b := 0; b < blocks; b++ {
+		block, ok := <-lanes[b%workers]
+		if !ok {
+			return nil
+		}
+		for _, txn := range block {
+			select {
+			case output <- txn:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// generateByRound assigns round r to worker r % workers. Round IDs derive from
+// the sequence number (seq/10), so each worker builds the sequence numbers of
+// its own rounds.
+func (p *Producer) generateByRound(ctx context.Context, count int, workers int, output chan<- *models.Transaction) {
+	base := p.sequence.Add(int64(count)) - int64(count)
+	pool := p.newAgentPool(func(models.Agent) bool { return true })
+
+	var wg sync.WaitGroup
+	p.startWorkers(workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			generated, release := p.enterWorker(worker)
+			defer release()
+			rngs := p.newStreams(int64(worker))
+
+			first, last := base+1, base+int64(count)
+			round := first / 10
+			round += (int64(worker) - round%int64(workers) + int64(workers)) % int64(workers)
+			for ; round*10 <= last; round += int64(workers) {
+				for seq := max(round*10, first); seq <= min(round*10+9, last); seq++ {
+					select {
+					case output <- p.buildTransaction(rngs, seq, pool.pick(rngs.entity)):
+						generated.Add(1)
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// generateByAgent hashes agents (or master agents) onto workers and has each
+// worker pick only from its own agents. Worker shares follow the weight of
+// their agents, keeping the overall agent distribution of Generate.
+func (p *Producer) generateByAgent(ctx context.Context, count int, workers int, key string, output chan<- *models.Transaction) {
+	pools := make([]*agentPool, workers)
+	total := 0.0
+	for i := range pools {
+		worker := i
+		pools[i] = p.newAgentPool(func(a models.Agent) bool {
+			id := a.ID
+			if key == OrderByMasterAgent {
+				id = a.MasterAgentID
+			}
+			h := fnv.New32a()
+			h.Write([]byte(strconv.Itoa(id)))
+			return int(h.Sum32()%uint32(workers)) == worker
+		})
+		total += pools[i].weight()
+	}
+
+	shares := make([]int, workers)
+	assigned, last := 0, -1
+	for i, pool := range pools {
+		if pool.weight() == 0 {
+			continue
+		}
+		shares[i] = int(float64(count) * pool.weight() / total)
+		assigned += shares[i]
+		last = i
+	}
+	if last < 0 {
+		return
+	}
+	shares[last] += count - assigned
+
+	var wg sync.WaitGroup
+	p.startWorkers(workers)
+	for i := range pools {
+		if shares[i] == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			generated, release := p.enterWorker(worker)
+			defer release()
+			rngs := p.newStreams(int64(worker))
+
+			for j := 0; j < shares[worker]; j++ {
+				select {
+				case output <- p.buildTransaction(rngs, p.sequence.Add(1), pools[worker].pick(rngs.entity)):
+					generated.Add(1)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// agentPool picks agents with the probability Generate gives them: a uniform
+// master agent (or a master agent of a house chosen by weight), then a
+// uniform agent of that master
+type agentPool struct {
+	agents     []models.Agent
+	cumulative []float64
+}
+
+func (p *Producer) newAgentPool(include func(models.Agent) bool) *agentPool {
+	masters := make([]int, 0, len(p.refData.AgentsByMasterID))
+	for id := range p.refData.AgentsByMasterID {
+		masters = append(masters, id)
+	}
+	sort.Ints(masters)
+
+	pool := &agentPool{}
+	sum := 0.0
+	for _, id := range masters {
+		share := 1 / float64(len(masters))
+		if p.houses != nil {
+			share = p.houses.share(id)
+		}
+		if share == 0 {
+			continue
+		}
+		agents := p.refData.AgentsByMasterID[id]
+		for _, a := range agents {
+			if !include(a) {
+				continue
+			}
+			sum += share / float64(len(agents))
+			pool.agents = append(pool.agents, a)
+			pool.cumulative = append(pool.cumulative, sum)
+		}
+	}
+	return pool
+}
+
+// weight is the probability Generate would pick any agent in the pool
+func (a *agentPool) weight() float64 {
+	if len(a.cumulative) == 0 {
+		return 0
+	}
+	return a.cumulative[len(a.cumulative)-1]
+}
+
+func (a *agentPool) pick(rng *rand.Rand) models.Agent {
+	target := rng.Float64() * a.weight()
+	i := sort.SearchFloat64s(a.cumulative, target)
+	if i == len(a.agents) {
+		i--
+	}
+	return a.agents[i]
+}
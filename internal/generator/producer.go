@@ -5,34 +5,59 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
-	"math/rand"
+	"maps"
 	"os"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/supratick/message_producer/internal/models"
+	"github.com/supratick/message_producer/internal/ring"
 )
 
 // Producer generates transaction messages
 type Producer struct {
 	refData        *models.ReferenceData
 	sequence       atomic.Int64
-	rng            *rand.Rand
+	streams        *streams // GenerateSingle's random streams
 	mu             sync.Mutex
 	vendorCodes    []string
 	betAmounts     []decimal.Decimal
+	betMinor       []int64 // betAmounts in minor units
+	fixedPoint     bool    // amount math in int64 minor units
+	rates          *RateWalk
+	commissions    *Commissions
+	behaviors      *behaviorInjector
+	tracer         *tracer
+	regions        *regionPicker
+	datasets       map[string]*dataset // custom reference datasets by name
+	fields         *fieldSet
+	templates      *templateSet
+	houses         *houseSet
+	consistency    *consistencyChecker
 	winMultipliers []float64
+	masterAgentIDs []int // sorted so seeded runs pick the same agents
+	seed           int64
+	profile        *activeProfile
+	lockThreads    bool
+	workers        atomic.Pointer[[]workerCounter]
 	logger         *slog.Logger
 }
 
 // NewProducer creates a new message producer
 func NewProducer(refData *models.ReferenceData, logger *slog.Logger) *Producer {
-	return &Producer{
-		refData:     refData,
-		rng:         rand.New(rand.NewSource(time.Now().UnixNano())),
-		vendorCodes: []string{"PRAGMATIC", "EVOLUTION", "NETENT", "MICROGAMING", "PLAYTECH", "EGT", "PLAYSON"},
+	masterAgentIDs := make([]int, 0, len(refData.AgentsByMasterID))
+	for k := range refData.AgentsByMasterID {
+		masterAgentIDs = append(masterAgentIDs, k)
+	}
+	sort.Ints(masterAgentIDs)
+
+	p := &Producer{
+		refData:        refData,
+		masterAgentIDs: masterAgentIDs,
+		vendorCodes:    []string{"PRAGMATIC", "EVOLUTION", "NETENT", "MICROGAMING", "PLAYTECH", "EGT", "PLAYSON"},
 		betAmounts: []decimal.Decimal{
 			decimal.NewFromFloat(10.0),
 			decimal.NewFromFloat(50.0),
@@ -41,9 +66,12 @@ func NewProducer(refData *models.ReferenceData, logger *slog.Logger) *Producer {
 			decimal.NewFromFloat(500.0),
 			decimal.NewFromFloat(1000.0),
 		},
+		betMinor:       []int64{10 * minorScale, 50 * minorScale, 100 * minorScale, 200 * minorScale, 500 * minorScale, 1000 * minorScale},
 		winMultipliers: []float64{0, 0, 0.5, 0.8, 1.0, 1.5, 2.0, 3.0, 5.0, 10.0}, // More losses than wins
 		logger:         logger,
 	}
+	p.streams = p.newStreams(-1)
+	return p
 }
 
 // LoadReferenceData loads all reference data from files
@@ -160,15 +188,43 @@ func loadGameCategories(path string) ([]models.GameCategory, error) {
 // GenerateSingle generates a single transaction
 func (p *Producer) GenerateSingle() *models.Transaction {
 	p.mu.Lock()
-	txn := p.generateTransaction(p.rng)
+	txn := p.generateTransaction(p.streams)
 	p.mu.Unlock()
 	return txn
 }
 
 // Generate produces transactions and sends them to the output channel
 func (p *Producer) Generate(ctx context.Context, count int, workers int, output chan<- *models.Transaction) error {
+	p.generate(ctx, count, workers, func(txn *models.Transaction) bool {
+		// A full channel must not outlive the run: once the sinks are gone
+		// nothing reads it
+		select {
+		case output <- txn:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+	close(output)
+	return nil
+}
+
+// GenerateRing produces transactions like Generate into a ring buffer,
+// closing it when done
+func (p *Producer) GenerateRing(ctx context.Context, count int, workers int, output *ring.Ring[*models.Transaction]) error {
+	p.generate(ctx, count, workers, func(txn *models.Transaction) bool {
+		return output.Put(ctx, txn) == nil
+	})
+	output.Close()
+	return nil
+}
+
+// generate splits count across workers, each passing its transactions to
+// emit until emit reports false or ctx is cancelled
+func (p *Producer) generate(ctx context.Context, count int, workers int, emit func(*models.Transaction) bool) {
 	var wg sync.WaitGroup
 	messagesPerWorker := count / workers
+	p.startWorkers(workers)
 
 	for i := 0; i < workers; i++ {
 		wg.Add(1)
@@ -178,68 +234,94 @@ func (p *Producer) Generate(ctx context.Context, count int, workers int, output
 			end = count // Last worker handles remainder
 		}
 
-		go func(start, end int) {
+		go func(worker, start, end int) {
 			defer wg.Done()
-			localRng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(start)))
+			generated, release := p.enterWorker(worker)
+			defer release()
+			rngs := p.newStreams(int64(start))
 			
 			for j := start; j < end; j++ {
 				select {
 				case <-ctx.Done():
 					return
 				default:
-					txn := p.generateTransaction(localRng)
-					output <- txn
+					if !emit(p.generateTransaction(rngs)) {
+						return
+					}
+					generated.Add(1)
 				}
 			}
-		}(start, end)
+		}(i, start, end)
 	}
 
 	wg.Wait()
-	close(output)
-	return nil
 }
 
-func (p *Producer) generateTransaction(rng *rand.Rand) *models.Transaction {
-	seq := p.sequence.Add(1)
-	now := time.Now()
-	
-	// Select random data
-	currency := p.refData.Currencies[rng.Intn(len(p.refData.Currencies))]
-	gameCategory := p.refData.GameCategories[rng.Intn(len(p.refData.GameCategories))]
-	
+func (p *Producer) generateTransaction(rngs *streams) *models.Transaction {
 	// Select master agent and then one of its agents
-	var agent models.Agent
-	masterAgentIDs := make([]int, 0, len(p.refData.AgentsByMasterID))
-	for k := range p.refData.AgentsByMasterID {
-		masterAgentIDs = append(masterAgentIDs, k)
+	var masterAgentID int
+	if p.houses != nil {
+		masterAgentID = p.houses.pickMaster(rngs.entity)
+	} else {
+		masterAgentID = p.masterAgentIDs[rngs.entity.Intn(len(p.masterAgentIDs))]
 	}
-	masterAgentID := masterAgentIDs[rng.Intn(len(masterAgentIDs))]
 	agents := p.refData.AgentsByMasterID[masterAgentID]
-	agent = agents[rng.Intn(len(agents))]
-	
-	vendorCode := p.vendorCodes[rng.Intn(len(p.vendorCodes))]
-	vendorID := rng.Intn(10) + 1
-	
-	// Generate bet amount based on currency
-	betAmount := p.betAmounts[rng.Intn(len(p.betAmounts))]
+	agent := agents[rngs.entity.Intn(len(agents))]
+
+	return p.buildTransaction(rngs, p.sequence.Add(1), agent)
+}
+
+// buildTransaction generates the transaction with sequence number seq for agent
+func (p *Producer) buildTransaction(rngs *streams, seq int64, agent models.Agent) *models.Transaction {
+	now := time.Now()
 	
-	// Adjust for currency (crypto gets smaller amounts, fiat gets larger)
-	if currency.Code == "BTC" {
-		betAmount = betAmount.Div(decimal.NewFromFloat(10000))
-	} else if currency.Code == "ETH" {
-		betAmount = betAmount.Div(decimal.NewFromFloat(1000))
-	} else if currency.Code == "JPY" {
-		betAmount = betAmount.Mul(decimal.NewFromFloat(100))
-	} else if currency.Code == "CNY" {
-		betAmount = betAmount.Mul(decimal.NewFromFloat(7))
+	// The agent's house limits the currencies it can bet in
+	houseID := 1
+	currencies := p.refData.Currencies
+	var allowed map[int]bool
+	if p.houses != nil {
+		h := p.houses.of(agent.MasterAgentID)
+		houseID = p.houses.houses[h].ID
+		if p.houses.allowed[h] != nil {
+			currencies, allowed = p.houses.currency[h], p.houses.allowed[h]
+		}
+	}
+
+	// A record template narrows the choices below
+	var tpl *template
+	if p.templates != nil {
+		tpl = p.templates.pick(rngs.template)
+		currencies, allowed = tpl.narrowCurrencies(currencies, allowed)
+	}
+
+	// Select random data
+	var currency models.Currency
+	var vendorCode string
+	var gameCategory models.GameCategory
+	settledAt := now
+	if p.profile != nil {
+		currency = p.profile.pickCurrency(rngs.currency, p.refData.Currencies, allowed)
+		var settled time.Duration
+		vendorCode, settled = p.profile.pickVendor(rngs.vendor, rngs.timing, p.vendorCodes, now.Sub(p.profile.start))
+		settledAt = p.profile.start.Add(settled)
+		gameCategory = p.profile.pickCategory(rngs.category, p.refData.GameCategories, settledAt)
+	} else {
+		currency = currencies[rngs.currency.Intn(len(currencies))]
+		vendorCode = p.vendorCodes[rngs.vendor.Intn(len(p.vendorCodes))]
+		gameCategory = p.refData.GameCategories[rngs.category.Intn(len(p.refData.GameCategories))]
+	}
+	if tpl != nil && len(tpl.vendors) > 0 {
+		vendorCode = tpl.vendors[rngs.vendor.Intn(len(tpl.vendors))]
+	}
+	if tpl != nil && len(tpl.categories) > 0 {
+		gameCategory = tpl.categories[rngs.category.Intn(len(tpl.categories))]
 	}
 	
-	// Generate win amount (weighted towards losses)
-	winMultiplier := p.winMultipliers[rng.Intn(len(p.winMultipliers))]
-	winAmount := betAmount.Mul(decimal.NewFromFloat(winMultiplier))
-	winLoss := winAmount.Sub(betAmount)
+	vendorID := rngs.vendor.Intn(10) + 1
 	
-	return &models.Transaction{
+	amounts := p.generateAmounts(rngs.amount, currency.Code, now, tpl)
+
+	txn := &models.Transaction{
 		ID:                    fmt.Sprintf("TXN-%s-%08d", now.Format("20060102"), seq),
 		ExternalTransactionID: fmt.Sprintf("EXT-%s-%08d", vendorCode, seq),
 		VendorBetID:           fmt.Sprintf("BET-%08d", seq),
@@ -248,14 +330,44 @@ func (p *Producer) generateTransaction(rng *rand.Rand) *models.Transaction {
 		VendorCode:            vendorCode,
 		VendorLineID:          1,
 		GameCategoryID:        gameCategory.ID,
-		HouseID:               1,
+		HouseID:               houseID,
 		MasterAgentID:         agent.MasterAgentID,
 		AgentID:               agent.ID,
 		CurrencyID:            currency.ID,
 		CurrencyCode:          currency.Code,
-		BetAmount:             betAmount.StringFixed(6),
-		WinAmount:             winAmount.StringFixed(6),
-		WinLoss:               winLoss.StringFixed(6),
-		SettledAt:             now.Format(time.RFC3339),
+		BetAmount:             amounts.bet,
+		WinAmount:             amounts.win,
+		WinLoss:               amounts.winLoss,
+		SettledAt:             settledAt.Format(time.RFC3339),
 	}
+	if p.behaviors != nil {
+		p.behaviors.apply(rngs.behavior, txn)
+	}
+	if p.regions != nil {
+		txn.Region = p.regions.pick(rngs.region)
+	}
+	if p.fields != nil {
+		p.fields.fill(rngs.field, seq, txn, now)
+	}
+	if tpl != nil && len(tpl.fields) > 0 {
+		if txn.Fields == nil {
+			txn.Fields = make(map[string]string, len(tpl.fields))
+		}
+		maps.Copy(txn.Fields, tpl.fields)
+	}
+	if p.tracer != nil {
+		txn.TraceID = p.tracer.id(txn, now)
+	}
+	if p.rates != nil {
+		if rate, ok := p.rates.RateAt(txn.CurrencyID, now); ok {
+			txn.RateID = rate.ID
+		}
+	}
+	if p.commissions != nil {
+		p.commissions.attribute(rngs.commission, txn)
+	}
+	if p.consistency != nil {
+		p.checkConsistency(seq, txn)
+	}
+	return txn
 }
@@ -0,0 +1,320 @@
+package generator
+
+import (
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Profile skews the generated data away from uniform choices and injects
+// time-windowed anomalies. Windows are measured from SetProfile.
+type Profile struct {
+	VendorWeights   map[string]float64 // unlisted vendors weigh 1
+	CurrencyWeights map[string]float64 // unlisted currencies weigh 1
+	VendorOutages   []VendorOutage
+	CurrencyShocks  []CurrencyShock
+	CategoryWindows []CategoryWindow
+	Location        *time.Location // for CategoryWindows times, nil = local
+}
+
+// VendorOutage removes a vendor from the mix for a window. Backfill is the
+// fraction of the bets the vendor missed that settle late: once the window
+// ends they are emitted in a burst, settled at times inside the window.
+type VendorOutage struct {
+	Vendor   string
+	Start    time.Duration
+	Duration time.Duration
+	Backfill float64
+}
+
+// CurrencyShock scales bet amounts in a currency from Start, ramping linearly
+// to Multiplier over Duration, with lognormal noise of Volatility
+type CurrencyShock struct {
+	Currency   string
+	Start      time.Duration
+	Duration   time.Duration
+	Multiplier float64
+	Volatility float64
+}
+
+// CategoryWindow opens a game category between two times of day of the
+// transaction timestamp, weighting it by Multiplier. A category with windows
+// is closed outside all of them; where its windows overlap the largest
+// multiplier applies.
+type CategoryWindow struct {
+	Category   string        // game category code
+	Open       time.Duration // since midnight; equal to Close for all day
+	Close      time.Duration // before Open wraps past midnight
+	Multiplier float64
+}
+
+// open reports whether the window covers time of day tod
+func (w CategoryWindow) open(tod time.Duration) bool {
+	switch {
+	case w.Open == w.Close:
+		return true
+	case w.Open < w.Close:
+		return tod >= w.Open && tod < w.Close
+	default:
+		return tod >= w.Open || tod < w.Close
+	}
+}
+
+// activeProfile is a Profile resolved against the producer's vendors and
+// currencies
+type activeProfile struct {
+	start           time.Time
+	vendorWeights   []float64 // aligned with Producer.vendorCodes
+	currencyWeights []float64 // aligned with ReferenceData.Currencies
+	outages         []VendorOutage
+	backlog         []atomic.Int64 // per outage: missed bets still to backfill
+	backfills       bool           // any outage backfills
+	shocks          []CurrencyShock
+	categoryWindows [][]CategoryWindow // aligned with ReferenceData.GameCategories, nil = always open
+	location        *time.Location
+}
+
+// SetProfile applies profile to subsequent transactions and starts its
+// anomaly clock. A nil profile restores uniform generation.
+func (p *Producer) SetProfile(profile *Profile) {
+	if profile == nil {
+		p.profile = nil
+		return
+	}
+
+	active := &activeProfile{
+		start:           time.Now(),
+		vendorWeights:   make([]float64, len(p.vendorCodes)),
+		currencyWeights: make([]float64, len(p.refData.Currencies)),
+		outages:         profile.VendorOutages,
+		backlog:         make([]atomic.Int64, len(profile.VendorOutages)),
+		shocks:          profile.CurrencyShocks,
+	}
+	for _, o := range profile.VendorOutages {
+		active.backfills = active.backfills || o.Backfill > 0
+	}
+	for i, code := range p.vendorCodes {
+		active.vendorWeights[i] = weightOf(profile.VendorWeights, code)
+	}
+	for i, currency := range p.refData.Currencies {
+		active.currencyWeights[i] = weightOf(profile.CurrencyWeights, currency.Code)
+	}
+	if len(profile.CategoryWindows) > 0 {
+		active.categoryWindows = make([][]CategoryWindow, len(p.refData.GameCategories))
+		for i, category := range p.refData.GameCategories {
+			for _, w := range profile.CategoryWindows {
+				if w.Category == category.Code {
+					active.categoryWindows[i] = append(active.categoryWindows[i], w)
+				}
+			}
+		}
+		active.location = profile.Location
+		if active.location == nil {
+			active.location = time.Local
+		}
+	}
+	p.profile = active
+}
+
+// SetSeed makes generation reproducible: the producer and every worker draw
+// from streams derived from seed. Zero keeps time-based seeding.
+func (p *Producer) SetSeed(seed int64) {
+	p.seed = seed
+	if seed != 0 {
+		p.streams = p.newStreams(-1)
+	}
+}
+
+func weightOf(weights map[string]float64, key string) float64 {
+	if w, ok := weights[key]; ok {
+		return w
+	}
+	return 1
+}
+
+// pickCurrency chooses a currency by weight, limited to the currency IDs in
+// allowed unless it is nil
+func (a *activeProfile) pickCurrency(rng *rand.Rand, currencies []models.Currency, allowed map[int]bool) models.Currency {
+	var skip func(int) bool
+	if allowed != nil {
+		skip = func(i int) bool { return !allowed[currencies[i].ID] }
+	}
+	return currencies[pickWeighted(rng, a.currencyWeights, skip)]
+}
+
+// pickVendor chooses a vendor by weight, skipping vendors in an outage, and
+// returns it with the elapsed time the bet settled at. Bets an outage turns
+// away are queued for its backfill and drained first once it has ended;
+// whether and when they settle is drawn from timing.
+func (a *activeProfile) pickVendor(rng, timing *rand.Rand, codes []string, elapsed time.Duration) (string, time.Duration) {
+	if a.backfills {
+		for i, o := range a.outages {
+			if elapsed >= o.Start+o.Duration && a.takeBacklog(i) {
+				return o.Vendor, o.Start + time.Duration(timing.Int63n(int64(o.Duration)))
+			}
+		}
+		// The vendor the bet would have gone to without the outages
+		if i := a.outageOf(codes[pickWeighted(rng, a.vendorWeights, nil)], elapsed); i >= 0 && timing.Float64() < a.outages[i].Backfill {
+			a.backlog[i].Add(1)
+		}
+	}
+	down := func(i int) bool {
+		return a.outageOf(codes[i], elapsed) >= 0
+	}
+	return codes[pickWeighted(rng, a.vendorWeights, down)], elapsed
+}
+
+// outageOf returns the index of the outage vendor code is in at elapsed, or
+// -1 when it is up
+func (a *activeProfile) outageOf(code string, elapsed time.Duration) int {
+	for i, o := range a.outages {
+		if o.Vendor == code && elapsed >= o.Start && elapsed < o.Start+o.Duration {
+			return i
+		}
+	}
+	return -1
+}
+
+// takeBacklog removes one missed bet from outage i's backlog, reporting
+// false when it is empty
+func (a *activeProfile) takeBacklog(i int) bool {
+	for {
+		n := a.backlog[i].Load()
+		if n == 0 {
+			return false
+		}
+		if a.backlog[i].CompareAndSwap(n, n-1) {
+			return true
+		}
+	}
+}
+
+// pickCategory chooses a game category, weighted by the windows open at the
+// time of day of now. When every category is closed it falls back to a
+// uniform choice.
+func (a *activeProfile) pickCategory(rng *rand.Rand, categories []models.GameCategory, now time.Time) models.GameCategory {
+	if a.categoryWindows == nil {
+		return categories[rng.Intn(len(categories))]
+	}
+	h, m, sec := now.In(a.location).Clock()
+	tod := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec)*time.Second
+
+	// Two passes over the windows rather than a weights slice per transaction
+	total := 0.0
+	for i := range categories {
+		total += a.categoryWeight(i, tod)
+	}
+	if total == 0 {
+		return categories[rng.Intn(len(categories))]
+	}
+	target := rng.Float64() * total
+	last := 0
+	for i := range categories {
+		w := a.categoryWeight(i, tod)
+		if w == 0 {
+			continue
+		}
+		if target < w {
+			return categories[i]
+		}
+		target -= w
+		last = i
+	}
+	return categories[last]
+}
+
+// categoryWeight is the weight of category i at time of day tod: 1 without
+// windows, otherwise the largest multiplier of its open windows, 0 if none
+func (a *activeProfile) categoryWeight(i int, tod time.Duration) float64 {
+	windows := a.categoryWindows[i]
+	if windows == nil {
+		return 1
+	}
+	weight := 0.0
+	for _, w := range windows {
+		if w.open(tod) {
+			weight = max(weight, w.Multiplier)
+		}
+	}
+	return weight
+}
+
+// shockAmount applies the currency shocks active at elapsed to amount
+func (a *activeProfile) shockAmount(rng *rand.Rand, code string, amount decimal.Decimal, elapsed time.Duration) decimal.Decimal {
+	for _, s := range a.shocks {
+		if factor, ok := s.factor(rng, code, elapsed); ok {
+			amount = amount.Mul(decimal.NewFromFloat(factor))
+		}
+	}
+	return amount
+}
+
+// shockMinor applies the currency shocks active at elapsed to an amount in
+// minor units, rounding to the nearest unit after each shock
+func (a *activeProfile) shockMinor(rng *rand.Rand, code string, amount int64, elapsed time.Duration) int64 {
+	for _, s := range a.shocks {
+		if factor, ok := s.factor(rng, code, elapsed); ok {
+			amount = int64(math.Round(float64(amount) * factor))
+		}
+	}
+	return amount
+}
+
+// factor returns the multiplier the shock applies to code at elapsed, and
+// false when it does not apply
+func (s CurrencyShock) factor(rng *rand.Rand, code string, elapsed time.Duration) (float64, bool) {
+	if s.Currency != code || elapsed < s.Start {
+		return 0, false
+	}
+	factor := s.Multiplier
+	if s.Duration > 0 && elapsed < s.Start+s.Duration {
+		progress := float64(elapsed-s.Start) / float64(s.Duration)
+		factor = 1 + (s.Multiplier-1)*progress
+	}
+	if s.Volatility > 0 {
+		factor *= math.Exp(s.Volatility * rng.NormFloat64())
+	}
+	return factor, true
+}
+
+// pickWeighted returns an index with probability proportional to its weight,
+// ignoring indexes excluded by skip. When every weight is zero it falls back
+// to a uniform choice among the remaining indexes.
+func pickWeighted(rng *rand.Rand, weights []float64, skip func(int) bool) int {
+	total := 0.0
+	for i, w := range weights {
+		if skip == nil || !skip(i) {
+			total += w
+		}
+	}
+	if total == 0 {
+		candidates := make([]int, 0, len(weights))
+		for i := range weights {
+			if skip == nil || !skip(i) {
+				candidates = append(candidates, i)
+			}
+		}
+		if len(candidates) == 0 {
+			return rng.Intn(len(weights))
+		}
+		return candidates[rng.Intn(len(candidates))]
+	}
+
+	target := rng.Float64() * total
+	last := 0
+	for i, w := range weights {
+		if skip != nil && skip(i) || w == 0 {
+			continue
+		}
+		if target < w {
+			return i
+		}
+		target -= w
+		last = i
+	}
+	return last
+}
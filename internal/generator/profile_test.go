@@ -0,0 +1,80 @@
+package generator
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func TestVendorOutageBackfill(t *testing.T) {
+	p := testProducer()
+	p.SetProfile(&Profile{VendorOutages: []VendorOutage{
+		{Vendor: p.vendorCodes[0], Start: time.Minute, Duration: time.Minute, Backfill: 1},
+	}})
+	a := p.profile
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 10000; i++ {
+		code, settled := a.pickVendor(rng, rng, p.vendorCodes, 90*time.Second)
+		if code == p.vendorCodes[0] {
+			t.Fatalf("%s picked during its outage", code)
+		}
+		if settled != 90*time.Second {
+			t.Fatalf("on-time bet settled at %v", settled)
+		}
+	}
+	missed := int(a.backlog[0].Load())
+	if missed == 0 {
+		t.Fatal("outage queued no backfill")
+	}
+
+	for i := 0; i < missed; i++ {
+		code, settled := a.pickVendor(rng, rng, p.vendorCodes, 3*time.Minute)
+		if code != p.vendorCodes[0] || settled < time.Minute || settled >= 2*time.Minute {
+			t.Fatalf("backfill %d = %s settled at %v", i, code, settled)
+		}
+	}
+	if _, settled := a.pickVendor(rng, rng, p.vendorCodes, 3*time.Minute); settled != 3*time.Minute {
+		t.Errorf("bet after the backfill settled at %v", settled)
+	}
+}
+
+func TestCategoryWindows(t *testing.T) {
+	p := testProducer()
+	p.refData.GameCategories = []models.GameCategory{
+		{ID: 1, Code: "SLOTS"}, {ID: 2, Code: "LIVE_CASINO"}, {ID: 6, Code: "SPORT"},
+	}
+	p.SetProfile(&Profile{
+		Location: time.UTC,
+		CategoryWindows: []CategoryWindow{
+			{Category: "LIVE_CASINO", Open: 18 * time.Hour, Close: 2 * time.Hour, Multiplier: 2},
+			{Category: "SPORT", Open: 0, Close: 0, Multiplier: 1},
+			{Category: "SPORT", Open: 20 * time.Hour, Close: 22 * time.Hour, Multiplier: 5},
+		},
+	})
+	rng := rand.New(rand.NewSource(1))
+
+	for _, tc := range []struct {
+		clock string
+		want  map[int]float64 // category ID -> expected share
+	}{
+		{"12:00", map[int]float64{1: 0.5, 2: 0, 6: 0.5}},
+		{"01:30", map[int]float64{1: 0.25, 2: 0.5, 6: 0.25}},
+		{"21:00", map[int]float64{1: 0.125, 2: 0.25, 6: 0.625}},
+	} {
+		now, _ := time.Parse("2006-01-02 15:04", "2024-01-01 "+tc.clock)
+		counts := map[int]int{}
+		total := 20000
+		for i := 0; i < total; i++ {
+			counts[p.profile.pickCategory(rng, p.refData.GameCategories, now).ID]++
+		}
+		for id, want := range tc.want {
+			if got := float64(counts[id]) / float64(total); math.Abs(got-want) > 0.02 {
+				t.Errorf("%s: category %d share = %.3f, want %.3f", tc.clock, id, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// RateWalk evolves the reference currency rates as a geometric random walk.
+// Rates step on whole-second boundaries every interval from the walk's start,
+// so a transaction's settled_at (second precision) identifies the rate it
+// references. Each new rate gets a fresh ID and is passed to emit.
+type RateWalk struct {
+	mu         sync.Mutex
+	start      int64 // unix seconds of step 0
+	interval   int64 // seconds per step
+	volatility float64
+	rng        *rand.Rand
+	rates      []models.CurrencyRate // current rate of each pair
+	levels     []float64             // unrounded walk state per pair
+	byCurrency map[int]int           // currency ID -> index of the pair it references
+	nextID     int
+	step       int64
+	emit       func(models.CurrencyRate)
+}
+
+// NewRateWalk starts a walk over rates at start. Step 0 emits the reference
+// rates unchanged; each later step multiplies every pair by
+// exp(volatility * N(0,1)). A currency references the first pair quoted from
+// it. seed 0 draws from the clock.
+func NewRateWalk(rates []models.CurrencyRate, interval time.Duration, volatility float64, seed int64, start time.Time, emit func(models.CurrencyRate)) *RateWalk {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	w := &RateWalk{
+		start:      start.Unix(),
+		interval:   max(int64(interval/time.Second), 1),
+		volatility: volatility,
+		rng:        rand.New(rand.NewSource(seed)),
+		rates:      make([]models.CurrencyRate, len(rates)),
+		levels:     make([]float64, len(rates)),
+		byCurrency: make(map[int]int),
+		emit:       emit,
+	}
+	copy(w.rates, rates)
+	for i, r := range rates {
+		w.levels[i] = r.Rate.InexactFloat64()
+		w.nextID = max(w.nextID, r.ID+1)
+		if _, ok := w.byCurrency[r.CurrencyFromID]; !ok {
+			w.byCurrency[r.CurrencyFromID] = i
+		}
+		if emit != nil {
+			emit(r)
+		}
+	}
+	return w
+}
+
+// SetRateWalk makes every transaction reference the rate walk's rate for its
+// currency at its settled time. Nil stops referencing rates.
+func (p *Producer) SetRateWalk(w *RateWalk) {
+	p.rates = w
+}
+
+// RateAt returns the rate currencyID references at t, stepping the walk
+// forward to t first. ok is false when no pair is quoted from the currency.
+func (w *RateWalk) RateAt(currencyID int, t time.Time) (rate models.CurrencyRate, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.advance(t)
+	i, ok := w.byCurrency[currencyID]
+	if !ok {
+		return models.CurrencyRate{}, false
+	}
+	return w.rates[i], true
+}
+
+// Run steps the walk on its interval until ctx is cancelled, so the rate
+// stream keeps moving while few transactions are generated
+func (w *RateWalk) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(w.interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.mu.Lock()
+			w.advance(now)
+			w.mu.Unlock()
+		}
+	}
+}
+
+// advance takes every step due by t. Callers hold mu.
+func (w *RateWalk) advance(t time.Time) {
+	target := (t.Unix() - w.start) / w.interval
+	for w.step < target {
+		w.step++
+		effective := w.start + w.step*w.interval
+		for i := range w.rates {
+			w.levels[i] *= math.Exp(w.volatility * w.rng.NormFloat64())
+			r := w.rates[i]
+			r.ID = w.nextID
+			r.Rate = decimal.NewFromFloat(w.levels[i]).Round(8)
+			r.EffectiveFrom = effective
+			w.nextID++
+			w.rates[i] = r
+			if w.emit != nil {
+				w.emit(r)
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/supratick/message_producer/internal/models"
+)
+
+var testRates = []models.CurrencyRate{
+	{ID: 1, CurrencyFrom: "CNY", CurrencyFromID: 2, CurrencyTo: "USDT", CurrencyToID: 1, Rate: decimal.RequireFromString("0.1429")},
+	{ID: 2, CurrencyFrom: "USD", CurrencyFromID: 3, CurrencyTo: "USDT", CurrencyToID: 1, Rate: decimal.RequireFromString("1")},
+	{ID: 3, CurrencyFrom: "CNY", CurrencyFromID: 2, CurrencyTo: "USD", CurrencyToID: 3, Rate: decimal.RequireFromString("0.14")},
+}
+
+func TestRateWalkSteps(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	var emitted []models.CurrencyRate
+	w := NewRateWalk(testRates, 10*time.Second, 0.01, 1, start, func(r models.CurrencyRate) {
+		emitted = append(emitted, r)
+	})
+	if len(emitted) != len(testRates) {
+		t.Fatalf("emitted %d initial rates, want %d", len(emitted), len(testRates))
+	}
+
+	// Within the first interval the reference rate stays in effect
+	rate, ok := w.RateAt(2, start.Add(9*time.Second))
+	if !ok || rate.ID != 1 {
+		t.Fatalf("rate at 9s = %+v, %v; want reference rate 1", rate, ok)
+	}
+
+	// 25s in, two steps have been taken
+	rate, _ = w.RateAt(2, start.Add(25*time.Second))
+	if len(emitted) != 3*len(testRates) {
+		t.Fatalf("emitted %d rates after two steps, want %d", len(emitted), 3*len(testRates))
+	}
+	if rate.EffectiveFrom != start.Unix()+20 || rate.CurrencyFrom != "CNY" || rate.CurrencyTo != "USDT" {
+		t.Errorf("rate at 25s = %+v, want CNY/USDT effective at 20s", rate)
+	}
+	seen := map[int]bool{}
+	for _, r := range emitted {
+		if seen[r.ID] {
+			t.Fatalf("rate ID %d emitted twice", r.ID)
+		}
+		seen[r.ID] = true
+	}
+	if rate.Rate.Equal(testRates[0].Rate) {
+		t.Error("rate did not move")
+	}
+
+	// Time going backwards never steps back
+	if earlier, _ := w.RateAt(2, start); earlier.ID != rate.ID {
+		t.Errorf("rate at start after stepping = %d, want %d", earlier.ID, rate.ID)
+	}
+	if _, ok := w.RateAt(99, start); ok {
+		t.Error("rate found for a currency without pairs")
+	}
+}
+
+func TestRateWalkSeeded(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	a := NewRateWalk(testRates, time.Second, 0.05, 9, start, nil)
+	b := NewRateWalk(testRates, time.Second, 0.05, 9, start, nil)
+	ra, _ := a.RateAt(3, start.Add(100*time.Second))
+	rb, _ := b.RateAt(3, start.Add(100*time.Second))
+	if ra.ID != rb.ID || !ra.Rate.Equal(rb.Rate) {
+		t.Errorf("seeded walks diverged: %+v vs %+v", ra, rb)
+	}
+}
+
+func TestTransactionsReferenceRates(t *testing.T) {
+	p := testProducer()
+	p.refData.Currencies = []models.Currency{{ID: 2, Code: "CNY"}, {ID: 3, Code: "USD"}, {ID: 4, Code: "EUR"}}
+	rates := map[int]models.CurrencyRate{}
+	p.SetRateWalk(NewRateWalk(testRates, time.Second, 0.01, 1, time.Now(), func(r models.CurrencyRate) {
+		rates[r.ID] = r
+	}))
+	p.SetSeed(3)
+
+	rngs := p.newStreams(0)
+	for i := 0; i < 200; i++ {
+		txn := p.generateTransaction(rngs)
+		if txn.CurrencyCode == "EUR" {
+			if txn.RateID != 0 {
+				t.Errorf("EUR transaction references rate %d", txn.RateID)
+			}
+			continue
+		}
+		rate, ok := rates[txn.RateID]
+		if !ok || rate.CurrencyFromID != txn.CurrencyID {
+			t.Fatalf("%s transaction references rate %d (%+v)", txn.CurrencyCode, txn.RateID, rate)
+		}
+		settled, _ := time.Parse(time.RFC3339, txn.SettledAt)
+		if rate.EffectiveFrom > settled.Unix() {
+			t.Fatalf("rate %d effective at %d, after settlement %s", rate.ID, rate.EffectiveFrom, txn.SettledAt)
+		}
+	}
+}
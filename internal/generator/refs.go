@@ -0,0 +1,132 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// defaultRefPool is how many emitted values a referenced source remembers
+const defaultRefPool = 10000
+
+// refColumns are the transaction columns a field can reference
+var refColumns = map[string]func(*models.Transaction) string{
+	"id":                      func(t *models.Transaction) string { return t.ID },
+	"external_transaction_id": func(t *models.Transaction) string { return t.ExternalTransactionID },
+	"vendor_bet_id":           func(t *models.Transaction) string { return t.VendorBetID },
+	"round_id":                func(t *models.Transaction) string { return t.RoundID },
+}
+
+// refPool remembers the latest values emitted for a referenced source, so
+// referencing fields only ever carry keys a downstream join can find
+type refPool struct {
+	mu     sync.Mutex
+	values []string
+	times  []time.Time
+	head   int // index of the oldest entry once the ring is full
+	full   bool
+}
+
+func newRefPool(size int) *refPool {
+	return &refPool{values: make([]string, 0, size), times: make([]time.Time, 0, size)}
+}
+
+// add remembers value, emitted at now, dropping the oldest beyond the pool size
+func (r *refPool) add(value string, now time.Time) {
+	if value == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		r.values = append(r.values, value)
+		r.times = append(r.times, now)
+		r.full = len(r.values) == cap(r.values)
+		return
+	}
+	r.values[r.head], r.times[r.head] = value, now
+	r.head = (r.head + 1) % len(r.values)
+}
+
+// pick returns a remembered value emitted within window before now, any
+// remembered value when window is zero, and false when there is none
+func (r *refPool) pick(rng *rand.Rand, now time.Time, window time.Duration) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := len(r.values)
+	at := func(i int) int { return (r.head + i) % n } // i-th oldest
+	first := 0
+	if window > 0 {
+		// Values arrive in about time order, so the window is a suffix
+		cutoff := now.Add(-window)
+		first = sort.Search(n, func(i int) bool { return !r.times[at(i)].Before(cutoff) })
+	}
+	if first >= n {
+		return "", false
+	}
+	return r.values[at(first+rng.Intn(n-first))], true
+}
+
+// refField is a field that references previously emitted values of a source
+type refField struct {
+	pool   *refPool
+	window time.Duration
+}
+
+// addRefs resolves the reference fields of s once all its fields are known.
+// A source referenced by several fields keeps the largest pool asked for.
+func (s *fieldSet) addRefs() error {
+	names := make(map[string]bool, len(s.fields))
+	for _, f := range s.fields {
+		names[f.Name] = true
+	}
+	sizes := make(map[string]int)
+	for _, f := range s.fields {
+		if f.References == "" {
+			continue
+		}
+		_, column := refColumns[f.References]
+		if !column && (!names[f.References] || f.References == f.Name) {
+			return fmt.Errorf("field %s: references unknown source %q", f.Name, f.References)
+		}
+		if f.Window < 0 || f.Pool < 0 {
+			return fmt.Errorf("field %s: window and pool must be non-negative", f.Name)
+		}
+		size := f.Pool
+		if size == 0 {
+			size = defaultRefPool
+		}
+		sizes[f.References] = max(sizes[f.References], size)
+	}
+	if len(sizes) == 0 {
+		return nil
+	}
+
+	s.pools = make(map[string]*refPool, len(sizes))
+	for source, size := range sizes {
+		s.pools[source] = newRefPool(size)
+	}
+	s.refs = make([]*refField, len(s.fields))
+	for i, f := range s.fields {
+		if f.References != "" {
+			s.refs[i] = &refField{pool: s.pools[f.References], window: f.Window}
+		}
+	}
+	return nil
+}
+
+// remember records txn's values of every referenced source, after its own
+// references have been filled
+func (s *fieldSet) remember(txn *models.Transaction, now time.Time) {
+	for source, pool := range s.pools {
+		if column, ok := refColumns[source]; ok {
+			pool.add(column(txn), now)
+			continue
+		}
+		pool.add(txn.Fields[source], now)
+	}
+}
@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestReferenceFields(t *testing.T) {
+	p := testProducer()
+	err := p.SetFields([]Field{
+		{Name: "session_id", Generator: GenUUID},
+		{Name: "parent_id", References: "id", Pool: 5},
+		{Name: "previous_session", References: "session_id"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rngs := p.newStreams(0)
+	ids := map[string]int{}
+	sessions := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		txn := p.generateTransaction(rngs)
+		parent, ok := txn.Fields["parent_id"]
+		if i == 0 {
+			if ok {
+				t.Errorf("first transaction references %s before anything was emitted", parent)
+			}
+		} else {
+			at, seen := ids[parent]
+			if !seen {
+				t.Fatalf("transaction %d references unemitted id %q", i, parent)
+			}
+			if i-at > 5 {
+				t.Errorf("transaction %d references id from %d, outside the pool of 5", i, at)
+			}
+			if !sessions[txn.Fields["previous_session"]] {
+				t.Errorf("transaction %d references unemitted session %q", i, txn.Fields["previous_session"])
+			}
+		}
+		ids[txn.ID] = i
+		sessions[txn.Fields["session_id"]] = true
+	}
+
+	for _, fields := range [][]Field{
+		{{Name: "a", References: "b"}},
+		{{Name: "a", References: "a"}},
+		{{Name: "a", References: "id", Window: -time.Second}},
+		{{Name: "a", References: "id", Generator: GenUUID}},
+	} {
+		if err := p.SetFields(fields); err == nil {
+			t.Errorf("fields %+v accepted", fields)
+		}
+	}
+}
+
+func TestRefPoolWindow(t *testing.T) {
+	pool := newRefPool(3)
+	start := time.Unix(1000, 0)
+	for i, v := range []string{"a", "b", "c", "d"} {
+		pool.add(v, start.Add(time.Duration(i)*time.Second))
+	}
+	rng := rand.New(rand.NewSource(1))
+	now := start.Add(4 * time.Second)
+
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		v, _ := pool.pick(rng, now, 0)
+		seen[v] = true
+	}
+	if len(seen) != 3 || seen["a"] {
+		t.Errorf("unwindowed picks %v, want b, c and d", seen)
+	}
+	for i := 0; i < 100; i++ {
+		if v, _ := pool.pick(rng, now, 1500*time.Millisecond); v != "d" {
+			t.Fatalf("windowed pick %s, want d", v)
+		}
+	}
+	if v, ok := pool.pick(rng, now.Add(time.Minute), time.Second); ok {
+		t.Errorf("stale pool gave %s", v)
+	}
+}
@@ -0,0 +1,36 @@
+package generator
+
+import (
+	"fmt"
+	"maps"
+	"math/rand"
+	"slices"
+)
+
+// regionPicker tags transactions with an origin region by weight
+type regionPicker struct {
+	names   []string // sorted so seeded runs pick the same regions
+	weights []float64
+}
+
+// SetRegions tags every subsequent transaction with one of the regions,
+// chosen in proportion to its weight. An empty map stops tagging.
+func (p *Producer) SetRegions(weights map[string]float64) error {
+	if len(weights) == 0 {
+		p.regions = nil
+		return nil
+	}
+	r := &regionPicker{names: slices.Sorted(maps.Keys(weights))}
+	for _, name := range r.names {
+		if weights[name] < 0 {
+			return fmt.Errorf("region %s has a negative weight", name)
+		}
+		r.weights = append(r.weights, weights[name])
+	}
+	p.regions = r
+	return nil
+}
+
+func (r *regionPicker) pick(rng *rand.Rand) string {
+	return r.names[pickWeighted(rng, r.weights, nil)]
+}
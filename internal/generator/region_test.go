@@ -0,0 +1,38 @@
+package generator
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRegionWeights(t *testing.T) {
+	p := testProducer()
+	p.SetSeed(11)
+	if err := p.SetRegions(map[string]float64{"us-east": 3, "eu-west": 1, "ap-south": 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	rngs := p.newStreams(0)
+	counts := map[string]int{}
+	const total = 20000
+	for i := 0; i < total; i++ {
+		counts[p.generateTransaction(rngs).Region]++
+	}
+	if counts["ap-south"] != 0 {
+		t.Errorf("zero-weight region tagged %d times", counts["ap-south"])
+	}
+	if share := float64(counts["us-east"]) / total; math.Abs(share-0.75) > 0.02 {
+		t.Errorf("us-east share %.3f, want about 0.75", share)
+	}
+	if counts["us-east"]+counts["eu-west"] != total {
+		t.Errorf("regions %v do not cover %d transactions", counts, total)
+	}
+
+	if err := p.SetRegions(map[string]float64{"us-east": -1}); err == nil {
+		t.Error("negative weight accepted")
+	}
+	p.SetRegions(nil)
+	if region := p.generateTransaction(rngs).Region; region != "" {
+		t.Errorf("untagged transaction has region %q", region)
+	}
+}
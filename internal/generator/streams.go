@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Random stream aspects, in the order streams are derived from the seed. New
+// aspects go at the end so existing streams keep their seeds.
+const (
+	streamEntity     = iota // master agent and agent
+	streamTemplate          // record template
+	streamCurrency          // currency
+	streamVendor            // vendor code and vendor ID
+	streamCategory          // game category
+	streamTiming            // settle times of backfilled outage bets
+	streamAmount            // bet and win amounts, currency shocks
+	streamBehavior          // player behavior sessions
+	streamRegion            // region tags
+	streamField             // custom fields
+	streamCommission        // sub-agent attribution
+	streamCount
+)
+
+// streams are the random sources of one generation worker. Every aspect of a
+// transaction draws from its own generator, so reconfiguring one
+// distribution (another bet range, a template, a new custom field) leaves
+// the values of the others unchanged in runs with the same seed.
+type streams struct {
+	entity     *rand.Rand
+	template   *rand.Rand
+	currency   *rand.Rand
+	vendor     *rand.Rand
+	category   *rand.Rand
+	timing     *rand.Rand
+	amount     *rand.Rand
+	behavior   *rand.Rand
+	region     *rand.Rand
+	field      *rand.Rand
+	commission *rand.Rand
+}
+
+// newStreams returns the random streams of a worker, derived from the seed
+// when one is set
+func (p *Producer) newStreams(worker int64) *streams {
+	base := time.Now().UnixNano() + worker
+	if p.seed != 0 {
+		base = p.seed + worker + 1
+	}
+	var rngs [streamCount]*rand.Rand
+	for aspect := range rngs {
+		rngs[aspect] = rand.New(rand.NewSource(streamSeed(base, aspect)))
+	}
+	return &streams{
+		entity:     rngs[streamEntity],
+		template:   rngs[streamTemplate],
+		currency:   rngs[streamCurrency],
+		vendor:     rngs[streamVendor],
+		category:   rngs[streamCategory],
+		timing:     rngs[streamTiming],
+		amount:     rngs[streamAmount],
+		behavior:   rngs[streamBehavior],
+		region:     rngs[streamRegion],
+		field:      rngs[streamField],
+		commission: rngs[streamCommission],
+	}
+}
+
+// streamSeed mixes the worker's base seed with the aspect (splitmix64), so
+// neighbouring workers and aspects get unrelated sequences
+func streamSeed(base int64, aspect int) int64 {
+	z := uint64(base) + uint64(aspect+1)*0x9e3779b97f4a7c15
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return int64(z ^ z>>31)
+}
@@ -0,0 +1,48 @@
+package generator
+
+import "testing"
+
+// Reconfiguring amounts and adding regions must not change the other fields
+// of a seeded run
+func TestStreamsIndependent(t *testing.T) {
+	base := testProducer()
+	base.SetSeed(7)
+	changed := testProducer()
+	changed.SetSeed(7)
+	changed.betAmounts = changed.betAmounts[:2]
+	changed.winMultipliers = []float64{2}
+	if err := changed.SetRegions(map[string]float64{"eu": 1, "us": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := base.newStreams(0), changed.newStreams(0)
+	amountsDiffer := false
+	for i := 0; i < 1000; i++ {
+		want, got := base.generateTransaction(a), changed.generateTransaction(b)
+		if got.AgentID != want.AgentID || got.CurrencyCode != want.CurrencyCode || got.VendorCode != want.VendorCode ||
+			got.VendorID != want.VendorID || got.GameCategoryID != want.GameCategoryID {
+			t.Fatalf("transaction %d: got %+v, want %+v", i, got, want)
+		}
+		if got.WinAmount != want.WinAmount {
+			amountsDiffer = true
+		}
+	}
+	if !amountsDiffer {
+		t.Error("amounts did not change with the bet configuration")
+	}
+}
+
+func TestStreamsSeeded(t *testing.T) {
+	p := testProducer()
+	p.SetSeed(7)
+	a, b := p.newStreams(0), p.newStreams(0)
+	if a.amount.Int63() != b.amount.Int63() {
+		t.Error("streams of the same worker differ")
+	}
+	if a.entity.Int63() == a.amount.Int63() {
+		t.Error("aspects share a sequence")
+	}
+	if p.newStreams(1).amount.Int63() == p.newStreams(0).amount.Int63() {
+		t.Error("workers share a sequence")
+	}
+}
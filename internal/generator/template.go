@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Template constrains the records generated from it, e.g. small slot bets or
+// big live-casino bets. Constraints left empty keep the usual choice.
+type Template struct {
+	Name       string
+	Weight     float64  // share of records relative to the other templates
+	Categories []string // game category codes
+	Vendors    []string // vendor codes
+	Currencies []string // currency codes; those a house does not allow are skipped
+	// BetMin and BetMax bound the base bet, drawn uniformly to the cent and
+	// scaled per currency like the usual bet sizes; both zero keep those
+	BetMin, BetMax float64
+	// WinMultipliers are the outcomes, picked uniformly, at most two decimals
+	WinMultipliers []float64
+	Fields         map[string]string // constant fields, e.g. bet_type: parlay
+}
+
+// template is a Template resolved against the reference data
+type template struct {
+	name       string
+	categories []models.GameCategory
+	vendors    []string
+	currencies map[int]bool // nil allows all
+	betCents   [2]int64     // base bet range in hundredths; zero = usual sizes
+	wins       []float64
+	fields     map[string]string
+}
+
+// templateSet picks a template per record by weight
+type templateSet struct {
+	templates []*template
+	weights   []float64
+}
+
+// SetTemplates makes every subsequent record follow one of the templates,
+// chosen by weight. No templates restores unconstrained records.
+func (p *Producer) SetTemplates(templates []Template) error {
+	if len(templates) == 0 {
+		p.templates = nil
+		return nil
+	}
+	categoryByCode := make(map[string]models.GameCategory, len(p.refData.GameCategories))
+	for _, c := range p.refData.GameCategories {
+		categoryByCode[c.Code] = c
+	}
+	currencyByCode := make(map[string]int, len(p.refData.Currencies))
+	for _, c := range p.refData.Currencies {
+		currencyByCode[c.Code] = c.ID
+	}
+
+	s := &templateSet{}
+	for _, t := range templates {
+		if t.Name == "" {
+			return fmt.Errorf("template name is required")
+		}
+		if t.Weight < 0 {
+			return fmt.Errorf("template %s: weight must be non-negative", t.Name)
+		}
+		tpl := &template{name: t.Name, vendors: t.Vendors, wins: t.WinMultipliers, fields: maps.Clone(t.Fields)}
+		for _, code := range t.Categories {
+			c, ok := categoryByCode[code]
+			if !ok {
+				return fmt.Errorf("template %s: unknown game category %s", t.Name, code)
+			}
+			tpl.categories = append(tpl.categories, c)
+		}
+		if len(t.Currencies) > 0 {
+			tpl.currencies = make(map[int]bool, len(t.Currencies))
+			for _, code := range t.Currencies {
+				id, ok := currencyByCode[code]
+				if !ok {
+					return fmt.Errorf("template %s: unknown currency %s", t.Name, code)
+				}
+				tpl.currencies[id] = true
+			}
+		}
+		if t.BetMin != 0 || t.BetMax != 0 {
+			if t.BetMin <= 0 || t.BetMax < t.BetMin {
+				return fmt.Errorf("template %s: bet range must be positive with bet_min <= bet_max", t.Name)
+			}
+			tpl.betCents = [2]int64{int64(math.Round(t.BetMin * 100)), int64(math.Round(t.BetMax * 100))}
+		}
+		for _, m := range t.WinMultipliers {
+			if m < 0 || math.Abs(m*100-math.Round(m*100)) > 1e-9 {
+				return fmt.Errorf("template %s: win multipliers must be non-negative with at most two decimals", t.Name)
+			}
+		}
+		s.templates = append(s.templates, tpl)
+		s.weights = append(s.weights, t.Weight)
+	}
+	p.templates = s
+	return nil
+}
+
+func (s *templateSet) pick(rng *rand.Rand) *template {
+	return s.templates[pickWeighted(rng, s.weights, nil)]
+}
+
+// narrowCurrencies limits currencies and the matching allowed IDs to the
+// template's, keeping the house's choice when none of the template's are
+// allowed there
+func (t *template) narrowCurrencies(currencies []models.Currency, allowed map[int]bool) ([]models.Currency, map[int]bool) {
+	if t.currencies == nil {
+		return currencies, allowed
+	}
+	var out []models.Currency
+	ids := make(map[int]bool, len(t.currencies))
+	for _, c := range currencies {
+		if t.currencies[c.ID] {
+			out = append(out, c)
+			ids[c.ID] = true
+		}
+	}
+	if len(out) == 0 {
+		return currencies, allowed
+	}
+	return out, ids
+}
+
+// baseBet draws a base bet in hundredths from the template's range
+func (t *template) baseBet(rng *rand.Rand) int64 {
+	return t.betCents[0] + rng.Int63n(t.betCents[1]-t.betCents[0]+1)
+}
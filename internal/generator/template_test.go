@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func templateProducer() *Producer {
+	p := testProducer()
+	p.refData.GameCategories = []models.GameCategory{{ID: 1, Code: "SLOT"}, {ID: 2, Code: "LIVE"}, {ID: 3, Code: "SPORT"}}
+	return p
+}
+
+var testTemplates = []Template{
+	{Name: "small_slot", Weight: 3, Categories: []string{"SLOT"}, BetMin: 0.2, BetMax: 5, WinMultipliers: []float64{0, 0, 1.5}},
+	{Name: "big_live", Weight: 1, Categories: []string{"LIVE"}, Vendors: []string{"EVOLUTION"}, Currencies: []string{"USD"},
+		BetMin: 500, BetMax: 5000, Fields: map[string]string{"table": "vip"}},
+}
+
+func TestTemplates(t *testing.T) {
+	p := templateProducer()
+	p.SetSeed(3)
+	if err := p.SetTemplates(testTemplates); err != nil {
+		t.Fatal(err)
+	}
+
+	rngs := p.newStreams(0)
+	slots := 0
+	const total = 10000
+	for i := 0; i < total; i++ {
+		txn := p.generateTransaction(rngs)
+		bet := decimal.RequireFromString(txn.BetAmount)
+		switch txn.GameCategoryID {
+		case 1:
+			slots++
+			// BTC and ETH scale the base bet down, JPY and CNY up
+			if txn.CurrencyCode == "USD" && (bet.LessThan(decimal.New(2, -1)) || bet.GreaterThan(decimal.New(5, 0))) {
+				t.Fatalf("small_slot bet %s outside 0.2-5", bet)
+			}
+			win := decimal.RequireFromString(txn.WinAmount)
+			if txn.CurrencyCode == "USD" && !win.IsZero() && !win.Equal(bet.Mul(decimal.NewFromFloat(1.5))) {
+				t.Fatalf("small_slot win %s of bet %s not an allowed outcome", win, bet)
+			}
+		case 2:
+			if txn.VendorCode != "EVOLUTION" || txn.CurrencyCode != "USD" || txn.Fields["table"] != "vip" {
+				t.Fatalf("big_live record %+v", txn)
+			}
+			if bet.LessThan(decimal.New(500, 0)) || bet.GreaterThan(decimal.New(5000, 0)) {
+				t.Fatalf("big_live bet %s outside 500-5000", bet)
+			}
+		default:
+			t.Fatalf("record in category %d outside every template", txn.GameCategoryID)
+		}
+	}
+	if share := float64(slots) / total; math.Abs(share-0.75) > 0.02 {
+		t.Errorf("small_slot share %.3f, want about 0.75", share)
+	}
+
+	for _, bad := range []Template{
+		{Name: "x", Categories: []string{"POKER"}},
+		{Name: "x", Currencies: []string{"XYZ"}},
+		{Name: "x", BetMin: 10, BetMax: 1},
+		{Name: "x", WinMultipliers: []float64{1.234}},
+		{Name: "x", Weight: -1},
+	} {
+		if err := p.SetTemplates([]Template{bad}); err == nil {
+			t.Errorf("template %+v accepted", bad)
+		}
+	}
+}
+
+// Fixed-point and decimal amounts agree within templates too
+func TestTemplateFixedPoint(t *testing.T) {
+	decimalProducer, fixedProducer := templateProducer(), templateProducer()
+	fixedProducer.SetFixedPoint(true)
+	for _, p := range []*Producer{decimalProducer, fixedProducer} {
+		p.SetSeed(9)
+		if err := p.SetTemplates(testTemplates); err != nil {
+			t.Fatal(err)
+		}
+	}
+	decimalRngs, fixedRngs := decimalProducer.newStreams(0), fixedProducer.newStreams(0)
+	for i := 0; i < 5000; i++ {
+		want := decimalProducer.generateTransaction(decimalRngs)
+		got := fixedProducer.generateTransaction(fixedRngs)
+		if got.BetAmount != want.BetAmount || got.WinAmount != want.WinAmount {
+			t.Fatalf("transaction %d (%s): fixed %s/%s, decimal %s/%s",
+				i, got.CurrencyCode, got.BetAmount, got.WinAmount, want.BetAmount, want.WinAmount)
+		}
+	}
+}
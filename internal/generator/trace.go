@@ -0,0 +1,91 @@
+package generator
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Trace scopes: which transactions share a trace ID
+const (
+	TraceTransaction = "transaction" // each transaction has its own (default)
+	TraceRound       = "round"       // the bets of a round share one
+	TracePlayer      = "player"      // a scripted player session shares one; other transactions have their own
+)
+
+// TraceScopes lists the accepted trace scopes
+var TraceScopes = []string{TraceTransaction, TraceRound, TracePlayer}
+
+// tracer derives UUIDv7 trace IDs from a transaction's correlation key, so
+// workers agree on a shared ID without coordinating and no random draws are
+// taken from the generation streams
+type tracer struct {
+	scope string
+	epoch time.Time // timestamp of IDs shared by several transactions
+	salt  [8]byte   // keeps IDs distinct between runs
+}
+
+// SetTracing gives every subsequent transaction a UUIDv7 TraceID, shared by
+// the transactions of one scope. An empty scope stops tracing.
+func (p *Producer) SetTracing(scope string) error {
+	switch scope {
+	case "":
+		p.tracer = nil
+		return nil
+	case TraceTransaction, TraceRound, TracePlayer:
+	default:
+		return fmt.Errorf("unknown trace scope: %s", scope)
+	}
+	t := &tracer{scope: scope, epoch: time.Now()}
+	if _, err := rand.Read(t.salt[:]); err != nil {
+		return fmt.Errorf("failed to seed trace IDs: %w", err)
+	}
+	p.tracer = t
+	return nil
+}
+
+// id returns the trace ID of txn, generated at now. A transaction's own ID is
+// stamped with now; a shared ID with the time tracing started.
+func (t *tracer) id(txn *models.Transaction, now time.Time) string {
+	key, at := txn.ID, now
+	switch {
+	case t.scope == TraceRound:
+		key, at = txn.RoundID, t.epoch
+	case t.scope == TracePlayer && txn.PlayerID != "":
+		key, at = txn.PlayerID, t.epoch
+	}
+
+	h := fnv.New128a()
+	h.Write(t.salt[:])
+	h.Write([]byte(key))
+	var u [16]byte
+	h.Sum(u[:0])
+	return formatUUIDv7(u, at)
+}
+
+// formatUUIDv7 stamps the random bits in u with the millisecond timestamp,
+// version and variant of a UUIDv7 and formats it
+func formatUUIDv7(u [16]byte, at time.Time) string {
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(at.UnixMilli()))
+	copy(u[:6], ms[2:])
+	u[6] = 0x70 | u[6]&0x0f
+	u[8] = 0x80 | u[8]&0x3f
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
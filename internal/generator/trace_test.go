@@ -0,0 +1,64 @@
+package generator
+
+import (
+	"regexp"
+	"testing"
+)
+
+var uuidV7 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestTraceScopes(t *testing.T) {
+	for _, tc := range []struct {
+		scope string
+		key   func(txnID, roundID, playerID string) string // what should share a trace ID
+	}{
+		{TraceTransaction, func(id, _, _ string) string { return id }},
+		{TraceRound, func(_, round, _ string) string { return round }},
+		{TracePlayer, func(id, _, player string) string {
+			if player != "" {
+				return player
+			}
+			return id
+		}},
+	} {
+		p := testProducer()
+		p.SetSeed(7)
+		if err := p.SetBehaviors(BehaviorConfig{Rate: 0.3, SessionLength: 5}, func(BehaviorLabel) {}); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.SetTracing(tc.scope); err != nil {
+			t.Fatal(err)
+		}
+
+		rngs := p.newStreams(0)
+		byKey := map[string]string{}   // key -> trace ID
+		byTrace := map[string]string{} // trace ID -> key
+		for i := 0; i < 2000; i++ {
+			txn := p.generateTransaction(rngs)
+			if !uuidV7.MatchString(txn.TraceID) {
+				t.Fatalf("%s: trace ID %q is not a UUIDv7", tc.scope, txn.TraceID)
+			}
+			key := tc.key(txn.ID, txn.RoundID, txn.PlayerID)
+			if id, ok := byKey[key]; ok && id != txn.TraceID {
+				t.Fatalf("%s: %s has trace IDs %s and %s", tc.scope, key, id, txn.TraceID)
+			}
+			if other, ok := byTrace[txn.TraceID]; ok && other != key {
+				t.Fatalf("%s: %s and %s share trace ID %s", tc.scope, key, other, txn.TraceID)
+			}
+			byKey[key], byTrace[txn.TraceID] = txn.TraceID, key
+		}
+		if tc.scope != TraceTransaction && len(byKey) >= 2000 {
+			t.Errorf("%s: no transactions shared a trace ID", tc.scope)
+		}
+	}
+}
+
+func TestTraceOff(t *testing.T) {
+	p := testProducer()
+	if err := p.SetTracing("span"); err == nil {
+		t.Error("unknown scope accepted")
+	}
+	if txn := p.generateTransaction(p.newStreams(0)); txn.TraceID != "" {
+		t.Errorf("untraced transaction has trace ID %q", txn.TraceID)
+	}
+}
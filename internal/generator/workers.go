@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// workerCounter is padded to a cache line so workers counting side by side
+// do not false-share
+type workerCounter struct {
+	n    atomic.Int64
+	busy atomic.Int64 // nanoseconds from start to finish, set when done
+	_    [48]byte
+}
+
+// SetLockOSThread pins each generation worker goroutine to its own OS thread
+// for the worker's lifetime, so OS-level tools (perf, taskset, numastat)
+// see one stable thread per worker
+func (p *Producer) SetLockOSThread(lock bool) {
+	p.lockThreads = lock
+}
+
+// WorkerCounts returns the transactions each worker of the current (or last)
+// fixed-count run has generated, indexed by worker
+func (p *Producer) WorkerCounts() []int64 {
+	counters := p.workers.Load()
+	if counters == nil {
+		return nil
+	}
+	counts := make([]int64, len(*counters))
+	for i := range *counters {
+		counts[i] = (*counters)[i].n.Load()
+	}
+	return counts
+}
+
+// WorkerBusy returns how long each worker of the last run took, zero for
+// workers still running
+func (p *Producer) WorkerBusy() []time.Duration {
+	counters := p.workers.Load()
+	if counters == nil {
+		return nil
+	}
+	busy := make([]time.Duration, len(*counters))
+	for i := range *counters {
+		busy[i] = time.Duration((*counters)[i].busy.Load())
+	}
+	return busy
+}
+
+// startWorkers resets the per-worker counters for a run with n workers
+func (p *Producer) startWorkers(n int) {
+	counters := make([]workerCounter, n)
+	p.workers.Store(&counters)
+}
+
+// enterWorker runs at the top of worker i's goroutine and returns its
+// counter and a release function to defer
+func (p *Producer) enterWorker(i int) (*atomic.Int64, func()) {
+	counter := &(*p.workers.Load())[i]
+	if p.lockThreads {
+		runtime.LockOSThread()
+	}
+	start := time.Now()
+	return &counter.n, func() {
+		counter.busy.Store(int64(time.Since(start)))
+		if p.lockThreads {
+			runtime.UnlockOSThread()
+		}
+	}
+}
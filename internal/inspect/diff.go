@@ -0,0 +1,413 @@
+package inspect
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// distinctLimit caps the distinct values tracked per column so high
+// cardinality columns do not hold every value in memory
+const distinctLimit = 100000
+
+// categoricalLimit is the most distinct values a text column may have for
+// its value set to be compared, as with vendor and currency codes
+const categoricalLimit = 1000
+
+// runColumns differ between runs by design, so their values are not
+// compared; transaction IDs are compared as a set when DiffOptions.SameIDs
+// asks for it
+var runColumns = []string{"id", "external_transaction_id", "vendor_bet_id", "round_id", "settled_at"}
+
+// idSampleSize is the number of IDs or values listed from each side of a set
+// difference
+const idSampleSize = 10
+
+// Summary describes the transactions of one output dataset: a file or every
+// CSV and Parquet file below a directory
+type Summary struct {
+	Path    string                    `json:"path"`
+	Files   []string                  `json:"files"`
+	Rows    int64                     `json:"rows"`
+	Columns map[string]*ColumnSummary `json:"columns"`
+	order   []string
+	ids     map[string]struct{}
+}
+
+// ColumnSummary aggregates the values of one column. Sum and Mean are set
+// when every non-empty value is numeric; Min and Max compare numerically
+// then and as strings otherwise.
+type ColumnSummary struct {
+	Count          int64    `json:"count"` // non-empty values
+	Distinct       int      `json:"distinct"`
+	DistinctCapped bool     `json:"distinct_capped,omitempty"` // more than distinctLimit values
+	Numeric        bool     `json:"numeric"`
+	Min            string   `json:"min,omitempty"`
+	Max            string   `json:"max,omitempty"`
+	Sum            *float64 `json:"sum,omitempty"`
+	Mean           *float64 `json:"mean,omitempty"`
+
+	values   map[string]struct{}
+	nonNum   bool
+	sum      float64
+	min, max float64
+}
+
+// DiffOptions controls what Diff reports as a difference
+type DiffOptions struct {
+	// Tolerance is the relative difference allowed between row counts and
+	// numeric aggregates, e.g. 0.01 for 1%
+	Tolerance float64
+	// SameIDs requires both datasets to hold the same transaction IDs, for
+	// runs with a fixed seed
+	SameIDs bool
+}
+
+// DiffReport compares two output datasets
+type DiffReport struct {
+	A           *Summary     `json:"a"`
+	B           *Summary     `json:"b"`
+	Columns     []ColumnDiff `json:"columns"`
+	OnlyInA     int          `json:"only_in_a"` // transaction IDs
+	OnlyInB     int          `json:"only_in_b"`
+	SampleA     []string     `json:"sample_only_in_a,omitempty"`
+	SampleB     []string     `json:"sample_only_in_b,omitempty"`
+	Differences []string     `json:"differences,omitempty"`
+}
+
+// ColumnDiff pairs the summaries of a column present in either dataset
+type ColumnDiff struct {
+	Name string         `json:"name"`
+	A    *ColumnSummary `json:"a,omitempty"`
+	B    *ColumnSummary `json:"b,omitempty"`
+}
+
+// Diff summarizes the datasets at a and b and compares their row counts,
+// per-column aggregates and transaction IDs, so a generator change can be
+// checked for unintended shifts in the data it produces
+func Diff(a, b string, opts DiffOptions) (*DiffReport, error) {
+	sa, err := Summarize(a)
+	if err != nil {
+		return nil, err
+	}
+	sb, err := Summarize(b)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(sa, sb, opts), nil
+}
+
+// Compare reports the differences between two summaries
+func Compare(a, b *Summary, opts DiffOptions) *DiffReport {
+	r := &DiffReport{A: a, B: b}
+	differ := func(format string, args ...any) {
+		r.Differences = append(r.Differences, fmt.Sprintf(format, args...))
+	}
+
+	if !within(float64(a.Rows), float64(b.Rows), opts.Tolerance) {
+		differ("rows: %d vs %d", a.Rows, b.Rows)
+	}
+
+	names := slices.Clone(a.order)
+	for _, name := range b.order {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		ca, cb := a.Columns[name], b.Columns[name]
+		r.Columns = append(r.Columns, ColumnDiff{Name: name, A: ca, B: cb})
+		switch {
+		case ca == nil:
+			differ("%s: only in %s", name, b.Path)
+		case cb == nil:
+			differ("%s: only in %s", name, a.Path)
+		default:
+			for _, d := range compareColumns(name, ca, cb, opts.Tolerance) {
+				differ("%s: %s", name, d)
+			}
+		}
+	}
+
+	r.SampleA, r.OnlyInA = setDifference(a.ids, b.ids)
+	r.SampleB, r.OnlyInB = setDifference(b.ids, a.ids)
+	if opts.SameIDs && (r.OnlyInA > 0 || r.OnlyInB > 0) {
+		differ("ids: %d only in %s, %d only in %s", r.OnlyInA, a.Path, r.OnlyInB, b.Path)
+	}
+	return r
+}
+
+// compareColumns lists how two column summaries differ beyond tolerance
+func compareColumns(name string, a, b *ColumnSummary, tolerance float64) []string {
+	var diffs []string
+	if !within(float64(a.Count), float64(b.Count), tolerance) {
+		diffs = append(diffs, fmt.Sprintf("count %d vs %d", a.Count, b.Count))
+	}
+	if slices.Contains(runColumns, name) {
+		return diffs
+	}
+	if !a.DistinctCapped && !b.DistinctCapped && !within(float64(a.Distinct), float64(b.Distinct), tolerance) {
+		diffs = append(diffs, fmt.Sprintf("distinct %d vs %d", a.Distinct, b.Distinct))
+	}
+	if a.Numeric != b.Numeric {
+		return append(diffs, fmt.Sprintf("numeric %t vs %t", a.Numeric, b.Numeric))
+	}
+	if !a.Numeric {
+		// Only categorical value sets are compared; free text differs
+		if a.Distinct <= categoricalLimit && b.Distinct <= categoricalLimit {
+			if sample, n := setDifference(a.values, b.values); n > 0 {
+				diffs = append(diffs, fmt.Sprintf("%d values only in a: %s", n, strings.Join(sample, ", ")))
+			}
+			if sample, n := setDifference(b.values, a.values); n > 0 {
+				diffs = append(diffs, fmt.Sprintf("%d values only in b: %s", n, strings.Join(sample, ", ")))
+			}
+		}
+		return diffs
+	}
+	if a.Count == 0 || b.Count == 0 {
+		return diffs
+	}
+	if !within(a.min, b.min, tolerance) || !within(a.max, b.max, tolerance) {
+		diffs = append(diffs, fmt.Sprintf("range [%s, %s] vs [%s, %s]", a.Min, a.Max, b.Min, b.Max))
+	}
+	if !within(*a.Mean, *b.Mean, tolerance) {
+		diffs = append(diffs, fmt.Sprintf("mean %g vs %g", *a.Mean, *b.Mean))
+	}
+	return diffs
+}
+
+// within reports whether a and b differ by at most tolerance relative to the
+// larger magnitude
+func within(a, b, tolerance float64) bool {
+	if a == b {
+		return true
+	}
+	return math.Abs(a-b) <= tolerance*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// setDifference returns a sorted sample of the keys in a but not b and how
+// many there are
+func setDifference(a, b map[string]struct{}) ([]string, int) {
+	var only []string
+	for id := range a {
+		if _, ok := b[id]; !ok {
+			only = append(only, id)
+		}
+	}
+	slices.Sort(only)
+	return only[:min(len(only), idSampleSize)], len(only)
+}
+
+// Summarize reads the CSV or Parquet file at path, or every such file below
+// the directory at path
+func Summarize(path string) (*Summary, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && dataFormat(p) != "" {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no CSV or Parquet files in %s", path)
+		}
+	}
+
+	s := &Summary{Path: path, Files: files, Columns: make(map[string]*ColumnSummary), ids: make(map[string]struct{})}
+	for _, file := range files {
+		switch dataFormat(file) {
+		case "csv":
+			err = s.addCSV(file)
+		case "parquet":
+			err = s.addParquet(file)
+		default:
+			err = fmt.Errorf("unsupported file type: %s", file)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range s.Columns {
+		c.finish()
+	}
+	return s, nil
+}
+
+// dataFormat returns csv or parquet by file extension, empty for other files
+func dataFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv"
+	case ".parquet":
+		return "parquet"
+	}
+	return ""
+}
+
+func (s *Summary) addCSV(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.ReuseRecord = true
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	header = slices.Clone(header)
+	columns := s.columns(header)
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		s.Rows++
+		for i, value := range record {
+			if i < len(columns) {
+				s.add(header[i], columns[i], value)
+			}
+		}
+	}
+}
+
+func (s *Summary) addParquet(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open Parquet file: %w", err)
+	}
+	defer file.Close()
+
+	r := parquet.NewReader(file)
+	defer r.Close()
+	var header []string
+	for _, column := range r.Schema().Columns() {
+		header = append(header, strings.Join(column, "."))
+	}
+	columns := s.columns(header)
+
+	rows := make([]parquet.Row, 256)
+	for {
+		n, err := r.ReadRows(rows)
+		for _, row := range rows[:n] {
+			s.Rows++
+			for _, v := range row {
+				if i := v.Column(); i >= 0 && i < len(columns) && !v.IsNull() {
+					s.add(header[i], columns[i], v.String())
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+}
+
+// columns returns the summaries of the named columns, adding missing ones
+func (s *Summary) columns(names []string) []*ColumnSummary {
+	columns := make([]*ColumnSummary, len(names))
+	for i, name := range names {
+		c, ok := s.Columns[name]
+		if !ok {
+			c = &ColumnSummary{values: make(map[string]struct{})}
+			s.Columns[name] = c
+			s.order = append(s.order, name)
+		}
+		columns[i] = c
+	}
+	return columns
+}
+
+func (s *Summary) add(name string, c *ColumnSummary, value string) {
+	if value == "" {
+		return
+	}
+	if name == "id" {
+		s.ids[value] = struct{}{}
+	}
+	c.add(value)
+}
+
+func (c *ColumnSummary) add(value string) {
+	if !c.DistinctCapped {
+		c.values[value] = struct{}{}
+		if len(c.values) > distinctLimit {
+			c.DistinctCapped = true
+			c.values = nil
+		}
+	}
+
+	first := c.Count == 0
+	c.Count++
+	if first || value < c.Min {
+		c.Min = value
+	}
+	if first || value > c.Max {
+		c.Max = value
+	}
+
+	if c.nonNum {
+		return
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		c.nonNum = true
+		return
+	}
+	c.sum += f
+	if first || f < c.min {
+		c.min = f
+	}
+	if first || f > c.max {
+		c.max = f
+	}
+}
+
+// finish fills the exported aggregates once every value has been added
+func (c *ColumnSummary) finish() {
+	c.Distinct = len(c.values)
+	if c.DistinctCapped {
+		c.Distinct = distinctLimit
+	}
+	c.Numeric = c.Count > 0 && !c.nonNum
+	if !c.Numeric {
+		return
+	}
+	sum, mean := c.sum, c.sum/float64(c.Count)
+	c.Sum, c.Mean = &sum, &mean
+	c.Min = strconv.FormatFloat(c.min, 'f', -1, 64)
+	c.Max = strconv.FormatFloat(c.max, 'f', -1, 64)
+}
@@ -0,0 +1,104 @@
+package inspect
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCSV(t *testing.T, dir, name string, rows ...string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	content := "id,vendor_code,bet_amount\n" + strings.Join(rows, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSummarizeCSV(t *testing.T) {
+	dir := t.TempDir()
+	writeCSV(t, dir, "a.csv", "t1,PG,10.5", "t2,PG,2.5", "t3,EVO,")
+	writeCSV(t, dir, "b.csv", "t4,EVO,3")
+
+	s, err := Summarize(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Rows != 4 || len(s.Files) != 2 {
+		t.Fatalf("rows=%d files=%d, want 4 rows in 2 files", s.Rows, len(s.Files))
+	}
+	bet := s.Columns["bet_amount"]
+	if !bet.Numeric || bet.Count != 3 || *bet.Sum != 16 || bet.Min != "2.5" || bet.Max != "10.5" {
+		t.Errorf("bet_amount = %+v", bet)
+	}
+	vendor := s.Columns["vendor_code"]
+	if vendor.Numeric || vendor.Distinct != 2 || vendor.Min != "EVO" || vendor.Max != "PG" {
+		t.Errorf("vendor_code = %+v", vendor)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	a := writeCSV(t, dir, "a.csv", "t1,PG,10", "t2,PG,20", "t3,EVO,30")
+	same := writeCSV(t, dir, "same.csv", "t4,PG,10", "t5,PG,20", "t6,EVO,30")
+	shifted := writeCSV(t, dir, "shifted.csv", "t1,PG,10", "t2,PG,20", "t3,JILI,60")
+
+	r, err := Diff(a, same, DiffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Differences) > 0 {
+		t.Errorf("same characteristics reported %v", r.Differences)
+	}
+	if r.OnlyInA != 3 || r.OnlyInB != 3 {
+		t.Errorf("only in a=%d b=%d, want 3 and 3", r.OnlyInA, r.OnlyInB)
+	}
+
+	r, err = Diff(a, same, DiffOptions{SameIDs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Differences) != 1 || !strings.HasPrefix(r.Differences[0], "ids:") {
+		t.Errorf("differences = %v, want the id difference", r.Differences)
+	}
+
+	r, err = Diff(a, shifted, DiffOptions{Tolerance: 0.01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"vendor_code: 1 values only in a: EVO",
+		"vendor_code: 1 values only in b: JILI",
+		"bet_amount: range [10, 30] vs [10, 60]",
+		"bet_amount: mean 20 vs 30",
+	}
+	if strings.Join(r.Differences, "\n") != strings.Join(want, "\n") {
+		t.Errorf("differences = %q, want %q", r.Differences, want)
+	}
+	if r.OnlyInA != 0 || r.OnlyInB != 0 {
+		t.Errorf("only in a=%d b=%d, want 0", r.OnlyInA, r.OnlyInB)
+	}
+}
+
+func TestDiffTolerance(t *testing.T) {
+	dir := t.TempDir()
+	a := writeCSV(t, dir, "a.csv", "t1,PG,96", "t2,PG,104")
+	b := writeCSV(t, dir, "b.csv", "t3,PG,100", "t4,PG,108")
+
+	r, err := Diff(a, b, DiffOptions{Tolerance: 0.05})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Differences) > 0 {
+		t.Errorf("within tolerance reported %v", r.Differences)
+	}
+	r, err = Diff(a, b, DiffOptions{Tolerance: 0.01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Differences) == 0 {
+		t.Error("beyond tolerance reported no differences")
+	}
+}
@@ -0,0 +1,154 @@
+package inspect
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+)
+
+// ParquetReport describes the footer metadata of a Parquet file
+type ParquetReport struct {
+	Path      string           `json:"path"`
+	Size      int64            `json:"size"`
+	NumRows   int64            `json:"num_rows"`
+	CreatedBy string           `json:"created_by"`
+	Schema    string           `json:"schema"`
+	RowGroups []RowGroupReport `json:"row_groups"`
+	Problems  []string         `json:"problems,omitempty"`
+}
+
+// RowGroupReport describes one row group
+type RowGroupReport struct {
+	Index          int            `json:"index"`
+	NumRows        int64          `json:"num_rows"`
+	TotalByteSize  int64          `json:"total_byte_size"`
+	CompressedSize int64          `json:"compressed_size"`
+	SortingColumns []string       `json:"sorting_columns,omitempty"`
+	Columns        []ColumnReport `json:"columns"`
+}
+
+// ColumnReport describes one column chunk and its statistics
+type ColumnReport struct {
+	Path             string   `json:"path"`
+	Type             string   `json:"type"`
+	Codec            string   `json:"codec"`
+	Encodings        []string `json:"encodings"`
+	NumValues        int64    `json:"num_values"`
+	CompressedSize   int64    `json:"compressed_size"`
+	UncompressedSize int64    `json:"uncompressed_size"`
+	HasStatistics    bool     `json:"has_statistics"`
+	Min              string   `json:"min,omitempty"`
+	Max              string   `json:"max,omitempty"`
+	NullCount        int64    `json:"null_count"`
+	Dictionary       bool     `json:"dictionary"`
+	BloomFilter      bool     `json:"bloom_filter"`
+}
+
+// InspectParquet reads the footer of a Parquet file and verifies that every
+// column chunk carries min/max statistics with min <= max, so consumers can
+// rely on predicate pushdown.
+func InspectParquet(path string) (*ParquetReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Parquet file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat Parquet file: %w", err)
+	}
+
+	pf, err := parquet.OpenFile(file, info.Size(), parquet.SkipPageIndex(true), parquet.SkipBloomFilters(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Parquet footer: %w", err)
+	}
+
+	metadata := pf.Metadata()
+	schema := pf.Schema()
+	report := &ParquetReport{
+		Path:      path,
+		Size:      info.Size(),
+		NumRows:   metadata.NumRows,
+		CreatedBy: metadata.CreatedBy,
+		Schema:    schema.String(),
+	}
+
+	for i, rg := range metadata.RowGroups {
+		rgReport := RowGroupReport{
+			Index:          i,
+			NumRows:        rg.NumRows,
+			TotalByteSize:  rg.TotalByteSize,
+			CompressedSize: rg.TotalCompressedSize,
+		}
+
+		for _, sc := range rg.SortingColumns {
+			direction := "asc"
+			if sc.Descending {
+				direction = "desc"
+			}
+			name := fmt.Sprintf("#%d", sc.ColumnIdx)
+			if int(sc.ColumnIdx) < len(rg.Columns) {
+				name = strings.Join(rg.Columns[sc.ColumnIdx].MetaData.PathInSchema, ".")
+			}
+			rgReport.SortingColumns = append(rgReport.SortingColumns, name+" "+direction)
+		}
+
+		for _, chunk := range rg.Columns {
+			col := columnReport(schema, chunk.MetaData)
+			if !col.HasStatistics {
+				report.Problems = append(report.Problems,
+					fmt.Sprintf("row group %d column %s: missing min/max statistics", i, col.Path))
+			} else if minGreaterThanMax(schema, chunk.MetaData) {
+				report.Problems = append(report.Problems,
+					fmt.Sprintf("row group %d column %s: min %q greater than max %q", i, col.Path, col.Min, col.Max))
+			}
+			rgReport.Columns = append(rgReport.Columns, col)
+		}
+
+		report.RowGroups = append(report.RowGroups, rgReport)
+	}
+
+	return report, nil
+}
+
+func columnReport(schema *parquet.Schema, md format.ColumnMetaData) ColumnReport {
+	col := ColumnReport{
+		Path:             strings.Join(md.PathInSchema, "."),
+		Type:             md.Type.String(),
+		Codec:            md.Codec.String(),
+		NumValues:        md.NumValues,
+		CompressedSize:   md.TotalCompressedSize,
+		UncompressedSize: md.TotalUncompressedSize,
+		NullCount:        md.Statistics.NullCount,
+		Dictionary:       md.DictionaryPageOffset != 0,
+		BloomFilter:      md.BloomFilterOffset != 0,
+	}
+	for _, e := range md.Encoding {
+		col.Encodings = append(col.Encodings, e.String())
+	}
+
+	stats := md.Statistics
+	col.HasStatistics = stats.MinValue != nil && stats.MaxValue != nil
+	if col.HasStatistics {
+		if leaf, ok := schema.Lookup(md.PathInSchema...); ok {
+			kind := leaf.Node.Type().Kind()
+			col.Min = kind.Value(stats.MinValue).String()
+			col.Max = kind.Value(stats.MaxValue).String()
+		}
+	}
+	return col
+}
+
+func minGreaterThanMax(schema *parquet.Schema, md format.ColumnMetaData) bool {
+	leaf, ok := schema.Lookup(md.PathInSchema...)
+	if !ok {
+		return false
+	}
+	typ := leaf.Node.Type()
+	kind := typ.Kind()
+	return typ.Compare(kind.Value(md.Statistics.MinValue), kind.Value(md.Statistics.MaxValue)) > 0
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// closeResult is how closing one writer at the end of the run went
+type closeResult struct {
+	name    string
+	elapsed time.Duration
+	err     error
+}
+
+// RecordClose notes how closing the named writer went, for FinalReport. An
+// error wrapping context.DeadlineExceeded counts as a timeout.
+func (m *Monitor) RecordClose(name string, elapsed time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closes = append(m.closes, closeResult{name: name, elapsed: elapsed, err: err})
+}
+
+// reportCloses logs whether every writer closed cleanly, naming those that
+// timed out or failed. Callers hold m.mu.
+func (m *Monitor) reportCloses() {
+	if len(m.closes) == 0 {
+		return
+	}
+	timedOut, failed := []string{}, []string{}
+	slowest := m.closes[0]
+	for _, c := range m.closes {
+		switch {
+		case errors.Is(c.err, context.DeadlineExceeded):
+			timedOut = append(timedOut, c.name)
+		case c.err != nil:
+			failed = append(failed, c.name)
+		}
+		if c.elapsed > slowest.elapsed {
+			slowest = c
+		}
+	}
+	attrs := []any{
+		"writers", len(m.closes),
+		"clean", len(m.closes) - len(timedOut) - len(failed),
+		"timed_out", timedOut,
+		"failed", failed,
+		"slowest", slowest.name,
+		"slowest_time", formatDuration(slowest.elapsed),
+	}
+	if len(timedOut) > 0 || len(failed) > 0 {
+		m.logger.Warn("Writer shutdown incomplete", attrs...)
+		return
+	}
+	m.logger.Info("Writer shutdown", attrs...)
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportCloses(t *testing.T) {
+	var logs bytes.Buffer
+	m := NewMonitor(1, false, slog.New(slog.NewTextHandler(&logs, nil)))
+	m.RecordClose("CSV", 10*time.Millisecond, nil)
+	m.RecordClose("Kafka", 30*time.Second, fmt.Errorf("close abandoned: %w", context.DeadlineExceeded))
+	m.RecordClose("Socket", time.Millisecond, errors.New("connection reset"))
+	m.FinalReport()
+
+	want := `level=WARN msg="Writer shutdown incomplete" writers=3 clean=1 timed_out=[Kafka] failed=[Socket] slowest=Kafka`
+	if !strings.Contains(logs.String(), want) {
+		t.Errorf("report missing %q:\n%s", want, logs.String())
+	}
+
+	logs.Reset()
+	m = NewMonitor(1, false, slog.New(slog.NewTextHandler(&logs, nil)))
+	m.RecordClose("CSV", time.Millisecond, nil)
+	m.FinalReport()
+	if !strings.Contains(logs.String(), `level=INFO msg="Writer shutdown" writers=1 clean=1 timed_out=[] failed=[]`) {
+		t.Errorf("clean shutdown report:\n%s", logs.String())
+	}
+}
@@ -24,15 +24,43 @@ type Monitor struct {
 	parquetCount atomic.Int64
 	kafkaCount   atomic.Int64
 	kafkaErrors  atomic.Int64
+
+	// Counters for additional sinks, keyed by sink name
+	sinkMu     sync.Mutex
+	sinkNames  []string
+	sinkCounts map[string]*atomic.Int64
+
+	// Live counters polled while the run is in progress
+	trackedNames  []string
+	trackedCounts map[string]func() int64
+	trackedErrors []func() int64
+
+	// Per-generation-worker counters and their previous snapshot
+	workerCounts func() []int64
+	workerBusy   func() []time.Duration
+	lastWorkers  []int64
+
+	// Windowed SLO evaluation, nil when no SLO is set
+	slo *sloState
+
+	// Writers closed at the end of the run. Guarded by mu.
+	closes []closeResult
+
+	// Delivered file uploads over the run and since the last report.
+	// Guarded by mu.
+	uploads, recentUploads uploadStats
 }
 
 // NewMonitor creates a new performance monitor
 func NewMonitor(interval int, detailed bool, logger *slog.Logger) *Monitor {
 	m := &Monitor{
-		startTime: time.Now(),
-		interval:  time.Duration(interval) * time.Second,
-		detailed:  detailed,
-		logger:    logger,
+		startTime:  time.Now(),
+		interval:   time.Duration(interval) * time.Second,
+		detailed:   detailed,
+		logger:     logger,
+		sinkCounts: make(map[string]*atomic.Int64),
+
+		trackedCounts: make(map[string]func() int64),
 	}
 	m.lastReportTime.Store(time.Now())
 	return m
@@ -63,6 +91,100 @@ func (m *Monitor) IncrementKafkaErrors(count int64) {
 	m.kafkaErrors.Add(count)
 }
 
+// IncrementSink increments the counter for a named sink
+func (m *Monitor) IncrementSink(name string, count int64) {
+	m.sinkMu.Lock()
+	counter, ok := m.sinkCounts[name]
+	if !ok {
+		counter = &atomic.Int64{}
+		m.sinkCounts[name] = counter
+		m.sinkNames = append(m.sinkNames, name)
+	}
+	m.sinkMu.Unlock()
+	counter.Add(count)
+}
+
+// sinkAttrs returns the additional sink counters as log attributes
+func (m *Monitor) sinkAttrs() []any {
+	m.sinkMu.Lock()
+	defer m.sinkMu.Unlock()
+	attrs := make([]any, 0, len(m.sinkNames)*2)
+	for _, name := range m.sinkNames {
+		attrs = append(attrs, name, m.sinkCounts[name].Load())
+	}
+	return attrs
+}
+
+// SinkCounts returns the messages each sink has accepted, keyed by sink name
+func (m *Monitor) SinkCounts() map[string]int64 {
+	counts := make(map[string]int64)
+	if n := m.csvCount.Load(); n > 0 {
+		counts["csv"] = n
+	}
+	if n := m.parquetCount.Load(); n > 0 {
+		counts["parquet"] = n
+	}
+	if n := m.kafkaCount.Load(); n > 0 {
+		counts["kafka"] = n
+	}
+
+	m.sinkMu.Lock()
+	defer m.sinkMu.Unlock()
+	for _, name := range m.sinkNames {
+		counts[name] = m.sinkCounts[name].Load()
+	}
+	return counts
+}
+
+// Track registers a sink's live counter. Writer counters are otherwise only
+// added once a writer finishes; tracked ones are readable mid-run.
+func (m *Monitor) Track(name string, count func() int64) {
+	m.sinkMu.Lock()
+	defer m.sinkMu.Unlock()
+	if _, ok := m.trackedCounts[name]; !ok {
+		m.trackedNames = append(m.trackedNames, name)
+	}
+	m.trackedCounts[name] = count
+}
+
+// TrackErrors registers a sink's live error counter
+func (m *Monitor) TrackErrors(errors func() int64) {
+	m.sinkMu.Lock()
+	defer m.sinkMu.Unlock()
+	m.trackedErrors = append(m.trackedErrors, errors)
+}
+
+// LiveErrors polls the tracked error counters and returns their total
+func (m *Monitor) LiveErrors() int64 {
+	m.sinkMu.Lock()
+	defer m.sinkMu.Unlock()
+	var total int64
+	for _, errors := range m.trackedErrors {
+		total += errors()
+	}
+	return total
+}
+
+// TrackWorkers registers the per-worker generation counters and busy times,
+// reported with each interval's rates and in the final summary
+func (m *Monitor) TrackWorkers(counts func() []int64, busy func() []time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.workerCounts = counts
+	m.workerBusy = busy
+}
+
+// LiveCounts polls the tracked sinks, keyed by sink name
+func (m *Monitor) LiveCounts() map[string]int64 {
+	m.sinkMu.Lock()
+	defer m.sinkMu.Unlock()
+	counts := make(map[string]int64, len(m.trackedNames))
+	for _, name := range m.trackedNames {
+		counts[name] = m.trackedCounts[name]()
+	}
+	return counts
+}
+
 // Report generates and prints a performance report
 func (m *Monitor) Report() {
 	m.mu.Lock()
@@ -90,14 +212,21 @@ func (m *Monitor) Report() {
 	)
 	
 	if m.detailed {
-		m.logger.Info("Writer metrics",
+		attrs := []any{
 			"csv", m.csvCount.Load(),
 			"parquet", m.parquetCount.Load(),
 			"kafka", m.kafkaCount.Load(),
 			"kafka_errors", m.kafkaErrors.Load(),
-		)
+		}
+		m.logger.Info("Writer metrics", append(attrs, m.sinkAttrs()...)...)
 	}
 	
+	if m.workerCounts != nil {
+		m.reportWorkers(intervalElapsed)
+	}
+	m.reportUploads("Upload throughput", m.recentUploads)
+	m.recentUploads = uploadStats{}
+	
 	// Update for next report
 	m.lastMessages.Store(total)
 	m.lastReportTime.Store(now)
@@ -117,14 +246,24 @@ func (m *Monitor) FinalReport() {
 	)
 	
 	if m.detailed {
-		m.logger.Info("Output breakdown",
+		attrs := []any{
 			"csv", m.csvCount.Load(),
 			"parquet", m.parquetCount.Load(),
 			"kafka", m.kafkaCount.Load(),
 			"kafka_errors", m.kafkaErrors.Load(),
-		)
+		}
+		m.logger.Info("Output breakdown", append(attrs, m.sinkAttrs()...)...)
 	}
 	
+	m.mu.Lock()
+	if m.workerCounts != nil {
+		m.reportWorkerTotals()
+	}
+	m.finishSLO(time.Now())
+	m.reportUploads("Upload summary", m.uploads)
+	m.reportCloses()
+	m.mu.Unlock()
+	
 	// Performance assessment
 	var assessment string
 	if rate >= 30000 {
@@ -140,6 +279,63 @@ func (m *Monitor) FinalReport() {
 	m.logger.Info("Performance assessment", "result", assessment, "rate_msg_per_sec", int64(rate))
 }
 
+// reportWorkers logs each worker's rate over the last interval. imbalance is
+// the fastest rate over the slowest; near 1 means evenly loaded workers.
+// Callers hold m.mu.
+func (m *Monitor) reportWorkers(seconds float64) {
+	counts := m.workerCounts()
+	if len(counts) == 0 {
+		return
+	}
+	if len(m.lastWorkers) != len(counts) {
+		m.lastWorkers = make([]int64, len(counts)) // a new run started
+	}
+	rates := make([]int64, len(counts))
+	for i, n := range counts {
+		rates[i] = int64(float64(n-m.lastWorkers[i]) / seconds)
+	}
+	m.lastWorkers = counts
+	m.logger.Info("Worker metrics", workerAttrs("rates", rates)...)
+}
+
+// reportWorkerTotals logs each worker's total, busy time and average rate
+// while busy. Workers given equal shares finish at different times when one
+// is slower. Callers hold m.mu.
+func (m *Monitor) reportWorkerTotals() {
+	counts, busy := m.workerCounts(), m.workerBusy()
+	if len(counts) == 0 || len(busy) != len(counts) {
+		return
+	}
+	rates := make([]int64, len(counts))
+	seconds := make([]string, len(counts))
+	for i, n := range counts {
+		if busy[i] > 0 {
+			rates[i] = int64(float64(n) / busy[i].Seconds())
+		}
+		seconds[i] = formatDuration(busy[i])
+	}
+	attrs := append([]any{"totals", counts, "busy", seconds}, workerAttrs("average_rates", rates)...)
+	m.logger.Info("Worker summary", attrs...)
+}
+
+func workerAttrs(key string, rates []int64) []any {
+	slowest, fastest := rates[0], rates[0]
+	for _, r := range rates {
+		slowest, fastest = min(slowest, r), max(fastest, r)
+	}
+	imbalance := 0.0
+	if slowest > 0 {
+		imbalance = float64(fastest) / float64(slowest)
+	}
+	return []any{
+		"workers", len(rates),
+		key, rates,
+		"slowest", slowest,
+		"fastest", fastest,
+		"imbalance", fmt.Sprintf("%.2f", imbalance),
+	}
+}
+
 func formatDuration(d time.Duration) string {
 	if d < time.Second {
 		return fmt.Sprintf("%dms", d.Milliseconds())
@@ -160,6 +356,8 @@ func formatRate(rate float64) string {
 
 // StartReporting starts periodic metric reporting
 func (m *Monitor) StartReporting(done <-chan struct{}) {
+	go m.runSLO(done)
+
 	ticker := time.NewTicker(m.interval)
 	defer ticker.Stop()
 	
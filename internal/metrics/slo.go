@@ -0,0 +1,202 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+)
+
+// SLO is a throughput and error objective evaluated over fixed windows
+type SLO struct {
+	MinRate      float64       // messages/sec every full window must sustain, 0 = none
+	MaxErrorRate float64       // errors / (delivered + errors) per window and overall, 0 = none
+	Window       time.Duration // evaluation window length
+	Warmup       time.Duration // ignored at the start of the run
+}
+
+// Enabled reports whether any objective is set
+func (s SLO) Enabled() bool {
+	return s.MinRate > 0 || s.MaxErrorRate > 0
+}
+
+// sloState accumulates window results. Guarded by Monitor.mu.
+type sloState struct {
+	SLO
+	windows      int
+	violations   int
+	lowestRate   float64
+	worstErrors  float64
+	lastAt       time.Time
+	lastCount    int64
+	lastErrors   int64
+	baselineSeen bool
+	overallError float64
+}
+
+// SetSLO enables per-window SLO evaluation, reported by FinalReport and
+// SLOPassed. Windows are measured on delivered messages: the sum of the
+// tracked sinks, or the generated total when none are tracked.
+func (m *Monitor) SetSLO(slo SLO) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.slo = &sloState{SLO: slo}
+}
+
+// delivered returns messages accepted by the tracked sinks so far
+func (m *Monitor) delivered() int64 {
+	counts := m.LiveCounts()
+	if len(counts) == 0 {
+		return m.totalMessages.Load()
+	}
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
+
+// evaluateSLO closes the current window at now
+func (m *Monitor) evaluateSLO(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closeWindow(now)
+}
+
+// closeWindow evaluates the window ending at now. The first call after the
+// warmup only records the baseline. Callers hold m.mu.
+func (m *Monitor) closeWindow(now time.Time) {
+	s := m.slo
+	if s == nil || now.Sub(m.startTime) < s.Warmup {
+		return
+	}
+
+	count, errors := m.delivered(), m.LiveErrors()
+	if !s.baselineSeen {
+		s.lastAt, s.lastCount, s.lastErrors, s.baselineSeen = now, count, errors, true
+		return
+	}
+
+	seconds := now.Sub(s.lastAt).Seconds()
+	delivered, failed := count-s.lastCount, errors-s.lastErrors
+	s.lastAt, s.lastCount, s.lastErrors = now, count, errors
+	if seconds <= 0 {
+		return
+	}
+
+	rate := float64(delivered) / seconds
+	errorRate := 0.0
+	if delivered+failed > 0 {
+		errorRate = float64(failed) / float64(delivered+failed)
+	}
+	if s.windows == 0 || rate < s.lowestRate {
+		s.lowestRate = rate
+	}
+	s.worstErrors = max(s.worstErrors, errorRate)
+	s.windows++
+
+	var reasons []string
+	if s.MinRate > 0 && rate < s.MinRate {
+		reasons = append(reasons, fmt.Sprintf("rate %s below %s", formatRate(rate), formatRate(s.MinRate)))
+	}
+	if s.MaxErrorRate > 0 && errorRate > s.MaxErrorRate {
+		reasons = append(reasons, fmt.Sprintf("error rate %.4f%% above %.4f%%", errorRate*100, s.MaxErrorRate*100))
+	}
+	if len(reasons) > 0 {
+		s.violations++
+		m.logger.Warn("SLO window violated",
+			"window", s.windows,
+			"reasons", reasons,
+			"delivered", delivered,
+			"errors", failed,
+		)
+	}
+}
+
+// runSLO evaluates a window every SLO.Window until done
+func (m *Monitor) runSLO(done <-chan struct{}) {
+	m.mu.Lock()
+	s := m.slo
+	m.mu.Unlock()
+	if s == nil || !s.Enabled() || s.Window <= 0 {
+		return
+	}
+
+	if s.Warmup > 0 {
+		select {
+		case <-time.After(s.Warmup):
+		case <-done:
+			return
+		}
+	}
+	m.evaluateSLO(time.Now())
+
+	ticker := time.NewTicker(s.Window)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			m.evaluateSLO(now)
+		case <-done:
+			return
+		}
+	}
+}
+
+// finishSLO judges a run too short to complete a window on its whole
+// length, records the overall error rate and logs the verdict. Callers hold
+// m.mu.
+func (m *Monitor) finishSLO(now time.Time) {
+	s := m.slo
+	if s == nil || !s.Enabled() {
+		return
+	}
+	if s.windows == 0 && s.baselineSeen {
+		m.closeWindow(now)
+	}
+	count, errors := m.delivered(), m.LiveErrors()
+	if count+errors > 0 {
+		s.overallError = float64(errors) / float64(count+errors)
+	}
+	attrs := []any{
+		"passed", m.sloPassed(),
+		"windows", s.windows,
+		"violations", s.violations,
+		"min_rate", s.MinRate,
+		"max_error_rate", s.MaxErrorRate,
+	}
+	if s.windows > 0 {
+		attrs = append(attrs,
+			"lowest_rate", formatRate(s.lowestRate),
+			"worst_error_rate", fmt.Sprintf("%.4f%%", s.worstErrors*100),
+		)
+	}
+	attrs = append(attrs, "overall_error_rate", fmt.Sprintf("%.4f%%", s.overallError*100))
+	if m.sloPassed() {
+		m.logger.Info("SLO result", attrs...)
+	} else {
+		m.logger.Error("SLO result", attrs...)
+	}
+}
+
+// sloPassed reports whether every window and the run overall met the
+// objectives. A run that never measured a window fails a throughput
+// objective, since nothing showed the rate. Callers hold m.mu.
+func (m *Monitor) sloPassed() bool {
+	s := m.slo
+	if s == nil || !s.Enabled() {
+		return true
+	}
+	if s.MinRate > 0 && s.windows == 0 {
+		return false
+	}
+	if s.MaxErrorRate > 0 && s.overallError > s.MaxErrorRate {
+		return false
+	}
+	return s.violations == 0
+}
+
+// SLOPassed reports whether the run met its SLO; true when none is set
+func (m *Monitor) SLOPassed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sloPassed()
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newSLOMonitor(slo SLO) (*Monitor, *atomic.Int64, *atomic.Int64) {
+	m := NewMonitor(1, false, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var delivered, errors atomic.Int64
+	m.Track("kafka", delivered.Load)
+	m.TrackErrors(errors.Load)
+	m.SetSLO(slo)
+	return m, &delivered, &errors
+}
+
+func TestSLOWindows(t *testing.T) {
+	m, delivered, errors := newSLOMonitor(SLO{MinRate: 1000, MaxErrorRate: 0.01, Window: time.Second})
+	start := m.startTime
+
+	m.evaluateSLO(start)
+	delivered.Add(1500)
+	m.evaluateSLO(start.Add(time.Second))
+	if !m.SLOPassed() {
+		t.Fatal("1500 msg/s window failed a 1000 msg/s objective")
+	}
+
+	delivered.Add(900)
+	m.evaluateSLO(start.Add(2 * time.Second))
+	if m.SLOPassed() {
+		t.Fatal("900 msg/s window passed a 1000 msg/s objective")
+	}
+
+	m, delivered, errors = newSLOMonitor(SLO{MaxErrorRate: 0.01, Window: time.Second})
+	m.evaluateSLO(m.startTime)
+	delivered.Add(980)
+	errors.Add(20)
+	m.evaluateSLO(m.startTime.Add(time.Second))
+	if m.SLOPassed() {
+		t.Fatal("2% error window passed a 1% objective")
+	}
+}
+
+func TestSLOShortRunJudgedWhole(t *testing.T) {
+	m, delivered, _ := newSLOMonitor(SLO{MinRate: 1000, Window: time.Minute})
+	m.evaluateSLO(m.startTime)
+	delivered.Add(5000)
+
+	m.mu.Lock()
+	m.finishSLO(m.startTime.Add(2 * time.Second))
+	m.mu.Unlock()
+	if !m.SLOPassed() {
+		t.Fatal("2500 msg/s run shorter than a window failed a 1000 msg/s objective")
+	}
+
+	m, _, _ = newSLOMonitor(SLO{MinRate: 1000, Window: time.Minute, Warmup: time.Hour})
+	m.mu.Lock()
+	m.finishSLO(m.startTime.Add(time.Second))
+	m.mu.Unlock()
+	if m.SLOPassed() {
+		t.Fatal("run that never measured a window passed a throughput objective")
+	}
+}
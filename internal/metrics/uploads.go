@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"math"
+	"time"
+)
+
+// uploadStats accumulates delivered file uploads. Guarded by Monitor.mu.
+type uploadStats struct {
+	files, bytes int64
+	took         time.Duration
+	slowest      float64 // lowest per-upload MB/s
+	fastest      float64 // highest per-upload MB/s
+}
+
+func (u *uploadStats) add(bytes int64, took time.Duration) {
+	rate := megabytesPerSecond(bytes, took)
+	if u.files == 0 || rate < u.slowest {
+		u.slowest = rate
+	}
+	if rate > u.fastest {
+		u.fastest = rate
+	}
+	u.files++
+	u.bytes += bytes
+	u.took += took
+}
+
+// RecordUpload notes one delivered file of bytes that took took to upload,
+// for the periodic and final upload throughput
+func (m *Monitor) RecordUpload(bytes int64, took time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.uploads.add(bytes, took)
+	m.recentUploads.add(bytes, took)
+}
+
+// reportUploads logs the throughput of the uploads in u, if any. Callers
+// hold m.mu.
+func (m *Monitor) reportUploads(msg string, u uploadStats) {
+	if u.files == 0 {
+		return
+	}
+	m.logger.Info(msg,
+		"files", u.files,
+		"bytes", u.bytes,
+		"upload_time", u.took.Round(time.Millisecond),
+		"mb_per_sec", megabytesPerSecond(u.bytes, u.took),
+		"slowest_mb_per_sec", u.slowest,
+		"fastest_mb_per_sec", u.fastest,
+	)
+}
+
+// megabytesPerSecond is the throughput of n bytes in d, rounded to 0.01
+func megabytesPerSecond(n int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return math.Round(float64(n)/1e6/d.Seconds()*100) / 100
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportUploads(t *testing.T) {
+	var logs bytes.Buffer
+	m := NewMonitor(1, false, slog.New(slog.NewTextHandler(&logs, nil)))
+	m.RecordUpload(10_000_000, time.Second)
+	m.RecordUpload(30_000_000, time.Second)
+	m.Report()
+
+	want := `msg="Upload throughput" files=2 bytes=40000000 upload_time=2s mb_per_sec=20 slowest_mb_per_sec=10 fastest_mb_per_sec=30`
+	if !strings.Contains(logs.String(), want) {
+		t.Errorf("report missing %q:\n%s", want, logs.String())
+	}
+
+	// The interval report starts afresh; the summary covers the run
+	logs.Reset()
+	m.RecordUpload(5_000_000, 2*time.Second)
+	m.Report()
+	if !strings.Contains(logs.String(), `msg="Upload throughput" files=1 bytes=5000000 upload_time=2s mb_per_sec=2.5`) {
+		t.Errorf("second interval:\n%s", logs.String())
+	}
+	logs.Reset()
+	m.FinalReport()
+	if !strings.Contains(logs.String(), `msg="Upload summary" files=3 bytes=45000000 upload_time=4s mb_per_sec=11.25 slowest_mb_per_sec=2.5 fastest_mb_per_sec=30`) {
+		t.Errorf("final report:\n%s", logs.String())
+	}
+}
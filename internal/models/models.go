@@ -1,5 +1,7 @@
 package models
 
+//go:generate go run ../codec/gen -type Transaction -output transaction_codec.go
+
 import (
 	"time"
 
@@ -8,23 +10,43 @@ import (
 
 // Transaction represents a betting transaction
 type Transaction struct {
-	ID                    string          `json:"id" parquet:"name=id, type=BYTE_ARRAY, convertedtype=UTF8"`
-	ExternalTransactionID string          `json:"external_transaction_id" parquet:"name=external_transaction_id, type=BYTE_ARRAY, convertedtype=UTF8"`
-	VendorBetID           string          `json:"vendor_bet_id" parquet:"name=vendor_bet_id, type=BYTE_ARRAY, convertedtype=UTF8"`
-	RoundID               string          `json:"round_id" parquet:"name=round_id, type=BYTE_ARRAY, convertedtype=UTF8"`
-	VendorID              int             `json:"vendor_id" parquet:"name=vendor_id, type=INT32"`
-	VendorCode            string          `json:"vendor_code" parquet:"name=vendor_code, type=BYTE_ARRAY, convertedtype=UTF8"`
-	VendorLineID          int             `json:"vendor_line_id" parquet:"name=vendor_line_id, type=INT32"`
-	GameCategoryID        int             `json:"game_category_id" parquet:"name=game_category_id, type=INT32"`
-	HouseID               int             `json:"house_id" parquet:"name=house_id, type=INT32"`
-	MasterAgentID         int             `json:"master_agent_id" parquet:"name=master_agent_id, type=INT32"`
-	AgentID               int             `json:"agent_id" parquet:"name=agent_id, type=INT32"`
-	CurrencyID            int             `json:"currency_id" parquet:"name=currency_id, type=INT32"`
-	CurrencyCode          string          `json:"currency_code" parquet:"name=currency_code, type=BYTE_ARRAY, convertedtype=UTF8"`
-	BetAmount             string          `json:"bet_amount" parquet:"name=bet_amount, type=BYTE_ARRAY, convertedtype=UTF8"`
-	WinAmount             string          `json:"win_amount" parquet:"name=win_amount, type=BYTE_ARRAY, convertedtype=UTF8"`
-	WinLoss               string          `json:"win_loss" parquet:"name=win_loss, type=BYTE_ARRAY, convertedtype=UTF8"`
-	SettledAt             string          `json:"settled_at" parquet:"name=settled_at, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ID                    string          `json:"id" parquet:"id"`
+	ExternalTransactionID string          `json:"external_transaction_id" parquet:"external_transaction_id"`
+	VendorBetID           string          `json:"vendor_bet_id" parquet:"vendor_bet_id"`
+	RoundID               string          `json:"round_id" parquet:"round_id"`
+	VendorID              int             `json:"vendor_id" parquet:"vendor_id"`
+	VendorCode            string          `json:"vendor_code" parquet:"vendor_code"`
+	VendorLineID          int             `json:"vendor_line_id" parquet:"vendor_line_id"`
+	GameCategoryID        int             `json:"game_category_id" parquet:"game_category_id"`
+	HouseID               int             `json:"house_id" parquet:"house_id"`
+	MasterAgentID         int             `json:"master_agent_id" parquet:"master_agent_id"`
+	AgentID               int             `json:"agent_id" parquet:"agent_id"`
+	CurrencyID            int             `json:"currency_id" parquet:"currency_id"`
+	CurrencyCode          string          `json:"currency_code" parquet:"currency_code"`
+	BetAmount             string          `json:"bet_amount" parquet:"bet_amount"`
+	WinAmount             string          `json:"win_amount" parquet:"win_amount"`
+	WinLoss               string          `json:"win_loss" parquet:"win_loss"`
+	SettledAt             string          `json:"settled_at" parquet:"settled_at"`
+
+	// RateID is the simulated currency rate in effect at SettledAt, 0 when
+	// rates are not simulated. It travels as a Kafka header, not a column.
+	RateID int `json:"-" parquet:"-"`
+	// SubAgentID is the sub-agent below AgentID the transaction is attributed
+	// to, 0 when commissions are not simulated. Also a Kafka header only.
+	SubAgentID int `json:"-" parquet:"-"`
+	// PlayerID and Behavior identify a scripted responsible-gambling session
+	// the transaction belongs to, empty otherwise. Kafka headers only.
+	PlayerID string `json:"-" parquet:"-"`
+	Behavior string `json:"-" parquet:"-"`
+	// TraceID is a UUIDv7 shared by correlated transactions for lineage
+	// tests, empty when tracing is off. Also a Kafka header only.
+	TraceID string `json:"-" parquet:"-"`
+	// Region is the simulated origin region, empty unless regions are
+	// configured. Also a Kafka header only.
+	Region string `json:"-" parquet:"-"`
+	// Fields holds values drawn from custom reference datasets, keyed by
+	// field name, nil unless fields are configured. Kafka headers only.
+	Fields map[string]string `json:"-" parquet:"-"`
 }
 
 // CurrencyRate represents a currency conversion rate
@@ -63,6 +85,17 @@ type Currency struct {
 	Name string `json:"name"`
 }
 
+// House represents a brand (house) with the master agents and currencies it
+// operates and its share of traffic
+type House struct {
+	ID             int      `json:"id"`
+	Code           string   `json:"code"`
+	Name           string   `json:"name"`
+	Weight         float64  `json:"weight"`
+	MasterAgentIDs []int    `json:"master_agent_ids"`
+	Currencies     []string `json:"currencies"` // currency codes; empty allows all
+}
+
 // ReferenceData holds all reference data needed for message generation
 type ReferenceData struct {
 	CurrencyRates  []CurrencyRate
@@ -0,0 +1,183 @@
+// Code generated by codec/gen; DO NOT EDIT.
+
+package models
+
+import (
+	"strconv"
+
+	"github.com/supratick/message_producer/internal/codec"
+)
+
+// TransactionAvroSchema is the Avro schema of Transaction.AppendAvro
+const TransactionAvroSchema = `{"type":"record","name":"Transaction","namespace":"message_producer","fields":[{"name":"id","type":"string"},{"name":"external_transaction_id","type":"string"},{"name":"vendor_bet_id","type":"string"},{"name":"round_id","type":"string"},{"name":"vendor_id","type":"long"},{"name":"vendor_code","type":"string"},{"name":"vendor_line_id","type":"long"},{"name":"game_category_id","type":"long"},{"name":"house_id","type":"long"},{"name":"master_agent_id","type":"long"},{"name":"agent_id","type":"long"},{"name":"currency_id","type":"long"},{"name":"currency_code","type":"string"},{"name":"bet_amount","type":"string"},{"name":"win_amount","type":"string"},{"name":"win_loss","type":"string"},{"name":"settled_at","type":"string"}]}`
+
+// TransactionJSONSchema is the JSON Schema of Transaction.AppendJSON
+const TransactionJSONSchema = `{"$schema":"https://json-schema.org/draft/2020-12/schema","$id":"message_producer.Transaction","title":"Transaction","type":"object","properties":{"id":{"type":"string"},"external_transaction_id":{"type":"string"},"vendor_bet_id":{"type":"string"},"round_id":{"type":"string"},"vendor_id":{"type":"integer"},"vendor_code":{"type":"string"},"vendor_line_id":{"type":"integer"},"game_category_id":{"type":"integer"},"house_id":{"type":"integer"},"master_agent_id":{"type":"integer"},"agent_id":{"type":"integer"},"currency_id":{"type":"integer"},"currency_code":{"type":"string"},"bet_amount":{"type":"string"},"win_amount":{"type":"string"},"win_loss":{"type":"string"},"settled_at":{"type":"string"}},"required":["id","external_transaction_id","vendor_bet_id","round_id","vendor_id","vendor_code","vendor_line_id","game_category_id","house_id","master_agent_id","agent_id","currency_id","currency_code","bet_amount","win_amount","win_loss","settled_at"],"additionalProperties":false}`
+
+// TransactionProtoSchema is the proto3 definition of Transaction, fields numbered in order
+const TransactionProtoSchema = `syntax = "proto3";
+
+package message_producer;
+
+message Transaction {
+  string id = 1;
+  string external_transaction_id = 2;
+  string vendor_bet_id = 3;
+  string round_id = 4;
+  int64 vendor_id = 5;
+  string vendor_code = 6;
+  int64 vendor_line_id = 7;
+  int64 game_category_id = 8;
+  int64 house_id = 9;
+  int64 master_agent_id = 10;
+  int64 agent_id = 11;
+  int64 currency_id = 12;
+  string currency_code = 13;
+  string bet_amount = 14;
+  string win_amount = 15;
+  string win_loss = 16;
+  string settled_at = 17;
+}
+`
+
+// TransactionCSVHeader names the columns of Transaction.AppendCSV
+var TransactionCSVHeader = []string{"id", "external_transaction_id", "vendor_bet_id", "round_id", "vendor_id", "vendor_code", "vendor_line_id", "game_category_id", "house_id", "master_agent_id", "agent_id", "currency_id", "currency_code", "bet_amount", "win_amount", "win_loss", "settled_at"}
+
+// AppendJSON appends the JSON object json.Marshal would produce
+func (t *Transaction) AppendJSON(dst []byte) []byte {
+	dst = append(dst, `{"id":`...)
+	dst = codec.AppendJSONString(dst, t.ID)
+	dst = append(dst, `,"external_transaction_id":`...)
+	dst = codec.AppendJSONString(dst, t.ExternalTransactionID)
+	dst = append(dst, `,"vendor_bet_id":`...)
+	dst = codec.AppendJSONString(dst, t.VendorBetID)
+	dst = append(dst, `,"round_id":`...)
+	dst = codec.AppendJSONString(dst, t.RoundID)
+	dst = append(dst, `,"vendor_id":`...)
+	dst = strconv.AppendInt(dst, int64(t.VendorID), 10)
+	dst = append(dst, `,"vendor_code":`...)
+	dst = codec.AppendJSONString(dst, t.VendorCode)
+	dst = append(dst, `,"vendor_line_id":`...)
+	dst = strconv.AppendInt(dst, int64(t.VendorLineID), 10)
+	dst = append(dst, `,"game_category_id":`...)
+	dst = strconv.AppendInt(dst, int64(t.GameCategoryID), 10)
+	dst = append(dst, `,"house_id":`...)
+	dst = strconv.AppendInt(dst, int64(t.HouseID), 10)
+	dst = append(dst, `,"master_agent_id":`...)
+	dst = strconv.AppendInt(dst, int64(t.MasterAgentID), 10)
+	dst = append(dst, `,"agent_id":`...)
+	dst = strconv.AppendInt(dst, int64(t.AgentID), 10)
+	dst = append(dst, `,"currency_id":`...)
+	dst = strconv.AppendInt(dst, int64(t.CurrencyID), 10)
+	dst = append(dst, `,"currency_code":`...)
+	dst = codec.AppendJSONString(dst, t.CurrencyCode)
+	dst = append(dst, `,"bet_amount":`...)
+	dst = codec.AppendJSONString(dst, t.BetAmount)
+	dst = append(dst, `,"win_amount":`...)
+	dst = codec.AppendJSONString(dst, t.WinAmount)
+	dst = append(dst, `,"win_loss":`...)
+	dst = codec.AppendJSONString(dst, t.WinLoss)
+	dst = append(dst, `,"settled_at":`...)
+	dst = codec.AppendJSONString(dst, t.SettledAt)
+	return append(dst, '}')
+}
+
+// AppendCSV appends a CSV line in field order, quoted as encoding/csv would
+func (t *Transaction) AppendCSV(dst []byte) []byte {
+	dst = codec.AppendCSVField(dst, t.ID, false)
+	dst = append(dst, ',')
+	dst = codec.AppendCSVField(dst, t.ExternalTransactionID, false)
+	dst = append(dst, ',')
+	dst = codec.AppendCSVField(dst, t.VendorBetID, false)
+	dst = append(dst, ',')
+	dst = codec.AppendCSVField(dst, t.RoundID, false)
+	dst = append(dst, ',')
+	dst = strconv.AppendInt(dst, int64(t.VendorID), 10)
+	dst = append(dst, ',')
+	dst = codec.AppendCSVField(dst, t.VendorCode, false)
+	dst = append(dst, ',')
+	dst = strconv.AppendInt(dst, int64(t.VendorLineID), 10)
+	dst = append(dst, ',')
+	dst = strconv.AppendInt(dst, int64(t.GameCategoryID), 10)
+	dst = append(dst, ',')
+	dst = strconv.AppendInt(dst, int64(t.HouseID), 10)
+	dst = append(dst, ',')
+	dst = strconv.AppendInt(dst, int64(t.MasterAgentID), 10)
+	dst = append(dst, ',')
+	dst = strconv.AppendInt(dst, int64(t.AgentID), 10)
+	dst = append(dst, ',')
+	dst = strconv.AppendInt(dst, int64(t.CurrencyID), 10)
+	dst = append(dst, ',')
+	dst = codec.AppendCSVField(dst, t.CurrencyCode, false)
+	dst = append(dst, ',')
+	dst = codec.AppendCSVField(dst, t.BetAmount, false)
+	dst = append(dst, ',')
+	dst = codec.AppendCSVField(dst, t.WinAmount, false)
+	dst = append(dst, ',')
+	dst = codec.AppendCSVField(dst, t.WinLoss, false)
+	dst = append(dst, ',')
+	dst = codec.AppendCSVField(dst, t.SettledAt, false)
+	return append(dst, '\n')
+}
+
+// AppendAvro appends the Avro binary encoding of TransactionAvroSchema
+func (t *Transaction) AppendAvro(dst []byte) []byte {
+	dst = codec.AppendAvroString(dst, t.ID)
+	dst = codec.AppendAvroString(dst, t.ExternalTransactionID)
+	dst = codec.AppendAvroString(dst, t.VendorBetID)
+	dst = codec.AppendAvroString(dst, t.RoundID)
+	dst = codec.AppendAvroLong(dst, int64(t.VendorID))
+	dst = codec.AppendAvroString(dst, t.VendorCode)
+	dst = codec.AppendAvroLong(dst, int64(t.VendorLineID))
+	dst = codec.AppendAvroLong(dst, int64(t.GameCategoryID))
+	dst = codec.AppendAvroLong(dst, int64(t.HouseID))
+	dst = codec.AppendAvroLong(dst, int64(t.MasterAgentID))
+	dst = codec.AppendAvroLong(dst, int64(t.AgentID))
+	dst = codec.AppendAvroLong(dst, int64(t.CurrencyID))
+	dst = codec.AppendAvroString(dst, t.CurrencyCode)
+	dst = codec.AppendAvroString(dst, t.BetAmount)
+	dst = codec.AppendAvroString(dst, t.WinAmount)
+	dst = codec.AppendAvroString(dst, t.WinLoss)
+	dst = codec.AppendAvroString(dst, t.SettledAt)
+	return dst
+}
+
+// AppendMsgpack appends a MessagePack map keyed by the JSON field names
+func (t *Transaction) AppendMsgpack(dst []byte) []byte {
+	dst = codec.AppendMsgpackMapHeader(dst, 17)
+	dst = codec.AppendMsgpackString(dst, "id")
+	dst = codec.AppendMsgpackString(dst, t.ID)
+	dst = codec.AppendMsgpackString(dst, "external_transaction_id")
+	dst = codec.AppendMsgpackString(dst, t.ExternalTransactionID)
+	dst = codec.AppendMsgpackString(dst, "vendor_bet_id")
+	dst = codec.AppendMsgpackString(dst, t.VendorBetID)
+	dst = codec.AppendMsgpackString(dst, "round_id")
+	dst = codec.AppendMsgpackString(dst, t.RoundID)
+	dst = codec.AppendMsgpackString(dst, "vendor_id")
+	dst = codec.AppendMsgpackInt(dst, int64(t.VendorID))
+	dst = codec.AppendMsgpackString(dst, "vendor_code")
+	dst = codec.AppendMsgpackString(dst, t.VendorCode)
+	dst = codec.AppendMsgpackString(dst, "vendor_line_id")
+	dst = codec.AppendMsgpackInt(dst, int64(t.VendorLineID))
+	dst = codec.AppendMsgpackString(dst, "game_category_id")
+	dst = codec.AppendMsgpackInt(dst, int64(t.GameCategoryID))
+	dst = codec.AppendMsgpackString(dst, "house_id")
+	dst = codec.AppendMsgpackInt(dst, int64(t.HouseID))
+	dst = codec.AppendMsgpackString(dst, "master_agent_id")
+	dst = codec.AppendMsgpackInt(dst, int64(t.MasterAgentID))
+	dst = codec.AppendMsgpackString(dst, "agent_id")
+	dst = codec.AppendMsgpackInt(dst, int64(t.AgentID))
+	dst = codec.AppendMsgpackString(dst, "currency_id")
+	dst = codec.AppendMsgpackInt(dst, int64(t.CurrencyID))
+	dst = codec.AppendMsgpackString(dst, "currency_code")
+	dst = codec.AppendMsgpackString(dst, t.CurrencyCode)
+	dst = codec.AppendMsgpackString(dst, "bet_amount")
+	dst = codec.AppendMsgpackString(dst, t.BetAmount)
+	dst = codec.AppendMsgpackString(dst, "win_amount")
+	dst = codec.AppendMsgpackString(dst, t.WinAmount)
+	dst = codec.AppendMsgpackString(dst, "win_loss")
+	dst = codec.AppendMsgpackString(dst, t.WinLoss)
+	dst = codec.AppendMsgpackString(dst, "settled_at")
+	dst = codec.AppendMsgpackString(dst, t.SettledAt)
+	return dst
+}
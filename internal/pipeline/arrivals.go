@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// The arrival processes below are Jitters that keep state from one gap to
+// the next. They measure time in the gaps they return, so a state lasts as
+// long in the paced schedule as it was drawn to, however fast Pace runs.
+
+// ArrivalState is one rate level of a Markov-modulated Poisson process
+type ArrivalState struct {
+	Multiplier float64       // of the pacing rate, 0 = silent
+	Duration   time.Duration // mean time spent in the state
+}
+
+// MMPPArrivals is a Markov-modulated Poisson process: arrivals are Poisson
+// at the current state's rate, and the process stays in a state for an
+// exponentially distributed time before moving to another one picked
+// uniformly. Multipliers are scaled so the long-run rate is the pacing rate.
+// At least one state needs a positive multiplier.
+func MMPPArrivals(rng *rand.Rand, states []ArrivalState) Jitter {
+	var weighted, total float64
+	for _, s := range states {
+		weighted += s.Multiplier * float64(s.Duration)
+		total += float64(s.Duration)
+	}
+	scale := total / weighted
+
+	state := rng.Intn(len(states))
+	left := exponential(rng, states[state].Duration)
+	return func(mean time.Duration) time.Duration {
+		var gap time.Duration
+		for {
+			if m := states[state].Multiplier * scale; m > 0 {
+				next := exponential(rng, time.Duration(float64(mean)/m))
+				if next <= left {
+					left -= next
+					return gap + next
+				}
+			}
+			// The state ends first. Arrivals are memoryless, so the rest of
+			// the gap is drawn afresh in the next state.
+			gap += left
+			state = otherState(rng, state, len(states))
+			left = exponential(rng, states[state].Duration)
+		}
+	}
+}
+
+// otherState picks a state other than current uniformly, or current when it
+// is the only one
+func otherState(rng *rand.Rand, current, n int) int {
+	if n == 1 {
+		return current
+	}
+	next := rng.Intn(n - 1)
+	if next >= current {
+		next++
+	}
+	return next
+}
+
+// BurstyArrivals alternates bursts of Poisson arrivals with silences, both
+// lasting Pareto-distributed times with shape 3-2*hurst. The heavy tails
+// make the traffic self-similar: it stays bursty when averaged over longer
+// windows instead of smoothing out as Poisson traffic does. on and off are
+// the mean burst and silence lengths, and bursts run fast enough that the
+// long-run rate is the pacing rate. hurst must be at least 0.5 and below 1.
+func BurstyArrivals(rng *rand.Rand, hurst float64, on, off time.Duration) Jitter {
+	shape := 3 - 2*hurst
+	speedup := float64(on+off) / float64(on)
+	left := pareto(rng, shape, on)
+	return func(mean time.Duration) time.Duration {
+		var gap time.Duration
+		for {
+			next := exponential(rng, time.Duration(float64(mean)/speedup))
+			if next <= left {
+				left -= next
+				return gap + next
+			}
+			gap += left + pareto(rng, shape, off)
+			left = pareto(rng, shape, on)
+		}
+	}
+}
+
+// exponential draws an exponentially distributed duration
+func exponential(rng *rand.Rand, mean time.Duration) time.Duration {
+	return time.Duration(rng.ExpFloat64() * float64(mean))
+}
+
+// pareto draws a Pareto-distributed duration with the given shape (above 1)
+// and mean. Draws are capped at 1000 times the mean, which keeps them in
+// range and only trims the tail beyond any run's length.
+func pareto(rng *rand.Rand, shape float64, mean time.Duration) time.Duration {
+	scale := float64(mean) * (shape - 1) / shape
+	d := scale / math.Pow(1-rng.Float64(), 1/shape)
+	return time.Duration(min(d, 1000*float64(mean)))
+}
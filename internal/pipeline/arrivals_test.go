@@ -0,0 +1,67 @@
+package pipeline_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/pipeline"
+)
+
+// arrivalCounts draws n gaps of the given mean and counts the arrivals in
+// every window of the schedule they make
+func arrivalCounts(jitter pipeline.Jitter, mean time.Duration, n int, window time.Duration) (elapsed time.Duration, counts []int) {
+	for i := 0; i < n; i++ {
+		elapsed += jitter(mean)
+		w := int(elapsed / window)
+		for len(counts) <= w {
+			counts = append(counts, 0)
+		}
+		counts[w]++
+	}
+	return elapsed, counts
+}
+
+// dispersion is the variance of counts over their mean: about 1 for
+// Poisson arrivals, 0 for even ones and above 1 for bursty ones
+func dispersion(counts []int) float64 {
+	var sum, squares float64
+	for _, c := range counts {
+		sum += float64(c)
+		squares += float64(c) * float64(c)
+	}
+	mean := sum / float64(len(counts))
+	return (squares/float64(len(counts)) - mean*mean) / mean
+}
+
+func TestArrivalProcessesKeepTheRate(t *testing.T) {
+	const mean = time.Millisecond
+	processes := map[string]pipeline.Jitter{
+		"mmpp": pipeline.MMPPArrivals(rand.New(rand.NewSource(1)), []pipeline.ArrivalState{
+			{Multiplier: 1, Duration: time.Second},
+			{Multiplier: 5, Duration: 200 * time.Millisecond},
+			{Multiplier: 0, Duration: 100 * time.Millisecond},
+		}),
+		"bursty": pipeline.BurstyArrivals(rand.New(rand.NewSource(1)), 0.8, 100*time.Millisecond, 100*time.Millisecond),
+	}
+	for name, jitter := range processes {
+		elapsed, counts := arrivalCounts(jitter, mean, 500_000, 100*time.Millisecond)
+		// 500k arrivals at 1000/s take about 500s
+		if elapsed < 400*time.Second || elapsed > 600*time.Second {
+			t.Errorf("%s: 500k arrivals took %v, want about 500s", name, elapsed)
+		}
+		// Both are burstier than Poisson arrivals
+		if d := dispersion(counts); d < 2 {
+			t.Errorf("%s: dispersion %.2f, want above 2", name, d)
+		}
+	}
+}
+
+func TestMMPPArrivalsFollowTheState(t *testing.T) {
+	// A single state is a Poisson process at the pacing rate
+	poisson := pipeline.MMPPArrivals(rand.New(rand.NewSource(1)), []pipeline.ArrivalState{{Multiplier: 3, Duration: time.Second}})
+	_, counts := arrivalCounts(poisson, time.Millisecond, 200_000, 100*time.Millisecond)
+	if d := dispersion(counts); d < 0.8 || d > 1.2 {
+		t.Errorf("single state dispersion %.2f, want about 1", d)
+	}
+}
@@ -0,0 +1,93 @@
+package pipeline
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// BatchWriter is a sink that can write a batch assembled by the pipeline
+// in one call. WriteBatch must not keep batch after it returns.
+type BatchWriter interface {
+	Writer
+	WriteBatch(ctx context.Context, batch []*models.Transaction) error
+}
+
+// Batching groups a stage's transactions before they reach a BatchWriter. A
+// batch is written once it holds MaxRecords transactions or MaxBytes of
+// record data, or Linger after its first transaction arrived, whichever
+// comes first. Zero limits are not applied; all zero leaves batching to the
+// writer.
+type Batching struct {
+	MaxRecords int
+	MaxBytes   int
+	Linger     time.Duration
+}
+
+// Enabled reports whether any limit is set
+func (b Batching) Enabled() bool {
+	return b.MaxRecords > 0 || b.MaxBytes > 0 || b.Linger > 0
+}
+
+// writeBatches feeds w from input in batches until input is closed or ctx
+// is done, writing the partial batch either way. When a write fails it
+// returns the batch that failed with the error.
+func writeBatches(ctx context.Context, w BatchWriter, input <-chan *models.Transaction, b Batching, clock Clock) ([]*models.Transaction, error) {
+	var batch []*models.Transaction
+	if b.MaxRecords > 0 {
+		batch = make([]*models.Transaction, 0, b.MaxRecords)
+	}
+	size := 0
+	var linger <-chan time.Time
+
+	flush := func(ctx context.Context) ([]*models.Transaction, error) {
+		linger = nil
+		if len(batch) == 0 {
+			return nil, nil
+		}
+		var failed []*models.Transaction
+		err := w.WriteBatch(ctx, batch)
+		if err != nil {
+			failed = slices.Clone(batch)
+		}
+		clear(batch)
+		batch, size = batch[:0], 0
+		return failed, err
+	}
+
+	for {
+		select {
+		case txn, ok := <-input:
+			if !ok {
+				return flush(ctx)
+			}
+			if len(batch) == 0 && b.Linger > 0 {
+				linger = clock.After(b.Linger)
+			}
+			batch = append(batch, txn)
+			size += RecordSize(txn)
+			if (b.MaxRecords > 0 && len(batch) >= b.MaxRecords) || (b.MaxBytes > 0 && size >= b.MaxBytes) {
+				if failed, err := flush(ctx); err != nil {
+					return failed, err
+				}
+			}
+		case <-linger:
+			if failed, err := flush(ctx); err != nil {
+				return failed, err
+			}
+		case <-ctx.Done():
+			// Write what was taken from the source, as writers do on shutdown
+			return flush(context.WithoutCancel(ctx))
+		}
+	}
+}
+
+// RecordSize approximates the encoded size of txn for byte limits: its
+// string fields plus eight bytes per number
+func RecordSize(txn *models.Transaction) int {
+	return len(txn.ID) + len(txn.ExternalTransactionID) + len(txn.VendorBetID) + len(txn.RoundID) +
+		len(txn.VendorCode) + len(txn.CurrencyCode) + len(txn.BetAmount) + len(txn.WinAmount) +
+		len(txn.WinLoss) + len(txn.SettledAt) + 8*7
+}
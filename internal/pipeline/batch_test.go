@@ -0,0 +1,154 @@
+package pipeline_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+	"github.com/supratick/message_producer/internal/pipeline"
+	"github.com/supratick/message_producer/internal/pipeline/pipelinetest"
+)
+
+// batchSink records the size of every batch it is given
+type batchSink struct {
+	pipelinetest.Sink
+	mu      sync.Mutex
+	batches []int
+	written chan int
+}
+
+func (s *batchSink) WriteBatch(ctx context.Context, batch []*models.Transaction) error {
+	s.mu.Lock()
+	s.batches = append(s.batches, len(batch))
+	s.mu.Unlock()
+	if s.written != nil {
+		s.written <- len(batch)
+	}
+	return nil
+}
+
+func (s *batchSink) sizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.batches...)
+}
+
+func TestBatchingBySize(t *testing.T) {
+	txn := &models.Transaction{ID: "TXN-00000000"}
+	perRecord := pipeline.RecordSize(txn)
+
+	for _, tc := range []struct {
+		name  string
+		batch pipeline.Batching
+		want  []int
+	}{
+		{"records", pipeline.Batching{MaxRecords: 3}, []int{3, 3, 3, 1}},
+		{"bytes", pipeline.Batching{MaxBytes: 4 * perRecord}, []int{4, 4, 2}},
+		{"records before bytes", pipeline.Batching{MaxRecords: 2, MaxBytes: 4 * perRecord}, []int{2, 2, 2, 2, 2}},
+	} {
+		sink := &batchSink{}
+		p := pipeline.New(pipeline.FromChannel(source(10)), 16, discard)
+		p.Start(context.Background(), pipeline.Stage{Name: "batched", Writer: sink, Batch: tc.batch})
+		p.Dispatch(context.Background())
+		if err := p.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		if got := sink.sizes(); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: batches %v, want %v", tc.name, got, tc.want)
+		}
+		if len(sink.Received()) != 0 {
+			t.Errorf("%s: batched writer also read the stream", tc.name)
+		}
+	}
+}
+
+func TestBatchingLinger(t *testing.T) {
+	clock := pipelinetest.NewClock(time.Unix(0, 0))
+	sink := &batchSink{written: make(chan int, 4)}
+	ch := make(chan *models.Transaction, 4)
+	p := pipeline.New(pipeline.FromChannel(ch), 16, discard)
+	p.SetClock(clock)
+	p.Start(context.Background(), pipeline.Stage{
+		Name:   "batched",
+		Writer: sink,
+		Batch:  pipeline.Batching{MaxRecords: 100, Linger: 50 * time.Millisecond},
+	})
+	p.Dispatch(context.Background())
+
+	ch <- &models.Transaction{ID: "TXN-1"}
+	ch <- &models.Transaction{ID: "TXN-2"}
+	clock.BlockUntil(1)
+	clock.Advance(49 * time.Millisecond)
+	select {
+	case n := <-sink.written:
+		t.Fatalf("batch of %d written before the linger elapsed", n)
+	case <-time.After(20 * time.Millisecond):
+	}
+	clock.Advance(time.Millisecond)
+	if n := <-sink.written; n < 1 || n > 2 {
+		t.Fatalf("lingered batch has %d transactions", n)
+	}
+
+	close(ch)
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, n := range sink.sizes() {
+		total += n
+	}
+	if total != 2 {
+		t.Errorf("batches held %d transactions, want 2", total)
+	}
+}
+
+func TestBatchingNeedsBatchWriter(t *testing.T) {
+	sink := &pipelinetest.Sink{}
+	p := pipeline.New(pipeline.FromChannel(source(10)), 16, discard)
+	p.Start(context.Background(), pipeline.Stage{Name: "plain", Writer: sink, Batch: pipeline.Batching{MaxRecords: 3}})
+	p.Dispatch(context.Background())
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.Received()) != 10 {
+		t.Errorf("plain writer received %d transactions, want 10", len(sink.Received()))
+	}
+}
+
+// failingBatches fails its second batch
+type failingBatches struct {
+	batches int
+}
+
+func (*failingBatches) Write(context.Context, <-chan *models.Transaction) error { return nil }
+
+func (s *failingBatches) WriteBatch(context.Context, []*models.Transaction) error {
+	if s.batches++; s.batches == 2 {
+		return pipelinetest.ErrInjected
+	}
+	return nil
+}
+
+func TestBatchingHandsFailedBatchToStandby(t *testing.T) {
+	standby := &pipelinetest.Sink{}
+	p := pipeline.New(pipeline.FromChannel(source(10)), 16, discard)
+	p.Start(context.Background(), pipeline.Stage{
+		Name:   "batched",
+		Writer: &failingBatches{},
+		Batch:  pipeline.Batching{MaxRecords: 3},
+		Standby: func() (*pipeline.Stage, error) {
+			return &pipeline.Stage{Name: "standby", Writer: standby}, nil
+		},
+	})
+	p.Dispatch(context.Background())
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	received := standby.Received()
+	if len(received) != 7 || received[0].ID != "TXN-00000003" {
+		t.Fatalf("standby got %d starting %v", len(received), received[0])
+	}
+}
@@ -0,0 +1,121 @@
+package pipeline
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Dedup is a Writer that drops transactions whose ID is among the most
+// recent window distinct IDs it has passed on, so retried or replayed
+// records reach the wrapped writer once
+type Dedup struct {
+	writer  Writer
+	window  int
+	seen    map[string]struct{}
+	recent  []string // ring of the IDs in seen, oldest at next once full
+	next    int
+	passed  atomic.Int64
+	dropped atomic.Int64
+	unsent  []*models.Transaction // passed on but left unread by a failed writer
+}
+
+// NewDedup wraps w, remembering up to window IDs
+func NewDedup(w Writer, window int) *Dedup {
+	return &Dedup{
+		writer: w,
+		window: window,
+		seen:   make(map[string]struct{}, window),
+		recent: make([]string, 0, window),
+	}
+}
+
+// Write relays input to the wrapped writer without duplicates. Once the
+// wrapped writer returns it stops reading input, leaving the rest to the
+// pipeline, which may hand it to a standby.
+func (d *Dedup) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	out := make(chan *models.Transaction, cap(input))
+	stopped := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		defer close(out)
+		for {
+			var txn *models.Transaction
+			select {
+			case t, ok := <-input:
+				if !ok {
+					return
+				}
+				txn = t
+			case <-stopped:
+				return
+			}
+			if !d.first(txn.ID) {
+				d.dropped.Add(1)
+				continue
+			}
+			select {
+			case out <- txn:
+				d.passed.Add(1)
+			case <-stopped:
+				d.unsent = append(d.unsent, txn)
+				return
+			}
+		}
+	}()
+	err := d.writer.Write(ctx, out)
+	close(stopped)
+	<-exited
+	if err != nil {
+		// What the writer never read went no further than here
+		var unread []*models.Transaction
+		for txn := range out {
+			unread = append(unread, txn)
+			d.passed.Add(-1)
+		}
+		d.unsent = append(unread, d.unsent...)
+	}
+	return err
+}
+
+// Undelivered returns what the wrapped writer, once failed, did not
+// deliver: its own undelivered transactions, if it tells, then those it
+// never read. They no longer count as passed.
+func (d *Dedup) Undelivered() []*models.Transaction {
+	var txns []*models.Transaction
+	if u, ok := d.writer.(Undelivered); ok {
+		txns = u.Undelivered()
+		d.passed.Add(-int64(len(txns)))
+	}
+	txns = append(txns, d.unsent...)
+	d.unsent = nil
+	return txns
+}
+
+// first records id and reports whether it was not in the window
+func (d *Dedup) first(id string) bool {
+	if _, ok := d.seen[id]; ok {
+		return false
+	}
+	if len(d.recent) < d.window {
+		d.recent = append(d.recent, id)
+	} else {
+		delete(d.seen, d.recent[d.next])
+		d.recent[d.next] = id
+		d.next = (d.next + 1) % d.window
+	}
+	d.seen[id] = struct{}{}
+	return true
+}
+
+// Passed returns the number of distinct transactions handed to the writer
+func (d *Dedup) Passed() int64 {
+	return d.passed.Load()
+}
+
+// Dropped returns the number of duplicate transactions dropped
+func (d *Dedup) Dropped() int64 {
+	return d.dropped.Load()
+}
@@ -0,0 +1,114 @@
+package pipeline_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/supratick/message_producer/internal/models"
+	"github.com/supratick/message_producer/internal/pipeline"
+	"github.com/supratick/message_producer/internal/pipeline/pipelinetest"
+)
+
+func TestDedupDropsRepeatsWithinWindow(t *testing.T) {
+	// 0..9, then 5..14 replayed, then 0 once it has left a window of 10
+	var ids []int
+	for i := 0; i < 10; i++ {
+		ids = append(ids, i)
+	}
+	for i := 5; i < 15; i++ {
+		ids = append(ids, i)
+	}
+	ids = append(ids, 0)
+
+	ch := make(chan *models.Transaction, len(ids))
+	for _, i := range ids {
+		ch <- &models.Transaction{ID: fmt.Sprintf("TXN-%08d", i)}
+	}
+	close(ch)
+
+	sink := &pipelinetest.Sink{}
+	d := pipeline.NewDedup(sink, 10)
+	if err := d.Write(context.Background(), ch); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(sink.Received()); got != 16 {
+		t.Errorf("sink received %d transactions, want 16", got)
+	}
+	if d.Passed() != 16 || d.Dropped() != 5 {
+		t.Errorf("passed %d dropped %d, want 16 and 5", d.Passed(), d.Dropped())
+	}
+}
+
+func TestDedupInPipeline(t *testing.T) {
+	sink := &pipelinetest.Sink{FailAt: 4}
+	d := pipeline.NewDedup(sink, 100)
+	p := pipeline.New(pipeline.FromChannel(source(1000)), 16, discard)
+	p.Start(context.Background(), pipeline.Stage{Name: "files", Writer: d})
+	p.Dispatch(context.Background())
+	if err := p.Wait(); err == nil {
+		t.Fatal("sink failure not reported through the dedup stage")
+	}
+	if got := len(sink.Received()); got != 3 {
+		t.Errorf("sink accepted %d transactions before failing, want 3", got)
+	}
+}
+
+func TestDedupLeavesInputOnceWriterFails(t *testing.T) {
+	ch := make(chan *models.Transaction, 4)
+	go func() {
+		for i := 0; i < 4; i++ {
+			ch <- &models.Transaction{ID: fmt.Sprintf("TXN-%08d", i)}
+		}
+	}()
+	sink := &pipelinetest.Sink{FailAt: 4}
+	d := pipeline.NewDedup(sink, 100)
+	if err := d.Write(context.Background(), ch); err == nil {
+		t.Fatal("sink failure not returned")
+	}
+
+	// What arrives once the writer failed is left for a standby, not
+	// drained and dropped behind the pipeline's back
+	go func() {
+		for i := 4; i < 54; i++ {
+			ch <- &models.Transaction{ID: fmt.Sprintf("TXN-%08d", i)}
+		}
+		close(ch)
+	}()
+	left := 0
+	for range ch {
+		left++
+	}
+	if left != 50 {
+		t.Errorf("%d of 50 later transactions left in input", left)
+	}
+}
+
+func TestDedupHandsUndeliveredToStandby(t *testing.T) {
+	sink := &pipelinetest.Sink{FailAt: 4}
+	d := pipeline.NewDedup(sink, 100)
+	standby := &pipelinetest.Sink{}
+	p := pipeline.New(pipeline.FromChannel(source(1000)), 16, discard)
+	p.Start(context.Background(), pipeline.Stage{
+		Name:   "files",
+		Writer: d,
+		Standby: func() (*pipeline.Stage, error) {
+			return &pipeline.Stage{Name: "standby", Writer: standby}, nil
+		},
+	})
+	p.Dispatch(context.Background())
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Whatever the dedup stage had queued for the failed sink goes to the
+	// standby, in order, and no longer counts as passed
+	received := standby.Received()
+	if len(received) != 997 || received[0].ID != "TXN-00000003" || received[996].ID != "TXN-00000999" {
+		t.Fatalf("standby got %d starting %v", len(received), received[0])
+	}
+	if d.Passed() != 3 {
+		t.Errorf("passed = %d, want the 3 the sink wrote", d.Passed())
+	}
+}
@@ -0,0 +1,158 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Phase is a group of a run's goroutines. Phases depend on the ones before
+// them: generation feeds fan-out, which feeds the sinks.
+type Phase int
+
+const (
+	PhaseGenerate Phase = iota // generators and sources
+	PhaseFanOut                // pacing, replay and dispatch to the stages
+	PhaseSink                  // writers draining their input
+	numPhases
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseGenerate:
+		return "generate"
+	case PhaseFanOut:
+		return "fan-out"
+	case PhaseSink:
+		return "sink"
+	}
+	return fmt.Sprintf("phase(%d)", int(p))
+}
+
+// Lifecycle runs a run's goroutines by phase and shuts them down in order:
+// Stop ends generation, fan-out then drains what was generated and closes
+// the stage channels, and the sinks flush last, once their input is closed.
+// Each phase has its own context, so a stop does not cut the later phases
+// short; they get the drain timeout to finish before they are cancelled too.
+// Once a later phase has finished entirely, the earlier ones are cancelled,
+// as nothing is left to take their output.
+type Lifecycle struct {
+	contexts [numPhases]context.Context
+	cancels  [numPhases]context.CancelFunc
+	tasks    [numPhases]sync.WaitGroup
+	started  [numPhases]bool // phases Go has been called for
+	drain    time.Duration
+	stop     sync.Once
+	timer    *time.Timer // cancels the later phases once the drain timeout passes
+	mu       sync.Mutex
+	first    error
+	logger   *slog.Logger
+}
+
+// NewLifecycle creates a lifecycle whose phases are all cancelled with
+// parent. drain bounds how long fan-out and the sinks may take after Stop;
+// zero or less waits for them indefinitely.
+func NewLifecycle(parent context.Context, drain time.Duration, logger *slog.Logger) *Lifecycle {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	l := &Lifecycle{drain: drain, logger: logger}
+	for p := range l.contexts {
+		l.contexts[p], l.cancels[p] = context.WithCancel(parent)
+	}
+	return l
+}
+
+// Context returns the context of phase, cancelled when the phase has to
+// stop
+func (l *Lifecycle) Context(phase Phase) context.Context {
+	return l.contexts[phase]
+}
+
+// Go runs fn as part of phase. An error fails the run but leaves the other
+// goroutines running. Call Go before Wait.
+func (l *Lifecycle) Go(phase Phase, name string, fn func(ctx context.Context) error) {
+	l.mu.Lock()
+	l.started[phase] = true
+	l.mu.Unlock()
+	l.tasks[phase].Add(1)
+	go func() {
+		defer l.tasks[phase].Done()
+		if err := fn(l.contexts[phase]); err != nil {
+			l.logger.Error("Pipeline task failed", "phase", phase.String(), "task", name, "error", err)
+			l.fail(fmt.Errorf("%s: %w", name, err))
+		}
+	}()
+}
+
+// Stop ends generation. The later phases drain what was generated, and are
+// cancelled if they are still running after the drain timeout. Stop may be
+// called more than once.
+func (l *Lifecycle) Stop() {
+	l.stop.Do(func() {
+		l.cancels[PhaseGenerate]()
+		if l.drain > 0 {
+			l.mu.Lock()
+			l.timer = time.AfterFunc(l.drain, func() {
+				l.logger.Warn("Drain timeout passed, cancelling remaining stages", "drain_timeout", l.drain)
+				l.cancelFrom(PhaseFanOut)
+			})
+			l.mu.Unlock()
+		}
+	})
+}
+
+// Abort cancels every phase at once, dropping transactions not yet written
+func (l *Lifecycle) Abort() {
+	l.cancelFrom(PhaseGenerate)
+}
+
+func (l *Lifecycle) cancelFrom(phase Phase) {
+	for p := phase; p < numPhases; p++ {
+		l.cancels[p]()
+	}
+}
+
+// Stopped reports whether phase was cancelled rather than left to finish
+func (l *Lifecycle) Stopped(phase Phase) bool {
+	return l.contexts[phase].Err() != nil
+}
+
+func (l *Lifecycle) fail(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.first == nil {
+		l.first = err
+	}
+}
+
+// Wait blocks until every phase has finished, in order, and returns the
+// first error
+func (l *Lifecycle) Wait() error {
+	l.mu.Lock()
+	started := l.started
+	l.mu.Unlock()
+	for p := PhaseFanOut; p < numPhases; p++ {
+		if !started[p] {
+			continue
+		}
+		go func(p Phase) {
+			l.tasks[p].Wait()
+			for earlier := PhaseGenerate; earlier < p; earlier++ {
+				l.cancels[earlier]()
+			}
+		}(p)
+	}
+	for p := range l.tasks {
+		l.tasks[p].Wait()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	return l.first
+}
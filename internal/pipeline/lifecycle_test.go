@@ -0,0 +1,111 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+	"github.com/supratick/message_producer/internal/pipeline"
+	"github.com/supratick/message_producer/internal/pipeline/pipelinetest"
+)
+
+// startRun wires a generator that runs until stopped through a paced
+// fan-out into one sink, the way the producer does
+func startRun(run *pipeline.Lifecycle, sink *pipelinetest.Sink) *int {
+	genChan := make(chan *models.Transaction, 64)
+	txnChan := make(chan *models.Transaction, 64)
+	generated := new(int)
+	run.Go(pipeline.PhaseGenerate, "generator", func(ctx context.Context) error {
+		defer close(genChan)
+		for {
+			select {
+			case genChan <- &models.Transaction{ID: fmt.Sprintf("TXN-%08d", *generated)}:
+				*generated++
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+	run.Go(pipeline.PhaseFanOut, "pace", func(ctx context.Context) error {
+		pipeline.Pace(ctx, genChan, txnChan, 1_000_000, nil, nil, pipeline.SystemClock)
+		return nil
+	})
+
+	p := pipeline.New(pipeline.FromChannel(txnChan), 16, discard)
+	p.Start(run.Context(pipeline.PhaseSink), pipeline.Stage{Name: "sink", Writer: sink})
+	p.Dispatch(run.Context(pipeline.PhaseFanOut))
+	run.Go(pipeline.PhaseSink, "writers", func(context.Context) error { return p.Wait() })
+	return generated
+}
+
+func TestLifecycleStopDrainsGeneratedTransactions(t *testing.T) {
+	run := pipeline.NewLifecycle(context.Background(), time.Minute, discard)
+	sink := &pipelinetest.Sink{}
+	generated := startRun(run, sink)
+
+	time.Sleep(10 * time.Millisecond)
+	run.Stop()
+	if err := run.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every transaction that left the generator reached the sink
+	if got := len(sink.Received()); got != *generated || got == 0 {
+		t.Errorf("sink received %d of %d generated transactions", got, *generated)
+	}
+	if run.Stopped(pipeline.PhaseSink) {
+		t.Error("sinks were cancelled instead of drained")
+	}
+}
+
+func TestLifecycleDrainTimeout(t *testing.T) {
+	run := pipeline.NewLifecycle(context.Background(), 20*time.Millisecond, discard)
+	sink := &pipelinetest.Sink{Gate: make(chan struct{})} // never accepts a transaction
+	startRun(run, sink)
+
+	run.Stop()
+	done := make(chan error)
+	go func() { done <- run.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not return after the drain timeout")
+	}
+	if !run.Stopped(pipeline.PhaseSink) {
+		t.Error("stalled sink was not cancelled")
+	}
+}
+
+func TestLifecycleSinksFinishingStopsGeneration(t *testing.T) {
+	run := pipeline.NewLifecycle(context.Background(), 0, discard)
+	sink := &pipelinetest.Sink{FailAt: 100}
+	startRun(run, sink)
+
+	done := make(chan error)
+	go func() { done <- run.Wait() }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, pipelinetest.ErrInjected) {
+			t.Errorf("Wait = %v, want the sink failure", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("generation kept running with no sink left")
+	}
+}
+
+func TestLifecycleAbort(t *testing.T) {
+	run := pipeline.NewLifecycle(context.Background(), 0, discard)
+	sink := &pipelinetest.Sink{Gate: make(chan struct{})}
+	startRun(run, sink)
+
+	run.Abort()
+	if err := run.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if !run.Stopped(pipeline.PhaseSink) {
+		t.Error("sink phase not cancelled")
+	}
+}
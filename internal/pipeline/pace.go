@@ -0,0 +1,97 @@
+package pipeline
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Clock is the time source for pacing, replaced by a manual clock in tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// SystemClock is the wall clock
+var SystemClock Clock = systemClock{}
+
+// Spike multiplies the rate for Duration starting Start into the run
+type Spike struct {
+	Start      time.Duration
+	Duration   time.Duration
+	Multiplier float64
+}
+
+// Jitter draws the gap before the next transaction from the mean gap at the
+// current rate, so arrivals are not unnaturally even
+type Jitter func(mean time.Duration) time.Duration
+
+// ExponentialJitter draws exponentially distributed gaps, which makes the
+// arrivals a Poisson process
+func ExponentialJitter(rng *rand.Rand) Jitter {
+	return func(mean time.Duration) time.Duration {
+		return time.Duration(rng.ExpFloat64() * float64(mean))
+	}
+}
+
+// UniformJitter draws gaps uniformly within spread times the mean either
+// side of it; spread 0.5 varies them from half to one and a half times the
+// mean
+func UniformJitter(rng *rand.Rand, spread float64) Jitter {
+	return func(mean time.Duration) time.Duration {
+		return time.Duration((1 + spread*(2*rng.Float64()-1)) * float64(mean))
+	}
+}
+
+// Pace relays transactions from in to out at rate per second, multiplied by
+// any active spike, and closes out when in is drained. A non-nil jitter
+// varies the gaps around the mean while keeping the rate on average. On
+// cancellation in is still drained so generators blocked on it can exit.
+func Pace(ctx context.Context, in <-chan *models.Transaction, out chan<- *models.Transaction, rate int, spikes []Spike, jitter Jitter, clock Clock) {
+	defer close(out)
+	defer func() {
+		go func() {
+			for range in {
+			}
+		}()
+	}()
+
+	start := clock.Now()
+	due := start
+	for txn := range in {
+		elapsed := due.Sub(start)
+		current := float64(rate)
+		for _, s := range spikes {
+			if elapsed >= s.Start && elapsed < s.Start+s.Duration {
+				current *= s.Multiplier
+			}
+		}
+		gap := time.Duration(float64(time.Second) / current)
+		if jitter != nil {
+			gap = jitter(gap)
+		}
+		due = due.Add(gap)
+
+		// Sleep in batches rather than per message at high rates
+		if wait := due.Sub(clock.Now()); wait > time.Millisecond {
+			select {
+			case <-clock.After(wait):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case out <- txn:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package pipeline_test
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+	"github.com/supratick/message_producer/internal/pipeline"
+	"github.com/supratick/message_producer/internal/pipeline/pipelinetest"
+)
+
+// expectNone fails if out has a transaction ready
+func expectNone(t *testing.T, out <-chan *models.Transaction) {
+	t.Helper()
+	select {
+	case txn, ok := <-out:
+		t.Fatalf("unexpected receive %v (open %v) before the clock advanced", txn, ok)
+	default:
+	}
+}
+
+func TestPaceReleasesOnSchedule(t *testing.T) {
+	clock := pipelinetest.NewClock(time.Unix(0, 0))
+	out := make(chan *models.Transaction)
+	go pipeline.Pace(context.Background(), source(3), out, 10, nil, nil, clock)
+
+	for i := 0; i < 3; i++ {
+		clock.BlockUntil(1)
+		expectNone(t, out)
+		clock.Advance(100 * time.Millisecond)
+		if _, ok := <-out; !ok {
+			t.Fatalf("out closed after %d transactions", i)
+		}
+	}
+	if _, ok := <-out; ok {
+		t.Fatal("out not closed once the input drained")
+	}
+}
+
+func TestPaceAppliesSpikes(t *testing.T) {
+	clock := pipelinetest.NewClock(time.Unix(0, 0))
+	out := make(chan *models.Transaction)
+	spikes := []pipeline.Spike{{Start: 0, Duration: time.Second, Multiplier: 4}}
+	go pipeline.Pace(context.Background(), source(2), out, 10, spikes, nil, clock)
+
+	// 40/s during the spike is one transaction every 25ms
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(24 * time.Millisecond)
+		clock.BlockUntil(1)
+		expectNone(t, out)
+		clock.Advance(time.Millisecond)
+		<-out
+	}
+}
+
+func TestPaceAppliesJitter(t *testing.T) {
+	clock := pipelinetest.NewClock(time.Unix(0, 0))
+	out := make(chan *models.Transaction)
+	// Alternate a short and a long gap around the 100ms mean
+	var n int
+	jitter := func(mean time.Duration) time.Duration {
+		n++
+		if n%2 == 1 {
+			return mean / 2
+		}
+		return mean * 3 / 2
+	}
+	go pipeline.Pace(context.Background(), source(2), out, 10, nil, jitter, clock)
+
+	for _, gap := range []time.Duration{50 * time.Millisecond, 150 * time.Millisecond} {
+		clock.BlockUntil(1)
+		clock.Advance(gap - time.Millisecond)
+		clock.BlockUntil(1)
+		expectNone(t, out)
+		clock.Advance(time.Millisecond)
+		<-out
+	}
+}
+
+func TestJitterKeepsTheMean(t *testing.T) {
+	const mean = 10 * time.Millisecond
+	jitters := map[string]pipeline.Jitter{
+		"exponential": pipeline.ExponentialJitter(rand.New(rand.NewSource(1))),
+		"uniform":     pipeline.UniformJitter(rand.New(rand.NewSource(1)), 0.5),
+	}
+	for name, jitter := range jitters {
+		var total time.Duration
+		lo, hi := time.Duration(math.MaxInt64), time.Duration(0)
+		for i := 0; i < 10000; i++ {
+			gap := jitter(mean)
+			total += gap
+			lo, hi = min(lo, gap), max(hi, gap)
+		}
+		if avg := total / 10000; avg < mean*95/100 || avg > mean*105/100 {
+			t.Errorf("%s: average gap %v, want about %v", name, avg, mean)
+		}
+		if name == "uniform" && (lo < mean/2 || hi > mean*3/2) {
+			t.Errorf("uniform: gaps from %v to %v, want within 5ms to 15ms", lo, hi)
+		}
+	}
+}
+
+func TestPaceCancelDrainsInput(t *testing.T) {
+	clock := pipelinetest.NewClock(time.Unix(0, 0))
+	in := make(chan *models.Transaction)
+	out := make(chan *models.Transaction)
+	ctx, cancel := context.WithCancel(context.Background())
+	go pipeline.Pace(ctx, in, out, 1, nil, nil, clock)
+
+	in <- &models.Transaction{}
+	clock.BlockUntil(1)
+	cancel()
+
+	if _, ok := <-out; ok {
+		t.Fatal("out not closed on cancellation")
+	}
+	// A generator still sending must not block
+	for i := 0; i < 10; i++ {
+		in <- &models.Transaction{}
+	}
+	close(in)
+}
@@ -0,0 +1,378 @@
+// Package pipeline fans generated transactions out to the configured writers
+// and paces them at a scenario rate. The transaction source and the clock are
+// injected so tests can drive a run deterministically.
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Writer is the part of a sink the pipeline drives
+type Writer interface {
+	Write(ctx context.Context, input <-chan *models.Transaction) error
+}
+
+// Source hands out transactions until it is drained. The ring transport
+// satisfies it directly; FromChannel adapts a channel.
+type Source interface {
+	Get() (*models.Transaction, bool)
+}
+
+type chanSource <-chan *models.Transaction
+
+func (c chanSource) Get() (*models.Transaction, bool) {
+	txn, ok := <-c
+	return txn, ok
+}
+
+// FromChannel returns a source that drains ch until it is closed
+func FromChannel(ch <-chan *models.Transaction) Source {
+	return chanSource(ch)
+}
+
+// Dispatch modes: how the source is shared between stages
+const (
+	DispatchBroadcast = "broadcast" // every stage receives every transaction (default)
+	DispatchSplit     = "split"     // each transaction goes to whichever stage takes it first
+)
+
+// Stage is one writer fed by the pipeline
+type Stage struct {
+	Name   string
+	Writer Writer
+	// Done runs once Write has returned, e.g. to report the writer's counts
+	Done func()
+	// Batch, when enabled, has the pipeline assemble batches for a
+	// BatchWriter instead of the writer reading the stream itself
+	Batch Batching
+	// Standby, when set, is opened once Writer fails and the stage carries
+	// on into it: first with what Writer took but did not deliver, then
+	// with the transactions still to come. The failure is only reported if
+	// the standby cannot be opened or fails too.
+	Standby func() (*Stage, error)
+}
+
+// Undelivered is implemented by writers that can say, once Write has
+// failed, which of the transactions they took were not delivered. The
+// writer gives them up: a standby writes them in its place.
+type Undelivered interface {
+	Undelivered() []*models.Transaction
+}
+
+// StageError is a writer failing: its Write returned an error and the stage
+// stopped
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("%s writer: %v", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Pipeline feeds every stage from a shared source through the stage's own
+// bounded channel. By default a dispatcher copies each transaction to every
+// stage, so all sinks see the same transactions and the slowest paces the
+// rest once its buffer is full. In split mode stages pull from the source
+// independently and each writes a share of it.
+type Pipeline struct {
+	source     Source
+	bufferSize int
+	split      bool
+	outlets    []outlet // stages awaiting Dispatch in broadcast mode
+	dispatch   sync.Once
+	wg         sync.WaitGroup
+	mu         sync.Mutex
+	first      error
+	onError    func(*StageError)
+	clock      Clock
+	logger     *slog.Logger
+}
+
+// outlet is a stage's channel and the signal that its writer has returned
+type outlet struct {
+	input   chan *models.Transaction
+	stopped <-chan struct{}
+}
+
+// New creates a pipeline over source with bufferSize transactions buffered
+// per stage
+func New(source Source, bufferSize int, logger *slog.Logger) *Pipeline {
+	return &Pipeline{
+		source:     source,
+		bufferSize: bufferSize,
+		clock:      SystemClock,
+		logger:     logger,
+	}
+}
+
+// SetClock replaces the clock batch lingering is timed on. Call before
+// Start.
+func (p *Pipeline) SetClock(clock Clock) {
+	p.clock = clock
+}
+
+// SetOnError has handler called as soon as a stage fails, from that stage's
+// goroutine, so the caller can stop the run or carry on without waiting for
+// Wait. The other stages keep running either way. Call before Start.
+func (p *Pipeline) SetOnError(handler func(*StageError)) {
+	p.onError = handler
+}
+
+// SetDispatch selects how transactions are shared between stages: broadcast
+// (or empty) or split. Call before Start.
+func (p *Pipeline) SetDispatch(mode string) error {
+	switch mode {
+	case "", DispatchBroadcast:
+		p.split = false
+	case DispatchSplit:
+		p.split = true
+	default:
+		return fmt.Errorf("unknown dispatch mode: %s", mode)
+	}
+	return nil
+}
+
+// Start runs the stage's writer in the background. In broadcast mode it
+// receives transactions once Dispatch is called.
+func (p *Pipeline) Start(ctx context.Context, stage Stage) {
+	input := make(chan *models.Transaction, p.bufferSize)
+	stopped := make(chan struct{})
+	if p.split {
+		go p.relay(ctx, input, stopped)
+	} else {
+		p.outlets = append(p.outlets, outlet{input: input, stopped: stopped})
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		var handed []*models.Transaction // left undelivered by a failed writer
+		for {
+			var c *carrier
+			in := (<-chan *models.Transaction)(input)
+			if len(handed) > 0 {
+				c = carry(handed, input)
+				in = c.out
+			}
+			undelivered, err := p.write(ctx, stage, in)
+			if c != nil {
+				undelivered = append(undelivered, c.stop()...)
+			}
+			if err != nil && stage.Standby != nil && ctx.Err() == nil {
+				standby, openErr := stage.Standby()
+				if openErr == nil {
+					p.logger.Warn("Writer failed, failing over to its standby",
+						"writer", stage.Name,
+						"standby", standby.Name,
+						"handed_over", len(undelivered),
+						"error", err,
+					)
+					if stage.Done != nil {
+						stage.Done()
+					}
+					stage, handed = *standby, undelivered
+					continue
+				}
+				err = fmt.Errorf("%w; standby failed to open: %v", err, openErr)
+			}
+
+			close(stopped)
+			if err != nil {
+				p.logger.Error("Writer error", "writer", stage.Name, "undelivered", len(undelivered), "error", err)
+				stageErr := &StageError{Stage: stage.Name, Err: err}
+				p.fail(stageErr)
+				if p.onError != nil {
+					p.onError(stageErr)
+				}
+			}
+			if stage.Done != nil {
+				stage.Done()
+			}
+			return
+		}
+	}()
+}
+
+// write drives stage's writer over input: the writer itself, or the
+// pipeline's batching in front of a BatchWriter. If the writer fails it also
+// returns the transactions it took but did not deliver, as far as the
+// writer, or else the batch it failed on, tells.
+func (p *Pipeline) write(ctx context.Context, stage Stage, input <-chan *models.Transaction) ([]*models.Transaction, error) {
+	var failed []*models.Transaction
+	var err error
+	bw, ok := stage.Writer.(BatchWriter)
+	switch {
+	case !stage.Batch.Enabled():
+		err = stage.Writer.Write(ctx, input)
+	case !ok:
+		p.logger.Warn("Writer batches on its own; pipeline batching ignored", "writer", stage.Name)
+		err = stage.Writer.Write(ctx, input)
+	default:
+		failed, err = writeBatches(ctx, bw, input, stage.Batch, p.clock)
+	}
+	if err == nil {
+		return nil, nil
+	}
+	if u, ok := stage.Writer.(Undelivered); ok {
+		return u.Undelivered(), err
+	}
+	return failed, err
+}
+
+// carrier feeds a standby the transactions its failed writer handed over,
+// then the stage's input
+type carrier struct {
+	out   chan *models.Transaction
+	quit  chan struct{}
+	done  chan struct{}
+	taken []*models.Transaction // in hand when stopped
+}
+
+func carry(handed []*models.Transaction, input <-chan *models.Transaction) *carrier {
+	c := &carrier{
+		out:  make(chan *models.Transaction, cap(input)),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(c.done)
+		defer close(c.out)
+		for i, txn := range handed {
+			select {
+			case c.out <- txn:
+			case <-c.quit:
+				c.taken = handed[i:]
+				return
+			}
+		}
+		for {
+			select {
+			case txn, ok := <-input:
+				if !ok {
+					return
+				}
+				select {
+				case c.out <- txn:
+				case <-c.quit:
+					c.taken = []*models.Transaction{txn}
+					return
+				}
+			case <-c.quit:
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// stop ends the carrier once its writer has returned and returns, in order,
+// the transactions the writer never read
+func (c *carrier) stop() []*models.Transaction {
+	close(c.quit)
+	<-c.done
+	var unread []*models.Transaction
+	for txn := range c.out {
+		unread = append(unread, txn)
+	}
+	return append(unread, c.taken...)
+}
+
+// Dispatch starts copying the source to the stages in broadcast mode. Call
+// it once every stage has been started; in split mode stages are fed from
+// Start and Dispatch does nothing.
+func (p *Pipeline) Dispatch(ctx context.Context) {
+	if p.split {
+		return
+	}
+	p.dispatch.Do(func() {
+		go p.broadcast(ctx, p.outlets)
+	})
+}
+
+// broadcast copies every transaction to each stage whose writer is still
+// running. Once all writers have returned it stops, draining the source
+// first if the run was cancelled so generators blocked on it can exit.
+func (p *Pipeline) broadcast(ctx context.Context, outlets []outlet) {
+	defer func() {
+		for _, o := range outlets {
+			close(o.input)
+		}
+	}()
+
+	running := make([]bool, len(outlets))
+	live := len(outlets)
+	for i := range running {
+		running[i] = true
+	}
+	for live > 0 {
+		txn, ok := p.source.Get()
+		if !ok {
+			return
+		}
+		for i, o := range outlets {
+			if !running[i] {
+				continue
+			}
+			select {
+			case o.input <- txn:
+			case <-o.stopped:
+				running[i] = false
+				live--
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		for _, ok := p.source.Get(); ok; _, ok = p.source.Get() {
+		}
+	}
+}
+
+// relay feeds one stage's channel from the shared source. Once the writer
+// has returned it stops taking transactions meant for the other stages,
+// unless the run was cancelled, in which case it drains the source so
+// generators blocked on it can exit.
+func (p *Pipeline) relay(ctx context.Context, out chan<- *models.Transaction, stopped <-chan struct{}) {
+	defer close(out)
+	for {
+		txn, ok := p.source.Get()
+		if !ok {
+			return
+		}
+		select {
+		case out <- txn:
+		case <-stopped:
+			if ctx.Err() != nil {
+				for _, ok := p.source.Get(); ok; _, ok = p.source.Get() {
+				}
+			}
+			return
+		}
+	}
+}
+
+func (p *Pipeline) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.first == nil {
+		p.first = err
+	}
+}
+
+// Wait blocks until every started writer has returned and reports the first
+// writer error, a *StageError
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.first
+}
@@ -0,0 +1,310 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+	"github.com/supratick/message_producer/internal/pipeline"
+	"github.com/supratick/message_producer/internal/pipeline/pipelinetest"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// source returns a closed channel holding n transactions with distinct IDs
+func source(n int) chan *models.Transaction {
+	ch := make(chan *models.Transaction, n)
+	for i := 0; i < n; i++ {
+		ch <- &models.Transaction{ID: fmt.Sprintf("TXN-%08d", i)}
+	}
+	close(ch)
+	return ch
+}
+
+func TestPipelineBroadcastsToEveryStage(t *testing.T) {
+	sinks := []*pipelinetest.Sink{{}, {}, {}}
+	p := pipeline.New(pipeline.FromChannel(source(1000)), 16, discard)
+	for i, sink := range sinks {
+		p.Start(context.Background(), pipeline.Stage{Name: fmt.Sprint(i), Writer: sink})
+	}
+	p.Dispatch(context.Background())
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, sink := range sinks {
+		received := sink.Received()
+		if len(received) != 1000 {
+			t.Fatalf("sink %d received %d transactions, want 1000", i, len(received))
+		}
+		for j, txn := range received {
+			if want := fmt.Sprintf("TXN-%08d", j); txn.ID != want {
+				t.Fatalf("sink %d: transaction %d is %s, want %s", i, j, txn.ID, want)
+			}
+		}
+	}
+}
+
+func TestPipelineBroadcastSurvivesFailedStage(t *testing.T) {
+	failing := &pipelinetest.Sink{FailAt: 3}
+	healthy := &pipelinetest.Sink{}
+	p := pipeline.New(pipeline.FromChannel(source(500)), 4, discard)
+	p.Start(context.Background(), pipeline.Stage{Name: "failing", Writer: failing})
+	p.Start(context.Background(), pipeline.Stage{Name: "healthy", Writer: healthy})
+	p.Dispatch(context.Background())
+
+	if err := p.Wait(); !errors.Is(err, pipelinetest.ErrInjected) {
+		t.Fatalf("Wait() = %v, want ErrInjected", err)
+	}
+	if healthy.Count() != 500 {
+		t.Fatalf("healthy sink accepted %d, want 500", healthy.Count())
+	}
+}
+
+func TestPipelineReportsStageErrors(t *testing.T) {
+	failing := &pipelinetest.Sink{FailAt: 3}
+	healthy := &pipelinetest.Sink{}
+	p := pipeline.New(pipeline.FromChannel(source(100)), 4, discard)
+	var reported []*pipeline.StageError
+	p.SetOnError(func(err *pipeline.StageError) {
+		reported = append(reported, err)
+	})
+	p.Start(context.Background(), pipeline.Stage{Name: "failing", Writer: failing})
+	p.Start(context.Background(), pipeline.Stage{Name: "healthy", Writer: healthy})
+	p.Dispatch(context.Background())
+
+	err := p.Wait()
+	var stageErr *pipeline.StageError
+	if !errors.As(err, &stageErr) || stageErr.Stage != "failing" {
+		t.Fatalf("Wait() = %v, want a failing stage error", err)
+	}
+	// Only the failing stage is reported, once
+	if len(reported) != 1 || reported[0].Stage != "failing" || !errors.Is(reported[0], pipelinetest.ErrInjected) {
+		t.Fatalf("reported %v", reported)
+	}
+}
+
+func TestPipelineFailsOverToStandby(t *testing.T) {
+	failing := &pipelinetest.Sink{FailAt: 3}
+	standby := &pipelinetest.Sink{}
+	p := pipeline.New(pipeline.FromChannel(source(100)), 4, discard)
+	var reported int
+	p.SetOnError(func(*pipeline.StageError) { reported++ })
+	var done []string
+	p.Start(context.Background(), pipeline.Stage{
+		Name:   "primary",
+		Writer: failing,
+		Done:   func() { done = append(done, "primary") },
+		Standby: func() (*pipeline.Stage, error) {
+			return &pipeline.Stage{Name: "standby", Writer: standby, Done: func() { done = append(done, "standby") }}, nil
+		},
+	})
+	p.Dispatch(context.Background())
+
+	if err := p.Wait(); err != nil || reported != 0 {
+		t.Fatalf("Wait() = %v with %d reported, want a silent failover", err, reported)
+	}
+	// The transaction the primary failed on is handed over with the rest
+	received := standby.Received()
+	if failing.Count() != 2 || len(received) != 98 || received[0].ID != "TXN-00000002" {
+		t.Fatalf("primary took %d, standby %d starting %v", failing.Count(), len(received), received[0])
+	}
+	if strings.Join(done, ",") != "primary,standby" {
+		t.Errorf("done ran for %v", done)
+	}
+}
+
+func TestPipelineReportsFailedStandby(t *testing.T) {
+	p := pipeline.New(pipeline.FromChannel(source(10)), 4, discard)
+	p.Start(context.Background(), pipeline.Stage{
+		Name:    "primary",
+		Writer:  &pipelinetest.Sink{FailAt: 1},
+		Standby: func() (*pipeline.Stage, error) { return nil, errors.New("no space") },
+	})
+	p.Dispatch(context.Background())
+
+	err := p.Wait()
+	if !errors.Is(err, pipelinetest.ErrInjected) || !strings.Contains(err.Error(), "standby failed to open: no space") {
+		t.Fatalf("Wait() = %v", err)
+	}
+}
+
+func TestPipelineBroadcastCancelDrainsSource(t *testing.T) {
+	src := make(chan *models.Transaction)
+	generated := make(chan struct{})
+	go func() {
+		defer close(generated)
+		defer close(src)
+		for i := 0; i < 1000; i++ {
+			src <- &models.Transaction{}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := pipeline.New(pipeline.FromChannel(src), 8, discard)
+	p.Start(ctx, pipeline.Stage{Name: "stalled", Writer: &pipelinetest.Sink{Gate: make(chan struct{})}})
+	p.Start(ctx, pipeline.Stage{Name: "fast", Writer: &pipelinetest.Sink{}})
+	p.Dispatch(ctx)
+	cancel()
+
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-generated:
+	case <-time.After(10 * time.Second):
+		t.Fatal("generator still blocked on the source after cancellation")
+	}
+}
+
+func TestPipelineSplitsSourceAcrossStages(t *testing.T) {
+	a, b := &pipelinetest.Sink{}, &pipelinetest.Sink{}
+	p := pipeline.New(pipeline.FromChannel(source(1000)), 16, discard)
+	if err := p.SetDispatch(pipeline.DispatchSplit); err != nil {
+		t.Fatal(err)
+	}
+
+	var done atomic.Int32
+	for _, stage := range []pipeline.Stage{{Name: "a", Writer: a}, {Name: "b", Writer: b}} {
+		stage.Done = func() { done.Add(1) }
+		p.Start(context.Background(), stage)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	if done.Load() != 2 {
+		t.Fatalf("Done ran %d times, want 2", done.Load())
+	}
+	seen := make(map[string]bool)
+	for _, txn := range append(a.Received(), b.Received()...) {
+		if seen[txn.ID] {
+			t.Fatalf("%s delivered twice", txn.ID)
+		}
+		seen[txn.ID] = true
+	}
+	if len(seen) != 1000 {
+		t.Fatalf("delivered %d transactions, want 1000", len(seen))
+	}
+}
+
+func TestPipelineFailedStageLeavesSourceToOthers(t *testing.T) {
+	failing := &pipelinetest.Sink{FailAt: 3}
+	healthy := &pipelinetest.Sink{}
+	p := pipeline.New(pipeline.FromChannel(source(500)), 4, discard)
+	if err := p.SetDispatch(pipeline.DispatchSplit); err != nil {
+		t.Fatal(err)
+	}
+	p.Start(context.Background(), pipeline.Stage{Name: "failing", Writer: failing})
+	p.Start(context.Background(), pipeline.Stage{Name: "healthy", Writer: healthy})
+
+	err := p.Wait()
+	if !errors.Is(err, pipelinetest.ErrInjected) {
+		t.Fatalf("Wait() = %v, want ErrInjected", err)
+	}
+	if !strings.HasPrefix(err.Error(), "failing writer: ") {
+		t.Fatalf("error %q does not name the stage", err)
+	}
+	if failing.Count() != 2 {
+		t.Fatalf("failing sink accepted %d, want 2", failing.Count())
+	}
+	// At most the failed transaction, the failing stage's buffer and the one
+	// its relay held are lost
+	if lost := 500 - failing.Count() - healthy.Count(); lost > 1+4+1 {
+		t.Fatalf("%d transactions lost after the failure", lost)
+	}
+}
+
+func TestPipelineBackpressure(t *testing.T) {
+	const bufferSize, total = 4, 100
+	gate := make(chan struct{})
+	sink := &pipelinetest.Sink{Gate: gate}
+
+	src := make(chan *models.Transaction)
+	var sent atomic.Int64
+	go func() {
+		defer close(src)
+		for i := 0; i < total; i++ {
+			src <- &models.Transaction{ID: fmt.Sprintf("TXN-%08d", i)}
+			sent.Add(1)
+		}
+	}()
+
+	p := pipeline.New(pipeline.FromChannel(src), bufferSize, discard)
+	p.Start(context.Background(), pipeline.Stage{Name: "slow", Writer: sink})
+	p.Dispatch(context.Background())
+
+	for step := 1; step <= 10; step++ {
+		gate <- struct{}{}
+		// The sink has taken at most step transactions; beyond those only the
+		// stage buffer and the relay's hand can hold any
+		if n, limit := sent.Load(), int64(step+bufferSize+1); n > limit {
+			t.Fatalf("step %d: source sent %d transactions, want at most %d", step, n, limit)
+		}
+	}
+
+	close(gate)
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if sink.Count() != total {
+		t.Fatalf("sink accepted %d, want %d", sink.Count(), total)
+	}
+}
+
+func TestPipelineCancelDrainsSource(t *testing.T) {
+	sink := &pipelinetest.Sink{Gate: make(chan struct{})}
+	src := make(chan *models.Transaction)
+	generated := make(chan struct{})
+	go func() {
+		defer close(generated)
+		defer close(src)
+		for i := 0; i < 1000; i++ {
+			src <- &models.Transaction{}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := pipeline.New(pipeline.FromChannel(src), 8, discard)
+	p.Start(ctx, pipeline.Stage{Name: "stalled", Writer: sink})
+	p.Dispatch(ctx)
+	cancel()
+
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-generated:
+	case <-time.After(10 * time.Second):
+		t.Fatal("generator still blocked on the source after cancellation")
+	}
+}
+
+func TestSinkLatencyFollowsClock(t *testing.T) {
+	clock := pipelinetest.NewClock(time.Unix(0, 0))
+	sink := &pipelinetest.Sink{Latency: time.Second, Clock: clock}
+	p := pipeline.New(pipeline.FromChannel(source(3)), 4, discard)
+	p.Start(context.Background(), pipeline.Stage{Name: "slow", Writer: sink})
+	p.Dispatch(context.Background())
+
+	for want := int64(0); want < 3; want++ {
+		clock.BlockUntil(1)
+		if sink.Count() != want {
+			t.Fatalf("sink accepted %d before %s, want %d", sink.Count(), time.Duration(want+1)*time.Second, want)
+		}
+		clock.Advance(time.Second)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if sink.Count() != 3 {
+		t.Fatalf("sink accepted %d, want 3", sink.Count())
+	}
+}
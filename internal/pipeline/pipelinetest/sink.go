@@ -0,0 +1,123 @@
+package pipelinetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// ErrInjected is the failure a Sink returns when no Err is scripted
+var ErrInjected = errors.New("injected sink failure")
+
+// Sink is a fault injection writer. It records every transaction it accepts
+// and, as scripted by its fields, fails, stalls or slows down. Set the fields
+// before the pipeline starts.
+type Sink struct {
+	// FailAt fails Write on the FailAt-th transaction, which is not
+	// accepted but reported by Undelivered; zero never fails
+	FailAt int
+	// Err is returned by the scripted failure, ErrInjected when nil
+	Err error
+	// Gate, when set, must yield once per transaction before it is accepted,
+	// letting a test step the sink or stall it to build backpressure
+	Gate chan struct{}
+	// Latency is waited on Clock for every transaction
+	Latency time.Duration
+	Clock   *Clock
+	// CloseErr is returned by Close
+	CloseErr error
+
+	mu       sync.Mutex
+	received []*models.Transaction
+	failed   *models.Transaction
+	closed   bool
+}
+
+// Write accepts transactions until input is closed, ctx is done or the
+// scripted failure fires
+func (s *Sink) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for {
+		if s.Gate != nil {
+			select {
+			case <-s.Gate:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		var txn *models.Transaction
+		select {
+		case <-ctx.Done():
+			return nil
+		case t, ok := <-input:
+			if !ok {
+				return nil
+			}
+			txn = t
+		}
+
+		if s.Latency > 0 {
+			select {
+			case <-s.Clock.After(s.Latency):
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		s.mu.Lock()
+		if s.FailAt > 0 && len(s.received)+1 == s.FailAt {
+			s.failed = txn
+			s.mu.Unlock()
+			if s.Err != nil {
+				return s.Err
+			}
+			return ErrInjected
+		}
+		s.received = append(s.received, txn)
+		s.mu.Unlock()
+	}
+}
+
+// Undelivered returns the transaction the scripted failure fired on, once
+func (s *Sink) Undelivered() []*models.Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed == nil {
+		return nil
+	}
+	txn := s.failed
+	s.failed = nil
+	return []*models.Transaction{txn}
+}
+
+// Close marks the sink closed and returns CloseErr
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return s.CloseErr
+}
+
+// Count returns the number of transactions accepted
+func (s *Sink) Count() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.received))
+}
+
+// Received returns the accepted transactions in arrival order
+func (s *Sink) Received() []*models.Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*models.Transaction(nil), s.received...)
+}
+
+// Closed reports whether Close has been called
+func (s *Sink) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
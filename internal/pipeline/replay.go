@@ -0,0 +1,57 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Replay relays transactions from in to out at the pace they originally
+// settled, with every gap between settled_at times divided by speed, so a
+// day of captured traffic can be replayed in an hour with its bursts intact.
+// maxGap caps any single pause after rescaling; zero leaves gaps uncapped.
+// Records with an unparseable settled_at, or settled before the pace has
+// reached them, pass straight through. out is closed when in is drained; on
+// cancellation in is still drained so the source blocked on it can exit.
+func Replay(ctx context.Context, in <-chan *models.Transaction, out chan<- *models.Transaction, speed float64, maxGap time.Duration, clock Clock) {
+	defer close(out)
+	defer func() {
+		go func() {
+			for range in {
+			}
+		}()
+	}()
+
+	// Each record is due at wallStart plus its offset from the first
+	// record's settled_at, rescaled; tying every record to the same anchor
+	// keeps sleep overshoot from accumulating
+	var eventStart, wallStart time.Time
+	for txn := range in {
+		if settled, err := time.Parse(time.RFC3339Nano, txn.SettledAt); err == nil {
+			if wallStart.IsZero() {
+				eventStart, wallStart = settled, clock.Now()
+			}
+			due := wallStart.Add(time.Duration(float64(settled.Sub(eventStart)) / speed))
+			wait := due.Sub(clock.Now())
+			if maxGap > 0 && wait > maxGap {
+				// Move the anchor so later records keep their spacing
+				wallStart = wallStart.Add(maxGap - wait)
+				wait = maxGap
+			}
+			if wait > time.Millisecond {
+				select {
+				case <-clock.After(wait):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		select {
+		case out <- txn:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
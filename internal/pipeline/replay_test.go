@@ -0,0 +1,80 @@
+package pipeline_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+	"github.com/supratick/message_producer/internal/pipeline"
+	"github.com/supratick/message_producer/internal/pipeline/pipelinetest"
+)
+
+// settledSource returns a closed channel of transactions settled at the given
+// offsets from a fixed time
+func settledSource(offsets ...time.Duration) chan *models.Transaction {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ch := make(chan *models.Transaction, len(offsets))
+	for _, offset := range offsets {
+		ch <- &models.Transaction{SettledAt: base.Add(offset).Format(time.RFC3339)}
+	}
+	close(ch)
+	return ch
+}
+
+func TestReplayRescalesGaps(t *testing.T) {
+	clock := pipelinetest.NewClock(time.Unix(0, 0))
+	out := make(chan *models.Transaction)
+	// 10x: a 60s gap takes 6s, a burst in the same second none
+	go pipeline.Replay(context.Background(), settledSource(0, 60*time.Second, 60*time.Second, 80*time.Second), out, 10, 0, clock)
+
+	<-out
+	clock.BlockUntil(1)
+	clock.Advance(5999 * time.Millisecond)
+	clock.BlockUntil(1)
+	expectNone(t, out)
+	clock.Advance(time.Millisecond)
+	<-out
+	<-out // same settled_at, no wait
+
+	clock.BlockUntil(1)
+	clock.Advance(2 * time.Second)
+	<-out
+	if _, ok := <-out; ok {
+		t.Fatal("out not closed once the input drained")
+	}
+}
+
+func TestReplayCapsGaps(t *testing.T) {
+	clock := pipelinetest.NewClock(time.Unix(0, 0))
+	out := make(chan *models.Transaction)
+	// An hour of silence at 2x would be 30 minutes; capped to 5s, and the
+	// 10s gap after it keeps its 5s rescaled length
+	go pipeline.Replay(context.Background(), settledSource(0, time.Hour, time.Hour+10*time.Second), out, 2, 5*time.Second, clock)
+
+	<-out
+	clock.BlockUntil(1)
+	clock.Advance(5 * time.Second)
+	<-out
+	clock.BlockUntil(1)
+	clock.Advance(4 * time.Second)
+	clock.BlockUntil(1)
+	expectNone(t, out)
+	clock.Advance(time.Second)
+	<-out
+}
+
+func TestReplayPassesUntimedRecords(t *testing.T) {
+	clock := pipelinetest.NewClock(time.Unix(0, 0))
+	in := make(chan *models.Transaction, 2)
+	in <- &models.Transaction{SettledAt: "yesterday"}
+	in <- &models.Transaction{}
+	close(in)
+	out := make(chan *models.Transaction)
+	go pipeline.Replay(context.Background(), in, out, 1, 0, clock)
+	<-out
+	<-out
+	if _, ok := <-out; ok {
+		t.Fatal("out not closed once the input drained")
+	}
+}
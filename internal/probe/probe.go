@@ -0,0 +1,203 @@
+// Package probe measures produce-to-consume latency by reading the Kafka
+// writer's timestamped probe messages back from the topic
+package probe
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+// maxSamples bounds the latencies kept for percentiles. Beyond it samples
+// are replaced at random so every probe is equally likely to be kept.
+const maxSamples = 100000
+
+// Summary holds latency percentiles over the probes received
+type Summary struct {
+	Count int64
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	P999  time.Duration
+	Max   time.Duration
+}
+
+// Attrs returns the summary as log attributes
+func (s Summary) Attrs() []any {
+	return []any{
+		"probes", s.Count,
+		"p50", s.P50.String(),
+		"p90", s.P90.String(),
+		"p99", s.P99.String(),
+		"p999", s.P999.String(),
+		"max", s.Max.String(),
+	}
+}
+
+// Consumer reads every partition of the topic from the newest offset at
+// creation, so it must be created before production starts
+type Consumer struct {
+	consumer   sarama.Consumer
+	partitions []sarama.PartitionConsumer
+
+	mu       sync.Mutex
+	count    int64
+	max      time.Duration
+	samples  []time.Duration
+	interval []time.Duration // latencies since the last interval report
+	received chan struct{}   // signalled on every probe
+	wg       sync.WaitGroup
+}
+
+// NewConsumer connects to the brokers and starts consuming topic
+func NewConsumer(brokers []string, topic string, auth writer.KafkaAuth, timeout time.Duration) (*Consumer, error) {
+	config := sarama.NewConfig()
+	config.Net.DialTimeout = timeout
+	config.Consumer.Return.Errors = false
+	auth.Apply(config)
+
+	consumer, err := sarama.NewConsumer(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create probe consumer: %w", err)
+	}
+	ids, err := consumer.Partitions(topic)
+	if err != nil {
+		consumer.Close()
+		return nil, fmt.Errorf("failed to list partitions for %s: %w", topic, err)
+	}
+
+	c := &Consumer{
+		consumer: consumer,
+		received: make(chan struct{}, 1),
+	}
+	for _, id := range ids {
+		pc, err := consumer.ConsumePartition(topic, id, sarama.OffsetNewest)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to consume %s partition %d: %w", topic, id, err)
+		}
+		c.partitions = append(c.partitions, pc)
+		c.wg.Add(1)
+		go c.consume(pc)
+	}
+	return c, nil
+}
+
+func (c *Consumer) consume(pc sarama.PartitionConsumer) {
+	defer c.wg.Done()
+	for msg := range pc.Messages() {
+		if latency, ok := probeLatency(msg, time.Now()); ok {
+			c.record(latency)
+		}
+	}
+}
+
+// probeLatency returns how long ago a probe message was sent
+func probeLatency(msg *sarama.ConsumerMessage, now time.Time) (time.Duration, bool) {
+	for _, h := range msg.Headers {
+		if string(h.Key) != writer.ProbeHeader {
+			continue
+		}
+		sentAt, err := strconv.ParseInt(string(h.Value), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return now.Sub(time.Unix(0, sentAt)), true
+	}
+	return 0, false
+}
+
+func (c *Consumer) record(latency time.Duration) {
+	c.mu.Lock()
+	c.count++
+	c.max = max(c.max, latency)
+	if len(c.samples) < maxSamples {
+		c.samples = append(c.samples, latency)
+	} else if i := rand.Int64N(c.count); i < maxSamples {
+		c.samples[i] = latency
+	}
+	if len(c.interval) < maxSamples {
+		c.interval = append(c.interval, latency)
+	}
+	c.mu.Unlock()
+
+	select {
+	case c.received <- struct{}{}:
+	default:
+	}
+}
+
+// Report logs percentiles of the probes received since the previous report
+func (c *Consumer) Report(logger *slog.Logger) {
+	c.mu.Lock()
+	interval := c.interval
+	c.interval = nil
+	c.mu.Unlock()
+	if len(interval) == 0 {
+		return
+	}
+	s := summarize(interval, int64(len(interval)), 0)
+	logger.Info("Latency metrics", s.Attrs()...)
+}
+
+// Summary returns percentiles over every probe received
+func (c *Consumer) Summary() Summary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return summarize(slices.Clone(c.samples), c.count, c.max)
+}
+
+// Wait blocks until want probes have been received or no probe has arrived
+// for idle, whichever comes first, and returns how many were received
+func (c *Consumer) Wait(want int64, idle time.Duration) int64 {
+	timer := time.NewTimer(idle)
+	defer timer.Stop()
+	for {
+		c.mu.Lock()
+		count := c.count
+		c.mu.Unlock()
+		if count >= want {
+			return count
+		}
+		select {
+		case <-c.received:
+			timer.Reset(idle)
+		case <-timer.C:
+			return count
+		}
+	}
+}
+
+// Close stops consuming
+func (c *Consumer) Close() error {
+	for _, pc := range c.partitions {
+		pc.AsyncClose()
+	}
+	c.wg.Wait()
+	return c.consumer.Close()
+}
+
+// summarize sorts samples in place and reads off the percentiles. A zero
+// maxLatency is taken from the samples.
+func summarize(samples []time.Duration, count int64, maxLatency time.Duration) Summary {
+	s := Summary{Count: count, Max: maxLatency}
+	if len(samples) == 0 {
+		return s
+	}
+	slices.Sort(samples)
+	at := func(q float64) time.Duration {
+		return samples[min(int(q*float64(len(samples))), len(samples)-1)]
+	}
+	s.P50, s.P90, s.P99, s.P999 = at(0.50), at(0.90), at(0.99), at(0.999)
+	if s.Max == 0 {
+		s.Max = samples[len(samples)-1]
+	}
+	return s
+}
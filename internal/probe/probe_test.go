@@ -0,0 +1,38 @@
+package probe
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/supratick/message_producer/internal/writer"
+)
+
+func TestProbeLatency(t *testing.T) {
+	sent := time.Unix(1700000000, 0)
+	msg := &sarama.ConsumerMessage{Headers: []*sarama.RecordHeader{
+		{Key: []byte("content-type"), Value: []byte("text/csv")},
+		{Key: []byte(writer.ProbeHeader), Value: []byte(strconv.FormatInt(sent.UnixNano(), 10))},
+	}}
+	if got, ok := probeLatency(msg, sent.Add(15*time.Millisecond)); !ok || got != 15*time.Millisecond {
+		t.Fatalf("probeLatency = %v, %v; want 15ms", got, ok)
+	}
+	if _, ok := probeLatency(&sarama.ConsumerMessage{}, sent); ok {
+		t.Fatal("message without a probe header measured")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	samples := make([]time.Duration, 1000)
+	for i := range samples {
+		samples[len(samples)-1-i] = time.Duration(i+1) * time.Millisecond
+	}
+	s := summarize(samples, 1000, 0)
+	if s.P50 != 501*time.Millisecond || s.P99 != 991*time.Millisecond || s.P999 != 1000*time.Millisecond || s.Max != time.Second {
+		t.Fatalf("summarize = %+v", s)
+	}
+	if s := summarize(nil, 0, 0); s.Count != 0 || s.P50 != 0 {
+		t.Fatalf("empty summarize = %+v", s)
+	}
+}
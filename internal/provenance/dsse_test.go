@@ -0,0 +1,206 @@
+package provenance
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testStatement() *Statement {
+	return &Statement{
+		Type:          StatementType,
+		Subject:       []Subject{{Name: "transactions.csv", Digest: map[string]string{"sha256": strings.Repeat("ab", 32)}, Size: 1024}},
+		PredicateType: PredicateType,
+		Predicate: Run{
+			RunID:        "run-1",
+			MessageCount: 10,
+			Sinks:        []Sink{{Name: "csv", Count: 10}},
+			StartedAt:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			FinishedAt:   time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC),
+		},
+	}
+}
+
+// testSigners returns one key of each supported type
+func testSigners(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]crypto.Signer{"ed25519": ed, "ecdsa": ec, "rsa": rs}
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	for name, signer := range testSigners(t) {
+		t.Run(name, func(t *testing.T) {
+			env, err := Sign(testStatement(), signer, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			keyID, err := KeyID(signer.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if env.PayloadType != PayloadType || len(env.Signatures) != 1 || env.Signatures[0].KeyID != keyID {
+				t.Errorf("envelope = %+v", env)
+			}
+			stmt, err := env.Verify(signer.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stmt.Predicate.RunID != "run-1" || len(stmt.Subject) != 1 || stmt.Subject[0].Name != "transactions.csv" {
+				t.Errorf("verified statement = %+v", stmt)
+			}
+		})
+	}
+
+	// An explicit key ID replaces the fingerprint
+	env, err := Sign(testStatement(), testSigners(t)["ed25519"], "release-2024")
+	if err != nil || env.Signatures[0].KeyID != "release-2024" {
+		t.Errorf("key ID = %+v, %v", env, err)
+	}
+}
+
+func TestVerifyRejectsDamage(t *testing.T) {
+	signers := testSigners(t)
+	for name, signer := range signers {
+		t.Run(name, func(t *testing.T) {
+			env, err := Sign(testStatement(), signer, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			sig, _ := base64.StdEncoding.DecodeString(env.Signatures[0].Sig)
+			payload, _ := base64.StdEncoding.DecodeString(env.Payload)
+			other := signers["ed25519"]
+			if name == "ed25519" {
+				other = signers["ecdsa"]
+			}
+
+			for _, tc := range []struct {
+				name   string
+				damage func(e *Envelope)
+				pub    crypto.PublicKey
+				want   string
+			}{
+				{"tampered payload", func(e *Envelope) {
+					e.Payload = base64.StdEncoding.EncodeToString([]byte(strings.Replace(string(payload), "run-1", "run-2", 1)))
+				}, signer.Public(), "no valid signature"},
+				{"tampered signature", func(e *Envelope) {
+					bad := append([]byte(nil), sig...)
+					bad[len(bad)/2] ^= 1
+					e.Signatures[0].Sig = base64.StdEncoding.EncodeToString(bad)
+				}, signer.Public(), "no valid signature"},
+				{"truncated signature", func(e *Envelope) {
+					e.Signatures[0].Sig = base64.StdEncoding.EncodeToString(sig[:len(sig)-1])
+				}, signer.Public(), "no valid signature"},
+				{"signature not base64", func(e *Envelope) {
+					e.Signatures[0].Sig = "!!"
+				}, signer.Public(), "no valid signature"},
+				{"no signatures", func(e *Envelope) {
+					e.Signatures = nil
+				}, signer.Public(), "no valid signature"},
+				{"payload type changed", func(e *Envelope) {
+					e.PayloadType = "application/json"
+				}, signer.Public(), "unexpected payload type"},
+				{"wrong key", func(e *Envelope) {}, other.Public(), ""},
+			} {
+				t.Run(tc.name, func(t *testing.T) {
+					damaged := *env
+					damaged.Signatures = append([]Signature(nil), env.Signatures...)
+					tc.damage(&damaged)
+					_, err := damaged.Verify(tc.pub)
+					if err == nil || !strings.Contains(err.Error(), tc.want) {
+						t.Errorf("err = %v, want %q", err, tc.want)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	signers := testSigners(t)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(signers["ed25519"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec1, err := x509.MarshalECPrivateKey(signers["ecdsa"].(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name   string
+		path   string
+		signer crypto.Signer
+	}{
+		{"pkcs8", write("ed25519.pem", "PRIVATE KEY", pkcs8), signers["ed25519"]},
+		{"sec1", write("ecdsa.pem", "EC PRIVATE KEY", sec1), signers["ecdsa"]},
+		{"pkcs1", write("rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(signers["rsa"].(*rsa.PrivateKey))), signers["rsa"]},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			signer, err := LoadSigner(tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			der, err := x509.MarshalPKIXPublicKey(signer.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			pub, err := LoadPublicKey(write(tc.name+".pub", "PUBLIC KEY", der))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The loaded pair signs and verifies like the original key
+			env, err := Sign(testStatement(), signer, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := env.Verify(pub); err != nil {
+				t.Error(err)
+			}
+			if _, err := env.Verify(tc.signer.Public()); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	if _, err := LoadSigner(write("garbage.pem", "PRIVATE KEY", []byte("garbage"))); err == nil {
+		t.Error("garbage signing key accepted")
+	}
+	notPEM := filepath.Join(dir, "key.txt")
+	if err := os.WriteFile(notPEM, []byte("not pem"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSigner(notPEM); err == nil || !strings.Contains(err.Error(), "not PEM encoded") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+// Package provenance records what a run produced as an in-toto statement
+// whose subjects are the output files and their SHA-256 digests, optionally
+// signed in a DSSE envelope
+package provenance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// StatementType is the in-toto statement type
+	StatementType = "https://in-toto.io/Statement/v1"
+	// PredicateType identifies the run manifest predicate
+	PredicateType = "https://github.com/supratick/message_producer/run-manifest/v1"
+	// PayloadType is the DSSE payload type for in-toto statements
+	PayloadType = "application/vnd.in-toto+json"
+)
+
+// Run describes the run that produced the files
+type Run struct {
+	RunID        string    `json:"run_id"`
+	Version      string    `json:"version"`
+	Scenario     string    `json:"scenario,omitempty"`
+	Seed         int64     `json:"seed,omitempty"`
+	MessageCount int       `json:"message_count"`
+	Sinks        []Sink    `json:"sinks"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+}
+
+// Sink is the number of transactions one writer accepted
+type Sink struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// Subject is an output file in the statement
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+	Size   int64             `json:"size"`
+}
+
+// Statement is an in-toto v1 statement with the run as its predicate
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Run       `json:"predicate"`
+}
+
+// NewStatement hashes the regular files under dirs modified at or after
+// since, naming each relative to base, and returns the statement for run.
+// Files whose names appear in exclude are skipped.
+func NewStatement(run Run, base string, dirs []string, since time.Time, exclude ...string) (*Statement, error) {
+	skip := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		skip[name] = true
+	}
+
+	seen := make(map[string]bool)
+	stmt := &Statement{
+		Type:          StatementType,
+		Subject:       []Subject{},
+		PredicateType: PredicateType,
+		Predicate:     run,
+	}
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if info.ModTime().Before(since) {
+				return nil
+			}
+
+			name, err := filepath.Rel(base, path)
+			if err != nil {
+				name = path
+			}
+			name = filepath.ToSlash(name)
+			if seen[name] || skip[name] {
+				return nil
+			}
+			seen[name] = true
+
+			sum, err := HashFile(path)
+			if err != nil {
+				return err
+			}
+			stmt.Subject = append(stmt.Subject, Subject{
+				Name:   name,
+				Digest: map[string]string{"sha256": sum},
+				Size:   info.Size(),
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash output files: %w", err)
+		}
+	}
+
+	sort.Slice(stmt.Subject, func(i, j int) bool {
+		return stmt.Subject[i].Name < stmt.Subject[j].Name
+	})
+	return stmt, nil
+}
+
+// HashFile returns the hex SHA-256 digest of the file at path
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Marshal encodes the statement as indented JSON
+func (s *Statement) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode provenance statement: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Mismatch is a subject whose file no longer matches the statement
+type Mismatch struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// CheckSubjects re-hashes every subject, resolving names against base
+func (s *Statement) CheckSubjects(base string) []Mismatch {
+	var mismatches []Mismatch
+	for _, subj := range s.Subject {
+		path := filepath.FromSlash(subj.Name)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(base, path)
+		}
+		sum, err := HashFile(path)
+		if err != nil {
+			mismatches = append(mismatches, Mismatch{Name: subj.Name, Reason: err.Error()})
+			continue
+		}
+		if sum != subj.Digest["sha256"] {
+			mismatches = append(mismatches, Mismatch{Name: subj.Name, Reason: "sha256 mismatch"})
+		}
+	}
+	return mismatches
+}
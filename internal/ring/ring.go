@@ -0,0 +1,147 @@
+// Package ring provides a bounded lock-free multi-producer multi-consumer
+// ring buffer, a disruptor-style alternative to a buffered channel for the
+// hop between generation workers and sink relays
+package ring
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// cacheLine separates the hot cursors so producers and consumers do not
+// false-share
+const cacheLine = 64
+
+type slot[T any] struct {
+	seq   atomic.Uint64
+	value T
+}
+
+// Ring is a bounded MPMC queue (Vyukov's algorithm). Each slot carries a
+// sequence number that tells producers and consumers whether it is free or
+// filled for their lap, so claiming a slot is one compare-and-swap on a
+// cursor and nothing ever takes a lock.
+type Ring[T any] struct {
+	_       [cacheLine]byte
+	enqueue atomic.Uint64
+	_       [cacheLine - 8]byte
+	dequeue atomic.Uint64
+	_       [cacheLine - 8]byte
+	closed  atomic.Bool
+	mask    uint64
+	slots   []slot[T]
+}
+
+// New creates a ring holding at least size items, rounded up to a power of two
+func New[T any](size int) *Ring[T] {
+	n := uint64(2)
+	for n < uint64(size) {
+		n <<= 1
+	}
+	r := &Ring[T]{mask: n - 1, slots: make([]slot[T], n)}
+	for i := range r.slots {
+		r.slots[i].seq.Store(uint64(i))
+	}
+	return r
+}
+
+// Cap returns the ring's capacity
+func (r *Ring[T]) Cap() int {
+	return len(r.slots)
+}
+
+// TryPut adds v unless the ring is full
+func (r *Ring[T]) TryPut(v T) bool {
+	pos := r.enqueue.Load()
+	for {
+		s := &r.slots[pos&r.mask]
+		seq := s.seq.Load()
+		switch diff := int64(seq) - int64(pos); {
+		case diff == 0:
+			if r.enqueue.CompareAndSwap(pos, pos+1) {
+				s.value = v
+				s.seq.Store(pos + 1)
+				return true
+			}
+			pos = r.enqueue.Load()
+		case diff < 0:
+			return false // full: the slot still holds the previous lap
+		default:
+			pos = r.enqueue.Load() // another producer claimed it
+		}
+	}
+}
+
+// TryGet removes the oldest item unless the ring is empty
+func (r *Ring[T]) TryGet() (T, bool) {
+	var zero T
+	pos := r.dequeue.Load()
+	for {
+		s := &r.slots[pos&r.mask]
+		seq := s.seq.Load()
+		switch diff := int64(seq) - int64(pos+1); {
+		case diff == 0:
+			if r.dequeue.CompareAndSwap(pos, pos+1) {
+				v := s.value
+				s.value = zero
+				s.seq.Store(pos + r.mask + 1)
+				return v, true
+			}
+			pos = r.dequeue.Load()
+		case diff < 0:
+			return zero, false // empty
+		default:
+			pos = r.dequeue.Load()
+		}
+	}
+}
+
+// Put adds v, waiting while the ring is full. It returns ctx.Err() if ctx
+// ends first.
+func (r *Ring[T]) Put(ctx context.Context, v T) error {
+	for spins := 0; ; spins++ {
+		if r.TryPut(v) {
+			return nil
+		}
+		if spins&63 == 63 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		backoff(spins)
+	}
+}
+
+// Get removes the oldest item, waiting while the ring is empty. It reports
+// false once the ring is closed and drained.
+func (r *Ring[T]) Get() (T, bool) {
+	for spins := 0; ; spins++ {
+		if v, ok := r.TryGet(); ok {
+			return v, true
+		}
+		if r.closed.Load() {
+			// Items put before Close are visible by now
+			return r.TryGet()
+		}
+		backoff(spins)
+	}
+}
+
+// Close marks the end of input. Producers must not Put after Close.
+func (r *Ring[T]) Close() {
+	r.closed.Store(true)
+}
+
+// backoff is the wait strategy: busy-spin briefly, then yield the processor,
+// then sleep so an idle ring does not burn a core
+func backoff(spins int) {
+	switch {
+	case spins < 16:
+	case spins < 256:
+		runtime.Gosched()
+	default:
+		time.Sleep(50 * time.Microsecond)
+	}
+}
@@ -0,0 +1,160 @@
+package ring
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRingDeliversEveryItemOnce(t *testing.T) {
+	const producers, consumers, perProducer = 4, 4, 50000
+	r := New[int](1024)
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				if err := r.Put(context.Background(), p*perProducer+i); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(p)
+	}
+
+	seen := make([][]int, consumers)
+	var cwg sync.WaitGroup
+	for c := 0; c < consumers; c++ {
+		cwg.Add(1)
+		go func(c int) {
+			defer cwg.Done()
+			for {
+				v, ok := r.Get()
+				if !ok {
+					return
+				}
+				seen[c] = append(seen[c], v)
+			}
+		}(c)
+	}
+
+	wg.Wait()
+	r.Close()
+	cwg.Wait()
+
+	got := make([]bool, producers*perProducer)
+	for c, values := range seen {
+		last := make(map[int]int) // per-producer order seen by this consumer
+		for _, v := range values {
+			if got[v] {
+				t.Fatalf("item %d delivered twice", v)
+			}
+			got[v] = true
+			p := v / perProducer
+			if prev, ok := last[p]; ok && v < prev {
+				t.Fatalf("consumer %d saw producer %d out of order: %d after %d", c, p, v, prev)
+			}
+			last[p] = v
+		}
+	}
+	for v, ok := range got {
+		if !ok {
+			t.Fatalf("item %d never delivered", v)
+		}
+	}
+}
+
+// transport abstracts the two queues for the benchmarks
+type transport struct {
+	put   func(v *int)
+	get   func() (*int, bool)
+	close func()
+}
+
+func channelTransport(size int) transport {
+	ch := make(chan *int, size)
+	return transport{
+		put: func(v *int) { ch <- v },
+		get: func() (*int, bool) {
+			v, ok := <-ch
+			return v, ok
+		},
+		close: func() { close(ch) },
+	}
+}
+
+func ringTransport(size int) transport {
+	r := New[*int](size)
+	return transport{
+		put:   func(v *int) { r.Put(context.Background(), v) },
+		get:   r.Get,
+		close: r.Close,
+	}
+}
+
+// BenchmarkTransport moves b.N pointers from generation workers to sink
+// relays, as between Producer.Generate and the writers. Run with -cpu 8 or
+// more to see where the shared channel's lock becomes the limit:
+//
+//	go test -bench Transport -cpu 8 ./internal/ring
+func BenchmarkTransport(b *testing.B) {
+	impls := []struct {
+		name string
+		new  func(size int) transport
+	}{
+		{"channel", channelTransport},
+		{"ring", ringTransport},
+	}
+	for _, workers := range [][2]int{{1, 1}, {4, 4}, {8, 8}} {
+		for _, impl := range impls {
+			name := fmt.Sprintf("%s/%dx%d", impl.name, workers[0], workers[1])
+			b.Run(name, func(b *testing.B) {
+				benchmarkTransport(b, impl.new(10000), workers[0], workers[1])
+			})
+		}
+	}
+}
+
+func benchmarkTransport(b *testing.B, t transport, producers, consumers int) {
+	item := new(int)
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		n := b.N / producers
+		if p == producers-1 {
+			n += b.N % producers
+		}
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				t.put(item)
+			}
+		}(n)
+	}
+
+	var cwg sync.WaitGroup
+	for c := 0; c < consumers; c++ {
+		cwg.Add(1)
+		go func() {
+			defer cwg.Done()
+			for {
+				if _, ok := t.get(); !ok {
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	t.close()
+	cwg.Wait()
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "msgs/sec")
+}
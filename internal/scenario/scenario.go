@@ -0,0 +1,63 @@
+// Package scenario bundles named configuration overlays for standard,
+// comparable runs (steady state, traffic spikes, outages, volatility)
+package scenario
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed scenarios/*.yaml
+var bundled embed.FS
+
+// Scenario is a bundled configuration overlay
+type Scenario struct {
+	Name        string
+	Description string // first comment line of the bundle
+	Overlay     []byte // partial config YAML
+}
+
+// Get returns the bundled scenario called name
+func Get(name string) (*Scenario, error) {
+	data, err := bundled.ReadFile(path.Join("scenarios", name+".yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown scenario %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return &Scenario{
+		Name:        name,
+		Description: description(data),
+		Overlay:     data,
+	}, nil
+}
+
+// List returns every bundled scenario sorted by name
+func List() []*Scenario {
+	var scenarios []*Scenario
+	for _, name := range Names() {
+		if s, err := Get(name); err == nil {
+			scenarios = append(scenarios, s)
+		}
+	}
+	return scenarios
+}
+
+// Names returns the bundled scenario names sorted
+func Names() []string {
+	entries, _ := bundled.ReadDir("scenarios")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".yaml"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func description(data []byte) string {
+	line, _, _ := bufio.NewReader(bytes.NewReader(data)).ReadLine()
+	return strings.TrimSpace(strings.TrimPrefix(string(line), "#"))
+}
@@ -0,0 +1,130 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Job is one scheduled producer run. Each run executes the producer binary
+// with Args, so a failing job cannot take the scheduler down with it.
+type Job struct {
+	Name         string
+	Cron         *Cron
+	Args         []string
+	Env          []string // extra KEY=VALUE pairs, e.g. config overrides
+	AllowOverlap bool     // start even if the previous run is still going
+}
+
+// Scheduler runs jobs when their cron expressions come due
+type Scheduler struct {
+	binary   string
+	jobs     []Job
+	location *time.Location
+	grace    time.Duration
+	logger   *slog.Logger
+
+	wg sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler that evaluates expressions in location.
+// On shutdown running jobs get SIGTERM and are killed after grace.
+func NewScheduler(binary string, jobs []Job, location *time.Location, grace time.Duration, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		binary:   binary,
+		jobs:     jobs,
+		location: location,
+		grace:    grace,
+		logger:   logger,
+	}
+}
+
+// Run schedules every job until ctx is done, then waits for running jobs
+func (s *Scheduler) Run(ctx context.Context) {
+	var loops sync.WaitGroup
+	for _, job := range s.jobs {
+		loops.Add(1)
+		go func(job Job) {
+			defer loops.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+	loops.Wait()
+	s.wg.Wait()
+}
+
+// loop waits for each due time of one job and starts it
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	var mu sync.Mutex
+	running := false
+
+	for {
+		next := job.Cron.Next(time.Now().In(s.location))
+		if next.IsZero() {
+			s.logger.Warn("Scheduled job never runs", "job", job.Name)
+			return
+		}
+		s.logger.Info("Next scheduled run", "job", job.Name, "at", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		mu.Lock()
+		if running && !job.AllowOverlap {
+			mu.Unlock()
+			s.logger.Warn("Skipping scheduled run, previous run still in progress", "job", job.Name)
+			continue
+		}
+		running = true
+		mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.execute(ctx, job)
+			mu.Lock()
+			running = false
+			mu.Unlock()
+		}()
+	}
+}
+
+// execute runs the job to completion and logs the outcome
+func (s *Scheduler) execute(ctx context.Context, job Job) {
+	cmd := exec.CommandContext(ctx, s.binary, job.Args...)
+	cmd.Env = append(os.Environ(), job.Env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// Let the run close its writers cleanly when the scheduler stops
+	cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+	cmd.WaitDelay = s.grace
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		s.logger.Error("Failed to start scheduled job", "job", job.Name, "error", err)
+		return
+	}
+	s.logger.Info("Scheduled job started", "job", job.Name, "pid", cmd.Process.Pid)
+
+	err := cmd.Wait()
+	attrs := []any{"job", job.Name, "duration", time.Since(start).Round(time.Millisecond).String()}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		s.logger.Info("Scheduled job finished", attrs...)
+	case errors.As(err, &exitErr):
+		s.logger.Error("Scheduled job failed", append(attrs, "exit_code", exitErr.ExitCode())...)
+	default:
+		s.logger.Error("Scheduled job failed", append(attrs, "error", err)...)
+	}
+}
@@ -0,0 +1,102 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/supratick/message_producer/internal/awsauth"
+)
+
+// AWSClient reads secrets from AWS Secrets Manager
+type AWSClient struct {
+	client   *http.Client
+	creds    awsauth.Credentials
+	region   string
+	endpoint string
+}
+
+// NewAWSClient creates a Secrets Manager client. An empty endpoint uses the
+// regional endpoint.
+func NewAWSClient(region, endpoint string, creds awsauth.Credentials, timeout time.Duration) (*AWSClient, error) {
+	if !creds.Valid() {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for Secrets Manager")
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", region)
+	}
+	return &AWSClient{
+		client:   &http.Client{Timeout: timeout},
+		creds:    creds,
+		region:   region,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+	}, nil
+}
+
+// GetSecretValue returns the secret's fields. The raw secret string is
+// always available under the empty field name; JSON object secrets also
+// expose each top-level key.
+func (a *AWSClient) GetSecretValue(ctx context.Context, secretID string) (map[string]string, error) {
+	body, err := json.Marshal(map[string]string{"SecretId": secretID})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Secrets Manager request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	awsauth.SignV4(req, body, a.creds, a.region, "secretsmanager", time.Now().UTC())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("secrets manager request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Secrets Manager response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get secret %s (%d): %s", secretID, resp.StatusCode, data)
+	}
+
+	var out struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary string `json:"SecretBinary"` // base64
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode Secrets Manager response: %w", err)
+	}
+
+	raw := out.SecretString
+	if raw == "" && out.SecretBinary != "" {
+		decoded, err := base64.StdEncoding.DecodeString(out.SecretBinary)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode binary secret %s: %w", secretID, err)
+		}
+		raw = string(decoded)
+	}
+
+	values := map[string]string{"": raw}
+	var fields map[string]interface{}
+	if json.Unmarshal([]byte(raw), &fields) == nil {
+		for k, v := range fields {
+			if s, ok := v.(string); ok {
+				values[k] = s
+			} else if encoded, err := json.Marshal(v); err == nil {
+				values[k] = string(encoded)
+			}
+		}
+	}
+	return values, nil
+}
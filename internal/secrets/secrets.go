@@ -0,0 +1,227 @@
+// Package secrets resolves secret references in configuration values from
+// HashiCorp Vault or AWS Secrets Manager, keeping Vault tokens and leases
+// renewed for long-running runs
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reference prefixes. A reference is the whole config value:
+//
+//	vault:<path>#<field>     e.g. vault:secret/data/kafka#password
+//	aws-sm:<secret-id>#<field>  (#field optional for plain-string secrets)
+const (
+	VaultPrefix = "vault:"
+	AWSPrefix   = "aws-sm:"
+)
+
+// lease is a renewable Vault lease behind a resolved value
+type lease struct {
+	id       string
+	duration time.Duration
+}
+
+// Resolver fetches referenced secrets, caching each secret for the run
+type Resolver struct {
+	vault  *VaultClient
+	aws    *AWSClient
+	cache  map[string]map[string]string
+	mu     sync.Mutex
+	leases []lease
+	logger *slog.Logger
+}
+
+// NewResolver creates a resolver. Either client may be nil when its backend
+// is not configured; references to it then fail.
+func NewResolver(vault *VaultClient, aws *AWSClient, logger *slog.Logger) *Resolver {
+	return &Resolver{
+		vault:  vault,
+		aws:    aws,
+		cache:  make(map[string]map[string]string),
+		logger: logger,
+	}
+}
+
+// IsReference reports whether value names a secret
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, VaultPrefix) || strings.HasPrefix(value, AWSPrefix)
+}
+
+// Resolve returns the secret value a reference points at
+func (r *Resolver) Resolve(ctx context.Context, ref string) (string, error) {
+	backend, rest, _ := strings.Cut(ref, ":")
+	path, field, _ := strings.Cut(rest, "#")
+	if path == "" {
+		return "", fmt.Errorf("invalid secret reference %q", ref)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := backend + ":" + path
+	values, ok := r.cache[key]
+	if !ok {
+		var err error
+		switch backend + ":" {
+		case VaultPrefix:
+			if r.vault == nil {
+				return "", fmt.Errorf("secret %s: vault is not configured", ref)
+			}
+			var l *lease
+			values, l, err = r.vault.Read(ctx, path)
+			if l != nil {
+				r.leases = append(r.leases, *l)
+			}
+		case AWSPrefix:
+			if r.aws == nil {
+				return "", fmt.Errorf("secret %s: AWS Secrets Manager is not configured", ref)
+			}
+			values, err = r.aws.GetSecretValue(ctx, path)
+		}
+		if err != nil {
+			return "", err
+		}
+		r.cache[key] = values
+	}
+
+	value, ok := values[field]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no field %q", path, field)
+	}
+	return value, nil
+}
+
+// ResolveAll replaces every secret reference among the string fields,
+// string slices and string maps reachable from v, which must be a pointer.
+// It returns the number of values resolved.
+func (r *Resolver) ResolveAll(ctx context.Context, v interface{}) (int, error) {
+	return r.walk(ctx, reflect.ValueOf(v))
+}
+
+func (r *Resolver) walk(ctx context.Context, v reflect.Value) (int, error) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return 0, nil
+		}
+		return r.walk(ctx, v.Elem())
+	case reflect.Struct:
+		total := 0
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			n, err := r.walk(ctx, v.Field(i))
+			total += n
+			if err != nil {
+				return total, err
+			}
+		}
+		return total, nil
+	case reflect.Slice, reflect.Array:
+		total := 0
+		for i := 0; i < v.Len(); i++ {
+			n, err := r.walk(ctx, v.Index(i))
+			total += n
+			if err != nil {
+				return total, err
+			}
+		}
+		return total, nil
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return 0, nil
+		}
+		total := 0
+		for _, k := range v.MapKeys() {
+			s := v.MapIndex(k).String()
+			if !IsReference(s) {
+				continue
+			}
+			resolved, err := r.Resolve(ctx, s)
+			if err != nil {
+				return total, err
+			}
+			v.SetMapIndex(k, reflect.ValueOf(resolved).Convert(v.Type().Elem()))
+			total++
+		}
+		return total, nil
+	case reflect.String:
+		if !IsReference(v.String()) || !v.CanSet() {
+			return 0, nil
+		}
+		resolved, err := r.Resolve(ctx, v.String())
+		if err != nil {
+			return 0, err
+		}
+		v.SetString(resolved)
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// KeepAlive renews the Vault token and any leases behind resolved secrets
+// until ctx is done, at two thirds of the shortest TTL
+func (r *Resolver) KeepAlive(ctx context.Context) {
+	if r.vault == nil {
+		return
+	}
+
+	for {
+		interval := r.renew(ctx)
+		if interval <= 0 {
+			return
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// renew renews the token and leases once and returns when to renew next,
+// or zero when nothing is renewable
+func (r *Resolver) renew(ctx context.Context) time.Duration {
+	var next time.Duration
+	track := func(ttl time.Duration) {
+		if ttl > 0 && (next == 0 || ttl < next) {
+			next = ttl
+		}
+	}
+
+	ttl, err := r.vault.RenewToken(ctx)
+	if err != nil {
+		r.logger.Warn("Vault token renewal failed", "error", err)
+	}
+	track(ttl)
+
+	r.mu.Lock()
+	leases := append([]lease(nil), r.leases...)
+	r.mu.Unlock()
+	for _, l := range leases {
+		ttl, err := r.vault.RenewLease(ctx, l.id, l.duration)
+		if err != nil {
+			r.logger.Warn("Vault lease renewal failed", "lease_id", l.id, "error", err)
+			continue
+		}
+		track(ttl)
+	}
+
+	if next == 0 {
+		return 0
+	}
+	interval := next * 2 / 3
+	if interval < 10*time.Second {
+		interval = 10 * time.Second
+	}
+	r.logger.Debug("Vault credentials renewed", "next_renewal", interval.String())
+	return interval
+}
@@ -0,0 +1,143 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VaultClient reads secrets through the Vault HTTP API with a token
+type VaultClient struct {
+	client    *http.Client
+	address   string
+	token     string
+	namespace string
+}
+
+// NewVaultClient creates a client for the Vault server at address
+func NewVaultClient(address, token, namespace string, timeout time.Duration) *VaultClient {
+	return &VaultClient{
+		client:    &http.Client{Timeout: timeout},
+		address:   strings.TrimSuffix(address, "/"),
+		token:     token,
+		namespace: namespace,
+	}
+}
+
+// vaultResponse covers KV v1, KV v2 and dynamic secret responses
+type vaultResponse struct {
+	LeaseID       string                 `json:"lease_id"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+	Data          map[string]interface{} `json:"data"`
+	Auth          *struct {
+		LeaseDuration int  `json:"lease_duration"`
+		Renewable     bool `json:"renewable"`
+	} `json:"auth"`
+}
+
+// Read returns the fields of the secret at path. KV v2 paths include the
+// data/ segment (secret/data/app). The lease is returned for renewable
+// dynamic secrets.
+func (v *VaultClient) Read(ctx context.Context, path string) (map[string]string, *lease, error) {
+	var resp vaultResponse
+	if err := v.do(ctx, http.MethodGet, "/v1/"+strings.TrimPrefix(path, "/"), nil, &resp); err != nil {
+		return nil, nil, fmt.Errorf("failed to read vault secret %s: %w", path, err)
+	}
+
+	data := resp.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMeta := data["metadata"]; hasMeta {
+			data = inner // KV v2
+		}
+	}
+
+	values := make(map[string]string, len(data))
+	for k, val := range data {
+		switch t := val.(type) {
+		case string:
+			values[k] = t
+		default:
+			encoded, err := json.Marshal(t)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to encode vault field %s: %w", k, err)
+			}
+			values[k] = string(encoded)
+		}
+	}
+
+	var l *lease
+	if resp.LeaseID != "" && resp.Renewable {
+		l = &lease{id: resp.LeaseID, duration: time.Duration(resp.LeaseDuration) * time.Second}
+	}
+	return values, l, nil
+}
+
+// RenewToken renews the client token and returns its new TTL, or zero when
+// the token is not renewable
+func (v *VaultClient) RenewToken(ctx context.Context) (time.Duration, error) {
+	var resp vaultResponse
+	if err := v.do(ctx, http.MethodPost, "/v1/auth/token/renew-self", map[string]interface{}{}, &resp); err != nil {
+		return 0, err
+	}
+	if resp.Auth == nil || !resp.Auth.Renewable {
+		return 0, nil
+	}
+	return time.Duration(resp.Auth.LeaseDuration) * time.Second, nil
+}
+
+// RenewLease extends a lease by increment and returns its new TTL
+func (v *VaultClient) RenewLease(ctx context.Context, leaseID string, increment time.Duration) (time.Duration, error) {
+	var resp vaultResponse
+	body := map[string]interface{}{
+		"lease_id":  leaseID,
+		"increment": int(increment.Seconds()),
+	}
+	if err := v.do(ctx, http.MethodPut, "/v1/sys/leases/renew", body, &resp); err != nil {
+		return 0, err
+	}
+	return time.Duration(resp.LeaseDuration) * time.Second, nil
+}
+
+func (v *VaultClient) do(ctx context.Context, method, path string, input, output interface{}) error {
+	var body io.Reader
+	if input != nil {
+		data, err := json.Marshal(input)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, v.address+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	if input != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("vault returned %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, output)
+}
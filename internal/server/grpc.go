@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/supratick/message_producer/internal/generator"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ServiceName is the fully qualified gRPC service name
+const ServiceName = "producer.v1.TransactionStream"
+
+// RateHeader carries the negotiated rate back to the client
+const RateHeader = "x-negotiated-rate"
+
+// shutdownGrace bounds how long Serve waits for in-flight sends to finish
+// once ctx is cancelled before it drops the remaining connections
+const shutdownGrace = 5 * time.Second
+
+// StreamRequest is sent by a client to open a transaction stream
+type StreamRequest struct {
+	Rate  int `json:"rate"`  // messages/sec, 0 = server default
+	Count int `json:"count"` // messages to send, 0 = until the client disconnects
+}
+
+// jsonCodec lets clients use the service without generated protobuf code.
+// Clients select it with grpc.CallContentSubtype("json").
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                               { return "json" }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// GRPCServer streams generated transactions to connected clients
+type GRPCServer struct {
+	producer    *generator.Producer
+	server      *grpc.Server
+	address     string
+	defaultRate int
+	maxRate     int
+	clients     atomic.Int64
+	count       atomic.Int64
+	stopping    chan struct{} // closed when Serve's ctx is cancelled
+	logger      *slog.Logger
+}
+
+// NewGRPCServer creates a new gRPC streaming source
+func NewGRPCServer(producer *generator.Producer, address string, defaultRate, maxRate int, logger *slog.Logger) *GRPCServer {
+	s := &GRPCServer{
+		producer:    producer,
+		server:      grpc.NewServer(),
+		address:     address,
+		defaultRate: defaultRate,
+		maxRate:     maxRate,
+		stopping:    make(chan struct{}),
+		logger:      logger,
+	}
+	s.server.RegisterService(&serviceDesc, s)
+	return s
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*interface{})(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       streamHandler,
+			ServerStreams: true,
+		},
+	},
+}
+
+func streamHandler(srv interface{}, stream grpc.ServerStream) error {
+	req := new(StreamRequest)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	return srv.(*GRPCServer).stream(req, stream)
+}
+
+// Serve listens on the configured address until ctx is cancelled
+func (s *GRPCServer) Serve(ctx context.Context) error {
+	lis, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.address, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		// Open streams end at their next transaction; one blocked on a
+		// slow client is cut off after shutdownGrace
+		close(s.stopping)
+		stop := time.AfterFunc(shutdownGrace, s.server.Stop)
+		defer stop.Stop()
+		s.server.GracefulStop()
+	}()
+
+	s.logger.Info("gRPC source listening", "address", lis.Addr().String())
+	if err := s.server.Serve(lis); err != nil {
+		return fmt.Errorf("gRPC server error: %w", err)
+	}
+	return nil
+}
+
+// negotiateRate resolves the client's requested rate against server limits
+func (s *GRPCServer) negotiateRate(requested int) int {
+	rate := requested
+	if rate <= 0 {
+		rate = s.defaultRate
+	}
+	if s.maxRate > 0 && (rate <= 0 || rate > s.maxRate) {
+		rate = s.maxRate
+	}
+	return rate
+}
+
+func (s *GRPCServer) stream(req *StreamRequest, stream grpc.ServerStream) error {
+	if req.Count < 0 {
+		return status.Error(codes.InvalidArgument, "count must be non-negative")
+	}
+
+	rate := s.negotiateRate(req.Rate)
+	if err := stream.SendHeader(metadata.Pairs(RateHeader, strconv.Itoa(rate))); err != nil {
+		return err
+	}
+
+	s.clients.Add(1)
+	defer s.clients.Add(-1)
+	s.logger.Info("gRPC client connected", "rate", rate, "count", req.Count)
+
+	ctx := stream.Context()
+	start := time.Now()
+	for sent := 0; req.Count == 0 || sent < req.Count; sent++ {
+		if rate > 0 {
+			due := start.Add(time.Duration(sent) * time.Second / time.Duration(rate))
+			if wait := time.Until(due); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-s.stopping:
+					return nil
+				case <-time.After(wait):
+				}
+			}
+		}
+		// Checked before every send, so an unpaced stream still sees the
+		// server shutting down
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.stopping:
+			return nil
+		default:
+		}
+
+		txn := s.producer.GenerateSingle()
+		if err := stream.SendMsg(txn); err != nil {
+			// Client went away; not a server failure
+			s.logger.Debug("gRPC client disconnected", "sent", sent, "error", err)
+			return nil
+		}
+		s.count.Add(1)
+	}
+	return nil
+}
+
+// Count returns the number of transactions streamed to all clients
+func (s *GRPCServer) Count() int64 {
+	return s.count.Load()
+}
+
+// Clients returns the number of currently connected clients
+func (s *GRPCServer) Clients() int64 {
+	return s.clients.Load()
+}
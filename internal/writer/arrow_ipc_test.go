@@ -0,0 +1,95 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func writeArrowIPC(t *testing.T, format, compression string) string {
+	t.Helper()
+	dir := t.TempDir()
+	w, err := NewArrowIPCWriter(dir, "transactions.arrow", 2, format, compression, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := make(chan *models.Transaction, 5)
+	for i := 0; i < 5; i++ {
+		input <- &models.Transaction{ID: fmt.Sprintf("TXN-%d", i), HouseID: i, CurrencyCode: "USD"}
+	}
+	close(input)
+	if err := w.Write(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Count() != 5 {
+		t.Errorf("count = %d, want 5", w.Count())
+	}
+	return filepath.Join(dir, "transactions.arrow")
+}
+
+func TestArrowIPCFile(t *testing.T) {
+	f, err := os.Open(writeArrowIPC(t, ArrowIPCFile, "zstd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := ipc.NewFileReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// Five rows in batches of two
+	if r.NumRecords() != 3 {
+		t.Fatalf("batches = %d, want 3", r.NumRecords())
+	}
+	rec, err := r.Record(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := rec.Column(0).(*array.String)
+	houses := rec.Column(8).(*array.Int32)
+	if rec.NumRows() != 1 || ids.Value(0) != "TXN-4" || houses.Value(0) != 4 {
+		t.Errorf("last batch = %v", rec)
+	}
+}
+
+func TestArrowIPCStream(t *testing.T) {
+	f, err := os.Open(writeArrowIPC(t, ArrowIPCStream, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := ipc.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Release()
+
+	var rows int64
+	for r.Next() {
+		rows += r.Record().NumRows()
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 5 {
+		t.Errorf("rows = %d, want 5", rows)
+	}
+}
+
+func TestArrowIPCUnsupportedCompression(t *testing.T) {
+	if _, err := NewArrowIPCWriter(t.TempDir(), "transactions.arrow", 10, ArrowIPCFile, "snappy", nil); err == nil {
+		t.Fatal("snappy accepted")
+	}
+}
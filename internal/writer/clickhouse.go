@@ -0,0 +1,180 @@
+package writer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// clickHouseDriver is the database/sql name clickhouse-go registers under
+const clickHouseDriver = "clickhouse"
+
+// ClickHouseOptions holds connection and insert settings for the ClickHouse
+// writer
+type ClickHouseOptions struct {
+	Addr      string // host or host:port of the native protocol, default port 9000
+	User      string
+	Password  string
+	Database  string
+	Secure    bool // TLS
+	Table     string
+	BlockSize int // rows per INSERT block
+	// AsyncInsert has the server buffer inserts and write them to parts
+	// in the background; WaitForAsyncInsert acknowledges an insert only
+	// once it is written
+	AsyncInsert        bool
+	WaitForAsyncInsert bool
+	Timeout            time.Duration // dial and read
+}
+
+// ClickHouseWriter inserts transactions into a ClickHouse table over the
+// native protocol. Each batch of BlockSize rows is sent as one block.
+type ClickHouseWriter struct {
+	*sqlWriter
+}
+
+// NewClickHouseWriter connects and creates the table if it does not exist.
+// The clickhouse-go driver is only linked into binaries built with
+// -tags clickhouse.
+func NewClickHouseWriter(opts ClickHouseOptions, logger *slog.Logger) (*ClickHouseWriter, error) {
+	return newClickHouseWriter(clickHouseDriver, opts, logger)
+}
+
+func newClickHouseWriter(driver string, opts ClickHouseOptions, logger *slog.Logger) (*ClickHouseWriter, error) {
+	db, err := openClickHouse(driver, opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(clickHouseTableDDL(opts.Table)); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create ClickHouse table: %w", err)
+	}
+
+	w := newSQLWriter(db, opts.Table, opts.BlockSize, questionPlaceholder, logger)
+	w.blockInsert = true
+	w.bind = appendClickHouseArgs
+	return &ClickHouseWriter{sqlWriter: w}, nil
+}
+
+// CheckClickHouse connects with opts and returns the server version
+func CheckClickHouse(ctx context.Context, opts ClickHouseOptions) (string, error) {
+	db, err := openClickHouse(clickHouseDriver, opts)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT version()").Scan(&version); err != nil {
+		return "", fmt.Errorf("failed to query ClickHouse: %w", err)
+	}
+	return version, nil
+}
+
+func openClickHouse(driver string, opts ClickHouseOptions) (*sql.DB, error) {
+	if !slices.Contains(sql.Drivers(), driver) {
+		return nil, fmt.Errorf("clickhouse support not compiled in; rebuild with -tags clickhouse")
+	}
+	db, err := sql.Open(driver, clickHouseDSN(opts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ClickHouse connection: %w", err)
+	}
+	// Batches are inserted one at a time
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// clickHouseDSN builds the clickhouse-go DSN. Parameters the driver does
+// not know are sent to the server as query settings.
+func clickHouseDSN(opts ClickHouseOptions) string {
+	host := opts.Addr
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "9000")
+	}
+	query := url.Values{}
+	if opts.Timeout > 0 {
+		query.Set("dial_timeout", opts.Timeout.String())
+		query.Set("read_timeout", opts.Timeout.String())
+	}
+	if opts.Secure {
+		query.Set("secure", "true")
+	}
+	if opts.AsyncInsert {
+		query.Set("async_insert", "1")
+		query.Set("wait_for_async_insert", "0")
+		if opts.WaitForAsyncInsert {
+			query.Set("wait_for_async_insert", "1")
+		}
+	}
+	dsn := url.URL{
+		Scheme:   "clickhouse",
+		Host:     host,
+		Path:     "/" + opts.Database,
+		RawQuery: query.Encode(),
+	}
+	if opts.User != "" {
+		dsn.User = url.UserPassword(opts.User, opts.Password)
+	}
+	return dsn.String()
+}
+
+// clickHouseTableDDL generates a MergeTree table from the transaction
+// columns, ordered by settlement time
+func clickHouseTableDDL(table string) string {
+	return generatedTableDDL(table, func(column string, field columnField) string {
+		switch {
+		case field.amount:
+			return "Decimal(20, 6)"
+		case field.numeric:
+			return "Int32"
+		case column == "settled_at":
+			return "DateTime('UTC')"
+		default:
+			return "String"
+		}
+	}) + " ENGINE = MergeTree ORDER BY (settled_at, id)"
+}
+
+// appendClickHouseArgs appends txn's columns typed as the native protocol
+// encodes them. An amount or timestamp that does not parse fails the row
+// rather than being inserted as zero.
+func appendClickHouseArgs(args []interface{}, txn *models.Transaction) ([]interface{}, error) {
+	var amounts [3]decimal.Decimal
+	for i, s := range []string{txn.BetAmount, txn.WinAmount, txn.WinLoss} {
+		d, err := decimal.NewFromString(s)
+		if err != nil {
+			return args, fmt.Errorf("transaction %s: invalid amount %q: %w", txn.ID, s, err)
+		}
+		amounts[i] = d
+	}
+	settledAt, err := time.Parse(time.RFC3339, txn.SettledAt)
+	if err != nil {
+		return args, fmt.Errorf("transaction %s: invalid settled_at %q: %w", txn.ID, txn.SettledAt, err)
+	}
+	return append(args,
+		txn.ID,
+		txn.ExternalTransactionID,
+		txn.VendorBetID,
+		txn.RoundID,
+		int32(txn.VendorID),
+		txn.VendorCode,
+		int32(txn.VendorLineID),
+		int32(txn.GameCategoryID),
+		int32(txn.HouseID),
+		int32(txn.MasterAgentID),
+		int32(txn.AgentID),
+		int32(txn.CurrencyID),
+		txn.CurrencyCode,
+		amounts[0],
+		amounts[1],
+		amounts[2],
+		settledAt.UTC(),
+	), nil
+}
@@ -0,0 +1,6 @@
+//go:build clickhouse
+
+package writer
+
+// Registers the "clickhouse" database/sql driver for the native protocol
+import _ "github.com/ClickHouse/clickhouse-go/v2"
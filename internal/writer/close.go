@@ -0,0 +1,45 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// CloseWithin runs close and returns its error, or gives up once ctx is done.
+// A close that outlives ctx keeps running in the background: a flush stuck
+// on a hung NFS mount or broker cannot be interrupted, only abandoned, and
+// the process exits soon after shutdown anyway.
+func CloseWithin(ctx context.Context, close func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- close()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("close abandoned: %w", ctx.Err())
+	}
+}
+
+// closeConnWithin runs flush, then closes conn. Once ctx is done conn is
+// closed early, failing a flush blocked on a peer that stopped reading.
+func closeConnWithin(ctx context.Context, conn net.Conn, flush func() error) error {
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	err := flush()
+	if !stop() {
+		// ctx closed conn, cutting the flush short if it had not finished
+		if err != nil {
+			return fmt.Errorf("close abandoned: %w", ctx.Err())
+		}
+		return nil
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,56 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestCloseWithin(t *testing.T) {
+	if err := CloseWithin(context.Background(), func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	failed := errors.New("flush failed")
+	if err := CloseWithin(context.Background(), func() error { return failed }); !errors.Is(err, failed) {
+		t.Fatalf("err = %v, want the close error", err)
+	}
+
+	// A close that hangs is abandoned at the deadline
+	hung := make(chan struct{})
+	defer close(hung)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := CloseWithin(ctx, func() error { <-hung; return nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+}
+
+func TestCloseConnWithin(t *testing.T) {
+	// The peer never reads, so the flush blocks until the deadline closes
+	// the connection
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := closeConnWithin(ctx, conn, func() error {
+		_, err := conn.Write([]byte("pending"))
+		return err
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+
+	conn, peer = net.Pipe()
+	go peer.Read(make([]byte, 16))
+	if err := closeConnWithin(context.Background(), conn, func() error {
+		_, err := conn.Write([]byte("pending"))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write([]byte("x")); err == nil {
+		t.Error("connection still open after close")
+	}
+}
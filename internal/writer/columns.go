@@ -0,0 +1,44 @@
+package writer
+
+import (
+	"strconv"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// columnField reads one transaction column as text, for the writers that
+// lay out columns by name
+type columnField struct {
+	value   func(t *models.Transaction) string
+	numeric bool // a number; fixed-width right-aligns and never truncates it
+	amount  bool // decimal string, may use implied decimals
+}
+
+func intField(get func(t *models.Transaction) int) columnField {
+	return columnField{value: func(t *models.Transaction) string { return strconv.Itoa(get(t)) }, numeric: true}
+}
+
+func amountField(get func(t *models.Transaction) string) columnField {
+	return columnField{value: get, numeric: true, amount: true}
+}
+
+// columnFields are the columns of models.TransactionCSVHeader
+var columnFields = map[string]columnField{
+	"id":                      {value: func(t *models.Transaction) string { return t.ID }},
+	"external_transaction_id": {value: func(t *models.Transaction) string { return t.ExternalTransactionID }},
+	"vendor_bet_id":           {value: func(t *models.Transaction) string { return t.VendorBetID }},
+	"round_id":                {value: func(t *models.Transaction) string { return t.RoundID }},
+	"vendor_id":               intField(func(t *models.Transaction) int { return t.VendorID }),
+	"vendor_code":             {value: func(t *models.Transaction) string { return t.VendorCode }},
+	"vendor_line_id":          intField(func(t *models.Transaction) int { return t.VendorLineID }),
+	"game_category_id":        intField(func(t *models.Transaction) int { return t.GameCategoryID }),
+	"house_id":                intField(func(t *models.Transaction) int { return t.HouseID }),
+	"master_agent_id":         intField(func(t *models.Transaction) int { return t.MasterAgentID }),
+	"agent_id":                intField(func(t *models.Transaction) int { return t.AgentID }),
+	"currency_id":             intField(func(t *models.Transaction) int { return t.CurrencyID }),
+	"currency_code":           {value: func(t *models.Transaction) string { return t.CurrencyCode }},
+	"bet_amount":              amountField(func(t *models.Transaction) string { return t.BetAmount }),
+	"win_amount":              amountField(func(t *models.Transaction) string { return t.WinAmount }),
+	"win_loss":                amountField(func(t *models.Transaction) string { return t.WinLoss }),
+	"settled_at":              {value: func(t *models.Transaction) string { return t.SettledAt }},
+}
@@ -1,21 +1,81 @@
 package writer
 
 import (
+	"bufio"
+	"bytes"
 	"context"
-	"encoding/csv"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"sync/atomic"
 
+	"github.com/shopspring/decimal"
+
+	"github.com/supratick/message_producer/internal/codec"
 	"github.com/supratick/message_producer/internal/models"
 )
 
+// CSV quoting modes
+const (
+	CSVQuoteMinimal = "minimal" // quote only fields that need it (default)
+	CSVQuoteStrings = "strings" // quote text columns, leave numeric columns raw
+	CSVQuoteAll     = "all"     // quote every field
+)
+
+// csvDecimalColumns lists the amount columns that accept a decimal format
+var csvDecimalColumns = []string{"bet_amount", "win_amount", "win_loss"}
+
+// CSVFormat controls how fields are written. The zero value matches the
+// previous output: minimal quoting and amounts with six decimal places.
+type CSVFormat struct {
+	Quoting       string         // minimal, strings or all
+	DecimalPlaces map[string]int // amount column -> fixed places, negative trims trailing zeros
+	// Append continues an existing file instead of truncating it. The header
+	// must match and a partial final line left by an interrupted run is cut.
+	Append bool
+}
+
+// csvEncoder is the compiled form of CSVFormat
+type csvEncoder struct {
+	quoteText    bool // always quote text columns
+	quoteNumeric bool // always quote numeric columns
+	places       [3]int
+	reformat     [3]bool
+}
+
+func newCSVEncoder(format CSVFormat) (csvEncoder, error) {
+	var enc csvEncoder
+	switch format.Quoting {
+	case "", CSVQuoteMinimal:
+	case CSVQuoteStrings:
+		enc.quoteText = true
+	case CSVQuoteAll:
+		enc.quoteText = true
+		enc.quoteNumeric = true
+	default:
+		return enc, fmt.Errorf("unsupported CSV quoting: %s", format.Quoting)
+	}
+	for column, places := range format.DecimalPlaces {
+		i := slices.Index(csvDecimalColumns, column)
+		if i < 0 {
+			return enc, fmt.Errorf("unsupported CSV decimal column: %s", column)
+		}
+		enc.places[i] = places
+		enc.reformat[i] = true
+	}
+	return enc, nil
+}
+
 // CSVWriter writes transactions to CSV file
 type CSVWriter struct {
 	file       *os.File
-	writer     *csv.Writer
+	writer     *bufio.Writer
+	encoder    csvEncoder
+	record     []byte // reused encoding buffer for one row
 	bufferSize int
 	buffer     []*models.Transaction
 	count      atomic.Int64
@@ -23,40 +83,129 @@ type CSVWriter struct {
 }
 
 // NewCSVWriter creates a new CSV writer
-func NewCSVWriter(outputDir, filename string, bufferSize int, logger *slog.Logger) (*CSVWriter, error) {
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create output directory: %w", err)
+func NewCSVWriter(outputDir, filename string, bufferSize int, format CSVFormat, logger *slog.Logger) (*CSVWriter, error) {
+	encoder, err := newCSVEncoder(format)
+	if err != nil {
+		return nil, err
 	}
 
 	path := filepath.Join(outputDir, filename)
-	file, err := os.Create(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var file *os.File
+	if format.Append {
+		file, err = openCSVAppend(path, encoder, logger)
+	} else {
+		file, err = os.Create(path)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CSV file: %w", err)
 	}
 
-	writer := csv.NewWriter(file)
-	
-	// Write header
-	header := []string{
-		"id", "external_transaction_id", "vendor_bet_id", "round_id",
-		"vendor_id", "vendor_code", "vendor_line_id", "game_category_id",
-		"house_id", "master_agent_id", "agent_id", "currency_id",
-		"currency_code", "bet_amount", "win_amount", "win_loss", "settled_at",
-	}
-	if err := writer.Write(header); err != nil {
-		file.Close()
-		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	writer := bufio.NewWriterSize(retryWrites(file, logger), 64*1024)
+
+	// Write header unless continuing a file that already has one
+	if offset, _ := file.Seek(0, io.SeekCurrent); offset == 0 {
+		if _, err := writer.Write(encoder.header()); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write CSV header: %w", err)
+		}
 	}
 
 	return &CSVWriter{
 		file:       file,
 		writer:     writer,
+		encoder:    encoder,
+		record:     make([]byte, 0, 512),
 		bufferSize: bufferSize,
 		buffer:     make([]*models.Transaction, 0, bufferSize),
 		logger:     logger,
 	}, nil
 }
 
+// header encodes the header line
+func (e *csvEncoder) header() []byte {
+	header := make([]byte, 0, 256)
+	for i, column := range transactionColumns {
+		if i > 0 {
+			header = append(header, ',')
+		}
+		header = codec.AppendCSVField(header, column, e.quoteText)
+	}
+	return append(header, '\n')
+}
+
+// openCSVAppend opens path for appending, positioned after its last complete
+// line. An existing header must match the encoder's; a partial final line is
+// truncated away. A new or empty file is left at offset 0 for the header.
+func openCSVAppend(path string, encoder csvEncoder, logger *slog.Logger) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return file, nil
+	}
+
+	end, err := lastLineEnd(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if end < info.Size() {
+		logger.Warn("Truncating partial final CSV line", "path", path, "bytes", info.Size()-end)
+		if err := file.Truncate(end); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	if end > 0 {
+		header := encoder.header()
+		existing := make([]byte, len(header))
+		if _, err := file.ReadAt(existing, 0); err != nil && err != io.EOF {
+			file.Close()
+			return nil, err
+		}
+		if !bytes.Equal(existing, header) {
+			file.Close()
+			return nil, fmt.Errorf("cannot append to %s: header does not match the configured columns and quoting", path)
+		}
+	}
+
+	if _, err := file.Seek(end, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	logger.Info("Appending to existing CSV file", "path", path, "bytes", end)
+	return file, nil
+}
+
+// lastLineEnd returns the offset just past the file's last newline, or 0 when
+// it has none
+func lastLineEnd(file *os.File, size int64) (int64, error) {
+	chunk := make([]byte, 64*1024)
+	for end := size; end > 0; {
+		start := max(end-int64(len(chunk)), 0)
+		n, err := file.ReadAt(chunk[:end-start], start)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(chunk[:n], '\n'); i >= 0 {
+			return start + int64(i) + 1, nil
+		}
+		end = start
+	}
+	return 0, nil
+}
+
 // Write writes transactions from the channel to CSV
 func (w *CSVWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
 	for {
@@ -85,41 +234,82 @@ func (w *CSVWriter) flush() error {
 	}
 
 	for _, txn := range w.buffer {
-		record := []string{
-			txn.ID,
-			txn.ExternalTransactionID,
-			txn.VendorBetID,
-			txn.RoundID,
-			fmt.Sprintf("%d", txn.VendorID),
-			txn.VendorCode,
-			fmt.Sprintf("%d", txn.VendorLineID),
-			fmt.Sprintf("%d", txn.GameCategoryID),
-			fmt.Sprintf("%d", txn.HouseID),
-			fmt.Sprintf("%d", txn.MasterAgentID),
-			fmt.Sprintf("%d", txn.AgentID),
-			fmt.Sprintf("%d", txn.CurrencyID),
-			txn.CurrencyCode,
-			txn.BetAmount,
-			txn.WinAmount,
-			txn.WinLoss,
-			txn.SettledAt,
-		}
-		
-		if err := w.writer.Write(record); err != nil {
+		w.record = w.encoder.appendRecord(w.record[:0], txn)
+		if _, err := w.writer.Write(w.record); err != nil {
 			return fmt.Errorf("failed to write CSV record: %w", err)
 		}
 	}
-	
-	w.writer.Flush()
-	if err := w.writer.Error(); err != nil {
+
+	if err := w.writer.Flush(); err != nil {
 		return fmt.Errorf("failed to flush CSV writer: %w", err)
 	}
-	
+
 	w.count.Add(int64(len(w.buffer)))
 	w.buffer = w.buffer[:0]
 	return nil
 }
 
+// appendRecord encodes one transaction as a CSV line without allocating
+func (e *csvEncoder) appendRecord(dst []byte, txn *models.Transaction) []byte {
+	dst = codec.AppendCSVField(dst, txn.ID, e.quoteText)
+	dst = append(dst, ',')
+	dst = codec.AppendCSVField(dst, txn.ExternalTransactionID, e.quoteText)
+	dst = append(dst, ',')
+	dst = codec.AppendCSVField(dst, txn.VendorBetID, e.quoteText)
+	dst = append(dst, ',')
+	dst = codec.AppendCSVField(dst, txn.RoundID, e.quoteText)
+	dst = append(dst, ',')
+	dst = e.appendInt(dst, txn.VendorID)
+	dst = append(dst, ',')
+	dst = codec.AppendCSVField(dst, txn.VendorCode, e.quoteText)
+	dst = append(dst, ',')
+	dst = e.appendInt(dst, txn.VendorLineID)
+	dst = append(dst, ',')
+	dst = e.appendInt(dst, txn.GameCategoryID)
+	dst = append(dst, ',')
+	dst = e.appendInt(dst, txn.HouseID)
+	dst = append(dst, ',')
+	dst = e.appendInt(dst, txn.MasterAgentID)
+	dst = append(dst, ',')
+	dst = e.appendInt(dst, txn.AgentID)
+	dst = append(dst, ',')
+	dst = e.appendInt(dst, txn.CurrencyID)
+	dst = append(dst, ',')
+	dst = codec.AppendCSVField(dst, txn.CurrencyCode, e.quoteText)
+	dst = append(dst, ',')
+	dst = e.appendDecimal(dst, txn.BetAmount, 0)
+	dst = append(dst, ',')
+	dst = e.appendDecimal(dst, txn.WinAmount, 1)
+	dst = append(dst, ',')
+	dst = e.appendDecimal(dst, txn.WinLoss, 2)
+	dst = append(dst, ',')
+	dst = codec.AppendCSVField(dst, txn.SettledAt, e.quoteText)
+	return append(dst, '\n')
+}
+
+func (e *csvEncoder) appendInt(dst []byte, v int) []byte {
+	if e.quoteNumeric {
+		dst = append(dst, '"')
+		dst = strconv.AppendInt(dst, int64(v), 10)
+		return append(dst, '"')
+	}
+	return strconv.AppendInt(dst, int64(v), 10)
+}
+
+// appendDecimal writes an amount column, reformatting it when configured
+func (e *csvEncoder) appendDecimal(dst []byte, v string, column int) []byte {
+	if e.reformat[column] {
+		if d, err := decimal.NewFromString(v); err == nil {
+			if e.places[column] < 0 {
+				v = d.String()
+			} else {
+				v = d.StringFixed(int32(e.places[column]))
+			}
+		}
+	}
+	return codec.AppendCSVField(dst, v, e.quoteNumeric)
+}
+
 // Close closes the CSV writer
 func (w *CSVWriter) Close() error {
 	if err := w.flush(); err != nil {
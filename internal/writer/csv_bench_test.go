@@ -0,0 +1,173 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// legacyCSVRecord is the fmt.Sprintf based encoding CSVWriter used before
+// csvEncoder, kept to compare against
+func legacyCSVRecord(w *csv.Writer, txn *models.Transaction) error {
+	return w.Write([]string{
+		txn.ID,
+		txn.ExternalTransactionID,
+		txn.VendorBetID,
+		txn.RoundID,
+		fmt.Sprintf("%d", txn.VendorID),
+		txn.VendorCode,
+		fmt.Sprintf("%d", txn.VendorLineID),
+		fmt.Sprintf("%d", txn.GameCategoryID),
+		fmt.Sprintf("%d", txn.HouseID),
+		fmt.Sprintf("%d", txn.MasterAgentID),
+		fmt.Sprintf("%d", txn.AgentID),
+		fmt.Sprintf("%d", txn.CurrencyID),
+		txn.CurrencyCode,
+		txn.BetAmount,
+		txn.WinAmount,
+		txn.WinLoss,
+		txn.SettledAt,
+	})
+}
+
+func TestCSVEncoderMatchesEncodingCSV(t *testing.T) {
+	txns := benchTransactions(100)
+	txns[1].VendorCode = `quoted "vendor", with comma`
+	txns[2].RoundID = " leading space"
+	txns[3].CurrencyCode = "multi\nline"
+
+	var want bytes.Buffer
+	cw := csv.NewWriter(&want)
+	var enc csvEncoder
+	var got []byte
+	for _, txn := range txns {
+		if err := legacyCSVRecord(cw, txn); err != nil {
+			t.Fatal(err)
+		}
+		got = enc.appendRecord(got, txn)
+	}
+	cw.Flush()
+
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Fatalf("encoding differs from encoding/csv:\ngot:\n%s\nwant:\n%s", got, want.Bytes())
+	}
+}
+
+func TestCSVEncoderFormats(t *testing.T) {
+	txn := benchTransactions(1)[0]
+	txn.WinLoss = "-124.505000"
+
+	tests := []struct {
+		format CSVFormat
+		want   string
+	}{
+		{CSVFormat{}, `TXN-20240101-00000000,EXT-PRAGMATIC-00000000,BET-00000000,ROUND-00000000,1,PRAGMATIC,1,1,1,1,1,1,USDT,125.500000,250.000000,-124.505000,2024-01-01T00:00:00Z`},
+		{CSVFormat{Quoting: CSVQuoteStrings}, `"TXN-20240101-00000000","EXT-PRAGMATIC-00000000","BET-00000000","ROUND-00000000",1,"PRAGMATIC",1,1,1,1,1,1,"USDT",125.500000,250.000000,-124.505000,"2024-01-01T00:00:00Z"`},
+		{CSVFormat{Quoting: CSVQuoteAll}, `"TXN-20240101-00000000","EXT-PRAGMATIC-00000000","BET-00000000","ROUND-00000000","1","PRAGMATIC","1","1","1","1","1","1","USDT","125.500000","250.000000","-124.505000","2024-01-01T00:00:00Z"`},
+		{CSVFormat{DecimalPlaces: map[string]int{"bet_amount": 2, "win_amount": -1, "win_loss": 2}}, `TXN-20240101-00000000,EXT-PRAGMATIC-00000000,BET-00000000,ROUND-00000000,1,PRAGMATIC,1,1,1,1,1,1,USDT,125.50,250,-124.51,2024-01-01T00:00:00Z`},
+	}
+	for _, tt := range tests {
+		enc, err := newCSVEncoder(tt.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(enc.appendRecord(nil, txn)); got != tt.want+"\n" {
+			t.Errorf("format %+v:\ngot  %s\nwant %s", tt.format, got, tt.want)
+		}
+	}
+
+	if _, err := newCSVEncoder(CSVFormat{DecimalPlaces: map[string]int{"agent_id": 2}}); err == nil {
+		t.Error("expected error for non-amount decimal column")
+	}
+}
+
+func TestCSVWriterAppend(t *testing.T) {
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	txns := benchTransactions(5)
+
+	write := func(format CSVFormat, txns []*models.Transaction) error {
+		w, err := NewCSVWriter(dir, "out.csv", 10, format, logger)
+		if err != nil {
+			return err
+		}
+		input := make(chan *models.Transaction, len(txns))
+		for _, txn := range txns {
+			input <- txn
+		}
+		close(input)
+		if err := w.Write(context.Background(), input); err != nil {
+			return err
+		}
+		return w.Close()
+	}
+
+	if err := write(CSVFormat{Append: true}, txns[:3]); err != nil {
+		t.Fatal(err)
+	}
+	// An interrupted run leaves half a line behind
+	path := filepath.Join(dir, "out.csv")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("TXN-partial,EXT-")
+	f.Close()
+
+	if err := write(CSVFormat{Append: true}, txns[3:]); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(mustOpen(t, path)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 6 || records[0][0] != "id" || records[5][0] != txns[4].ID {
+		t.Fatalf("appended file has %d records: %v", len(records), records)
+	}
+
+	if err := write(CSVFormat{Append: true, Quoting: CSVQuoteAll}, txns[:1]); err == nil {
+		t.Error("expected error appending with a different header")
+	}
+}
+
+func mustOpen(t *testing.T, path string) io.Reader {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(data)
+}
+
+func BenchmarkCSVRecordSprintf(b *testing.B) {
+	txns := benchTransactions(1000)
+	w := csv.NewWriter(io.Discard)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := legacyCSVRecord(w, txns[i%len(txns)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+	w.Flush()
+}
+
+func BenchmarkCSVRecordAppend(b *testing.B) {
+	txns := benchTransactions(1000)
+	var enc csvEncoder
+	record := make([]byte, 0, 512)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		record = enc.appendRecord(record[:0], txns[i%len(txns)])
+		io.Discard.Write(record)
+	}
+}
@@ -0,0 +1,65 @@
+package writer
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// duckDBTableDDL creates the transactions table with typed columns
+const duckDBTableDDL = `CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR,
+	external_transaction_id VARCHAR,
+	vendor_bet_id VARCHAR,
+	round_id VARCHAR,
+	vendor_id INTEGER,
+	vendor_code VARCHAR,
+	vendor_line_id INTEGER,
+	game_category_id INTEGER,
+	house_id INTEGER,
+	master_agent_id INTEGER,
+	agent_id INTEGER,
+	currency_id INTEGER,
+	currency_code VARCHAR,
+	bet_amount DECIMAL(20, 6),
+	win_amount DECIMAL(20, 6),
+	win_loss DECIMAL(20, 6),
+	settled_at TIMESTAMPTZ
+)`
+
+// DuckDBWriter appends transactions into a local DuckDB database file
+type DuckDBWriter struct {
+	*sqlWriter
+}
+
+// NewDuckDBWriter creates a new DuckDB writer. The DuckDB driver is cgo-based
+// and only linked into binaries built with -tags duckdb.
+func NewDuckDBWriter(outputDir, filename, table string, batchSize int, logger *slog.Logger) (*DuckDBWriter, error) {
+	if !slices.Contains(sql.Drivers(), "duckdb") {
+		return nil, fmt.Errorf("duckdb support not compiled in; rebuild with -tags duckdb")
+	}
+
+	path := filepath.Join(outputDir, filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	db, err := sql.Open("duckdb", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DuckDB database: %w", err)
+	}
+	// DuckDB allows a single writer per database file
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(fmt.Sprintf(duckDBTableDDL, table)); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create DuckDB table: %w", err)
+	}
+
+	return &DuckDBWriter{
+		sqlWriter: newSQLWriter(db, table, batchSize, questionPlaceholder, logger),
+	}, nil
+}
@@ -0,0 +1,6 @@
+//go:build duckdb
+
+package writer
+
+// Registers the "duckdb" database/sql driver
+import _ "github.com/marcboeker/go-duckdb"
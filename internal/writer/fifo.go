@@ -0,0 +1,363 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// FIFO policies for records no reader takes
+const (
+	FIFOWait   = "wait"   // hold the pipeline until a reader attaches (default)
+	FIFODrop   = "drop"   // drop records while no reader keeps up
+	FIFOBuffer = "buffer" // spool records to disk and replay them to the next reader
+)
+
+const (
+	fifoRetry = 250 * time.Millisecond // interval between attempts to find a reader
+	fifoPoll  = 5 * time.Millisecond   // interval between writes to a full pipe
+)
+
+var (
+	errFIFOFull = errors.New("named pipe is full")
+	errFIFOGone = errors.New("named pipe reader detached")
+)
+
+// FIFOOptions holds settings for the FIFO writer
+type FIFOOptions struct {
+	Path       string
+	Format     string        // ndjson or csv
+	NoReader   string        // wait, drop or buffer
+	SlowReader time.Duration // how long a full pipe is waited out before drop or buffer applies
+	SpoolDir   string        // buffer: directory of the spool file, default the system temp dir
+	SpoolLimit int64         // buffer: spool bytes beyond which records are dropped, 0 = unlimited
+	BufferSize int           // encoded bytes collected before a write to the pipe
+}
+
+// FIFOWriter streams NDJSON or CSV lines to a named pipe that readers can
+// attach to and detach from while the producer runs. The pipe is opened
+// without blocking; while no reader is attached, or one leaves the pipe full
+// for longer than SlowReader, records are held, dropped or spooled according
+// to NoReader. Lines go out whole, so a reader never sees a torn line.
+type FIFOWriter struct {
+	opts     FIFOOptions
+	csv      csvEncoder
+	header   []byte // written to each new reader, csv only
+	fd       int    // -1 while no reader is attached
+	nextOpen time.Time
+	pending  []byte // encoded records not yet written
+	records  int    // records in pending
+	chunk    []byte // spool replay buffer
+	spool    *os.File
+	spoolLen int64 // bytes in the spool
+	spoolOff int64 // bytes of the spool already replayed
+	count    atomic.Int64
+	dropped  atomic.Int64
+	spooled  atomic.Int64
+	logger   *slog.Logger
+}
+
+// NewFIFOWriter creates the named pipe if needed and returns a writer for it.
+// No reader has to be attached yet.
+func NewFIFOWriter(opts FIFOOptions, logger *slog.Logger) (*FIFOWriter, error) {
+	w := &FIFOWriter{
+		opts:    opts,
+		fd:      -1,
+		pending: make([]byte, 0, opts.BufferSize+1024),
+		logger:  logger,
+	}
+	switch opts.Format {
+	case "ndjson":
+	case "csv":
+		w.csv, _ = newCSVEncoder(CSVFormat{})
+		w.header = w.csv.header()
+	default:
+		return nil, fmt.Errorf("unsupported FIFO format: %s", opts.Format)
+	}
+	switch opts.NoReader {
+	case FIFOWait, FIFODrop:
+	case FIFOBuffer:
+		spool, err := os.CreateTemp(opts.SpoolDir, "fifo-*.spool")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create FIFO spool: %w", err)
+		}
+		w.spool = spool
+		w.chunk = make([]byte, max(opts.BufferSize, 64*1024))
+	default:
+		return nil, fmt.Errorf("unsupported FIFO no_reader policy: %s", opts.NoReader)
+	}
+
+	if err := makeFIFO(opts.Path); err != nil {
+		w.removeSpool()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write writes transactions from the channel to the pipe
+func (w *FIFOWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return w.flush(ctx)
+		case txn, ok := <-input:
+			if !ok {
+				// Channel closed, write what is pending
+				return w.flush(ctx)
+			}
+
+			if w.opts.Format == "csv" {
+				w.pending = w.csv.appendRecord(w.pending, txn)
+			} else {
+				w.pending = append(txn.AppendJSON(w.pending), '\n')
+			}
+			w.records++
+
+			// Write when the producer side is idle so readers see records promptly
+			if len(w.pending) >= w.opts.BufferSize || len(input) == 0 {
+				if err := w.flush(ctx); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// flush hands the pending records to a reader, waiting for one in wait mode
+// until ctx is done
+func (w *FIFOWriter) flush(ctx context.Context) error {
+	if w.records == 0 {
+		return nil
+	}
+	data, n := w.pending, w.records
+	defer func() {
+		w.pending, w.records = w.pending[:0], 0
+	}()
+
+	waiting := false
+	for {
+		if err := w.attach(ctx); err != nil {
+			return err
+		}
+		if w.fd < 0 {
+			if w.opts.NoReader != FIFOWait || ctx.Err() != nil {
+				return w.hold(data, n)
+			}
+			if !waiting {
+				w.logger.Info("Waiting for a FIFO reader", "path", w.opts.Path)
+				waiting = true
+			}
+			w.pause(ctx, fifoRetry)
+			continue
+		}
+		if w.spoolOff < w.spoolLen {
+			// The reader has not caught up with the spool; keep the order
+			return w.hold(data, n)
+		}
+
+		written, err := w.send(ctx, data, w.opts.NoReader != FIFOWait)
+		sent := bytes.Count(data[:written], []byte{'\n'})
+		w.count.Add(int64(sent))
+		data, n = data[written:], n-sent
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, errFIFOGone):
+			w.detach()
+		case errors.Is(err, errFIFOFull), ctx.Err() != nil:
+			return w.hold(data, n)
+		default:
+			return fmt.Errorf("failed to write to FIFO %s: %w", w.opts.Path, err)
+		}
+	}
+}
+
+// attach opens the pipe when no reader is attached and it is time to look
+// again, greets a new reader with the CSV header and replays the spool
+func (w *FIFOWriter) attach(ctx context.Context) error {
+	if w.fd < 0 {
+		if time.Now().Before(w.nextOpen) {
+			return nil
+		}
+		fd, err := openFIFO(w.opts.Path)
+		if err != nil {
+			return fmt.Errorf("failed to open FIFO %s: %w", w.opts.Path, err)
+		}
+		if fd < 0 {
+			w.nextOpen = time.Now().Add(fifoRetry)
+			return nil
+		}
+		w.fd = fd
+		w.logger.Info("FIFO reader attached", "path", w.opts.Path)
+
+		// The header is shorter than the pipe buffer, so an empty pipe takes it whole
+		if w.header != nil {
+			if _, err := writeFIFO(w.fd, w.header); err != nil {
+				w.detach()
+				return nil
+			}
+		}
+	}
+	return w.replay(ctx)
+}
+
+// replay writes the spool to the reader in whole lines, stopping while the
+// reader is slow or gone and keeping the rest for later
+func (w *FIFOWriter) replay(ctx context.Context) error {
+	for w.fd >= 0 && w.spoolOff < w.spoolLen {
+		chunk := w.chunk[:min(int64(len(w.chunk)), w.spoolLen-w.spoolOff)]
+		if _, err := w.spool.ReadAt(chunk, w.spoolOff); err != nil {
+			return fmt.Errorf("failed to read FIFO spool: %w", err)
+		}
+		chunk = chunk[:bytes.LastIndexByte(chunk, '\n')+1]
+		if len(chunk) == 0 {
+			return fmt.Errorf("FIFO spool line longer than %d bytes", len(w.chunk))
+		}
+
+		written, err := w.send(ctx, chunk, true)
+		n := int64(bytes.Count(chunk[:written], []byte{'\n'}))
+		w.spoolOff += int64(written)
+		w.count.Add(n)
+		w.spooled.Add(-n)
+		switch {
+		case err == nil:
+		case errors.Is(err, errFIFOGone):
+			w.detach()
+			return nil
+		case errors.Is(err, errFIFOFull), ctx.Err() != nil:
+			return nil
+		default:
+			return fmt.Errorf("failed to write to FIFO %s: %w", w.opts.Path, err)
+		}
+	}
+
+	if w.spoolLen > 0 && w.spoolOff == w.spoolLen {
+		if err := w.spool.Truncate(0); err != nil {
+			return fmt.Errorf("failed to reset FIFO spool: %w", err)
+		}
+		w.spoolLen, w.spoolOff = 0, 0
+	}
+	return nil
+}
+
+// send writes data to the pipe in whole lines, each write at most pipeBuf
+// so the pipe takes it whole or not at all. A full pipe is waited out, except
+// that with yield errFIFOFull is returned once the pipe has taken nothing for
+// SlowReader. It returns how much of data was written.
+func (w *FIFOWriter) send(ctx context.Context, data []byte, yield bool) (int, error) {
+	written := 0
+	var fullSince time.Time
+	for written < len(data) {
+		piece := data[written:min(written+pipeBuf, len(data))]
+		if end := bytes.LastIndexByte(piece, '\n'); end >= 0 {
+			piece = piece[:end+1]
+		} else if i := bytes.IndexByte(data[written:], '\n'); i >= 0 {
+			// A line longer than pipeBuf; the pipe may take part of it
+			piece = data[written : written+i+1]
+		}
+
+		n, err := writeFIFO(w.fd, piece)
+		written += n
+		switch {
+		case err == nil:
+			fullSince = time.Time{}
+		case errors.Is(err, errFIFOFull):
+			if fullSince.IsZero() {
+				fullSince = time.Now()
+			}
+			if yield && bytes.LastIndexByte(data[:written], '\n') == written-1 && time.Since(fullSince) >= w.opts.SlowReader {
+				return written, errFIFOFull
+			}
+			if !w.pause(ctx, fifoPoll) {
+				return written, ctx.Err()
+			}
+		default:
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// hold deals with records no reader took: they are spooled in buffer mode
+// while the spool has room and dropped otherwise
+func (w *FIFOWriter) hold(data []byte, n int) error {
+	if w.spool != nil && (w.opts.SpoolLimit == 0 || w.spoolLen+int64(len(data)) <= w.opts.SpoolLimit) {
+		if _, err := w.spool.WriteAt(data, w.spoolLen); err != nil {
+			return fmt.Errorf("failed to spool FIFO records: %w", err)
+		}
+		w.spoolLen += int64(len(data))
+		w.spooled.Add(int64(n))
+		return nil
+	}
+	w.dropped.Add(int64(n))
+	return nil
+}
+
+// pause sleeps for d and reports whether ctx is still live
+func (w *FIFOWriter) pause(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (w *FIFOWriter) detach() {
+	closeFIFO(w.fd)
+	w.fd = -1
+	w.logger.Info("FIFO reader detached", "path", w.opts.Path)
+}
+
+func (w *FIFOWriter) removeSpool() {
+	if w.spool != nil {
+		w.spool.Close()
+		os.Remove(w.spool.Name())
+	}
+}
+
+// CloseContext closes the pipe and removes the spool. Spooled records no
+// reader took by now are counted as dropped. Neither blocks, so ctx is not
+// needed.
+func (w *FIFOWriter) CloseContext(context.Context) error {
+	if n := w.spooled.Swap(0); n > 0 {
+		w.dropped.Add(n)
+		w.logger.Warn("FIFO records still spooled at close were dropped", "path", w.opts.Path, "records", n)
+	}
+	w.removeSpool()
+	if w.fd < 0 {
+		return nil
+	}
+	err := closeFIFO(w.fd)
+	w.fd = -1
+	return err
+}
+
+// Count returns the number of transactions written to a reader
+func (w *FIFOWriter) Count() int64 {
+	return w.count.Load()
+}
+
+// Dropped returns the number of transactions no reader took
+func (w *FIFOWriter) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Errors is always zero; transactions no reader took are counted by Dropped
+func (w *FIFOWriter) Errors() int64 {
+	return 0
+}
+
+// Spooled returns the number of transactions waiting in the spool
+func (w *FIFOWriter) Spooled() int64 {
+	return w.spooled.Load()
+}
@@ -0,0 +1,25 @@
+//go:build !unix
+
+package writer
+
+import "errors"
+
+const pipeBuf = 512
+
+var errFIFOUnsupported = errors.New("named pipes are not supported on this platform")
+
+func makeFIFO(path string) error {
+	return errFIFOUnsupported
+}
+
+func openFIFO(path string) (int, error) {
+	return -1, errFIFOUnsupported
+}
+
+func writeFIFO(fd int, data []byte) (int, error) {
+	return 0, errFIFOUnsupported
+}
+
+func closeFIFO(fd int) error {
+	return errFIFOUnsupported
+}
@@ -0,0 +1,215 @@
+//go:build unix
+
+package writer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func fifoInput(from, to int) <-chan *models.Transaction {
+	ch := make(chan *models.Transaction, to-from)
+	for i := from; i < to; i++ {
+		ch <- &models.Transaction{ID: fmt.Sprintf("TXN-%d", i), BetAmount: "1.000000"}
+	}
+	close(ch)
+	return ch
+}
+
+func newTestFIFO(t *testing.T, format, noReader string) *FIFOWriter {
+	t.Helper()
+	dir := t.TempDir()
+	w, err := NewFIFOWriter(FIFOOptions{
+		Path:       filepath.Join(dir, "transactions.pipe"),
+		Format:     format,
+		NoReader:   noReader,
+		SpoolDir:   dir,
+		BufferSize: 4096,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.CloseContext(context.Background()) })
+	return w
+}
+
+// attachReader opens the pipe for reading and returns its lines until
+// detach is called. Reads poll, as a FIFO reads as empty until the writer
+// first opens it.
+func attachReader(t *testing.T, path string) (detach func(), lines <-chan string) {
+	t.Helper()
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(chan string, 64)
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		var partial []byte
+		buf := make([]byte, 4096)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			n, _ := syscall.Read(fd, buf)
+			if n <= 0 {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			partial = append(partial, buf[:n]...)
+			for {
+				i := bytes.IndexByte(partial, '\n')
+				if i < 0 {
+					break
+				}
+				out <- string(partial[:i])
+				partial = partial[i+1:]
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+		syscall.Close(fd)
+	}, out
+}
+
+func readLines(t *testing.T, lines <-chan string, n int) []string {
+	t.Helper()
+	var got []string
+	for len(got) < n {
+		select {
+		case line := <-lines:
+			got = append(got, line)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("read %d of %d lines: %q", len(got), n, got)
+		}
+	}
+	return got
+}
+
+func TestFIFODropsWithoutReader(t *testing.T) {
+	w := newTestFIFO(t, "ndjson", FIFODrop)
+	if err := w.Write(context.Background(), fifoInput(0, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if w.Count() != 0 || w.Dropped() != 10 {
+		t.Errorf("count %d dropped %d, want 0 and 10", w.Count(), w.Dropped())
+	}
+}
+
+func TestFIFOBufferReplaysToReader(t *testing.T) {
+	w := newTestFIFO(t, "csv", FIFOBuffer)
+	if err := w.Write(context.Background(), fifoInput(0, 5)); err != nil {
+		t.Fatal(err)
+	}
+	if w.Spooled() != 5 {
+		t.Fatalf("spooled %d transactions, want 5", w.Spooled())
+	}
+
+	detach, lines := attachReader(t, w.opts.Path)
+	defer detach()
+	time.Sleep(fifoRetry) // the writer looks for a reader again after this
+	if err := w.Write(context.Background(), fifoInput(5, 8)); err != nil {
+		t.Fatal(err)
+	}
+
+	got := readLines(t, lines, 9)
+	if got[0] != strings.Join(transactionColumns, ",") {
+		t.Errorf("first line %q is not the CSV header", got[0])
+	}
+	for i, line := range got[1:] {
+		if want := fmt.Sprintf("TXN-%d,", i); !strings.HasPrefix(line, want) {
+			t.Errorf("line %d is %q, want it to start with %q", i+1, line, want)
+		}
+	}
+	if w.Count() != 8 || w.Spooled() != 0 || w.Dropped() != 0 {
+		t.Errorf("count %d spooled %d dropped %d, want 8, 0 and 0", w.Count(), w.Spooled(), w.Dropped())
+	}
+}
+
+func TestFIFOReaderDetaches(t *testing.T) {
+	w := newTestFIFO(t, "ndjson", FIFODrop)
+	detach, lines := attachReader(t, w.opts.Path)
+	if err := w.Write(context.Background(), fifoInput(0, 3)); err != nil {
+		t.Fatal(err)
+	}
+	readLines(t, lines, 3)
+	detach()
+
+	if err := w.Write(context.Background(), fifoInput(3, 6)); err != nil {
+		t.Fatal(err)
+	}
+	if w.Count() != 3 || w.Dropped() != 3 {
+		t.Errorf("count %d dropped %d, want 3 and 3", w.Count(), w.Dropped())
+	}
+}
+
+func TestFIFOWaitsForReader(t *testing.T) {
+	w := newTestFIFO(t, "ndjson", FIFOWait)
+	done := make(chan error, 1)
+	go func() { done <- w.Write(context.Background(), fifoInput(0, 4)) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("write returned without a reader: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	detach, lines := attachReader(t, w.opts.Path)
+	defer detach()
+	readLines(t, lines, 4)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFIFOSlowReaderDrops(t *testing.T) {
+	w := newTestFIFO(t, "ndjson", FIFODrop)
+	w.opts.SlowReader = 20 * time.Millisecond
+	w.opts.BufferSize = 64 * 1024
+	fd, err := syscall.Open(w.opts.Path, syscall.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fd)
+
+	// Nothing reads until the writer is done, so the pipe fills up
+	if err := w.Write(context.Background(), fifoInput(0, 2000)); err != nil {
+		t.Fatal(err)
+	}
+	if w.Dropped() == 0 || w.Count()+w.Dropped() != 2000 {
+		t.Fatalf("count %d dropped %d, want some dropped of 2000", w.Count(), w.Dropped())
+	}
+
+	var data []byte
+	buf := make([]byte, 64*1024)
+	for {
+		n, _ := syscall.Read(fd, buf)
+		if n <= 0 {
+			break
+		}
+		data = append(data, buf[:n]...)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if int64(len(lines)) != w.Count() {
+		t.Errorf("pipe holds %d lines, writer counted %d", len(lines), w.Count())
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, `{"id":`) || !strings.HasSuffix(line, "}") {
+			t.Fatalf("torn line %q", line)
+		}
+	}
+}
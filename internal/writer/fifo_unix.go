@@ -0,0 +1,72 @@
+//go:build unix
+
+package writer
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// pipeBuf is PIPE_BUF, the largest write a pipe takes whole or not at all.
+// POSIX guarantees 512 bytes; Linux has 4096.
+var pipeBuf = 512
+
+func init() {
+	if runtime.GOOS == "linux" {
+		pipeBuf = 4096
+	}
+}
+
+// makeFIFO creates a named pipe at path unless one is already there
+func makeFIFO(path string) error {
+	info, err := os.Stat(path)
+	if err == nil {
+		if info.Mode()&os.ModeNamedPipe == 0 {
+			return fmt.Errorf("%s exists and is not a named pipe", path)
+		}
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+	if err := syscall.Mkfifo(path, 0644); err != nil {
+		return fmt.Errorf("failed to create named pipe %s: %w", path, err)
+	}
+	return nil
+}
+
+// openFIFO opens path for writing without blocking. It returns -1 while no
+// reader has the pipe open.
+func openFIFO(path string) (int, error) {
+	fd, err := syscall.Open(path, syscall.O_WRONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err == syscall.ENXIO {
+		return -1, nil
+	}
+	return fd, err
+}
+
+// writeFIFO writes what the pipe takes of data, returning errFIFOFull when
+// the pipe buffer is full and errFIFOGone when the reader has detached
+func writeFIFO(fd int, data []byte) (int, error) {
+	for {
+		n, err := syscall.Write(fd, data)
+		switch err {
+		case nil:
+			return n, nil
+		case syscall.EINTR:
+			continue
+		case syscall.EAGAIN:
+			return 0, errFIFOFull
+		case syscall.EPIPE:
+			return 0, errFIFOGone
+		default:
+			return 0, err
+		}
+	}
+}
+
+func closeFIFO(fd int) error {
+	return syscall.Close(fd)
+}
@@ -0,0 +1,73 @@
+package writer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FilenameVars holds the values substituted into output filename templates.
+// Supported placeholders are {{date}} (2006-01-02), {{hour}} (15),
+// {{run_id}}, {{hostname}} and {{seq}}. Templates may contain subdirectories,
+// e.g. "{{date}}/transactions-{{hour}}.parquet".
+type FilenameVars struct {
+	Time     time.Time
+	RunID    string
+	Hostname string
+}
+
+// NewFilenameVars captures the run start time and host name. An empty runID
+// is replaced by a timestamp plus a random suffix.
+func NewFilenameVars(runID string) FilenameVars {
+	now := time.Now().UTC()
+	if runID == "" {
+		suffix := make([]byte, 3)
+		rand.Read(suffix)
+		runID = now.Format("20060102T150405") + "-" + hex.EncodeToString(suffix)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return FilenameVars{Time: now, RunID: runID, Hostname: hostname}
+}
+
+// Expand substitutes every placeholder except {{seq}}, which is resolved
+// per file by FileSeq or PartFilename
+func (v FilenameVars) Expand(template string) string {
+	if !strings.Contains(template, "{{") {
+		return template
+	}
+	return strings.NewReplacer(
+		"{{date}}", v.Time.Format("2006-01-02"),
+		"{{hour}}", v.Time.Format("15"),
+		"{{run_id}}", v.RunID,
+		"{{hostname}}", v.Hostname,
+	).Replace(template)
+}
+
+// FileSeq replaces {{seq}} with a zero-padded sequence number
+func FileSeq(filename string, seq int) string {
+	return strings.ReplaceAll(filename, "{{seq}}", fmt.Sprintf("%05d", seq))
+}
+
+// VersionFilename inserts .v<version> before the extension, so
+// transactions.csv becomes transactions.v2.csv
+func VersionFilename(filename string, version int) string {
+	ext := fileExt(filename)
+	return fmt.Sprintf("%s.v%d%s", strings.TrimSuffix(filename, ext), version, ext)
+}
+
+// fileExt returns the extension of filename, keeping a .gz suffix with the
+// one before it so transactions.jsonl.gz yields .jsonl.gz
+func fileExt(filename string) string {
+	ext := filepath.Ext(filename)
+	if ext == ".gz" {
+		ext = filepath.Ext(strings.TrimSuffix(filename, ext)) + ext
+	}
+	return ext
+}
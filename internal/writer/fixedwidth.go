@@ -0,0 +1,327 @@
+package writer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Fixed-width file encodings
+const (
+	EncodingASCII  = "ascii"  // 7-bit ASCII (default)
+	EncodingLatin1 = "latin1" // ISO 8859-1
+	EncodingEBCDIC = "ebcdic" // IBM code page 037
+)
+
+// Fixed-width overflow policies, for text columns longer than their width
+const (
+	OverflowTruncate     = "truncate"      // cut the end of the value (default)
+	OverflowTruncateLeft = "truncate_left" // cut the start, keeping e.g. the unique tail of an ID
+	OverflowError        = "error"         // fail the write
+)
+
+// Column alignments
+const (
+	AlignLeft  = "left"
+	AlignRight = "right"
+)
+
+// FixedWidthColumn lays out one field of a fixed-width record
+type FixedWidthColumn struct {
+	Name            string // transaction column, as in the CSV header
+	Width           int
+	Align           string // left or right; numbers default to right, text to left
+	Pad             rune   // 0 means space
+	ImpliedDecimals int    // amounts only: store 10.5 as 1050 with 2; 0 keeps the amount as generated
+	Overflow        string // text wider than Width, empty = the format's Overflow
+}
+
+// FixedWidthFormat controls how the fixed-width writer lays out its file
+type FixedWidthFormat struct {
+	Columns    []FixedWidthColumn
+	Encoding   string // ascii (default), latin1 or ebcdic
+	LineEnding string // lf (default), crlf or none
+	Overflow   string // truncate (default), truncate_left or error; columns may override it
+	Header     bool   // write the column names as a first record
+}
+
+// fixedWidthColumn is a FixedWidthColumn resolved against its field and
+// the format's overflow policy
+type fixedWidthColumn struct {
+	FixedWidthColumn
+	field columnField
+	right bool
+}
+
+func validOverflow(policy string) bool {
+	switch policy {
+	case "", OverflowTruncate, OverflowTruncateLeft, OverflowError:
+		return true
+	}
+	return false
+}
+
+// FixedWidthWriter writes transactions as fixed-width flat-file records in
+// an 8-bit encoding, for legacy settlement systems that read positional
+// layouts. Every record has the same byte length.
+type FixedWidthWriter struct {
+	file       *os.File
+	writer     *bufio.Writer
+	columns    []fixedWidthColumn
+	encode     func(r rune) byte
+	ending     string
+	record     []byte // reused encoding buffer for one record
+	bufferSize int
+	buffer     []*models.Transaction
+	count      atomic.Int64
+	logger     *slog.Logger
+}
+
+// fixedWidthEncoder maps a rune to its byte in the named encoding, '?' when
+// the encoding has no such character
+func fixedWidthEncoder(encoding string) (func(r rune) byte, error) {
+	var cm *charmap.Charmap
+	switch encoding {
+	case "", EncodingASCII:
+		return func(r rune) byte {
+			if r < utf8.RuneSelf {
+				return byte(r)
+			}
+			return '?'
+		}, nil
+	case EncodingLatin1:
+		cm = charmap.ISO8859_1
+	case EncodingEBCDIC:
+		cm = charmap.CodePage037
+	default:
+		return nil, fmt.Errorf("unsupported fixed-width encoding: %s", encoding)
+	}
+	unknown, _ := cm.EncodeRune('?')
+	return func(r rune) byte {
+		if b, ok := cm.EncodeRune(r); ok {
+			return b
+		}
+		return unknown
+	}, nil
+}
+
+// NewFixedWidthWriter creates a fixed-width writer with format
+func NewFixedWidthWriter(outputDir, filename string, bufferSize int, format FixedWidthFormat, logger *slog.Logger) (*FixedWidthWriter, error) {
+	if len(format.Columns) == 0 {
+		return nil, fmt.Errorf("fixed-width layout has no columns")
+	}
+	if !validOverflow(format.Overflow) {
+		return nil, fmt.Errorf("unsupported fixed-width overflow policy: %s", format.Overflow)
+	}
+	columns := make([]fixedWidthColumn, len(format.Columns))
+	recordLen := 0
+	for i, col := range format.Columns {
+		field, ok := columnFields[col.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown fixed-width column: %s", col.Name)
+		}
+		if col.Width <= 0 {
+			return nil, fmt.Errorf("fixed-width column %s: width must be positive", col.Name)
+		}
+		if col.ImpliedDecimals != 0 && !field.amount {
+			return nil, fmt.Errorf("fixed-width column %s: implied decimals apply to amounts only", col.Name)
+		}
+		if col.Pad == 0 {
+			col.Pad = ' '
+		}
+		if !validOverflow(col.Overflow) {
+			return nil, fmt.Errorf("fixed-width column %s: unsupported overflow policy %s", col.Name, col.Overflow)
+		}
+		if col.Overflow == "" {
+			col.Overflow = format.Overflow
+		}
+		var right bool
+		switch col.Align {
+		case "":
+			right = field.numeric
+		case AlignLeft:
+		case AlignRight:
+			right = true
+		default:
+			return nil, fmt.Errorf("fixed-width column %s: unsupported alignment %s", col.Name, col.Align)
+		}
+		columns[i] = fixedWidthColumn{FixedWidthColumn: col, field: field, right: right}
+		recordLen += col.Width
+	}
+
+	encode, err := fixedWidthEncoder(format.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	var ending string
+	switch format.LineEnding {
+	case "", LineEndingLF:
+		ending = "\n"
+	case LineEndingCRLF:
+		ending = "\r\n"
+	case LineEndingNone:
+	default:
+		return nil, fmt.Errorf("unsupported line ending: %s", format.LineEnding)
+	}
+	path := filepath.Join(outputDir, filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fixed-width file: %w", err)
+	}
+
+	bufferSize = max(bufferSize, 1)
+	w := &FixedWidthWriter{
+		file:       file,
+		writer:     bufio.NewWriterSize(retryWrites(file, logger), 64*1024),
+		columns:    columns,
+		encode:     encode,
+		ending:     ending,
+		record:     make([]byte, 0, recordLen+len(ending)),
+		bufferSize: bufferSize,
+		buffer:     make([]*models.Transaction, 0, bufferSize),
+		logger:     logger,
+	}
+	if format.Header {
+		// Column names are left-aligned with spaces, and cut to their widths
+		// rather than failing the layout
+		w.record = w.record[:0]
+		for _, col := range columns {
+			col.Pad, col.right, col.Overflow = ' ', false, OverflowTruncate
+			w.record = w.appendField(w.record, col, col.Name, false)
+		}
+		w.record = w.appendText(w.record, ending)
+		if _, err := w.writer.Write(w.record); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+	return w, nil
+}
+
+// Write writes transactions from the channel as fixed-width records
+func (w *FixedWidthWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return w.flush()
+		case txn, ok := <-input:
+			if !ok {
+				return w.flush()
+			}
+			w.buffer = append(w.buffer, txn)
+			if len(w.buffer) >= w.bufferSize {
+				if err := w.flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (w *FixedWidthWriter) flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	for _, txn := range w.buffer {
+		record, err := w.appendRecord(w.record[:0], txn)
+		if err != nil {
+			return fmt.Errorf("failed to lay out record %s: %w", txn.ID, err)
+		}
+		w.record = record
+		if _, err := w.writer.Write(w.record); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush fixed-width writer: %w", err)
+	}
+
+	w.count.Add(int64(len(w.buffer)))
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+// appendRecord appends the encoded record of txn, line ending included
+func (w *FixedWidthWriter) appendRecord(dst []byte, txn *models.Transaction) ([]byte, error) {
+	for _, col := range w.columns {
+		value := col.field.value(txn)
+		if col.ImpliedDecimals != 0 {
+			d, err := decimal.NewFromString(value)
+			if err != nil {
+				return dst, fmt.Errorf("column %s: invalid amount %q: %w", col.Name, value, err)
+			}
+			value = d.Shift(int32(col.ImpliedDecimals)).Round(0).String()
+		}
+		if n := utf8.RuneCountInString(value); n > col.Width && (col.field.numeric || col.Overflow == OverflowError) {
+			// A cut number would be a different number
+			return dst, fmt.Errorf("column %s: %q is %d characters, wider than %d", col.Name, value, n, col.Width)
+		}
+		dst = w.appendField(dst, col, value, col.field.numeric)
+	}
+	return w.appendText(dst, w.ending), nil
+}
+
+// appendField appends value padded or cut to the column width. Numbers
+// zero-padded on the left keep their sign in front, as in -0000105.
+func (w *FixedWidthWriter) appendField(dst []byte, col fixedWidthColumn, value string, numeric bool) []byte {
+	runes := utf8.RuneCountInString(value)
+	if runes >= col.Width {
+		r := []rune(value)
+		if col.Overflow == OverflowTruncateLeft {
+			return w.appendText(dst, string(r[len(r)-col.Width:]))
+		}
+		return w.appendText(dst, string(r[:col.Width]))
+	}
+	fill := w.encode(col.Pad)
+	if !col.right {
+		dst = w.appendText(dst, value)
+		for i := runes; i < col.Width; i++ {
+			dst = append(dst, fill)
+		}
+		return dst
+	}
+	if numeric && col.Pad == '0' && len(value) > 0 && (value[0] == '-' || value[0] == '+') {
+		dst = append(dst, w.encode(rune(value[0])))
+		value = value[1:]
+	}
+	for i := runes; i < col.Width; i++ {
+		dst = append(dst, fill)
+	}
+	return w.appendText(dst, value)
+}
+
+// appendText appends s in the writer's encoding
+func (w *FixedWidthWriter) appendText(dst []byte, s string) []byte {
+	for _, r := range s {
+		dst = append(dst, w.encode(r))
+	}
+	return dst
+}
+
+// Close flushes remaining records and closes the file
+func (w *FixedWidthWriter) Close() error {
+	if err := w.flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// Count returns the number of transactions written
+func (w *FixedWidthWriter) Count() int64 {
+	return w.count.Load()
+}
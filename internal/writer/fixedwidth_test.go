@@ -0,0 +1,133 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+var settlementLayout = []FixedWidthColumn{
+	{Name: "id", Width: 8},
+	{Name: "vendor_code", Width: 6},
+	{Name: "house_id", Width: 4, Pad: '0'},
+	{Name: "win_loss", Width: 8, Pad: '0', ImpliedDecimals: 2},
+}
+
+func writeFixedWidth(t *testing.T, format FixedWidthFormat, txns ...*models.Transaction) ([]byte, error) {
+	t.Helper()
+	dir := t.TempDir()
+	w, err := NewFixedWidthWriter(dir, "settlement.dat", 2, format, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := make(chan *models.Transaction, len(txns))
+	for _, txn := range txns {
+		input <- txn
+	}
+	close(input)
+	writeErr := w.Write(context.Background(), input)
+	closeErr := w.Close()
+	if writeErr == nil {
+		writeErr = closeErr
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "settlement.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, writeErr
+}
+
+func TestFixedWidthWriter(t *testing.T) {
+	data, err := writeFixedWidth(t, FixedWidthFormat{Columns: settlementLayout, LineEnding: LineEndingCRLF},
+		&models.Transaction{ID: "TXN-1", VendorCode: "ACME", HouseID: 7, WinLoss: "-10.5"},
+		&models.Transaction{ID: "TXN-2-LONG-ID", VendorCode: "Café", HouseID: 12, WinLoss: "3.25"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "TXN-1   ACME  0007-0001050\r\n" +
+		"TXN-2-LOCaf?  001200000325\r\n"
+	if string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+}
+
+func TestFixedWidthWriterEncodings(t *testing.T) {
+	txn := &models.Transaction{ID: "É1", VendorCode: "ACME", HouseID: 1, WinLoss: "0"}
+
+	data, err := writeFixedWidth(t, FixedWidthFormat{Columns: settlementLayout[:1], Encoding: EncodingLatin1, LineEnding: LineEndingNone}, txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte("\xc91      ")) {
+		t.Errorf("latin1 = %q", data)
+	}
+
+	data, err = writeFixedWidth(t, FixedWidthFormat{Columns: settlementLayout[1:2], Encoding: EncodingEBCDIC, LineEnding: LineEndingNone}, txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ACME and two spaces in code page 037
+	if !bytes.Equal(data, []byte{0xc1, 0xc3, 0xd4, 0xc5, 0x40, 0x40}) {
+		t.Errorf("ebcdic = % x", data)
+	}
+}
+
+func TestFixedWidthWriterHeader(t *testing.T) {
+	data, err := writeFixedWidth(t, FixedWidthFormat{Columns: settlementLayout, Header: true},
+		&models.Transaction{ID: "TXN-1", VendorCode: "ACME", HouseID: 7, WinLoss: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "id      vendorhouswin_loss\nTXN-1   ACME  000700000100\n"
+	if string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+}
+
+func TestFixedWidthWriterOverflow(t *testing.T) {
+	// Numbers never get cut
+	if _, err := writeFixedWidth(t, FixedWidthFormat{Columns: settlementLayout},
+		&models.Transaction{ID: "TXN-1", HouseID: 12345, WinLoss: "0"}); err == nil {
+		t.Error("house_id 12345 fit in 4 columns")
+	}
+	// Text does only when asked to
+	if _, err := writeFixedWidth(t, FixedWidthFormat{Columns: settlementLayout, Overflow: OverflowError},
+		&models.Transaction{ID: "TXN-2-LONG-ID", WinLoss: "0"}); err == nil {
+		t.Error("long id fit in 8 columns")
+	}
+
+	// Columns override the file's policy
+	columns := []FixedWidthColumn{
+		{Name: "id", Width: 8, Overflow: OverflowTruncateLeft},
+		{Name: "vendor_code", Width: 3, Overflow: OverflowTruncate},
+	}
+	data, err := writeFixedWidth(t, FixedWidthFormat{Columns: columns, Overflow: OverflowError, LineEnding: LineEndingNone},
+		&models.Transaction{ID: "TXN-2-LONG-ID", VendorCode: "ACME"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "-LONG-IDACM" {
+		t.Errorf("file = %q", data)
+	}
+}
+
+func TestFixedWidthWriterInvalidLayout(t *testing.T) {
+	layouts := [][]FixedWidthColumn{
+		nil,
+		{{Name: "nope", Width: 4}},
+		{{Name: "id", Width: 0}},
+		{{Name: "id", Width: 4, ImpliedDecimals: 2}},
+		{{Name: "id", Width: 4, Align: "center"}},
+		{{Name: "id", Width: 4, Overflow: "wrap"}},
+	}
+	for _, columns := range layouts {
+		if _, err := NewFixedWidthWriter(t.TempDir(), "settlement.dat", 1, FixedWidthFormat{Columns: columns}, nil); err == nil {
+			t.Errorf("layout %+v accepted", columns)
+		}
+	}
+}
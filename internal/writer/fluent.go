@@ -0,0 +1,128 @@
+package writer
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/supratick/message_producer/internal/codec"
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// FluentWriter sends transactions to a Fluentd/Fluent Bit aggregator using
+// the forward protocol in Forward mode: [tag, [[time, record], ...], option]
+type FluentWriter struct {
+	conn      net.Conn
+	tag       string
+	batchSize int
+	buffer    []*models.Transaction
+	encoded   []byte
+	timeout   time.Duration
+	count     atomic.Int64
+	logger    *slog.Logger
+}
+
+// NewFluentWriter creates a new Fluentd forward protocol writer
+func NewFluentWriter(address, tag string, batchSize int, timeout time.Duration, logger *slog.Logger) (*FluentWriter, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to fluentd at %s: %w", address, err)
+	}
+
+	return &FluentWriter{
+		conn:      conn,
+		tag:       tag,
+		batchSize: batchSize,
+		buffer:    make([]*models.Transaction, 0, batchSize),
+		timeout:   timeout,
+		logger:    logger,
+	}, nil
+}
+
+// Write writes transactions from the channel to fluentd
+func (w *FluentWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return w.flush()
+		case txn, ok := <-input:
+			if !ok {
+				// Channel closed, flush remaining buffer
+				return w.flush()
+			}
+
+			w.buffer = append(w.buffer, txn)
+			if len(w.buffer) >= w.batchSize {
+				if err := w.flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// WriteBatch sends batch, plus anything already buffered, as one forward
+// message
+func (w *FluentWriter) WriteBatch(ctx context.Context, batch []*models.Transaction) error {
+	w.buffer = append(w.buffer, batch...)
+	return w.flush()
+}
+
+func (w *FluentWriter) flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	buf := codec.AppendMsgpackArrayHeader(w.encoded[:0], 3)
+	buf = codec.AppendMsgpackString(buf, w.tag)
+	buf = codec.AppendMsgpackArrayHeader(buf, len(w.buffer))
+	for _, txn := range w.buffer {
+		buf = codec.AppendMsgpackArrayHeader(buf, 2)
+		buf = appendMsgpackEventTime(buf, now)
+		buf = txn.AppendMsgpack(buf)
+	}
+	buf = codec.AppendMsgpackMapHeader(buf, 1)
+	buf = codec.AppendMsgpackString(buf, "size")
+	buf = codec.AppendMsgpackInt(buf, int64(len(w.buffer)))
+	w.encoded = buf
+
+	if w.timeout > 0 {
+		w.conn.SetWriteDeadline(now.Add(w.timeout))
+	}
+	if _, err := w.conn.Write(buf); err != nil {
+		return fmt.Errorf("failed to write forward message: %w", err)
+	}
+
+	w.count.Add(int64(len(w.buffer)))
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+// CloseContext flushes remaining events and closes the connection. Once ctx is done
+// the connection is closed without waiting for the flush.
+func (w *FluentWriter) CloseContext(ctx context.Context) error {
+	return closeConnWithin(ctx, w.conn, w.flush)
+}
+
+// Count returns the number of transactions written
+func (w *FluentWriter) Count() int64 {
+	return w.count.Load()
+}
+
+// Errors is always zero: a failed flush stops the writer instead
+func (w *FluentWriter) Errors() int64 {
+	return 0
+}
+
+// appendMsgpackEventTime encodes t as the Fluentd EventTime extension
+// (fixext 8, type 0) carrying seconds and nanoseconds.
+func appendMsgpackEventTime(buf []byte, t time.Time) []byte {
+	buf = append(buf, 0xd7, 0x00)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(t.Unix()))
+	return binary.BigEndian.AppendUint32(buf, uint32(t.Nanosecond()))
+}
@@ -0,0 +1,72 @@
+package writer
+
+import "sync"
+
+// inFlightLimit caps the bytes of messages handed to a producer and not yet
+// acknowledged, so a slow broker holds sends back instead of growing the
+// producer's queues without bound
+type inFlightLimit struct {
+	limit   int64
+	mu      sync.Mutex
+	used    int64
+	waiting int
+	freed   chan struct{} // closed on a release while someone waits
+	waits   int64         // acquires that had to wait
+}
+
+func newInFlightLimit(limit int64) *inFlightLimit {
+	return &inFlightLimit{limit: limit, freed: make(chan struct{})}
+}
+
+// acquire takes n bytes, waiting while that would exceed the limit. A
+// message larger than the limit is let through on its own. It returns
+// false if done is closed first.
+func (l *inFlightLimit) acquire(done <-chan struct{}, n int64) bool {
+	l.mu.Lock()
+	if l.used > 0 && l.used+n > l.limit {
+		l.waits++
+	}
+	for l.used > 0 && l.used+n > l.limit {
+		l.waiting++
+		freed := l.freed
+		l.mu.Unlock()
+		select {
+		case <-freed:
+		case <-done:
+			l.mu.Lock()
+			l.waiting--
+			l.mu.Unlock()
+			return false
+		}
+		l.mu.Lock()
+		l.waiting--
+	}
+	l.used += n
+	l.mu.Unlock()
+	return true
+}
+
+// release returns n bytes once their message is acknowledged or failed
+func (l *inFlightLimit) release(n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.used -= n
+	if l.waiting > 0 {
+		close(l.freed)
+		l.freed = make(chan struct{})
+	}
+}
+
+// inUse returns the bytes in flight
+func (l *inFlightLimit) inUse() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.used
+}
+
+// waited returns how many acquires had to wait for the limit
+func (l *inFlightLimit) waited() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.waits
+}
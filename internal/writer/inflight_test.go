@@ -0,0 +1,45 @@
+package writer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInFlightLimit(t *testing.T) {
+	l := newInFlightLimit(100)
+	if !l.acquire(nil, 60) || !l.acquire(nil, 40) {
+		t.Fatal("acquire under the limit failed")
+	}
+
+	acquired := make(chan bool)
+	go func() { acquired <- l.acquire(nil, 30) }()
+	select {
+	case <-acquired:
+		t.Fatal("acquired past the limit")
+	case <-time.After(20 * time.Millisecond):
+	}
+	l.release(60)
+	if !<-acquired {
+		t.Fatal("acquire failed after release")
+	}
+	if l.inUse() != 70 || l.waited() != 1 {
+		t.Errorf("in use = %d, waited = %d", l.inUse(), l.waited())
+	}
+
+	// A cancelled wait gives up without taking anything
+	cancelled, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if l.acquire(cancelled.Done(), 50) {
+		t.Error("acquired past the limit after cancel")
+	}
+	if l.inUse() != 70 {
+		t.Errorf("in use = %d after a cancelled acquire", l.inUse())
+	}
+
+	// A message larger than the limit goes through once nothing is in flight
+	l.release(70)
+	if !l.acquire(nil, 500) {
+		t.Error("oversized message blocked with nothing in flight")
+	}
+}
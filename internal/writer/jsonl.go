@@ -0,0 +1,114 @@
+package writer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// JSONLWriter writes transactions as newline-delimited JSON, one object per
+// line, optionally gzip-compressed
+type JSONLWriter struct {
+	file       *os.File
+	gz         *gzip.Writer // nil when uncompressed
+	writer     *bufio.Writer
+	line       []byte // reused encoding buffer for one line
+	bufferSize int
+	buffer     []*models.Transaction
+	count      atomic.Int64
+	logger     *slog.Logger
+}
+
+// NewJSONLWriter creates a JSONL writer. With compress the file is a gzip
+// stream, complete once the writer is closed.
+func NewJSONLWriter(outputDir, filename string, bufferSize int, compress bool, logger *slog.Logger) (*JSONLWriter, error) {
+	path := filepath.Join(outputDir, filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JSONL file: %w", err)
+	}
+
+	w := &JSONLWriter{
+		file:       file,
+		line:       make([]byte, 0, 512),
+		bufferSize: max(bufferSize, 1),
+		logger:     logger,
+	}
+	out := retryWrites(file, logger)
+	if compress {
+		w.gz = gzip.NewWriter(out)
+		out = w.gz
+	}
+	w.writer = bufio.NewWriterSize(out, 64*1024)
+	w.buffer = make([]*models.Transaction, 0, w.bufferSize)
+	return w, nil
+}
+
+// Write writes transactions from the channel as JSON lines
+func (w *JSONLWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return w.flush()
+		case txn, ok := <-input:
+			if !ok {
+				return w.flush()
+			}
+			w.buffer = append(w.buffer, txn)
+			if len(w.buffer) >= w.bufferSize {
+				if err := w.flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (w *JSONLWriter) flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	for _, txn := range w.buffer {
+		w.line = append(txn.AppendJSON(w.line[:0]), '\n')
+		if _, err := w.writer.Write(w.line); err != nil {
+			return fmt.Errorf("failed to write JSONL record: %w", err)
+		}
+	}
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush JSONL writer: %w", err)
+	}
+
+	w.count.Add(int64(len(w.buffer)))
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+// Close flushes remaining lines, ends the gzip stream and closes the file
+func (w *JSONLWriter) Close() error {
+	err := w.flush()
+	if w.gz != nil {
+		if gzErr := w.gz.Close(); err == nil {
+			err = gzErr
+		}
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Count returns the number of transactions written
+func (w *JSONLWriter) Count() int64 {
+	return w.count.Load()
+}
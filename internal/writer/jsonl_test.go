@@ -0,0 +1,96 @@
+package writer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func writeJSONL(t *testing.T, compress bool, filename string) string {
+	t.Helper()
+	dir := t.TempDir()
+	w, err := NewJSONLWriter(dir, filename, 2, compress, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := make(chan *models.Transaction, 5)
+	for i := 0; i < 5; i++ {
+		input <- &models.Transaction{ID: fmt.Sprintf("TXN-%d", i), CurrencyCode: "USD", BetAmount: "10.000000"}
+	}
+	close(input)
+	if err := w.Write(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Count() != 5 {
+		t.Errorf("count = %d, want 5", w.Count())
+	}
+	return filepath.Join(dir, filename)
+}
+
+// readJSONL decodes every line of r, failing on anything that is not one
+// transaction object per line
+func readJSONL(t *testing.T, r io.Reader) []models.Transaction {
+	t.Helper()
+	var txns []models.Transaction
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var txn models.Transaction
+		if err := json.Unmarshal(scanner.Bytes(), &txn); err != nil {
+			t.Fatalf("line %d: %v", len(txns)+1, err)
+		}
+		txns = append(txns, txn)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return txns
+}
+
+func TestJSONLWriter(t *testing.T) {
+	f, err := os.Open(writeJSONL(t, false, "transactions.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	txns := readJSONL(t, f)
+	if len(txns) != 5 || txns[4].ID != "TXN-4" || txns[0].BetAmount != "10.000000" {
+		t.Errorf("lines = %+v", txns)
+	}
+}
+
+func TestJSONLWriterGzip(t *testing.T) {
+	f, err := os.Open(writeJSONL(t, true, "transactions.jsonl.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if txns := readJSONL(t, gz); len(txns) != 5 {
+		t.Errorf("read %d lines, want 5", len(txns))
+	}
+}
+
+func TestPartFilenameKeepsGzipExtension(t *testing.T) {
+	if got := PartFilename("transactions.jsonl.gz", 1); got != "transactions-part-00001.jsonl.gz" {
+		t.Errorf("PartFilename = %s", got)
+	}
+	if got := RotationFilename("transactions.csv", 2); got != "transactions-00002.csv" {
+		t.Errorf("RotationFilename = %s", got)
+	}
+}
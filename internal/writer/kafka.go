@@ -2,33 +2,161 @@ package writer
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/IBM/sarama"
+	"github.com/supratick/message_producer/internal/codec"
+	"github.com/supratick/message_producer/internal/encrypt"
 	"github.com/supratick/message_producer/internal/models"
 )
 
 // KafkaWriter writes transactions to Kafka
 type KafkaWriter struct {
+	client    sarama.Client // the producer's, closed after it
 	producer  sarama.AsyncProducer
 	topic     string
 	count     atomic.Int64
 	errors    atomic.Int64
 	isAsync   bool
+	format    codec.Format
+	envelope  *encrypt.Envelope
+	key       *KeyEncoder // nil = transaction ID
+	version   []byte      // SchemaVersionHeader value, nil = none
+	trace     []byte      // header carrying the trace ID
 	logger    *slog.Logger
+
+	inFlight *inFlightLimit // nil = unlimited
+
+	// Latency probes: every probeEvery-th message carries its send time
+	probeEvery int64
+	probeSeq   int64
+	probesSent atomic.Int64
+
+	// Cluster events: retries are always counted, metadata refreshes and
+	// leader changes once WatchCluster is called
+	retries       atomic.Int64
+	refreshes     atomic.Int64
+	refreshErrors atomic.Int64
+	leaderChanges atomic.Int64
+	watching      atomic.Bool
+	stopWatch     chan struct{}
+	watchDone     chan struct{}
+
+	// Failover, when set: undeliverable messages go to a spool that is
+	// forwarded once the brokers are back
+	failover      *KafkaFailover
+	down          atomic.Bool // spool new messages straight away
+	forwarded     atomic.Int64
+	stopForward   chan struct{}
+	forwardDone   chan struct{}
+	responsesDone chan struct{}
+}
+
+// ProbeHeader carries a probe message's send time as decimal Unix
+// nanoseconds, read back by the latency probe consumer
+const ProbeHeader = "probe-sent-at"
+
+// RateHeader carries the ID of the simulated currency rate a transaction
+// message references
+const RateHeader = "rate-id"
+
+// SubAgentHeader carries the ID of the sub-agent a transaction is attributed
+// to when commissions are simulated
+const SubAgentHeader = "sub-agent-id"
+
+// SchemaVersionHeader carries the configured payload schema version, so
+// consumers reading a shared topic can tell versions apart
+const SchemaVersionHeader = "schema-version"
+
+// TraceHeader carries a transaction's trace ID unless SetTraceHeader names
+// another header
+const TraceHeader = "trace-id"
+
+// Scripted player sessions carry the player and behaviour in these headers
+const (
+	PlayerHeader   = "player-id"
+	BehaviorHeader = "rg-behavior"
+)
+
+// KafkaAuth holds optional SASL/PLAIN credentials and TLS for the brokers
+type KafkaAuth struct {
+	Username string
+	Password string
+	TLS      bool
+}
+
+// Apply sets the SASL and TLS options on a sarama config
+func (a KafkaAuth) Apply(config *sarama.Config) {
+	if a.Username != "" {
+		config.Net.SASL.Enable = true
+		config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		config.Net.SASL.User = a.Username
+		config.Net.SASL.Password = a.Password
+	}
+	config.Net.TLS.Enable = a.TLS
+}
+
+// CheckKafka connects to the brokers and fetches the topic's metadata
+// without creating it, returning the partition count
+func CheckKafka(brokers []string, topic string, auth KafkaAuth, timeout time.Duration) (int, error) {
+	config := sarama.NewConfig()
+	config.Net.DialTimeout = timeout
+	config.Net.ReadTimeout = timeout
+	config.Net.WriteTimeout = timeout
+	config.Metadata.Retry.Max = 0
+	config.Metadata.AllowAutoTopicCreation = false
+	auth.Apply(config)
+
+	client, err := sarama.NewClient(brokers, config)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to kafka: %w", err)
+	}
+	defer client.Close()
+
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch metadata for topic %s: %w", topic, err)
+	}
+	return len(partitions), nil
+}
+
+// KafkaLimits bounds the memory a slow broker can make the producer hold.
+// Zero values keep the defaults.
+type KafkaLimits struct {
+	MaxOpenRequests   int   // unacknowledged requests per broker connection, default 5
+	ChannelBufferSize int   // messages queued in each of the producer's internal channels, default 10000
+	MaxInFlightBytes  int64 // messages handed to the producer and not yet acknowledged, default unlimited
+}
+
+// payloadBuffers recycles encoded payloads. A buffer rides along with its
+// message and returns here once the message is acknowledged or fails.
+var payloadBuffers = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 512)
+		return &b
+	},
 }
 
-// NewKafkaWriter creates a new Kafka writer
-func NewKafkaWriter(brokers []string, topic string, compression string, batchSize, flushFreq int, async bool, logger *slog.Logger) (*KafkaWriter, error) {
+// NewKafkaWriter creates a new Kafka writer encoding values with format. A
+// non-nil envelope encrypts every payload and tags it with the key ID header.
+// A compressionLevel of 0 keeps the codec's default; only gzip and zstd take
+// a level.
+func NewKafkaWriter(brokers []string, topic string, compression string, compressionLevel int, batchSize, flushFreq int, async bool, format codec.Format, auth KafkaAuth, limits KafkaLimits, envelope *encrypt.Envelope, logger *slog.Logger) (*KafkaWriter, error) {
 	config := sarama.NewConfig()
 	config.Producer.Return.Successes = true
 	config.Producer.Return.Errors = true
 	config.Producer.RequiredAcks = sarama.WaitForLocal
 	config.Producer.Retry.Max = 3
+
+	auth.Apply(config)
 	
 	// Set compression
 	switch compression {
@@ -43,6 +171,9 @@ func NewKafkaWriter(brokers []string, topic string, compression string, batchSiz
 	default:
 		config.Producer.Compression = sarama.CompressionNone
 	}
+	if compressionLevel > 0 {
+		config.Producer.CompressionLevel = compressionLevel
+	}
 	
 	// Batch settings for higher throughput
 	config.Producer.Flush.Messages = batchSize
@@ -51,17 +182,45 @@ func NewKafkaWriter(brokers []string, topic string, compression string, batchSiz
 	
 	// Channel buffer sizes
 	config.ChannelBufferSize = 10000
-	
-	producer, err := sarama.NewAsyncProducer(brokers, config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
+	if limits.ChannelBufferSize > 0 {
+		config.ChannelBufferSize = limits.ChannelBufferSize
 	}
-
+	if limits.MaxOpenRequests > 0 {
+		config.Net.MaxOpenRequests = limits.MaxOpenRequests
+	}
+	
 	kw := &KafkaWriter{
-		producer: producer,
 		topic:    topic,
 		isAsync:  async,
+		format:   format,
+		envelope: envelope,
+		trace:    []byte(TraceHeader),
 		logger:   logger,
+
+		responsesDone: make(chan struct{}),
+	}
+
+	// Count retries, keeping the fixed backoff
+	backoff := config.Producer.Retry.Backoff
+	config.Producer.Retry.BackoffFunc = func(retries, maxRetries int) time.Duration {
+		kw.retried(retries, maxRetries)
+		return backoff
+	}
+
+	// The producer runs on a client of our own, so WatchCluster sees and
+	// refreshes the metadata it routes by
+	client, err := sarama.NewClient(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
+	}
+	producer, err := sarama.NewAsyncProducerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
+	}
+	kw.client, kw.producer = client, producer
+	if limits.MaxInFlightBytes > 0 {
+		kw.inFlight = newInFlightLimit(limits.MaxInFlightBytes)
 	}
 
 	// Handle successes and errors in background
@@ -71,6 +230,7 @@ func NewKafkaWriter(brokers []string, topic string, compression string, batchSiz
 }
 
 func (w *KafkaWriter) handleResponses() {
+	defer close(w.responsesDone)
 	for {
 		select {
 		case success, ok := <-w.producer.Successes():
@@ -78,16 +238,27 @@ func (w *KafkaWriter) handleResponses() {
 				return
 			}
 			if success != nil {
+				w.releaseInFlight(success)
 				w.count.Add(1)
+				if _, ok := success.Metadata.(spooledMessage); ok {
+					w.forwarded.Add(1)
+				}
+				releaseBuffer(success)
 			}
 		case err, ok := <-w.producer.Errors():
 			if !ok {
 				return
 			}
 			if err != nil {
-				w.errors.Add(1)
-				// Log error but don't stop production
-				w.logger.Error("Kafka producer error", "error", err.Err, "msg_key", err.Msg.Key)
+				w.releaseInFlight(err.Msg)
+				if w.failover != nil {
+					w.spool(err.Msg, err.Err)
+				} else {
+					w.errors.Add(1)
+					// Log error but don't stop production
+					w.logger.Error("Kafka producer error", "error", err.Err, "msg_key", err.Msg.Key)
+				}
+				releaseBuffer(err.Msg)
 			}
 		}
 	}
@@ -105,35 +276,219 @@ func (w *KafkaWriter) Write(ctx context.Context, input <-chan *models.Transactio
 				return nil
 			}
 			
-			// Serialize transaction to JSON
-			data, err := json.Marshal(txn)
-			if err != nil {
-				w.errors.Add(1)
-				continue
-			}
+			// Serialize transaction in a pooled buffer
+			buf := payloadBuffers.Get().(*[]byte)
+			data := w.format.Append(txn, (*buf)[:0])
+			*buf = data
 			
-			// Create Kafka message
+			// Create Kafka message; the buffer rides along until acknowledged
 			msg := &sarama.ProducerMessage{
-				Topic: w.topic,
-				Key:   sarama.StringEncoder(txn.ID),
-				Value: sarama.ByteEncoder(data),
+				Topic:    w.topic,
+				Key:      sarama.StringEncoder(txn.ID),
+				Value:    sarama.ByteEncoder(data),
+				Metadata: buf,
+			}
+			if w.key != nil {
+				msg.Key = sarama.ByteEncoder(w.key.Append(nil, txn))
+			}
+			if w.envelope != nil {
+				sealed, err := w.envelope.Seal(data)
+				releaseBuffer(msg)
+				if err != nil {
+					w.errors.Add(1)
+					continue
+				}
+				msg.Value = sarama.ByteEncoder(sealed)
+				msg.Headers = []sarama.RecordHeader{
+					{Key: []byte(encrypt.HeaderAlgorithm), Value: []byte(encrypt.AlgorithmAESGCM)},
+					{Key: []byte(encrypt.HeaderKeyID), Value: []byte(w.envelope.KeyID())},
+				}
+			}
+			if w.format.Name != "json" {
+				// JSON stays header-free as before; other encodings say what they are
+				msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte("content-type"), Value: []byte(w.format.ContentType)})
 			}
 			
+			if w.version != nil {
+				msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(SchemaVersionHeader), Value: w.version})
+			}
+			if txn.RateID != 0 {
+				msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(RateHeader), Value: strconv.AppendInt(nil, int64(txn.RateID), 10)})
+			}
+			if txn.SubAgentID != 0 {
+				msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(SubAgentHeader), Value: strconv.AppendInt(nil, int64(txn.SubAgentID), 10)})
+			}
+			if txn.Region != "" {
+				msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(RegionHeader), Value: []byte(txn.Region)})
+			}
+			if txn.TraceID != "" {
+				msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: w.trace, Value: []byte(txn.TraceID)})
+			}
+			if txn.PlayerID != "" {
+				msg.Headers = append(msg.Headers,
+					sarama.RecordHeader{Key: []byte(PlayerHeader), Value: []byte(txn.PlayerID)},
+					sarama.RecordHeader{Key: []byte(BehaviorHeader), Value: []byte(txn.Behavior)},
+				)
+			}
+			// Custom dataset fields are headers named after the field
+			for _, name := range slices.Sorted(maps.Keys(txn.Fields)) {
+				msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(name), Value: []byte(txn.Fields[name])})
+			}
+
+			if w.probeEvery > 0 {
+				w.probeSeq++
+				if w.probeSeq%w.probeEvery == 0 {
+					sentAt := strconv.AppendInt(nil, time.Now().UnixNano(), 10)
+					msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(ProbeHeader), Value: sentAt})
+					w.probesSent.Add(1)
+				}
+			}
+			
+			// While the brokers are down, straight to the spool
+			if w.failover != nil && w.down.Load() {
+				w.spool(msg, nil)
+				releaseBuffer(msg)
+				continue
+			}
+
+			// Hold back while the in-flight limit is reached
+			if w.inFlight != nil && !w.inFlight.acquire(ctx.Done(), messageBytes(msg)) {
+				releaseBuffer(msg)
+				return nil
+			}
+
 			// Send to Kafka
 			select {
 			case w.producer.Input() <- msg:
 				// Message queued successfully
 			case <-ctx.Done():
+				w.releaseInFlight(msg)
+				releaseBuffer(msg)
 				return nil
 			}
 		}
 	}
 }
 
-// Close closes the Kafka writer
-func (w *KafkaWriter) Close() error {
-	// Close producer (this will flush pending messages)
-	return w.producer.Close()
+// messageBytes estimates what msg holds in memory, as sarama sizes records
+func messageBytes(msg *sarama.ProducerMessage) int64 {
+	return int64(msg.ByteSize(2))
+}
+
+// releaseInFlight returns msg's bytes to the in-flight limit once the
+// producer is done with it
+func (w *KafkaWriter) releaseInFlight(msg *sarama.ProducerMessage) {
+	if w.inFlight != nil {
+		w.inFlight.release(messageBytes(msg))
+	}
+}
+
+// InFlightBytes returns the bytes of messages awaiting acknowledgement,
+// zero without an in-flight limit
+func (w *KafkaWriter) InFlightBytes() int64 {
+	if w.inFlight == nil {
+		return 0
+	}
+	return w.inFlight.inUse()
+}
+
+// Throttled returns how many sends waited for the in-flight limit
+func (w *KafkaWriter) Throttled() int64 {
+	if w.inFlight == nil {
+		return 0
+	}
+	return w.inFlight.waited()
+}
+
+// releaseBuffer returns a message's pooled payload buffer once the producer no
+// longer needs it
+func releaseBuffer(msg *sarama.ProducerMessage) {
+	if buf, ok := msg.Metadata.(*[]byte); ok {
+		msg.Metadata = nil
+		payloadBuffers.Put(buf)
+	}
+}
+
+// SetProbe stamps every nth message with ProbeHeader; zero disables probes.
+// Call before Write.
+func (w *KafkaWriter) SetProbe(every int) {
+	w.probeEvery = int64(every)
+}
+
+// SetKey replaces the transaction ID key with keys built by enc. Call before
+// Write.
+func (w *KafkaWriter) SetKey(enc *KeyEncoder) {
+	w.key = enc
+}
+
+// SetSchemaVersion stamps every message with SchemaVersionHeader; zero
+// leaves messages unstamped. Call before Write.
+func (w *KafkaWriter) SetSchemaVersion(version int) {
+	w.version = nil
+	if version > 0 {
+		w.version = strconv.AppendInt(nil, int64(version), 10)
+	}
+}
+
+// SetTraceHeader carries trace IDs in the named header, e.g. correlation-id,
+// instead of TraceHeader. Call before Write.
+func (w *KafkaWriter) SetTraceHeader(name string) {
+	w.trace = []byte(name)
+}
+
+// ProbesSent returns the number of probe messages handed to the producer
+func (w *KafkaWriter) ProbesSent() int64 {
+	return w.probesSent.Load()
+}
+
+// CloseContext closes the producer, which flushes pending messages, and
+// then its client. Messages still unacknowledged when ctx is done are
+// abandoned.
+func (w *KafkaWriter) CloseContext(ctx context.Context) error {
+	closeProducer := w.producer.Close
+	if w.failover != nil {
+		closeProducer = w.closeFailover
+	}
+	return CloseWithin(ctx, func() error {
+		err := closeProducer()
+		w.stopWatching()
+		if closeErr := w.client.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	})
+}
+
+// closeFailover stops forwarding, closes the producer and spools the
+// messages still failing, leaving any backlog for the next run
+func (w *KafkaWriter) closeFailover() error {
+	w.stopForwarding()
+	err := w.producer.Close()
+	// Close collects the last failures itself
+	var failed sarama.ProducerErrors
+	if errors.As(err, &failed) {
+		for _, e := range failed {
+			w.releaseInFlight(e.Msg)
+			w.spool(e.Msg, e.Err)
+			releaseBuffer(e.Msg)
+		}
+		err = nil
+	}
+	<-w.responsesDone
+	if closeErr := w.failover.Spool.Close(); err == nil {
+		err = closeErr
+	}
+
+	spool := w.failover.Spool
+	w.logger.Info("Kafka failover",
+		"spooled", spool.Records(),
+		"forwarded", w.Forwarded(),
+		"dropped", spool.Dropped(),
+	)
+	if backlog := spool.Backlog(); backlog > 0 {
+		w.logger.Warn("Kafka spool backlog left for the next run", "spool", spool.dir, "backlog_bytes", backlog)
+	}
+	return err
 }
 
 // Count returns the number of transactions successfully written
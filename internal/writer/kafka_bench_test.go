@@ -0,0 +1,49 @@
+package writer
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/supratick/message_producer/internal/codec"
+)
+
+// BenchmarkKafkaJSONMarshal is the per-message json.Marshal the Kafka writer
+// used before the generated encoder, kept to compare against
+func BenchmarkKafkaJSONMarshal(b *testing.B) {
+	txns := benchTransactions(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, err := json.Marshal(txns[i%len(txns)])
+		if err != nil {
+			b.Fatal(err)
+		}
+		io.Discard.Write(data)
+	}
+}
+
+func BenchmarkKafkaJSONPooled(b *testing.B) {
+	benchmarkKafkaPayload(b, "json")
+}
+
+func BenchmarkKafkaAvroPooled(b *testing.B) {
+	benchmarkKafkaPayload(b, "avro")
+}
+
+// benchmarkKafkaPayload encodes payloads the way KafkaWriter.Write does
+func benchmarkKafkaPayload(b *testing.B, serialization string) {
+	format, err := codec.Lookup(serialization)
+	if err != nil {
+		b.Fatal(err)
+	}
+	txns := benchTransactions(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf := payloadBuffers.Get().(*[]byte)
+		*buf = format.Append(txns[i%len(txns)], (*buf)[:0])
+		io.Discard.Write(*buf)
+		payloadBuffers.Put(buf)
+	}
+}
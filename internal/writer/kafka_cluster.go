@@ -0,0 +1,128 @@
+package writer
+
+import (
+	"slices"
+	"time"
+)
+
+// noLeader stands for a partition without a leader in a leader snapshot
+const noLeader int32 = -1
+
+// LeaderChange is a partition moving from one leader broker to another;
+// either side is -1 while the partition has no leader
+type LeaderChange struct {
+	Partition int32
+	From, To  int32
+}
+
+// WatchCluster forces a refresh of the topic's metadata every interval on
+// the producer's client and logs the refreshes, partition leader changes
+// and producer retries, so throughput dips in chaos tests can be lined up
+// with cluster events. Call before Write.
+func (w *KafkaWriter) WatchCluster(interval time.Duration) {
+	w.watching.Store(true)
+	w.stopWatch = make(chan struct{})
+	w.watchDone = make(chan struct{})
+	go w.watchCluster(interval)
+}
+
+func (w *KafkaWriter) watchCluster(interval time.Duration) {
+	defer close(w.watchDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	leaders := w.leaders()
+	for {
+		select {
+		case <-w.stopWatch:
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		err := w.client.RefreshMetadata(w.topic)
+		took := time.Since(start)
+		w.refreshes.Add(1)
+		if err != nil {
+			w.refreshErrors.Add(1)
+			w.logger.Warn("Kafka metadata refresh failed", "topic", w.topic, "error", err, "took", took)
+			continue
+		}
+		w.logger.Debug("Kafka metadata refreshed", "topic", w.topic, "brokers", len(w.client.Brokers()), "took", took)
+
+		current := w.leaders()
+		for _, change := range leaderChanges(leaders, current) {
+			w.leaderChanges.Add(1)
+			w.logger.Warn("Kafka partition leader changed",
+				"topic", w.topic,
+				"partition", change.Partition,
+				"from", change.From,
+				"to", change.To,
+			)
+		}
+		leaders = current
+	}
+}
+
+// leaders snapshots the leader broker of every partition from the client's
+// cached metadata
+func (w *KafkaWriter) leaders() map[int32]int32 {
+	partitions, err := w.client.Partitions(w.topic)
+	if err != nil {
+		return nil
+	}
+	leaders := make(map[int32]int32, len(partitions))
+	for _, p := range partitions {
+		leaders[p] = noLeader
+		if broker, err := w.client.Leader(w.topic, p); err == nil {
+			leaders[p] = broker.ID()
+		}
+	}
+	return leaders
+}
+
+// leaderChanges diffs two leader snapshots by partition. Partitions new in
+// after are not changes; an empty before, from a failed snapshot, has
+// nothing to compare.
+func leaderChanges(before, after map[int32]int32) []LeaderChange {
+	var changes []LeaderChange
+	for p, to := range after {
+		if from, ok := before[p]; ok && from != to {
+			changes = append(changes, LeaderChange{Partition: p, From: from, To: to})
+		}
+	}
+	slices.SortFunc(changes, func(a, b LeaderChange) int { return int(a.Partition - b.Partition) })
+	return changes
+}
+
+// retried counts one partition's retry round and logs it while watching
+func (w *KafkaWriter) retried(retries, maxRetries int) {
+	w.retries.Add(1)
+	if w.watching.Load() {
+		w.logger.Warn("Kafka producer retrying", "attempt", retries, "max_retries", maxRetries)
+	}
+}
+
+// stopWatching stops WatchCluster, if started
+func (w *KafkaWriter) stopWatching() {
+	if w.stopWatch != nil {
+		close(w.stopWatch)
+		<-w.watchDone
+		w.stopWatch = nil
+	}
+}
+
+// Retries returns how often a partition's messages were retried
+func (w *KafkaWriter) Retries() int64 {
+	return w.retries.Load()
+}
+
+// MetadataRefreshes returns the forced metadata refreshes and how many of
+// them failed
+func (w *KafkaWriter) MetadataRefreshes() (total, failed int64) {
+	return w.refreshes.Load(), w.refreshErrors.Load()
+}
+
+// LeaderChanges returns the partition leader changes WatchCluster saw
+func (w *KafkaWriter) LeaderChanges() int64 {
+	return w.leaderChanges.Load()
+}
@@ -0,0 +1,20 @@
+package writer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLeaderChanges(t *testing.T) {
+	before := map[int32]int32{0: 1, 1: 2, 2: 3}
+	after := map[int32]int32{0: 1, 1: 3, 2: noLeader, 3: 2}
+
+	changes := leaderChanges(before, after)
+	want := []LeaderChange{{Partition: 1, From: 2, To: 3}, {Partition: 2, From: 3, To: noLeader}}
+	if fmt.Sprint(changes) != fmt.Sprint(want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+	if changes := leaderChanges(nil, after); len(changes) != 0 {
+		t.Errorf("changes from an empty snapshot = %v", changes)
+	}
+}
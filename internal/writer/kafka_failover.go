@@ -0,0 +1,158 @@
+package writer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// KafkaFailover spools messages the brokers do not take to local NDJSON
+// files and forwards them once the brokers are back, so a run survives a
+// broker outage without losing data
+type KafkaFailover struct {
+	Spool         *Spool
+	Probe         func() error  // checks the brokers are back
+	RetryInterval time.Duration // between probes while down, and between forwards
+}
+
+// spooledMessage marks a message forwarded from the spool
+type spooledMessage struct{}
+
+// SetFailover has undeliverable messages spooled instead of counted as
+// errors, and starts forwarding the spool, a backlog from an earlier run
+// included. Call before Write.
+func (w *KafkaWriter) SetFailover(failover KafkaFailover) {
+	w.failover = &failover
+	w.stopForward = make(chan struct{})
+	w.forwardDone = make(chan struct{})
+	go w.forward()
+}
+
+// spool stores msg for forwarding later. Once the first delivery failed,
+// new messages are spooled straight away until a probe finds the brokers
+// back.
+func (w *KafkaWriter) spool(msg *sarama.ProducerMessage, cause error) {
+	if !w.down.Swap(true) {
+		w.logger.Warn("Kafka unavailable, spooling messages", "error", cause, "spool", w.failover.Spool.dir)
+	}
+	rec := SpoolRecord{Headers: make([]SpoolHeader, len(msg.Headers))}
+	var err error
+	if msg.Key != nil {
+		rec.Key, err = msg.Key.Encode()
+	}
+	if err == nil && msg.Value != nil {
+		rec.Value, err = msg.Value.Encode()
+	}
+	for i, h := range msg.Headers {
+		rec.Headers[i] = SpoolHeader{Key: string(h.Key), Value: h.Value}
+	}
+	if err == nil {
+		err = w.failover.Spool.Append(rec)
+	}
+	if err != nil {
+		w.errors.Add(1)
+		w.logger.Error("Failed to spool Kafka message", "error", err)
+	}
+}
+
+// forward probes the brokers while they are down and replays sealed spool
+// segments while they are up. Messages that fail again return to the spool
+// through the error path, so forwarding is at least once.
+func (w *KafkaWriter) forward() {
+	defer close(w.forwardDone)
+	f := w.failover
+	ticker := time.NewTicker(f.RetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stopForward:
+			return
+		case <-ticker.C:
+		}
+
+		if w.down.Load() {
+			if err := f.Probe(); err != nil {
+				w.logger.Debug("Kafka still unavailable", "error", err)
+				continue
+			}
+			w.down.Store(false)
+			w.logger.Info("Kafka available again, forwarding spool", "backlog_bytes", f.Spool.Backlog())
+		}
+
+		if err := f.Spool.Seal(); err != nil {
+			w.logger.Error("Failed to seal Kafka spool", "error", err)
+			continue
+		}
+		segments, err := f.Spool.Sealed()
+		if err != nil {
+			w.logger.Error("Failed to list Kafka spool", "error", err)
+			continue
+		}
+		for _, path := range segments {
+			err := f.Spool.Replay(path, w.forwardRecord)
+			if errors.Is(err, errForwardStopped) {
+				return
+			}
+			if err != nil {
+				w.logger.Error("Failed to forward Kafka spool", "segment", path, "error", err)
+				break
+			}
+		}
+	}
+}
+
+// forwardRecord sends a spooled record, or spools it again if the brokers
+// went down meanwhile
+func (w *KafkaWriter) forwardRecord(rec SpoolRecord) error {
+	msg := &sarama.ProducerMessage{
+		Topic:    w.topic,
+		Value:    sarama.ByteEncoder(rec.Value),
+		Metadata: spooledMessage{},
+	}
+	if rec.Key != nil {
+		msg.Key = sarama.ByteEncoder(rec.Key)
+	}
+	for _, h := range rec.Headers {
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(h.Key), Value: h.Value})
+	}
+	if w.down.Load() {
+		if err := w.failover.Spool.Append(rec); err != nil {
+			w.errors.Add(1)
+			w.logger.Error("Failed to spool Kafka message", "error", err)
+		}
+		return nil
+	}
+	if w.inFlight != nil && !w.inFlight.acquire(w.stopForward, messageBytes(msg)) {
+		return errForwardStopped
+	}
+	select {
+	case w.producer.Input() <- msg:
+		return nil
+	case <-w.stopForward:
+		w.releaseInFlight(msg)
+		return errForwardStopped
+	}
+}
+
+// stopForwarding stops the forwarder before the producer closes
+func (w *KafkaWriter) stopForwarding() {
+	if w.failover == nil {
+		return
+	}
+	close(w.stopForward)
+	<-w.forwardDone
+}
+
+// Spooled returns the number of messages spooled
+func (w *KafkaWriter) Spooled() int64 {
+	if w.failover == nil {
+		return 0
+	}
+	return w.failover.Spool.Records()
+}
+
+// Forwarded returns the number of spooled messages delivered
+func (w *KafkaWriter) Forwarded() int64 {
+	return w.forwarded.Load()
+}
@@ -0,0 +1,130 @@
+package writer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/supratick/message_producer/internal/codec"
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Kafka key formats
+const (
+	KeyString = "string" // transaction ID as plain bytes (default)
+	KeyJSON   = "json"   // JSON object of the key fields
+	KeyAvro   = "avro"   // Avro record in the schema registry wire format
+)
+
+// DefaultKeyFields are the structured key columns used when none are given
+var DefaultKeyFields = []string{"agent_id", "round_id"}
+
+// keyField is one column available in a structured key
+type keyField struct {
+	avroType string // string or long
+	text     func(txn *models.Transaction) string
+	number   func(txn *models.Transaction) int64
+}
+
+var keyFields = map[string]keyField{
+	"id":              {avroType: "string", text: func(txn *models.Transaction) string { return txn.ID }},
+	"round_id":        {avroType: "string", text: func(txn *models.Transaction) string { return txn.RoundID }},
+	"vendor_code":     {avroType: "string", text: func(txn *models.Transaction) string { return txn.VendorCode }},
+	"currency_code":   {avroType: "string", text: func(txn *models.Transaction) string { return txn.CurrencyCode }},
+	"vendor_id":       {avroType: "long", number: func(txn *models.Transaction) int64 { return int64(txn.VendorID) }},
+	"house_id":        {avroType: "long", number: func(txn *models.Transaction) int64 { return int64(txn.HouseID) }},
+	"master_agent_id": {avroType: "long", number: func(txn *models.Transaction) int64 { return int64(txn.MasterAgentID) }},
+	"agent_id":        {avroType: "long", number: func(txn *models.Transaction) int64 { return int64(txn.AgentID) }},
+	"currency_id":     {avroType: "long", number: func(txn *models.Transaction) int64 { return int64(txn.CurrencyID) }},
+}
+
+// KeyEncoder builds message keys from transactions
+type KeyEncoder struct {
+	format   string
+	names    []string
+	fields   []keyField
+	schemaID uint32
+}
+
+// NewKeyEncoder returns an encoder for format. Structured formats use fields,
+// or DefaultKeyFields when empty; the string format takes no fields.
+func NewKeyEncoder(format string, fields []string) (*KeyEncoder, error) {
+	switch format {
+	case "", KeyString:
+		if len(fields) > 0 {
+			return nil, fmt.Errorf("key fields need a json or avro key format")
+		}
+		return &KeyEncoder{format: KeyString}, nil
+	case KeyJSON, KeyAvro:
+	default:
+		return nil, fmt.Errorf("unsupported key format: %s", format)
+	}
+	if len(fields) == 0 {
+		fields = DefaultKeyFields
+	}
+
+	e := &KeyEncoder{format: format, names: fields}
+	for _, name := range fields {
+		f, ok := keyFields[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported key field: %s", name)
+		}
+		e.fields = append(e.fields, f)
+	}
+	return e, nil
+}
+
+// Format returns the key format name
+func (e *KeyEncoder) Format() string {
+	return e.format
+}
+
+// AvroSchema returns the Avro record schema of the key fields
+func (e *KeyEncoder) AvroSchema() string {
+	fields := make([]string, len(e.names))
+	for i, name := range e.names {
+		fields[i] = fmt.Sprintf(`{"name":%q,"type":%q}`, name, e.fields[i].avroType)
+	}
+	return `{"type":"record","name":"TransactionKey","namespace":"message_producer","fields":[` + strings.Join(fields, ",") + `]}`
+}
+
+// SetSchemaID sets the registry ID written ahead of each Avro key
+func (e *KeyEncoder) SetSchemaID(id uint32) {
+	e.schemaID = id
+}
+
+// Append appends txn's encoded key to dst
+func (e *KeyEncoder) Append(dst []byte, txn *models.Transaction) []byte {
+	switch e.format {
+	case KeyJSON:
+		dst = append(dst, '{')
+		for i, f := range e.fields {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = codec.AppendJSONString(dst, e.names[i])
+			dst = append(dst, ':')
+			if f.text != nil {
+				dst = codec.AppendJSONString(dst, f.text(txn))
+			} else {
+				dst = strconv.AppendInt(dst, f.number(txn), 10)
+			}
+		}
+		return append(dst, '}')
+	case KeyAvro:
+		// Confluent wire format: magic byte, then the big-endian schema ID
+		dst = append(dst, 0)
+		dst = binary.BigEndian.AppendUint32(dst, e.schemaID)
+		for _, f := range e.fields {
+			if f.text != nil {
+				dst = codec.AppendAvroString(dst, f.text(txn))
+			} else {
+				dst = codec.AppendAvroLong(dst, f.number(txn))
+			}
+		}
+		return dst
+	default:
+		return append(dst, txn.ID...)
+	}
+}
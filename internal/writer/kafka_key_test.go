@@ -0,0 +1,134 @@
+package writer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+var keyTxn = &models.Transaction{ID: "txn-1", RoundID: "round-7", AgentID: 42, VendorCode: "PG"}
+
+func TestKeyEncoderString(t *testing.T) {
+	enc, err := NewKeyEncoder("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(enc.Append(nil, keyTxn)); got != "txn-1" {
+		t.Errorf("key = %q, want txn-1", got)
+	}
+	if _, err := NewKeyEncoder(KeyString, []string{"agent_id"}); err == nil {
+		t.Error("string key with fields accepted")
+	}
+}
+
+func TestKeyEncoderJSON(t *testing.T) {
+	enc, err := NewKeyEncoder(KeyJSON, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(enc.Append(nil, keyTxn))
+	if want := `{"agent_id":42,"round_id":"round-7"}`; got != want {
+		t.Errorf("key = %s, want %s", got, want)
+	}
+	if _, err := NewKeyEncoder(KeyJSON, []string{"bet_amount"}); err == nil {
+		t.Error("unsupported key field accepted")
+	}
+}
+
+func TestKeyEncoderAvro(t *testing.T) {
+	enc, err := NewKeyEncoder(KeyAvro, []string{"vendor_code", "agent_id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc.SetSchemaID(7)
+	got := enc.Append(nil, keyTxn)
+	// magic, schema ID 7, "PG" (len 2 zig-zag = 4), 42 zig-zag = 84
+	want := []byte{0, 0, 0, 0, 7, 4, 'P', 'G', 84}
+	if string(got) != string(want) {
+		t.Errorf("key = %v, want %v", got, want)
+	}
+
+	var schema struct {
+		Fields []struct{ Name, Type string }
+	}
+	if err := json.Unmarshal([]byte(enc.AvroSchema()), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if len(schema.Fields) != 2 || schema.Fields[0].Type != "string" || schema.Fields[1].Type != "long" {
+		t.Errorf("schema fields = %+v", schema.Fields)
+	}
+}
+
+func TestSchemaRegistryRegister(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/subjects/transactions-key/versions" {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct{ Schema string }
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Schema == "" {
+			http.Error(w, "bad schema", http.StatusUnprocessableEntity)
+			return
+		}
+		w.Write([]byte(`{"id":12}`))
+	}))
+	defer server.Close()
+
+	registry := NewSchemaRegistry(server.URL+"/", "", "", time.Second)
+	id, err := registry.Register(context.Background(), "transactions-key", `"string"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 12 {
+		t.Errorf("id = %d, want 12", id)
+	}
+	if _, err := registry.Register(context.Background(), "other", `"string"`); err == nil {
+		t.Error("registration error not returned")
+	}
+}
+
+func TestSchemaRegistryCheckCompatibility(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Schema, SchemaType string }
+		json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/compatibility/subjects/transactions-value/versions/latest":
+			if body.SchemaType != SchemaTypeJSON {
+				http.Error(w, "wrong schema type "+body.SchemaType, http.StatusUnprocessableEntity)
+				return
+			}
+			if body.Schema == "breaking" {
+				w.Write([]byte(`{"is_compatible":false,"messages":["property removed"]}`))
+				return
+			}
+			w.Write([]byte(`{"is_compatible":true}`))
+		case "/compatibility/subjects/new-value/versions/latest":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":40401,"message":"Subject 'new-value' not found."}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	registry := NewSchemaRegistry(server.URL, "", "", time.Second)
+	ctx := context.Background()
+	if ok, _, err := registry.CheckCompatibility(ctx, "transactions-value", SchemaTypeJSON, "{}"); err != nil || !ok {
+		t.Errorf("compatible schema: %v, %v", ok, err)
+	}
+	ok, messages, err := registry.CheckCompatibility(ctx, "transactions-value", SchemaTypeJSON, "breaking")
+	if err != nil || ok || len(messages) != 1 {
+		t.Errorf("breaking schema: %v, %v, %v", ok, messages, err)
+	}
+	if ok, _, err := registry.CheckCompatibility(ctx, "new-value", SchemaTypeAvro, `"string"`); err != nil || !ok {
+		t.Errorf("unknown subject: %v, %v", ok, err)
+	}
+	if _, _, err := registry.CheckCompatibility(ctx, "broken", SchemaTypeAvro, `"string"`); err == nil {
+		t.Error("registry error not returned")
+	}
+}
@@ -0,0 +1,96 @@
+package writer
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// TopicOffsets returns the next offset to be written on every partition of
+// topic, keyed by partition ID
+func TopicOffsets(brokers []string, topic string, auth KafkaAuth, timeout time.Duration) (map[int32]int64, error) {
+	config := sarama.NewConfig()
+	config.Net.DialTimeout = timeout
+	config.Net.ReadTimeout = timeout
+	config.Net.WriteTimeout = timeout
+	config.Metadata.AllowAutoTopicCreation = false
+	auth.Apply(config)
+
+	client, err := sarama.NewClient(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to kafka: %w", err)
+	}
+	defer client.Close()
+
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch metadata for topic %s: %w", topic, err)
+	}
+	offsets := make(map[int32]int64, len(partitions))
+	for _, p := range partitions {
+		offset, err := client.GetOffset(topic, p, sarama.OffsetNewest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch offset of %s/%d: %w", topic, p, err)
+		}
+		offsets[p] = offset
+	}
+	return offsets, nil
+}
+
+// PartitionDistribution is how many messages landed on each partition between
+// two offset snapshots
+type PartitionDistribution struct {
+	Counts map[int32]int64
+	Total  int64
+	Min    int64
+	Max    int64
+	// Skew is the busiest partition's count over the mean; 1 is perfectly even
+	Skew float64
+}
+
+// NewPartitionDistribution diffs two TopicOffsets snapshots. Partitions
+// missing from before, such as ones added during the run, count from zero.
+func NewPartitionDistribution(before, after map[int32]int64) PartitionDistribution {
+	d := PartitionDistribution{Counts: make(map[int32]int64, len(after))}
+	first := true
+	for p, offset := range after {
+		n := max(offset-before[p], 0)
+		d.Counts[p] = n
+		d.Total += n
+		if first || n < d.Min {
+			d.Min = n
+		}
+		if first || n > d.Max {
+			d.Max = n
+		}
+		first = false
+	}
+	if d.Total > 0 {
+		mean := float64(d.Total) / float64(len(d.Counts))
+		d.Skew = float64(d.Max) / mean
+	}
+	return d
+}
+
+// Attrs returns the distribution as slog key-value pairs, one per partition
+// in ID order after the totals
+func (d PartitionDistribution) Attrs() []any {
+	attrs := []any{
+		"partitions", len(d.Counts),
+		"total", d.Total,
+		"min", d.Min,
+		"max", d.Max,
+		"skew", fmt.Sprintf("%.3f", d.Skew),
+	}
+	ids := make([]int32, 0, len(d.Counts))
+	for p := range d.Counts {
+		ids = append(ids, p)
+	}
+	slices.Sort(ids)
+	for _, p := range ids {
+		attrs = append(attrs, fmt.Sprintf("partition_%d", p), d.Counts[p])
+	}
+	return attrs
+}
@@ -0,0 +1,29 @@
+package writer
+
+import "testing"
+
+func TestPartitionDistribution(t *testing.T) {
+	before := map[int32]int64{0: 100, 1: 50, 2: 0}
+	after := map[int32]int64{0: 160, 1: 70, 2: 40, 3: 80}
+
+	d := NewPartitionDistribution(before, after)
+	want := map[int32]int64{0: 60, 1: 20, 2: 40, 3: 80}
+	for p, n := range want {
+		if d.Counts[p] != n {
+			t.Errorf("partition %d = %d, want %d", p, d.Counts[p], n)
+		}
+	}
+	if d.Total != 200 || d.Min != 20 || d.Max != 80 {
+		t.Errorf("total/min/max = %d/%d/%d, want 200/20/80", d.Total, d.Min, d.Max)
+	}
+	if d.Skew != 1.6 {
+		t.Errorf("skew = %v, want 1.6", d.Skew)
+	}
+}
+
+func TestPartitionDistributionEmpty(t *testing.T) {
+	d := NewPartitionDistribution(map[int32]int64{0: 5}, map[int32]int64{0: 5, 1: 0})
+	if d.Total != 0 || d.Skew != 0 {
+		t.Errorf("total/skew = %d/%v, want 0/0", d.Total, d.Skew)
+	}
+}
@@ -0,0 +1,78 @@
+package writer
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/IBM/sarama"
+)
+
+// KafkaRecordWriter publishes side-stream records, such as simulated rates
+// and commission events, as JSON to their own topic
+type KafkaRecordWriter struct {
+	producer sarama.AsyncProducer
+	topic    string
+	sent     atomic.Int64
+	errors   atomic.Int64
+	done     chan struct{}
+	logger   *slog.Logger
+}
+
+// NewKafkaRecordWriter connects an asynchronous producer for topic.
+// Publishing never blocks generation on the broker.
+func NewKafkaRecordWriter(brokers []string, topic string, auth KafkaAuth, logger *slog.Logger) (*KafkaRecordWriter, error) {
+	config := sarama.NewConfig()
+	config.Producer.Return.Errors = true
+	config.Producer.RequiredAcks = sarama.WaitForLocal
+	config.Producer.Retry.Max = 3
+	auth.Apply(config)
+
+	producer, err := sarama.NewAsyncProducer(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka producer for %s: %w", topic, err)
+	}
+	w := &KafkaRecordWriter{producer: producer, topic: topic, done: make(chan struct{}), logger: logger}
+	go func() {
+		defer close(w.done)
+		for err := range producer.Errors() {
+			w.errors.Add(1)
+			logger.Error("Kafka record producer error", "topic", topic, "error", err.Err)
+		}
+	}()
+	return w, nil
+}
+
+// Publish queues record under key. Records sharing a key stay ordered on one
+// partition.
+func (w *KafkaRecordWriter) Publish(key string, record any) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		w.errors.Add(1)
+		return
+	}
+	w.producer.Input() <- &sarama.ProducerMessage{
+		Topic: w.topic,
+		Key:   sarama.StringEncoder(key),
+		Value: sarama.ByteEncoder(data),
+	}
+	w.sent.Add(1)
+}
+
+// Close flushes queued records and closes the producer
+func (w *KafkaRecordWriter) Close() error {
+	w.producer.AsyncClose()
+	<-w.done
+	return nil
+}
+
+// Count returns the number of records queued
+func (w *KafkaRecordWriter) Count() int64 {
+	return w.sent.Load()
+}
+
+// Errors returns the number of records that failed to send
+func (w *KafkaRecordWriter) Errors() int64 {
+	return w.errors.Load()
+}
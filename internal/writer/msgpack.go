@@ -0,0 +1,116 @@
+package writer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// MsgpackWriter writes transactions as a stream of MessagePack maps, one per
+// transaction with no framing in between, optionally gzip-compressed. The
+// maps carry the JSON field names, so the file decodes to the same records
+// as the JSONL output in less space.
+type MsgpackWriter struct {
+	file       *os.File
+	gz         *gzip.Writer // nil when uncompressed
+	writer     *bufio.Writer
+	encoded    []byte // reused encoding buffer for one record
+	bufferSize int
+	buffer     []*models.Transaction
+	count      atomic.Int64
+	logger     *slog.Logger
+}
+
+// NewMsgpackWriter creates a MessagePack writer. With compress the file is a gzip
+// stream, complete once the writer is closed.
+func NewMsgpackWriter(outputDir, filename string, bufferSize int, compress bool, logger *slog.Logger) (*MsgpackWriter, error) {
+	path := filepath.Join(outputDir, filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MessagePack file: %w", err)
+	}
+
+	w := &MsgpackWriter{
+		file:       file,
+		encoded:    make([]byte, 0, 512),
+		bufferSize: max(bufferSize, 1),
+		logger:     logger,
+	}
+	out := retryWrites(file, logger)
+	if compress {
+		w.gz = gzip.NewWriter(out)
+		out = w.gz
+	}
+	w.writer = bufio.NewWriterSize(out, 64*1024)
+	w.buffer = make([]*models.Transaction, 0, w.bufferSize)
+	return w, nil
+}
+
+// Write writes transactions from the channel as MessagePack maps
+func (w *MsgpackWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return w.flush()
+		case txn, ok := <-input:
+			if !ok {
+				return w.flush()
+			}
+			w.buffer = append(w.buffer, txn)
+			if len(w.buffer) >= w.bufferSize {
+				if err := w.flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (w *MsgpackWriter) flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	for _, txn := range w.buffer {
+		w.encoded = txn.AppendMsgpack(w.encoded[:0])
+		if _, err := w.writer.Write(w.encoded); err != nil {
+			return fmt.Errorf("failed to write MessagePack record: %w", err)
+		}
+	}
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush MessagePack writer: %w", err)
+	}
+
+	w.count.Add(int64(len(w.buffer)))
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+// Close flushes remaining records, ends the gzip stream and closes the file
+func (w *MsgpackWriter) Close() error {
+	err := w.flush()
+	if w.gz != nil {
+		if gzErr := w.gz.Close(); err == nil {
+			err = gzErr
+		}
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Count returns the number of transactions written
+func (w *MsgpackWriter) Count() int64 {
+	return w.count.Load()
+}
@@ -0,0 +1,61 @@
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func TestMsgpackWriter(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		dir := t.TempDir()
+		w, err := NewMsgpackWriter(dir, "transactions.msgpack", 2, compress, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		input := make(chan *models.Transaction, 5)
+		var want []byte
+		for i := 0; i < 5; i++ {
+			txn := &models.Transaction{ID: fmt.Sprintf("TXN-%d", i), HouseID: i * 100, BetAmount: "10.000000"}
+			want = txn.AppendMsgpack(want)
+			input <- txn
+		}
+		close(input)
+		if err := w.Write(context.Background(), input); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if w.Count() != 5 {
+			t.Errorf("gzip=%v: count = %d, want 5", compress, w.Count())
+		}
+
+		f, err := os.Open(filepath.Join(dir, "transactions.msgpack"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		var r io.Reader = f
+		if compress {
+			if r, err = gzip.NewReader(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Records follow each other with nothing in between
+		if !bytes.Equal(got, want) {
+			t.Errorf("gzip=%v: file holds %d bytes, want the %d of the records", compress, len(got), len(want))
+		}
+	}
+}
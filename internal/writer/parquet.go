@@ -1,71 +1,248 @@
 package writer
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"sync/atomic"
 
 	"github.com/parquet-go/parquet-go"
 	"github.com/parquet-go/parquet-go/compress"
+	"github.com/parquet-go/parquet-go/compress/gzip"
+	"github.com/parquet-go/parquet-go/compress/lz4"
+	"github.com/parquet-go/parquet-go/compress/zstd"
 	"github.com/supratick/message_producer/internal/models"
 )
 
+// ParquetTuning holds optional layout settings that affect downstream query
+// performance. The zero value keeps the library defaults.
+type ParquetTuning struct {
+	PageSize           int      // data page buffer size in bytes, 0 = 1MB
+	DictionaryColumns  []string // columns written with RLE dictionary encoding
+	BloomFilterColumns []string // columns with split-block bloom filters
+	BloomFilterBits    uint     // bits per value for bloom filters, 0 = 10
+	SortColumn         string   // column rows are sorted by within each row group
+	SortDescending     bool
+	PageStatistics     bool // also write min/max stats into each data page header
+	CompressionLevel   int  // gzip 1-9, zstd 1-19 or lz4 1-9; 0 = codec default
+}
+
+// parquetSortKeys compares transactions by a sortable column
+var parquetSortKeys = map[string]func(a, b *models.Transaction) int{
+	"id":               func(a, b *models.Transaction) int { return cmp.Compare(a.ID, b.ID) },
+	"round_id":         func(a, b *models.Transaction) int { return cmp.Compare(a.RoundID, b.RoundID) },
+	"vendor_id":        func(a, b *models.Transaction) int { return cmp.Compare(a.VendorID, b.VendorID) },
+	"vendor_code":      func(a, b *models.Transaction) int { return cmp.Compare(a.VendorCode, b.VendorCode) },
+	"game_category_id": func(a, b *models.Transaction) int { return cmp.Compare(a.GameCategoryID, b.GameCategoryID) },
+	"house_id":         func(a, b *models.Transaction) int { return cmp.Compare(a.HouseID, b.HouseID) },
+	"master_agent_id":  func(a, b *models.Transaction) int { return cmp.Compare(a.MasterAgentID, b.MasterAgentID) },
+	"agent_id":         func(a, b *models.Transaction) int { return cmp.Compare(a.AgentID, b.AgentID) },
+	"currency_id":      func(a, b *models.Transaction) int { return cmp.Compare(a.CurrencyID, b.CurrencyID) },
+	"currency_code":    func(a, b *models.Transaction) int { return cmp.Compare(a.CurrencyCode, b.CurrencyCode) },
+	"settled_at":       func(a, b *models.Transaction) int { return cmp.Compare(a.SettledAt, b.SettledAt) },
+}
+
+// IsParquetSortColumn reports whether rows can be sorted by the column
+func IsParquetSortColumn(column string) bool {
+	_, ok := parquetSortKeys[column]
+	return ok
+}
+
+// lz4Levels maps lz4 levels 1-9 to the high-compression encoder settings
+var lz4Levels = [...]lz4.Level{lz4.Level1, lz4.Level2, lz4.Level3, lz4.Level4, lz4.Level5, lz4.Level6, lz4.Level7, lz4.Level8, lz4.Level9}
+
+// parquetCodec returns the page codec for compression at level, 0 meaning
+// the codec's default
+func parquetCodec(compression string, level int) compress.Codec {
+	switch compression {
+	case "snappy":
+		return &parquet.Snappy
+	case "gzip":
+		if level > 0 {
+			return &gzip.Codec{Level: level}
+		}
+		return &parquet.Gzip
+	case "lz4":
+		if level > 0 && level <= len(lz4Levels) {
+			return &lz4.Codec{Level: lz4Levels[level-1]}
+		}
+		return &parquet.Lz4Raw
+	case "zstd":
+		if level > 0 {
+			return &zstd.Codec{Level: zstdLevel(level)}
+		}
+		return &parquet.Zstd
+	default:
+		return &parquet.Uncompressed
+	}
+}
+
+// zstdLevel maps a zstd level to the nearest encoder speed, as the reference
+// library's levels 1-2, 3-5, 6-9 and 10 and up
+func zstdLevel(level int) zstd.Level {
+	switch {
+	case level < 3:
+		return zstd.SpeedFastest
+	case level < 6:
+		return zstd.SpeedDefault
+	case level < 10:
+		return zstd.SpeedBetterCompression
+	default:
+		return zstd.SpeedBestCompression
+	}
+}
+
 // ParquetWriter writes transactions to Parquet file
 type ParquetWriter struct {
 	file         *os.File
 	writer       *parquet.GenericWriter[*models.Transaction]
 	rowGroupSize int
 	buffer       []*models.Transaction
+	sortKey      func(a, b *models.Transaction) int
 	count        atomic.Int64
 	logger       *slog.Logger
 }
 
 // NewParquetWriter creates a new Parquet writer
-func NewParquetWriter(outputDir, filename string, rowGroupSize int, compression string, logger *slog.Logger) (*ParquetWriter, error) {
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
+func NewParquetWriter(outputDir, filename string, rowGroupSize int, compression string, tuning ParquetTuning, logger *slog.Logger) (*ParquetWriter, error) {
+	path := filepath.Join(outputDir, filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	path := filepath.Join(outputDir, filename)
 	file, err := os.Create(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Parquet file: %w", err)
 	}
 
 	// Configure compression
-	var compressionCodec compress.Codec
-	switch compression {
-	case "snappy":
-		compressionCodec = &parquet.Snappy
-	case "gzip":
-		compressionCodec = &parquet.Gzip
-	case "lz4":
-		compressionCodec = &parquet.Lz4Raw
-	case "zstd":
-		compressionCodec = &parquet.Zstd
-	default:
-		compressionCodec = &parquet.Uncompressed
+	compressionCodec := parquetCodec(compression, tuning.CompressionLevel)
+
+	schema, err := parquetSchema(tuning.DictionaryColumns)
+	if err != nil {
+		file.Close()
+		return nil, err
 	}
 
-	// Create writer with schema
-	writer := parquet.NewGenericWriter[*models.Transaction](
-		file,
+	pageSize := tuning.PageSize
+	if pageSize <= 0 {
+		pageSize = 1024 * 1024 // 1MB page buffer
+	}
+
+	options := []parquet.WriterOption{
+		schema,
 		parquet.Compression(compressionCodec),
-		parquet.PageBufferSize(1024*1024), // 1MB page buffer
-	)
+		parquet.PageBufferSize(pageSize),
+		parquet.DataPageStatistics(tuning.PageStatistics),
+	}
+
+	if len(tuning.BloomFilterColumns) > 0 {
+		bits := tuning.BloomFilterBits
+		if bits == 0 {
+			bits = 10
+		}
+		filters := make([]parquet.BloomFilterColumn, 0, len(tuning.BloomFilterColumns))
+		for _, column := range tuning.BloomFilterColumns {
+			if _, ok := schema.Lookup(column); !ok {
+				file.Close()
+				return nil, fmt.Errorf("unknown bloom filter column: %s", column)
+			}
+			filters = append(filters, parquet.SplitBlockFilter(bits, column))
+		}
+		options = append(options, parquet.BloomFilters(filters...))
+	}
+
+	var sortKey func(a, b *models.Transaction) int
+	if tuning.SortColumn != "" {
+		compare, ok := parquetSortKeys[tuning.SortColumn]
+		if !ok {
+			file.Close()
+			return nil, fmt.Errorf("unsupported sort column: %s", tuning.SortColumn)
+		}
+		sortKey = compare
+		sorting := parquet.Ascending(tuning.SortColumn)
+		if tuning.SortDescending {
+			sortKey = func(a, b *models.Transaction) int { return compare(b, a) }
+			sorting = parquet.Descending(tuning.SortColumn)
+		}
+		// Record the order in row group metadata so engines can rely on it
+		options = append(options, parquet.SortingWriterConfig(parquet.SortingColumns(sorting)))
+	}
+
+	// Create writer with schema
+	writer := parquet.NewGenericWriter[*models.Transaction](retryWrites(file, logger), options...)
 
 	return &ParquetWriter{
 		file:         file,
 		writer:       writer,
 		rowGroupSize: rowGroupSize,
 		buffer:       make([]*models.Transaction, 0, rowGroupSize),
+		sortKey:      sortKey,
 		logger:       logger,
 	}, nil
 }
 
+// ParquetSchema returns the message schema of the Parquet files written with
+// dictionaryColumns, in Parquet's textual schema notation
+func ParquetSchema(dictionaryColumns []string) (string, error) {
+	schema, err := parquetSchema(dictionaryColumns)
+	if err != nil {
+		return "", err
+	}
+	return schema.String(), nil
+}
+
+// parquetSchema derives the schema from the Transaction struct in declared
+// field order, with integer columns stored as INT32 and the requested
+// columns switched to RLE dictionary encoding
+func parquetSchema(dictionaryColumns []string) (*parquet.Schema, error) {
+	base := parquet.SchemaOf(new(models.Transaction))
+	fields := make([]parquet.Field, len(base.Fields()))
+	index := make(map[string]int, len(fields))
+	for i, field := range base.Fields() {
+		node := parquet.Node(field)
+		if field.Type().Kind() == parquet.Int64 {
+			node = parquet.Int(32)
+		}
+		fields[i] = parquetField{Node: node, field: field}
+		index[field.Name()] = i
+	}
+	for _, column := range dictionaryColumns {
+		i, ok := index[column]
+		if !ok {
+			return nil, fmt.Errorf("unknown dictionary column: %s", column)
+		}
+		f := fields[i].(parquetField)
+		f.Node = parquet.Encoded(f.Node, &parquet.RLEDictionary)
+		fields[i] = f
+	}
+	return parquet.NewSchema(base.Name(), parquetStruct{Node: base, fields: fields}), nil
+}
+
+// parquetStruct is a struct node with its fields replaced. parquet.Group
+// would sort them by name, reordering the file's columns.
+type parquetStruct struct {
+	parquet.Node
+	fields []parquet.Field
+}
+
+func (s parquetStruct) Fields() []parquet.Field { return s.fields }
+
+// parquetField stores a Transaction field with node's type and encoding
+type parquetField struct {
+	parquet.Node
+	field parquet.Field
+}
+
+func (f parquetField) Name() string { return f.field.Name() }
+
+func (f parquetField) Value(base reflect.Value) reflect.Value { return f.field.Value(base) }
+
 // Write writes transactions from the channel to Parquet
 func (w *ParquetWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
 	for {
@@ -93,11 +270,20 @@ func (w *ParquetWriter) flush() error {
 		return nil
 	}
 
+	if w.sortKey != nil {
+		slices.SortStableFunc(w.buffer, w.sortKey)
+	}
+
 	n, err := w.writer.Write(w.buffer)
 	if err != nil {
 		return fmt.Errorf("failed to write to Parquet: %w", err)
 	}
 
+	// Each flush becomes its own row group so sorting and row_group_size hold
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush Parquet row group: %w", err)
+	}
+
 	w.count.Add(int64(n))
 	w.buffer = w.buffer[:0]
 	return nil
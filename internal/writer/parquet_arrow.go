@@ -0,0 +1,254 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// arrowTransactionSchema mirrors the Transaction struct in column order,
+// integer columns as INT32 like the parquet-go engine
+var arrowTransactionSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.BinaryTypes.String},
+	{Name: "external_transaction_id", Type: arrow.BinaryTypes.String},
+	{Name: "vendor_bet_id", Type: arrow.BinaryTypes.String},
+	{Name: "round_id", Type: arrow.BinaryTypes.String},
+	{Name: "vendor_id", Type: arrow.PrimitiveTypes.Int32},
+	{Name: "vendor_code", Type: arrow.BinaryTypes.String},
+	{Name: "vendor_line_id", Type: arrow.PrimitiveTypes.Int32},
+	{Name: "game_category_id", Type: arrow.PrimitiveTypes.Int32},
+	{Name: "house_id", Type: arrow.PrimitiveTypes.Int32},
+	{Name: "master_agent_id", Type: arrow.PrimitiveTypes.Int32},
+	{Name: "agent_id", Type: arrow.PrimitiveTypes.Int32},
+	{Name: "currency_id", Type: arrow.PrimitiveTypes.Int32},
+	{Name: "currency_code", Type: arrow.BinaryTypes.String},
+	{Name: "bet_amount", Type: arrow.BinaryTypes.String},
+	{Name: "win_amount", Type: arrow.BinaryTypes.String},
+	{Name: "win_loss", Type: arrow.BinaryTypes.String},
+	{Name: "settled_at", Type: arrow.BinaryTypes.String},
+}, nil)
+
+// ArrowParquetWriter writes transactions to Parquet by building Arrow record
+// batches column by column. It skips the per-row reflection done by
+// parquet-go's GenericWriter and is selected with engine: arrow.
+type ArrowParquetWriter struct {
+	writer       *pqarrow.FileWriter
+	builder      *array.RecordBuilder
+	rowGroupSize int
+	buffer       []*models.Transaction
+	sortKey      func(a, b *models.Transaction) int
+	count        atomic.Int64
+	logger       *slog.Logger
+}
+
+// NewArrowParquetWriter creates a new Arrow-backed Parquet writer
+func NewArrowParquetWriter(outputDir, filename string, rowGroupSize int, compression string, tuning ParquetTuning, logger *slog.Logger) (*ArrowParquetWriter, error) {
+	if len(tuning.BloomFilterColumns) > 0 {
+		return nil, fmt.Errorf("bloom filters are not supported by the arrow parquet engine")
+	}
+
+	var codec compress.Compression
+	switch compression {
+	case "snappy":
+		codec = compress.Codecs.Snappy
+	case "gzip":
+		codec = compress.Codecs.Gzip
+	case "lz4":
+		codec = compress.Codecs.Lz4Raw
+	case "zstd":
+		codec = compress.Codecs.Zstd
+	case "none":
+		codec = compress.Codecs.Uncompressed
+	default:
+		codec = compress.Codecs.Snappy
+	}
+
+	pageSize := int64(tuning.PageSize)
+	if pageSize <= 0 {
+		pageSize = 1024 * 1024 // 1MB page buffer
+	}
+
+	props := []parquet.WriterProperty{
+		parquet.WithCompression(codec),
+		parquet.WithDataPageSize(pageSize),
+		parquet.WithMaxRowGroupLength(int64(rowGroupSize)),
+		parquet.WithStats(true),
+		// Match the parquet-go path: plain encoding unless requested
+		parquet.WithDictionaryDefault(false),
+	}
+	if tuning.CompressionLevel > 0 {
+		props = append(props, parquet.WithCompressionLevel(tuning.CompressionLevel))
+	}
+	for _, column := range tuning.DictionaryColumns {
+		if arrowTransactionSchema.FieldIndices(column) == nil {
+			return nil, fmt.Errorf("unknown dictionary column: %s", column)
+		}
+		props = append(props, parquet.WithDictionaryFor(column, true))
+	}
+
+	var sortKey func(a, b *models.Transaction) int
+	if tuning.SortColumn != "" {
+		compare, ok := parquetSortKeys[tuning.SortColumn]
+		if !ok {
+			return nil, fmt.Errorf("unsupported sort column: %s", tuning.SortColumn)
+		}
+		sortKey = compare
+		if tuning.SortDescending {
+			sortKey = func(a, b *models.Transaction) int { return compare(b, a) }
+		}
+		// Record the order in row group metadata so engines can rely on it
+		props = append(props, parquet.WithSortingColumns([]parquet.SortingColumn{{
+			ColumnIdx:  int32(arrowTransactionSchema.FieldIndices(tuning.SortColumn)[0]),
+			Descending: tuning.SortDescending,
+		}}))
+	}
+
+	path := filepath.Join(outputDir, filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Parquet file: %w", err)
+	}
+
+	writer, err := pqarrow.NewFileWriter(arrowTransactionSchema, retryWrites(file, logger),
+		parquet.NewWriterProperties(props...), pqarrow.DefaultWriterProps())
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to create Arrow Parquet writer: %w", err)
+	}
+
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, arrowTransactionSchema)
+
+	return &ArrowParquetWriter{
+		writer:       writer,
+		builder:      builder,
+		rowGroupSize: rowGroupSize,
+		buffer:       make([]*models.Transaction, 0, rowGroupSize),
+		sortKey:      sortKey,
+		logger:       logger,
+	}, nil
+}
+
+// Write writes transactions from the channel to Parquet
+func (w *ArrowParquetWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return w.flush()
+		case txn, ok := <-input:
+			if !ok {
+				// Channel closed, flush remaining buffer
+				return w.flush()
+			}
+
+			w.buffer = append(w.buffer, txn)
+			if len(w.buffer) >= w.rowGroupSize {
+				if err := w.flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// appendArrowColumns fills each column builder of b in one pass over buffer
+func appendArrowColumns(b *array.RecordBuilder, buffer []*models.Transaction) {
+	stringColumn := func(i int, value func(txn *models.Transaction) string) {
+		sb := b.Field(i).(*array.StringBuilder)
+		size := 0
+		for _, txn := range buffer {
+			size += len(value(txn))
+		}
+		// Size the value buffer up front instead of growing it per append
+		sb.Reserve(len(buffer))
+		sb.ReserveData(size)
+		for _, txn := range buffer {
+			sb.Append(value(txn))
+		}
+	}
+	intColumn := func(i int, value func(txn *models.Transaction) int) {
+		ib := b.Field(i).(*array.Int32Builder)
+		ib.Reserve(len(buffer))
+		for _, txn := range buffer {
+			ib.Append(int32(value(txn)))
+		}
+	}
+
+	stringColumn(0, func(txn *models.Transaction) string { return txn.ID })
+	stringColumn(1, func(txn *models.Transaction) string { return txn.ExternalTransactionID })
+	stringColumn(2, func(txn *models.Transaction) string { return txn.VendorBetID })
+	stringColumn(3, func(txn *models.Transaction) string { return txn.RoundID })
+	intColumn(4, func(txn *models.Transaction) int { return txn.VendorID })
+	stringColumn(5, func(txn *models.Transaction) string { return txn.VendorCode })
+	intColumn(6, func(txn *models.Transaction) int { return txn.VendorLineID })
+	intColumn(7, func(txn *models.Transaction) int { return txn.GameCategoryID })
+	intColumn(8, func(txn *models.Transaction) int { return txn.HouseID })
+	intColumn(9, func(txn *models.Transaction) int { return txn.MasterAgentID })
+	intColumn(10, func(txn *models.Transaction) int { return txn.AgentID })
+	intColumn(11, func(txn *models.Transaction) int { return txn.CurrencyID })
+	stringColumn(12, func(txn *models.Transaction) string { return txn.CurrencyCode })
+	stringColumn(13, func(txn *models.Transaction) string { return txn.BetAmount })
+	stringColumn(14, func(txn *models.Transaction) string { return txn.WinAmount })
+	stringColumn(15, func(txn *models.Transaction) string { return txn.WinLoss })
+	stringColumn(16, func(txn *models.Transaction) string { return txn.SettledAt })
+}
+
+func (w *ArrowParquetWriter) flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	if w.sortKey != nil {
+		slices.SortStableFunc(w.buffer, w.sortKey)
+	}
+
+	appendArrowColumns(w.builder, w.buffer)
+	record := w.builder.NewRecord()
+	defer record.Release()
+
+	// Each record becomes its own row group
+	if err := w.writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write to Parquet: %w", err)
+	}
+
+	w.count.Add(int64(len(w.buffer)))
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+// Close closes the Parquet writer
+func (w *ArrowParquetWriter) Close() error {
+	defer w.builder.Release()
+
+	if err := w.flush(); err != nil {
+		w.writer.Close()
+		return err
+	}
+
+	// Closing the pqarrow writer writes the footer and closes the file
+	if err := w.writer.Close(); err != nil {
+		return fmt.Errorf("failed to close Parquet writer: %w", err)
+	}
+	return nil
+}
+
+// Count returns the number of transactions written
+func (w *ArrowParquetWriter) Count() int64 {
+	return w.count.Load()
+}
@@ -0,0 +1,83 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func benchTransactions(n int) []*models.Transaction {
+	txns := make([]*models.Transaction, n)
+	for i := range txns {
+		txns[i] = &models.Transaction{
+			ID:                    fmt.Sprintf("TXN-20240101-%08d", i),
+			ExternalTransactionID: fmt.Sprintf("EXT-PRAGMATIC-%08d", i),
+			VendorBetID:           fmt.Sprintf("BET-%08d", i),
+			RoundID:               fmt.Sprintf("ROUND-%08d", i/10),
+			VendorID:              i%10 + 1,
+			VendorCode:            "PRAGMATIC",
+			VendorLineID:          1,
+			GameCategoryID:        i%6 + 1,
+			HouseID:               1,
+			MasterAgentID:         i%36 + 1,
+			AgentID:               i%54 + 1,
+			CurrencyID:            i%8 + 1,
+			CurrencyCode:          "USDT",
+			BetAmount:             "125.500000",
+			WinAmount:             "250.000000",
+			WinLoss:               "124.500000",
+			SettledAt:             "2024-01-01T00:00:00Z",
+		}
+	}
+	return txns
+}
+
+type benchParquetWriter interface {
+	Write(ctx context.Context, input <-chan *models.Transaction) error
+	Close() error
+}
+
+func benchmarkParquet(b *testing.B, open func(dir string) (benchParquetWriter, error)) {
+	txns := benchTransactions(50000)
+	w, err := open(b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	input := make(chan *models.Transaction, 10000)
+	go func() {
+		for i := 0; i < b.N; i++ {
+			input <- txns[i%len(txns)]
+		}
+		close(input)
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	if err := w.Write(context.Background(), input); err != nil {
+		b.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		b.Fatal(err)
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "rows/s")
+}
+
+func BenchmarkParquetWriter(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	benchmarkParquet(b, func(dir string) (benchParquetWriter, error) {
+		return NewParquetWriter(dir, "bench.parquet", 50000, "snappy", ParquetTuning{}, logger)
+	})
+}
+
+func BenchmarkArrowParquetWriter(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	benchmarkParquet(b, func(dir string) (benchParquetWriter, error) {
+		return NewArrowParquetWriter(dir, "bench.parquet", 50000, "snappy", ParquetTuning{}, logger)
+	})
+}
@@ -0,0 +1,41 @@
+package writer
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestParquetSchemaKeepsLayout(t *testing.T) {
+	for _, dictionary := range [][]string{nil, {"vendor_code", "currency_code", "vendor_id"}} {
+		schema, err := parquetSchema(dictionary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Columns stay in Transaction field order with the dictionary
+		// columns switched on, not sorted by name
+		var names []string
+		for _, field := range schema.Fields() {
+			names = append(names, field.Name())
+			want := parquet.ByteArray
+			if f := columnFields[field.Name()]; f.numeric && !f.amount {
+				want = parquet.Int32
+			}
+			if kind := field.Type().Kind(); kind != want {
+				t.Errorf("%s is %s, want %s", field.Name(), kind, want)
+			}
+			encoding := field.Encoding()
+			if dictionary := slices.Contains(dictionary, field.Name()); dictionary != (encoding != nil && encoding.Encoding() == parquet.RLEDictionary.Encoding()) {
+				t.Errorf("%s encoding = %v", field.Name(), encoding)
+			}
+		}
+		if !slices.Equal(names, transactionColumns) {
+			t.Errorf("columns = %v", names)
+		}
+	}
+
+	if _, err := parquetSchema([]string{"nope"}); err == nil {
+		t.Error("unknown dictionary column accepted")
+	}
+}
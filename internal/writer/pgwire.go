@@ -0,0 +1,474 @@
+package writer
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PostgreSQL protocol codes
+const (
+	pgProtocolVersion = 196608   // 3.0
+	pgSSLRequest      = 80877103 // asks the server to switch to TLS
+	pgCopyChunk       = 64 * 1024
+)
+
+// Postgres SSL modes, as in libpq
+const (
+	PostgresSSLDisable    = "disable"     // plain TCP
+	PostgresSSLPrefer     = "prefer"      // TLS if the server offers it, unverified (default)
+	PostgresSSLRequire    = "require"     // TLS, unverified
+	PostgresSSLVerifyFull = "verify-full" // TLS, certificate and host name verified
+)
+
+// PostgresError is an ErrorResponse from the server
+type PostgresError struct {
+	Severity string
+	Code     string // SQLSTATE
+	Message  string
+	Detail   string
+}
+
+func (e *PostgresError) Error() string {
+	msg := fmt.Sprintf("postgres %s: %s (SQLSTATE %s)", strings.ToLower(e.Severity), e.Message, e.Code)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+// pgConn speaks just enough of the PostgreSQL frontend/backend protocol to
+// authenticate, run simple queries and COPY FROM STDIN. A failed operation
+// leaves it unusable; the caller closes it.
+type pgConn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+	msg     []byte // outgoing message being built
+	body    []byte // last incoming message body
+	timeout time.Duration
+	params  map[string]string // ParameterStatus values, e.g. server_version
+}
+
+// dialPostgres connects and authenticates with opts
+func dialPostgres(ctx context.Context, opts PostgresOptions) (*pgConn, error) {
+	if opts.SSLMode == "" {
+		opts.SSLMode = PostgresSSLPrefer
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	addr := opts.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "5432")
+	}
+	dialer := net.Dialer{Timeout: opts.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	c := &pgConn{conn: conn, timeout: opts.Timeout, params: make(map[string]string)}
+	c.deadline()
+
+	if opts.SSLMode != PostgresSSLDisable {
+		if err := c.startTLS(opts); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	c.r = bufio.NewReader(c.conn)
+	c.w = bufio.NewWriter(c.conn)
+
+	if err := c.startup(opts); err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// deadline bounds the next operation by the connection timeout
+func (c *pgConn) deadline() {
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+}
+
+// startTLS asks the server for TLS and wraps the connection if it agrees
+func (c *pgConn) startTLS(opts PostgresOptions) error {
+	var req [8]byte
+	binary.BigEndian.PutUint32(req[0:], 8)
+	binary.BigEndian.PutUint32(req[4:], pgSSLRequest)
+	if _, err := c.conn.Write(req[:]); err != nil {
+		return fmt.Errorf("failed to request postgres TLS: %w", err)
+	}
+	var answer [1]byte
+	if _, err := io.ReadFull(c.conn, answer[:]); err != nil {
+		return fmt.Errorf("failed to request postgres TLS: %w", err)
+	}
+	if answer[0] != 'S' {
+		if opts.SSLMode == PostgresSSLPrefer {
+			return nil
+		}
+		return fmt.Errorf("postgres server does not support TLS (sslmode %s)", opts.SSLMode)
+	}
+
+	host, _, err := net.SplitHostPort(opts.Host)
+	if err != nil {
+		host = opts.Host
+	}
+	config := &tls.Config{ServerName: host, InsecureSkipVerify: opts.SSLMode != PostgresSSLVerifyFull}
+	tlsConn := tls.Client(c.conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		return fmt.Errorf("postgres TLS handshake failed: %w", err)
+	}
+	c.conn = tlsConn
+	return nil
+}
+
+// startup sends the startup message, authenticates and waits until the
+// server is ready for queries
+func (c *pgConn) startup(opts PostgresOptions) error {
+	c.msg = c.msg[:0]
+	c.msg = binary.BigEndian.AppendUint32(c.msg, 0)
+	c.msg = binary.BigEndian.AppendUint32(c.msg, pgProtocolVersion)
+	for _, kv := range [][2]string{{"user", opts.User}, {"database", opts.Database}, {"application_name", "message_producer"}} {
+		c.msg = append(append(c.msg, kv[0]...), 0)
+		c.msg = append(append(c.msg, kv[1]...), 0)
+	}
+	c.msg = append(c.msg, 0)
+	binary.BigEndian.PutUint32(c.msg, uint32(len(c.msg)))
+	if _, err := c.w.Write(c.msg); err != nil {
+		return fmt.Errorf("failed to start postgres session: %w", err)
+	}
+	if err := c.w.Flush(); err != nil {
+		return fmt.Errorf("failed to start postgres session: %w", err)
+	}
+
+	var scram *scramClient
+	for {
+		typ, err := c.receive()
+		if err != nil {
+			return fmt.Errorf("postgres authentication failed: %w", err)
+		}
+		switch typ {
+		case 'R':
+			if len(c.body) < 4 {
+				return fmt.Errorf("postgres authentication failed: short message")
+			}
+			code, data := binary.BigEndian.Uint32(c.body), c.body[4:]
+			switch code {
+			case 0: // AuthenticationOk
+			case 3: // cleartext password
+				err = c.password(opts.Password)
+			case 5: // MD5 password
+				err = c.password(pgMD5Password(opts.User, opts.Password, data))
+			case 10: // SASL: pick SCRAM-SHA-256
+				if !strings.Contains(string(data), "SCRAM-SHA-256\x00") {
+					return fmt.Errorf("postgres authentication failed: no supported SASL mechanism")
+				}
+				scram = newSCRAMClient(opts.Password, "")
+				err = c.saslInitial("SCRAM-SHA-256", scram.clientFirst())
+			case 11: // SASL continue
+				if scram == nil {
+					return fmt.Errorf("postgres authentication failed: unexpected SASL continue")
+				}
+				var final string
+				if final, err = scram.clientFinal(string(data)); err == nil {
+					err = c.send('p', []byte(final))
+				}
+			case 12: // SASL final
+				if scram == nil {
+					return fmt.Errorf("postgres authentication failed: unexpected SASL final")
+				}
+				err = scram.verifyServer(string(data))
+			default:
+				return fmt.Errorf("postgres authentication method %d is not supported", code)
+			}
+			if err != nil {
+				return fmt.Errorf("postgres authentication failed: %w", err)
+			}
+		case 'E':
+			return parsePostgresError(c.body)
+		case 'S':
+			c.parameter()
+		case 'Z':
+			return nil
+		}
+	}
+}
+
+// password sends a PasswordMessage
+func (c *pgConn) password(password string) error {
+	return c.send('p', append([]byte(password), 0))
+}
+
+// saslInitial sends a SASLInitialResponse
+func (c *pgConn) saslInitial(mechanism, data string) error {
+	body := append([]byte(mechanism), 0)
+	body = binary.BigEndian.AppendUint32(body, uint32(len(data)))
+	return c.send('p', append(body, data...))
+}
+
+// parameter records a ParameterStatus message
+func (c *pgConn) parameter() {
+	parts := strings.SplitN(string(c.body), "\x00", 3)
+	if len(parts) >= 2 {
+		c.params[parts[0]] = parts[1]
+	}
+}
+
+// send writes and flushes one message
+func (c *pgConn) send(typ byte, body []byte) error {
+	c.queue(typ, body)
+	return c.w.Flush()
+}
+
+// queue writes one message without flushing
+func (c *pgConn) queue(typ byte, body []byte) {
+	var header [5]byte
+	header[0] = typ
+	binary.BigEndian.PutUint32(header[1:], uint32(len(body)+4))
+	c.w.Write(header[:])
+	c.w.Write(body)
+}
+
+// receive reads the next message into c.body, skipping notices
+func (c *pgConn) receive() (byte, error) {
+	for {
+		var header [5]byte
+		if _, err := io.ReadFull(c.r, header[:]); err != nil {
+			return 0, err
+		}
+		n := int(binary.BigEndian.Uint32(header[1:])) - 4
+		if n < 0 {
+			return 0, fmt.Errorf("malformed postgres message")
+		}
+		if cap(c.body) < n {
+			c.body = make([]byte, n)
+		}
+		c.body = c.body[:n]
+		if _, err := io.ReadFull(c.r, c.body); err != nil {
+			return 0, err
+		}
+		if header[0] != 'N' {
+			return header[0], nil
+		}
+	}
+}
+
+// exec runs a simple query and returns its first error
+func (c *pgConn) exec(query string) error {
+	c.deadline()
+	if err := c.send('Q', append([]byte(query), 0)); err != nil {
+		return err
+	}
+	var queryErr error
+	for {
+		typ, err := c.receive()
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case 'E':
+			if queryErr == nil {
+				queryErr = parsePostgresError(c.body)
+			}
+		case 'S':
+			c.parameter()
+		case 'Z':
+			return queryErr
+		}
+	}
+}
+
+// copyIn runs a COPY ... FROM STDIN query, sends data as its input and
+// returns the rows the server reports copied
+func (c *pgConn) copyIn(query string, data []byte) (int64, error) {
+	c.deadline()
+	if err := c.send('Q', append([]byte(query), 0)); err != nil {
+		return 0, err
+	}
+	var queryErr error
+	var rows int64
+	for {
+		typ, err := c.receive()
+		if err != nil {
+			return 0, err
+		}
+		switch typ {
+		case 'G': // CopyInResponse
+			for len(data) > 0 {
+				n := min(len(data), pgCopyChunk)
+				c.queue('d', data[:n])
+				data = data[n:]
+			}
+			if err := c.send('c', nil); err != nil {
+				return 0, err
+			}
+		case 'C':
+			tag := strings.TrimRight(string(c.body), "\x00")
+			if n, ok := strings.CutPrefix(tag, "COPY "); ok {
+				rows, _ = strconv.ParseInt(n, 10, 64)
+			}
+		case 'E':
+			if queryErr == nil {
+				queryErr = parsePostgresError(c.body)
+			}
+		case 'S':
+			c.parameter()
+		case 'Z':
+			return rows, queryErr
+		}
+	}
+}
+
+// close ends the session
+func (c *pgConn) close() error {
+	c.send('X', nil)
+	return c.conn.Close()
+}
+
+// parsePostgresError decodes an ErrorResponse body
+func parsePostgresError(body []byte) error {
+	e := &PostgresError{}
+	for len(body) > 1 {
+		field := body[0]
+		end := strings.IndexByte(string(body[1:]), 0)
+		if end < 0 {
+			break
+		}
+		value := string(body[1 : 1+end])
+		body = body[2+end:]
+		switch field {
+		case 'S':
+			e.Severity = value
+		case 'C':
+			e.Code = value
+		case 'M':
+			e.Message = value
+		case 'D':
+			e.Detail = value
+		}
+	}
+	return e
+}
+
+// pgMD5Password answers an MD5 challenge: "md5" + md5(md5(password + user) + salt)
+func pgMD5Password(user, password string, salt []byte) string {
+	inner := md5.Sum([]byte(password + user))
+	outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+	return "md5" + hex.EncodeToString(outer[:])
+}
+
+// scramClient runs the client side of SCRAM-SHA-256 (RFC 5802, 7677) as
+// PostgreSQL uses it: no channel binding, the user name taken from the
+// startup message
+type scramClient struct {
+	password    string
+	nonce       string
+	firstBare   string
+	serverProof []byte
+}
+
+// newSCRAMClient starts an exchange; nonce is random when empty
+func newSCRAMClient(password, nonce string) *scramClient {
+	if nonce == "" {
+		var raw [18]byte
+		rand.Read(raw[:])
+		nonce = base64.RawStdEncoding.EncodeToString(raw[:])
+	}
+	return &scramClient{password: password, nonce: nonce, firstBare: "n=,r=" + nonce}
+}
+
+func (s *scramClient) clientFirst() string {
+	return "n,," + s.firstBare
+}
+
+// clientFinal answers the server-first message with the client proof
+func (s *scramClient) clientFinal(serverFirst string) (string, error) {
+	var nonce, salt string
+	iterations := 0
+	for _, attr := range strings.Split(serverFirst, ",") {
+		key, value, _ := strings.Cut(attr, "=")
+		switch key {
+		case "r":
+			nonce = value
+		case "s":
+			salt = value
+		case "i":
+			iterations, _ = strconv.Atoi(value)
+		}
+	}
+	if !strings.HasPrefix(nonce, s.nonce) || salt == "" || iterations <= 0 {
+		return "", errors.New("invalid SCRAM server-first message")
+	}
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return "", fmt.Errorf("invalid SCRAM salt: %w", err)
+	}
+
+	salted := pbkdf2SHA256([]byte(s.password), saltBytes, iterations)
+	clientKey := hmacSHA256(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	withoutProof := "c=biws,r=" + nonce
+	authMessage := s.firstBare + "," + serverFirst + "," + withoutProof
+	signature := hmacSHA256(storedKey[:], authMessage)
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ signature[i]
+	}
+	s.serverProof = hmacSHA256(hmacSHA256(salted, "Server Key"), authMessage)
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+// verifyServer checks the server-final message proves the server knows
+// the password too
+func (s *scramClient) verifyServer(serverFinal string) error {
+	value, ok := strings.CutPrefix(serverFinal, "v=")
+	if !ok {
+		return fmt.Errorf("SCRAM server error: %s", serverFinal)
+	}
+	proof, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || !hmac.Equal(proof, s.serverProof) {
+		return errors.New("SCRAM server signature mismatch")
+	}
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// pbkdf2SHA256 derives one SHA-256 sized key, all SCRAM needs
+func pbkdf2SHA256(password, salt []byte, iterations int) []byte {
+	mac := hmac.New(sha256.New, password)
+	mac.Write(salt)
+	mac.Write([]byte{0, 0, 0, 1})
+	u := mac.Sum(nil)
+	key := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
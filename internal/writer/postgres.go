@@ -0,0 +1,229 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// PostgresOptions holds connection settings for the Postgres writer
+type PostgresOptions struct {
+	Host      string // host or host:port, default port 5432
+	User      string
+	Password  string
+	Database  string
+	SSLMode   string // disable, prefer, require or verify-full
+	Table     string
+	BatchSize int
+	PoolSize  int           // connections copying batches in parallel, default 2
+	Timeout   time.Duration // per connect and per COPY, default 30s
+}
+
+// PostgresWriter bulk-loads transactions into a PostgreSQL table with COPY
+// FROM STDIN, one COPY per batch. Batches are encoded as CSV by the writer
+// and copied by a pool of connections in parallel; the first failure stops
+// the writer.
+type PostgresWriter struct {
+	opts    PostgresOptions
+	copySQL string
+	encoder csvEncoder
+	buffer  []*models.Transaction
+	batches chan postgresBatch
+	conns   []*pgConn
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	err     error // first failed COPY
+	count   atomic.Int64
+	errors  atomic.Int64
+	logger  *slog.Logger
+}
+
+// postgresBatch is one encoded COPY payload
+type postgresBatch struct {
+	data []byte
+	rows int
+}
+
+// NewPostgresWriter connects the pool and creates the table if it does not
+// exist
+func NewPostgresWriter(ctx context.Context, opts PostgresOptions, logger *slog.Logger) (*PostgresWriter, error) {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 2
+	}
+	// Quoting text keeps empty strings apart from NULL, which COPY reads
+	// from an unquoted empty field
+	encoder, err := newCSVEncoder(CSVFormat{Quoting: CSVQuoteStrings})
+	if err != nil {
+		return nil, err
+	}
+
+	w := &PostgresWriter{
+		opts:    opts,
+		copySQL: fmt.Sprintf("COPY %s (%s) FROM STDIN WITH (FORMAT csv)", opts.Table, strings.Join(transactionColumns, ", ")),
+		encoder: encoder,
+		buffer:  make([]*models.Transaction, 0, opts.BatchSize),
+		batches: make(chan postgresBatch, opts.PoolSize),
+		logger:  logger,
+	}
+	for i := 0; i < opts.PoolSize; i++ {
+		conn, err := dialPostgres(ctx, opts)
+		if err != nil {
+			w.closeConns()
+			return nil, err
+		}
+		w.conns = append(w.conns, conn)
+	}
+	if err := w.conns[0].exec(postgresTableDDL(opts.Table)); err != nil {
+		w.closeConns()
+		return nil, fmt.Errorf("failed to create postgres table: %w", err)
+	}
+
+	for _, conn := range w.conns {
+		w.wg.Add(1)
+		go w.copyBatches(conn)
+	}
+	return w, nil
+}
+
+// CheckPostgres connects and authenticates with opts and returns the server
+// version
+func CheckPostgres(ctx context.Context, opts PostgresOptions) (string, error) {
+	conn, err := dialPostgres(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	defer conn.close()
+	return conn.params["server_version"], nil
+}
+
+// postgresTableDDL generates the table from the transaction columns
+func postgresTableDDL(table string) string {
+	return generatedTableDDL(table, func(column string, field columnField) string {
+		switch {
+		case field.amount:
+			return "NUMERIC(20, 6)"
+		case field.numeric:
+			return "INTEGER"
+		case column == "settled_at":
+			return "TIMESTAMPTZ"
+		default:
+			return "TEXT"
+		}
+	})
+}
+
+// copyBatches runs COPY on conn for each batch it takes
+func (w *PostgresWriter) copyBatches(conn *pgConn) {
+	defer w.wg.Done()
+	for batch := range w.batches {
+		if w.failed() != nil {
+			// Another connection failed; the writer is stopping
+			w.errors.Add(int64(batch.rows))
+			continue
+		}
+		rows, err := conn.copyIn(w.copySQL, batch.data)
+		if err != nil {
+			w.errors.Add(int64(batch.rows))
+			w.fail(fmt.Errorf("failed to copy into %s: %w", w.opts.Table, err))
+			continue
+		}
+		w.count.Add(rows)
+	}
+}
+
+func (w *PostgresWriter) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *PostgresWriter) failed() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Write writes transactions from the channel to Postgres
+func (w *PostgresWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return w.flush()
+		case txn, ok := <-input:
+			if !ok {
+				// Channel closed, flush remaining buffer
+				return w.flush()
+			}
+
+			w.buffer = append(w.buffer, txn)
+			if len(w.buffer) >= w.opts.BatchSize {
+				if err := w.flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// WriteBatch copies batch, plus anything already buffered, in one COPY
+func (w *PostgresWriter) WriteBatch(ctx context.Context, batch []*models.Transaction) error {
+	w.buffer = append(w.buffer, batch...)
+	return w.flush()
+}
+
+// flush hands the buffer to the pool, waiting for a free connection. It
+// returns the first COPY failure so far.
+func (w *PostgresWriter) flush() error {
+	if err := w.failed(); err != nil {
+		return err
+	}
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	var data []byte
+	for _, txn := range w.buffer {
+		data = w.encoder.appendRecord(data, txn)
+	}
+	w.batches <- postgresBatch{data: data, rows: len(w.buffer)}
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+// CloseContext copies what is buffered, waits for the pool and closes the
+// connections, abandoning them once ctx is done
+func (w *PostgresWriter) CloseContext(ctx context.Context) error {
+	return CloseWithin(ctx, w.close)
+}
+
+func (w *PostgresWriter) close() error {
+	w.flush()
+	close(w.batches)
+	w.wg.Wait()
+	w.closeConns()
+	return w.failed()
+}
+
+func (w *PostgresWriter) closeConns() {
+	for _, conn := range w.conns {
+		conn.close()
+	}
+}
+
+// Count returns the number of transactions copied
+func (w *PostgresWriter) Count() int64 {
+	return w.count.Load()
+}
+
+// Errors returns the number of transactions in failed batches
+func (w *PostgresWriter) Errors() int64 {
+	return w.errors.Load()
+}
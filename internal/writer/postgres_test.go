@@ -0,0 +1,235 @@
+package writer
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// fakePostgres accepts connections with MD5 authentication and records
+// what COPY receives. COPY into a table named "missing" fails.
+type fakePostgres struct {
+	listener net.Listener
+	mu       sync.Mutex
+	queries  []string
+	copied   []string // CSV lines
+}
+
+func startFakePostgres(t *testing.T) *fakePostgres {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakePostgres{listener: l}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakePostgres) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	send := func(typ byte, body string) {
+		header := []byte{typ, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(header[1:], uint32(len(body)+4))
+		conn.Write(append(header, body...))
+	}
+	receive := func() (byte, string, error) {
+		var header [5]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return 0, "", err
+		}
+		body := make([]byte, binary.BigEndian.Uint32(header[1:])-4)
+		_, err := io.ReadFull(r, body)
+		return header[0], string(body), err
+	}
+
+	// Startup, declining TLS
+	var params map[string]string
+	for params == nil {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return
+		}
+		body := make([]byte, binary.BigEndian.Uint32(size[:])-4)
+		io.ReadFull(r, body)
+		if binary.BigEndian.Uint32(body) == pgSSLRequest {
+			conn.Write([]byte{'N'})
+			continue
+		}
+		params = make(map[string]string)
+		fields := strings.Split(string(body[4:]), "\x00")
+		for i := 0; i+1 < len(fields); i += 2 {
+			params[fields[i]] = fields[i+1]
+		}
+	}
+
+	salt := "salt"
+	send('R', "\x00\x00\x00\x05"+salt)
+	if _, password, err := receive(); err != nil || password != pgMD5Password(params["user"], "secret", []byte(salt))+"\x00" {
+		send('E', "SFATAL\x00C28P01\x00Mpassword authentication failed\x00\x00")
+		return
+	}
+	send('R', "\x00\x00\x00\x00")
+	send('S', "server_version\x0016.2\x00")
+	send('Z', "I")
+
+	for {
+		typ, body, err := receive()
+		if err != nil || typ == 'X' {
+			return
+		}
+		query := strings.TrimSuffix(body, "\x00")
+		s.mu.Lock()
+		s.queries = append(s.queries, query)
+		s.mu.Unlock()
+		switch {
+		case strings.HasPrefix(query, "COPY missing"):
+			send('E', "SERROR\x00C42P01\x00Mrelation \"missing\" does not exist\x00\x00")
+		case strings.HasPrefix(query, "COPY"):
+			send('G', "\x00\x00\x00")
+			var data strings.Builder
+			for {
+				typ, body, err := receive()
+				if err != nil {
+					return
+				}
+				if typ == 'c' {
+					break
+				}
+				data.WriteString(body)
+			}
+			lines := strings.Split(strings.TrimSuffix(data.String(), "\n"), "\n")
+			s.mu.Lock()
+			s.copied = append(s.copied, lines...)
+			s.mu.Unlock()
+			send('C', fmt.Sprintf("COPY %d\x00", len(lines)))
+		default:
+			send('C', "CREATE TABLE\x00")
+		}
+		send('Z', "I")
+	}
+}
+
+func (s *fakePostgres) options(table string) PostgresOptions {
+	return PostgresOptions{
+		Host:      s.listener.Addr().String(),
+		User:      "producer",
+		Password:  "secret",
+		Database:  "test",
+		Table:     table,
+		BatchSize: 3,
+		PoolSize:  2,
+		Timeout:   5 * time.Second,
+	}
+}
+
+func TestPostgresWriter(t *testing.T) {
+	server := startFakePostgres(t)
+	w, err := NewPostgresWriter(context.Background(), server.options("transactions"), discardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := make(chan *models.Transaction, 7)
+	for i := 0; i < 7; i++ {
+		input <- &models.Transaction{ID: fmt.Sprintf("TXN-%d", i), VendorCode: "A,B", BetAmount: "1.50"}
+	}
+	close(input)
+	if err := w.Write(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.CloseContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if w.Count() != 7 || w.Errors() != 0 {
+		t.Errorf("count = %d, errors = %d", w.Count(), w.Errors())
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if !strings.HasPrefix(server.queries[0], "CREATE TABLE IF NOT EXISTS transactions (") ||
+		!strings.Contains(server.queries[0], "bet_amount NUMERIC(20, 6)") ||
+		!strings.Contains(server.queries[0], "settled_at TIMESTAMPTZ") {
+		t.Errorf("ddl = %s", server.queries[0])
+	}
+	if want := "COPY transactions (" + strings.Join(transactionColumns, ", ") + ") FROM STDIN WITH (FORMAT csv)"; server.queries[1] != want {
+		t.Errorf("copy = %s", server.queries[1])
+	}
+	if len(server.copied) != 7 {
+		t.Fatalf("copied %d rows, want 7", len(server.copied))
+	}
+	// Text is quoted, so empty strings stay apart from NULL
+	if line := server.copied[0]; !strings.Contains(line, `"A,B"`) || !strings.Contains(line, `,"",`) {
+		t.Errorf("row = %s", line)
+	}
+}
+
+func TestPostgresWriterCopyError(t *testing.T) {
+	server := startFakePostgres(t)
+	w, err := NewPostgresWriter(context.Background(), server.options("missing"), discardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.WriteBatch(context.Background(), []*models.Transaction{{ID: "TXN-1"}})
+	if err == nil {
+		err = w.CloseContext(context.Background())
+	}
+	var pgErr *PostgresError
+	if !errors.As(err, &pgErr) || pgErr.Code != "42P01" {
+		t.Fatalf("err = %v, want undefined table", err)
+	}
+	if w.Errors() != 1 {
+		t.Errorf("errors = %d, want 1", w.Errors())
+	}
+}
+
+func TestPostgresWrongPassword(t *testing.T) {
+	server := startFakePostgres(t)
+	opts := server.options("transactions")
+	opts.Password = "wrong"
+	if _, err := CheckPostgres(context.Background(), opts); err == nil || !strings.Contains(err.Error(), "28P01") {
+		t.Fatalf("err = %v", err)
+	}
+	opts.Password = "secret"
+	if version, err := CheckPostgres(context.Background(), opts); err != nil || version != "16.2" {
+		t.Fatalf("version = %q, %v", version, err)
+	}
+}
+
+func TestSCRAMClient(t *testing.T) {
+	// RFC 7677 section 3
+	s := newSCRAMClient("pencil", "rOprNGfwEbeRWgbNEkqO")
+	s.firstBare = "n=user,r=rOprNGfwEbeRWgbNEkqO"
+	final, err := s.clientFinal("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ="; final != want {
+		t.Errorf("client final = %s", final)
+	}
+	if err := s.verifyServer("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="); err != nil {
+		t.Error(err)
+	}
+	if err := s.verifyServer("v=AAAA"); err == nil {
+		t.Error("accepted a wrong server signature")
+	}
+}
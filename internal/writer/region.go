@@ -0,0 +1,100 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// RegionHeader carries the simulated origin region of a transaction
+const RegionHeader = "region"
+
+// RegionRoute sends one region's transactions to its own Kafka cluster or
+// topic
+type RegionRoute struct {
+	Region string
+	Writer *KafkaWriter
+}
+
+// RegionRouter sends each transaction to the Kafka writer routed for its
+// region and the rest to a default writer, so geo-replication and failover
+// consumers can be fed per-region streams. The slowest writer paces the
+// others.
+type RegionRouter struct {
+	fallback *KafkaWriter
+	routes   []RegionRoute
+	index    map[string]int // region -> position in routes
+}
+
+// NewRegionRouter creates a router over routes, sending unrouted regions to
+// fallback. The caller closes the writers.
+func NewRegionRouter(fallback *KafkaWriter, routes []RegionRoute) *RegionRouter {
+	r := &RegionRouter{fallback: fallback, routes: routes, index: make(map[string]int, len(routes))}
+	for i, route := range routes {
+		r.index[route.Region] = i
+	}
+	return r
+}
+
+// Write routes transactions from the channel by region
+func (r *RegionRouter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	writers := []*KafkaWriter{r.fallback}
+	names := []string{"default"}
+	for _, route := range r.routes {
+		writers = append(writers, route.Writer)
+		names = append(names, route.Region)
+	}
+	channels := make([]chan *models.Transaction, len(writers))
+	errs := make([]error, len(writers))
+
+	var wg sync.WaitGroup
+	for i, w := range writers {
+		channels[i] = make(chan *models.Transaction, cap(input))
+		wg.Add(1)
+		go func(i int, w *KafkaWriter) {
+			defer wg.Done()
+			errs[i] = w.Write(ctx, channels[i])
+			if errs[i] != nil {
+				// Keep draining so one failed route does not stall the rest
+				for range channels[i] {
+				}
+			}
+		}(i, w)
+	}
+
+	func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case txn, ok := <-input:
+				if !ok {
+					return
+				}
+				ch := channels[0]
+				if i, ok := r.index[txn.Region]; ok {
+					ch = channels[i+1]
+				}
+				select {
+				case ch <- txn:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	for _, ch := range channels {
+		close(ch)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("region route %s: %w", names[i], err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package writer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"text/template"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Record template line endings
+const (
+	LineEndingLF   = "lf"   // \n after every record (default)
+	LineEndingCRLF = "crlf" // \r\n after every record
+	LineEndingNone = "none" // the template writes its own separators
+)
+
+// renderFuncs are the functions a record template can call. The padding
+// functions cut values longer than the width, so columns stay fixed.
+var renderFuncs = template.FuncMap{
+	"lpad":    func(width int, v any) string { return pad(fmt.Sprint(v), width, ' ', true) },
+	"rpad":    func(width int, v any) string { return pad(fmt.Sprint(v), width, ' ', false) },
+	"zpad":    func(width int, v any) string { return pad(fmt.Sprint(v), width, '0', true) },
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"fixed":   fixedPlaces,
+	"implied": impliedPlaces,
+}
+
+// pad fits s into width runes, padding on the left (right-aligned) or the
+// right, and cutting the end of a longer value
+func pad(s string, width int, fill rune, left bool) string {
+	runes := []rune(s)
+	if len(runes) >= width {
+		return string(runes[:width])
+	}
+	padding := strings.Repeat(string(fill), width-len(runes))
+	if left {
+		return padding + s
+	}
+	return s + padding
+}
+
+// fixedPlaces formats an amount with exactly places decimals
+func fixedPlaces(places int, amount string) (string, error) {
+	d, err := decimal.NewFromString(amount)
+	if err != nil {
+		return "", fmt.Errorf("invalid amount %q: %w", amount, err)
+	}
+	return d.StringFixed(int32(places)), nil
+}
+
+// impliedPlaces formats an amount as an integer with places implied
+// decimals, e.g. 10.5 with 2 places is 1050, as legacy record layouts store
+// amounts
+func impliedPlaces(places int, amount string) (string, error) {
+	d, err := decimal.NewFromString(amount)
+	if err != nil {
+		return "", fmt.Errorf("invalid amount %q: %w", amount, err)
+	}
+	return d.Shift(int32(places)).Round(0).String(), nil
+}
+
+// ParseRenderTemplate compiles a render template. It is executed with each
+// *models.Transaction, so {{.CurrencyCode}} or
+// {{lpad 12 (implied 2 .BetAmount)}} refer to its fields.
+func ParseRenderTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("render").Funcs(renderFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid record template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// RenderFormat controls how the render writer lays out its file
+type RenderFormat struct {
+	Template   *template.Template
+	Header     string // written once at the top of the file, as is
+	LineEnding string // lf (default), crlf or none
+}
+
+// RenderWriter renders every transaction through a text template, for
+// fixed-width and other legacy line formats
+type RenderWriter struct {
+	file       *os.File
+	writer     *bufio.Writer
+	tmpl       *template.Template
+	ending     string
+	record     bytes.Buffer // reused rendering buffer for one record
+	bufferSize int
+	buffer     []*models.Transaction
+	count      atomic.Int64
+	logger     *slog.Logger
+}
+
+// NewRenderWriter creates a writer rendering records with format
+func NewRenderWriter(outputDir, filename string, bufferSize int, format RenderFormat, logger *slog.Logger) (*RenderWriter, error) {
+	var ending string
+	switch format.LineEnding {
+	case "", LineEndingLF:
+		ending = "\n"
+	case LineEndingCRLF:
+		ending = "\r\n"
+	case LineEndingNone:
+	default:
+		return nil, fmt.Errorf("unsupported line ending: %s", format.LineEnding)
+	}
+
+	path := filepath.Join(outputDir, filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create render file: %w", err)
+	}
+
+	writer := bufio.NewWriterSize(retryWrites(file, logger), 64*1024)
+	if format.Header != "" {
+		if _, err := writer.WriteString(format.Header + ending); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+
+	bufferSize = max(bufferSize, 1)
+	return &RenderWriter{
+		file:       file,
+		writer:     writer,
+		tmpl:       format.Template,
+		ending:     ending,
+		bufferSize: bufferSize,
+		buffer:     make([]*models.Transaction, 0, bufferSize),
+		logger:     logger,
+	}, nil
+}
+
+// Write renders transactions from the channel
+func (w *RenderWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return w.flush()
+		case txn, ok := <-input:
+			if !ok {
+				return w.flush()
+			}
+			w.buffer = append(w.buffer, txn)
+			if len(w.buffer) >= w.bufferSize {
+				if err := w.flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (w *RenderWriter) flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	for _, txn := range w.buffer {
+		w.record.Reset()
+		if err := w.tmpl.Execute(&w.record, txn); err != nil {
+			return fmt.Errorf("failed to render record %s: %w", txn.ID, err)
+		}
+		w.record.WriteString(w.ending)
+		if _, err := w.writer.Write(w.record.Bytes()); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush render writer: %w", err)
+	}
+
+	w.count.Add(int64(len(w.buffer)))
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+// Close flushes remaining records and closes the file
+func (w *RenderWriter) Close() error {
+	if err := w.flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// Count returns the number of transactions written
+func (w *RenderWriter) Count() int64 {
+	return w.count.Load()
+}
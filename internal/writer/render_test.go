@@ -0,0 +1,79 @@
+package writer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func TestRenderWriterFixedWidth(t *testing.T) {
+	tmpl, err := ParseRenderTemplate(`{{rpad 8 .ID}}{{rpad 3 (upper .CurrencyCode)}}{{zpad 10 (implied 2 .BetAmount)}}{{lpad 6 .HouseID}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	w, err := NewRenderWriter(dir, "feed.dat", 10, RenderFormat{
+		Template:   tmpl,
+		Header:     "HDR",
+		LineEnding: LineEndingCRLF,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := make(chan *models.Transaction, 2)
+	input <- &models.Transaction{ID: "TXN-1", CurrencyCode: "usd", BetAmount: "10.500000", HouseID: 7}
+	input <- &models.Transaction{ID: "TXN-1234567890", CurrencyCode: "EUR", BetAmount: "0.005000", HouseID: 1234567}
+	close(input)
+	if err := w.Write(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "feed.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "HDR\r\n" +
+		"TXN-1   USD0000001050     7\r\n" +
+		"TXN-1234EUR0000000001123456\r\n"
+	if string(data) != want {
+		t.Errorf("output =\n%q\nwant\n%q", data, want)
+	}
+	if w.Count() != 2 {
+		t.Errorf("count = %d, want 2", w.Count())
+	}
+}
+
+func TestRenderWriterRenderError(t *testing.T) {
+	tmpl, err := ParseRenderTemplate(`{{implied 2 .ID}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewRenderWriter(t.TempDir(), "feed.dat", 1, RenderFormat{Template: tmpl}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	input := make(chan *models.Transaction, 1)
+	input <- &models.Transaction{ID: "TXN-1"}
+	close(input)
+	if err := w.Write(context.Background(), input); err == nil {
+		t.Fatal("non-numeric amount rendered")
+	}
+}
+
+func TestParseRenderTemplateInvalid(t *testing.T) {
+	if _, err := ParseRenderTemplate(`{{.ID`); err == nil {
+		t.Fatal("unterminated action accepted")
+	}
+	if _, err := ParseRenderTemplate(`{{nosuchfunc .ID}}`); err == nil {
+		t.Fatal("unknown function accepted")
+	}
+}
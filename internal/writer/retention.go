@@ -0,0 +1,86 @@
+package writer
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// RunMarker is written into every run subdirectory so retention only ever
+// removes directories a run created
+const RunMarker = ".producer-run"
+
+// CreateRunDir creates a run subdirectory and marks it with the run ID
+func CreateRunDir(dir, runID string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, RunMarker), []byte(runID+"\n"), 0644)
+}
+
+// runDir is a marked run subdirectory and when its run started
+type runDir struct {
+	path    string
+	started time.Time
+}
+
+// PruneRuns removes marked run subdirectories under base, keeping the newest
+// keep runs and dropping any started more than maxAge before now. A zero keep
+// or maxAge disables that limit. The current run directory is never removed
+// and counts towards keep. Parent directories left empty, such as a
+// {{date}} level, are removed too. It returns the removed directories.
+func PruneRuns(base, current string, keep int, maxAge time.Duration, now time.Time) ([]string, error) {
+	var runs []runDir
+	err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || path == base {
+			return nil
+		}
+		info, err := os.Stat(filepath.Join(path, RunMarker))
+		if err != nil {
+			return nil
+		}
+		runs = append(runs, runDir{path: path, started: info.ModTime()})
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].started.After(runs[j].started) })
+
+	current = filepath.Clean(current)
+	var removed []string
+	kept := 0
+	for _, run := range runs {
+		if filepath.Clean(run.path) == current {
+			kept++
+			continue
+		}
+		if (keep > 0 && kept >= keep) || (maxAge > 0 && now.Sub(run.started) > maxAge) {
+			if err := os.RemoveAll(run.path); err != nil {
+				return removed, err
+			}
+			removed = append(removed, run.path)
+			removeEmptyParents(base, filepath.Dir(run.path))
+			continue
+		}
+		kept++
+	}
+	return removed, nil
+}
+
+// removeEmptyParents removes dir and its ancestors below base while they are
+// empty
+func removeEmptyParents(base, dir string) {
+	base = filepath.Clean(base)
+	for dir = filepath.Clean(dir); dir != base && len(dir) > len(base); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
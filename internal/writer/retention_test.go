@@ -0,0 +1,74 @@
+package writer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// makeRun creates a marked run directory whose run started at started
+func makeRun(t *testing.T, dir string, started time.Time) {
+	t.Helper()
+	if err := CreateRunDir(dir, filepath.Base(dir)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(dir, RunMarker), started, started); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestPruneRunsKeepsNewest(t *testing.T) {
+	base := t.TempDir()
+	now := time.Now()
+	for i := 1; i <= 4; i++ {
+		makeRun(t, filepath.Join(base, fmt.Sprintf("run%d", i)), now.Add(-time.Duration(i)*time.Hour))
+	}
+	current := filepath.Join(base, "run0")
+	makeRun(t, current, now)
+	// Not a run directory, never touched
+	if err := os.Mkdir(filepath.Join(base, "manual"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := PruneRuns(base, current, 3, 0, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 {
+		t.Fatalf("removed %v, want run3 and run4", removed)
+	}
+	for name, want := range map[string]bool{"run0": true, "run1": true, "run2": true, "run3": false, "run4": false, "manual": true} {
+		if got := exists(filepath.Join(base, name)); got != want {
+			t.Errorf("%s exists = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestPruneRunsMaxAge(t *testing.T) {
+	base := t.TempDir()
+	now := time.Now()
+	old := filepath.Join(base, "2024-01-01", "old")
+	recent := filepath.Join(base, "2024-01-09", "recent")
+	makeRun(t, old, now.Add(-8*24*time.Hour))
+	makeRun(t, recent, now.Add(-24*time.Hour))
+	// The current run is kept even when its marker looks old
+	current := filepath.Join(base, "2024-01-10", "current")
+	makeRun(t, current, now.Add(-30*24*time.Hour))
+
+	if _, err := PruneRuns(base, current, 0, 7*24*time.Hour, now); err != nil {
+		t.Fatal(err)
+	}
+	if exists(filepath.Join(base, "2024-01-01")) {
+		t.Error("empty date directory of the expired run was left behind")
+	}
+	if !exists(recent) || !exists(current) {
+		t.Error("recent or current run was removed")
+	}
+}
@@ -0,0 +1,83 @@
+package writer
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"syscall"
+	"time"
+)
+
+// WriteRetry bounds how the file writers retry a write that failed with a
+// transient error
+type WriteRetry struct {
+	Retries int           // attempts after the first failure, 0 = none
+	Backoff time.Duration // before the first retry, doubling after each
+}
+
+// writeRetry is the policy of file writers created from now on
+var writeRetry = WriteRetry{Retries: 3, Backoff: 50 * time.Millisecond}
+
+// SetWriteRetry replaces the retry policy for transient file write errors,
+// by default 3 retries from 50ms. Call before creating writers.
+func SetWriteRetry(retry WriteRetry) {
+	writeRetry = retry
+}
+
+// retryWriter retries writes that fail with a transient error, resuming
+// after the bytes already written. Without it a single EINTR or NFS timeout
+// would fail the bufio or Parquet writer above it for good, and with it the
+// rest of the stream.
+type retryWriter struct {
+	w      io.Writer
+	name   string
+	retry  WriteRetry
+	logger *slog.Logger
+}
+
+// retryWrites wraps an output file in the current retry policy
+func retryWrites(file *os.File, logger *slog.Logger) io.Writer {
+	return newRetryWriter(file, file.Name(), writeRetry, logger)
+}
+
+func newRetryWriter(w io.Writer, name string, retry WriteRetry, logger *slog.Logger) *retryWriter {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &retryWriter{w: w, name: name, retry: retry, logger: logger}
+}
+
+func (r *retryWriter) Write(p []byte) (int, error) {
+	written := 0
+	backoff := r.retry.Backoff
+	for attempt := 0; ; attempt++ {
+		n, err := r.w.Write(p[written:])
+		written += n
+		if err == nil || attempt >= r.retry.Retries || !transientWriteError(err) {
+			return written, err
+		}
+		r.logger.Warn("Transient write error, retrying",
+			"file", r.name,
+			"retry", attempt+1,
+			"backoff", backoff,
+			"error", err,
+		)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// transientWriteError reports whether a write failing with err may succeed
+// when retried: an interrupted call, a momentarily unavailable resource, or
+// an NFS server that did not answer in time, which soft mounts report as
+// EIO or ETIMEDOUT
+func transientWriteError(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.EINTR, syscall.EAGAIN, syscall.ETIMEDOUT, syscall.EIO} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	var timeout interface{ Timeout() bool }
+	return errors.As(err, &timeout) && timeout.Timeout()
+}
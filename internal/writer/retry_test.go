@@ -0,0 +1,65 @@
+package writer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// flakyWriter fails its first writes with err after writing part of the
+// data, the way an interrupted write returns a short count
+type flakyWriter struct {
+	bytes.Buffer
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyWriter) Write(p []byte) (int, error) {
+	f.calls++
+	if f.failures > 0 {
+		f.failures--
+		n := len(p) / 2
+		f.Buffer.Write(p[:n])
+		return n, f.err
+	}
+	return f.Buffer.Write(p)
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestRetryWriter(t *testing.T) {
+	flaky := &flakyWriter{failures: 2, err: &os.PathError{Op: "write", Path: "out.csv", Err: syscall.EINTR}}
+	w := newRetryWriter(flaky, "out.csv", WriteRetry{Retries: 3}, discardLogger)
+	n, err := w.Write([]byte("0123456789"))
+	if err != nil || n != 10 {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	// The retries resume where the short writes stopped
+	if flaky.String() != "0123456789" || flaky.calls != 3 {
+		t.Errorf("wrote %q in %d calls", flaky.String(), flaky.calls)
+	}
+}
+
+func TestRetryWriterGivesUp(t *testing.T) {
+	// Out of retries
+	flaky := &flakyWriter{failures: 5, err: syscall.EIO}
+	w := newRetryWriter(flaky, "out.csv", WriteRetry{Retries: 2}, discardLogger)
+	if _, err := w.Write([]byte("data")); !errors.Is(err, syscall.EIO) {
+		t.Fatalf("err = %v, want EIO", err)
+	}
+	if flaky.calls != 3 {
+		t.Errorf("calls = %d, want 3", flaky.calls)
+	}
+
+	// A full disk is not transient
+	flaky = &flakyWriter{failures: 1, err: syscall.ENOSPC}
+	w = newRetryWriter(flaky, "out.csv", WriteRetry{Retries: 2}, discardLogger)
+	if _, err := w.Write([]byte("data")); !errors.Is(err, syscall.ENOSPC) || flaky.calls != 1 {
+		t.Errorf("ENOSPC: err = %v after %d calls", err, flaky.calls)
+	}
+}
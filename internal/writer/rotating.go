@@ -0,0 +1,158 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// RotationFilename returns the file name for the seq-th rotated file, filling
+// in {{seq}} or appending -NNNNN before the extension
+func RotationFilename(filename string, seq int) string {
+	if strings.Contains(filename, "{{seq}}") {
+		return FileSeq(filename, seq)
+	}
+	ext := fileExt(filename)
+	return fmt.Sprintf("%s-%05d%s", strings.TrimSuffix(filename, ext), seq, ext)
+}
+
+// RotatingWriter closes the current file and opens the next one after a
+// number of rows or a time interval, whichever comes first
+type RotatingWriter struct {
+	open     func(seq int) (FileWriter, error)
+	maxRows  int64
+	interval time.Duration
+	mu       sync.Mutex // guards current and written for Count
+	current  FileWriter
+	seq      int
+	written  int64 // rows in closed files
+	logger   *slog.Logger
+}
+
+// NewRotatingWriter creates a writer that rotates files produced by open.
+// A zero maxRows or interval disables that trigger.
+func NewRotatingWriter(open func(seq int) (FileWriter, error), maxRows int64, interval time.Duration, logger *slog.Logger) (*RotatingWriter, error) {
+	current, err := open(0)
+	if err != nil {
+		return nil, err
+	}
+	return &RotatingWriter{
+		open:     open,
+		maxRows:  maxRows,
+		interval: interval,
+		current:  current,
+		logger:   logger,
+	}, nil
+}
+
+// Write writes transactions from the channel, rotating files as configured
+func (w *RotatingWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	var tick <-chan time.Time
+	if w.interval > 0 {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		ch := make(chan *models.Transaction, cap(input))
+		var writeErr error
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func(current FileWriter) {
+			defer wg.Done()
+			writeErr = current.Write(ctx, ch)
+			if writeErr != nil {
+				for range ch {
+				}
+			}
+		}(w.currentWriter())
+
+		done, err := w.forward(ctx, input, ch, tick)
+		close(ch)
+		wg.Wait()
+		if err == nil {
+			err = writeErr
+		}
+		if done || err != nil {
+			return err
+		}
+
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+}
+
+// forward copies transactions into the current file until it is due for
+// rotation. It reports done when the input is exhausted or ctx is cancelled.
+func (w *RotatingWriter) forward(ctx context.Context, input <-chan *models.Transaction, out chan<- *models.Transaction, tick <-chan time.Time) (bool, error) {
+	var rows int64
+	for {
+		select {
+		case <-ctx.Done():
+			return true, nil
+		case <-tick:
+			if rows > 0 {
+				return false, nil
+			}
+		case txn, ok := <-input:
+			if !ok {
+				return true, nil
+			}
+			select {
+			case out <- txn:
+			case <-ctx.Done():
+				return true, nil
+			}
+			rows++
+			if w.maxRows > 0 && rows >= w.maxRows {
+				return false, nil
+			}
+		}
+	}
+}
+
+func (w *RotatingWriter) rotate() error {
+	current := w.currentWriter()
+	if err := current.Close(); err != nil {
+		return fmt.Errorf("failed to close rotated file %d: %w", w.seq, err)
+	}
+
+	next, err := w.open(w.seq + 1)
+	if err != nil {
+		return fmt.Errorf("failed to open rotated file %d: %w", w.seq+1, err)
+	}
+	w.seq++
+
+	w.mu.Lock()
+	w.written += current.Count()
+	w.current = next
+	written := w.written
+	w.mu.Unlock()
+	w.logger.Info("Rotated output file", "seq", w.seq, "rows_written", written)
+	return nil
+}
+
+func (w *RotatingWriter) currentWriter() FileWriter {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Close closes the current file
+func (w *RotatingWriter) Close() error {
+	return w.currentWriter().Close()
+}
+
+// Count returns the number of transactions written across all files
+func (w *RotatingWriter) Count() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written + w.current.Count()
+}
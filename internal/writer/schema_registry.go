@@ -0,0 +1,131 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SchemaRegistry registers and checks schemas with a Confluent-compatible
+// schema registry
+type SchemaRegistry struct {
+	baseURL  string
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewSchemaRegistry creates a registry client. Username enables basic auth.
+func NewSchemaRegistry(baseURL, username, password string, timeout time.Duration) *SchemaRegistry {
+	return &SchemaRegistry{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Register adds schema under subject, or finds it if already registered, and
+// returns its global ID
+func (r *SchemaRegistry) Register(ctx context.Context, subject, schema string) (uint32, error) {
+	body, err := json.Marshal(map[string]string{"schema": schema})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode schema registration: %w", err)
+	}
+
+	u := fmt.Sprintf("%s/subjects/%s/versions", r.baseURL, url.PathEscape(subject))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create schema registry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("schema registry request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("failed to register schema for %s (%d): %s", subject, resp.StatusCode, data)
+	}
+	var result struct {
+		ID uint32 `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("failed to decode schema registry response: %w", err)
+	}
+	return result.ID, nil
+}
+
+// Schema types accepted by the registry alongside the default Avro
+const (
+	SchemaTypeAvro     = "AVRO"
+	SchemaTypeJSON     = "JSON"
+	SchemaTypeProtobuf = "PROTOBUF"
+)
+
+// subjectNotFound is the registry error code for an unknown subject
+const subjectNotFound = 40401
+
+// CheckCompatibility tests schema against the latest version registered
+// under subject with the subject's compatibility level. A subject with no
+// versions yet accepts any schema. When incompatible, the registry's reasons
+// are returned if it gave any.
+func (r *SchemaRegistry) CheckCompatibility(ctx context.Context, subject, schemaType, schema string) (bool, []string, error) {
+	request := map[string]string{"schema": schema}
+	if schemaType != "" && schemaType != SchemaTypeAvro {
+		request["schemaType"] = schemaType
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to encode compatibility check: %w", err)
+	}
+
+	u := fmt.Sprintf("%s/compatibility/subjects/%s/versions/latest?verbose=true", r.baseURL, url.PathEscape(subject))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to create schema registry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return false, nil, fmt.Errorf("schema registry request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusNotFound {
+		var registryErr struct {
+			ErrorCode int `json:"error_code"`
+		}
+		if json.Unmarshal(data, &registryErr) == nil && registryErr.ErrorCode == subjectNotFound {
+			return true, nil, nil
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, nil, fmt.Errorf("failed to check schema compatibility for %s (%d): %s", subject, resp.StatusCode, data)
+	}
+	var result struct {
+		IsCompatible bool     `json:"is_compatible"`
+		Messages     []string `json:"messages"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return false, nil, fmt.Errorf("failed to decode schema registry response: %w", err)
+	}
+	return result.IsCompatible, result.Messages, nil
+}
@@ -0,0 +1,22 @@
+package writer
+
+// SealedWriter runs a finishing step on a file once its writer has closed
+// it, such as encrypting it at rest. The step also gets the file's row count.
+type SealedWriter struct {
+	FileWriter
+	path string
+	seal func(path string, rows int64) error
+}
+
+// NewSealedWriter wraps w, which writes the file at path
+func NewSealedWriter(w FileWriter, path string, seal func(path string, rows int64) error) *SealedWriter {
+	return &SealedWriter{FileWriter: w, path: path, seal: seal}
+}
+
+// Close closes the file and then seals it
+func (w *SealedWriter) Close() error {
+	if err := w.FileWriter.Close(); err != nil {
+		return err
+	}
+	return w.seal(w.path, w.FileWriter.Count())
+}
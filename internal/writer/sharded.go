@@ -0,0 +1,173 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// FileWriter is implemented by the file writers and by the wrappers that
+// shard, rotate or duplicate them
+type FileWriter interface {
+	Write(ctx context.Context, input <-chan *models.Transaction) error
+	Close() error
+	Count() int64
+}
+
+// shardKeys extracts the routing key for key-based sharding
+var shardKeys = map[string]func(txn *models.Transaction) string{
+	"id":              func(txn *models.Transaction) string { return txn.ID },
+	"round_id":        func(txn *models.Transaction) string { return txn.RoundID },
+	"vendor_code":     func(txn *models.Transaction) string { return txn.VendorCode },
+	"house_id":        func(txn *models.Transaction) string { return strconv.Itoa(txn.HouseID) },
+	"master_agent_id": func(txn *models.Transaction) string { return strconv.Itoa(txn.MasterAgentID) },
+	"agent_id":        func(txn *models.Transaction) string { return strconv.Itoa(txn.AgentID) },
+	"currency_code":   func(txn *models.Transaction) string { return txn.CurrencyCode },
+}
+
+// PartFilename returns the file name of a shard, e.g. transactions-part-00001.csv,
+// or fills in {{seq}} when the name template has one
+func PartFilename(filename string, part int) string {
+	if strings.Contains(filename, "{{seq}}") {
+		return FileSeq(filename, part)
+	}
+	ext := fileExt(filename)
+	return fmt.Sprintf("%s-part-%05d%s", strings.TrimSuffix(filename, ext), part, ext)
+}
+
+// ShardedWriter splits the stream across several writers of the same kind,
+// each writing its own part file, so a single encoder is not the bottleneck.
+// Transactions are dealt round-robin, or by hashing a key column so that all
+// rows with the same key land in the same part.
+type ShardedWriter struct {
+	shards  []FileWriter
+	keyFunc func(txn *models.Transaction) string
+	logger  *slog.Logger
+}
+
+// NewShardedWriter creates shards writers from open, one per part file
+func NewShardedWriter(shards int, shardKey string, open func(part int) (FileWriter, error), logger *slog.Logger) (*ShardedWriter, error) {
+	if shards <= 0 {
+		return nil, fmt.Errorf("shard count must be positive: %d", shards)
+	}
+
+	var keyFunc func(txn *models.Transaction) string
+	if shardKey != "" {
+		fn, ok := shardKeys[shardKey]
+		if !ok {
+			return nil, fmt.Errorf("unsupported shard key: %s", shardKey)
+		}
+		keyFunc = fn
+	}
+
+	w := &ShardedWriter{keyFunc: keyFunc, logger: logger}
+	for part := 0; part < shards; part++ {
+		shard, err := open(part)
+		if err != nil {
+			w.Close()
+			return nil, fmt.Errorf("failed to create shard %d: %w", part, err)
+		}
+		w.shards = append(w.shards, shard)
+	}
+	return w, nil
+}
+
+// IsShardKey reports whether the column can be used as a shard key
+func IsShardKey(column string) bool {
+	_, ok := shardKeys[column]
+	return ok
+}
+
+// Write distributes transactions from the channel across the shards
+func (w *ShardedWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	channels := make([]chan *models.Transaction, len(w.shards))
+	errs := make([]error, len(w.shards))
+
+	var wg sync.WaitGroup
+	for i, shard := range w.shards {
+		channels[i] = make(chan *models.Transaction, cap(input))
+		wg.Add(1)
+		go func(i int, shard FileWriter) {
+			defer wg.Done()
+			errs[i] = shard.Write(ctx, channels[i])
+			if errs[i] != nil {
+				// Keep draining so the distributor never blocks on a failed shard
+				for range channels[i] {
+				}
+			}
+		}(i, shard)
+	}
+
+	next := 0
+	func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case txn, ok := <-input:
+				if !ok {
+					return
+				}
+				part := next
+				if w.keyFunc != nil {
+					h := fnv.New32a()
+					h.Write([]byte(w.keyFunc(txn)))
+					part = int(h.Sum32() % uint32(len(channels)))
+				} else {
+					next = (next + 1) % len(channels)
+				}
+				select {
+				case channels[part] <- txn:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	for _, ch := range channels {
+		close(ch)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("shard %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Close closes every shard
+func (w *ShardedWriter) Close() error {
+	var firstErr error
+	for i, shard := range w.shards {
+		if err := shard.Close(); err != nil {
+			w.logger.Error("Failed to close shard", "shard", i, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Count returns the number of transactions written across all shards
+func (w *ShardedWriter) Count() int64 {
+	var total int64
+	for _, shard := range w.shards {
+		total += shard.Count()
+	}
+	return total
+}
+
+// Shards returns the number of part files being written
+func (w *ShardedWriter) Shards() int {
+	return len(w.shards)
+}
@@ -0,0 +1,37 @@
+package writer
+
+import (
+	"context"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Sink is an output the pipeline feeds transactions to. Count reports what
+// it delivered and Errors what it failed to deliver without stopping.
+// CloseContext flushes and releases the output, giving up once ctx is done
+// so a hung broker or mount cannot block shutdown.
+type Sink interface {
+	Write(ctx context.Context, input <-chan *models.Transaction) error
+	CloseContext(ctx context.Context) error
+	Count() int64
+	Errors() int64
+}
+
+// FileSink returns a file output as a Sink. File writers fail on the first
+// error, so its Errors is always zero.
+func FileSink(w FileWriter) Sink {
+	return fileSink{w}
+}
+
+type fileSink struct {
+	FileWriter
+}
+
+// CloseContext closes the file writer, abandoning it once ctx is done
+func (s fileSink) CloseContext(ctx context.Context) error {
+	return CloseWithin(ctx, s.FileWriter.Close)
+}
+
+func (fileSink) Errors() int64 {
+	return 0
+}
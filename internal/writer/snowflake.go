@@ -0,0 +1,413 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// SnowflakeOptions holds connection settings for the Snowflake writer
+type SnowflakeOptions struct {
+	Account        string
+	User           string
+	PrivateKeyPath string
+	PrivateKey     string // PEM, used instead of PrivateKeyPath when set
+	Role           string
+	Warehouse      string
+	Database       string
+	Schema         string
+	Table          string
+	Method         string // SnowflakeInsert (default) or SnowflakeStreaming
+	Pipe           string // streaming: default <TABLE>-STREAMING
+	Channel        string // streaming: default message_producer
+	BatchSize      int
+	Timeout        time.Duration
+}
+
+// Snowflake load methods
+const (
+	SnowflakeInsert    = "insert"
+	SnowflakeStreaming = "streaming"
+)
+
+// SnowflakeWriter loads transactions into a Snowflake table, authenticating
+// with key-pair JWTs. The insert method submits each batch through the SQL
+// API as a single array-bound INSERT statement; the streaming method appends
+// it to a Snowpipe Streaming channel instead (see snowflake_streaming.go).
+type SnowflakeWriter struct {
+	opts        SnowflakeOptions
+	client      *http.Client
+	base        string // account URL
+	endpoint    string
+	statement   string
+	key         *rsa.PrivateKey
+	issuer      string
+	subject     string
+	token       string
+	tokenExpiry time.Time
+	stream      snowflakeStream
+	buffer      []*models.Transaction
+	count       atomic.Int64
+	errors      atomic.Int64
+	logger      *slog.Logger
+}
+
+// NewSnowflakeWriter creates a new Snowflake SQL API writer
+func NewSnowflakeWriter(opts SnowflakeOptions, logger *slog.Logger) (*SnowflakeWriter, error) {
+	pemData := []byte(opts.PrivateKey)
+	source := "private_key"
+	if opts.PrivateKey == "" {
+		data, err := os.ReadFile(opts.PrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key: %w", err)
+		}
+		pemData, source = data, opts.PrivateKeyPath
+	}
+	key, err := parseRSAPrivateKey(pemData, source)
+	if err != nil {
+		return nil, err
+	}
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode Snowflake public key: %w", err)
+	}
+	fingerprint := sha256.Sum256(pubDER)
+
+	// Account identifiers in JWT claims are upper-case without region suffix
+	account := strings.ToUpper(strings.SplitN(opts.Account, ".", 2)[0])
+	user := strings.ToUpper(opts.User)
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(transactionColumns)), ", ")
+	table := fmt.Sprintf("%s.%s.%s", opts.Database, opts.Schema, opts.Table)
+
+	if opts.Method == "" {
+		opts.Method = SnowflakeInsert
+	}
+	if opts.Pipe == "" {
+		opts.Pipe = opts.Table + "-STREAMING"
+	}
+	if opts.Channel == "" {
+		opts.Channel = "message_producer"
+	}
+
+	base := fmt.Sprintf("https://%s.snowflakecomputing.com", opts.Account)
+	return &SnowflakeWriter{
+		opts:      opts,
+		client:    &http.Client{Timeout: opts.Timeout},
+		base:      base,
+		endpoint:  base + "/api/v2/statements",
+		statement: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(transactionColumns, ", "), placeholders),
+		key:       key,
+		issuer:    fmt.Sprintf("%s.%s.SHA256:%s", account, user, base64.StdEncoding.EncodeToString(fingerprint[:])),
+		subject:   fmt.Sprintf("%s.%s", account, user),
+		buffer:    make([]*models.Transaction, 0, opts.BatchSize),
+		logger:    logger,
+	}, nil
+}
+
+func parseRSAPrivateKey(data []byte, source string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode private key PEM: %s", source)
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if rsaKey, pkcs1Err := x509.ParsePKCS1PrivateKey(block.Bytes); pkcs1Err == nil {
+			return rsaKey, nil
+		}
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not RSA: %s", source)
+	}
+	return key, nil
+}
+
+// Ping runs a zero-row query against the target table, which exercises the
+// key pair, role, warehouse and table privileges without loading data. With
+// the streaming method it reads the channel's status from the pipe instead.
+func (w *SnowflakeWriter) Ping(ctx context.Context) error {
+	if w.opts.Method == SnowflakeStreaming {
+		_, err := w.channelStatus(ctx)
+		return err
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"statement": fmt.Sprintf("SELECT 1 FROM %s.%s.%s LIMIT 0", w.opts.Database, w.opts.Schema, w.opts.Table),
+		"timeout":   int(w.opts.Timeout.Seconds()),
+		"warehouse": w.opts.Warehouse,
+		"database":  w.opts.Database,
+		"schema":    w.opts.Schema,
+		"role":      w.opts.Role,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode Snowflake request: %w", err)
+	}
+	return w.execute(ctx, body)
+}
+
+// jwt returns a cached key-pair token, re-signing shortly before expiry
+func (w *SnowflakeWriter) jwt() (string, error) {
+	now := time.Now()
+	if w.token != "" && now.Before(w.tokenExpiry.Add(-5*time.Minute)) {
+		return w.token, nil
+	}
+
+	expiry := now.Add(time.Hour)
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": w.issuer,
+		"sub": w.subject,
+		"iat": now.Unix(),
+		"exp": expiry.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, w.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign Snowflake JWT: %w", err)
+	}
+
+	w.token = signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+	w.tokenExpiry = expiry
+	return w.token, nil
+}
+
+// Write writes transactions from the channel to Snowflake
+func (w *SnowflakeWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return w.flush(context.Background())
+		case txn, ok := <-input:
+			if !ok {
+				// Channel closed, flush remaining buffer
+				return w.flush(ctx)
+			}
+
+			w.buffer = append(w.buffer, txn)
+			if len(w.buffer) >= w.opts.BatchSize {
+				if err := w.flush(ctx); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// WriteBatch inserts batch, plus anything already buffered, in one request
+func (w *SnowflakeWriter) WriteBatch(ctx context.Context, batch []*models.Transaction) error {
+	w.buffer = append(w.buffer, batch...)
+	return w.flush(ctx)
+}
+
+type snowflakeBinding struct {
+	Type  string   `json:"type"`
+	Value []string `json:"value"`
+}
+
+func (w *SnowflakeWriter) flush(ctx context.Context) error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+	if w.opts.Method == SnowflakeStreaming {
+		return w.appendRows(ctx)
+	}
+
+	bindings := make(map[string]snowflakeBinding, len(transactionColumns))
+	for i := range transactionColumns {
+		values := make([]string, len(w.buffer))
+		for j, txn := range w.buffer {
+			values[j] = snowflakeValue(txn, i)
+		}
+		bindingType := "TEXT"
+		if snowflakeIsFixed(i) {
+			bindingType = "FIXED"
+		}
+		bindings[strconv.Itoa(i+1)] = snowflakeBinding{Type: bindingType, Value: values}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"statement": w.statement,
+		"timeout":   int(w.opts.Timeout.Seconds()),
+		"warehouse": w.opts.Warehouse,
+		"database":  w.opts.Database,
+		"schema":    w.opts.Schema,
+		"role":      w.opts.Role,
+		"bindings":  bindings,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode Snowflake request: %w", err)
+	}
+
+	if err := w.execute(ctx, body); err != nil {
+		// Drop the failed batch so Close does not resubmit it
+		w.errors.Add(int64(len(w.buffer)))
+		w.buffer = w.buffer[:0]
+		return err
+	}
+
+	w.count.Add(int64(len(w.buffer)))
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+// execute submits a statement and polls until it completes
+func (w *SnowflakeWriter) execute(ctx context.Context, body []byte) error {
+	resp, err := w.do(ctx, http.MethodPost, w.endpoint, body)
+	if err != nil {
+		return err
+	}
+
+	// 202 means the statement is still running; poll its status URL
+	for resp.status == http.StatusAccepted {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+		resp, err = w.do(ctx, http.MethodGet, w.endpoint+"/"+resp.StatementHandle, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	if resp.status != http.StatusOK {
+		return fmt.Errorf("snowflake statement failed (%d): %s %s", resp.status, resp.Code, resp.Message)
+	}
+	return nil
+}
+
+type snowflakeResponse struct {
+	status          int
+	Code            string `json:"code"`
+	Message         string `json:"message"`
+	StatementHandle string `json:"statementHandle"`
+}
+
+func (w *SnowflakeWriter) do(ctx context.Context, method, url string, body []byte) (*snowflakeResponse, error) {
+	token, err := w.jwt()
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Snowflake request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Snowflake-Authorization-Token-Type", "KEYPAIR_JWT")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	httpResp, err := w.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("snowflake request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	resp := &snowflakeResponse{status: httpResp.StatusCode}
+	if err := json.NewDecoder(httpResp.Body).Decode(resp); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to decode Snowflake response: %w", err)
+	}
+	return resp, nil
+}
+
+func snowflakeIsFixed(column int) bool {
+	switch transactionColumns[column] {
+	case "vendor_id", "vendor_line_id", "game_category_id", "house_id",
+		"master_agent_id", "agent_id", "currency_id":
+		return true
+	}
+	return false
+}
+
+func snowflakeValue(txn *models.Transaction, column int) string {
+	switch column {
+	case 0:
+		return txn.ID
+	case 1:
+		return txn.ExternalTransactionID
+	case 2:
+		return txn.VendorBetID
+	case 3:
+		return txn.RoundID
+	case 4:
+		return strconv.Itoa(txn.VendorID)
+	case 5:
+		return txn.VendorCode
+	case 6:
+		return strconv.Itoa(txn.VendorLineID)
+	case 7:
+		return strconv.Itoa(txn.GameCategoryID)
+	case 8:
+		return strconv.Itoa(txn.HouseID)
+	case 9:
+		return strconv.Itoa(txn.MasterAgentID)
+	case 10:
+		return strconv.Itoa(txn.AgentID)
+	case 11:
+		return strconv.Itoa(txn.CurrencyID)
+	case 12:
+		return txn.CurrencyCode
+	case 13:
+		return txn.BetAmount
+	case 14:
+		return txn.WinAmount
+	case 15:
+		return txn.WinLoss
+	default:
+		return txn.SettledAt
+	}
+}
+
+// CloseContext flushes remaining rows, cancelling the load once ctx is done.
+// A streaming channel is then waited on until Snowflake has committed every
+// row appended to it.
+func (w *SnowflakeWriter) CloseContext(ctx context.Context) error {
+	if err := w.flush(ctx); err != nil {
+		return err
+	}
+	if w.opts.Method == SnowflakeStreaming {
+		return w.waitCommitted(ctx)
+	}
+	return nil
+}
+
+// Count returns the number of transactions written
+func (w *SnowflakeWriter) Count() int64 {
+	return w.count.Load()
+}
+
+// Errors returns the number of transactions that failed to load
+func (w *SnowflakeWriter) Errors() int64 {
+	return w.errors.Load()
+}
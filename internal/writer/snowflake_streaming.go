@@ -0,0 +1,270 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The streaming method loads through the Snowpipe Streaming REST API: the
+// account URL names the ingest host, the key-pair JWT is exchanged for a
+// token scoped to that host, and the writer opens a channel on the table's
+// pipe and appends each batch to it as NDJSON. Rows need no warehouse and
+// are committed by Snowflake within seconds; CloseContext waits for the
+// commit of the last batch. Reopening a channel fences any other writer
+// still appending to it, so concurrent producers need their own channel.
+
+// snowflakeStream is the state of an open streaming channel
+type snowflakeStream struct {
+	host         string // ingest host URL
+	token        string // scoped to host
+	exchanged    string // the JWT token was exchanged from
+	channel      string // channel URL
+	continuation string
+	offset       int64 // offset token of the last appended batch
+	baseErrors   int64 // rows_error_count when the channel was opened
+}
+
+type snowflakeChannelStatus struct {
+	StatusCode           string `json:"channel_status_code"`
+	CommittedOffsetToken string `json:"last_committed_offset_token"`
+	RowsErrorCount       int64  `json:"rows_error_count"`
+	LastErrorMessage     string `json:"last_error_message"`
+}
+
+// authorize discovers the ingest host and returns a token scoped to it,
+// exchanging a fresh one whenever the JWT is re-signed
+func (w *SnowflakeWriter) authorize(ctx context.Context) error {
+	jwt, err := w.jwt()
+	if err != nil {
+		return err
+	}
+	if w.stream.host != "" && w.stream.exchanged == jwt {
+		return nil
+	}
+
+	if w.stream.host == "" {
+		host, err := w.send(ctx, http.MethodGet, w.base+"/v2/streaming/hostname", "", "KEYPAIR_JWT", jwt, nil)
+		if err != nil {
+			return fmt.Errorf("failed to discover Snowpipe Streaming host: %w", err)
+		}
+		w.stream.host = "https://" + strings.TrimSpace(string(host))
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"scope":      {strings.TrimPrefix(w.stream.host, "https://")},
+		"assertion":  {jwt},
+	}
+	token, err := w.send(ctx, http.MethodPost, w.base+"/oauth/token", "application/x-www-form-urlencoded", "", "", []byte(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to exchange Snowflake JWT for a streaming token: %w", err)
+	}
+	w.stream.token = strings.TrimSpace(string(token))
+	w.stream.exchanged = jwt
+	return nil
+}
+
+// pipeURL is the REST path of the table's pipe on the ingest host
+func (w *SnowflakeWriter) pipeURL(prefix string) string {
+	return fmt.Sprintf("%s/v2/streaming/%sdatabases/%s/schemas/%s/pipes/%s", w.stream.host, prefix,
+		url.PathEscape(w.opts.Database), url.PathEscape(w.opts.Schema), url.PathEscape(w.opts.Pipe))
+}
+
+// openChannel opens (or reopens) the writer's channel. Offset tokens carry
+// on from the last one the channel committed.
+func (w *SnowflakeWriter) openChannel(ctx context.Context) error {
+	if err := w.authorize(ctx); err != nil {
+		return err
+	}
+	channel := w.pipeURL("") + "/channels/" + url.PathEscape(w.opts.Channel)
+	body, err := w.send(ctx, http.MethodPut, channel, "application/json", "OAUTH", w.stream.token, []byte("{}"))
+	if err != nil {
+		return fmt.Errorf("failed to open Snowpipe Streaming channel %s: %w", w.opts.Channel, err)
+	}
+
+	var opened struct {
+		NextContinuationToken string                 `json:"next_continuation_token"`
+		ChannelStatus         snowflakeChannelStatus `json:"channel_status"`
+	}
+	if err := json.Unmarshal(body, &opened); err != nil {
+		return fmt.Errorf("failed to decode Snowpipe Streaming channel: %w", err)
+	}
+	w.stream.channel = channel
+	w.stream.continuation = opened.NextContinuationToken
+	w.stream.offset, _ = strconv.ParseInt(opened.ChannelStatus.CommittedOffsetToken, 10, 64)
+	w.stream.baseErrors = opened.ChannelStatus.RowsErrorCount
+
+	w.logger.Info("Snowpipe Streaming channel opened",
+		"pipe", w.opts.Pipe,
+		"channel", w.opts.Channel,
+		"offset", w.stream.offset,
+	)
+	return nil
+}
+
+// appendRows appends the buffer to the channel as one NDJSON request
+func (w *SnowflakeWriter) appendRows(ctx context.Context) error {
+	if w.stream.channel == "" {
+		if err := w.openChannel(ctx); err != nil {
+			w.errors.Add(int64(len(w.buffer)))
+			w.buffer = w.buffer[:0]
+			return err
+		}
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	row := make(map[string]interface{}, len(transactionColumns))
+	for _, txn := range w.buffer {
+		for i, column := range transactionColumns {
+			if value := snowflakeValue(txn, i); snowflakeIsFixed(i) {
+				row[column] = json.Number(value)
+			} else {
+				row[column] = value
+			}
+		}
+		if err := enc.Encode(row); err != nil {
+			return fmt.Errorf("failed to encode Snowflake row: %w", err)
+		}
+	}
+
+	err := w.authorize(ctx)
+	if err == nil {
+		offset := w.stream.offset + 1
+		query := url.Values{
+			"continuationToken": {w.stream.continuation},
+			"offsetToken":       {strconv.FormatInt(offset, 10)},
+		}
+		rowsURL := w.pipeURL("data/") + "/channels/" + url.PathEscape(w.opts.Channel) + "/rows?" + query.Encode()
+		var resp []byte
+		if resp, err = w.send(ctx, http.MethodPost, rowsURL, "application/x-ndjson", "OAUTH", w.stream.token, body.Bytes()); err == nil {
+			var appended struct {
+				NextContinuationToken string `json:"next_continuation_token"`
+			}
+			if err = json.Unmarshal(resp, &appended); err == nil {
+				w.stream.continuation = appended.NextContinuationToken
+				w.stream.offset = offset
+			}
+		}
+	}
+	if err != nil {
+		// Drop the failed batch so Close does not resubmit it
+		w.errors.Add(int64(len(w.buffer)))
+		w.buffer = w.buffer[:0]
+		return fmt.Errorf("failed to append rows to Snowpipe Streaming channel %s: %w", w.opts.Channel, err)
+	}
+
+	w.count.Add(int64(len(w.buffer)))
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+// channelStatus reads the writer's channel status from the pipe
+func (w *SnowflakeWriter) channelStatus(ctx context.Context) (*snowflakeChannelStatus, error) {
+	if err := w.authorize(ctx); err != nil {
+		return nil, err
+	}
+	req, err := json.Marshal(map[string][]string{"channel_names": {w.opts.Channel}})
+	if err != nil {
+		return nil, err
+	}
+	body, err := w.send(ctx, http.MethodPost, w.pipeURL("")+":bulk-channel-status", "application/json", "OAUTH", w.stream.token, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Snowpipe Streaming channel status: %w", err)
+	}
+
+	var statuses struct {
+		ChannelStatuses map[string]snowflakeChannelStatus `json:"channel_statuses"`
+	}
+	if err := json.Unmarshal(body, &statuses); err != nil {
+		return nil, fmt.Errorf("failed to decode Snowpipe Streaming channel status: %w", err)
+	}
+	for name, status := range statuses.ChannelStatuses {
+		if strings.EqualFold(name, w.opts.Channel) {
+			return &status, nil
+		}
+	}
+	return &snowflakeChannelStatus{}, nil
+}
+
+// waitCommitted polls the channel until its committed offset token reaches
+// the last appended batch, within Timeout. Rows Snowflake rejected while
+// committing move from Count to Errors.
+func (w *SnowflakeWriter) waitCommitted(ctx context.Context) error {
+	if w.stream.channel == "" {
+		return nil
+	}
+	if w.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.opts.Timeout)
+		defer cancel()
+	}
+
+	want := strconv.FormatInt(w.stream.offset, 10)
+	for {
+		status, err := w.channelStatus(ctx)
+		if err != nil {
+			return err
+		}
+		if status.CommittedOffsetToken == want {
+			if rejected := status.RowsErrorCount - w.stream.baseErrors; rejected > 0 {
+				w.count.Add(-rejected)
+				w.errors.Add(rejected)
+				return fmt.Errorf("snowflake rejected %d streamed rows: %s", rejected, status.LastErrorMessage)
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("snowpipe streaming channel %s committed offset %q of %s: %w", w.opts.Channel, status.CommittedOffsetToken, want, ctx.Err())
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// send makes one request, authenticated when token is set, and returns the
+// body of a 2xx response
+func (w *SnowflakeWriter) send(ctx context.Context, method, url, contentType, tokenType, token string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Snowflake request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Snowflake-Authorization-Token-Type", tokenType)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("snowflake request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Snowflake response: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		var failure snowflakeResponse
+		if json.Unmarshal(data, &failure) == nil && failure.Message != "" {
+			return nil, fmt.Errorf("%d: %s %s", resp.StatusCode, failure.Code, failure.Message)
+		}
+		return nil, fmt.Errorf("%d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
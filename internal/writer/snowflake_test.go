@@ -0,0 +1,206 @@
+package writer
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// fakeSnowpipe serves the account and ingest host endpoints of the
+// Snowpipe Streaming REST API on one TLS server
+type fakeSnowpipe struct {
+	*httptest.Server
+	mu        sync.Mutex
+	opened    int
+	appends   []int // rows per append
+	offset    string
+	rejected  int64
+	vendorIDs []interface{}
+}
+
+func newFakeSnowpipe(t *testing.T, committed string) *fakeSnowpipe {
+	f := &fakeSnowpipe{offset: committed}
+	const pipe = "/v2/streaming/databases/DB/schemas/PUBLIC/pipes/TRANSACTIONS-STREAMING"
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/streaming/hostname", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Snowflake-Authorization-Token-Type") != "KEYPAIR_JWT" {
+			http.Error(w, "no JWT", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, strings.TrimPrefix(f.URL, "https://"))
+	})
+	mux.HandleFunc("POST /oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || r.FormValue("assertion") == "" {
+			http.Error(w, "bad grant", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, "scoped-token")
+	})
+	scoped := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer scoped-token" {
+				http.Error(w, `{"code":"390303","message":"Invalid OAuth access token"}`, http.StatusUnauthorized)
+				return
+			}
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			next(w, r)
+		}
+	}
+	mux.HandleFunc("PUT "+pipe+"/channels/message_producer", scoped(func(w http.ResponseWriter, r *http.Request) {
+		f.opened++
+		fmt.Fprintf(w, `{"next_continuation_token":"c0","channel_status":{"last_committed_offset_token":%q,"rows_error_count":2}}`, f.offset)
+	}))
+	mux.HandleFunc("POST /v2/streaming/data/databases/DB/schemas/PUBLIC/pipes/TRANSACTIONS-STREAMING/channels/message_producer/rows", scoped(func(w http.ResponseWriter, r *http.Request) {
+		next, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Query().Get("continuationToken"), "c"))
+		if r.URL.Query().Get("continuationToken") != fmt.Sprintf("c%d", len(f.appends)) {
+			http.Error(w, `{"code":"STALE_CONTINUATION_TOKEN_SEQUENCER","message":"stale token"}`, http.StatusBadRequest)
+			return
+		}
+		rows := 0
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var row map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			f.vendorIDs = append(f.vendorIDs, row["vendor_id"])
+			rows++
+		}
+		f.appends = append(f.appends, rows)
+		f.offset = r.URL.Query().Get("offsetToken")
+		fmt.Fprintf(w, `{"next_continuation_token":"c%d"}`, next+1)
+	}))
+	mux.HandleFunc("POST "+pipe+":bulk-channel-status", scoped(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"channel_statuses":{"MESSAGE_PRODUCER":{"channel_status_code":"SUCCESS","last_committed_offset_token":%q,"rows_error_count":%d,"last_error_message":"bad settled_at"}}}`,
+			f.offset, 2+f.rejected)
+	}))
+	f.Server = httptest.NewTLSServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func testSnowflakeKey(t *testing.T) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func newStreamingWriter(t *testing.T, f *fakeSnowpipe) *SnowflakeWriter {
+	t.Helper()
+	w, err := NewSnowflakeWriter(SnowflakeOptions{
+		Account:    "myorg-acct",
+		User:       "loader",
+		PrivateKey: testSnowflakeKey(t),
+		Database:   "DB",
+		Schema:     "PUBLIC",
+		Table:      "TRANSACTIONS",
+		Method:     SnowflakeStreaming,
+		BatchSize:  3,
+		Timeout:    5 * time.Second,
+	}, discardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.base = f.URL
+	w.client = f.Client()
+	return w
+}
+
+func TestSnowflakeStreamingAppends(t *testing.T) {
+	f := newFakeSnowpipe(t, "4")
+	w := newStreamingWriter(t, f)
+	if err := w.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	input := make(chan *models.Transaction, 7)
+	for i := 0; i < 7; i++ {
+		input <- &models.Transaction{ID: fmt.Sprintf("TXN-%d", i), VendorID: 7, BetAmount: "12.50", SettledAt: "2024-03-01T10:00:00+02:00"}
+	}
+	close(input)
+	if err := w.Write(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.CloseContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.opened != 1 || fmt.Sprint(f.appends) != "[3 3 1]" {
+		t.Errorf("opened %d channels, appended %v; want 1 and [3 3 1]", f.opened, f.appends)
+	}
+	// Offset tokens carry on from the one the channel last committed
+	if f.offset != "7" {
+		t.Errorf("last offset token = %s, want 7", f.offset)
+	}
+	if f.vendorIDs[0] != float64(7) {
+		t.Errorf("vendor_id = %#v, want a number", f.vendorIDs[0])
+	}
+	if w.Count() != 7 || w.Errors() != 0 {
+		t.Errorf("count = %d, errors = %d", w.Count(), w.Errors())
+	}
+}
+
+func TestSnowflakeStreamingRejectedRows(t *testing.T) {
+	f := newFakeSnowpipe(t, "")
+	f.rejected = 2
+	w := newStreamingWriter(t, f)
+	if err := w.WriteBatch(context.Background(), []*models.Transaction{{ID: "TXN-1"}, {ID: "TXN-2"}, {ID: "TXN-3"}}); err != nil {
+		t.Fatal(err)
+	}
+	// Errors already on the channel when it was opened are not this run's
+	err := w.CloseContext(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "rejected 2 streamed rows: bad settled_at") {
+		t.Errorf("err = %v", err)
+	}
+	if w.Count() != 1 || w.Errors() != 2 {
+		t.Errorf("count = %d, errors = %d, want 1 and 2", w.Count(), w.Errors())
+	}
+}
+
+func TestSnowflakeStreamingAppendFails(t *testing.T) {
+	f := newFakeSnowpipe(t, "")
+	w := newStreamingWriter(t, f)
+	w.stream.host = f.URL
+	w.stream.channel = "stale"
+	w.stream.continuation = "c9"
+	w.stream.token, w.stream.exchanged = "scoped-token", "expired"
+
+	err := w.WriteBatch(context.Background(), []*models.Transaction{{ID: "TXN-1"}, {ID: "TXN-2"}})
+	if err == nil || !strings.Contains(err.Error(), "stale token") {
+		t.Errorf("err = %v", err)
+	}
+	if w.Count() != 0 || w.Errors() != 2 {
+		t.Errorf("count = %d, errors = %d, want 0 and 2", w.Count(), w.Errors())
+	}
+	// The failed batch is dropped, not resubmitted on close, and nothing
+	// appended before it ever commits
+	w.opts.Timeout = 100 * time.Millisecond
+	if err := w.CloseContext(context.Background()); err == nil {
+		t.Error("close succeeded on a channel that never committed")
+	}
+}
@@ -0,0 +1,223 @@
+package writer
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// SocketWriter streams transactions to a TCP or Unix domain socket
+type SocketWriter struct {
+	network     string
+	address     string
+	framing     string
+	dialTimeout time.Duration
+	conn        net.Conn
+	writer      *bufio.Writer
+	lenBuf      [4]byte
+	// pending holds the records buffered since the last successful flush,
+	// counted once a flush delivers them and re-sent after a reconnect
+	pending [][]byte
+	count   atomic.Int64
+	errors  atomic.Int64
+	logger  *slog.Logger
+}
+
+// NewSocketWriter creates a new socket writer. Framing is either "ndjson"
+// (newline-delimited JSON) or "length_prefixed" (4-byte big-endian length
+// followed by the JSON payload).
+func NewSocketWriter(network, address, framing string, bufferSize int, dialTimeout time.Duration, logger *slog.Logger) (*SocketWriter, error) {
+	if network != "tcp" && network != "unix" {
+		return nil, fmt.Errorf("unsupported socket network: %s", network)
+	}
+	if framing != "ndjson" && framing != "length_prefixed" {
+		return nil, fmt.Errorf("unsupported socket framing: %s", framing)
+	}
+
+	w := &SocketWriter{
+		network:     network,
+		address:     address,
+		framing:     framing,
+		dialTimeout: dialTimeout,
+		logger:      logger,
+	}
+	if err := w.connect(bufferSize); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *SocketWriter) connect(bufferSize int) error {
+	conn, err := net.DialTimeout(w.network, w.address, w.dialTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s %s: %w", w.network, w.address, err)
+	}
+	w.conn = conn
+	w.writer = bufio.NewWriterSize(conn, bufferSize)
+	return nil
+}
+
+// Write writes transactions from the channel to the socket
+func (w *SocketWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return w.flush()
+		case txn, ok := <-input:
+			if !ok {
+				// Channel closed, flush what is buffered
+				return w.flush()
+			}
+
+			if err := w.writeTransaction(txn); err != nil {
+				return err
+			}
+
+			// Flush when the producer side is idle so readers see records promptly
+			if len(input) == 0 {
+				if err := w.flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// WriteBatch writes batch and flushes it to the socket
+func (w *SocketWriter) WriteBatch(ctx context.Context, batch []*models.Transaction) error {
+	for _, txn := range batch {
+		if err := w.writeTransaction(txn); err != nil {
+			return err
+		}
+	}
+	return w.flush()
+}
+
+// writeTransaction encodes and buffers txn, reconnecting if the write fails.
+// The buffer is flushed before it would fill, so bufio never sends part of
+// it behind the writer's back.
+func (w *SocketWriter) writeTransaction(txn *models.Transaction) error {
+	data, err := json.Marshal(txn)
+	if err != nil {
+		w.errors.Add(1)
+		return nil
+	}
+
+	if w.writer.Buffered() > 0 && w.recordSize(data) > w.writer.Available() {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+	w.pending = append(w.pending, data)
+	if err := w.writeRecord(data); err != nil {
+		return w.resend(err)
+	}
+	return nil
+}
+
+// recordSize is the framed size of a record
+func (w *SocketWriter) recordSize(data []byte) int {
+	if w.framing == "length_prefixed" {
+		return len(w.lenBuf) + len(data)
+	}
+	return len(data) + 1
+}
+
+func (w *SocketWriter) writeRecord(data []byte) error {
+	if w.framing == "length_prefixed" {
+		binary.BigEndian.PutUint32(w.lenBuf[:], uint32(len(data)))
+		if _, err := w.writer.Write(w.lenBuf[:]); err != nil {
+			return err
+		}
+		_, err := w.writer.Write(data)
+		return err
+	}
+
+	if _, err := w.writer.Write(data); err != nil {
+		return err
+	}
+	return w.writer.WriteByte('\n')
+}
+
+func (w *SocketWriter) reconnect() error {
+	size := w.writer.Size()
+	w.conn.Close()
+	if err := w.connect(size); err != nil {
+		return err
+	}
+	return nil
+}
+
+// resend reconnects after a failed write or flush and sends the pending
+// records again on the new connection. Only a failed reconnect or resend
+// is returned, with the pending records counted as errors.
+func (w *SocketWriter) resend(cause error) error {
+	w.logger.Warn("Socket write failed, reconnecting", "address", w.address, "pending", len(w.pending), "error", cause)
+	err := w.reconnect()
+	if err == nil {
+		for _, data := range w.pending {
+			if err = w.writeRecord(data); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = w.writer.Flush()
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to resend %d records to %s: %w", len(w.pending), w.address, err)
+		}
+	}
+	if err != nil {
+		w.errors.Add(int64(len(w.pending)))
+		w.pending = w.pending[:0]
+		return err
+	}
+	w.count.Add(int64(len(w.pending)))
+	w.pending = w.pending[:0]
+	return nil
+}
+
+// flush sends the buffered records, counting them once the flush succeeds
+func (w *SocketWriter) flush() error {
+	if err := w.writer.Flush(); err != nil {
+		return w.resend(err)
+	}
+	w.count.Add(int64(len(w.pending)))
+	w.pending = w.pending[:0]
+	return nil
+}
+
+// CloseContext flushes buffered records and closes the connection. Once ctx is done
+// the connection is closed without waiting for the flush. A failed final
+// flush is not retried on a new connection; its records count as errors.
+func (w *SocketWriter) CloseContext(ctx context.Context) error {
+	return closeConnWithin(ctx, w.conn, func() error {
+		err := w.writer.Flush()
+		if err != nil {
+			w.errors.Add(int64(len(w.pending)))
+			err = fmt.Errorf("failed to flush socket writer: %w", err)
+		} else {
+			w.count.Add(int64(len(w.pending)))
+		}
+		w.pending = w.pending[:0]
+		return err
+	})
+}
+
+// Count returns the number of transactions written
+func (w *SocketWriter) Count() int64 {
+	return w.count.Load()
+}
+
+// Errors returns the number of errors encountered
+func (w *SocketWriter) Errors() int64 {
+	return w.errors.Load()
+}
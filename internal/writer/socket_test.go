@@ -0,0 +1,164 @@
+package writer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// socketPeer accepts connections and records the ndjson lines it reads
+type socketPeer struct {
+	net.Listener
+	mu    sync.Mutex
+	lines []string
+	conns int
+}
+
+func newSocketPeer(t *testing.T) *socketPeer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &socketPeer{Listener: l}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			p.mu.Lock()
+			p.conns++
+			p.mu.Unlock()
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					p.mu.Lock()
+					p.lines = append(p.lines, scanner.Text())
+					p.mu.Unlock()
+				}
+			}()
+		}
+	}()
+	return p
+}
+
+// received waits for n lines and returns them
+func (p *socketPeer) received(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		p.mu.Lock()
+		lines := append([]string(nil), p.lines...)
+		p.mu.Unlock()
+		if len(lines) >= n || time.Now().After(deadline) {
+			return lines
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// brokenConn fails every write, like a connection the peer reset
+type brokenConn struct{ net.Conn }
+
+func (brokenConn) Write([]byte) (int, error) { return 0, errors.New("connection reset by peer") }
+func (brokenConn) Close() error              { return nil }
+
+func socketBatch(n int) []*models.Transaction {
+	batch := make([]*models.Transaction, n)
+	for i := range batch {
+		batch[i] = &models.Transaction{ID: fmt.Sprintf("TXN-%d", i)}
+	}
+	return batch
+}
+
+func TestSocketWriterCountsFlushedRecords(t *testing.T) {
+	peer := newSocketPeer(t)
+	w, err := NewSocketWriter("tcp", peer.Addr().String(), "ndjson", 256, time.Second, discardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Small enough buffer that the batch takes several flushes
+	if err := w.WriteBatch(context.Background(), socketBatch(20)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.CloseContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	lines := peer.received(t, 20)
+	if len(lines) != 20 || w.Count() != 20 || w.Errors() != 0 {
+		t.Errorf("peer got %d lines, count = %d, errors = %d", len(lines), w.Count(), w.Errors())
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, fmt.Sprintf(`"TXN-%d"`, i)) {
+			t.Errorf("line %d = %s", i, line)
+		}
+	}
+}
+
+func TestSocketWriterResendsAfterReconnect(t *testing.T) {
+	peer := newSocketPeer(t)
+	w, err := NewSocketWriter("tcp", peer.Addr().String(), "ndjson", 4096, time.Second, discardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The connection breaks with three records buffered: none reached the
+	// peer, so none may be counted yet
+	w.conn.Close()
+	w.writer.Reset(brokenConn{w.conn})
+	for _, txn := range socketBatch(3) {
+		if err := w.writeTransaction(txn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w.Count() != 0 {
+		t.Fatalf("count = %d before any flush", w.Count())
+	}
+
+	if err := w.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.CloseContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	lines := peer.received(t, 3)
+	if len(lines) != 3 || w.Count() != 3 || w.Errors() != 0 {
+		t.Errorf("peer got %d lines, count = %d, errors = %d", len(lines), w.Count(), w.Errors())
+	}
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+	if peer.conns != 2 {
+		t.Errorf("%d connections, want 2", peer.conns)
+	}
+}
+
+func TestSocketWriterCountsLostRecordsAsErrors(t *testing.T) {
+	peer := newSocketPeer(t)
+	w, err := NewSocketWriter("tcp", peer.Addr().String(), "ndjson", 4096, time.Second, discardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.writer.Reset(brokenConn{w.conn})
+	for _, txn := range socketBatch(3) {
+		if err := w.writeTransaction(txn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Nowhere to reconnect to
+	peer.Close()
+	if err := w.flush(); err == nil {
+		t.Fatal("flush succeeded without a peer")
+	}
+	if w.Count() != 0 || w.Errors() != 3 {
+		t.Errorf("count = %d, errors = %d, want 0 and 3", w.Count(), w.Errors())
+	}
+}
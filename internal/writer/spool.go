@@ -0,0 +1,241 @@
+package writer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// errSpoolFull is returned by Append once the spool holds its limit
+var errSpoolFull = errors.New("spool is full")
+
+// errForwardStopped ends a replay when the writer closes
+var errForwardStopped = errors.New("forwarding stopped")
+
+// SpoolRecord is one message held in a spool: the encoded key, value and
+// headers exactly as they were to be sent
+type SpoolRecord struct {
+	Key     []byte        `json:"key,omitempty"`
+	Value   []byte        `json:"value"`
+	Headers []SpoolHeader `json:"headers,omitempty"`
+}
+
+// SpoolHeader is a message header of a SpoolRecord
+type SpoolHeader struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Spool keeps messages a sink could not deliver in NDJSON segment files,
+// oldest first, until they are replayed. Appends go to the active segment;
+// Seal closes it so it can be replayed while new appends start the next
+// one. Segments left by an earlier run are picked up again, so a backlog
+// survives a restart.
+type Spool struct {
+	dir     string
+	limit   int64 // bytes, 0 = unlimited
+	mu      sync.Mutex
+	file    *os.File // active segment, nil until the next append
+	buf     *bufio.Writer
+	seq     int   // sequence number of the active or next segment
+	size    int64 // bytes in every segment
+	line    []byte
+	records atomic.Int64
+	dropped atomic.Int64
+	logger  *slog.Logger
+}
+
+// OpenSpool opens the spool in dir, creating dir if needed. Beyond limit
+// bytes, 0 = unlimited, appends are dropped.
+func OpenSpool(dir string, limit int64, logger *slog.Logger) (*Spool, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	s := &Spool{dir: dir, limit: limit, logger: logger}
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range segments {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spool: %w", err)
+		}
+		s.size += info.Size()
+		s.seq = max(s.seq, spoolSeq(path)+1)
+	}
+	return s, nil
+}
+
+// spoolSegment names the segment with sequence number seq; the zero
+// padding keeps names in sequence order
+func spoolSegment(seq int) string {
+	return fmt.Sprintf("spool-%010d.ndjson", seq)
+}
+
+// spoolSeq returns the sequence number of a segment path
+func spoolSeq(path string) int {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "spool-"), ".ndjson")
+	seq, _ := strconv.Atoi(name)
+	return seq
+}
+
+// segments lists every segment file, oldest first
+func (s *Spool) segments() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "spool-*.ndjson"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list spool: %w", err)
+	}
+	slices.Sort(paths)
+	return paths, nil
+}
+
+// Append adds rec to the active segment
+func (s *Spool) Append(rec SpoolRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.limit > 0 && s.size >= s.limit {
+		s.dropped.Add(1)
+		return errSpoolFull
+	}
+	if s.file == nil {
+		file, err := os.OpenFile(filepath.Join(s.dir, spoolSegment(s.seq)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open spool segment: %w", err)
+		}
+		s.file = file
+		s.buf = bufio.NewWriterSize(retryWrites(file, s.logger), 64*1024)
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode spool record: %w", err)
+	}
+	s.line = append(append(s.line[:0], line...), '\n')
+	if _, err := s.buf.Write(s.line); err != nil {
+		return fmt.Errorf("failed to write spool: %w", err)
+	}
+	s.size += int64(len(s.line))
+	s.records.Add(1)
+	return nil
+}
+
+// Seal closes the active segment, if any, making it ready to replay
+func (s *Spool) Seal() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seal()
+}
+
+func (s *Spool) seal() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.buf.Flush()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	s.file, s.buf = nil, nil
+	s.seq++
+	if err != nil {
+		return fmt.Errorf("failed to seal spool segment: %w", err)
+	}
+	return nil
+}
+
+// Sealed lists the segments ready to replay, oldest first
+func (s *Spool) Sealed() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	if s.file != nil {
+		active := filepath.Join(s.dir, spoolSegment(s.seq))
+		segments = slices.DeleteFunc(segments, func(path string) bool { return path == active })
+	}
+	return segments, nil
+}
+
+// Replay hands each record of a sealed segment to send, in order, and
+// removes the segment once all of them were taken. If send fails the
+// segment stays and is replayed again from the start later.
+func (s *Spool) Replay(path string, send func(SpoolRecord) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open spool segment: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read spool segment: %w", err)
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64<<20)
+	for scanner.Scan() {
+		var rec SpoolRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// A torn last line from a crash; the records before it count
+			s.logger.Warn("Skipping unreadable spool record", "segment", path, "error", err)
+			continue
+		}
+		if err := send(rec); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read spool segment: %w", err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove replayed spool segment: %w", err)
+	}
+	s.mu.Lock()
+	s.size -= info.Size()
+	s.mu.Unlock()
+	return nil
+}
+
+// Backlog returns the bytes waiting in the spool
+func (s *Spool) Backlog() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// Full reports whether the spool holds its limit, so Append would drop
+func (s *Spool) Full() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limit > 0 && s.size >= s.limit
+}
+
+// Records returns the number of records appended
+func (s *Spool) Records() int64 {
+	return s.records.Load()
+}
+
+// Dropped returns the number of records dropped because the spool was full
+func (s *Spool) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close seals the active segment. What is still spooled stays on disk for
+// the next run.
+func (s *Spool) Close() error {
+	return s.Seal()
+}
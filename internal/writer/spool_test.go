@@ -0,0 +1,100 @@
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSpoolReplay(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSpool(dir, 0, discardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		rec := SpoolRecord{Key: []byte(fmt.Sprint(i)), Value: []byte{0, byte(i), 0xff}, Headers: []SpoolHeader{{Key: "h", Value: []byte("v")}}}
+		if err := s.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The active segment is not replayed until sealed
+	if sealed, _ := s.Sealed(); len(sealed) != 0 {
+		t.Fatalf("sealed = %v before Seal", sealed)
+	}
+	if err := s.Seal(); err != nil {
+		t.Fatal(err)
+	}
+	s.Append(SpoolRecord{Value: []byte("next")})
+
+	sealed, err := s.Sealed()
+	if err != nil || len(sealed) != 1 {
+		t.Fatalf("sealed = %v, %v", sealed, err)
+	}
+	var replayed []SpoolRecord
+	err = s.Replay(sealed[0], func(rec SpoolRecord) error {
+		replayed = append(replayed, rec)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed) != 3 || string(replayed[2].Key) != "2" || replayed[2].Value[1] != 2 || string(replayed[0].Headers[0].Value) != "v" {
+		t.Fatalf("replayed %+v", replayed)
+	}
+	if _, err := os.Stat(sealed[0]); !os.IsNotExist(err) {
+		t.Errorf("replayed segment still exists: %v", err)
+	}
+
+	// What is left is picked up by the next run
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenSpool(dir, 0, discardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Backlog() != s.Backlog() || reopened.Backlog() == 0 {
+		t.Errorf("backlog = %d after reopening, want %d", reopened.Backlog(), s.Backlog())
+	}
+	reopened.Append(SpoolRecord{Value: []byte("later")})
+	reopened.Close()
+	if sealed, _ := reopened.Sealed(); len(sealed) != 2 || filepath.Base(sealed[1]) != spoolSegment(2) {
+		t.Errorf("sealed = %v", sealed)
+	}
+}
+
+func TestSpoolReplayFailureKeepsSegment(t *testing.T) {
+	s, err := OpenSpool(t.TempDir(), 0, discardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Append(SpoolRecord{Value: []byte("a")})
+	s.Seal()
+	sealed, _ := s.Sealed()
+	down := errors.New("down")
+	if err := s.Replay(sealed[0], func(SpoolRecord) error { return down }); !errors.Is(err, down) {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := os.Stat(sealed[0]); err != nil {
+		t.Errorf("segment removed after failed replay: %v", err)
+	}
+}
+
+func TestSpoolLimit(t *testing.T) {
+	s, err := OpenSpool(t.TempDir(), 10, discardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(SpoolRecord{Value: []byte("first")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(SpoolRecord{Value: []byte("second")}); !errors.Is(err, errSpoolFull) {
+		t.Fatalf("err = %v, want errSpoolFull", err)
+	}
+	if s.Records() != 1 || s.Dropped() != 1 {
+		t.Errorf("records = %d, dropped = %d", s.Records(), s.Dropped())
+	}
+}
@@ -0,0 +1,289 @@
+package writer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// errSinkStopped is a spooled sink's Write returning before its input ended
+var errSinkStopped = errors.New("sink stopped reading")
+
+// SpooledOptions configures a SpooledSink
+type SpooledOptions struct {
+	Open          func() (Sink, error) // opens the sink, and again after it failed
+	RetryInterval time.Duration        // between attempts to reopen the sink
+	Linger        time.Duration        // how long spooled transactions wait before forwarding
+}
+
+// SpooledSink puts a disk spool in front of a network sink. Write appends
+// transactions to the spool as fast as the disk takes them, and a forwarder
+// replays sealed segments into the sink at the sink's own pace, so a slow
+// or briefly unreachable endpoint does not hold generation back. When the
+// sink fails it is reopened after RetryInterval and the segment it was
+// sending is replayed from the start, so forwarding is at least once. A
+// full spool makes Write wait for the forwarder to free space.
+type SpooledSink struct {
+	spool *Spool
+	opts  SpooledOptions
+
+	ctx     context.Context // for the sink's Write
+	sink    Sink            // the open sink, nil until opened or after it failed
+	feed    chan *models.Transaction
+	sinkErr chan error // the open sink's Write result
+
+	mu     sync.Mutex
+	closed struct{ count, errors int64 } // from sinks that failed
+
+	freed    chan struct{} // signalled when forwarding frees spool space
+	ended    chan struct{} // closed once the input has ended and is spooled
+	stop     chan struct{} // closed to abandon forwarding
+	stopOnce sync.Once
+	done     chan struct{} // closed when the forwarder returns
+	failures atomic.Int64  // sink failures and failed opens
+	logger   *slog.Logger
+}
+
+// NewSpooledSink starts forwarding spool to the sink opts.Open returns,
+// a backlog from an earlier run included. The sink is opened by the
+// forwarder, so an endpoint that is down at startup does not fail the run.
+func NewSpooledSink(ctx context.Context, spool *Spool, opts SpooledOptions, logger *slog.Logger) *SpooledSink {
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 5 * time.Second
+	}
+	if opts.Linger <= 0 {
+		opts.Linger = 200 * time.Millisecond
+	}
+	s := &SpooledSink{
+		spool:  spool,
+		opts:   opts,
+		ctx:    ctx,
+		freed:  make(chan struct{}, 1),
+		ended:  make(chan struct{}),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		logger: logger,
+	}
+	go s.forward()
+	return s
+}
+
+// Write spools transactions from the channel. Once the input ends it waits
+// for the backlog to be forwarded; if ctx is done first the rest stays in
+// the spool for the next run.
+func (s *SpooledSink) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	var line []byte
+	for {
+		select {
+		case <-ctx.Done():
+			return s.spool.Seal()
+		case txn, ok := <-input:
+			if !ok {
+				if err := s.spool.Seal(); err != nil {
+					return err
+				}
+				close(s.ended)
+				select {
+				case <-s.done:
+				case <-ctx.Done():
+				}
+				return nil
+			}
+
+			for s.spool.Full() {
+				// Hand the active segment to the forwarder and wait for room
+				if err := s.spool.Seal(); err != nil {
+					return err
+				}
+				select {
+				case <-s.freed:
+				case <-time.After(s.opts.Linger):
+				case <-ctx.Done():
+					return s.spool.Seal()
+				}
+			}
+			line = txn.AppendJSON(line[:0])
+			if err := s.spool.Append(SpoolRecord{Value: line}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// forward seals the active segment every Linger and replays the sealed
+// ones into the sink, until the input has ended and the spool is empty or
+// forwarding is stopped
+func (s *SpooledSink) forward() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.Linger)
+	defer ticker.Stop()
+	ended := s.ended
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ended:
+			// Forward what is left straight away, then keep the ticker pace
+			ended = nil
+		case <-ticker.C:
+		}
+
+		if err := s.spool.Seal(); err != nil {
+			s.logger.Error("Failed to seal spool", "error", err)
+			continue
+		}
+		segments, err := s.spool.Sealed()
+		if err != nil {
+			s.logger.Error("Failed to list spool", "error", err)
+			continue
+		}
+		if len(segments) == 0 && ended == nil {
+			return
+		}
+		for _, path := range segments {
+			err := s.forwardSegment(path)
+			if errors.Is(err, errForwardStopped) {
+				return
+			}
+			if err != nil {
+				// Wait before reopening the sink
+				select {
+				case <-s.stop:
+					return
+				case <-time.After(s.opts.RetryInterval):
+				}
+				break
+			}
+			select {
+			case s.freed <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// forwardSegment replays one sealed segment into the sink, opening it first
+// if needed
+func (s *SpooledSink) forwardSegment(path string) error {
+	if s.sink == nil {
+		sink, err := s.opts.Open()
+		if err != nil {
+			s.failures.Add(1)
+			s.logger.Warn("Failed to open spooled sink, retrying", "error", err, "backlog_bytes", s.spool.Backlog())
+			return err
+		}
+		s.feed = make(chan *models.Transaction)
+		s.sinkErr = make(chan error, 1)
+		go func(feed <-chan *models.Transaction, result chan<- error) {
+			result <- sink.Write(s.ctx, feed)
+		}(s.feed, s.sinkErr)
+		s.mu.Lock()
+		s.sink = sink
+		s.mu.Unlock()
+	}
+
+	return s.spool.Replay(path, func(rec SpoolRecord) error {
+		txn := new(models.Transaction)
+		if err := json.Unmarshal(rec.Value, txn); err != nil {
+			s.logger.Warn("Skipping unreadable spooled transaction", "error", err)
+			return nil
+		}
+		select {
+		case s.feed <- txn:
+			return nil
+		case err := <-s.sinkErr:
+			if err == nil {
+				err = errSinkStopped
+			}
+			s.sinkFailed(err)
+			return err
+		case <-s.stop:
+			return errForwardStopped
+		}
+	})
+}
+
+// sinkFailed closes a sink whose Write returned, keeping its counts, so the
+// next segment reopens it
+func (s *SpooledSink) sinkFailed(err error) {
+	s.failures.Add(1)
+	s.logger.Warn("Spooled sink failed, reopening", "error", err, "retry_in", s.opts.RetryInterval)
+	ctx, cancel := context.WithTimeout(s.ctx, s.opts.RetryInterval)
+	defer cancel()
+	s.sink.CloseContext(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed.count += s.sink.Count()
+	s.closed.errors += s.sink.Errors()
+	s.sink, s.feed, s.sinkErr = nil, nil, nil
+}
+
+// CloseContext stops forwarding, closes the sink and seals the spool. What
+// was not forwarded stays on disk for the next run.
+func (s *SpooledSink) CloseContext(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	var err error
+	if s.sink != nil {
+		close(s.feed)
+		select {
+		case err = <-s.sinkErr:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if closeErr := s.sink.CloseContext(ctx); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := s.spool.Close(); err == nil {
+		err = closeErr
+	}
+	if backlog := s.spool.Backlog(); backlog > 0 {
+		s.logger.Warn("Spool backlog left for the next run", "spool", s.spool.dir, "backlog_bytes", backlog)
+	}
+	return err
+}
+
+// Count returns the number of transactions the sink delivered
+func (s *SpooledSink) Count() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := s.closed.count
+	if s.sink != nil {
+		count += s.sink.Count()
+	}
+	return count
+}
+
+// Errors returns the number of transactions the sink failed to deliver
+func (s *SpooledSink) Errors() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := s.closed.errors
+	if s.sink != nil {
+		total += s.sink.Errors()
+	}
+	return total
+}
+
+// Backlog returns the bytes waiting in the spool
+func (s *SpooledSink) Backlog() int64 {
+	return s.spool.Backlog()
+}
+
+// Failures returns how often the sink failed or could not be opened
+func (s *SpooledSink) Failures() int64 {
+	return s.failures.Load()
+}
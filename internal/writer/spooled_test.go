@@ -0,0 +1,135 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// flakySink records what it is fed. Its Write fails after failAfter
+// transactions, when set, and it counts what it received.
+type flakySink struct {
+	mu        sync.Mutex
+	ids       *[]string
+	failAfter int
+	count     atomic.Int64
+}
+
+func (s *flakySink) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for txn := range input {
+		if s.failAfter > 0 && int(s.count.Load()) == s.failAfter {
+			return errors.New("connection reset")
+		}
+		s.mu.Lock()
+		*s.ids = append(*s.ids, txn.ID)
+		s.mu.Unlock()
+		s.count.Add(1)
+	}
+	return nil
+}
+
+func (s *flakySink) CloseContext(ctx context.Context) error { return nil }
+func (s *flakySink) Count() int64                           { return s.count.Load() }
+func (s *flakySink) Errors() int64                          { return 0 }
+
+func TestSpooledSinkReopensAfterFailure(t *testing.T) {
+	spool, err := OpenSpool(t.TempDir(), 0, discardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	var opens atomic.Int32
+	open := func() (Sink, error) {
+		switch opens.Add(1) {
+		case 1:
+			return nil, errors.New("connection refused")
+		case 2:
+			return &flakySink{ids: &ids, failAfter: 2}, nil
+		default:
+			return &flakySink{ids: &ids}, nil
+		}
+	}
+	s := NewSpooledSink(context.Background(), spool, SpooledOptions{
+		Open:          open,
+		RetryInterval: 10 * time.Millisecond,
+		Linger:        10 * time.Millisecond,
+	}, discardLogger)
+
+	input := make(chan *models.Transaction, 5)
+	for i := 0; i < 5; i++ {
+		input <- &models.Transaction{ID: fmt.Sprintf("TXN-%d", i), BetAmount: "1.00"}
+	}
+	close(input)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Write(ctx, input); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CloseContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The failed segment is replayed from the start on the reopened sink
+	if opens.Load() != 3 || s.Failures() != 2 {
+		t.Errorf("opens = %d, failures = %d", opens.Load(), s.Failures())
+	}
+	want := []string{"TXN-0", "TXN-1", "TXN-0", "TXN-1", "TXN-2", "TXN-3", "TXN-4"}
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("delivered %v, want %v", ids, want)
+	}
+	if s.Count() != int64(len(want)) || s.Backlog() != 0 {
+		t.Errorf("count = %d, backlog = %d", s.Count(), s.Backlog())
+	}
+}
+
+func TestSpooledSinkKeepsBacklog(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := OpenSpool(dir, 0, discardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSpooledSink(context.Background(), spool, SpooledOptions{
+		Open:          func() (Sink, error) { return nil, errors.New("connection refused") },
+		RetryInterval: time.Hour,
+		Linger:        10 * time.Millisecond,
+	}, discardLogger)
+
+	input := make(chan *models.Transaction, 1)
+	input <- &models.Transaction{ID: "TXN-1"}
+	close(input)
+	// The endpoint stays down, so Write gives up at the drain deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := s.Write(ctx, input); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CloseContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next run forwards it
+	reopened, err := OpenSpool(dir, 0, discardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	next := NewSpooledSink(context.Background(), reopened, SpooledOptions{
+		Open:   func() (Sink, error) { return &flakySink{ids: &ids}, nil },
+		Linger: 10 * time.Millisecond,
+	}, discardLogger)
+	empty := make(chan *models.Transaction)
+	close(empty)
+	if err := next.Write(context.Background(), empty); err != nil {
+		t.Fatal(err)
+	}
+	next.CloseContext(context.Background())
+	if len(ids) != 1 || ids[0] != "TXN-1" {
+		t.Errorf("forwarded %v from the earlier run", ids)
+	}
+}
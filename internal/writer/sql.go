@@ -0,0 +1,303 @@
+package writer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// transactionColumns lists table columns in the order rows are bound, the
+// generated field order of models.Transaction
+var transactionColumns = models.TransactionCSVHeader
+
+// sqlWriter batches transactions into multi-row INSERT statements over
+// database/sql. Database-specific writers supply the connection, DDL and
+// placeholder style.
+type sqlWriter struct {
+	db          *sql.DB
+	table       string
+	batchSize   int
+	placeholder func(n int) string
+	buffer      []*models.Transaction
+	args        []interface{}
+	statements  map[int]*sql.Stmt
+	count       atomic.Int64
+	logger      *slog.Logger
+
+	// statementRows caps the rows of one INSERT, 0 = none. A larger batch
+	// is inserted by several statements in one transaction.
+	statementRows int
+	// blockInsert inserts a batch by executing a one-row INSERT per row in
+	// a transaction, which drivers such as clickhouse-go send as one block
+	// on commit
+	blockInsert bool
+	// bind appends a row's arguments, as typed for the driver, and fails
+	// on a value the driver's type cannot hold
+	bind func(args []interface{}, txn *models.Transaction) ([]interface{}, error)
+}
+
+func newSQLWriter(db *sql.DB, table string, batchSize int, placeholder func(n int) string, logger *slog.Logger) *sqlWriter {
+	return &sqlWriter{
+		db:          db,
+		table:       table,
+		batchSize:   batchSize,
+		placeholder: placeholder,
+		buffer:      make([]*models.Transaction, 0, batchSize),
+		args:        make([]interface{}, 0, batchSize*len(transactionColumns)),
+		statements:  make(map[int]*sql.Stmt),
+		bind:        appendTransactionArgs,
+		logger:      logger,
+	}
+}
+
+// appendTransactionArgs appends txn's columns as they are held, amounts and
+// timestamps as text
+func appendTransactionArgs(args []interface{}, txn *models.Transaction) ([]interface{}, error) {
+	return append(args,
+		txn.ID,
+		txn.ExternalTransactionID,
+		txn.VendorBetID,
+		txn.RoundID,
+		txn.VendorID,
+		txn.VendorCode,
+		txn.VendorLineID,
+		txn.GameCategoryID,
+		txn.HouseID,
+		txn.MasterAgentID,
+		txn.AgentID,
+		txn.CurrencyID,
+		txn.CurrencyCode,
+		txn.BetAmount,
+		txn.WinAmount,
+		txn.WinLoss,
+		txn.SettledAt,
+	), nil
+}
+
+// generatedTableDDL creates table with a column per transaction column,
+// typed by columnType
+func generatedTableDDL(table string, columnType func(column string, field columnField) string) string {
+	columns := make([]string, len(transactionColumns))
+	for i, column := range transactionColumns {
+		columns[i] = column + " " + columnType(column, columnFields[column])
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s\n)", table, strings.Join(columns, ",\n\t"))
+}
+
+// questionPlaceholder is the ? style used by DuckDB, SQLite and MySQL
+func questionPlaceholder(int) string {
+	return "?"
+}
+
+// insertSQL builds a multi-row INSERT for the given number of rows
+func (w *sqlWriter) insertSQL(rows int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", w.table, strings.Join(transactionColumns, ", "))
+	n := 1
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for c := range transactionColumns {
+			if c > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(w.placeholder(n))
+			n++
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+// Write writes transactions from the channel to the database
+func (w *sqlWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return w.flush()
+		case txn, ok := <-input:
+			if !ok {
+				// Channel closed, flush remaining buffer
+				return w.flush()
+			}
+
+			w.buffer = append(w.buffer, txn)
+			if len(w.buffer) >= w.batchSize {
+				if err := w.flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// WriteBatch inserts batch, plus anything already buffered, in one statement
+// or, past statementRows, one transaction
+func (w *sqlWriter) WriteBatch(ctx context.Context, batch []*models.Transaction) error {
+	w.buffer = append(w.buffer, batch...)
+	return w.flush()
+}
+
+func (w *sqlWriter) flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	switch {
+	case w.blockInsert:
+		if err := w.insertBlock(); err != nil {
+			return err
+		}
+	case w.statementRows <= 0 || len(w.buffer) <= w.statementRows:
+		if err := w.insert(nil, w.buffer); err != nil {
+			return err
+		}
+	default:
+		if err := w.insertChunks(); err != nil {
+			return err
+		}
+	}
+
+	w.count.Add(int64(len(w.buffer)))
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+// insertChunks inserts the buffer statementRows at a time in one
+// transaction, so a batch is committed whole or not at all
+func (w *sqlWriter) insertChunks() error {
+	// Prepare up front: the transaction may hold the only connection
+	for _, rows := range []int{w.statementRows, len(w.buffer) % w.statementRows} {
+		if rows == 0 {
+			continue
+		}
+		if _, err := w.statement(rows); err != nil {
+			return err
+		}
+	}
+	tx, err := w.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin insert into %s: %w", w.table, err)
+	}
+	for start := 0; start < len(w.buffer); start += w.statementRows {
+		end := min(start+w.statementRows, len(w.buffer))
+		if err := w.insert(tx, w.buffer[start:end]); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit insert into %s: %w", w.table, err)
+	}
+	return nil
+}
+
+// insertBlock executes a one-row INSERT for each buffered row in one
+// transaction. The statement is prepared on the transaction, since block
+// drivers start the block there.
+func (w *sqlWriter) insertBlock() error {
+	tx, err := w.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin insert into %s: %w", w.table, err)
+	}
+	stmt, err := tx.Prepare(w.insertSQL(1))
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to prepare insert into %s: %w", w.table, err)
+	}
+	defer stmt.Close()
+	for _, txn := range w.buffer {
+		if w.args, err = w.bind(w.args[:0], txn); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to insert into %s: %w", w.table, err)
+		}
+		if _, err := stmt.Exec(w.args...); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to insert into %s: %w", w.table, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit insert into %s: %w", w.table, err)
+	}
+	return nil
+}
+
+// statement returns the prepared INSERT for the given number of rows
+func (w *sqlWriter) statement(rows int) (*sql.Stmt, error) {
+	if stmt, ok := w.statements[rows]; ok {
+		return stmt, nil
+	}
+	stmt, err := w.db.Prepare(w.insertSQL(rows))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare insert into %s: %w", w.table, err)
+	}
+	w.statements[rows] = stmt
+	return stmt, nil
+}
+
+// insert runs one INSERT for rows, inside tx when it is not nil
+func (w *sqlWriter) insert(tx *sql.Tx, rows []*models.Transaction) error {
+	stmt, err := w.statement(len(rows))
+	if err != nil {
+		return err
+	}
+	if tx != nil {
+		stmt = tx.Stmt(stmt)
+	}
+
+	w.args = w.args[:0]
+	for _, txn := range rows {
+		if w.args, err = w.bind(w.args, txn); err != nil {
+			return fmt.Errorf("failed to insert into %s: %w", w.table, err)
+		}
+	}
+
+	if _, err := stmt.Exec(w.args...); err != nil {
+		return fmt.Errorf("failed to insert into %s: %w", w.table, err)
+	}
+	return nil
+}
+
+// CloseContext flushes remaining rows and closes the database, abandoning
+// both once ctx is done
+func (w *sqlWriter) CloseContext(ctx context.Context) error {
+	return CloseWithin(ctx, w.close)
+}
+
+func (w *sqlWriter) close() error {
+	err := w.flush()
+	for _, stmt := range w.statements {
+		stmt.Close()
+	}
+	if closeErr := w.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Undelivered gives up the rows a failed insert left buffered, so a standby
+// writes them and Close does not retry them
+func (w *sqlWriter) Undelivered() []*models.Transaction {
+	rows := slices.Clone(w.buffer)
+	clear(w.buffer)
+	w.buffer = w.buffer[:0]
+	return rows
+}
+
+// Count returns the number of transactions written
+func (w *sqlWriter) Count() int64 {
+	return w.count.Load()
+}
+
+// Errors is always zero: a failed insert stops the writer instead
+func (w *sqlWriter) Errors() int64 {
+	return 0
+}
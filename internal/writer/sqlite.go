@@ -0,0 +1,102 @@
+package writer
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// sqliteDrivers are the database/sql names SQLite drivers register under:
+// modernc.org/sqlite and github.com/mattn/go-sqlite3
+var sqliteDrivers = []string{"sqlite", "sqlite3"}
+
+// sqliteMaxVariables is the bound parameter limit of SQLite builds before
+// 3.32; newer builds allow more, but system libraries may still be older
+const sqliteMaxVariables = 999
+
+// sqlitePragmas trade durability of the last commits on power loss for
+// write speed, and let readers query the file while the run is writing
+var sqlitePragmas = []string{
+	"PRAGMA journal_mode = WAL",
+	"PRAGMA synchronous = NORMAL",
+	"PRAGMA busy_timeout = 5000",
+}
+
+// SQLiteWriter inserts transactions into a local SQLite database file. Each
+// batch is committed in one transaction, split into INSERT statements that
+// stay under SQLite's parameter limit.
+type SQLiteWriter struct {
+	*sqlWriter
+}
+
+// NewSQLiteWriter creates a new SQLite writer. The SQLite driver is only
+// linked into binaries built with -tags sqlite.
+func NewSQLiteWriter(outputDir, filename, table string, batchSize int, logger *slog.Logger) (*SQLiteWriter, error) {
+	driver := sqliteDriver()
+	if driver == "" {
+		return nil, fmt.Errorf("sqlite support not compiled in; rebuild with -tags sqlite")
+	}
+
+	path := filepath.Join(outputDir, filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	db, err := sql.Open(driver, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	// SQLite allows a single writer per database file, and pragmas apply
+	// to the connection that ran them
+	db.SetMaxOpenConns(1)
+
+	for _, pragma := range sqlitePragmas {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to configure SQLite database: %w", err)
+		}
+	}
+	if _, err := db.Exec(sqliteTableDDL(table)); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create SQLite table: %w", err)
+	}
+
+	w := newSQLWriter(db, table, batchSize, questionPlaceholder, logger)
+	w.statementRows = sqliteMaxVariables / len(transactionColumns)
+	return &SQLiteWriter{sqlWriter: w}, nil
+}
+
+// SQLiteLinked reports whether a SQLite driver is linked into the binary
+func SQLiteLinked() bool {
+	return sqliteDriver() != ""
+}
+
+// sqliteDriver returns the name of a linked SQLite driver, or "" if none is
+func sqliteDriver() string {
+	drivers := sql.Drivers()
+	for _, name := range sqliteDrivers {
+		if slices.Contains(drivers, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// sqliteTableDDL generates the table from the transaction columns: integer
+// columns as INTEGER, amounts as NUMERIC so they sum, and the rest as TEXT,
+// which SQLite's date functions read settled_at from
+func sqliteTableDDL(table string) string {
+	return generatedTableDDL(table, func(column string, field columnField) string {
+		switch {
+		case field.amount:
+			return "NUMERIC"
+		case field.numeric:
+			return "INTEGER"
+		default:
+			return "TEXT"
+		}
+	})
+}
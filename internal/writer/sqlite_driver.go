@@ -0,0 +1,6 @@
+//go:build sqlite
+
+package writer
+
+// Registers the "sqlite" database/sql driver, pure Go so cgo is not needed
+import _ "modernc.org/sqlite"
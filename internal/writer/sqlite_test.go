@@ -0,0 +1,30 @@
+package writer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSQLiteTableDDL(t *testing.T) {
+	ddl := sqliteTableDDL("transactions")
+	if !strings.HasPrefix(ddl, "CREATE TABLE IF NOT EXISTS transactions (") {
+		t.Errorf("ddl = %s", ddl)
+	}
+	for _, column := range []string{"id TEXT,", "house_id INTEGER,", "bet_amount NUMERIC,", "settled_at TEXT\n"} {
+		if !strings.Contains(ddl, column) {
+			t.Errorf("ddl missing %q:\n%s", column, ddl)
+		}
+	}
+	if n := strings.Count(ddl, ",") + 1; n != len(transactionColumns) {
+		t.Errorf("ddl has %d columns, want %d", n, len(transactionColumns))
+	}
+}
+
+func TestSQLiteWriterWithoutDriver(t *testing.T) {
+	if sqliteDriver() != "" {
+		t.Skip("SQLite driver linked")
+	}
+	if _, err := NewSQLiteWriter(t.TempDir(), "out.sqlite", "transactions", 100, nil); err == nil || !strings.Contains(err.Error(), "-tags sqlite") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package writer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// syslogPriority is facility local0 (16) with severity informational (6)
+const syslogPriority = 16*8 + 6
+
+// SyslogWriter sends transactions as RFC5424 syslog messages with the JSON
+// payload as MSG. TCP uses octet-counting framing (RFC6587), UDP sends one
+// message per datagram.
+type SyslogWriter struct {
+	conn     net.Conn
+	network  string
+	writer   *bufio.Writer
+	hostname string
+	appName  string
+	procID   string
+	line     []byte
+	count    atomic.Int64
+	errors   atomic.Int64
+	logger   *slog.Logger
+}
+
+// NewSyslogWriter creates a new RFC5424 syslog writer
+func NewSyslogWriter(network, address, appName string, timeout time.Duration, logger *slog.Logger) (*SyslogWriter, error) {
+	if network != "tcp" && network != "udp" {
+		return nil, fmt.Errorf("unsupported syslog network: %s", network)
+	}
+
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog at %s: %w", address, err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+
+	w := &SyslogWriter{
+		conn:     conn,
+		network:  network,
+		hostname: hostname,
+		appName:  appName,
+		procID:   strconv.Itoa(os.Getpid()),
+		logger:   logger,
+	}
+	if network == "tcp" {
+		w.writer = bufio.NewWriterSize(conn, 64*1024)
+	}
+	return w, nil
+}
+
+// Write writes transactions from the channel to syslog
+func (w *SyslogWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return w.flush()
+		case txn, ok := <-input:
+			if !ok {
+				return w.flush()
+			}
+
+			if err := w.writeTransaction(txn); err != nil {
+				return err
+			}
+
+			if len(input) == 0 {
+				if err := w.flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// WriteBatch sends batch and flushes it to the connection
+func (w *SyslogWriter) WriteBatch(ctx context.Context, batch []*models.Transaction) error {
+	for _, txn := range batch {
+		if err := w.writeTransaction(txn); err != nil {
+			return err
+		}
+	}
+	return w.flush()
+}
+
+// writeTransaction encodes and sends txn. Encoding failures and dropped UDP
+// datagrams are counted as errors, not returned.
+func (w *SyslogWriter) writeTransaction(txn *models.Transaction) error {
+	data, err := json.Marshal(txn)
+	if err != nil {
+		w.errors.Add(1)
+		return nil
+	}
+
+	if err := w.send(data); err != nil {
+		// Dropped datagrams are expected under load; keep going
+		if w.network == "udp" {
+			w.errors.Add(1)
+			return nil
+		}
+		return err
+	}
+	w.count.Add(1)
+	return nil
+}
+
+func (w *SyslogWriter) send(payload []byte) error {
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	line := append(w.line[:0], '<')
+	line = strconv.AppendInt(line, syslogPriority, 10)
+	line = append(line, ">1 "...)
+	line = time.Now().UTC().AppendFormat(line, time.RFC3339Nano)
+	line = append(line, ' ')
+	line = append(line, w.hostname...)
+	line = append(line, ' ')
+	line = append(line, w.appName...)
+	line = append(line, ' ')
+	line = append(line, w.procID...)
+	line = append(line, " transaction - "...)
+	line = append(line, payload...)
+	w.line = line
+
+	if w.network == "udp" {
+		if _, err := w.conn.Write(line); err != nil {
+			return fmt.Errorf("failed to send syslog datagram: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := w.writer.WriteString(strconv.Itoa(len(line))); err != nil {
+		return fmt.Errorf("failed to write syslog message: %w", err)
+	}
+	if err := w.writer.WriteByte(' '); err != nil {
+		return fmt.Errorf("failed to write syslog message: %w", err)
+	}
+	if _, err := w.writer.Write(line); err != nil {
+		return fmt.Errorf("failed to write syslog message: %w", err)
+	}
+	return nil
+}
+
+func (w *SyslogWriter) flush() error {
+	if w.writer == nil {
+		return nil
+	}
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush syslog writer: %w", err)
+	}
+	return nil
+}
+
+// CloseContext flushes buffered messages and closes the connection. Once ctx is done
+// the connection is closed without waiting for the flush.
+func (w *SyslogWriter) CloseContext(ctx context.Context) error {
+	return closeConnWithin(ctx, w.conn, w.flush)
+}
+
+// Count returns the number of transactions written
+func (w *SyslogWriter) Count() int64 {
+	return w.count.Load()
+}
+
+// Errors returns the number of errors encountered
+func (w *SyslogWriter) Errors() int64 {
+	return w.errors.Load()
+}
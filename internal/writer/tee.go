@@ -0,0 +1,107 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Destination is one copy of a file output in a TeeWriter
+type Destination struct {
+	Name   string
+	Writer FileWriter
+}
+
+// TeeWriter copies every transaction to several destinations of the same
+// format, e.g. a local directory and an NFS mount, for redundancy. The
+// slowest destination paces the others.
+type TeeWriter struct {
+	destinations []Destination
+	logger       *slog.Logger
+}
+
+// NewTeeWriter creates a writer that duplicates the stream to destinations
+func NewTeeWriter(destinations []Destination, logger *slog.Logger) *TeeWriter {
+	return &TeeWriter{destinations: destinations, logger: logger}
+}
+
+// Write copies transactions from the channel to every destination
+func (w *TeeWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	channels := make([]chan *models.Transaction, len(w.destinations))
+	errs := make([]error, len(w.destinations))
+
+	var wg sync.WaitGroup
+	for i, dest := range w.destinations {
+		channels[i] = make(chan *models.Transaction, cap(input))
+		wg.Add(1)
+		go func(i int, dest Destination) {
+			defer wg.Done()
+			errs[i] = dest.Writer.Write(ctx, channels[i])
+			if errs[i] != nil {
+				// Keep draining so one failed destination does not stall the rest
+				for range channels[i] {
+				}
+			}
+		}(i, dest)
+	}
+
+	func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case txn, ok := <-input:
+				if !ok {
+					return
+				}
+				for _, ch := range channels {
+					select {
+					case ch <- txn:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	for _, ch := range channels {
+		close(ch)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("destination %s: %w", w.destinations[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// Close closes every destination
+func (w *TeeWriter) Close() error {
+	var firstErr error
+	for _, dest := range w.destinations {
+		if err := dest.Writer.Close(); err != nil {
+			w.logger.Error("Failed to close destination", "destination", dest.Name, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Count returns the number of transactions written to every destination
+func (w *TeeWriter) Count() int64 {
+	var count int64
+	for i, dest := range w.destinations {
+		if n := dest.Writer.Count(); i == 0 || n < count {
+			count = n
+		}
+	}
+	return count
+}
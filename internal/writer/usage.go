@@ -0,0 +1,44 @@
+package writer
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// OutputUsage totals the regular files under dirs modified since the run
+// started, counting a file reachable from several dirs once. Files that
+// vanish mid-walk, e.g. plaintext removed after encryption, are skipped.
+func OutputUsage(dirs []string, since time.Time) (bytes int64, files int, err error) {
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			abs, _ := filepath.Abs(path)
+			if info.ModTime().Before(since) || seen[abs] {
+				return nil
+			}
+			seen[abs] = true
+			bytes += info.Size()
+			files++
+			return nil
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return bytes, files, nil
+}
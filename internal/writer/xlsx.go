@@ -0,0 +1,276 @@
+package writer
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// XLSXMaxRows is the most transactions one worksheet holds, below Excel's
+// row limit with room for the header
+const XLSXMaxRows = 1048575
+
+// xlsxEpoch is day zero of Excel's date serials
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Cell styles, as indexes into cellXfs of xlsxStyles
+const (
+	xlsxStyleDefault  = 0
+	xlsxStyleDateTime = 1
+	xlsxStyleHeader   = 2
+)
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="Transactions" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// xlsxStyles defines a date-time format and a bold header font
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`</cellXfs>` +
+	`</styleSheet>`
+
+// XLSXWriter writes the first transactions of a run into an Excel workbook
+// for people who open samples in a spreadsheet rather than a data tool.
+// Numbers and amounts are numeric cells and settled_at a date-time, so they
+// sort and sum. Once maxRows are written Write returns, and the pipeline
+// stops feeding it: the sample never holds back the other sinks.
+type XLSXWriter struct {
+	file    *os.File
+	zip     *zip.Writer
+	sheet   *bufio.Writer // the worksheet, the last part of the package
+	columns []columnField
+	row     bytes.Buffer // reused encoding buffer for one row
+	maxRows int64
+	count   atomic.Int64
+	logger  *slog.Logger
+}
+
+// NewXLSXWriter creates a workbook holding up to maxRows transactions. The
+// fixed parts of the package and the header row are written right away.
+func NewXLSXWriter(outputDir, filename string, maxRows int, logger *slog.Logger) (*XLSXWriter, error) {
+	if maxRows <= 0 || maxRows > XLSXMaxRows {
+		return nil, fmt.Errorf("XLSX max rows must be between 1 and %d", XLSXMaxRows)
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	path := filepath.Join(outputDir, filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create XLSX file: %w", err)
+	}
+
+	w := &XLSXWriter{
+		file:    file,
+		zip:     zip.NewWriter(retryWrites(file, logger)),
+		maxRows: int64(maxRows),
+		logger:  logger,
+	}
+	for _, column := range models.TransactionCSVHeader {
+		w.columns = append(w.columns, columnFields[column])
+	}
+	if err := w.start(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write XLSX package: %w", err)
+	}
+	return w, nil
+}
+
+// start writes the fixed package parts, then opens the worksheet with its
+// column widths, a frozen header row and the header itself
+func (w *XLSXWriter) start() error {
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	} {
+		f, err := w.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write([]byte(part.content)); err != nil {
+			return err
+		}
+	}
+
+	f, err := w.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	w.sheet = bufio.NewWriterSize(f, 64*1024)
+	w.sheet.WriteString(xml.Header)
+	w.sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	w.sheet.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	w.sheet.WriteString(`<cols>`)
+	for i, column := range models.TransactionCSVHeader {
+		width := 24
+		switch {
+		case column == "settled_at":
+			width = 20
+		case w.columns[i].numeric:
+			width = 14
+		}
+		fmt.Fprintf(w.sheet, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width)
+	}
+	w.sheet.WriteString(`</cols><sheetData>`)
+
+	w.row.WriteString(`<row r="1">`)
+	for _, column := range models.TransactionCSVHeader {
+		w.stringCell(column, xlsxStyleHeader)
+	}
+	w.row.WriteString(`</row>`)
+	_, err = w.sheet.Write(w.row.Bytes())
+	return err
+}
+
+// Write adds transactions as rows until the input is closed or maxRows are
+// written
+func (w *XLSXWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	if w.count.Load() >= w.maxRows {
+		return nil
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case txn, ok := <-input:
+			if !ok {
+				return nil
+			}
+			w.encode(txn)
+			if _, err := w.sheet.Write(w.row.Bytes()); err != nil {
+				return fmt.Errorf("failed to write XLSX row: %w", err)
+			}
+			if w.count.Add(1) >= w.maxRows {
+				w.logger.Info("XLSX sample complete", "rows", w.maxRows)
+				return nil
+			}
+		}
+	}
+}
+
+// encode lays out one row in w.row
+func (w *XLSXWriter) encode(txn *models.Transaction) {
+	w.row.Reset()
+	fmt.Fprintf(&w.row, `<row r="%d">`, w.count.Load()+2)
+	for i, column := range models.TransactionCSVHeader {
+		field := w.columns[i]
+		value := field.value(txn)
+		switch {
+		case value == "":
+			w.row.WriteString(`<c/>`)
+		case field.numeric:
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				w.stringCell(value, xlsxStyleDefault)
+				continue
+			}
+			w.row.WriteString(`<c><v>` + value + `</v></c>`)
+		case column == "settled_at":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				w.stringCell(value, xlsxStyleDefault)
+				continue
+			}
+			fmt.Fprintf(&w.row, `<c s="%d"><v>%s</v></c>`, xlsxStyleDateTime, strconv.FormatFloat(xlsxSerial(t), 'f', -1, 64))
+		default:
+			w.stringCell(value, xlsxStyleDefault)
+		}
+	}
+	w.row.WriteString(`</row>`)
+}
+
+// stringCell appends an inline string cell to w.row
+func (w *XLSXWriter) stringCell(value string, style int) {
+	if style != xlsxStyleDefault {
+		fmt.Fprintf(&w.row, `<c t="inlineStr" s="%d"><is><t>`, style)
+	} else {
+		w.row.WriteString(`<c t="inlineStr"><is><t>`)
+	}
+	// Writes to a bytes.Buffer cannot fail
+	xml.EscapeText(&w.row, []byte(value))
+	w.row.WriteString(`</t></is></c>`)
+}
+
+// xlsxSerial converts t to an Excel date serial in the offset t was written
+// with, as Excel dates carry no time zone
+func xlsxSerial(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return wall.Sub(xlsxEpoch).Seconds() / 86400
+}
+
+// xlsxColumn returns the letters of the 1-based column n, e.g. 28 = AB
+func xlsxColumn(n int) string {
+	var letters []byte
+	for ; n > 0; n = (n - 1) / 26 {
+		letters = append([]byte{byte('A' + (n-1)%26)}, letters...)
+	}
+	return string(letters)
+}
+
+// Close ends the worksheet with a filter over the rows written and finishes
+// the package
+func (w *XLSXWriter) Close() error {
+	fmt.Fprintf(w.sheet, `</sheetData><autoFilter ref="A1:%s%d"/></worksheet>`,
+		xlsxColumn(len(w.columns)), w.count.Load()+1)
+	err := w.sheet.Flush()
+	if zipErr := w.zip.Close(); err == nil {
+		err = zipErr
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Count returns the number of transactions written
+func (w *XLSXWriter) Count() int64 {
+	return w.count.Load()
+}
@@ -0,0 +1,131 @@
+package writer
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// xlsxSheet decodes the worksheet cells
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Type   string `xml:"t,attr"`
+			Style  int    `xml:"s,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+	AutoFilter struct {
+		Ref string `xml:"ref,attr"`
+	} `xml:"autoFilter"`
+}
+
+func TestXLSXWriter(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewXLSXWriter(dir, "sample.xlsx", 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := make(chan *models.Transaction, 5)
+	for i := 0; i < 5; i++ {
+		input <- &models.Transaction{
+			ID:         fmt.Sprintf("TXN-%d", i),
+			VendorCode: "A&B",
+			HouseID:    i,
+			BetAmount:  "10.50",
+			SettledAt:  "2024-01-02T12:00:00+08:00",
+		}
+	}
+	close(input)
+	if err := w.Write(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Count() != 3 {
+		t.Errorf("count = %d, want 3", w.Count())
+	}
+	if len(input) != 2 {
+		t.Errorf("writer kept reading past max rows: %d left", len(input))
+	}
+
+	r, err := zip.OpenReader(filepath.Join(dir, "sample.xlsx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	parts := make(map[string]*zip.File)
+	for _, f := range r.File {
+		parts[f.Name] = f
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		if parts[name] == nil {
+			t.Errorf("package missing %s", name)
+		}
+	}
+	f, err := parts["xl/worksheets/sheet1.xml"].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sheet xlsxSheet
+	if err := xml.Unmarshal(data, &sheet); err != nil {
+		t.Fatalf("%v\n%s", err, data)
+	}
+
+	if len(sheet.Rows) != 4 || sheet.Rows[3].R != 4 {
+		t.Fatalf("rows = %d, want header and 3", len(sheet.Rows))
+	}
+	if sheet.AutoFilter.Ref != "A1:Q4" {
+		t.Errorf("autoFilter = %s", sheet.AutoFilter.Ref)
+	}
+	column := func(name string) int {
+		for i, c := range models.TransactionCSVHeader {
+			if c == name {
+				return i
+			}
+		}
+		t.Fatalf("no column %s", name)
+		return 0
+	}
+	header, last := sheet.Rows[0].Cells, sheet.Rows[3].Cells
+	if header[0].Inline != "id" || header[0].Style != xlsxStyleHeader {
+		t.Errorf("header cell = %+v", header[0])
+	}
+	if c := last[column("id")]; c.Type != "inlineStr" || c.Inline != "TXN-2" {
+		t.Errorf("id cell = %+v", c)
+	}
+	if c := last[column("vendor_code")]; c.Inline != "A&B" {
+		t.Errorf("vendor_code cell = %+v", c)
+	}
+	if c := last[column("house_id")]; c.Type != "" || c.Value != "2" {
+		t.Errorf("house_id cell = %+v", c)
+	}
+	if c := last[column("bet_amount")]; c.Type != "" || c.Value != "10.50" {
+		t.Errorf("bet_amount cell = %+v", c)
+	}
+	// 2024-01-02 12:00 is day 45293 and a half, in the offset it was written with
+	if c := last[column("settled_at")]; c.Style != xlsxStyleDateTime || c.Value != "45293.5" {
+		t.Errorf("settled_at cell = %+v", c)
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for n, want := range map[int]string{1: "A", 17: "Q", 26: "Z", 27: "AA", 28: "AB", 702: "ZZ", 703: "AAA"} {
+		if got := xlsxColumn(n); got != want {
+			t.Errorf("xlsxColumn(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
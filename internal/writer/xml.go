@@ -0,0 +1,188 @@
+package writer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// XMLFormat controls the document the XML writer produces
+type XMLFormat struct {
+	RootElement   string // wraps every record, default transactions
+	RecordElement string // one per transaction, default transaction
+	Namespace     string // xmlns of the root element, empty = none
+	Pretty        bool   // indent elements, one per line; compact writes one record per line
+}
+
+// XMLWriter writes transactions as an XML batch document: a root element
+// holding one record element per transaction, with a child element per
+// column. The root is closed when the writer is, so every rotated file is a
+// complete document.
+type XMLWriter struct {
+	file       *os.File
+	writer     *bufio.Writer
+	format     XMLFormat
+	record     bytes.Buffer // reused encoding buffer for one record
+	bufferSize int
+	buffer     []*models.Transaction
+	count      atomic.Int64
+	logger     *slog.Logger
+}
+
+// validXMLName reports whether name can be used as an element name. Names
+// starting with "xml" are reserved, and namespace prefixes are not
+// supported.
+func validXMLName(name string) bool {
+	if name == "" || len(name) >= 3 && (name[0]|0x20) == 'x' && (name[1]|0x20) == 'm' && (name[2]|0x20) == 'l' {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewXMLWriter creates an XML writer, writing the declaration and the
+// opening root element
+func NewXMLWriter(outputDir, filename string, bufferSize int, format XMLFormat, logger *slog.Logger) (*XMLWriter, error) {
+	if format.RootElement == "" {
+		format.RootElement = "transactions"
+	}
+	if format.RecordElement == "" {
+		format.RecordElement = "transaction"
+	}
+	for _, name := range []string{format.RootElement, format.RecordElement} {
+		if !validXMLName(name) {
+			return nil, fmt.Errorf("invalid XML element name: %q", name)
+		}
+	}
+
+	path := filepath.Join(outputDir, filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create XML file: %w", err)
+	}
+
+	writer := bufio.NewWriterSize(retryWrites(file, logger), 64*1024)
+	writer.WriteString(xml.Header)
+	writer.WriteString("<" + format.RootElement)
+	if format.Namespace != "" {
+		writer.WriteString(` xmlns="`)
+		xml.EscapeText(writer, []byte(format.Namespace))
+		writer.WriteString(`"`)
+	}
+	writer.WriteString(">\n")
+
+	bufferSize = max(bufferSize, 1)
+	return &XMLWriter{
+		file:       file,
+		writer:     writer,
+		format:     format,
+		bufferSize: bufferSize,
+		buffer:     make([]*models.Transaction, 0, bufferSize),
+		logger:     logger,
+	}, nil
+}
+
+// Write writes transactions from the channel as record elements
+func (w *XMLWriter) Write(ctx context.Context, input <-chan *models.Transaction) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return w.flush()
+		case txn, ok := <-input:
+			if !ok {
+				return w.flush()
+			}
+			w.buffer = append(w.buffer, txn)
+			if len(w.buffer) >= w.bufferSize {
+				if err := w.flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (w *XMLWriter) flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	for _, txn := range w.buffer {
+		w.encode(txn)
+		if _, err := w.writer.Write(w.record.Bytes()); err != nil {
+			return fmt.Errorf("failed to write XML record: %w", err)
+		}
+	}
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush XML writer: %w", err)
+	}
+
+	w.count.Add(int64(len(w.buffer)))
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+// encode lays out one record element in w.record
+func (w *XMLWriter) encode(txn *models.Transaction) {
+	b := &w.record
+	b.Reset()
+	record := w.format.RecordElement
+	if w.format.Pretty {
+		b.WriteString("  <" + record + ">\n")
+	} else {
+		b.WriteString("<" + record + ">")
+	}
+	for _, column := range models.TransactionCSVHeader {
+		if w.format.Pretty {
+			b.WriteString("    ")
+		}
+		b.WriteString("<" + column + ">")
+		// Writes to a bytes.Buffer cannot fail
+		xml.EscapeText(b, []byte(columnFields[column].value(txn)))
+		b.WriteString("</" + column + ">")
+		if w.format.Pretty {
+			b.WriteByte('\n')
+		}
+	}
+	if w.format.Pretty {
+		b.WriteString("  ")
+	}
+	b.WriteString("</" + record + ">\n")
+}
+
+// Close flushes remaining records, closes the root element and the file
+func (w *XMLWriter) Close() error {
+	err := w.flush()
+	if err == nil {
+		w.writer.WriteString("</" + w.format.RootElement + ">\n")
+		err = w.writer.Flush()
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Count returns the number of transactions written
+func (w *XMLWriter) Count() int64 {
+	return w.count.Load()
+}
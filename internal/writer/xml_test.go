@@ -0,0 +1,92 @@
+package writer
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/supratick/message_producer/internal/models"
+)
+
+func writeXML(t *testing.T, format XMLFormat) string {
+	t.Helper()
+	dir := t.TempDir()
+	w, err := NewXMLWriter(dir, "transactions.xml", 2, format, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := make(chan *models.Transaction, 3)
+	for i := 0; i < 3; i++ {
+		input <- &models.Transaction{ID: fmt.Sprintf("TXN-%d", i), VendorCode: "A&B <Games>", HouseID: i, BetAmount: "10.50"}
+	}
+	close(input)
+	if err := w.Write(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Count() != 3 {
+		t.Errorf("count = %d, want 3", w.Count())
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "transactions.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// xmlBatch decodes the default layout
+type xmlBatch struct {
+	XMLName      xml.Name `xml:"transactions"`
+	Transactions []struct {
+		ID         string `xml:"id"`
+		VendorCode string `xml:"vendor_code"`
+		HouseID    int    `xml:"house_id"`
+		BetAmount  string `xml:"bet_amount"`
+	} `xml:"transaction"`
+}
+
+func TestXMLWriter(t *testing.T) {
+	for _, pretty := range []bool{false, true} {
+		doc := writeXML(t, XMLFormat{Pretty: pretty})
+		var batch xmlBatch
+		if err := xml.Unmarshal([]byte(doc), &batch); err != nil {
+			t.Fatalf("pretty=%v: %v\n%s", pretty, err, doc)
+		}
+		if len(batch.Transactions) != 3 {
+			t.Fatalf("pretty=%v: %d records", pretty, len(batch.Transactions))
+		}
+		last := batch.Transactions[2]
+		if last.ID != "TXN-2" || last.VendorCode != "A&B <Games>" || last.HouseID != 2 || last.BetAmount != "10.50" {
+			t.Errorf("pretty=%v: last record = %+v", pretty, last)
+		}
+
+		// Compact writes one record per line after the declaration and root
+		if lines := strings.Count(doc, "\n"); pretty && lines < 3*19 || !pretty && lines != 6 {
+			t.Errorf("pretty=%v: %d lines\n%s", pretty, lines, doc)
+		}
+	}
+}
+
+func TestXMLWriterElements(t *testing.T) {
+	doc := writeXML(t, XMLFormat{RootElement: "Batch", RecordElement: "Bet", Namespace: "urn:regulator:v1"})
+	if !strings.Contains(doc, `<Batch xmlns="urn:regulator:v1">`) || !strings.HasSuffix(doc, "</Batch>\n") {
+		t.Errorf("document = %s", doc)
+	}
+	if strings.Count(doc, "<Bet>") != 3 {
+		t.Errorf("document = %s", doc)
+	}
+}
+
+func TestXMLWriterInvalidElement(t *testing.T) {
+	for _, name := range []string{"1st", "a b", "xmlData", "ns:tx"} {
+		if _, err := NewXMLWriter(t.TempDir(), "transactions.xml", 1, XMLFormat{RootElement: name}, nil); err == nil {
+			t.Errorf("element %q accepted", name)
+		}
+	}
+}
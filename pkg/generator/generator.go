@@ -0,0 +1,138 @@
+// Package generator exposes the synthetic transaction generator as a
+// pull-based iterator so tests can consume transactions inline without
+// channels, goroutines, or sinks.
+package generator
+
+import (
+	"context"
+	"io"
+	"iter"
+	"log/slog"
+
+	"github.com/supratick/message_producer/internal/generator"
+	"github.com/supratick/message_producer/internal/models"
+)
+
+// Transaction is the generated betting transaction
+type Transaction = models.Transaction
+
+// ReferenceData holds the reference data used to build transactions
+type ReferenceData = models.ReferenceData
+
+// LoadReferenceData loads all reference data files from dataPath
+func LoadReferenceData(dataPath string) (*ReferenceData, error) {
+	return generator.LoadReferenceData(dataPath)
+}
+
+// Dataset is a custom reference dataset, e.g. promotions or campaigns
+type Dataset = generator.Dataset
+
+// Field fills a named transaction value from a dataset attribute. Field has
+// yaml and json tags so fields can be defined in the embedder's config.
+type Field = generator.Field
+
+// Selection strategies for a Dataset
+const (
+	SelectUniform    = generator.SelectUniform
+	SelectWeighted   = generator.SelectWeighted
+	SelectRoundRobin = generator.SelectRoundRobin
+	SelectAgent      = generator.SelectAgent
+)
+
+// Template constrains the records generated from it, e.g. small slot bets
+type Template = generator.Template
+
+// Built-in generators for a Field without a dataset
+const (
+	GenUUID      = generator.GenUUID
+	GenULID      = generator.GenULID
+	GenName      = generator.GenName
+	GenFirstName = generator.GenFirstName
+	GenLastName  = generator.GenLastName
+	GenEmail     = generator.GenEmail
+	GenIPv4      = generator.GenIPv4
+	GenIPv6      = generator.GenIPv6
+	GenUserAgent = generator.GenUserAgent
+	GenCountry   = generator.GenCountry
+	GenLorem     = generator.GenLorem
+	GenCounter   = generator.GenCounter
+)
+
+// LoadDataset reads dataset records from a JSON array of objects
+func LoadDataset(path string) ([]map[string]any, error) {
+	return generator.LoadDataset(path)
+}
+
+// Iterator pulls transactions from the generator one at a time
+type Iterator struct {
+	producer *generator.Producer
+	limit    int64
+	emitted  int64
+}
+
+// New creates an iterator over generated transactions. A limit of 0 yields
+// transactions until the caller stops pulling, mirroring continuous mode.
+func New(refData *ReferenceData, limit int) *Iterator {
+	return &Iterator{
+		producer: generator.NewProducer(refData, slog.Default()),
+		limit:    int64(limit),
+	}
+}
+
+// SetSeed makes the sequence reproducible: iterators with the same seed and
+// reference data yield the same transactions. Zero keeps time-based seeding.
+func (it *Iterator) SetSeed(seed int64) {
+	it.producer.SetSeed(seed)
+}
+
+// RegisterDataset adds a custom reference dataset that fields can draw from,
+// replacing any dataset of the same name
+func (it *Iterator) RegisterDataset(d Dataset) error {
+	return it.producer.RegisterDataset(d)
+}
+
+// SetFields fills fields on every following transaction's Fields map. The
+// datasets they name must be registered first.
+func (it *Iterator) SetFields(fields []Field) error {
+	return it.producer.SetFields(fields)
+}
+
+// SetTemplates makes every following transaction follow one of the
+// templates, chosen by weight
+func (it *Iterator) SetTemplates(templates []Template) error {
+	return it.producer.SetTemplates(templates)
+}
+
+// Next returns the next transaction. It returns io.EOF once the limit has
+// been reached and ctx.Err() if the context is done.
+func (it *Iterator) Next(ctx context.Context) (*Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if it.limit > 0 && it.emitted >= it.limit {
+		return nil, io.EOF
+	}
+	it.emitted++
+	return it.producer.GenerateSingle(), nil
+}
+
+// Seq returns a range-over-func sequence that stops at the limit, when the
+// context is done, or when the loop body breaks.
+func (it *Iterator) Seq(ctx context.Context) iter.Seq[*Transaction] {
+	return func(yield func(*Transaction) bool) {
+		for {
+			txn, err := it.Next(ctx)
+			if err != nil {
+				return
+			}
+			if !yield(txn) {
+				return
+			}
+		}
+	}
+}
+
+// Count returns the number of transactions emitted so far
+func (it *Iterator) Count() int64 {
+	return it.emitted
+}
@@ -0,0 +1,110 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func testReferenceData(t *testing.T) *ReferenceData {
+	t.Helper()
+	refData, err := LoadReferenceData("../../data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return refData
+}
+
+func TestIteratorSeeded(t *testing.T) {
+	refData := testReferenceData(t)
+	a, b := New(refData, 0), New(refData, 0)
+	a.SetSeed(42)
+	b.SetSeed(42)
+	for i := 0; i < 100; i++ {
+		x, err := a.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		y, err := b.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(x, y) {
+			t.Fatalf("transaction %d differs under the same seed:\n%+v\n%+v", i, x, y)
+		}
+	}
+
+	seeded, other := New(refData, 0), New(refData, 0)
+	seeded.SetSeed(42)
+	other.SetSeed(43)
+	x, _ := seeded.Next(context.Background())
+	y, _ := other.Next(context.Background())
+	if reflect.DeepEqual(x, y) {
+		t.Error("different seeds yield the same transaction")
+	}
+}
+
+func TestIteratorLimit(t *testing.T) {
+	it := New(testReferenceData(t), 3)
+	for i := 0; i < 3; i++ {
+		if _, err := it.Next(context.Background()); err != nil {
+			t.Fatalf("transaction %d: %v", i, err)
+		}
+	}
+	if _, err := it.Next(context.Background()); err != io.EOF {
+		t.Errorf("err = %v past the limit, want io.EOF", err)
+	}
+	if it.Count() != 3 {
+		t.Errorf("count = %d, want 3", it.Count())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := New(testReferenceData(t), 0).Next(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v on a cancelled context", err)
+	}
+}
+
+func TestIteratorSeq(t *testing.T) {
+	refData := testReferenceData(t)
+
+	// Stops at the limit, with Count matching what the loop saw
+	it := New(refData, 5)
+	produced := 0
+	for range it.Seq(context.Background()) {
+		produced++
+	}
+	if produced != 5 || it.Count() != 5 {
+		t.Errorf("produced %d, count = %d, want 5", produced, it.Count())
+	}
+
+	// Break ends an unlimited sequence without pulling another transaction
+	it = New(refData, 0)
+	produced = 0
+	for range it.Seq(context.Background()) {
+		produced++
+		if produced == 7 {
+			break
+		}
+	}
+	if produced != 7 || it.Count() != 7 {
+		t.Errorf("produced %d, count = %d, want 7", produced, it.Count())
+	}
+
+	// Stops once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	it = New(refData, 0)
+	produced = 0
+	for range it.Seq(ctx) {
+		produced++
+		if produced == 4 {
+			cancel()
+		}
+	}
+	if produced != 4 || it.Count() != 4 {
+		t.Errorf("produced %d, count = %d after cancel, want 4", produced, it.Count())
+	}
+}